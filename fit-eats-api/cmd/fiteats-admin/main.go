@@ -0,0 +1,167 @@
+// Command fiteats-admin runs the operator actions of the admin api from a shell, using the same repositories.
+// It reads the .env file of the api from the working directory and writes every action to the audit log.
+//
+//	fiteats-admin [-actor name] <command> [arguments]
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/user"
+	"strconv"
+
+	"fit-eats-api/config"
+	"fit-eats-api/models"
+	"fit-eats-api/repositories"
+	"fit-eats-api/services"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type command struct {
+	usage string
+	args  int
+	run   func(ctx context.Context, adminService *services.AdminService, actor models.AuditActor, args []string) (any, error)
+}
+
+var commands = map[string]command{
+	"search-users": {"search-users <query> [limit]", 1, func(ctx context.Context, s *services.AdminService, actor models.AuditActor, args []string) (any, error) {
+		limit := int64(20)
+		if len(args) > 1 {
+			parsed, err := strconv.ParseInt(args[1], 10, 64)
+			if err != nil || parsed < 1 {
+				return nil, fmt.Errorf("limit must be a positive number")
+			}
+			limit = parsed
+		}
+		return s.SearchUsers(ctx, actor, args[0], limit)
+	}},
+	"set-role": {"set-role <userId> <user|coach|admin>", 2, func(ctx context.Context, s *services.AdminService, actor models.AuditActor, args []string) (any, error) {
+		userId, err := parseObjectId(args[0], "userId")
+		if err != nil {
+			return nil, err
+		}
+		return "User role updated", s.SetUserRole(ctx, actor, userId, models.Role(args[1]))
+	}},
+	"reset-password": {"reset-password <userId>", 1, func(ctx context.Context, s *services.AdminService, actor models.AuditActor, args []string) (any, error) {
+		userId, err := parseObjectId(args[0], "userId")
+		if err != nil {
+			return nil, err
+		}
+		password, err := s.ResetPassword(ctx, actor, userId)
+		return map[string]string{"temporaryPassword": password}, err
+	}},
+	"revoke-sessions": {"revoke-sessions <userId>", 1, func(ctx context.Context, s *services.AdminService, actor models.AuditActor, args []string) (any, error) {
+		userId, err := parseObjectId(args[0], "userId")
+		if err != nil {
+			return nil, err
+		}
+		return "Sessions revoked", s.RevokeSessions(ctx, actor, userId)
+	}},
+	"get-goal": {"get-goal <userId>", 1, func(ctx context.Context, s *services.AdminService, actor models.AuditActor, args []string) (any, error) {
+		userId, err := parseObjectId(args[0], "userId")
+		if err != nil {
+			return nil, err
+		}
+		return s.GetUserGoal(ctx, actor, userId)
+	}},
+	"delete-goal": {"delete-goal <goalId>", 1, func(ctx context.Context, s *services.AdminService, actor models.AuditActor, args []string) (any, error) {
+		goalId, err := parseObjectId(args[0], "goalId")
+		if err != nil {
+			return nil, err
+		}
+		return "Goal deleted", s.DeleteGoal(ctx, actor, goalId)
+	}},
+	"get-meal-plans": {"get-meal-plans <userId>", 1, func(ctx context.Context, s *services.AdminService, actor models.AuditActor, args []string) (any, error) {
+		userId, err := parseObjectId(args[0], "userId")
+		if err != nil {
+			return nil, err
+		}
+		return s.GetMealPlans(ctx, actor, userId)
+	}},
+	"delete-meal-plan": {"delete-meal-plan <mealPlanId>", 1, func(ctx context.Context, s *services.AdminService, actor models.AuditActor, args []string) (any, error) {
+		mealPlanId, err := parseObjectId(args[0], "mealPlanId")
+		if err != nil {
+			return nil, err
+		}
+		return "Meal plan deleted", s.DeleteMealPlan(ctx, actor, mealPlanId)
+	}},
+	"refresh-meal-images": {"refresh-meal-images <mealPlanId>", 1, func(ctx context.Context, s *services.AdminService, actor models.AuditActor, args []string) (any, error) {
+		mealPlanId, err := parseObjectId(args[0], "mealPlanId")
+		if err != nil {
+			return nil, err
+		}
+		meals, err := s.RefreshMealImages(ctx, actor, mealPlanId)
+		return map[string]int{"meals": meals}, err
+	}},
+}
+
+func main() {
+	actorName := flag.String("actor", "", "name recorded in the audit log, defaults to the os user")
+	timeout := flag.Int("timeout", 120, "timeout of the command in seconds")
+	flag.Usage = usage
+	flag.Parse()
+
+	cmd, ok := commands[flag.Arg(0)]
+	args := flag.Args()
+	if !ok || len(args)-1 < cmd.args {
+		usage()
+		os.Exit(2)
+	}
+
+	if *actorName == "" {
+		*actorName = "unknown"
+		if osUser, err := user.Current(); err == nil {
+			*actorName = osUser.Username
+		}
+	}
+	actor := models.AuditActor{Name: *actorName, Source: models.AUDIT_SOURCE_CLI}
+
+	cfg := config.GetConfig()
+	client := config.ConnectDB(cfg)
+	db := client.Database(cfg.Database)
+	defer client.Disconnect(context.Background())
+
+	adminService := services.NewAdminService(
+		repositories.NewMongoUserRepository(db),
+		repositories.NewSessionRepository(db),
+		repositories.NewUserTokenRepository(db),
+		repositories.NewMongoLoginAttemptStore(db),
+		repositories.NewMongoUserGoalRepository(db),
+		repositories.NewMongoMealRepository(db),
+		repositories.NewMongoAuditRepository(db),
+		repositories.NewMongoUnitOfWork(db, cfg.MongoWithoutTransactions),
+	)
+
+	timedContext, cancel := config.GetTimedContext(*timeout)
+	defer cancel()
+
+	result, err := cmd.run(timedContext, adminService, actor, args[1:])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		cancel()
+		os.Exit(1)
+	}
+
+	output, _ := json.MarshalIndent(result, "", "  ")
+	fmt.Println(string(output))
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: fiteats-admin [-actor name] [-timeout seconds] <command> [arguments]")
+	fmt.Fprintln(os.Stderr, "\nCommands:")
+	for _, name := range []string{"search-users", "set-role", "reset-password", "revoke-sessions", "get-goal", "delete-goal", "get-meal-plans", "delete-meal-plan", "refresh-meal-images"} {
+		fmt.Fprintln(os.Stderr, "  "+commands[name].usage)
+	}
+}
+
+func parseObjectId(value string, field string) (primitive.ObjectID, error) {
+	id, err := primitive.ObjectIDFromHex(value)
+	if err != nil {
+		return primitive.NilObjectID, fmt.Errorf("invalid %s format: must be a valid ObjectId", field)
+	}
+	return id, nil
+}
@@ -0,0 +1,65 @@
+// Command fiteats-migrate lists and applies the database migrations, for deployments which start the api
+// with MIGRATE_ON_STARTUP=false. It reads the .env file of the api from the working directory.
+//
+//	fiteats-migrate [-timeout seconds] <status|up>
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"fit-eats-api/config"
+	"fit-eats-api/migrations"
+	"fit-eats-api/repositories"
+)
+
+var commands = map[string]func(ctx context.Context, runner *migrations.Runner) (any, error){
+	"status": func(ctx context.Context, runner *migrations.Runner) (any, error) {
+		return runner.GetStatus(ctx)
+	},
+	"up": func(ctx context.Context, runner *migrations.Runner) (any, error) {
+		ran, err := runner.Run(ctx)
+		return map[string][]string{"applied": ran}, err
+	},
+}
+
+func main() {
+	timeout := flag.Int("timeout", 600, "timeout of the command in seconds")
+	flag.Usage = usage
+	flag.Parse()
+
+	run, ok := commands[flag.Arg(0)]
+	if !ok || flag.NArg() != 1 {
+		usage()
+		os.Exit(2)
+	}
+
+	cfg := config.GetConfig()
+	client := config.ConnectDB(cfg)
+	db := client.Database(cfg.Database)
+	defer client.Disconnect(context.Background())
+
+	runner := migrations.NewRunner(db, repositories.NewMongoMigrationRepository(db))
+
+	timedContext, cancel := config.GetTimedContext(*timeout)
+	defer cancel()
+
+	result, err := run(timedContext, runner)
+	output, _ := json.MarshalIndent(result, "", "  ")
+	fmt.Println(string(output))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		cancel()
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: fiteats-migrate [-timeout seconds] <command>")
+	fmt.Fprintln(os.Stderr, "\nCommands:")
+	fmt.Fprintln(os.Stderr, "  status    list the migrations and when they were applied")
+	fmt.Fprintln(os.Stderr, "  up        apply the pending migrations in order")
+}
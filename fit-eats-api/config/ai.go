@@ -2,6 +2,7 @@ package config
 
 import (
 	"fit-eats-api/models"
+	"strings"
 	"sync"
 
 	"fmt"
@@ -17,6 +18,7 @@ var tdeeModel *genai.GenerativeModel
 var macroModel *genai.GenerativeModel
 var mealModel *genai.GenerativeModel
 var singleMealModel *genai.GenerativeModel
+var workoutModel *genai.GenerativeModel
 
 var loadOnceWeightRange sync.Once
 var loadOnceGoalDuration sync.Once
@@ -24,6 +26,7 @@ var loadOnceTdee sync.Once
 var loadOnceMacro sync.Once
 var loadOnceMeal sync.Once
 var loadOnceSingleMeal sync.Once
+var loadOnceWorkout sync.Once
 
 func getBaseModel() genai.GenerativeModel {
 	ctx, cancel := GetTimedContext()
@@ -51,6 +54,40 @@ func getBaseModel() genai.GenerativeModel {
 	return *baseModel
 }
 
+// getMicronutrientSchema is shared by the meal schemas, the units are mentioned in the meal prompts
+func getMicronutrientSchema() *genai.Schema {
+	return &genai.Schema{
+		Type:     genai.TypeObject,
+		Required: []string{"fibre", "sugar", "sodium", "saturated_fat", "iron", "calcium", "vitamin_b12", "vitamin_d"},
+		Properties: map[string]*genai.Schema{
+			"fibre": {
+				Type: genai.TypeNumber,
+			},
+			"sugar": {
+				Type: genai.TypeNumber,
+			},
+			"sodium": {
+				Type: genai.TypeNumber,
+			},
+			"saturated_fat": {
+				Type: genai.TypeNumber,
+			},
+			"iron": {
+				Type: genai.TypeNumber,
+			},
+			"calcium": {
+				Type: genai.TypeNumber,
+			},
+			"vitamin_b12": {
+				Type: genai.TypeNumber,
+			},
+			"vitamin_d": {
+				Type: genai.TypeNumber,
+			},
+		},
+	}
+}
+
 func GetWeightRangeModel() *genai.GenerativeModel {
 	if weightRangeModel == nil {
 		loadOnceWeightRange.Do(func() {
@@ -114,11 +151,14 @@ func GetGoalDurationModel() *genai.GenerativeModel {
 			temp.ResponseSchema = &genai.Schema{
 				Type: genai.TypeObject,
 				Properties: map[string]*genai.Schema{
+					// Event goals end on their event, the duration is not estimated for them
 					"type": {
 						Type: genai.TypeString,
 						Enum: []string{
-							"Fat loss",
-							"Muscle gain",
+							string(models.FAT_LOSS),
+							string(models.MUSCLE_GAIN),
+							string(models.RECOMPOSITION),
+							string(models.MAINTENANCE),
 						},
 					},
 					"pace_options": {
@@ -348,7 +388,7 @@ func GetMealModel() *genai.GenerativeModel {
 									Type: genai.TypeArray,
 									Items: &genai.Schema{
 										Type:     genai.TypeObject,
-										Required: []string{"time", "name", "description", "ingredients", "recipe_steps", "calories", "protein", "fat", "carbs"},
+										Required: []string{"time", "name", "description", "ingredients", "recipe_steps", "calories", "protein", "fat", "carbs", "micronutrients"},
 										Properties: map[string]*genai.Schema{
 											"time": {
 												Type: genai.TypeString,
@@ -392,6 +432,7 @@ func GetMealModel() *genai.GenerativeModel {
 											"carbs": {
 												Type: genai.TypeInteger,
 											},
+											"micronutrients": getMicronutrientSchema(),
 										},
 									},
 								},
@@ -418,7 +459,7 @@ func GetSingleMealModel() *genai.GenerativeModel {
 						Type: genai.TypeArray,
 						Items: &genai.Schema{
 							Type:     genai.TypeObject,
-							Required: []string{"time", "name", "description", "ingredients", "recipe_steps", "calories", "protein", "fat", "carbs"},
+							Required: []string{"time", "name", "description", "ingredients", "recipe_steps", "calories", "protein", "fat", "carbs", "micronutrients"},
 							Properties: map[string]*genai.Schema{
 								"time": {
 									Type: genai.TypeString,
@@ -462,6 +503,7 @@ func GetSingleMealModel() *genai.GenerativeModel {
 								"carbs": {
 									Type: genai.TypeInteger,
 								},
+								"micronutrients": getMicronutrientSchema(),
 							},
 						},
 					},
@@ -473,6 +515,72 @@ func GetSingleMealModel() *genai.GenerativeModel {
 	return singleMealModel
 }
 
+func GetWorkoutModel() *genai.GenerativeModel {
+	if workoutModel == nil {
+		loadOnceWorkout.Do(func() {
+			temp := getBaseModel()
+			temp.ResponseSchema = &genai.Schema{
+				Type:     genai.TypeObject,
+				Required: []string{"workoutDays"},
+				Properties: map[string]*genai.Schema{
+					"workoutDays": {
+						Type: genai.TypeArray,
+						Items: &genai.Schema{
+							Type:     genai.TypeObject,
+							Required: []string{"dayOfWeek", "name", "isRestDay", "exercises"},
+							Properties: map[string]*genai.Schema{
+								"dayOfWeek": {
+									Type: genai.TypeString,
+									Enum: []string{"Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday", "Sunday"},
+								},
+								"name": {
+									Type: genai.TypeString,
+								},
+								"isRestDay": {
+									Type: genai.TypeBoolean,
+								},
+								"exercises": {
+									Type: genai.TypeArray,
+									Items: &genai.Schema{
+										Type:     genai.TypeObject,
+										Required: []string{"exercise", "sets", "reps", "weight_in_kg", "duration_minutes", "rest_seconds", "notes"},
+										Properties: map[string]*genai.Schema{
+											"exercise": {
+												Type: genai.TypeString,
+												Enum: models.GetExerciseNames(),
+											},
+											"sets": {
+												Type: genai.TypeInteger,
+											},
+											"reps": {
+												Type: genai.TypeInteger,
+											},
+											"weight_in_kg": {
+												Type: genai.TypeNumber,
+											},
+											"duration_minutes": {
+												Type: genai.TypeInteger,
+											},
+											"rest_seconds": {
+												Type: genai.TypeInteger,
+											},
+											"notes": {
+												Type: genai.TypeString,
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			}
+			workoutModel = &temp
+		})
+	}
+	return workoutModel
+}
+
 func GetWeightRangePrompt(user models.User, currentWeightInKg float32, currentBodyFatPercentage float32) string {
 	bodyFatString := ""
 	if currentBodyFatPercentage != 0 {
@@ -494,7 +602,9 @@ func GetGoalDurationPrompt(user models.User, currentWeightInKg float32, currentB
 	return fmt.Sprintf("I am %.1f kg %s, %s year old %s, and %.1f cm in height."+
 		" My goal is to get to target weight as %.1f kg and %.1f%% body fat."+
 		" I want 3 pace options to get to my target namely slow paced, medium paced and fast paced."+
-		" For each option I want also want to know duration in weeks to achieve the target, weekly weight change i.e. loss or gain in kg.",
+		" For each option I want also want to know duration in weeks to achieve the target, weekly weight change i.e. loss or gain in kg."+
+		" The type is fat loss or muscle gain when the weight changes, body recomposition when the weight stays within 3 kg while the body fat goes down"+
+		" and maintenance when the weight stays within 1 kg, the weekly weight change of body recomposition and maintenance is 0.",
 		currentWeightInKg, bodyFatString, user.Age, user.Sex, user.HeightInCm, goalWeightInKg, goalBodyFatPercentage)
 }
 
@@ -520,10 +630,61 @@ func GetDailyMacroPrompt(user models.User, currentWeightInKg float32, currentBod
 	}
 
 	return fmt.Sprintf("I am %.1f kg %s, %s year old %s, and %.1f cm in height."+
-		" My goal is %s, with target weight as %.1f kg and %.1f%% body fat."+
+		" My goal is %s, with target weight as %.1f kg and %.1f%% body fat.%s"+
 		" With my Current bmr of %d calories and tdee of %d calories, I want to know my daily calorie intake to achieve a weight change of %.2f kg per week. "+
 		" Make sure to include daily calories and macros in response.",
-		currentWeightInKg, bodyFatString, user.Age, user.Sex, user.HeightInCm, goalType, goalWeightInKg, goalBodyFatPercentage, currentBmr, currentTdee, weightChange)
+		currentWeightInKg, bodyFatString, user.Age, user.Sex, user.HeightInCm, goalType, goalWeightInKg, goalBodyFatPercentage,
+		getGoalTypePrompt(models.GoalType(goalType)), currentBmr, currentTdee, weightChange)
+}
+
+// getGoalTypePrompt describes how the goal type sets the calories and macros
+func getGoalTypePrompt(goalType models.GoalType) string {
+	switch goalType {
+	case models.FAT_LOSS:
+		return " Since my goal is fat loss, keep a calorie deficit with high protein so I lose fat and keep my muscle."
+	case models.MUSCLE_GAIN:
+		return " Since my goal is muscle gain, keep a small calorie surplus with high protein so I gain muscle with little fat."
+	case models.RECOMPOSITION:
+		return " Since my goal is body recomposition, I want to lose fat and build muscle while my weight stays about the same," +
+			" keep the calories at or just below maintenance with around 2 grams of protein per kg of body weight."
+	case models.MAINTENANCE:
+		return " Since my goal is maintenance, keep the calories at my maintenance so my weight stays the same, with balanced macros."
+	case models.EVENT:
+		return " My goal is to reach the target weight by an event on the end date of the goal, never go beyond a safe rate of change" +
+			" and keep protein high so the change comes from fat rather than muscle."
+	}
+	return ""
+}
+
+// getDietPreferencePrompt describes the user's country, diet pattern rules, cuisines, meals per day and fasting window
+func getDietPreferencePrompt(user models.User) string {
+	dietPrompt := fmt.Sprintf(" I am from %s.", user.Country)
+
+	if rule, ok := models.DietRules[user.DietPreference]; ok {
+		dietPrompt += fmt.Sprintf(" I follow a %s diet, that is %s.", rule.Pattern, rule.Description)
+		if len(rule.ForbiddenCategories) > 0 {
+			forbidden := make([]string, len(rule.ForbiddenCategories))
+			for i, category := range rule.ForbiddenCategories {
+				forbidden[i] = strings.ToLower(string(category))
+			}
+			dietPrompt += fmt.Sprintf(" Never include any ingredient from these categories: %s.", strings.Join(forbidden, ", "))
+		}
+	} else if user.DietPreference != "" {
+		dietPrompt += fmt.Sprintf(" I prefer %s diet.", user.DietPreference)
+	}
+
+	if len(user.CuisinePreferences) > 0 {
+		dietPrompt += fmt.Sprintf(" I prefer %s cuisine.", strings.Join(user.CuisinePreferences, ", "))
+	}
+	if user.MealsPerDay > 0 {
+		dietPrompt += fmt.Sprintf(" I want exactly %d meals per day.", user.MealsPerDay)
+	}
+	if user.FastingWindow != nil && user.FastingWindow.StartTime != "" && user.FastingWindow.EndTime != "" {
+		dietPrompt += fmt.Sprintf(" I fast from %s to %s, do not schedule any meal within this window.",
+			user.FastingWindow.StartTime, user.FastingWindow.EndTime)
+	}
+
+	return dietPrompt
 }
 
 // TODO add a user prompt for preferences
@@ -538,19 +699,21 @@ func GetWeeklyMealPrompt(user models.User, prompt string,
 	}
 
 	return fmt.Sprintf("I am %.1f kg %s, %s year old %s, and %.1f cm in height."+
-		" My goal is %s, with target weight as %.1f kg and %.1f%% body fat."+
+		" My goal is %s, with target weight as %.1f kg and %.1f%% body fat.%s"+
 		" For the next week I will be on a %d calorie per day diet with %d grams protein %d grams fat and %d grams carbs."+
-		" I am from %s and prefer %s diet."+
+		"%s"+
 		" Include meals that are easily available in my country, and keep my dietary preference in line with this."+
 		" Suggest a meal plan for a the whole week including time frames for each meal."+
 		" Make sure to include calories and macros."+
+		" Also include micronutrients for each meal: fibre, sugar and saturated fat in grams, sodium, iron and calcium in milligrams, vitamin b12 and vitamin d in micrograms."+
 		" Time should always be in am/pm format for eg. 6:30 pm. "+
 		" Make sure the ingredients are generic and not specific to a brand or country, also make sure to include raw ingredients rather than processed or store bought finished products."+
 		" for eg. ingredient should not include 'chicken tikka masala' instead break it down into raw ingredients and include in recipe steps."+
 		" I will also attach a prompt with any special requests."+
 		" Make sure to only include items from the prompt that are relevant to meal plan and exclude anything else."+
 		" prompt: %s",
-		currentWeightInKg, bodyFatString, user.Age, user.Sex, user.HeightInCm, goalType, goalWeightInKg, goalBodyFatPercentage, maxCalories, maxProtein, maxFat, maxCarb, user.Country, user.DietPreference, prompt)
+		currentWeightInKg, bodyFatString, user.Age, user.Sex, user.HeightInCm, goalType, goalWeightInKg, goalBodyFatPercentage, getGoalTypePrompt(models.GoalType(goalType)),
+		maxCalories, maxProtein, maxFat, maxCarb, getDietPreferencePrompt(user), prompt)
 }
 
 func GetSingleMealEditPrompt(user models.User, mealsAsJsonString string, prompt string,
@@ -564,18 +727,65 @@ func GetSingleMealEditPrompt(user models.User, mealsAsJsonString string, prompt
 	}
 
 	return fmt.Sprintf("I am %.1f kg %s, %s year old %s, and %.1f cm in height."+
-		" My goal is %s, with target weight as %.1f kg and %.1f%% body fat."+
+		" My goal is %s, with target weight as %.1f kg and %.1f%% body fat.%s"+
 		" For the next week I will be on a %d calorie per day diet with %d grams protein %d grams fat and %d grams carbs."+
-		" I am from %s and prefer %s diet."+
+		"%s"+
 		" Include meals that are easily available in my country, and keep my dietary preference in line with this."+
 		" Suggest changes to a single day meal plan. I will attach the meal plan and also a prompt with the requested changes."+
 		" Make sure to only include items from the prompt that are relevant to meal plan and exclude anything else."+
 		" Make sure to include calories and macros."+
+		" Also include micronutrients for each meal: fibre, sugar and saturated fat in grams, sodium, iron and calcium in milligrams, vitamin b12 and vitamin d in micrograms."+
 		" Time should always be in am/pm format for eg. 6:30 pm. "+
 		" If you don't find anything relevant in the prompt send the same meal back."+
 		" Make sure the ingredients are generic and not specific to a brand or country, also make sure to include raw ingredients rather than processed or store bought finished products."+
 		" for eg. ingredient should not include 'chicken tikka masala' instead break it down into raw ingredients and include in recipe steps."+
 		" Meals: %s."+
 		" Prompt: %s.",
-		currentWeightInKg, bodyFatString, user.Age, user.Sex, user.HeightInCm, goalType, goalWeightInKg, goalBodyFatPercentage, maxCalories, maxProtein, maxFat, maxCarb, user.Country, user.DietPreference, mealsAsJsonString, prompt)
+		currentWeightInKg, bodyFatString, user.Age, user.Sex, user.HeightInCm, goalType, goalWeightInKg, goalBodyFatPercentage, getGoalTypePrompt(models.GoalType(goalType)),
+		maxCalories, maxProtein, maxFat, maxCarb, getDietPreferencePrompt(user), mealsAsJsonString, prompt)
+}
+
+func GetWeeklyWorkoutPrompt(user models.User, prompt string,
+	currentWeightInKg float32, currentBodyFatPercentage float32,
+	goalWeightInKg float32, goalBodyFatPercentage float32,
+	goalType models.GoalType, activityLevel models.ActivityLevel) string {
+	bodyFatString := ""
+	if currentBodyFatPercentage != 0 {
+		bodyFatString = fmt.Sprintf("with approx %.1f%% body fat", currentBodyFatPercentage)
+	}
+
+	activityString := ""
+	if activityLevel != "" {
+		activityString = fmt.Sprintf(" My current activity level is %s.", activityLevel)
+	}
+
+	goalString := ""
+	switch goalType {
+	case models.FAT_LOSS:
+		goalString = " Since my goal is fat loss, keep full body strength training 3 to 4 days a week to preserve muscle," +
+			" add cardio or conditioning on 2 to 3 days and keep rest between sets short."
+	case models.MUSCLE_GAIN:
+		goalString = " Since my goal is muscle gain, use a hypertrophy focused split 4 to 5 days a week with progressive overload," +
+			" mostly 6 to 12 reps per set, longer rest between heavy sets and only light cardio."
+	case models.RECOMPOSITION:
+		goalString = " Since my goal is body recomposition, train strength 4 days a week with progressive overload as the priority," +
+			" mostly 6 to 12 reps per set, and add moderate cardio on 2 days."
+	case models.MAINTENANCE:
+		goalString = " Since my goal is maintenance, keep a balanced routine of full body strength training 3 days a week" +
+			" and cardio or mobility on 2 days."
+	case models.EVENT:
+		goalString = " Since I am getting ready for an event, keep full body strength training 3 to 4 days a week," +
+			" add cardio on 2 to 3 days and make the last days before the event lighter."
+	}
+
+	return fmt.Sprintf("I am %.1f kg %s, %s year old %s, and %.1f cm in height."+
+		" My goal is %s, with target weight as %.1f kg and %.1f%% body fat.%s"+
+		" Suggest a workout routine for the whole week with one entry for each day of the week.%s"+
+		" Include at least one rest day, rest days should have no exercises."+
+		" Only use exercises from the provided list, suggest a starting weight in kg suitable for my body weight, use 0 for bodyweight exercises."+
+		" For cardio and mobility exercises set sets and reps to 0 and use duration in minutes instead."+
+		" I will also attach a prompt with any special requests."+
+		" Make sure to only include items from the prompt that are relevant to the workout routine and exclude anything else."+
+		" prompt: %s",
+		currentWeightInKg, bodyFatString, user.Age, user.Sex, user.HeightInCm, goalType, goalWeightInKg, goalBodyFatPercentage, activityString, goalString, prompt)
 }
@@ -4,6 +4,7 @@ import (
 	"context"
 	"log"
 	"os"
+	"strings"
 	"sync"
 	"time"
 
@@ -18,6 +19,41 @@ type Config struct {
 	Port             string
 	GeminiApiKey     string
 	SerperApiKey     string
+
+	// Mail settings, MAIL_DRIVER is required and either "smtp" or "log" which writes mails to MAIL_LOG_PATH for local development
+	MailDriver   string
+	MailFrom     string
+	MailLogPath  string
+	SmtpHost     string
+	SmtpPort     string
+	SmtpUsername string
+	SmtpPassword string
+
+	// Base url of the app, used to build the links sent in emails
+	AppBaseUrl string
+
+	// OpenID Connect providers keyed by name, listed in OIDC_PROVIDERS
+	OidcProviders map[string]OidcProviderConfig
+
+	// Addresses of the reverse proxies in front of the api, listed in TRUSTED_PROXIES. The client ip, which login
+	// throttling is keyed on, is only read from X-Forwarded-For when the request comes from one of them
+	TrustedProxies []string
+
+	// Pending migrations are applied when the server starts unless MIGRATE_ON_STARTUP is "false",
+	// they can be applied with fiteats-migrate instead
+	MigrateOnStartup bool
+
+	// Writes spanning several documents run in Mongo transactions, which need a replica set. The server refuses to
+	// start against a standalone server unless MONGO_WITHOUT_TRANSACTIONS is "true", for local development only
+	MongoWithoutTransactions bool
+}
+
+// OidcProviderConfig is read from OIDC_<NAME>_ISSUER, OIDC_<NAME>_CLIENT_ID, OIDC_<NAME>_CLIENT_SECRET and OIDC_<NAME>_REDIRECT_URI
+type OidcProviderConfig struct {
+	Issuer       string
+	ClientId     string
+	ClientSecret string
+	RedirectUri  string
 }
 
 var projectConfig *Config
@@ -38,14 +74,64 @@ func GetConfig() *Config {
 			Port:             os.Getenv("PORT"),
 			GeminiApiKey:     os.Getenv("GEMINI_API_KEY"),
 			SerperApiKey:     os.Getenv("SERPER_API_KEY"),
+			MailDriver:       os.Getenv("MAIL_DRIVER"),
+			MailFrom:         os.Getenv("MAIL_FROM"),
+			MailLogPath:      os.Getenv("MAIL_LOG_PATH"),
+			SmtpHost:         os.Getenv("SMTP_HOST"),
+			SmtpPort:         os.Getenv("SMTP_PORT"),
+			SmtpUsername:     os.Getenv("SMTP_USERNAME"),
+			SmtpPassword:     os.Getenv("SMTP_PASSWORD"),
+			AppBaseUrl:       os.Getenv("APP_BASE_URL"),
+			MigrateOnStartup: os.Getenv("MIGRATE_ON_STARTUP") != "false",
+
+			MongoWithoutTransactions: os.Getenv("MONGO_WITHOUT_TRANSACTIONS") == "true",
 		}
 
+		config.OidcProviders = loadOidcProviders(os.Getenv("OIDC_PROVIDERS"))
+		config.TrustedProxies = loadList(os.Getenv("TRUSTED_PROXIES"))
+
 		projectConfig = &config
 	})
 
 	return projectConfig
 }
 
+// loadOidcProviders reads the settings of each provider in a comma separated list like "google,apple"
+func loadOidcProviders(names string) map[string]OidcProviderConfig {
+	providers := map[string]OidcProviderConfig{}
+	for _, name := range strings.Split(names, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+
+		prefix := "OIDC_" + strings.ToUpper(name) + "_"
+		provider := OidcProviderConfig{
+			Issuer:       os.Getenv(prefix + "ISSUER"),
+			ClientId:     os.Getenv(prefix + "CLIENT_ID"),
+			ClientSecret: os.Getenv(prefix + "CLIENT_SECRET"),
+			RedirectUri:  os.Getenv(prefix + "REDIRECT_URI"),
+		}
+		if provider.Issuer == "" || provider.ClientId == "" {
+			log.Printf("Skipping oidc provider %s: issuer and client id are required", name)
+			continue
+		}
+		providers[name] = provider
+	}
+	return providers
+}
+
+// loadList splits a comma separated setting, nil when it is empty
+func loadList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
 func GetTimedContext(timeout ...int) (context.Context, context.CancelFunc) {
 	if len(timeout) == 0 {
 		return context.WithTimeout(context.Background(), 5*time.Second)
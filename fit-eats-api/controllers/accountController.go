@@ -0,0 +1,196 @@
+package controllers
+
+import (
+	"bytes"
+	"fit-eats-api/config"
+	"fit-eats-api/middleware"
+	"fit-eats-api/models"
+	"fit-eats-api/services"
+	"fit-eats-api/utils"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type AccountController struct {
+	UserController *UserController
+	AccountService *services.AccountService
+}
+
+// NewAccountController reuses the UserController to check the credentials confirming a deletion
+func NewAccountController(userController *UserController, accountService *services.AccountService) *AccountController {
+	return &AccountController{UserController: userController, AccountService: accountService}
+}
+
+// DeleteAccount schedules the deletion of the account after the grace period. The password, and a second factor
+// when enabled, confirm the request. They are sent as json since DELETE requests have no form body.
+func (c *AccountController) DeleteAccount(ctx *gin.Context) {
+	var body struct {
+		Password     string `json:"password"`
+		Code         string `json:"code"`
+		RecoveryCode string `json:"recoveryCode"`
+	}
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		ctx.Error(models.NewApiError(models.INVALID_REQUEST, "Invalid request format"))
+		return
+	}
+
+	// Mail servers can be slow to answer
+	timedContext, cancel := config.GetTimedContext(30)
+	defer cancel()
+
+	user, err := c.UserController.UserRepository.GetUserCredentialsById(timedContext, middleware.GetUserId(ctx))
+	if err != nil {
+		ctx.Error(models.NewApiError(models.USER_NOT_FOUND, "User not found"))
+		return
+	}
+
+	// Accounts created through a social login have no password to confirm with
+	if user.HasPassword() && !utils.IsPasswordCorrect(user.Password, body.Password) {
+		ctx.Error(models.NewApiError(models.INVALID_CREDENTIALS, "invalid Credentials"))
+		return
+	}
+	if user.IsMfaEnabled() && !c.UserController.verifySecondFactor(timedContext, user, body.Code, body.RecoveryCode, time.Now()) {
+		ctx.Error(models.NewApiError(models.INVALID_CREDENTIALS, "invalid Credentials"))
+		return
+	}
+
+	scheduledAt, err := c.AccountService.ScheduleDeletion(timedContext, user)
+	if err == mongo.ErrNoDocuments {
+		ctx.Error(models.NewApiError(models.INVALID_STATE, "Account deletion is already scheduled"))
+		return
+	}
+	if err != nil {
+		ctx.Error(models.NewApiError(models.INTERNAL_ERROR, "Could not delete account").WithCause(err))
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, gin.H{"message": "Account will be deleted, log in and restore it to cancel", "deletionScheduledAt": scheduledAt})
+}
+
+// RestoreAccount cancels a scheduled deletion during the grace period
+func (c *AccountController) RestoreAccount(ctx *gin.Context) {
+	timedContext, cancel := config.GetTimedContext()
+	defer cancel()
+
+	err := c.AccountService.CancelDeletion(timedContext, middleware.GetUserId(ctx))
+	if err == mongo.ErrNoDocuments {
+		ctx.Error(models.NewApiError(models.INVALID_STATE, "Account deletion is not scheduled"))
+		return
+	}
+	if err != nil {
+		ctx.Error(models.NewApiError(models.INTERNAL_ERROR, "Could not restore account").WithCause(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Account restored"})
+}
+
+// ExportAccount returns a zip of the user's data, large accounts or async=true get an export generated in the background
+// whose status is polled with GetAccountExport
+func (c *AccountController) ExportAccount(ctx *gin.Context) {
+	userId := middleware.GetUserId(ctx)
+
+	timedContext, cancel := config.GetTimedContext()
+	defer cancel()
+
+	large, err := c.AccountService.IsLargeAccount(timedContext, userId)
+	if err != nil {
+		ctx.Error(models.NewApiError(models.INTERNAL_ERROR, "Could not export data").WithCause(err))
+		return
+	}
+
+	if large || ctx.Query("async") == "true" {
+		user, err := c.UserController.UserRepository.GetUserProfileById(timedContext, userId)
+		if err != nil {
+			ctx.Error(models.NewApiError(models.USER_NOT_FOUND, "User not found"))
+			return
+		}
+		dataExport, err := c.AccountService.StartExport(timedContext, user)
+		if err != nil {
+			ctx.Error(models.NewApiError(models.INTERNAL_ERROR, "Could not start export").WithCause(err))
+			return
+		}
+		ctx.JSON(http.StatusAccepted, dataExport)
+		return
+	}
+
+	exportContext, cancelExport := config.GetTimedContext(60)
+	defer cancelExport()
+
+	// Small accounts fit in memory, the zip is complete before the status is sent so a failure is a proper error
+	var export bytes.Buffer
+	if err := c.AccountService.WriteExport(exportContext, userId, &export); err != nil {
+		ctx.Error(models.NewApiError(models.INTERNAL_ERROR, "Could not export data").WithCause(err))
+		return
+	}
+
+	ctx.Header("Content-Disposition", exportFileName(time.Now()))
+	ctx.Data(http.StatusOK, "application/zip", export.Bytes())
+}
+
+func (c *AccountController) GetAccountExport(ctx *gin.Context) {
+	dataExport, ok := c.getDataExport(ctx)
+	if !ok {
+		return
+	}
+
+	ctx.JSON(http.StatusOK, dataExport)
+}
+
+func (c *AccountController) DownloadAccountExport(ctx *gin.Context) {
+	dataExport, ok := c.getDataExport(ctx)
+	if !ok {
+		return
+	}
+	if dataExport.Status != models.DATA_EXPORT_READY {
+		ctx.Error(models.NewApiError(models.INVALID_STATE, "Export is not ready").WithDetails(map[string]any{"status": dataExport.Status}))
+		return
+	}
+
+	timedContext, cancel := config.GetTimedContext(60)
+	defer cancel()
+
+	ctx.Header("Content-Type", "application/zip")
+	ctx.Header("Content-Disposition", exportFileName(*dataExport.CompletedAt))
+	ctx.Status(http.StatusOK)
+	if err := c.AccountService.DataExportRepository.DownloadFile(timedContext, dataExport.FileId, ctx.Writer); err != nil {
+		log.Printf("Download of data export %s failed: %v", dataExport.ID.Hex(), err)
+	}
+}
+
+// getDataExport reads the export in the exportId query param, writing the error response when it is not found
+func (c *AccountController) getDataExport(ctx *gin.Context) (*models.DataExport, bool) {
+	exportId, ok := ctx.GetQuery("exportId")
+	if !ok {
+		ctx.Error(models.NewApiError(models.INVALID_REQUEST, "Invalid request format: missing exportId"))
+		return nil, false
+	}
+	mongoExportId, err := primitive.ObjectIDFromHex(exportId)
+	if err != nil {
+		ctx.Error(models.NewApiError(models.INVALID_REQUEST, "Invalid exportId format: must be a valid ObjectId"))
+		return nil, false
+	}
+
+	timedContext, cancel := config.GetTimedContext()
+	defer cancel()
+
+	dataExport, err := c.AccountService.DataExportRepository.GetDataExport(timedContext, middleware.GetUserId(ctx), mongoExportId)
+	if err != nil {
+		ctx.Error(models.NewApiError(models.INTERNAL_ERROR, "Could not get export").WithCause(err))
+		return nil, false
+	}
+	if dataExport == nil || dataExport.ExpiresAt.Before(time.Now()) {
+		ctx.Error(models.NewApiError(models.EXPORT_NOT_FOUND, "Export not found"))
+		return nil, false
+	}
+	return dataExport, true
+}
+
+func exportFileName(createdAt time.Time) string {
+	return `attachment; filename="fiteats-export-` + createdAt.Format("2006-01-02") + `.zip"`
+}
@@ -0,0 +1,50 @@
+package controllers
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"net/http"
+	"testing"
+
+	"fit-eats-api/models"
+	"fit-eats-api/repositories"
+	"fit-eats-api/services"
+	"fit-eats-api/utils"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func newTestAccountController() *AccountController {
+	users := repositories.NewInMemoryUserRepository()
+	dataExports := repositories.NewInMemoryDataExportRepository()
+	accounts := repositories.NewInMemoryAccountRepository(users, repositories.NewInMemoryUserGoalRepository(), repositories.NewInMemoryMealRepository(), dataExports)
+	accountService := services.NewAccountService(users, nil, accounts, dataExports, repositories.NewInMemoryLoginAttemptStore(),
+		repositories.NewInMemoryAuditRepository(), &utils.LogMailer{}, repositories.NewInMemoryUnitOfWork())
+	return NewAccountController(&UserController{UserRepository: users}, accountService)
+}
+
+func TestExportAccountSync(t *testing.T) {
+	controller := newTestAccountController()
+	user := &models.User{Name: "Asha", Email: "asha@fiteats.test"}
+	if err := controller.UserController.UserRepository.CreateUser(context.Background(), user); err != nil {
+		t.Fatal(err)
+	}
+
+	router := newTestRouter(user.ID, models.ROLE_USER)
+	router.GET("/api/account/export", controller.ExportAccount)
+	recorder := serve(router, http.MethodGet, "/api/account/export", nil)
+	expectStatus(t, recorder, http.StatusOK)
+	if contentType := recorder.Header().Get("Content-Type"); contentType != "application/zip" {
+		t.Fatalf("expected a zip, got %s", contentType)
+	}
+	if _, err := zip.NewReader(bytes.NewReader(recorder.Body.Bytes()), int64(recorder.Body.Len())); err != nil {
+		t.Fatalf("expected a complete zip, got %v", err)
+	}
+
+	// The export fails before anything is sent, the client gets an error instead of a broken zip
+	router = newTestRouter(primitive.NewObjectID(), models.ROLE_USER)
+	router.GET("/api/account/export", controller.ExportAccount)
+	recorder = serve(router, http.MethodGet, "/api/account/export", nil)
+	expectError(t, recorder, http.StatusInternalServerError, models.INTERNAL_ERROR)
+}
@@ -0,0 +1,244 @@
+package controllers
+
+import (
+	"context"
+	"fit-eats-api/config"
+	"fit-eats-api/middleware"
+	"fit-eats-api/models"
+	"fit-eats-api/repositories"
+	"fit-eats-api/utils"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type ActivityController struct {
+	UserRepository     repositories.UserRepository
+	UserGoalRepository repositories.UserGoalRepository
+	ActivityRepository *repositories.ActivityRepository
+}
+
+func NewActivityController(userRepository repositories.UserRepository, userGoalRepository repositories.UserGoalRepository, activityRepository *repositories.ActivityRepository) *ActivityController {
+	return &ActivityController{UserRepository: userRepository, UserGoalRepository: userGoalRepository, ActivityRepository: activityRepository}
+}
+
+// getCurrentWeightInKg reads the weight of the active weekly goal, 0 when there is none
+func (c *ActivityController) getCurrentWeightInKg(ctx context.Context, userId primitive.ObjectID) float64 {
+	mainGoal, err := c.UserGoalRepository.GetUserActiveGoalByUserId(ctx, userId)
+	if err != nil {
+		return 0
+	}
+	return mainGoal.WeeklyGoals[0].CurrentWeightInKg
+}
+
+func (c *ActivityController) LogWorkoutSession(ctx *gin.Context) {
+	var session models.WorkoutSession
+	if err := ctx.ShouldBindJSON(&session); err != nil {
+		ctx.Error(models.NewApiError(models.INVALID_REQUEST, "Invalid request format"))
+		return
+	}
+	if !resolveBodyUserId(ctx, &session.UserId) {
+		return
+	}
+
+	errors := utils.ValidateStruct(session)
+	if errors != nil {
+		ctx.Error(models.NewValidationError(errors))
+		return
+	}
+
+	for i := range session.Exercises {
+		exercise := &session.Exercises[i]
+		catalogueExercise, ok := models.GetExerciseById(exercise.ExerciseId)
+		if !ok {
+			ctx.Error(models.NewApiError(models.INVALID_REQUEST, "Invalid exerciseId: "+exercise.ExerciseId))
+			return
+		}
+		exercise.Name = catalogueExercise.Name
+	}
+	if session.Date.IsZero() {
+		session.Date = time.Now()
+	}
+
+	timedContext, cancel := config.GetTimedContext()
+	defer cancel()
+
+	session.EstimatedCalories = utils.EstimateWorkoutSessionCalories(session, c.getCurrentWeightInKg(timedContext, session.UserId))
+
+	err := c.ActivityRepository.CreateWorkoutSession(timedContext, &session)
+	if err != nil {
+		ctx.Error(models.NewApiError(models.INTERNAL_ERROR, "Could not log workout session").WithCause(err))
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, session)
+}
+
+func (c *ActivityController) LogActivity(ctx *gin.Context) {
+	var activityLog models.ActivityLog
+	if err := ctx.ShouldBindJSON(&activityLog); err != nil {
+		ctx.Error(models.NewApiError(models.INVALID_REQUEST, "Invalid request format"))
+		return
+	}
+	if !resolveBodyUserId(ctx, &activityLog.UserId) {
+		return
+	}
+
+	errors := utils.ValidateStruct(activityLog)
+	if errors != nil {
+		ctx.Error(models.NewValidationError(errors))
+		return
+	}
+
+	if !utils.IsValidActivityType(activityLog.ActivityType) {
+		ctx.Error(models.NewApiError(models.INVALID_REQUEST, "Invalid activityType"))
+		return
+	}
+	if activityLog.DurationMinutes == 0 && activityLog.Steps == 0 {
+		ctx.Error(models.NewApiError(models.INVALID_REQUEST, "durationMinutes or steps is required"))
+		return
+	}
+	if activityLog.Date.IsZero() {
+		activityLog.Date = time.Now()
+	}
+
+	timedContext, cancel := config.GetTimedContext()
+	defer cancel()
+
+	activityLog.EstimatedCalories = utils.EstimateActivityCalories(activityLog, c.getCurrentWeightInKg(timedContext, activityLog.UserId))
+
+	err := c.ActivityRepository.CreateActivityLog(timedContext, &activityLog)
+	if err != nil {
+		ctx.Error(models.NewApiError(models.INTERNAL_ERROR, "Could not log activity").WithCause(err))
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, activityLog)
+}
+
+func (c *ActivityController) GetActivity(ctx *gin.Context) {
+	userId, ok := ctx.GetQuery("userId")
+	if !ok {
+		ctx.Error(models.NewApiError(models.INVALID_REQUEST, "Invalid request format"))
+		return
+	}
+	mongoUserId, err := primitive.ObjectIDFromHex(userId)
+	if err != nil {
+		ctx.Error(models.NewApiError(models.INVALID_REQUEST, "Invalid request format"))
+		return
+	}
+
+	day, err := utils.ParseDay(ctx.Query("date"))
+	if err != nil {
+		ctx.Error(models.NewApiError(models.INVALID_REQUEST, "Invalid date format: must be yyyy-mm-dd"))
+		return
+	}
+
+	timedContext, cancel := config.GetTimedContext()
+	defer cancel()
+
+	user, err := c.UserRepository.GetUserProfileById(timedContext, mongoUserId)
+	if err != nil {
+		ctx.Error(models.NewApiError(models.USER_NOT_FOUND, "User not found"))
+		return
+	}
+
+	startOfDay, endOfDay := utils.GetDayRange(day)
+	sessions, err := c.ActivityRepository.GetWorkoutSessions(timedContext, mongoUserId, startOfDay, endOfDay)
+	if err != nil {
+		ctx.Error(models.NewApiError(models.INTERNAL_ERROR, "Could not get workout sessions").WithCause(err))
+		return
+	}
+	activityLogs, err := c.ActivityRepository.GetActivityLogs(timedContext, mongoUserId, startOfDay, endOfDay)
+	if err != nil {
+		ctx.Error(models.NewApiError(models.INTERNAL_ERROR, "Could not get activity logs").WithCause(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, utils.GetActivitySummary(day, sessions, activityLogs, user.GetEatBackPercentage()))
+}
+
+// WorkoutSessionSnapshot is the audit snapshot of the workout session addressed by the userId and sessionId query params
+func (c *ActivityController) WorkoutSessionSnapshot() middleware.AuditSnapshot {
+	return userLogSnapshot("sessionId", c.ActivityRepository.GetWorkoutSession)
+}
+
+// ActivityLogSnapshot is the audit snapshot of the activity log addressed by the userId and activityLogId query params
+func (c *ActivityController) ActivityLogSnapshot() middleware.AuditSnapshot {
+	return userLogSnapshot("activityLogId", c.ActivityRepository.GetActivityLog)
+}
+
+func (c *ActivityController) DeleteWorkoutSession(ctx *gin.Context) {
+	requiredFields := []string{"userId", "sessionId"}
+	values := make(map[string]string)
+
+	for _, field := range requiredFields {
+		value, ok := ctx.GetQuery(field)
+		if !ok {
+			ctx.Error(models.NewApiError(models.INVALID_REQUEST, fmt.Sprintf("Invalid request format: missing %s", field)))
+			return
+		}
+		values[field] = value
+	}
+
+	mongoUserId, err := primitive.ObjectIDFromHex(values["userId"])
+	if err != nil {
+		ctx.Error(models.NewApiError(models.INVALID_REQUEST, "Invalid userId format: must be a valid ObjectId"))
+		return
+	}
+	mongoSessionId, err := primitive.ObjectIDFromHex(values["sessionId"])
+	if err != nil {
+		ctx.Error(models.NewApiError(models.INVALID_REQUEST, "Invalid sessionId format: must be a valid ObjectId"))
+		return
+	}
+
+	timedContext, cancel := config.GetTimedContext()
+	defer cancel()
+
+	err = c.ActivityRepository.DeleteWorkoutSession(timedContext, mongoUserId, mongoSessionId)
+	if err != nil {
+		ctx.Error(models.NewApiError(models.LOG_NOT_FOUND, "Workout session not found"))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+func (c *ActivityController) DeleteActivityLog(ctx *gin.Context) {
+	requiredFields := []string{"userId", "activityLogId"}
+	values := make(map[string]string)
+
+	for _, field := range requiredFields {
+		value, ok := ctx.GetQuery(field)
+		if !ok {
+			ctx.Error(models.NewApiError(models.INVALID_REQUEST, fmt.Sprintf("Invalid request format: missing %s", field)))
+			return
+		}
+		values[field] = value
+	}
+
+	mongoUserId, err := primitive.ObjectIDFromHex(values["userId"])
+	if err != nil {
+		ctx.Error(models.NewApiError(models.INVALID_REQUEST, "Invalid userId format: must be a valid ObjectId"))
+		return
+	}
+	mongoActivityLogId, err := primitive.ObjectIDFromHex(values["activityLogId"])
+	if err != nil {
+		ctx.Error(models.NewApiError(models.INVALID_REQUEST, "Invalid activityLogId format: must be a valid ObjectId"))
+		return
+	}
+
+	timedContext, cancel := config.GetTimedContext()
+	defer cancel()
+
+	err = c.ActivityRepository.DeleteActivityLog(timedContext, mongoUserId, mongoActivityLogId)
+	if err != nil {
+		ctx.Error(models.NewApiError(models.LOG_NOT_FOUND, "Activity log not found"))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"success": true})
+}
@@ -0,0 +1,51 @@
+package controllers
+
+import (
+	"net/http"
+	"testing"
+
+	"fit-eats-api/models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// newLogTestRouter serves the handlers logging data for the userId of the body. The requests of the tests are
+// rejected before the repositories are used, so the controllers run without them
+func newLogTestRouter(callerId primitive.ObjectID, role models.Role) *gin.Engine {
+	activityController := NewActivityController(nil, nil, nil)
+	hydrationController := NewHydrationController(nil, nil)
+
+	router := newTestRouter(callerId, role)
+	router.POST("/api/logWorkoutSession", activityController.LogWorkoutSession)
+	router.POST("/api/logActivity", activityController.LogActivity)
+	router.POST("/api/logWater", hydrationController.LogWater)
+	return router
+}
+
+func TestLogHandlersRejectOtherUsers(t *testing.T) {
+	callerId, otherUserId := primitive.NewObjectID(), primitive.NewObjectID()
+	bodies := map[string]gin.H{
+		"/api/logWorkoutSession": {"userId": otherUserId, "durationMinutes": 45, "rpe": 7},
+		"/api/logActivity":       {"userId": otherUserId, "activityType": models.RUNNING, "durationMinutes": 30},
+		"/api/logWater":          {"userId": otherUserId, "amountInMl": 250},
+	}
+
+	for _, role := range []models.Role{models.ROLE_USER, models.ROLE_COACH} {
+		router := newLogTestRouter(callerId, role)
+		for target, body := range bodies {
+			t.Run(string(role)+" "+target, func(t *testing.T) {
+				expectError(t, serve(router, http.MethodPost, target, body), http.StatusForbidden, models.FORBIDDEN)
+			})
+		}
+	}
+}
+
+func TestLogWorkoutSessionRejectsUnknownExercises(t *testing.T) {
+	callerId := primitive.NewObjectID()
+	router := newLogTestRouter(callerId, models.ROLE_USER)
+
+	// Without a userId the session is logged for the caller
+	body := gin.H{"durationMinutes": 45, "exercises": []gin.H{{"exerciseId": "deadlift"}, {"exerciseId": "moon-walk"}}}
+	expectError(t, serve(router, http.MethodPost, "/api/logWorkoutSession", body), http.StatusBadRequest, models.INVALID_REQUEST)
+}
@@ -0,0 +1,234 @@
+package controllers
+
+import (
+	"fit-eats-api/config"
+	"fit-eats-api/middleware"
+	"fit-eats-api/models"
+	"fit-eats-api/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type AdminController struct {
+	AdminService *services.AdminService
+}
+
+func NewAdminController(adminService *services.AdminService) *AdminController {
+	return &AdminController{AdminService: adminService}
+}
+
+func (c *AdminController) SearchUsers(ctx *gin.Context) {
+	query, ok := ctx.GetQuery("query")
+	if !ok || query == "" {
+		ctx.Error(models.NewApiError(models.INVALID_REQUEST, "Invalid request format: missing query"))
+		return
+	}
+	limit := 20
+	if value, ok := ctx.GetQuery("limit"); ok {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > 100 {
+			ctx.Error(models.NewApiError(models.INVALID_REQUEST, "Invalid limit format: must be between 1 and 100"))
+			return
+		}
+		limit = parsed
+	}
+
+	timedContext, cancel := config.GetTimedContext()
+	defer cancel()
+
+	users, err := c.AdminService.SearchUsers(timedContext, getAdminActor(ctx), query, int64(limit))
+	if err != nil {
+		ctx.Error(models.NewApiError(models.INTERNAL_ERROR, "Could not search users").WithCause(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"users": users})
+}
+
+func (c *AdminController) SetUserRole(ctx *gin.Context) {
+	userId := ctx.PostForm("userId")
+	role := models.Role(ctx.PostForm("role"))
+	if userId == "" || role == "" {
+		ctx.Error(models.NewApiError(models.INVALID_REQUEST, "missing body params"))
+		return
+	}
+	if !models.IsValidRole(role) {
+		ctx.Error(models.NewValidationError(map[string]string{"role": "role must be one of user, coach or admin"}))
+		return
+	}
+
+	mongoUserId, err := primitive.ObjectIDFromHex(userId)
+	if err != nil {
+		ctx.Error(models.NewApiError(models.INVALID_REQUEST, "Invalid userId format: must be a valid ObjectId"))
+		return
+	}
+
+	timedContext, cancel := config.GetTimedContext()
+	defer cancel()
+
+	err = c.AdminService.SetUserRole(timedContext, getAdminActor(ctx), mongoUserId, role)
+	if !respondToAdminError(ctx, err, models.NewApiError(models.USER_NOT_FOUND, "User not found"), "Could not update user") {
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "User role updated successfully"})
+}
+
+// ResetUserPassword returns a temporary password for the user, it is not stored anywhere else
+func (c *AdminController) ResetUserPassword(ctx *gin.Context) {
+	mongoUserId, ok := getAdminObjectId(ctx, ctx.PostForm("userId"), "userId")
+	if !ok {
+		return
+	}
+
+	timedContext, cancel := config.GetTimedContext()
+	defer cancel()
+
+	password, err := c.AdminService.ResetPassword(timedContext, getAdminActor(ctx), mongoUserId)
+	if !respondToAdminError(ctx, err, models.NewApiError(models.USER_NOT_FOUND, "User not found"), "Could not reset password") {
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Password reset, the user has been logged out everywhere", "temporaryPassword": password})
+}
+
+func (c *AdminController) RevokeUserSessions(ctx *gin.Context) {
+	mongoUserId, ok := getAdminObjectId(ctx, ctx.PostForm("userId"), "userId")
+	if !ok {
+		return
+	}
+
+	timedContext, cancel := config.GetTimedContext()
+	defer cancel()
+
+	err := c.AdminService.RevokeSessions(timedContext, getAdminActor(ctx), mongoUserId)
+	if !respondToAdminError(ctx, err, models.NewApiError(models.USER_NOT_FOUND, "User not found"), "Could not revoke sessions") {
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Sessions revoked"})
+}
+
+func (c *AdminController) GetUserGoal(ctx *gin.Context) {
+	mongoUserId, ok := getAdminObjectId(ctx, ctx.Query("userId"), "userId")
+	if !ok {
+		return
+	}
+
+	timedContext, cancel := config.GetTimedContext()
+	defer cancel()
+
+	goal, err := c.AdminService.GetUserGoal(timedContext, getAdminActor(ctx), mongoUserId)
+	if !respondToAdminError(ctx, err, models.NewApiError(models.GOAL_NOT_FOUND, "Goal not found"), "Could not get goal") {
+		return
+	}
+	if goal == nil {
+		ctx.Error(models.NewApiError(models.GOAL_NOT_FOUND, "Goal not found"))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, goal)
+}
+
+func (c *AdminController) DeleteGoal(ctx *gin.Context) {
+	mongoGoalId, ok := getAdminObjectId(ctx, ctx.Query("goalId"), "goalId")
+	if !ok {
+		return
+	}
+
+	timedContext, cancel := config.GetTimedContext()
+	defer cancel()
+
+	err := c.AdminService.DeleteGoal(timedContext, getAdminActor(ctx), mongoGoalId)
+	if !respondToAdminError(ctx, err, models.NewApiError(models.GOAL_NOT_FOUND, "Goal not found"), "Could not delete goal") {
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Goal deleted"})
+}
+
+func (c *AdminController) GetMealPlans(ctx *gin.Context) {
+	mongoUserId, ok := getAdminObjectId(ctx, ctx.Query("userId"), "userId")
+	if !ok {
+		return
+	}
+
+	timedContext, cancel := config.GetTimedContext()
+	defer cancel()
+
+	mealPlans, err := c.AdminService.GetMealPlans(timedContext, getAdminActor(ctx), mongoUserId)
+	if !respondToAdminError(ctx, err, models.NewApiError(models.MEAL_PLAN_NOT_FOUND, "Meal plans not found"), "Could not get meal plans") {
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"mealPlans": mealPlans})
+}
+
+func (c *AdminController) DeleteMealPlan(ctx *gin.Context) {
+	mongoMealPlanId, ok := getAdminObjectId(ctx, ctx.Query("mealPlanId"), "mealPlanId")
+	if !ok {
+		return
+	}
+
+	timedContext, cancel := config.GetTimedContext()
+	defer cancel()
+
+	err := c.AdminService.DeleteMealPlan(timedContext, getAdminActor(ctx), mongoMealPlanId)
+	if !respondToAdminError(ctx, err, models.NewApiError(models.MEAL_PLAN_NOT_FOUND, "Meal plan not found"), "Could not delete meal plan") {
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Meal plan deleted"})
+}
+
+func (c *AdminController) RefreshMealImages(ctx *gin.Context) {
+	mongoMealPlanId, ok := getAdminObjectId(ctx, ctx.PostForm("mealPlanId"), "mealPlanId")
+	if !ok {
+		return
+	}
+
+	// Every meal is a separate image search
+	timedContext, cancel := config.GetTimedContext(120)
+	defer cancel()
+
+	meals, err := c.AdminService.RefreshMealImages(timedContext, getAdminActor(ctx), mongoMealPlanId)
+	if !respondToAdminError(ctx, err, models.NewApiError(models.MEAL_PLAN_NOT_FOUND, "Meal plan not found"), "Could not refresh meal images") {
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Meal images refreshed", "meals": meals})
+}
+
+func getAdminActor(ctx *gin.Context) models.AuditActor {
+	return models.AuditActor{ID: middleware.GetUserId(ctx), Name: middleware.GetEmail(ctx), Source: models.AUDIT_SOURCE_API}
+}
+
+func getAdminObjectId(ctx *gin.Context, value string, field string) (primitive.ObjectID, bool) {
+	if value == "" {
+		ctx.Error(models.NewApiError(models.INVALID_REQUEST, "Invalid request format: missing "+field))
+		return primitive.NilObjectID, false
+	}
+	id, err := primitive.ObjectIDFromHex(value)
+	if err != nil {
+		ctx.Error(models.NewApiError(models.INVALID_REQUEST, "Invalid "+field+" format: must be a valid ObjectId"))
+		return primitive.NilObjectID, false
+	}
+	return id, true
+}
+
+// respondToAdminError writes the error response and returns false when the admin action failed
+func respondToAdminError(ctx *gin.Context, err error, notFound *models.ApiError, failedMessage string) bool {
+	if err == nil {
+		return true
+	}
+	if err == mongo.ErrNoDocuments {
+		ctx.Error(notFound)
+		return false
+	}
+	ctx.Error(models.NewApiError(models.INTERNAL_ERROR, failedMessage).WithCause(err))
+	return false
+}
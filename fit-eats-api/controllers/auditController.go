@@ -0,0 +1,98 @@
+package controllers
+
+import (
+	"fit-eats-api/config"
+	"fit-eats-api/middleware"
+	"fit-eats-api/models"
+	"fit-eats-api/repositories"
+	"fit-eats-api/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type AuditController struct {
+	AuditRepository repositories.AuditRepository
+}
+
+func NewAuditController(auditRepository repositories.AuditRepository) *AuditController {
+	return &AuditController{AuditRepository: auditRepository}
+}
+
+// GetAuditLogs returns the history of changes made to the user's own data, by themselves, their coaches or admins
+func (c *AuditController) GetAuditLogs(ctx *gin.Context) {
+	filter, ok := getAuditLogFilter(ctx)
+	if !ok {
+		return
+	}
+	filter.UserId = middleware.GetUserId(ctx)
+	filter.ActorId = primitive.NilObjectID
+
+	c.respondWithAuditLogs(ctx, filter)
+}
+
+// GetAllAuditLogs lets admins search the audit logs of every user by userId, actorId, action and time
+func (c *AuditController) GetAllAuditLogs(ctx *gin.Context) {
+	filter, ok := getAuditLogFilter(ctx)
+	if !ok {
+		return
+	}
+
+	for field, target := range map[string]*primitive.ObjectID{"userId": &filter.UserId, "actorId": &filter.ActorId} {
+		value, ok := ctx.GetQuery(field)
+		if !ok {
+			continue
+		}
+		id, err := primitive.ObjectIDFromHex(value)
+		if err != nil {
+			ctx.Error(models.NewApiError(models.INVALID_REQUEST, "Invalid "+field+" format: must be a valid ObjectId"))
+			return
+		}
+		*target = id
+	}
+
+	c.respondWithAuditLogs(ctx, filter)
+}
+
+func (c *AuditController) respondWithAuditLogs(ctx *gin.Context, filter models.AuditLogFilter) {
+	page, pageSize, ok := getPagination(ctx)
+	if !ok {
+		return
+	}
+
+	timedContext, cancel := config.GetTimedContext()
+	defer cancel()
+
+	auditLogs, total, err := c.AuditRepository.GetAuditLogs(timedContext, filter, int64((page-1)*pageSize), int64(pageSize))
+	if err != nil {
+		ctx.Error(models.NewApiError(models.INTERNAL_ERROR, "Could not get audit logs").WithCause(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"auditLogs": auditLogs, "page": page, "pageSize": pageSize, "total": total})
+}
+
+// getAuditLogFilter reads the action and the from and to days, to is inclusive
+func getAuditLogFilter(ctx *gin.Context) (models.AuditLogFilter, bool) {
+	filter := models.AuditLogFilter{Action: models.AuditAction(ctx.Query("action"))}
+
+	if value, ok := ctx.GetQuery("from"); ok {
+		from, err := utils.ParseDay(value)
+		if err != nil {
+			ctx.Error(models.NewApiError(models.INVALID_REQUEST, "Invalid from format: must be yyyy-mm-dd"))
+			return filter, false
+		}
+		filter.From, _ = utils.GetDayRange(from)
+	}
+	if value, ok := ctx.GetQuery("to"); ok {
+		to, err := utils.ParseDay(value)
+		if err != nil {
+			ctx.Error(models.NewApiError(models.INVALID_REQUEST, "Invalid to format: must be yyyy-mm-dd"))
+			return filter, false
+		}
+		_, filter.To = utils.GetDayRange(to)
+	}
+
+	return filter, true
+}
@@ -0,0 +1,136 @@
+package controllers
+
+import (
+	"net/http"
+	"testing"
+
+	"fit-eats-api/middleware"
+	"fit-eats-api/models"
+	"fit-eats-api/repositories"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// runAuditTest answers the count and the find of the audit logs with the mocked database
+func runAuditTest(t *testing.T, name string, test func(mt *mtest.T, controller *AuditController)) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	mt.Run(name, func(mt *mtest.T) {
+		test(mt, NewAuditController(repositories.NewMongoAuditRepository(mt.DB)))
+	})
+}
+
+func addAuditLogResponses(mt *mtest.T, auditLogs ...models.AuditLog) {
+	batch := make([]bson.D, len(auditLogs))
+	for i, auditLog := range auditLogs {
+		data, err := bson.Marshal(auditLog)
+		if err != nil {
+			mt.Fatal(err)
+		}
+		if err := bson.Unmarshal(data, &batch[i]); err != nil {
+			mt.Fatal(err)
+		}
+	}
+	mt.AddMockResponses(
+		mtest.CreateCursorResponse(0, "fiteats.auditLogs", mtest.FirstBatch, bson.D{{Key: "n", Value: int32(len(auditLogs))}}),
+		mtest.CreateCursorResponse(0, "fiteats.auditLogs", mtest.FirstBatch, batch...),
+	)
+}
+
+// expectAuditLogFilter checks the filter of the find, the count runs before it with the same filter
+func expectAuditLogFilter(mt *mtest.T, expected bson.M) {
+	mt.Helper()
+	var filter bson.Raw
+	for event := mt.GetStartedEvent(); event != nil; event = mt.GetStartedEvent() {
+		if event.CommandName == "find" {
+			filter = event.Command.Lookup("filter").Document()
+		}
+	}
+	if filter == nil {
+		mt.Fatal("expected the audit logs to be searched")
+	}
+
+	var actual bson.M
+	if err := bson.Unmarshal(filter, &actual); err != nil {
+		mt.Fatal(err)
+	}
+	if len(actual) != len(expected) {
+		mt.Fatalf("expected the filter %v, got %v", expected, actual)
+	}
+	for key, value := range expected {
+		if actual[key] != value {
+			mt.Fatalf("expected %s to be %v in the filter %v", key, value, actual)
+		}
+	}
+}
+
+func TestGetAuditLogsOnlyOwnHistory(t *testing.T) {
+	callerId := primitive.NewObjectID()
+	otherUserId := primitive.NewObjectID()
+
+	runAuditTest(t, "own history", func(mt *mtest.T, controller *AuditController) {
+		router := newTestRouter(callerId, models.ROLE_USER)
+		router.GET("/api/getAuditLogs", controller.GetAuditLogs)
+
+		addAuditLogResponses(mt, models.AuditLog{ID: primitive.NewObjectID(), UserId: callerId, Action: models.AUDIT_PROFILE_UPDATED})
+		recorder := serve(router, http.MethodGet, "/api/getAuditLogs?userId="+otherUserId.Hex()+"&actorId="+otherUserId.Hex()+"&action=Profile%20updated", nil)
+		expectStatus(mt.T, recorder, http.StatusOK)
+
+		// The userId and actorId of the query are ignored, only the history of the caller is searched
+		expectAuditLogFilter(mt, bson.M{"userId": callerId, "action": string(models.AUDIT_PROFILE_UPDATED)})
+
+		var response struct {
+			AuditLogs []models.AuditLog `json:"auditLogs"`
+			Total     int64             `json:"total"`
+		}
+		decodeResponse(mt.T, recorder, &response)
+		if response.Total != 1 || len(response.AuditLogs) != 1 || response.AuditLogs[0].UserId != callerId {
+			mt.Fatalf("expected the audit log of the caller, got %+v", response)
+		}
+	})
+}
+
+func TestGetAllAuditLogsFilters(t *testing.T) {
+	adminId := primitive.NewObjectID()
+	userId := primitive.NewObjectID()
+	actorId := primitive.NewObjectID()
+
+	runAuditTest(t, "admin search", func(mt *mtest.T, controller *AuditController) {
+		router := newTestRouter(adminId, models.ROLE_ADMIN)
+		router.GET("/api/admin/getAuditLogs", middleware.RequireRole(models.ROLE_ADMIN), controller.GetAllAuditLogs)
+
+		addAuditLogResponses(mt)
+		recorder := serve(router, http.MethodGet, "/api/admin/getAuditLogs?userId="+userId.Hex()+"&actorId="+actorId.Hex(), nil)
+		expectStatus(mt.T, recorder, http.StatusOK)
+		expectAuditLogFilter(mt, bson.M{"userId": userId, "actor.id": actorId})
+
+		recorder = serve(router, http.MethodGet, "/api/admin/getAuditLogs?actorId=coach", nil)
+		expectError(mt.T, recorder, http.StatusBadRequest, models.INVALID_REQUEST)
+	})
+
+	runAuditTest(t, "not an admin", func(mt *mtest.T, controller *AuditController) {
+		router := newTestRouter(userId, models.ROLE_USER)
+		router.GET("/api/admin/getAuditLogs", middleware.RequireRole(models.ROLE_ADMIN), controller.GetAllAuditLogs)
+
+		recorder := serve(router, http.MethodGet, "/api/admin/getAuditLogs?userId="+actorId.Hex(), nil)
+		expectError(mt.T, recorder, http.StatusForbidden, models.FORBIDDEN)
+		if event := mt.GetStartedEvent(); event != nil {
+			mt.Fatalf("expected no query, got %s", event.CommandName)
+		}
+	})
+}
+
+func TestGetAuditLogsInvalidDays(t *testing.T) {
+	callerId := primitive.NewObjectID()
+
+	runAuditTest(t, "invalid days", func(mt *mtest.T, controller *AuditController) {
+		router := newTestRouter(callerId, models.ROLE_USER)
+		router.GET("/api/getAuditLogs", controller.GetAuditLogs)
+
+		for _, query := range []string{"?from=19-10-2026", "?to=yesterday", "?from=2026-10-01&to=2026-13-01"} {
+			recorder := serve(router, http.MethodGet, "/api/getAuditLogs"+query, nil)
+			expectError(mt.T, recorder, http.StatusBadRequest, models.INVALID_REQUEST)
+		}
+	})
+}
@@ -0,0 +1,316 @@
+package controllers
+
+import (
+	"context"
+	"fit-eats-api/config"
+	"fit-eats-api/middleware"
+	"fit-eats-api/models"
+	"fit-eats-api/repositories"
+	"fit-eats-api/utils"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type CoachController struct {
+	UserRepository      repositories.UserRepository
+	CoachLinkRepository *repositories.CoachLinkRepository
+	Mailer              utils.Mailer
+}
+
+func NewCoachController(userRepository repositories.UserRepository, coachLinkRepository *repositories.CoachLinkRepository, mailer utils.Mailer) *CoachController {
+	return &CoachController{UserRepository: userRepository, CoachLinkRepository: coachLinkRepository, Mailer: mailer}
+}
+
+// InviteClient sends a coaching invitation to the user with the email, the coach gets access once it is accepted
+func (c *CoachController) InviteClient(ctx *gin.Context) {
+	email := ctx.PostForm("email")
+	if email == "" {
+		ctx.Error(models.NewApiError(models.INVALID_REQUEST, "missing body params"))
+		return
+	}
+
+	// Mail servers can be slow to answer
+	timedContext, cancel := config.GetTimedContext(30)
+	defer cancel()
+
+	coachId := middleware.GetUserId(ctx)
+	client, err := c.UserRepository.GetUserProfileByEmailId(timedContext, email)
+	if err != nil {
+		ctx.Error(models.NewApiError(models.USER_NOT_FOUND, "User not found"))
+		return
+	}
+	if client.ID == coachId {
+		ctx.Error(models.NewApiError(models.INVALID_REQUEST, "You cannot coach yourself"))
+		return
+	}
+
+	existing, err := c.CoachLinkRepository.GetOpenCoachLink(timedContext, coachId, client.ID)
+	if err != nil {
+		ctx.Error(models.NewApiError(models.INTERNAL_ERROR, "Could not get coach links").WithCause(err))
+		return
+	}
+	if existing != nil {
+		ctx.Error(models.NewApiError(models.ALREADY_EXISTS, "User is already invited or your client"))
+		return
+	}
+
+	coachLink := models.CoachLink{CoachId: coachId, ClientId: client.ID, InvitedBy: coachId, Status: models.COACH_LINK_PENDING, InvitedAt: time.Now()}
+	err = c.CoachLinkRepository.CreateCoachLink(timedContext, &coachLink)
+	if err != nil {
+		ctx.Error(models.NewApiError(models.INTERNAL_ERROR, "Could not create invitation").WithCause(err))
+		return
+	}
+
+	coach, err := c.UserRepository.GetUserProfileById(timedContext, coachId)
+	if err == nil {
+		body := fmt.Sprintf("Hi %s,\n\n%s invited you to be their coaching client on FitEats. As your coach they will be able to view and edit your goals and meal plans.\n\nOpen the app to accept or decline the invitation.",
+			client.Name, coach.Name)
+		if err := c.Mailer.Send(timedContext, client.Email, "You have a coaching invitation on FitEats", body); err != nil {
+			log.Printf("Could not send coaching invitation to %s: %v", client.Email, err)
+		}
+	}
+
+	ctx.JSON(http.StatusCreated, coachLink)
+}
+
+// InviteCoach asks the coach with the email to coach the user, the coach gets access once they accept it
+func (c *CoachController) InviteCoach(ctx *gin.Context) {
+	email := ctx.PostForm("email")
+	if email == "" {
+		ctx.Error(models.NewApiError(models.INVALID_REQUEST, "missing body params"))
+		return
+	}
+
+	// Mail servers can be slow to answer
+	timedContext, cancel := config.GetTimedContext(30)
+	defer cancel()
+
+	clientId := middleware.GetUserId(ctx)
+	coach, err := c.UserRepository.GetUserProfileByEmailId(timedContext, email)
+	if err != nil || coach.GetRole() != models.ROLE_COACH {
+		ctx.Error(models.NewApiError(models.USER_NOT_FOUND, "Coach not found"))
+		return
+	}
+	if coach.ID == clientId {
+		ctx.Error(models.NewApiError(models.INVALID_REQUEST, "You cannot coach yourself"))
+		return
+	}
+
+	existing, err := c.CoachLinkRepository.GetOpenCoachLink(timedContext, coach.ID, clientId)
+	if err != nil {
+		ctx.Error(models.NewApiError(models.INTERNAL_ERROR, "Could not get coach links").WithCause(err))
+		return
+	}
+	if existing != nil {
+		ctx.Error(models.NewApiError(models.ALREADY_EXISTS, "Coach is already invited or your coach"))
+		return
+	}
+
+	coachLink := models.CoachLink{CoachId: coach.ID, ClientId: clientId, InvitedBy: clientId, Status: models.COACH_LINK_PENDING, InvitedAt: time.Now()}
+	err = c.CoachLinkRepository.CreateCoachLink(timedContext, &coachLink)
+	if err != nil {
+		ctx.Error(models.NewApiError(models.INTERNAL_ERROR, "Could not create invitation").WithCause(err))
+		return
+	}
+
+	client, err := c.UserRepository.GetUserProfileById(timedContext, clientId)
+	if err == nil {
+		body := fmt.Sprintf("Hi %s,\n\n%s asked you to be their coach on FitEats. As their coach you will be able to view and edit their goals and meal plans.\n\nOpen the app to accept or decline the invitation.",
+			coach.Name, client.Name)
+		if err := c.Mailer.Send(timedContext, coach.Email, "You have a coaching request on FitEats", body); err != nil {
+			log.Printf("Could not send coaching request to %s: %v", coach.Email, err)
+		}
+	}
+
+	ctx.JSON(http.StatusCreated, coachLink)
+}
+
+// GetCoachInvitations returns the pending invitations the user can respond to, sent by coaches or by clients
+func (c *CoachController) GetCoachInvitations(ctx *gin.Context) {
+	timedContext, cancel := config.GetTimedContext()
+	defer cancel()
+
+	coachLinks, err := c.CoachLinkRepository.GetReceivedInvitations(timedContext, middleware.GetUserId(ctx))
+	if err != nil {
+		ctx.Error(models.NewApiError(models.INTERNAL_ERROR, "Could not get invitations").WithCause(err))
+		return
+	}
+
+	c.fillNames(timedContext, coachLinks)
+	ctx.JSON(http.StatusOK, gin.H{"invitations": coachLinks})
+}
+
+func (c *CoachController) RespondToCoachInvitation(ctx *gin.Context) {
+	coachLinkId := ctx.PostForm("coachLinkId")
+	accept, err := strconv.ParseBool(ctx.PostForm("accept"))
+	if coachLinkId == "" || err != nil {
+		ctx.Error(models.NewApiError(models.INVALID_REQUEST, "missing body params"))
+		return
+	}
+
+	mongoCoachLinkId, err := primitive.ObjectIDFromHex(coachLinkId)
+	if err != nil {
+		ctx.Error(models.NewApiError(models.INVALID_REQUEST, "Invalid coachLinkId format: must be a valid ObjectId"))
+		return
+	}
+
+	status := models.COACH_LINK_DECLINED
+	if accept {
+		status = models.COACH_LINK_ACTIVE
+	}
+
+	timedContext, cancel := config.GetTimedContext()
+	defer cancel()
+
+	err = c.CoachLinkRepository.RespondToInvitation(timedContext, mongoCoachLinkId, middleware.GetUserId(ctx), status)
+	if err == mongo.ErrNoDocuments {
+		ctx.Error(models.NewApiError(models.COACH_LINK_NOT_FOUND, "Invitation not found"))
+		return
+	}
+	if err != nil {
+		ctx.Error(models.NewApiError(models.INTERNAL_ERROR, "Could not respond to invitation").WithCause(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Invitation " + string(status)})
+}
+
+func (c *CoachController) GetClients(ctx *gin.Context) {
+	timedContext, cancel := config.GetTimedContext()
+	defer cancel()
+
+	statuses := []models.CoachLinkStatus{models.COACH_LINK_PENDING, models.COACH_LINK_ACTIVE}
+	coachLinks, err := c.CoachLinkRepository.GetCoachLinksByCoachId(timedContext, middleware.GetUserId(ctx), statuses)
+	if err != nil {
+		ctx.Error(models.NewApiError(models.INTERNAL_ERROR, "Could not get clients").WithCause(err))
+		return
+	}
+
+	c.fillNames(timedContext, coachLinks)
+	ctx.JSON(http.StatusOK, gin.H{"clients": coachLinks})
+}
+
+func (c *CoachController) GetCoaches(ctx *gin.Context) {
+	timedContext, cancel := config.GetTimedContext()
+	defer cancel()
+
+	coachLinks, err := c.CoachLinkRepository.GetCoachLinksByClientId(timedContext, middleware.GetUserId(ctx), []models.CoachLinkStatus{models.COACH_LINK_ACTIVE})
+	if err != nil {
+		ctx.Error(models.NewApiError(models.INTERNAL_ERROR, "Could not get coaches").WithCause(err))
+		return
+	}
+
+	c.fillNames(timedContext, coachLinks)
+	ctx.JSON(http.StatusOK, gin.H{"coaches": coachLinks})
+}
+
+// GetCoachOverview returns the active clients of the coach with their adherence, progress and alerts
+func (c *CoachController) GetCoachOverview(ctx *gin.Context) {
+	page, pageSize, ok := getPagination(ctx)
+	if !ok {
+		return
+	}
+
+	sortBy := ctx.DefaultQuery("sortBy", "name")
+	sortField, ok := models.CoachOverviewSortFields[sortBy]
+	if !ok {
+		ctx.Error(models.NewApiError(models.INVALID_REQUEST, "Invalid sortBy: must be one of name, adherence, weightToGoal or daysSinceWeeklyGoal"))
+		return
+	}
+	order := ctx.DefaultQuery("order", "asc")
+	if order != "asc" && order != "desc" {
+		ctx.Error(models.NewApiError(models.INVALID_REQUEST, "Invalid order: must be asc or desc"))
+		return
+	}
+	sortOrder := 1
+	if order == "desc" {
+		sortOrder = -1
+	}
+	if sortBy == "daysSinceWeeklyGoal" {
+		sortOrder = -sortOrder
+	}
+
+	timedContext, cancel := config.GetTimedContext()
+	defer cancel()
+
+	now := time.Now()
+	startOfToday, endOfToday := utils.GetDayRange(now)
+	since := startOfToday.AddDate(0, 0, -(utils.COACH_OVERVIEW_DAYS - 1))
+
+	records, total, err := c.CoachLinkRepository.GetClientOverviews(timedContext, middleware.GetUserId(ctx), since, endOfToday,
+		sortField, sortOrder, (page-1)*pageSize, pageSize)
+	if err != nil {
+		ctx.Error(models.NewApiError(models.INTERNAL_ERROR, "Could not get clients").WithCause(err))
+		return
+	}
+
+	clients := make([]models.ClientOverview, 0, len(records))
+	for _, record := range records {
+		clients = append(clients, utils.GetClientOverview(record, now))
+	}
+
+	ctx.JSON(http.StatusOK, models.CoachOverview{Clients: clients, Page: page, PageSize: pageSize, Total: total})
+}
+
+// EndCoachLink lets either side end the coaching or withdraw a pending invitation
+func (c *CoachController) EndCoachLink(ctx *gin.Context) {
+	coachLinkId, ok := ctx.GetQuery("coachLinkId")
+	if !ok {
+		ctx.Error(models.NewApiError(models.INVALID_REQUEST, "Invalid request format: missing coachLinkId"))
+		return
+	}
+
+	mongoCoachLinkId, err := primitive.ObjectIDFromHex(coachLinkId)
+	if err != nil {
+		ctx.Error(models.NewApiError(models.INVALID_REQUEST, "Invalid coachLinkId format: must be a valid ObjectId"))
+		return
+	}
+
+	timedContext, cancel := config.GetTimedContext()
+	defer cancel()
+
+	err = c.CoachLinkRepository.EndCoachLink(timedContext, mongoCoachLinkId, middleware.GetUserId(ctx))
+	if err == mongo.ErrNoDocuments {
+		ctx.Error(models.NewApiError(models.COACH_LINK_NOT_FOUND, "Coach link not found"))
+		return
+	}
+	if err != nil {
+		ctx.Error(models.NewApiError(models.INTERNAL_ERROR, "Could not end coaching").WithCause(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Coaching ended"})
+}
+
+// fillNames adds the names of both sides, links to deleted users are returned without names
+func (c *CoachController) fillNames(timedContext context.Context, coachLinks []models.CoachLink) {
+	users := map[primitive.ObjectID]*models.User{}
+	getUser := func(userId primitive.ObjectID) *models.User {
+		if user, ok := users[userId]; ok {
+			return user
+		}
+		user, err := c.UserRepository.GetUserProfileById(timedContext, userId)
+		if err != nil {
+			user = nil
+		}
+		users[userId] = user
+		return user
+	}
+
+	for i := range coachLinks {
+		if coach := getUser(coachLinks[i].CoachId); coach != nil {
+			coachLinks[i].CoachName = coach.Name
+		}
+		if client := getUser(coachLinks[i].ClientId); client != nil {
+			coachLinks[i].ClientName = client.Name
+			coachLinks[i].ClientEmail = client.Email
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"fit-eats-api/middleware"
+	"fit-eats-api/models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// newTestRouter answers every request as the caller, with the error middleware of the api
+func newTestRouter(callerId primitive.ObjectID, role models.Role) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middleware.ErrorMiddleware())
+	router.Use(func(c *gin.Context) {
+		c.Set(middleware.USER_ID_KEY, callerId)
+		c.Set(middleware.EMAIL_KEY, "caller@fiteats.test")
+		c.Set(middleware.ROLE_KEY, role)
+		c.Next()
+	})
+	return router
+}
+
+func serve(router *gin.Engine, method string, target string, body any) *httptest.ResponseRecorder {
+	var reader *strings.Reader
+	if body == nil {
+		reader = strings.NewReader("")
+	} else {
+		data, _ := json.Marshal(body)
+		reader = strings.NewReader(string(data))
+	}
+
+	request := httptest.NewRequest(method, target, reader)
+	if body != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func expectStatus(t *testing.T, recorder *httptest.ResponseRecorder, status int) {
+	t.Helper()
+	if recorder.Code != status {
+		t.Fatalf("expected status %d, got %d %s", status, recorder.Code, recorder.Body)
+	}
+}
+
+func expectError(t *testing.T, recorder *httptest.ResponseRecorder, status int, code models.ErrorCode) {
+	t.Helper()
+	expectStatus(t, recorder, status)
+
+	var response models.ErrorResponse
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		t.Fatalf("could not read the error response %s: %v", recorder.Body, err)
+	}
+	if response.Code != code {
+		t.Fatalf("expected error code %s, got %s", code, response.Code)
+	}
+}
+
+func decodeResponse(t *testing.T, recorder *httptest.ResponseRecorder, value any) {
+	t.Helper()
+	if err := json.Unmarshal(recorder.Body.Bytes(), value); err != nil {
+		t.Fatalf("could not read the response %s: %v", recorder.Body, err)
+	}
+}
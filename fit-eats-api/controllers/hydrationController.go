@@ -0,0 +1,190 @@
+package controllers
+
+import (
+	"fit-eats-api/config"
+	"fit-eats-api/middleware"
+	"fit-eats-api/models"
+	"fit-eats-api/repositories"
+	"fit-eats-api/utils"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type HydrationController struct {
+	UserGoalRepository  repositories.UserGoalRepository
+	HydrationRepository *repositories.HydrationRepository
+}
+
+func NewHydrationController(userGoalRepository repositories.UserGoalRepository, hydrationRepository *repositories.HydrationRepository) *HydrationController {
+	return &HydrationController{UserGoalRepository: userGoalRepository, HydrationRepository: hydrationRepository}
+}
+
+func (c *HydrationController) LogWater(ctx *gin.Context) {
+	var hydrationLog models.HydrationLog
+	if err := ctx.ShouldBindJSON(&hydrationLog); err != nil {
+		ctx.Error(models.NewApiError(models.INVALID_REQUEST, "Invalid request format"))
+		return
+	}
+	if !resolveBodyUserId(ctx, &hydrationLog.UserId) {
+		return
+	}
+
+	errors := utils.ValidateStruct(hydrationLog)
+	if errors != nil {
+		ctx.Error(models.NewValidationError(errors))
+		return
+	}
+
+	if hydrationLog.BeverageType == "" {
+		hydrationLog.BeverageType = models.WATER
+	}
+	if !models.IsValidBeverageType(hydrationLog.BeverageType) {
+		ctx.Error(models.NewApiError(models.INVALID_REQUEST, "Invalid beverageType"))
+		return
+	}
+	if hydrationLog.LoggedAt.IsZero() {
+		hydrationLog.LoggedAt = time.Now()
+	}
+
+	timedContext, cancel := config.GetTimedContext()
+	defer cancel()
+
+	err := c.HydrationRepository.CreateHydrationLog(timedContext, &hydrationLog)
+	if err != nil {
+		ctx.Error(models.NewApiError(models.INTERNAL_ERROR, "Could not log water").WithCause(err))
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, hydrationLog)
+}
+
+func (c *HydrationController) QuickAddWater(ctx *gin.Context) {
+	requiredFields := []string{"userId", "preset"}
+	values := make(map[string]string)
+
+	for _, field := range requiredFields {
+		value, ok := ctx.GetQuery(field)
+		if !ok {
+			ctx.Error(models.NewApiError(models.INVALID_REQUEST, fmt.Sprintf("Invalid request format: missing %s", field)))
+			return
+		}
+		values[field] = value
+	}
+
+	mongoUserId, err := primitive.ObjectIDFromHex(values["userId"])
+	if err != nil {
+		ctx.Error(models.NewApiError(models.INVALID_REQUEST, "Invalid userId format: must be a valid ObjectId"))
+		return
+	}
+
+	amountInMl, ok := models.HydrationQuickAddPresets[values["preset"]]
+	if !ok {
+		ctx.Error(models.NewApiError(models.INVALID_REQUEST, "Invalid preset").WithDetails(map[string]any{"presets": models.HydrationQuickAddPresets}))
+		return
+	}
+
+	beverageType := models.BeverageType(ctx.DefaultQuery("beverageType", string(models.WATER)))
+	if !models.IsValidBeverageType(beverageType) {
+		ctx.Error(models.NewApiError(models.INVALID_REQUEST, "Invalid beverageType"))
+		return
+	}
+
+	timedContext, cancel := config.GetTimedContext()
+	defer cancel()
+
+	hydrationLog := models.HydrationLog{
+		UserId:       mongoUserId,
+		AmountInMl:   amountInMl,
+		BeverageType: beverageType,
+		LoggedAt:     time.Now(),
+	}
+	err = c.HydrationRepository.CreateHydrationLog(timedContext, &hydrationLog)
+	if err != nil {
+		ctx.Error(models.NewApiError(models.INTERNAL_ERROR, "Could not log water").WithCause(err))
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, hydrationLog)
+}
+
+func (c *HydrationController) GetHydration(ctx *gin.Context) {
+	userId, ok := ctx.GetQuery("userId")
+	if !ok {
+		ctx.Error(models.NewApiError(models.INVALID_REQUEST, "Invalid request format"))
+		return
+	}
+	mongoUserId, err := primitive.ObjectIDFromHex(userId)
+	if err != nil {
+		ctx.Error(models.NewApiError(models.INVALID_REQUEST, "Invalid request format"))
+		return
+	}
+
+	day, err := utils.ParseDay(ctx.Query("date"))
+	if err != nil {
+		ctx.Error(models.NewApiError(models.INVALID_REQUEST, "Invalid date format: must be yyyy-mm-dd"))
+		return
+	}
+
+	timedContext, cancel := config.GetTimedContext()
+	defer cancel()
+
+	var weeklyGoal *models.WeeklyGoal
+	mainGoal, err := c.UserGoalRepository.GetUserActiveGoalByUserId(timedContext, mongoUserId)
+	if err == nil {
+		weeklyGoal = &mainGoal.WeeklyGoals[0]
+	}
+
+	startOfDay, endOfDay := utils.GetDayRange(day)
+	logs, err := c.HydrationRepository.GetHydrationLogs(timedContext, mongoUserId, startOfDay, endOfDay)
+	if err != nil {
+		ctx.Error(models.NewApiError(models.INTERNAL_ERROR, "Could not get hydration logs").WithCause(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, utils.GetHydrationSummary(day, utils.GetDailyHydrationTargetInMl(weeklyGoal), logs))
+}
+
+// WaterLogSnapshot is the audit snapshot of the hydration log addressed by the userId and logId query params
+func (c *HydrationController) WaterLogSnapshot() middleware.AuditSnapshot {
+	return userLogSnapshot("logId", c.HydrationRepository.GetHydrationLog)
+}
+
+func (c *HydrationController) DeleteWaterLog(ctx *gin.Context) {
+	requiredFields := []string{"userId", "logId"}
+	values := make(map[string]string)
+
+	for _, field := range requiredFields {
+		value, ok := ctx.GetQuery(field)
+		if !ok {
+			ctx.Error(models.NewApiError(models.INVALID_REQUEST, fmt.Sprintf("Invalid request format: missing %s", field)))
+			return
+		}
+		values[field] = value
+	}
+
+	mongoUserId, err := primitive.ObjectIDFromHex(values["userId"])
+	if err != nil {
+		ctx.Error(models.NewApiError(models.INVALID_REQUEST, "Invalid userId format: must be a valid ObjectId"))
+		return
+	}
+	mongoLogId, err := primitive.ObjectIDFromHex(values["logId"])
+	if err != nil {
+		ctx.Error(models.NewApiError(models.INVALID_REQUEST, "Invalid logId format: must be a valid ObjectId"))
+		return
+	}
+
+	timedContext, cancel := config.GetTimedContext()
+	defer cancel()
+
+	err = c.HydrationRepository.DeleteHydrationLog(timedContext, mongoUserId, mongoLogId)
+	if err != nil {
+		ctx.Error(models.NewApiError(models.LOG_NOT_FOUND, "Hydration log not found"))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"success": true})
+}
@@ -1,391 +1,359 @@
 package controllers
 
 import (
-	"encoding/json"
+	"context"
 	"fit-eats-api/config"
-	"fit-eats-api/repositories"
-	"fit-eats-api/utils"
-	"fmt"
+	"fit-eats-api/middleware"
+	"fit-eats-api/models"
+	"fit-eats-api/services"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
-	"github.com/google/generative-ai-go/genai"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type MealController struct {
-	UserRepository     *repositories.UserRepository
-	UserGoalRepository *repositories.UserGoalRepository
-	UserMealRepository *repositories.MealRepository
+	MealPlanService *services.MealPlanService
+	GoalService     *services.GoalService
+	UserAccess      *middleware.UserAccess
 }
 
-func NewMealController(userRepository *repositories.UserRepository, userGoalRepository *repositories.UserGoalRepository, userMealRepository *repositories.MealRepository) *MealController {
-	return &MealController{UserRepository: userRepository, UserGoalRepository: userGoalRepository, UserMealRepository: userMealRepository}
+func NewMealController(mealPlanService *services.MealPlanService, goalService *services.GoalService, userAccess *middleware.UserAccess) *MealController {
+	return &MealController{MealPlanService: mealPlanService, GoalService: goalService, UserAccess: userAccess}
 }
 
-func (c *MealController) GetWeeklyMealPlan(ctx *gin.Context) {
-	requiredFields := []string{"userId", "mainGoalId", "weeklyGoalId"}
-	values := make(map[string]string)
-
-	for _, field := range requiredFields {
-		value, ok := ctx.GetQuery(field)
-		if !ok {
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid request format: missing %s", field)})
-			return
-		}
-		values[field] = value
+// getGoalOwnerId returns the owner of the main goal addressed by a v1 route, writing the error response
+// when it does not exist or the caller may not access it
+func (c *MealController) getGoalOwnerId(ctx *gin.Context, mainGoalId primitive.ObjectID) (primitive.ObjectID, bool) {
+	timedContext, cancel := config.GetTimedContext()
+	defer cancel()
+
+	ownerId, err := c.GoalService.GetGoalOwnerId(timedContext, mainGoalId)
+	if err != nil {
+		ctx.Error(err)
+		return primitive.NilObjectID, false
+	}
+	if !c.UserAccess.CanAccess(ctx, ownerId, true) {
+		ctx.Error(models.NewApiError(models.FORBIDDEN, "You do not have access to this user"))
+		return primitive.NilObjectID, false
 	}
+	return ownerId, true
+}
 
-	mongoUserIdStr := values["userId"]
-	mainGoalIdStr := values["mainGoalId"]
-	weeklyGoalIdStr := values["weeklyGoalId"]
+// getWeeklyMealPlan returns the meal plan of the weekly goal, writing the error response when there is none
+func (c *MealController) getWeeklyMealPlan(ctx *gin.Context, userId primitive.ObjectID, mainGoalId primitive.ObjectID, weeklyGoalId primitive.ObjectID) (*models.MealPlan, bool) {
+	timedContext, cancel := config.GetTimedContext()
+	defer cancel()
 
-	mongoUserId, err := primitive.ObjectIDFromHex(mongoUserIdStr)
+	mealPlan, err := c.MealPlanService.GetWeeklyMealPlan(timedContext, userId, mainGoalId, weeklyGoalId)
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid userId format: must be a valid ObjectId"})
-		return
+		ctx.Error(err)
+		return nil, false
 	}
-	mongoMainGoalId, err := primitive.ObjectIDFromHex(mainGoalIdStr)
-	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid mainGoalId format: must be a valid ObjectId"})
+	return mealPlan, true
+}
+
+func (c *MealController) GetWeeklyMealPlan(ctx *gin.Context) {
+	ids, ok := getQueryIds(ctx, "userId", "mainGoalId", "weeklyGoalId")
+	if !ok {
 		return
 	}
-	mongoWeeklyGoalId, err := primitive.ObjectIDFromHex(weeklyGoalIdStr)
-	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid weeklyGoalId format: must be a valid ObjectId"})
+
+	mealPlan, ok := c.getWeeklyMealPlan(ctx, ids[0], ids[1], ids[2])
+	if !ok {
 		return
 	}
+	ctx.JSON(http.StatusOK, mealPlan)
+}
 
-	timedContext, cancel := config.GetTimedContext()
-	defer cancel()
+// GetWeekMealPlan is the v1 meal plan of a weekly goal
+func (c *MealController) GetWeekMealPlan(ctx *gin.Context) {
+	ids, ok := getPathIds(ctx, "goalId", "weeklyGoalId")
+	if !ok {
+		return
+	}
+	ownerId, ok := c.getGoalOwnerId(ctx, ids[0])
+	if !ok {
+		return
+	}
 
-	mealPlan, err1 := c.UserMealRepository.GetWeeklyMealPlan(timedContext, mongoUserId, mongoMainGoalId, mongoWeeklyGoalId)
-	if err1 != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Meal Plan is not yet created"})
+	mealPlan, ok := c.getWeeklyMealPlan(ctx, ownerId, ids[0], ids[1])
+	if !ok {
 		return
 	}
 	ctx.JSON(http.StatusOK, mealPlan)
 }
 
-func (c *MealController) CreateWeeklyMealPlan(ctx *gin.Context) {
-	requiredFields := []string{"userId", "mainGoalId", "weeklyGoalId"}
-	values := make(map[string]string)
-
-	for _, field := range requiredFields {
-		value, ok := ctx.GetQuery(field)
-		if !ok {
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid request format: missing %s", field)})
-			return
-		}
-		values[field] = value
+func (c *MealController) GetMealPlan(ctx *gin.Context) {
+	ids, ok := getPathIds(ctx, "mealPlanId")
+	if !ok {
+		return
 	}
 
-	mongoUserIdStr := values["userId"]
-	mainGoalIdStr := values["mainGoalId"]
-	weeklyGoalIdStr := values["weeklyGoalId"]
+	timedContext, cancel := config.GetTimedContext()
+	defer cancel()
 
-	mongoUserId, err := primitive.ObjectIDFromHex(mongoUserIdStr)
-	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid userId format: must be a valid ObjectId"})
-		return
-	}
-	mongoMainGoalId, err := primitive.ObjectIDFromHex(mainGoalIdStr)
+	mealPlan, err := c.MealPlanService.GetMealPlan(timedContext, ids[0])
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid mainGoalId format: must be a valid ObjectId"})
+		ctx.Error(err)
 		return
 	}
-	mongoWeeklyGoalId, err := primitive.ObjectIDFromHex(weeklyGoalIdStr)
-	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid weeklyGoalId format: must be a valid ObjectId"})
+	if !c.UserAccess.CanAccess(ctx, mealPlan.UserId, true) {
+		ctx.Error(models.NewApiError(models.FORBIDDEN, "You do not have access to this user"))
 		return
 	}
 
+	ctx.JSON(http.StatusOK, mealPlan)
+}
+
+// createWeeklyMealPlan generates and stores the meal plan of the weekly goal, writing the error response when it fails
+func (c *MealController) createWeeklyMealPlan(ctx *gin.Context, mongoUserId primitive.ObjectID, mongoMainGoalId primitive.ObjectID, mongoWeeklyGoalId primitive.ObjectID) (*models.MealPlan, bool) {
 	//300 seconds for llm to respond
 	timedContext, cancel := config.GetTimedContext(300)
 	defer cancel()
 
-	user, err := c.UserRepository.GetUserProfileById(timedContext, mongoUserId)
+	// The userId was authorized by the route, the goal must belong to the same user
+	mealPlan, err := c.MealPlanService.CreateWeeklyMealPlan(timedContext, mongoUserId, mongoMainGoalId, mongoWeeklyGoalId, ctx.Query("prompt"))
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "User not found"})
-		return
+		ctx.Error(err)
+		return nil, false
 	}
-	if !user.IsProfileComplete() {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Profile incomplete"})
+	return mealPlan, true
+}
+
+func (c *MealController) CreateWeeklyMealPlan(ctx *gin.Context) {
+	ids, ok := getQueryIds(ctx, "userId", "mainGoalId", "weeklyGoalId")
+	if !ok {
 		return
 	}
 
-	goal, err := c.UserGoalRepository.GetUserWeeklyGoal(timedContext, mongoMainGoalId, mongoWeeklyGoalId)
-	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Goal not found"})
+	mealPlan, ok := c.createWeeklyMealPlan(ctx, ids[0], ids[1], ids[2])
+	if !ok {
 		return
 	}
+	ctx.JSON(http.StatusOK, mealPlan)
+}
 
-	isAlreadyCreated := c.UserMealRepository.IsWeeklyMealPlanCreated(timedContext, mongoUserId, mongoWeeklyGoalId)
-	if isAlreadyCreated {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Meal Plan is already created"})
+// CreateMealPlan is the v1 create of the meal plan of a weekly goal, the optional prompt query param steers the generation
+func (c *MealController) CreateMealPlan(ctx *gin.Context) {
+	ids, ok := getPathIds(ctx, "goalId", "weeklyGoalId")
+	if !ok {
+		return
+	}
+	ownerId, ok := c.getGoalOwnerId(ctx, ids[0])
+	if !ok {
 		return
 	}
 
-	extraPrompt, err1 := ctx.GetQuery("prompt")
-	if !err1 {
-		extraPrompt = ""
+	mealPlan, ok := c.createWeeklyMealPlan(ctx, ownerId, ids[0], ids[1])
+	if !ok {
+		return
 	}
+	respondCreated(ctx, "/meal-plans/"+mealPlan.ID.Hex(), mealPlan)
+}
 
-	prompt := config.GetWeeklyMealPrompt(*user, extraPrompt, float32(goal.WeeklyGoals[0].CurrentWeightInKg), float32(goal.WeeklyGoals[0].CurrentFatPercentage),
-		float32(goal.TargetWeightInKg), float32(goal.TargetFatPercentage), int32(goal.WeeklyGoals[0].TargetDailyCalories),
-		int32(goal.WeeklyGoals[0].TargetDailyMacrosFats), int32(goal.WeeklyGoals[0].TargetDailyMacrosCarbs), int32(goal.WeeklyGoals[0].TargetDailyMacrosProtein), string(goal.GoalType))
+// customizeDayMeal regenerates the meals of the day following the user prompt, writing the error response when it fails.
+// Returns the meal plan without its day meals and the updated day meal
+func (c *MealController) customizeDayMeal(ctx *gin.Context, mongoMealPlanId primitive.ObjectID, mongodayMealId primitive.ObjectID, userPrompt string) (*models.MealPlan, *models.DayMeal, bool) {
+	// 120 seconds for llm to respond
+	timedContext, cancel := config.GetTimedContext(120)
+	defer cancel()
 
-	resp, err := config.GetMealModel().GenerateContent(timedContext, genai.Text(prompt))
+	mealPlan, err := c.MealPlanService.GetMealPlanMeta(timedContext, mongoMealPlanId)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate content: " + err.Error()})
-		return
+		ctx.Error(err)
+		return nil, nil, false
+	}
+	if !c.UserAccess.CanAccess(ctx, mealPlan.UserId, true) {
+		ctx.Error(models.NewApiError(models.FORBIDDEN, "You do not have access to this user"))
+		return nil, nil, false
 	}
 
-	if len(resp.Candidates) == 0 || len(resp.Candidates[0].Content.Parts) == 0 {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "No content generated by the model"})
-		return
+	dayMeal, err := c.MealPlanService.CustomizeDayMeal(timedContext, mealPlan, mongodayMealId, userPrompt)
+	if err != nil {
+		ctx.Error(err)
+		return nil, nil, false
 	}
+	return mealPlan, dayMeal, true
+}
 
-	content, ok := resp.Candidates[0].Content.Parts[0].(genai.Text)
+func (c *MealController) CustomizeDayMealPlan(ctx *gin.Context) {
+	ids, ok := getQueryIds(ctx, "mealPlanId", "dayMealId")
 	if !ok {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unexpected content format from the model"})
 		return
 	}
-
-	var result map[string]any
-	if err := json.Unmarshal([]byte(content), &result); err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unmarshal response to JSON: " + err.Error()})
+	userPrompt, ok := ctx.GetQuery("userPrompt")
+	if !ok {
+		ctx.Error(models.NewApiError(models.INVALID_REQUEST, "Invalid request format: missing userPrompt"))
 		return
 	}
 
-	mealPlan := utils.ParseMealPlanResponse(mongoUserId, mongoMainGoalId, mongoWeeklyGoalId, goal.WeeklyGoals[0].StartDate, result)
-	for j := range mealPlan.DayMeals {
-		dayMealNew := &mealPlan.DayMeals[j]
-		for i := range dayMealNew.Meals {
-			meal := &dayMealNew.Meals[i]
-			var resultFoodImage map[string]any
-			queryParams := map[string]string{
-				"q":      meal.Name,
-				"num":    "1",
-				"apiKey": config.GetConfig().SerperApiKey,
-			}
-
-			errFoodImage := utils.MakeGETRequest(ctx, "https://google.serper.dev/images", queryParams, &resultFoodImage)
-			if errFoodImage != nil {
-				fmt.Println("Error getting image:", errFoodImage)
-				meal.ImageUrl = "https://www.foodiesfeed.com/wp-content/uploads/2023/09/healthy-food.jpg"
-				continue
-			}
-
-			images, ok := resultFoodImage["images"].([]any)
-			if !ok || len(images) == 0 {
-				fmt.Println("No images found for meal:", meal.Name)
-				meal.ImageUrl = "https://www.foodiesfeed.com/wp-content/uploads/2023/09/healthy-food.jpg"
-				continue
-			}
-
-			firstImage, ok := images[0].(map[string]any)
-			if !ok {
-				fmt.Println("Invalid image format for meal:", meal.Name)
-				meal.ImageUrl = "https://www.foodiesfeed.com/wp-content/uploads/2023/09/healthy-food.jpg"
-				continue
-			}
-
-			imageUrl, ok := firstImage["imageUrl"].(string)
-			if !ok {
-				fmt.Println("Image URL not found for meal:", meal.Name)
-				meal.ImageUrl = "https://www.foodiesfeed.com/wp-content/uploads/2023/09/healthy-food.jpg"
-				continue
-			}
-
-			meal.ImageUrl = imageUrl
-		}
-	}
-	err = c.UserMealRepository.CreateWeeklyMealPlan(timedContext, &mealPlan)
-	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate content: " + err.Error()})
+	mealPlan, _, ok := c.customizeDayMeal(ctx, ids[0], ids[1], userPrompt)
+	if !ok {
 		return
 	}
 
-	ctx.JSON(http.StatusOK, mealPlan)
+	ctx.JSON(http.StatusOK, gin.H{"mealPlanId": mealPlan.ID, "mainGoalId": mealPlan.MainGoalId, "weeklyGoalId": mealPlan.WeeklyGoalId})
 }
 
-func (c *MealController) CustomizeDayMealPlan(ctx *gin.Context) {
-	requiredFields := []string{"mealPlanId", "dayMealId", "userPrompt"}
-	values := make(map[string]string)
-
-	for _, field := range requiredFields {
-		value, ok := ctx.GetQuery(field)
-		if !ok {
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid request format: missing %s", field)})
-			return
-		}
-		values[field] = value
+// CustomizeDayMeal is the v1 update of a day of the meal plan, its meals are regenerated following the userPrompt of the body
+func (c *MealController) CustomizeDayMeal(ctx *gin.Context) {
+	ids, ok := getPathIds(ctx, "mealPlanId", "dayMealId")
+	if !ok {
+		return
 	}
 
-	mealPlanIdStr := values["mealPlanId"]
-	dayMealIdStr := values["dayMealId"]
-	userPrompt := values["userPrompt"]
-
-	mongoMealPlanId, err := primitive.ObjectIDFromHex(mealPlanIdStr)
-	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid mealPlanId format: must be a valid ObjectId"})
+	var body struct {
+		UserPrompt string `json:"userPrompt" binding:"required"`
+	}
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		ctx.Error(models.NewApiError(models.INVALID_REQUEST, "Invalid request format: missing userPrompt"))
 		return
 	}
-	mongodayMealId, err := primitive.ObjectIDFromHex(dayMealIdStr)
-	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid dayMealId format: must be a valid ObjectId"})
+
+	_, dayMeal, ok := c.customizeDayMeal(ctx, ids[0], ids[1], body.UserPrompt)
+	if !ok {
 		return
 	}
 
-	// 120 seconds for llm to respond
-	timedContext, cancel := config.GetTimedContext(120)
+	ctx.JSON(http.StatusOK, dayMeal)
+}
+
+// setMealConsumed marks the meal consumed or not, writing the error response when it fails.
+// The meal plan and day meal ids are zero when the meal is addressed by its id alone
+func (c *MealController) setMealConsumed(ctx *gin.Context, mealPlanId primitive.ObjectID, dayMealId primitive.ObjectID, mealId primitive.ObjectID, isConsumed bool) bool {
+	timedContext, cancel := config.GetTimedContext()
 	defer cancel()
 
-	mealPlan, err := c.UserMealRepository.GetMealPlanMeta(timedContext, mongoMealPlanId)
+	ownerId, err := c.MealPlanService.GetMealOwnerId(timedContext, mealId)
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Meal plan not found"})
-		return
+		ctx.Error(err)
+		return false
+	}
+	if !c.UserAccess.CanAccess(ctx, ownerId, true) {
+		ctx.Error(models.NewApiError(models.FORBIDDEN, "You do not have access to this user"))
+		return false
 	}
 
-	mongoUserId := mealPlan.UserId
-	mongoMainGoalId := mealPlan.MainGoalId
-	mongoWeeklyGoalId := mealPlan.WeeklyGoalId
-
-	user, err := c.UserRepository.GetUserProfileById(timedContext, mongoUserId)
+	err = c.MealPlanService.SetMealConsumed(timedContext, mealPlanId, dayMealId, mealId, isConsumed)
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "User not found"})
-		return
+		ctx.Error(err)
+		return false
 	}
-	if !user.IsProfileComplete() {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Profile incomplete"})
+	return true
+}
+
+func (c *MealController) ConsumeMeal(ctx *gin.Context) {
+	ids, ok := getQueryIds(ctx, "mealId")
+	if !ok || !c.setMealConsumed(ctx, primitive.NilObjectID, primitive.NilObjectID, ids[0], true) {
 		return
 	}
 
-	goal, err := c.UserGoalRepository.GetUserWeeklyGoal(timedContext, mongoMainGoalId, mongoWeeklyGoalId)
-	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Goal not found"})
+	ctx.JSON(http.StatusOK, gin.H{"sucess": true})
+}
+
+// UpdateMeal is the v1 update of a single meal, only isConsumed can be changed
+func (c *MealController) UpdateMeal(ctx *gin.Context) {
+	ids, ok := getPathIds(ctx, "mealPlanId", "dayMealId", "mealId")
+	if !ok {
 		return
 	}
 
-	dayMeal, err := c.UserMealRepository.GetSingleDayMeal(timedContext, mongoMainGoalId, mongoWeeklyGoalId, mongodayMealId)
-	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Goal not found"})
+	var body struct {
+		IsConsumed *bool `json:"isConsumed"`
+	}
+	if err := ctx.ShouldBindJSON(&body); err != nil || body.IsConsumed == nil {
+		ctx.Error(models.NewApiError(models.INVALID_REQUEST, "Invalid request format: missing isConsumed"))
 		return
 	}
 
-	jsonBytes, err := json.Marshal(dayMeal)
-	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Day Meal plan not found"})
+	if !c.setMealConsumed(ctx, ids[0], ids[1], ids[2], *body.IsConsumed) {
 		return
 	}
 
-	prompt := config.GetSingleMealEditPrompt(*user, string(jsonBytes), userPrompt, float32(goal.WeeklyGoals[0].CurrentWeightInKg), float32(goal.WeeklyGoals[0].CurrentFatPercentage),
-		float32(goal.TargetWeightInKg), float32(goal.TargetFatPercentage), int32(goal.WeeklyGoals[0].TargetDailyCalories),
-		int32(goal.WeeklyGoals[0].TargetDailyMacrosFats), int32(goal.WeeklyGoals[0].TargetDailyMacrosCarbs), int32(goal.WeeklyGoals[0].TargetDailyMacrosProtein), string(goal.GoalType))
+	ctx.Status(http.StatusNoContent)
+}
 
-	resp, err := config.GetSingleMealModel().GenerateContent(timedContext, genai.Text(prompt))
+// getNutritionReport compares the planned and consumed micronutrients of the weekly meal plan with the daily
+// reference intake of the user, writing the error response when it fails
+func (c *MealController) getNutritionReport(ctx *gin.Context, mongoUserId primitive.ObjectID, mongoMainGoalId primitive.ObjectID, mongoWeeklyGoalId primitive.ObjectID) (*models.NutritionReport, bool) {
+	timedContext, cancel := config.GetTimedContext()
+	defer cancel()
+
+	report, err := c.MealPlanService.GetNutritionReport(timedContext, mongoUserId, mongoMainGoalId, mongoWeeklyGoalId)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate content: " + err.Error()})
-		return
+		ctx.Error(err)
+		return nil, false
 	}
+	return report, true
+}
 
-	if len(resp.Candidates) == 0 || len(resp.Candidates[0].Content.Parts) == 0 {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "No content generated by the model"})
+func (c *MealController) GetNutritionReport(ctx *gin.Context) {
+	ids, ok := getQueryIds(ctx, "userId", "mainGoalId", "weeklyGoalId")
+	if !ok {
 		return
 	}
 
-	content, ok := resp.Candidates[0].Content.Parts[0].(genai.Text)
+	report, ok := c.getNutritionReport(ctx, ids[0], ids[1], ids[2])
 	if !ok {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unexpected content format from the model"})
 		return
 	}
+	ctx.JSON(http.StatusOK, report)
+}
 
-	var result map[string]any
-	if err := json.Unmarshal([]byte(content), &result); err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unmarshal response to JSON: " + err.Error()})
+// GetWeekNutritionReport is the v1 nutrition report of the meal plan of a weekly goal
+func (c *MealController) GetWeekNutritionReport(ctx *gin.Context) {
+	ids, ok := getPathIds(ctx, "goalId", "weeklyGoalId")
+	if !ok {
 		return
 	}
-
-	dayMealNew := utils.ParseSingleMealPlanResponse(result)
-	for i := range dayMealNew.Meals {
-		meal := &dayMealNew.Meals[i]
-		var resultFoodImage map[string]any
-		queryParams := map[string]string{
-			"q":      meal.Name,
-			"num":    "1",
-			"apiKey": config.GetConfig().SerperApiKey,
-		}
-
-		errFoodImage := utils.MakeGETRequest(ctx, "https://google.serper.dev/images", queryParams, &resultFoodImage)
-		if errFoodImage != nil {
-			fmt.Println("Error getting image:", errFoodImage)
-			meal.ImageUrl = "https://www.foodiesfeed.com/wp-content/uploads/2023/09/healthy-food.jpg"
-			continue
-		}
-
-		images, ok := resultFoodImage["images"].([]any)
-		if !ok || len(images) == 0 {
-			fmt.Println("No images found for meal:", meal.Name)
-			meal.ImageUrl = "https://www.foodiesfeed.com/wp-content/uploads/2023/09/healthy-food.jpg"
-			continue
-		}
-
-		firstImage, ok := images[0].(map[string]any)
-		if !ok {
-			fmt.Println("Invalid image format for meal:", meal.Name)
-			meal.ImageUrl = "https://www.foodiesfeed.com/wp-content/uploads/2023/09/healthy-food.jpg"
-			continue
-		}
-
-		imageUrl, ok := firstImage["imageUrl"].(string)
-		if !ok {
-			fmt.Println("Image URL not found for meal:", meal.Name)
-			meal.ImageUrl = "https://www.foodiesfeed.com/wp-content/uploads/2023/09/healthy-food.jpg"
-			continue
-		}
-
-		meal.ImageUrl = imageUrl
+	ownerId, ok := c.getGoalOwnerId(ctx, ids[0])
+	if !ok {
+		return
 	}
 
-	err = c.UserMealRepository.UpdateSingleDayMeal(timedContext, mongoMealPlanId, mongodayMealId, dayMealNew.Meals)
-	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate content: " + err.Error()})
+	report, ok := c.getNutritionReport(ctx, ownerId, ids[0], ids[1])
+	if !ok {
 		return
 	}
-
-	ctx.JSON(http.StatusOK, gin.H{"mealPlanId": mealPlan.ID, "mainGoalId": mealPlan.MainGoalId, "weeklyGoalId": mealPlan.WeeklyGoalId})
+	ctx.JSON(http.StatusOK, report)
 }
 
-func (c *MealController) ConsumeMeal(ctx *gin.Context) {
-	requiredFields := []string{"mealId"}
-	values := make(map[string]string)
-
-	for _, field := range requiredFields {
-		value, ok := ctx.GetQuery(field)
-		if !ok {
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid request format: missing %s", field)})
-			return
+// MealPlanSnapshot is the audit snapshot of the meal plan addressed by the mealPlanId or mealId path or query param,
+// or by the userId, mainGoalId and weeklyGoalId query params or goalId and weeklyGoalId path params when a plan is created
+func (c *MealController) MealPlanSnapshot(ctx *gin.Context, timedContext context.Context) (primitive.ObjectID, any, error) {
+	getId := func(field string) primitive.ObjectID {
+		value := ctx.Param(field)
+		if value == "" {
+			value = ctx.Query(field)
+		}
+		id, _ := primitive.ObjectIDFromHex(value)
+		return id
+	}
+
+	var mealPlan *models.MealPlan
+	var err error
+	if mealPlanId := getId("mealPlanId"); !mealPlanId.IsZero() {
+		mealPlan, err = c.MealPlanService.MealRepository.GetMealPlanById(timedContext, mealPlanId)
+	} else if mealId := getId("mealId"); !mealId.IsZero() {
+		mealPlan, err = c.MealPlanService.MealRepository.GetMealPlanByMealId(timedContext, mealId)
+	} else {
+		userId, mainGoalId, weeklyGoalId := getId("userId"), getId("mainGoalId"), getId("weeklyGoalId")
+		if goalId := getId("goalId"); !goalId.IsZero() {
+			mainGoalId = goalId
+			userId, _ = c.MealPlanService.UserGoalRepository.GetGoalOwnerId(timedContext, goalId)
+		}
+		if userId.IsZero() || mainGoalId.IsZero() || weeklyGoalId.IsZero() {
+			return primitive.NilObjectID, nil, nil
+		}
+		mealPlan, err = c.MealPlanService.MealRepository.GetWeeklyMealPlan(timedContext, userId, mainGoalId, weeklyGoalId)
+		if err == nil && mealPlan == nil {
+			return userId, nil, nil
 		}
-		values[field] = value
-	}
-
-	mealIdStr := values["mealId"]
-
-	mongoMealId, err := primitive.ObjectIDFromHex(mealIdStr)
-	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid mealId format: must be a valid ObjectId"})
-		return
 	}
 
-	timedContext, cancel := config.GetTimedContext()
-	defer cancel()
-
-	err = c.UserMealRepository.ConsumeSingleMeal(timedContext, mongoMealId)
-	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Meal not found"})
-		return
+	if err != nil || mealPlan == nil {
+		return primitive.NilObjectID, nil, err
 	}
-
-	ctx.JSON(http.StatusOK, gin.H{"sucess": true})
+	return mealPlan.UserId, mealPlan, nil
 }
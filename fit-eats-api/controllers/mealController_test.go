@@ -0,0 +1,122 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"fit-eats-api/middleware"
+	"fit-eats-api/models"
+	"fit-eats-api/repositories"
+	"fit-eats-api/services"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func newMealTestRouter(meals repositories.MealRepository, callerId primitive.ObjectID, role models.Role) *gin.Engine {
+	users, goals := repositories.NewInMemoryUserRepository(), repositories.NewInMemoryUserGoalRepository()
+	controller := NewMealController(services.NewMealPlanService(users, goals, meals, repositories.NewInMemoryUnitOfWork()), services.NewGoalService(users, goals, meals, repositories.NewInMemoryUnitOfWork()), middleware.NewUserAccess(nil))
+
+	router := newTestRouter(callerId, role)
+	router.PUT("/api/consumeMeal", controller.ConsumeMeal)
+	router.GET("/api/v1/meal-plans/:mealPlanId", controller.GetMealPlan)
+	router.PATCH("/api/v1/meal-plans/:mealPlanId/days/:dayMealId/meals/:mealId", controller.UpdateMeal)
+	return router
+}
+
+// createTestMealPlan creates a two day meal plan with the same breakfast on both days
+func createTestMealPlan(t *testing.T, meals repositories.MealRepository, userId primitive.ObjectID) *models.MealPlan {
+	t.Helper()
+	breakfastId := primitive.NewObjectID()
+	mealPlan := &models.MealPlan{ID: primitive.NewObjectID(), UserId: userId, MainGoalId: primitive.NewObjectID(), WeeklyGoalId: primitive.NewObjectID()}
+	for day := 0; day < 2; day++ {
+		mealPlan.DayMeals = append(mealPlan.DayMeals, models.DayMeal{
+			ID:    primitive.NewObjectID(),
+			Date:  time.Now().AddDate(0, 0, day),
+			Meals: []models.Meal{{ID: breakfastId, Name: "Oats"}, {ID: primitive.NewObjectID(), Name: "Dal"}},
+		})
+	}
+	if err := meals.CreateWeeklyMealPlan(context.Background(), mealPlan); err != nil {
+		t.Fatal(err)
+	}
+	return mealPlan
+}
+
+func getConsumedMeals(t *testing.T, meals repositories.MealRepository, mealPlanId primitive.ObjectID) []bool {
+	t.Helper()
+	mealPlan, err := meals.GetMealPlanById(context.Background(), mealPlanId)
+	if err != nil || mealPlan == nil {
+		t.Fatalf("could not get meal plan: %v", err)
+	}
+	consumed := []bool{}
+	for _, dayMeal := range mealPlan.DayMeals {
+		for _, meal := range dayMeal.Meals {
+			consumed = append(consumed, meal.IsConsumed)
+		}
+	}
+	return consumed
+}
+
+func expectConsumedMeals(t *testing.T, actual []bool, expected ...bool) {
+	t.Helper()
+	for i := range expected {
+		if actual[i] != expected[i] {
+			t.Fatalf("expected consumed meals %v, got %v", expected, actual)
+		}
+	}
+}
+
+func TestMealControllerConsumeMeal(t *testing.T) {
+	meals := repositories.NewInMemoryMealRepository()
+	userId := primitive.NewObjectID()
+	mealPlan := createTestMealPlan(t, meals, userId)
+	router := newMealTestRouter(meals, userId, models.ROLE_USER)
+	breakfastId := mealPlan.DayMeals[0].Meals[0].ID
+
+	recorder := serve(router, http.MethodPut, "/api/consumeMeal?mealId="+breakfastId.Hex(), nil)
+	expectStatus(t, recorder, http.StatusOK)
+	expectConsumedMeals(t, getConsumedMeals(t, meals, mealPlan.ID), true, false, true, false)
+
+	target := "/api/v1/meal-plans/" + mealPlan.ID.Hex() + "/days/" + mealPlan.DayMeals[1].ID.Hex() + "/meals/" + breakfastId.Hex()
+	recorder = serve(router, http.MethodPatch, target, gin.H{"isConsumed": false})
+	expectStatus(t, recorder, http.StatusNoContent)
+	expectConsumedMeals(t, getConsumedMeals(t, meals, mealPlan.ID), true, false, false, false)
+
+	recorder = serve(router, http.MethodPatch, target, gin.H{})
+	expectError(t, recorder, http.StatusBadRequest, models.INVALID_REQUEST)
+
+	recorder = serve(router, http.MethodPut, "/api/consumeMeal?mealId="+primitive.NewObjectID().Hex(), nil)
+	expectError(t, recorder, http.StatusNotFound, models.MEAL_NOT_FOUND)
+
+	// The meal exists but not in the addressed day
+	wrongDay := "/api/v1/meal-plans/" + mealPlan.ID.Hex() + "/days/" + primitive.NewObjectID().Hex() + "/meals/" + breakfastId.Hex()
+	recorder = serve(router, http.MethodPatch, wrongDay, gin.H{"isConsumed": true})
+	expectError(t, recorder, http.StatusNotFound, models.MEAL_NOT_FOUND)
+}
+
+func TestMealControllerAccess(t *testing.T) {
+	meals := repositories.NewInMemoryMealRepository()
+	ownerId := primitive.NewObjectID()
+	mealPlan := createTestMealPlan(t, meals, ownerId)
+	other := newMealTestRouter(meals, primitive.NewObjectID(), models.ROLE_USER)
+
+	recorder := serve(other, http.MethodPut, "/api/consumeMeal?mealId="+mealPlan.DayMeals[0].Meals[1].ID.Hex(), nil)
+	expectError(t, recorder, http.StatusForbidden, models.FORBIDDEN)
+	expectConsumedMeals(t, getConsumedMeals(t, meals, mealPlan.ID), false, false, false, false)
+
+	recorder = serve(other, http.MethodGet, "/api/v1/meal-plans/"+mealPlan.ID.Hex(), nil)
+	expectError(t, recorder, http.StatusForbidden, models.FORBIDDEN)
+
+	recorder = serve(newMealTestRouter(meals, ownerId, models.ROLE_USER), http.MethodGet, "/api/v1/meal-plans/"+mealPlan.ID.Hex(), nil)
+	expectStatus(t, recorder, http.StatusOK)
+	var found models.MealPlan
+	decodeResponse(t, recorder, &found)
+	if found.ID != mealPlan.ID || len(found.DayMeals) != 2 {
+		t.Errorf("expected the meal plan, got %s", recorder.Body)
+	}
+
+	recorder = serve(other, http.MethodGet, "/api/v1/meal-plans/"+primitive.NewObjectID().Hex(), nil)
+	expectError(t, recorder, http.StatusNotFound, models.MEAL_PLAN_NOT_FOUND)
+}
@@ -0,0 +1,252 @@
+package controllers
+
+import (
+	"fit-eats-api/config"
+	"fit-eats-api/middleware"
+	"fit-eats-api/models"
+	"fit-eats-api/repositories"
+	"fit-eats-api/utils"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type OidcController struct {
+	UserController      *UserController
+	OidcStateRepository *repositories.OidcStateRepository
+	OidcClients         map[string]*utils.OidcClient
+}
+
+// NewOidcController reuses the UserController to issue sessions, so social logins go through the same mfa and session handling
+func NewOidcController(userController *UserController, oidcStateRepository *repositories.OidcStateRepository, oidcClients map[string]*utils.OidcClient) *OidcController {
+	return &OidcController{UserController: userController, OidcStateRepository: oidcStateRepository, OidcClients: oidcClients}
+}
+
+func (c *OidcController) GetOidcProviders(ctx *gin.Context) {
+	providers := make([]string, 0, len(c.OidcClients))
+	for name := range c.OidcClients {
+		providers = append(providers, name)
+	}
+	sort.Strings(providers)
+
+	ctx.JSON(http.StatusOK, gin.H{"providers": providers})
+}
+
+// StartOidcLogin returns the provider url to send the user to, the provider redirects back with a code and the state
+func (c *OidcController) StartOidcLogin(ctx *gin.Context) {
+	c.startAuthorization(ctx, primitive.NilObjectID)
+}
+
+// LinkIdentity starts the same flow for a logged in user, the callback then links the identity to the user
+func (c *OidcController) LinkIdentity(ctx *gin.Context) {
+	c.startAuthorization(ctx, middleware.GetUserId(ctx))
+}
+
+func (c *OidcController) startAuthorization(ctx *gin.Context, userId primitive.ObjectID) {
+	provider, ok := ctx.GetQuery("provider")
+	if !ok {
+		ctx.Error(models.NewApiError(models.INVALID_REQUEST, "Invalid request format: missing provider"))
+		return
+	}
+	client, ok := c.OidcClients[provider]
+	if !ok {
+		ctx.Error(models.NewApiError(models.INVALID_REQUEST, "Unknown provider"))
+		return
+	}
+
+	// Discovery may need a round trip to the provider
+	timedContext, cancel := config.GetTimedContext(30)
+	defer cancel()
+
+	state := utils.GenerateRandomToken(32)
+	now := time.Now()
+	oidcState := models.OidcState{
+		StateHash:    utils.HashToken(state),
+		Provider:     provider,
+		Nonce:        utils.GenerateRandomToken(16),
+		CodeVerifier: utils.GenerateRandomToken(32),
+		UserId:       userId,
+		CreatedAt:    now,
+		ExpiresAt:    now.Add(models.OidcStateValidity),
+	}
+
+	authorizationUrl, err := client.GetAuthorizationUrl(timedContext, state, oidcState.Nonce, oidcState.CodeVerifier)
+	if err != nil {
+		log.Printf("Could not start oidc login with %s: %v", provider, err)
+		ctx.Error(models.NewApiError(models.PROVIDER_UNAVAILABLE, "Could not reach provider"))
+		return
+	}
+
+	err = c.OidcStateRepository.CreateOidcState(timedContext, &oidcState)
+	if err != nil {
+		ctx.Error(models.NewApiError(models.INTERNAL_ERROR, "Could not start login").WithCause(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"authorizationUrl": authorizationUrl, "state": state})
+}
+
+func (c *OidcController) OidcCallback(ctx *gin.Context) {
+	provider := ctx.PostForm("provider")
+	state := ctx.PostForm("state")
+	code := ctx.PostForm("code")
+	if provider == "" || state == "" || code == "" {
+		ctx.Error(models.NewApiError(models.INVALID_REQUEST, "missing body params"))
+		return
+	}
+	client, ok := c.OidcClients[provider]
+	if !ok {
+		ctx.Error(models.NewApiError(models.INVALID_REQUEST, "Unknown provider"))
+		return
+	}
+
+	// Exchanging the code and loading keys are round trips to the provider
+	timedContext, cancel := config.GetTimedContext(30)
+	defer cancel()
+
+	oidcState, err := c.OidcStateRepository.ConsumeOidcState(timedContext, provider, utils.HashToken(state))
+	if err != nil {
+		ctx.Error(models.NewApiError(models.INVALID_REQUEST, "Login expired, please try again"))
+		return
+	}
+
+	claims, err := client.ExchangeCode(timedContext, code, oidcState.CodeVerifier, oidcState.Nonce)
+	if err != nil {
+		log.Printf("Could not complete oidc login with %s: %v", provider, err)
+		ctx.Error(models.NewApiError(models.INVALID_CREDENTIALS, "Could not verify login with provider"))
+		return
+	}
+
+	identity := models.Identity{Provider: provider, Subject: claims.Subject, Email: claims.Email, LinkedAt: time.Now()}
+	userRepository := c.UserController.UserRepository
+
+	if !oidcState.UserId.IsZero() {
+		linkedUser, err := userRepository.GetUserByIdentity(timedContext, provider, claims.Subject)
+		if err == nil && linkedUser.ID != oidcState.UserId {
+			ctx.Error(models.NewApiError(models.ALREADY_EXISTS, "This account is already linked to another user"))
+			return
+		}
+
+		err = userRepository.AddUserIdentity(timedContext, oidcState.UserId, identity)
+		if err == mongo.ErrNoDocuments {
+			ctx.Error(models.NewApiError(models.ALREADY_EXISTS, "An account of this provider is already linked"))
+			return
+		}
+		if err != nil {
+			ctx.Error(models.NewApiError(models.INTERNAL_ERROR, "Could not link account").WithCause(err))
+			return
+		}
+
+		middleware.SetAuditUserId(ctx, oidcState.UserId)
+		ctx.JSON(http.StatusOK, gin.H{"message": "Account linked successfully"})
+		return
+	}
+
+	user, err := userRepository.GetUserByIdentity(timedContext, provider, claims.Subject)
+	if err == mongo.ErrNoDocuments {
+		user, err = c.findOrCreateUser(ctx, claims, identity)
+		if err != nil {
+			return
+		}
+		// Logins with an identity linked before change nothing and are not recorded
+		middleware.SetAuditUserId(ctx, user.ID)
+	} else if err != nil {
+		ctx.Error(models.NewApiError(models.INTERNAL_ERROR, "Could not get user").WithCause(err))
+		return
+	}
+
+	c.UserController.startLogin(ctx, timedContext, user, models.LOGIN_ACCOUNT_KEY_PREFIX+strings.ToLower(user.Email))
+}
+
+// findOrCreateUser links the identity to the account with the same email, or registers a new account.
+// Only emails the provider verified are trusted, otherwise anyone could take over an account by its email.
+// The error response is written here when an error is returned.
+func (c *OidcController) findOrCreateUser(ctx *gin.Context, claims *utils.OidcClaims, identity models.Identity) (*models.User, error) {
+	if claims.Email == "" || !claims.EmailVerified {
+		ctx.Error(models.NewApiError(models.INVALID_REQUEST, "The provider did not share a verified email"))
+		return nil, mongo.ErrNoDocuments
+	}
+
+	timedContext, cancel := config.GetTimedContext()
+	defer cancel()
+
+	userRepository := c.UserController.UserRepository
+	user, err := userRepository.GetUserByEmail(timedContext, claims.Email)
+	if err == nil {
+		err = userRepository.AddUserIdentity(timedContext, user.ID, identity)
+		if err == mongo.ErrNoDocuments {
+			ctx.Error(models.NewApiError(models.ALREADY_EXISTS, "Another account of this provider is already linked to this user"))
+			return nil, err
+		}
+		if err != nil {
+			ctx.Error(models.NewApiError(models.INTERNAL_ERROR, "Could not link account").WithCause(err))
+			return nil, err
+		}
+		return user, nil
+	}
+	if err != mongo.ErrNoDocuments {
+		ctx.Error(models.NewApiError(models.INTERNAL_ERROR, "Could not get user").WithCause(err))
+		return nil, err
+	}
+
+	name := claims.Name
+	if name == "" {
+		name = strings.Split(claims.Email, "@")[0]
+	}
+	// Social accounts have no password until the user sets one with a password reset
+	user = &models.User{
+		Name:          name,
+		Email:         claims.Email,
+		EmailVerified: true,
+		Identities:    []models.Identity{identity},
+	}
+	err = userRepository.CreateUser(timedContext, user)
+	if err != nil {
+		ctx.Error(models.NewApiError(models.INTERNAL_ERROR, "Could not register user").WithCause(err))
+		return nil, err
+	}
+	middleware.SetAuditAction(ctx, models.AUDIT_USER_REGISTERED)
+	return user, nil
+}
+
+func (c *OidcController) UnlinkIdentity(ctx *gin.Context) {
+	provider, ok := ctx.GetQuery("provider")
+	if !ok {
+		ctx.Error(models.NewApiError(models.INVALID_REQUEST, "Invalid request format: missing provider"))
+		return
+	}
+
+	timedContext, cancel := config.GetTimedContext()
+	defer cancel()
+
+	userRepository := c.UserController.UserRepository
+	user, err := userRepository.GetUserCredentialsById(timedContext, middleware.GetUserId(ctx))
+	if err != nil {
+		ctx.Error(models.NewApiError(models.USER_NOT_FOUND, "User not found"))
+		return
+	}
+
+	// The user must keep at least one way to login
+	if !user.HasPassword() && len(user.Identities) <= 1 {
+		ctx.Error(models.NewApiError(models.INVALID_STATE, "Set a password before removing your only login method"))
+		return
+	}
+
+	err = userRepository.RemoveUserIdentity(timedContext, user.ID, provider)
+	if err == mongo.ErrNoDocuments {
+		ctx.Error(models.NewApiError(models.IDENTITY_NOT_FOUND, "No account of this provider is linked"))
+		return
+	}
+	if err != nil {
+		ctx.Error(models.NewApiError(models.INTERNAL_ERROR, "Could not unlink account").WithCause(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Account unlinked successfully"})
+}
@@ -0,0 +1,35 @@
+package controllers
+
+import (
+	"fit-eats-api/models"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	DEFAULT_PAGE_SIZE = 20
+	MAX_PAGE_SIZE     = 100
+)
+
+// getPagination reads the page and pageSize query params, writing the error response when they are invalid
+func getPagination(ctx *gin.Context) (int, int, bool) {
+	page, pageSize := 1, DEFAULT_PAGE_SIZE
+	if value, ok := ctx.GetQuery("page"); ok {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 {
+			ctx.Error(models.NewApiError(models.INVALID_REQUEST, "Invalid page format: must be a positive number"))
+			return 0, 0, false
+		}
+		page = parsed
+	}
+	if value, ok := ctx.GetQuery("pageSize"); ok {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > MAX_PAGE_SIZE {
+			ctx.Error(models.NewApiError(models.INVALID_REQUEST, "Invalid pageSize format: must be between 1 and 100"))
+			return 0, 0, false
+		}
+		pageSize = parsed
+	}
+	return page, pageSize, true
+}
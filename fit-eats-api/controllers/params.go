@@ -0,0 +1,100 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fit-eats-api/middleware"
+	"fit-eats-api/models"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const API_V1_PATH = "/api/v1"
+
+// getPathIds parses the named path params of the v1 routes, writing the error response for the first invalid one
+func getPathIds(ctx *gin.Context, fields ...string) ([]primitive.ObjectID, bool) {
+	return getIds(ctx, ctx.Param, fields)
+}
+
+// getQueryIds parses the named query params of the legacy routes, writing the error response for the first missing or invalid one
+func getQueryIds(ctx *gin.Context, fields ...string) ([]primitive.ObjectID, bool) {
+	return getIds(ctx, ctx.Query, fields)
+}
+
+func getIds(ctx *gin.Context, get func(string) string, fields []string) ([]primitive.ObjectID, bool) {
+	ids := make([]primitive.ObjectID, len(fields))
+	for i, field := range fields {
+		value := get(field)
+		if value == "" {
+			ctx.Error(models.NewApiError(models.INVALID_REQUEST, fmt.Sprintf("Invalid request format: missing %s", field)))
+			return nil, false
+		}
+		id, err := primitive.ObjectIDFromHex(value)
+		if err != nil {
+			ctx.Error(models.NewApiError(models.INVALID_REQUEST, fmt.Sprintf("Invalid %s format: must be a valid ObjectId", field)))
+			return nil, false
+		}
+		ids[i] = id
+	}
+	return ids, true
+}
+
+// userLogSnapshot is the audit snapshot of a log of the user, addressed by the userId and the idField query params of
+// the legacy routes. Invalid params are left to the handler to answer
+func userLogSnapshot[T any](idField string, getLog func(ctx context.Context, userId primitive.ObjectID, logId primitive.ObjectID) (*T, error)) middleware.AuditSnapshot {
+	return func(ctx *gin.Context, timedContext context.Context) (primitive.ObjectID, any, error) {
+		userId, err := primitive.ObjectIDFromHex(ctx.Query("userId"))
+		if err != nil {
+			return primitive.NilObjectID, nil, nil
+		}
+		logId, err := primitive.ObjectIDFromHex(ctx.Query(idField))
+		if err != nil {
+			return userId, nil, nil
+		}
+
+		userLog, err := getLog(timedContext, userId, logId)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return userId, nil, nil
+		}
+		if err != nil {
+			return userId, nil, err
+		}
+		return userId, userLog, nil
+	}
+}
+
+// getUserIdOrSelf reads the optional userId query param of the v1 routes, defaulting to the signed in user.
+// Access to another user is checked by the ClientMiddleware and OwnerMiddleware
+func getUserIdOrSelf(ctx *gin.Context) (primitive.ObjectID, bool) {
+	if _, ok := ctx.GetQuery("userId"); !ok {
+		return middleware.GetUserId(ctx), true
+	}
+	ids, ok := getQueryIds(ctx, "userId")
+	if !ok {
+		return primitive.NilObjectID, false
+	}
+	return ids[0], true
+}
+
+// resolveBodyUserId defaults the userId of a request body to the signed in user, writing the error response when it
+// is another user and the caller is not an admin. The OwnerMiddleware of the routes only checks the query params
+func resolveBodyUserId(ctx *gin.Context, userId *primitive.ObjectID) bool {
+	if userId.IsZero() {
+		*userId = middleware.GetUserId(ctx)
+	}
+	if !middleware.IsSelfOrAdmin(ctx, *userId) {
+		ctx.Error(models.NewApiError(models.FORBIDDEN, "You do not have access to this user"))
+		return false
+	}
+	return true
+}
+
+// respondCreated answers a v1 create with the new resource and its location
+func respondCreated(ctx *gin.Context, location string, resource any) {
+	ctx.Header("Location", API_V1_PATH+location)
+	ctx.JSON(http.StatusCreated, resource)
+}
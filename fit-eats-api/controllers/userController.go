@@ -1,36 +1,56 @@
 package controllers
 
 import (
+	"context"
+	"errors"
 	"fit-eats-api/config"
+	"fit-eats-api/middleware"
 	"fit-eats-api/models"
 	"fit-eats-api/repositories"
+	"fit-eats-api/services"
 	"fit-eats-api/utils"
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	"time"
 
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
 type UserController struct {
-	UserRepository *repositories.UserRepository
+	UserService         *services.UserService
+	UserRepository      repositories.UserRepository
+	SessionRepository   *repositories.SessionRepository
+	UserTokenRepository *repositories.UserTokenRepository
+	LoginAttemptStore   repositories.LoginAttemptStore
+	AuditRepository     repositories.AuditRepository
+	Mailer              utils.Mailer
 }
 
-func NewUserController(repository *repositories.UserRepository) *UserController {
-	return &UserController{UserRepository: repository}
+func NewUserController(userService *services.UserService, repository repositories.UserRepository, sessionRepository *repositories.SessionRepository, userTokenRepository *repositories.UserTokenRepository,
+	loginAttemptStore repositories.LoginAttemptStore, auditRepository repositories.AuditRepository, mailer utils.Mailer) *UserController {
+	return &UserController{UserService: userService, UserRepository: repository, SessionRepository: sessionRepository, UserTokenRepository: userTokenRepository,
+		LoginAttemptStore: loginAttemptStore, AuditRepository: auditRepository, Mailer: mailer}
 }
 
 func (c *UserController) Register(ctx *gin.Context) {
 	var user models.User
 	if err := ctx.ShouldBindJSON(&user); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		ctx.Error(models.NewApiError(models.INVALID_REQUEST, "Invalid request format"))
 		return
 	}
 
 	// Validate input
 	errors := utils.ValidateStruct(user)
 	if errors != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"errors": errors})
+		ctx.Error(models.NewValidationError(errors))
 		return
 	}
 
@@ -39,18 +59,33 @@ func (c *UserController) Register(ctx *gin.Context) {
 
 	hashedPassword, err1 := utils.GeneratePasswordHashFromPlainText(user.Password)
 	if err1 != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Could not generate user password"})
+		ctx.Error(models.NewApiError(models.INTERNAL_ERROR, "Could not generate user password").WithCause(err1))
 		return
 	}
 	user.Password = hashedPassword
+	user.EmailVerified = false
+	user.Mfa = nil
+	user.Identities = nil
+	user.Role = ""
 
 	// Register user
 	err := c.UserRepository.CreateUser(timedContext, &user)
+	if mongo.IsDuplicateKeyError(err) {
+		ctx.Error(models.NewApiError(models.ALREADY_EXISTS, "Email is already registered"))
+		return
+	}
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Could not register user"})
+		ctx.Error(models.NewApiError(models.INTERNAL_ERROR, "Could not register user").WithCause(err))
 		return
 	}
 
+	// Registration succeeds even if the mail fails, the user can ask for another verification email
+	err = c.sendUserToken(timedContext, &user, models.EMAIL_VERIFICATION)
+	if err != nil {
+		log.Printf("Could not send verification email to %s: %v", user.Email, err)
+	}
+
+	middleware.SetAuditUserId(ctx, user.ID)
 	ctx.JSON(http.StatusCreated, gin.H{"message": "User registered successfully"})
 }
 
@@ -58,173 +93,663 @@ func (c *UserController) Login(ctx *gin.Context) {
 	email := ctx.PostForm("email")
 	password := ctx.PostForm("password")
 	if email == "" || password == "" {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "missing body params"})
+		ctx.Error(models.NewApiError(models.INVALID_REQUEST, "missing body params"))
 		return
 	}
 
-	timedContext, cancel := config.GetTimedContext()
+	// Mail servers can be slow to answer when a lockout sends the unlock email
+	timedContext, cancel := config.GetTimedContext(30)
 	defer cancel()
 
+	accountKey := models.LOGIN_ACCOUNT_KEY_PREFIX + models.NormalizeEmail(email)
+	ipKey := models.LOGIN_IP_KEY_PREFIX + ctx.ClientIP()
+
+	// Throttled requests are rejected before the password is compared
+	if c.isLoginThrottled(ctx, timedContext, accountKey, models.AccountLoginPolicy) || c.isLoginThrottled(ctx, timedContext, ipKey, models.IpLoginPolicy) {
+		return
+	}
+
 	user, err := c.UserRepository.GetUserByEmail(timedContext, email)
 	if err != nil {
-		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "user not found. Please register to continue"})
+		c.recordLoginFailure(ctx, timedContext, nil, accountKey, ipKey)
+		ctx.Error(models.NewApiError(models.INVALID_CREDENTIALS, "user not found. Please register to continue"))
 		return
 	}
 
 	if !utils.IsPasswordCorrect(user.Password, password) {
-		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "invalid Credentials"})
+		c.recordLoginFailure(ctx, timedContext, user, accountKey, ipKey)
+		ctx.Error(models.NewApiError(models.INVALID_CREDENTIALS, "invalid Credentials"))
+		return
+	}
+
+	c.startLogin(ctx, timedContext, user, accountKey)
+}
+
+// startLogin is called once the first factor is verified, it asks for the second factor when the user enabled one
+func (c *UserController) startLogin(ctx *gin.Context, timedContext context.Context, user *models.User, accountKey string) {
+	// The session is only issued once the second factor is verified by VerifyMfaLogin
+	if user.IsMfaEnabled() {
+		mfaToken, err := utils.GenerateMfaPendingJwt(user)
+		if err != nil {
+			ctx.Error(models.NewApiError(models.UNAUTHORIZED, "unable to generate mfa token"))
+			return
+		}
+		ctx.JSON(http.StatusOK, gin.H{"mfaRequired": true, "mfaToken": mfaToken})
 		return
 	}
 
-	accessToken, err := utils.GenerateAccessJwt(user)
+	c.completeLogin(ctx, timedContext, user, accountKey)
+}
+
+// completeLogin clears the failed attempts of the account and responds with the tokens of a new session. The ip
+// counter is left to its failure window, one valid account must not reset the failures an ip made on other accounts
+func (c *UserController) completeLogin(ctx *gin.Context, timedContext context.Context, user *models.User, accountKey string) {
+	if err := c.LoginAttemptStore.ResetLoginAttempt(timedContext, accountKey); err != nil {
+		log.Printf("Could not reset login attempts for %s: %v", accountKey, err)
+	}
+
+	accessToken, refreshToken, err := c.issueSession(ctx, timedContext, user)
 	if err != nil {
-		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "unable to generate access token"})
+		ctx.Error(err)
 		return
 	}
 
-	refreshToken, err := utils.GenerateRefreshJwt(user)
+	user, err = c.UserRepository.GetUserProfileById(timedContext, user.ID)
 	if err != nil {
-		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "unable to generate refresh token"})
+		ctx.Error(models.NewApiError(models.UNAUTHORIZED, "unable to get user token"))
 		return
 	}
 
-	err = c.UserRepository.UpdateUser(ctx, user.ID, bson.M{"refreshToken": refreshToken})
+	ctx.JSON(http.StatusOK, gin.H{"accessToken": accessToken, "refreshToken": refreshToken, "user": user})
+}
+
+// isLoginThrottled writes the error response when the key is locked or still backing off
+func (c *UserController) isLoginThrottled(ctx *gin.Context, timedContext context.Context, key string, policy models.LoginPolicy) bool {
+	attempt, err := c.LoginAttemptStore.GetLoginAttempt(timedContext, key)
+	if err != nil {
+		log.Printf("Could not get login attempts for %s: %v", key, err)
+		return false
+	}
+	if attempt == nil {
+		return false
+	}
+
+	now := time.Now()
+	retryAfter := attempt.GetRetryAfter(policy, now)
+	if retryAfter <= 0 {
+		return false
+	}
+
+	retryAfterSeconds := int(math.Ceil(retryAfter.Seconds()))
+	ctx.Header("Retry-After", strconv.Itoa(retryAfterSeconds))
+	if attempt.IsLocked(now) {
+		ctx.Error(models.NewApiError(models.LOGIN_LOCKED, "Too many failed logins, login is locked. Check your email to unlock your account").WithDetails(map[string]any{"retryAfterSeconds": retryAfterSeconds}))
+	} else {
+		ctx.Error(models.NewApiError(models.TOO_MANY_ATTEMPTS, "Too many failed logins, please try again later").WithDetails(map[string]any{"retryAfterSeconds": retryAfterSeconds}))
+	}
+	return true
+}
+
+// recordLoginFailure counts the failure against the account and the ip, locking either once it crosses its threshold.
+// The user is nil when the email is not registered.
+func (c *UserController) recordLoginFailure(ctx *gin.Context, timedContext context.Context, user *models.User, accountKey string, ipKey string) {
+	now := time.Now()
+
+	accountAttempt, err := c.LoginAttemptStore.RecordFailure(timedContext, accountKey, models.AccountLoginPolicy, now)
 	if err != nil {
-		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "unable to save refresh token"})
+		log.Printf("Could not record login failure for %s: %v", accountKey, err)
+	} else if accountAttempt.ShouldLock(models.AccountLoginPolicy, now) {
+		c.lockLogin(timedContext, accountKey, models.AccountLoginPolicy, models.AUDIT_ACCOUNT_LOCKED, user, ctx.ClientIP(), accountAttempt.FailedCount)
+
+		if user != nil {
+			if err := c.sendUserToken(timedContext, user, models.ACCOUNT_UNLOCK); err != nil {
+				log.Printf("Could not send unlock email to %s: %v", user.Email, err)
+			}
+		}
+	}
+
+	ipAttempt, err := c.LoginAttemptStore.RecordFailure(timedContext, ipKey, models.IpLoginPolicy, now)
+	if err != nil {
+		log.Printf("Could not record login failure for %s: %v", ipKey, err)
+	} else if ipAttempt.ShouldLock(models.IpLoginPolicy, now) {
+		c.lockLogin(timedContext, ipKey, models.IpLoginPolicy, models.AUDIT_IP_LOCKED, nil, ctx.ClientIP(), ipAttempt.FailedCount)
+	}
+}
+
+func (c *UserController) lockLogin(timedContext context.Context, key string, policy models.LoginPolicy, action models.AuditAction, user *models.User, ip string, failedCount int) {
+	lockedUntil := time.Now().Add(policy.LockoutDuration)
+	if err := c.LoginAttemptStore.LockUntil(timedContext, key, lockedUntil); err != nil {
+		log.Printf("Could not lock login for %s: %v", key, err)
+		return
+	}
+
+	auditLog := models.AuditLog{
+		Action:  action,
+		IP:      ip,
+		Details: map[string]any{"key": key, "failedCount": failedCount, "lockedUntil": lockedUntil},
+	}
+	if user != nil {
+		auditLog.UserId = user.ID
+	}
+	if err := c.AuditRepository.CreateAuditLog(timedContext, &auditLog); err != nil {
+		log.Printf("Could not write audit log for %s: %v", key, err)
+	}
+}
+
+func (c *UserController) UnlockAccount(ctx *gin.Context) {
+	token := ctx.PostForm("token")
+	if token == "" {
+		ctx.Error(models.NewApiError(models.INVALID_REQUEST, "missing body params"))
 		return
 	}
 
-	user, err = c.UserRepository.GetUserProfileById(ctx, user.ID)
+	timedContext, cancel := config.GetTimedContext()
+	defer cancel()
+
+	userToken, err := c.UserTokenRepository.ConsumeUserToken(timedContext, models.ACCOUNT_UNLOCK, utils.HashToken(token))
 	if err != nil {
-		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "unable to get user token"})
+		ctx.Error(models.NewApiError(models.INVALID_LINK, "Unlock link is invalid or has expired"))
 		return
 	}
 
-	ctx.JSON(http.StatusOK, gin.H{"accessToken": accessToken, "refreshToken": refreshToken, "user": user})
+	user, err := c.UserRepository.GetUserProfileById(timedContext, userToken.UserId)
+	if err != nil {
+		ctx.Error(models.NewApiError(models.USER_NOT_FOUND, "User not found"))
+		return
+	}
+
+	accountKey := models.LOGIN_ACCOUNT_KEY_PREFIX + strings.ToLower(user.Email)
+	err = c.LoginAttemptStore.ResetLoginAttempt(timedContext, accountKey)
+	if err != nil {
+		ctx.Error(models.NewApiError(models.INTERNAL_ERROR, "Could not unlock account").WithCause(err))
+		return
+	}
+
+	auditLog := models.AuditLog{UserId: user.ID, Action: models.AUDIT_ACCOUNT_UNLOCKED, IP: ctx.ClientIP(), Details: map[string]any{"key": accountKey}}
+	if err := c.AuditRepository.CreateAuditLog(timedContext, &auditLog); err != nil {
+		log.Printf("Could not write audit log for %s: %v", accountKey, err)
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Account unlocked, you can login again"})
+}
+
+// issueSession creates a session for the requesting device and returns its first access and refresh token
+func (c *UserController) issueSession(ctx *gin.Context, timedContext context.Context, user *models.User) (string, string, error) {
+	now := time.Now()
+	session := models.Session{
+		ID:         primitive.NewObjectID(),
+		UserId:     user.ID,
+		DeviceName: ctx.PostForm("deviceName"),
+		IP:         ctx.ClientIP(),
+		UserAgent:  ctx.Request.UserAgent(),
+		CreatedAt:  now,
+		LastUsedAt: now,
+		ExpiresAt:  now.Add(utils.RefreshTokenValidity),
+	}
+	if session.DeviceName == "" {
+		session.DeviceName = session.UserAgent
+	}
+
+	accessToken, err := utils.GenerateAccessJwt(user, session.ID)
+	if err != nil {
+		return "", "", errors.New("unable to generate access token")
+	}
+
+	refreshToken, err := utils.GenerateRefreshJwt(user, session.ID)
+	if err != nil {
+		return "", "", errors.New("unable to generate refresh token")
+	}
+	session.RefreshTokenHash = utils.HashToken(refreshToken)
+
+	err = c.SessionRepository.CreateSession(timedContext, &session)
+	if err != nil {
+		return "", "", errors.New("unable to save refresh token")
+	}
+
+	return accessToken, refreshToken, nil
 }
 
 func (c *UserController) RequestAccessToken(ctx *gin.Context) {
-	emailId := ctx.PostForm("emailId")
 	refreshToken := ctx.PostForm("refreshToken")
-	if refreshToken == "" || emailId == "" {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "missing body params"})
+	if refreshToken == "" {
+		ctx.Error(models.NewApiError(models.INVALID_REQUEST, "missing body params"))
+		return
+	}
+
+	claims, err := utils.ParseRefreshToken(refreshToken)
+	if err != nil {
+		ctx.Error(models.NewApiError(models.UNAUTHORIZED, "refresh token invalid"))
 		return
 	}
 
 	timedContext, cancel := config.GetTimedContext()
 	defer cancel()
 
-	user, err := c.UserRepository.GetUserByEmail(timedContext, emailId)
+	session, err := c.SessionRepository.GetSessionById(timedContext, claims.SessionId)
+	if err != nil || session.UserId != claims.UserId || !session.IsActive(time.Now()) {
+		ctx.Error(models.NewApiError(models.UNAUTHORIZED, "session expired or revoked"))
+		return
+	}
+
+	// A correctly signed token that is no longer current was already rotated, so it has leaked or been replayed
+	currentHash := utils.HashToken(refreshToken)
+	if session.RefreshTokenHash != currentHash {
+		c.revokeReusedSession(timedContext, session)
+		ctx.Error(models.NewApiError(models.INVALID_CREDENTIALS, "invalid credentials"))
+		return
+	}
+
+	user, err := c.UserRepository.GetUserProfileById(timedContext, claims.UserId)
 	if err != nil {
-		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		ctx.Error(models.NewApiError(models.INVALID_CREDENTIALS, "user not found"))
 		return
 	}
 
-	if !utils.IsRefreshTokenValid(refreshToken) {
-		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "refresh token invalid"})
+	token, err := utils.GenerateAccessJwt(user, session.ID)
+	if err != nil {
+		ctx.Error(models.NewApiError(models.UNAUTHORIZED, "unable to generate access token"))
 		return
 	}
 
-	if user.RefreshToken != refreshToken {
-		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
+	newRefreshToken, err := utils.GenerateRefreshJwt(user, session.ID)
+	if err != nil {
+		ctx.Error(models.NewApiError(models.UNAUTHORIZED, "unable to generate refresh token"))
 		return
 	}
 
-	token, err := utils.GenerateAccessJwt(user)
+	err = c.SessionRepository.RotateRefreshToken(timedContext, session.ID, currentHash, utils.HashToken(newRefreshToken),
+		time.Now().Add(utils.RefreshTokenValidity), ctx.ClientIP(), ctx.Request.UserAgent())
+	if err == mongo.ErrNoDocuments {
+		// Another request rotated the same token first
+		c.revokeReusedSession(timedContext, session)
+		ctx.Error(models.NewApiError(models.INVALID_CREDENTIALS, "invalid credentials"))
+		return
+	}
 	if err != nil {
-		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "unable to generate access toke"})
+		ctx.Error(models.NewApiError(models.INTERNAL_ERROR, "unable to rotate refresh token").WithCause(err))
 		return
 	}
 
-	ctx.JSON(http.StatusOK, gin.H{"accessToken": token, "refreshToken": refreshToken})
+	ctx.JSON(http.StatusOK, gin.H{"accessToken": token, "refreshToken": newRefreshToken})
+}
+
+func (c *UserController) revokeReusedSession(timedContext context.Context, session *models.Session) {
+	log.Printf("Refresh token reuse detected for session %s of user %s", session.ID.Hex(), session.UserId.Hex())
+	if err := c.SessionRepository.RevokeSession(timedContext, session.UserId, session.ID, models.SESSION_TOKEN_REUSE); err != nil {
+		log.Printf("Could not revoke session %s: %v", session.ID.Hex(), err)
+	}
 }
 
 func (c *UserController) LogoutUser(ctx *gin.Context) {
-	emailId := ctx.PostForm("emailId")
-	if emailId == "" {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "missing body params"})
+	timedContext, cancel := config.GetTimedContext()
+	defer cancel()
+
+	err := c.SessionRepository.RevokeSession(timedContext, middleware.GetUserId(ctx), middleware.GetSessionId(ctx), models.SESSION_LOGOUT)
+	if err != nil {
+		ctx.Error(models.NewApiError(models.UNAUTHORIZED, "could not revoke"))
 		return
 	}
 
+	ctx.JSON(http.StatusOK, gin.H{"message": "Logout user"})
+}
+
+func (c *UserController) GetSessions(ctx *gin.Context) {
 	timedContext, cancel := config.GetTimedContext()
 	defer cancel()
 
-	user, err := c.UserRepository.GetUserByEmail(timedContext, emailId)
+	sessions, err := c.SessionRepository.GetActiveSessions(timedContext, middleware.GetUserId(ctx))
 	if err != nil {
-		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		ctx.Error(models.NewApiError(models.INTERNAL_ERROR, "Could not get sessions").WithCause(err))
 		return
 	}
-	err = c.UserRepository.UpdateUser(timedContext, user.ID, bson.M{"refreshToken": ""})
+
+	currentSessionId := middleware.GetSessionId(ctx)
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == currentSessionId
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"sessions": sessions})
+}
+
+// revokeSession revokes one session of the caller, writing the error response when it fails
+func (c *UserController) revokeSession(ctx *gin.Context, sessionId primitive.ObjectID) bool {
+	timedContext, cancel := config.GetTimedContext()
+	defer cancel()
+
+	err := c.SessionRepository.RevokeSession(timedContext, middleware.GetUserId(ctx), sessionId, models.SESSION_REVOKED)
+	if err == mongo.ErrNoDocuments {
+		ctx.Error(models.NewApiError(models.SESSION_NOT_FOUND, "Session not found"))
+		return false
+	}
 	if err != nil {
-		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "could not revoke"})
+		ctx.Error(models.NewApiError(models.INTERNAL_ERROR, "Could not revoke session").WithCause(err))
+		return false
+	}
+	return true
+}
+
+func (c *UserController) RevokeSession(ctx *gin.Context) {
+	ids, ok := getQueryIds(ctx, "sessionId")
+	if !ok || !c.revokeSession(ctx, ids[0]) {
 		return
 	}
 
-	ctx.JSON(http.StatusOK, gin.H{"message": "Logout user"})
+	ctx.JSON(http.StatusOK, gin.H{"message": "Session revoked"})
+}
+
+// DeleteSession is the v1 revoke of one session of the caller
+func (c *UserController) DeleteSession(ctx *gin.Context) {
+	ids, ok := getPathIds(ctx, "sessionId")
+	if !ok || !c.revokeSession(ctx, ids[0]) {
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// revokeOtherSessions revokes every session of the caller but the current one, writing the error response when it fails
+func (c *UserController) revokeOtherSessions(ctx *gin.Context) bool {
+	timedContext, cancel := config.GetTimedContext()
+	defer cancel()
+
+	err := c.SessionRepository.RevokeUserSessions(timedContext, middleware.GetUserId(ctx), middleware.GetSessionId(ctx), models.SESSION_REVOKED)
+	if err != nil {
+		ctx.Error(models.NewApiError(models.INTERNAL_ERROR, "Could not revoke sessions").WithCause(err))
+		return false
+	}
+	return true
+}
+
+func (c *UserController) RevokeOtherSessions(ctx *gin.Context) {
+	if !c.revokeOtherSessions(ctx) {
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Other sessions revoked"})
+}
+
+// DeleteOtherSessions is the v1 revoke of every session of the caller but the current one
+func (c *UserController) DeleteOtherSessions(ctx *gin.Context) {
+	if !c.revokeOtherSessions(ctx) {
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// updateUser stores the profile fields set in user, writing the error response when it fails
+func (c *UserController) updateUser(ctx *gin.Context, user models.User) bool {
+	if !middleware.IsSelfOrAdmin(ctx, user.ID) {
+		ctx.Error(models.NewApiError(models.FORBIDDEN, "You do not have access to this user"))
+		return false
+	}
+
+	timedContext, cancel := config.GetTimedContext()
+	defer cancel()
+
+	err := c.UserService.UpdateProfile(timedContext, user)
+	if err != nil {
+		ctx.Error(err)
+		return false
+	}
+	return true
 }
 
 func (c *UserController) UpdateUser(ctx *gin.Context) {
 	var user models.User
 	if err := ctx.ShouldBindJSON(&user); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		ctx.Error(models.NewApiError(models.INVALID_REQUEST, "Invalid request format"))
+		return
+	}
+
+	if !c.updateUser(ctx, user) {
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{"message": "User updated successfully"})
+}
+
+// UpdateMe is the v1 partial update of the profile of the caller, answering with the updated profile
+func (c *UserController) UpdateMe(ctx *gin.Context) {
+	var user models.User
+	if err := ctx.ShouldBindJSON(&user); err != nil {
+		ctx.Error(models.NewApiError(models.INVALID_REQUEST, "Invalid request format"))
+		return
+	}
+	user.ID = middleware.GetUserId(ctx)
+
+	if !c.updateUser(ctx, user) {
+		return
+	}
+	c.GetMe(ctx)
+}
+
+func (c *UserController) GetUser(ctx *gin.Context) {
+	emailId, error := ctx.GetQuery("emailId")
+	if !error {
+		ctx.Error(models.NewApiError(models.INVALID_REQUEST, "Invalid request format"))
+		return
+	}
+	if !strings.EqualFold(emailId, middleware.GetEmail(ctx)) && middleware.GetRole(ctx) != models.ROLE_ADMIN {
+		ctx.Error(models.NewApiError(models.FORBIDDEN, "You do not have access to this user"))
 		return
 	}
 
 	timedContext, cancel := config.GetTimedContext()
 	defer cancel()
 
-	update := bson.M{}
+	user, err := c.UserService.GetProfileByEmail(timedContext, emailId)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{"user": user})
+}
+
+// GetMe is the v1 profile of the caller
+func (c *UserController) GetMe(ctx *gin.Context) {
+	timedContext, cancel := config.GetTimedContext()
+	defer cancel()
+
+	user, err := c.UserService.GetProfile(timedContext, middleware.GetUserId(ctx))
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, user)
+}
+
+func (c *UserController) GetDietOptions(ctx *gin.Context) {
+	dietRules := make([]models.DietRule, 0, len(models.DietPatterns))
+	for _, pattern := range models.DietPatterns {
+		dietRules = append(dietRules, models.DietRules[pattern])
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"dietPatterns": dietRules, "cuisines": models.Cuisines, "maxMealsPerDay": models.MAX_MEALS_PER_DAY})
+}
+
+// sendUserToken issues a new single use token, invalidating older ones of the same purpose, and mails its link to the user
+func (c *UserController) sendUserToken(timedContext context.Context, user *models.User, purpose models.TokenPurpose) error {
+	err := c.UserTokenRepository.InvalidateUserTokens(timedContext, user.ID, purpose)
+	if err != nil {
+		return err
+	}
+
+	token := utils.GenerateRandomToken(32)
+	now := time.Now()
+	userToken := models.UserToken{
+		UserId:    user.ID,
+		Purpose:   purpose,
+		TokenHash: utils.HashToken(token),
+		CreatedAt: now,
+		ExpiresAt: now.Add(models.TokenValidity[purpose]),
+	}
+	err = c.UserTokenRepository.CreateUserToken(timedContext, &userToken)
+	if err != nil {
+		return err
+	}
+
+	baseUrl := config.GetConfig().AppBaseUrl
+	var subject, body string
+	switch purpose {
+	case models.PASSWORD_RESET:
+		subject = "Reset your FitEats password"
+		body = fmt.Sprintf("Hi %s,\n\nUse the link below to reset your password. It is valid for one hour.\n\n%s/reset-password?token=%s\n\nIf you did not ask for a password reset you can ignore this email.",
+			user.Name, baseUrl, token)
+	case models.ACCOUNT_UNLOCK:
+		subject = "Your FitEats account has been locked"
+		body = fmt.Sprintf("Hi %s,\n\nWe locked logins to your account after too many failed attempts. If this was you, use the link below to unlock it. It is valid for one hour.\n\n%s/unlock-account?token=%s\n\nIf this was not you, consider resetting your password.",
+			user.Name, baseUrl, token)
+	case models.EMAIL_VERIFICATION:
+		subject = "Verify your FitEats email"
+		body = fmt.Sprintf("Hi %s,\n\nUse the link below to verify your email. It is valid for 24 hours.\n\n%s/verify-email?token=%s",
+			user.Name, baseUrl, token)
+	}
+
+	return c.Mailer.Send(timedContext, user.Email, subject, body)
+}
+
+func (c *UserController) RequestPasswordReset(ctx *gin.Context) {
+	email := ctx.PostForm("email")
+	if email == "" {
+		ctx.Error(models.NewApiError(models.INVALID_REQUEST, "missing body params"))
+		return
+	}
+
+	// Mail servers can be slow to answer
+	timedContext, cancel := config.GetTimedContext(30)
+	defer cancel()
+
+	// The response is the same whether or not the account exists, so the endpoint cannot be used to find registered emails
+	user, err := c.UserRepository.GetUserProfileByEmailId(timedContext, email)
+	if err == nil {
+		middleware.SetAuditUserId(ctx, user.ID)
+		err = c.sendUserToken(timedContext, user, models.PASSWORD_RESET)
+		if err != nil {
+			log.Printf("Could not send password reset email to %s: %v", email, err)
+		}
+	}
 
-	if user.Name != "" {
-		update["name"] = user.Name
+	ctx.JSON(http.StatusOK, gin.H{"message": "If an account exists for this email, a password reset link has been sent"})
+}
+
+func (c *UserController) ConfirmPasswordReset(ctx *gin.Context) {
+	token := ctx.PostForm("token")
+	password := ctx.PostForm("password")
+	if token == "" || password == "" {
+		ctx.Error(models.NewApiError(models.INVALID_REQUEST, "missing body params"))
+		return
 	}
-	if user.Age != "" {
-		update["age"] = user.Age
+	if len(password) < 6 {
+		ctx.Error(models.NewValidationError(map[string]string{"password": "password must be at least 6 characters long"}))
+		return
 	}
-	if user.Sex != "" {
-		update["sex"] = user.Sex
+
+	timedContext, cancel := config.GetTimedContext()
+	defer cancel()
+
+	userToken, err := c.UserTokenRepository.ConsumeUserToken(timedContext, models.PASSWORD_RESET, utils.HashToken(token))
+	if err != nil {
+		ctx.Error(models.NewApiError(models.INVALID_LINK, "Reset link is invalid or has expired"))
+		return
+	}
+
+	hashedPassword, err := utils.GeneratePasswordHashFromPlainText(password)
+	if err != nil {
+		ctx.Error(models.NewApiError(models.INTERNAL_ERROR, "Could not generate user password").WithCause(err))
+		return
 	}
-	if user.HeightInCm != 0 {
-		update["heightInCm"] = user.HeightInCm
+
+	// Receiving the reset mail proves ownership of the email as well
+	err = c.UserRepository.UpdateUser(timedContext, userToken.UserId, bson.M{"password": hashedPassword, "emailVerified": true})
+	if err != nil {
+		ctx.Error(models.NewApiError(models.INTERNAL_ERROR, "Could not update password").WithCause(err))
+		return
 	}
-	if user.Country != "" {
-		update["country"] = user.Country
+
+	// Sign out every device, whoever knew the old password should not stay logged in
+	err = c.SessionRepository.RevokeUserSessions(timedContext, userToken.UserId, primitive.NilObjectID, models.SESSION_PASSWORD_RESET)
+	if err != nil {
+		log.Printf("Could not revoke sessions of user %s: %v", userToken.UserId.Hex(), err)
 	}
-	if user.DietPreference != "" {
-		update["dietPreference"] = user.DietPreference
+
+	// A new password also lifts a lockout caused by guessing the old one
+	user, err := c.UserRepository.GetUserProfileById(timedContext, userToken.UserId)
+	if err == nil {
+		if err := c.LoginAttemptStore.ResetLoginAttempt(timedContext, models.LOGIN_ACCOUNT_KEY_PREFIX+strings.ToLower(user.Email)); err != nil {
+			log.Printf("Could not reset login attempts of user %s: %v", user.ID.Hex(), err)
+		}
 	}
 
-	if len(update) == 0 {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Could not update user"})
-		return // Nothing to update
+	middleware.SetAuditUserId(ctx, userToken.UserId)
+	ctx.JSON(http.StatusOK, gin.H{"message": "Password updated successfully"})
+}
+
+func (c *UserController) SendVerificationEmail(ctx *gin.Context) {
+	// Mail servers can be slow to answer
+	timedContext, cancel := config.GetTimedContext(30)
+	defer cancel()
+
+	user, err := c.UserRepository.GetUserProfileById(timedContext, middleware.GetUserId(ctx))
+	if err != nil {
+		ctx.Error(models.NewApiError(models.USER_NOT_FOUND, "User not found"))
+		return
+	}
+	if user.EmailVerified {
+		ctx.Error(models.NewApiError(models.INVALID_STATE, "Email is already verified"))
+		return
 	}
 
-	// Register user
-	err := c.UserRepository.UpdateUser(timedContext, user.ID, update)
+	err = c.sendUserToken(timedContext, user, models.EMAIL_VERIFICATION)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Could not update user"})
+		ctx.Error(models.NewApiError(models.INTERNAL_ERROR, "Could not send verification email").WithCause(err))
 		return
 	}
 
-	ctx.JSON(http.StatusCreated, gin.H{"message": "User updated successfully"})
+	ctx.JSON(http.StatusOK, gin.H{"message": "Verification email sent"})
 }
 
-func (c *UserController) GetUser(ctx *gin.Context) {
-	emailId, error := ctx.GetQuery("emailId")
-	if !error {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+func (c *UserController) VerifyEmail(ctx *gin.Context) {
+	token := ctx.PostForm("token")
+	if token == "" {
+		ctx.Error(models.NewApiError(models.INVALID_REQUEST, "missing body params"))
 		return
 	}
 
 	timedContext, cancel := config.GetTimedContext()
 	defer cancel()
 
-	// Get user
-	user, err := c.UserRepository.GetUserProfileByEmailId(timedContext, emailId)
+	userToken, err := c.UserTokenRepository.ConsumeUserToken(timedContext, models.EMAIL_VERIFICATION, utils.HashToken(token))
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Could not get user"})
+		ctx.Error(models.NewApiError(models.INVALID_LINK, "Verification link is invalid or has expired"))
 		return
 	}
 
-	ctx.JSON(http.StatusCreated, gin.H{"user": user})
+	err = c.UserRepository.UpdateUser(timedContext, userToken.UserId, bson.M{"emailVerified": true})
+	if err != nil {
+		ctx.Error(models.NewApiError(models.INTERNAL_ERROR, "Could not verify email").WithCause(err))
+		return
+	}
+
+	middleware.SetAuditUserId(ctx, userToken.UserId)
+	ctx.JSON(http.StatusOK, gin.H{"message": "Email verified successfully"})
+}
+
+// ProfileSnapshot is the audit snapshot of the profile sent to UpdateUser, the profile of the caller when the body has no id
+func (c *UserController) ProfileSnapshot(ctx *gin.Context, timedContext context.Context) (primitive.ObjectID, any, error) {
+	var user models.User
+	if err := middleware.PeekJSONBody(ctx, &user); err != nil {
+		return primitive.NilObjectID, nil, nil
+	}
+	if user.ID.IsZero() {
+		user.ID = middleware.GetUserId(ctx)
+	}
+
+	profile, err := c.UserRepository.GetUserProfileById(timedContext, user.ID)
+	if err == mongo.ErrNoDocuments {
+		return user.ID, nil, nil
+	}
+	return user.ID, profile, err
 }
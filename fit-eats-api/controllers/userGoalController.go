@@ -1,47 +1,61 @@
 package controllers
 
 import (
-	"encoding/json"
+	"context"
 	"fit-eats-api/config"
+	"fit-eats-api/middleware"
 	"fit-eats-api/models"
-	"fit-eats-api/repositories"
-	"fit-eats-api/utils"
+	"fit-eats-api/services"
 	"fmt"
 	"net/http"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
-	"github.com/google/generative-ai-go/genai"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
 type UserGoalController struct {
-	UserRepository     *repositories.UserRepository
-	UserGoalRepository *repositories.UserGoalRepository
+	GoalService *services.GoalService
+	UserAccess  *middleware.UserAccess
 }
 
-func NewUserGoalController(userRepository *repositories.UserRepository, userGoalRepository *repositories.UserGoalRepository) *UserGoalController {
-	return &UserGoalController{UserRepository: userRepository, UserGoalRepository: userGoalRepository}
+func NewUserGoalController(goalService *services.GoalService, userAccess *middleware.UserAccess) *UserGoalController {
+	return &UserGoalController{GoalService: goalService, UserAccess: userAccess}
+}
+
+// canAccessGoal checks that the caller may act on the owner of the main goal, writing the error response when not
+func (c *UserGoalController) canAccessGoal(ctx *gin.Context, timedContext context.Context, goalId primitive.ObjectID) bool {
+	ownerId, err := c.GoalService.GetGoalOwnerId(timedContext, goalId)
+	if err != nil {
+		ctx.Error(err)
+		return false
+	}
+	if !c.UserAccess.CanAccess(ctx, ownerId, true) {
+		ctx.Error(models.NewApiError(models.FORBIDDEN, "You do not have access to this user"))
+		return false
+	}
+	return true
 }
 
 func (c *UserGoalController) GetActiveUserGoal(ctx *gin.Context) {
 	userId, error := ctx.GetQuery("userId")
 	if !error {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		ctx.Error(models.NewApiError(models.INVALID_REQUEST, "Invalid request format"))
 		return
 	}
 	mongoUserId, error1 := primitive.ObjectIDFromHex(userId)
 	if error1 != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		ctx.Error(models.NewApiError(models.INVALID_REQUEST, "Invalid request format"))
 		return
 	}
 
 	timedContext, cancel := config.GetTimedContext()
 	defer cancel()
 
-	mainGoal, err := c.UserGoalRepository.GetUserActiveGoalByUserId(timedContext, mongoUserId)
+	mainGoal, err := c.GoalService.GetActiveGoal(timedContext, mongoUserId)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Could not get active weekly goal"})
+		ctx.Error(err)
 		return
 	}
 
@@ -51,579 +65,533 @@ func (c *UserGoalController) GetActiveUserGoal(ctx *gin.Context) {
 func (c *UserGoalController) GetUserGoals(ctx *gin.Context) {
 	userId, error := ctx.GetQuery("userId")
 	if !error {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		ctx.Error(models.NewApiError(models.INVALID_REQUEST, "Invalid request format"))
 		return
 	}
 	mongoUserId, error1 := primitive.ObjectIDFromHex(userId)
 	if error1 != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		ctx.Error(models.NewApiError(models.INVALID_REQUEST, "Invalid request format"))
 		return
 	}
 
 	timedContext, cancel := config.GetTimedContext()
 	defer cancel()
 
-	mainGoal, err := c.UserGoalRepository.GetUserGoalByUserId(timedContext, mongoUserId)
+	mainGoal, err := c.GoalService.GetUserGoal(timedContext, mongoUserId)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Could not get main goal"})
+		ctx.Error(err)
 		return
 	}
 
 	ctx.JSON(http.StatusOK, gin.H{"userGoals": mainGoal})
 }
 
-func (c *UserGoalController) RegisterUserGoal(ctx *gin.Context) {
-	var userGoal models.Goal
-	if err := ctx.ShouldBindJSON(&userGoal); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+// GetGoals is the v1 list of the goals of the user, the caller unless a userId is given. The status query param lists
+// the goals in that status, archived goals are only listed this way
+func (c *UserGoalController) GetGoals(ctx *gin.Context) {
+	userId, ok := getUserIdOrSelf(ctx)
+	if !ok {
 		return
 	}
 
-	errors := utils.ValidateStruct(userGoal)
-	if errors != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": errors})
+	status := models.GoalStatus(ctx.Query("status"))
+	switch status {
+	case "", models.GOAL_ACTIVE, models.GOAL_COMPLETED, models.GOAL_ABANDONED, models.GOAL_ARCHIVED:
+	default:
+		ctx.Error(models.NewApiError(models.INVALID_REQUEST, "Invalid status: must be one of Active, Completed, Abandoned or Archived"))
 		return
 	}
 
 	timedContext, cancel := config.GetTimedContext()
 	defer cancel()
 
-	goal, _ := c.UserGoalRepository.GetUserGoalByUserId(timedContext, userGoal.UserId)
-	if goal != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Main goal is already created!"})
-		return
-	}
-
-	// Register user
-	err := c.UserGoalRepository.CreateMainUserGoal(timedContext, &userGoal)
+	goals, err := c.GoalService.GetGoals(timedContext, userId, status)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Could not register goal: " + err.Error()})
+		ctx.Error(err)
 		return
 	}
 
-	ctx.JSON(http.StatusCreated, gin.H{"message": "Goal registered successfully"})
+	ctx.JSON(http.StatusOK, gin.H{"goals": goals})
 }
 
-func (c *UserGoalController) RegisterWeeklyUserGoal(ctx *gin.Context) {
-	var userGoal models.WeeklyGoal
-	if err := ctx.ShouldBindJSON(&userGoal); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
-		return
-	}
-
-	errors := utils.ValidateStruct(userGoal)
-	if errors != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"errors": errors})
-		return
-	}
-
-	mainGoalIdStr := ctx.Query("mainGoalId")
-	if mainGoalIdStr == "" {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "mainGoalId is required"})
-		return
-	}
-	mainGoalIdMongo, err1 := primitive.ObjectIDFromHex(mainGoalIdStr)
-	if err1 != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "mainGoalId is invalid"})
+// GetActiveGoal is the v1 goal of the user with only the weekly goal running today
+func (c *UserGoalController) GetActiveGoal(ctx *gin.Context) {
+	userId, ok := getUserIdOrSelf(ctx)
+	if !ok {
 		return
 	}
 
 	timedContext, cancel := config.GetTimedContext()
 	defer cancel()
 
-	// Register user
-	err := c.UserGoalRepository.CreateWeeklyUserGoal(timedContext, mainGoalIdMongo, &userGoal)
+	goal, err := c.GoalService.GetActiveGoal(timedContext, userId)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Could not register goal"})
+		ctx.Error(err)
 		return
 	}
 
-	ctx.JSON(http.StatusCreated, gin.H{"message": "Goal registered successfully"})
+	ctx.JSON(http.StatusOK, goal)
 }
 
-func (c *UserGoalController) DeleteUserMainGoal(ctx *gin.Context) {
-	goalId, error := ctx.GetQuery("goalId")
-	if !error {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
-		return
-	}
-	mongoGoalId, error1 := primitive.ObjectIDFromHex(goalId)
-	if error1 != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+func (c *UserGoalController) GetGoal(ctx *gin.Context) {
+	ids, ok := getPathIds(ctx, "goalId")
+	if !ok {
 		return
 	}
 
 	timedContext, cancel := config.GetTimedContext()
 	defer cancel()
 
-	// Register user
-	err := c.UserGoalRepository.DeleteMainUserGoal(timedContext, mongoGoalId)
-	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Could not get goal"})
+	if !c.canAccessGoal(ctx, timedContext, ids[0]) {
 		return
 	}
 
-	ctx.JSON(http.StatusMovedPermanently, gin.H{"success": true})
-}
-func (c *UserGoalController) DeleteUserWeeklyGoal(ctx *gin.Context) {
-	goalId, error := ctx.GetQuery("goalId")
-	if !error {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
-		return
-	}
-	mongoGoalId, error1 := primitive.ObjectIDFromHex(goalId)
-	if error1 != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+	goal, err := c.GoalService.GetGoal(timedContext, ids[0])
+	if err != nil {
+		ctx.Error(err)
 		return
 	}
 
-	weeklyGoalId, error := ctx.GetQuery("weeklyGoalId")
-	if !error {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
-		return
-	}
-	mongoWeeklyGoalId, error1 := primitive.ObjectIDFromHex(weeklyGoalId)
-	if error1 != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+	ctx.JSON(http.StatusOK, goal)
+}
+
+func (c *UserGoalController) GetWeeklyGoal(ctx *gin.Context) {
+	ids, ok := getPathIds(ctx, "goalId", "weeklyGoalId")
+	if !ok {
 		return
 	}
 
 	timedContext, cancel := config.GetTimedContext()
 	defer cancel()
 
-	// Register user
-	err := c.UserGoalRepository.DeleteWeeklyUserGoal(timedContext, mongoGoalId, mongoWeeklyGoalId)
+	if !c.canAccessGoal(ctx, timedContext, ids[0]) {
+		return
+	}
+
+	weeklyGoal, err := c.GoalService.GetWeeklyGoal(timedContext, ids[0], ids[1])
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Could not get goal"})
+		ctx.Error(err)
 		return
 	}
 
-	ctx.JSON(http.StatusMovedPermanently, gin.H{"success": true})
+	ctx.JSON(http.StatusOK, weeklyGoal)
 }
 
-func (c *UserGoalController) GetIdealWeightRange(ctx *gin.Context) {
-	requiredFields := []string{"userId", "currentWeightInKg", "currentBodyFatPercentage"}
-	values := make(map[string]string)
-
-	for _, field := range requiredFields {
-		value, ok := ctx.GetQuery(field)
-		if !ok {
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid request format: missing %s", field)})
-			return
-		}
-		values[field] = value
+// registerGoal stores the main goal of a user the caller may access, writing the error response when it fails
+func (c *UserGoalController) registerGoal(ctx *gin.Context, userGoal *models.Goal) bool {
+	if !c.UserAccess.CanAccess(ctx, userGoal.UserId, true) {
+		ctx.Error(models.NewApiError(models.FORBIDDEN, "You do not have access to this user"))
+		return false
 	}
 
-	mongoUserIdStr := values["userId"]
-	currentWeightInKgStr := values["currentWeightInKg"]
-	currentBodyFatPercentageStr := values["currentBodyFatPercentage"]
+	timedContext, cancel := config.GetTimedContext()
+	defer cancel()
 
-	mongoUserId, err := primitive.ObjectIDFromHex(mongoUserIdStr)
+	err := c.GoalService.CreateGoal(timedContext, userGoal)
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid userId format: must be a valid ObjectId"})
-		return
+		ctx.Error(err)
+		return false
 	}
+	return true
+}
 
-	currentWeightInKg, err := strconv.ParseFloat(currentWeightInKgStr, 32)
-	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid currentWeightInKg format: must be a number"})
+func (c *UserGoalController) RegisterUserGoal(ctx *gin.Context) {
+	var userGoal models.Goal
+	if err := ctx.ShouldBindJSON(&userGoal); err != nil {
+		ctx.Error(models.NewApiError(models.INVALID_REQUEST, "Invalid request format"))
 		return
 	}
 
-	if currentWeightInKg < 30 || currentWeightInKg > 250 {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "currentWeightInKg must be between 30 and 250"})
+	if !c.registerGoal(ctx, &userGoal) {
 		return
 	}
 
-	currentBodyFatPercentage, err := strconv.ParseFloat(currentBodyFatPercentageStr, 32)
-	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid currentBodyFatPercentage format: must be a number"})
+	ctx.JSON(http.StatusCreated, gin.H{"message": "Goal registered successfully"})
+}
+
+// CreateGoal is the v1 create of the main goal, for the caller unless the body names a client
+func (c *UserGoalController) CreateGoal(ctx *gin.Context) {
+	var userGoal models.Goal
+	if err := ctx.ShouldBindJSON(&userGoal); err != nil {
+		ctx.Error(models.NewApiError(models.INVALID_REQUEST, "Invalid request format"))
 		return
 	}
+	if userGoal.UserId.IsZero() {
+		userGoal.UserId = middleware.GetUserId(ctx)
+	}
 
-	if currentBodyFatPercentage < 10 || currentBodyFatPercentage > 80 {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "currentBodyFatPercentage must be between 10 and 80"})
+	if !c.registerGoal(ctx, &userGoal) {
 		return
 	}
 
+	respondCreated(ctx, "/goals/"+userGoal.ID.Hex(), userGoal)
+}
+
+// registerWeeklyGoal adds the weekly goal to a main goal the caller may access, writing the error response when it fails
+func (c *UserGoalController) registerWeeklyGoal(ctx *gin.Context, mainGoalId primitive.ObjectID, userGoal *models.WeeklyGoal) bool {
 	timedContext, cancel := config.GetTimedContext()
 	defer cancel()
 
-	user, err := c.UserRepository.GetUserProfileById(timedContext, mongoUserId)
-	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "User not found"})
-		return
-	}
-	if !user.IsProfileComplete() {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Profile incomplete"})
-		return
+	if !c.canAccessGoal(ctx, timedContext, mainGoalId) {
+		return false
 	}
 
-	prompt := config.GetWeightRangePrompt(*user, float32(currentWeightInKg), float32(currentBodyFatPercentage))
-
-	resp, err := config.GetWeightRangeModel().GenerateContent(timedContext, genai.Text(prompt))
+	err := c.GoalService.CreateWeeklyGoal(timedContext, mainGoalId, userGoal)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate content: " + err.Error()})
-		return
+		ctx.Error(err)
+		return false
 	}
+	return true
+}
 
-	if len(resp.Candidates) == 0 || len(resp.Candidates[0].Content.Parts) == 0 {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "No content generated by the model"})
+func (c *UserGoalController) RegisterWeeklyUserGoal(ctx *gin.Context) {
+	var userGoal models.WeeklyGoal
+	if err := ctx.ShouldBindJSON(&userGoal); err != nil {
+		ctx.Error(models.NewApiError(models.INVALID_REQUEST, "Invalid request format"))
 		return
 	}
 
-	content, ok := resp.Candidates[0].Content.Parts[0].(genai.Text)
+	ids, ok := getQueryIds(ctx, "mainGoalId")
 	if !ok {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unexpected content format from the model"})
 		return
 	}
 
-	var result map[string]any
-	if err := json.Unmarshal([]byte(content), &result); err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unmarshal response to JSON: " + err.Error()})
+	if !c.registerWeeklyGoal(ctx, ids[0], &userGoal) {
 		return
 	}
 
-	ctx.JSON(http.StatusOK, result)
+	ctx.JSON(http.StatusCreated, gin.H{"message": "Goal registered successfully"})
 }
 
-func (c *UserGoalController) GetGoalDuration(ctx *gin.Context) {
-	requiredFields := []string{"userId", "currentWeightInKg", "goalWeightInKg", "currentBodyFatPercentage", "goalBodyFatPercentage"}
-	values := make(map[string]string)
-
-	for _, field := range requiredFields {
-		value, ok := ctx.GetQuery(field)
-		if !ok {
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid request format: missing %s", field)})
-			return
-		}
-		values[field] = value
+func (c *UserGoalController) CreateWeeklyGoal(ctx *gin.Context) {
+	ids, ok := getPathIds(ctx, "goalId")
+	if !ok {
+		return
 	}
 
-	mongoUserIdStr := values["userId"]
-	currentWeightInKgStr := values["currentWeightInKg"]
-	goalWeightInKgStr := values["goalWeightInKg"]
-	currentBodyFatPercentageStr := values["currentBodyFatPercentage"]
-	goalBodyFatPercentageStr := values["goalBodyFatPercentage"]
-
-	mongoUserId, err := primitive.ObjectIDFromHex(mongoUserIdStr)
-	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid userId format: must be a valid ObjectId"})
+	var userGoal models.WeeklyGoal
+	if err := ctx.ShouldBindJSON(&userGoal); err != nil {
+		ctx.Error(models.NewApiError(models.INVALID_REQUEST, "Invalid request format"))
 		return
 	}
 
-	currentWeightInKg, err := strconv.ParseFloat(currentWeightInKgStr, 32)
-	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid currentWeightInKg format: must be a number"})
+	if !c.registerWeeklyGoal(ctx, ids[0], &userGoal) {
 		return
 	}
 
-	if currentWeightInKg < 30 || currentWeightInKg > 250 {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "currentWeightInKg must be between 30 and 250"})
-		return
+	respondCreated(ctx, "/goals/"+ids[0].Hex()+"/weeks/"+userGoal.ID.Hex(), userGoal)
+}
+
+// deleteGoal removes the main goal, writing the error response when it fails
+func (c *UserGoalController) deleteGoal(ctx *gin.Context, goalId primitive.ObjectID) bool {
+	timedContext, cancel := config.GetTimedContext()
+	defer cancel()
+
+	if !c.canAccessGoal(ctx, timedContext, goalId) {
+		return false
 	}
 
-	goalWeightInKg, err := strconv.ParseFloat(goalWeightInKgStr, 32)
+	err := c.GoalService.DeleteGoal(timedContext, goalId)
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid goalWeightInKg format: must be a number"})
-		return
+		ctx.Error(err)
+		return false
 	}
+	return true
+}
 
-	if currentWeightInKg < 30 || currentWeightInKg > 250 {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "currentWeightInKg must be between 30 and 250"})
+func (c *UserGoalController) DeleteUserMainGoal(ctx *gin.Context) {
+	ids, ok := getQueryIds(ctx, "goalId")
+	if !ok || !c.deleteGoal(ctx, ids[0]) {
 		return
 	}
 
-	currentBodyFatPercentage, err := strconv.ParseFloat(currentBodyFatPercentageStr, 32)
-	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid currentBodyFatPercentage format: must be a number"})
-		return
-	}
+	ctx.JSON(http.StatusOK, gin.H{"success": true})
+}
 
-	goalBodyFatPercentage, err := strconv.ParseFloat(goalBodyFatPercentageStr, 32)
-	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid currentBodyFatPercentage format: must be a number"})
+func (c *UserGoalController) DeleteGoal(ctx *gin.Context) {
+	ids, ok := getPathIds(ctx, "goalId")
+	if !ok || !c.deleteGoal(ctx, ids[0]) {
 		return
 	}
 
-	if currentBodyFatPercentage < 10 || currentBodyFatPercentage > 80 {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "currentBodyFatPercentage must be between 10 and 80"})
-		return
+	ctx.Status(http.StatusNoContent)
+}
+
+// changeGoalStatus applies the status from the body to the main goal, writing the error response when it fails
+func (c *UserGoalController) changeGoalStatus(ctx *gin.Context, goalId primitive.ObjectID) (*models.Goal, bool) {
+	var update models.GoalStatusUpdate
+	if err := ctx.ShouldBindJSON(&update); err != nil {
+		ctx.Error(models.NewApiError(models.INVALID_REQUEST, "Invalid request format"))
+		return nil, false
 	}
 
 	timedContext, cancel := config.GetTimedContext()
 	defer cancel()
 
-	user, err := c.UserRepository.GetUserProfileById(timedContext, mongoUserId)
-	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "User not found"})
-		return
-	}
-	if !user.IsProfileComplete() {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Profile incomplete"})
-		return
+	if !c.canAccessGoal(ctx, timedContext, goalId) {
+		return nil, false
 	}
 
-	prompt := config.GetGoalDurationPrompt(*user, float32(currentWeightInKg), float32(currentBodyFatPercentage), float32(goalWeightInKg), float32(goalBodyFatPercentage))
-
-	resp, err := config.GetGoalDurationModel().GenerateContent(timedContext, genai.Text(prompt))
+	goal, err := c.GoalService.ChangeGoalStatus(timedContext, goalId, update)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate content: " + err.Error()})
+		ctx.Error(err)
+		return nil, false
+	}
+	return goal, true
+}
+
+func (c *UserGoalController) UpdateUserGoalStatus(ctx *gin.Context) {
+	ids, ok := getQueryIds(ctx, "goalId")
+	if !ok {
 		return
 	}
 
-	if len(resp.Candidates) == 0 || len(resp.Candidates[0].Content.Parts) == 0 {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "No content generated by the model"})
+	goal, ok := c.changeGoalStatus(ctx, ids[0])
+	if !ok {
 		return
 	}
 
-	content, ok := resp.Candidates[0].Content.Parts[0].(genai.Text)
+	ctx.JSON(http.StatusOK, goal)
+}
+
+// UpdateGoalStatus is the v1 change of the status of a main goal, to end the active goal or archive an ended one
+func (c *UserGoalController) UpdateGoalStatus(ctx *gin.Context) {
+	ids, ok := getPathIds(ctx, "goalId")
 	if !ok {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unexpected content format from the model"})
 		return
 	}
 
-	var result map[string]any
-	if err := json.Unmarshal([]byte(content), &result); err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unmarshal response to JSON: " + err.Error()})
+	goal, ok := c.changeGoalStatus(ctx, ids[0])
+	if !ok {
 		return
 	}
 
-	ctx.JSON(http.StatusOK, result)
+	ctx.JSON(http.StatusOK, goal)
 }
 
-func (c *UserGoalController) GetTdee(ctx *gin.Context) {
-	requiredFields := []string{"userId", "currentWeightInKg", "goalWeightInKg", "currentBodyFatPercentage", "goalBodyFatPercentage", "goalType"}
-	values := make(map[string]string)
-
-	for _, field := range requiredFields {
-		value, ok := ctx.GetQuery(field)
-		if !ok {
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid request format: missing %s", field)})
-			return
-		}
-		values[field] = value
+// updateWeeklyGoal edits the weekly goal from the body, writing the error response when it fails
+func (c *UserGoalController) updateWeeklyGoal(ctx *gin.Context, goalId primitive.ObjectID, weeklyGoalId primitive.ObjectID) (*models.WeeklyGoal, bool) {
+	var update models.WeeklyGoalUpdate
+	if err := ctx.ShouldBindJSON(&update); err != nil {
+		ctx.Error(models.NewApiError(models.INVALID_REQUEST, "Invalid request format"))
+		return nil, false
 	}
 
-	mongoUserIdStr := values["userId"]
-	currentWeightInKgStr := values["currentWeightInKg"]
-	goalWeightInKgStr := values["goalWeightInKg"]
-	currentBodyFatPercentageStr := values["currentBodyFatPercentage"]
-	goalBodyFatPercentageStr := values["goalBodyFatPercentage"]
-	goalType := values["goalType"]
+	timedContext, cancel := config.GetTimedContext()
+	defer cancel()
 
-	mongoUserId, err := primitive.ObjectIDFromHex(mongoUserIdStr)
-	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid userId format: must be a valid ObjectId"})
-		return
+	if !c.canAccessGoal(ctx, timedContext, goalId) {
+		return nil, false
 	}
 
-	currentWeightInKg, err := strconv.ParseFloat(currentWeightInKgStr, 32)
+	weeklyGoal, err := c.GoalService.UpdateWeeklyGoal(timedContext, goalId, weeklyGoalId, update)
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid currentWeightInKg format: must be a number"})
-		return
+		ctx.Error(err)
+		return nil, false
 	}
+	return weeklyGoal, true
+}
 
-	if currentWeightInKg < 30 || currentWeightInKg > 250 {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "currentWeightInKg must be between 30 and 250"})
+func (c *UserGoalController) UpdateUserWeeklyGoal(ctx *gin.Context) {
+	ids, ok := getQueryIds(ctx, "goalId", "weeklyGoalId")
+	if !ok {
 		return
 	}
 
-	goalWeightInKg, err := strconv.ParseFloat(goalWeightInKgStr, 32)
-	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid goalWeightInKg format: must be a number"})
+	weeklyGoal, ok := c.updateWeeklyGoal(ctx, ids[0], ids[1])
+	if !ok {
 		return
 	}
 
-	if currentWeightInKg < 30 || currentWeightInKg > 250 {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "currentWeightInKg must be between 30 and 250"})
-		return
-	}
+	ctx.JSON(http.StatusOK, weeklyGoal)
+}
 
-	currentBodyFatPercentage, err := strconv.ParseFloat(currentBodyFatPercentageStr, 32)
-	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid currentBodyFatPercentage format: must be a number"})
+func (c *UserGoalController) UpdateWeeklyGoal(ctx *gin.Context) {
+	ids, ok := getPathIds(ctx, "goalId", "weeklyGoalId")
+	if !ok {
 		return
 	}
 
-	goalBodyFatPercentage, err := strconv.ParseFloat(goalBodyFatPercentageStr, 32)
-	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid currentBodyFatPercentage format: must be a number"})
+	weeklyGoal, ok := c.updateWeeklyGoal(ctx, ids[0], ids[1])
+	if !ok {
 		return
 	}
 
-	if currentBodyFatPercentage < 10 || currentBodyFatPercentage > 80 {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "currentBodyFatPercentage must be between 10 and 80"})
-		return
-	}
+	ctx.JSON(http.StatusOK, weeklyGoal)
+}
 
+// deleteWeeklyGoal removes the weekly goal, writing the error response when it fails
+func (c *UserGoalController) deleteWeeklyGoal(ctx *gin.Context, goalId primitive.ObjectID, weeklyGoalId primitive.ObjectID) bool {
 	timedContext, cancel := config.GetTimedContext()
 	defer cancel()
 
-	user, err := c.UserRepository.GetUserProfileById(timedContext, mongoUserId)
-	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "User not found"})
-		return
+	if !c.canAccessGoal(ctx, timedContext, goalId) {
+		return false
 	}
-	if !user.IsProfileComplete() {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Profile incomplete"})
-		return
-	}
-
-	prompt := config.GetTdeePrompt(*user, float32(currentWeightInKg), float32(currentBodyFatPercentage), float32(goalWeightInKg), float32(goalBodyFatPercentage), goalType)
 
-	resp, err := config.GetTdeeModel().GenerateContent(timedContext, genai.Text(prompt))
+	err := c.GoalService.DeleteWeeklyGoal(timedContext, goalId, weeklyGoalId)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate content: " + err.Error()})
-		return
+		ctx.Error(err)
+		return false
 	}
+	return true
+}
 
-	if len(resp.Candidates) == 0 || len(resp.Candidates[0].Content.Parts) == 0 {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "No content generated by the model"})
+func (c *UserGoalController) DeleteUserWeeklyGoal(ctx *gin.Context) {
+	ids, ok := getQueryIds(ctx, "goalId", "weeklyGoalId")
+	if !ok || !c.deleteWeeklyGoal(ctx, ids[0], ids[1]) {
 		return
 	}
 
-	content, ok := resp.Candidates[0].Content.Parts[0].(genai.Text)
-	if !ok {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unexpected content format from the model"})
-		return
-	}
+	ctx.JSON(http.StatusOK, gin.H{"success": true})
+}
 
-	var result map[string]any
-	if err := json.Unmarshal([]byte(content), &result); err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unmarshal response to JSON: " + err.Error()})
+func (c *UserGoalController) DeleteWeeklyGoal(ctx *gin.Context) {
+	ids, ok := getPathIds(ctx, "goalId", "weeklyGoalId")
+	if !ok || !c.deleteWeeklyGoal(ctx, ids[0], ids[1]) {
 		return
 	}
 
-	ctx.JSON(http.StatusOK, result)
+	ctx.Status(http.StatusNoContent)
 }
 
-func (c *UserGoalController) GetMacros(ctx *gin.Context) {
-	requiredFields := []string{"userId", "currentWeightInKg", "goalWeightInKg", "currentBodyFatPercentage", "goalBodyFatPercentage",
-		"goalType", "currentBmr", "currentTdee", "weightChange"}
+// getGoalEstimate parses the required query params of an estimate, writing the error response when one is missing or malformed
+func getGoalEstimate(ctx *gin.Context, requiredFields ...string) (services.GoalEstimate, bool) {
+	var estimate services.GoalEstimate
 	values := make(map[string]string)
 
 	for _, field := range requiredFields {
 		value, ok := ctx.GetQuery(field)
 		if !ok {
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid request format: missing %s", field)})
-			return
+			ctx.Error(models.NewApiError(models.INVALID_REQUEST, fmt.Sprintf("Invalid request format: missing %s", field)))
+			return estimate, false
 		}
 		values[field] = value
 	}
 
-	mongoUserIdStr := values["userId"]
-	currentWeightInKgStr := values["currentWeightInKg"]
-	goalWeightInKgStr := values["goalWeightInKg"]
-	currentBodyFatPercentageStr := values["currentBodyFatPercentage"]
-	goalBodyFatPercentageStr := values["goalBodyFatPercentage"]
-	goalType := values["goalType"]
-	currentBmrStr := values["currentBmr"]
-	currentTdeeStr := values["currentTdee"]
-	weightChangeStr := values["weightChange"]
-
-	mongoUserId, err := primitive.ObjectIDFromHex(mongoUserIdStr)
-	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid userId format: must be a valid ObjectId"})
-		return
-	}
-
-	currentWeightInKg, err := strconv.ParseFloat(currentWeightInKgStr, 32)
-	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid currentWeightInKg format: must be a number"})
-		return
-	}
-
-	if currentWeightInKg < 30 || currentWeightInKg > 250 {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "currentWeightInKg must be between 30 and 250"})
-		return
-	}
-
-	goalWeightInKg, err := strconv.ParseFloat(goalWeightInKgStr, 32)
-	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid goalWeightInKg format: must be a number"})
-		return
+	for _, field := range requiredFields {
+		value := values[field]
+		switch field {
+		case "userId":
+			userId, err := primitive.ObjectIDFromHex(value)
+			if err != nil {
+				ctx.Error(models.NewApiError(models.INVALID_REQUEST, "Invalid userId format: must be a valid ObjectId"))
+				return estimate, false
+			}
+			estimate.UserId = userId
+		case "goalType":
+			if !models.IsValidGoalType(models.GoalType(value)) {
+				ctx.Error(models.NewApiError(models.INVALID_REQUEST, "Invalid goalType: must be one of Fat loss, Muscle gain, Body recomposition, Maintenance or Event"))
+				return estimate, false
+			}
+			estimate.GoalType = value
+		case "currentBmr", "currentTdee":
+			number, err := strconv.ParseInt(value, 10, 32)
+			if err != nil {
+				ctx.Error(models.NewApiError(models.INVALID_REQUEST, fmt.Sprintf("Invalid %s format: must be an integer", field)))
+				return estimate, false
+			}
+			if field == "currentBmr" {
+				estimate.CurrentBmr = number
+			} else {
+				estimate.CurrentTdee = number
+			}
+		default:
+			number, err := strconv.ParseFloat(value, 32)
+			if err != nil {
+				ctx.Error(models.NewApiError(models.INVALID_REQUEST, fmt.Sprintf("Invalid %s format: must be a number", field)))
+				return estimate, false
+			}
+			switch field {
+			case "currentWeightInKg":
+				estimate.CurrentWeightInKg = number
+			case "goalWeightInKg":
+				estimate.GoalWeightInKg = number
+			case "currentBodyFatPercentage":
+				estimate.CurrentBodyFatPercentage = number
+			case "goalBodyFatPercentage":
+				estimate.GoalBodyFatPercentage = number
+			case "weightChange":
+				estimate.WeightChange = number
+			}
+		}
 	}
+	return estimate, true
+}
 
-	if currentWeightInKg < 30 || currentWeightInKg > 250 {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "currentWeightInKg must be between 30 and 250"})
+// respondWithEstimate answers with the estimate of the model
+func respondWithEstimate(ctx *gin.Context, estimate func(ctx context.Context, estimate services.GoalEstimate) (map[string]any, error), requiredFields ...string) {
+	goalEstimate, ok := getGoalEstimate(ctx, requiredFields...)
+	if !ok {
 		return
 	}
 
-	currentBodyFatPercentage, err := strconv.ParseFloat(currentBodyFatPercentageStr, 32)
-	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid currentBodyFatPercentage format: must be a number"})
-		return
-	}
+	timedContext, cancel := config.GetTimedContext()
+	defer cancel()
 
-	goalBodyFatPercentage, err := strconv.ParseFloat(goalBodyFatPercentageStr, 32)
+	result, err := estimate(timedContext, goalEstimate)
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid currentBodyFatPercentage format: must be a number"})
+		ctx.Error(err)
 		return
 	}
 
-	if currentBodyFatPercentage < 10 || currentBodyFatPercentage > 80 {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "currentBodyFatPercentage must be between 10 and 80"})
-		return
-	}
+	ctx.JSON(http.StatusOK, result)
+}
 
-	currentBmr, err := strconv.ParseInt(currentBmrStr, 10, 32)
-	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid currentBmr format: must be an integer"})
-		return
-	}
+func (c *UserGoalController) GetIdealWeightRange(ctx *gin.Context) {
+	respondWithEstimate(ctx, c.GoalService.GetIdealWeightRange, "userId", "currentWeightInKg", "currentBodyFatPercentage")
+}
 
-	currentTdee, err := strconv.ParseInt(currentTdeeStr, 10, 32)
-	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid currentTdee format: must be an integer"})
-		return
-	}
+func (c *UserGoalController) GetGoalDuration(ctx *gin.Context) {
+	respondWithEstimate(ctx, c.GoalService.GetGoalDuration, "userId", "currentWeightInKg", "goalWeightInKg", "currentBodyFatPercentage", "goalBodyFatPercentage")
+}
 
-	weightChange, err := strconv.ParseFloat(weightChangeStr, 32)
-	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid weightChange format: must be a number"})
-		return
-	}
+func (c *UserGoalController) GetTdee(ctx *gin.Context) {
+	respondWithEstimate(ctx, c.GoalService.GetTdee, "userId", "currentWeightInKg", "goalWeightInKg", "currentBodyFatPercentage", "goalBodyFatPercentage", "goalType")
+}
 
-	timedContext, cancel := config.GetTimedContext()
-	defer cancel()
+func (c *UserGoalController) GetMacros(ctx *gin.Context) {
+	respondWithEstimate(ctx, c.GoalService.GetMacros, "userId", "currentWeightInKg", "goalWeightInKg", "currentBodyFatPercentage", "goalBodyFatPercentage",
+		"goalType", "currentBmr", "currentTdee", "weightChange")
+}
 
-	user, err := c.UserRepository.GetUserProfileById(timedContext, mongoUserId)
-	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "User not found"})
-		return
+// GoalSnapshot is the audit snapshot of the main goal addressed by the goalId path param, the goalId or mainGoalId
+// query param, or of the goal of the userId in the body, the caller by default, when a goal is registered
+func (c *UserGoalController) GoalSnapshot(ctx *gin.Context, timedContext context.Context) (primitive.ObjectID, any, error) {
+	goalId := ctx.Param("goalId")
+	if goalId == "" {
+		goalId = ctx.Query("goalId")
 	}
-	if !user.IsProfileComplete() {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Profile incomplete"})
-		return
+	if goalId == "" {
+		goalId = ctx.Query("mainGoalId")
 	}
 
-	prompt := config.GetDailyMacroPrompt(*user, float32(currentWeightInKg), float32(currentBodyFatPercentage),
-		float32(goalWeightInKg), float32(goalBodyFatPercentage), goalType,
-		int32(currentBmr), int32(currentTdee), float32(weightChange))
-
-	resp, err := config.GetMacroModel().GenerateContent(timedContext, genai.Text(prompt))
-	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate content: " + err.Error()})
-		return
-	}
-
-	if len(resp.Candidates) == 0 || len(resp.Candidates[0].Content.Parts) == 0 {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "No content generated by the model"})
-		return
+	var goal *models.Goal
+	var ownerId primitive.ObjectID
+	var err error
+	if goalId != "" {
+		mongoGoalId, parseErr := primitive.ObjectIDFromHex(goalId)
+		if parseErr != nil {
+			return primitive.NilObjectID, nil, nil
+		}
+		goal, err = c.GoalService.UserGoalRepository.GetUserGoalById(timedContext, mongoGoalId)
+	} else {
+		var userGoal models.Goal
+		if middleware.PeekJSONBody(ctx, &userGoal) != nil {
+			return primitive.NilObjectID, nil, nil
+		}
+		if userGoal.UserId.IsZero() {
+			userGoal.UserId = middleware.GetUserId(ctx)
+		}
+		ownerId = userGoal.UserId
+		goal, err = c.GoalService.UserGoalRepository.GetUserGoalByUserId(timedContext, userGoal.UserId)
 	}
 
-	content, ok := resp.Candidates[0].Content.Parts[0].(genai.Text)
-	if !ok {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unexpected content format from the model"})
-		return
+	if err == mongo.ErrNoDocuments {
+		return ownerId, nil, nil
 	}
-
-	var result map[string]any
-	if err := json.Unmarshal([]byte(content), &result); err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unmarshal response to JSON: " + err.Error()})
-		return
+	if err != nil {
+		return ownerId, nil, err
 	}
-
-	ctx.JSON(http.StatusOK, result)
+	return goal.UserId, goal, nil
 }
@@ -0,0 +1,210 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"fit-eats-api/middleware"
+	"fit-eats-api/models"
+	"fit-eats-api/repositories"
+	"fit-eats-api/services"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func newGoalTestRouter(goals repositories.UserGoalRepository, callerId primitive.ObjectID, role models.Role) *gin.Engine {
+	controller := NewUserGoalController(services.NewGoalService(repositories.NewInMemoryUserRepository(), goals, repositories.NewInMemoryMealRepository(), repositories.NewInMemoryUnitOfWork()), middleware.NewUserAccess(nil))
+
+	router := newTestRouter(callerId, role)
+	router.GET("/api/v1/goals", controller.GetGoals)
+	router.POST("/api/v1/goals", controller.CreateGoal)
+	router.GET("/api/v1/goals/active", controller.GetActiveGoal)
+	router.GET("/api/v1/goals/:goalId", controller.GetGoal)
+	router.PATCH("/api/v1/goals/:goalId", controller.UpdateGoalStatus)
+	router.DELETE("/api/v1/goals/:goalId", controller.DeleteGoal)
+	router.GET("/api/v1/goals/:goalId/weeks/:weeklyGoalId", controller.GetWeeklyGoal)
+	router.PATCH("/api/v1/goals/:goalId/weeks/:weeklyGoalId", controller.UpdateWeeklyGoal)
+	router.DELETE("/api/v1/goals/:goalId/weeks/:weeklyGoalId", controller.DeleteWeeklyGoal)
+	return router
+}
+
+func createTestGoal(t *testing.T, goals repositories.UserGoalRepository, userId primitive.ObjectID) *models.Goal {
+	t.Helper()
+	ctx := context.Background()
+	now := time.Now()
+	goal := &models.Goal{ID: primitive.NewObjectID(), UserId: userId, GoalType: models.FAT_LOSS, Status: models.GOAL_ACTIVE, GoalStartDate: now, GoalEndDate: now.AddDate(0, 3, 0)}
+	if err := goals.CreateMainUserGoal(ctx, goal); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, start := range []time.Time{now.AddDate(0, 0, -3), now.AddDate(0, 0, 4)} {
+		weeklyGoal := &models.WeeklyGoal{StartDate: start, EndDate: start.AddDate(0, 0, 7), CurrentWeightInKg: 80}
+		if err := goals.CreateWeeklyUserGoal(ctx, goal.ID, weeklyGoal); err != nil {
+			t.Fatal(err)
+		}
+		goal.WeeklyGoals = append(goal.WeeklyGoals, *weeklyGoal)
+	}
+	return goal
+}
+
+func TestUserGoalControllerGetGoals(t *testing.T) {
+	goals := repositories.NewInMemoryUserGoalRepository()
+	userId := primitive.NewObjectID()
+	router := newGoalTestRouter(goals, userId, models.ROLE_USER)
+
+	var response struct {
+		Goals []models.Goal `json:"goals"`
+	}
+	recorder := serve(router, http.MethodGet, "/api/v1/goals", nil)
+	expectStatus(t, recorder, http.StatusOK)
+	decodeResponse(t, recorder, &response)
+	if response.Goals == nil || len(response.Goals) != 0 {
+		t.Errorf("expected an empty list, got %s", recorder.Body)
+	}
+
+	goal := createTestGoal(t, goals, userId)
+	recorder = serve(router, http.MethodGet, "/api/v1/goals", nil)
+	expectStatus(t, recorder, http.StatusOK)
+	decodeResponse(t, recorder, &response)
+	if len(response.Goals) != 1 || response.Goals[0].ID != goal.ID || len(response.Goals[0].WeeklyGoals) != 2 {
+		t.Errorf("expected the goal of the caller, got %s", recorder.Body)
+	}
+
+	recorder = serve(router, http.MethodGet, "/api/v1/goals/active", nil)
+	expectStatus(t, recorder, http.StatusOK)
+	var active models.Goal
+	decodeResponse(t, recorder, &active)
+	if len(active.WeeklyGoals) != 1 || active.WeeklyGoals[0].ID != goal.WeeklyGoals[0].ID {
+		t.Errorf("expected only the running week, got %s", recorder.Body)
+	}
+
+	recorder = serve(router, http.MethodGet, "/api/v1/goals/active?userId=invalid", nil)
+	expectError(t, recorder, http.StatusBadRequest, models.INVALID_REQUEST)
+}
+
+func TestUserGoalControllerGoalAccess(t *testing.T) {
+	goals := repositories.NewInMemoryUserGoalRepository()
+	ownerId := primitive.NewObjectID()
+	goal := createTestGoal(t, goals, ownerId)
+	target := "/api/v1/goals/" + goal.ID.Hex()
+
+	recorder := serve(newGoalTestRouter(goals, ownerId, models.ROLE_USER), http.MethodGet, target, nil)
+	expectStatus(t, recorder, http.StatusOK)
+	var found models.Goal
+	decodeResponse(t, recorder, &found)
+	if found.ID != goal.ID || found.UserId != ownerId {
+		t.Errorf("expected the goal, got %s", recorder.Body)
+	}
+
+	recorder = serve(newGoalTestRouter(goals, primitive.NewObjectID(), models.ROLE_USER), http.MethodGet, target, nil)
+	expectError(t, recorder, http.StatusForbidden, models.FORBIDDEN)
+
+	recorder = serve(newGoalTestRouter(goals, primitive.NewObjectID(), models.ROLE_ADMIN), http.MethodGet, target, nil)
+	expectStatus(t, recorder, http.StatusOK)
+
+	recorder = serve(newGoalTestRouter(goals, ownerId, models.ROLE_USER), http.MethodGet, "/api/v1/goals/"+primitive.NewObjectID().Hex(), nil)
+	expectError(t, recorder, http.StatusNotFound, models.GOAL_NOT_FOUND)
+
+	recorder = serve(newGoalTestRouter(goals, ownerId, models.ROLE_USER), http.MethodGet, target+"/weeks/"+goal.WeeklyGoals[1].ID.Hex(), nil)
+	expectStatus(t, recorder, http.StatusOK)
+	recorder = serve(newGoalTestRouter(goals, ownerId, models.ROLE_USER), http.MethodGet, target+"/weeks/"+primitive.NewObjectID().Hex(), nil)
+	expectError(t, recorder, http.StatusNotFound, models.WEEKLY_GOAL_NOT_FOUND)
+}
+
+func TestUserGoalControllerCreateAndDelete(t *testing.T) {
+	goals := repositories.NewInMemoryUserGoalRepository()
+	userId := primitive.NewObjectID()
+	router := newGoalTestRouter(goals, userId, models.ROLE_USER)
+	now := time.Now()
+	body := models.Goal{GoalType: models.FAT_LOSS, StartWeightInKg: 90, TargetWeightInKg: 80, GoalStartDate: now, GoalEndDate: now.AddDate(0, 3, 0)}
+
+	recorder := serve(router, http.MethodPost, "/api/v1/goals", body)
+	expectStatus(t, recorder, http.StatusCreated)
+	var created models.Goal
+	decodeResponse(t, recorder, &created)
+	if created.ID.IsZero() || created.UserId != userId || recorder.Header().Get("Location") != API_V1_PATH+"/goals/"+created.ID.Hex() {
+		t.Fatalf("unexpected created goal %s at %s", recorder.Body, recorder.Header().Get("Location"))
+	}
+
+	recorder = serve(router, http.MethodPost, "/api/v1/goals", body)
+	expectError(t, recorder, http.StatusConflict, models.ALREADY_EXISTS)
+
+	body.UserId = primitive.NewObjectID()
+	recorder = serve(router, http.MethodPost, "/api/v1/goals", body)
+	expectError(t, recorder, http.StatusForbidden, models.FORBIDDEN)
+
+	recorder = serve(router, http.MethodDelete, "/api/v1/goals/"+created.ID.Hex(), nil)
+	expectStatus(t, recorder, http.StatusNoContent)
+	if _, err := goals.GetUserGoalById(context.Background(), created.ID); err == nil {
+		t.Error("expected the goal deleted")
+	}
+
+	recorder = serve(router, http.MethodDelete, "/api/v1/goals/"+created.ID.Hex(), nil)
+	expectError(t, recorder, http.StatusNotFound, models.GOAL_NOT_FOUND)
+}
+
+func TestUserGoalControllerWeeklyGoals(t *testing.T) {
+	goals := repositories.NewInMemoryUserGoalRepository()
+	ownerId := primitive.NewObjectID()
+	goal := createTestGoal(t, goals, ownerId)
+	router := newGoalTestRouter(goals, ownerId, models.ROLE_USER)
+	target := "/api/v1/goals/" + goal.ID.Hex() + "/weeks/"
+
+	recorder := serve(router, http.MethodPatch, target+goal.WeeklyGoals[0].ID.Hex(), gin.H{"currentWeightInKg": 79})
+	expectStatus(t, recorder, http.StatusOK)
+	var updated models.WeeklyGoal
+	decodeResponse(t, recorder, &updated)
+	if updated.ID != goal.WeeklyGoals[0].ID || updated.CurrentWeightInKg != 79 {
+		t.Errorf("expected the updated week, got %s", recorder.Body)
+	}
+
+	recorder = serve(router, http.MethodPatch, target+goal.WeeklyGoals[0].ID.Hex(), gin.H{"targetDailyCalories": 2000, "targetDailyMacrosProtein": 150})
+	expectError(t, recorder, http.StatusBadRequest, models.VALIDATION_FAILED)
+	recorder = serve(newGoalTestRouter(goals, primitive.NewObjectID(), models.ROLE_USER), http.MethodPatch, target+goal.WeeklyGoals[0].ID.Hex(), gin.H{"currentWeightInKg": 79})
+	expectError(t, recorder, http.StatusForbidden, models.FORBIDDEN)
+
+	recorder = serve(router, http.MethodDelete, target+goal.WeeklyGoals[1].ID.Hex(), nil)
+	expectStatus(t, recorder, http.StatusNoContent)
+	found, err := goals.GetUserGoalById(context.Background(), goal.ID)
+	if err != nil || len(found.WeeklyGoals) != 1 || found.WeeklyGoals[0].ID != goal.WeeklyGoals[0].ID {
+		t.Errorf("expected only the other week kept, got %+v, %v", found, err)
+	}
+	recorder = serve(router, http.MethodDelete, target+goal.WeeklyGoals[1].ID.Hex(), nil)
+	expectError(t, recorder, http.StatusNotFound, models.WEEKLY_GOAL_NOT_FOUND)
+}
+
+func TestUserGoalControllerGoalStatus(t *testing.T) {
+	goals := repositories.NewInMemoryUserGoalRepository()
+	ownerId := primitive.NewObjectID()
+	goal := createTestGoal(t, goals, ownerId)
+	router := newGoalTestRouter(goals, ownerId, models.ROLE_USER)
+	target := "/api/v1/goals/" + goal.ID.Hex()
+
+	recorder := serve(newGoalTestRouter(goals, primitive.NewObjectID(), models.ROLE_USER), http.MethodPatch, target, gin.H{"status": models.GOAL_COMPLETED})
+	expectError(t, recorder, http.StatusForbidden, models.FORBIDDEN)
+	recorder = serve(router, http.MethodPatch, target, gin.H{"status": models.GOAL_ARCHIVED})
+	expectError(t, recorder, http.StatusConflict, models.INVALID_STATE)
+
+	recorder = serve(router, http.MethodPatch, target, gin.H{"status": models.GOAL_COMPLETED})
+	expectStatus(t, recorder, http.StatusOK)
+	var ended models.Goal
+	decodeResponse(t, recorder, &ended)
+	if ended.Status != models.GOAL_COMPLETED || ended.Outcome == nil || ended.Outcome.WeeksTracked != 2 {
+		t.Errorf("expected the completed goal with its outcome, got %s", recorder.Body)
+	}
+
+	var response struct {
+		Goals []models.Goal `json:"goals"`
+	}
+	recorder = serve(router, http.MethodGet, "/api/v1/goals?status=Completed", nil)
+	expectStatus(t, recorder, http.StatusOK)
+	decodeResponse(t, recorder, &response)
+	if len(response.Goals) != 1 || response.Goals[0].ID != goal.ID {
+		t.Errorf("expected the completed goal, got %s", recorder.Body)
+	}
+	recorder = serve(router, http.MethodGet, "/api/v1/goals?status=Paused", nil)
+	expectError(t, recorder, http.StatusBadRequest, models.INVALID_REQUEST)
+}
@@ -0,0 +1,250 @@
+package controllers
+
+import (
+	"context"
+	"fit-eats-api/config"
+	"fit-eats-api/middleware"
+	"fit-eats-api/models"
+	"fit-eats-api/utils"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Two factor authentication handlers of the UserController
+
+// Issuer shown in authenticator apps
+const totpIssuer = "FitEats"
+
+const recoveryCodeCount = 10
+
+func (c *UserController) VerifyMfaLogin(ctx *gin.Context) {
+	mfaToken := ctx.PostForm("mfaToken")
+	code := ctx.PostForm("code")
+	recoveryCode := ctx.PostForm("recoveryCode")
+	if mfaToken == "" || (code == "" && recoveryCode == "") {
+		ctx.Error(models.NewApiError(models.INVALID_REQUEST, "missing body params"))
+		return
+	}
+
+	mongoUserId, err := utils.ParseMfaPendingToken(mfaToken)
+	if err != nil {
+		ctx.Error(models.NewApiError(models.UNAUTHORIZED, "mfa token invalid or expired, please login again"))
+		return
+	}
+
+	// Mail servers can be slow to answer when a lockout sends the unlock email
+	timedContext, cancel := config.GetTimedContext(30)
+	defer cancel()
+
+	user, err := c.UserRepository.GetUserCredentialsById(timedContext, mongoUserId)
+	if err != nil || !user.IsMfaEnabled() {
+		ctx.Error(models.NewApiError(models.INVALID_CREDENTIALS, "user not found"))
+		return
+	}
+
+	// Wrong codes count as failed logins, so codes cannot be brute forced either
+	accountKey := models.LOGIN_ACCOUNT_KEY_PREFIX + strings.ToLower(user.Email)
+	ipKey := models.LOGIN_IP_KEY_PREFIX + ctx.ClientIP()
+	if c.isLoginThrottled(ctx, timedContext, accountKey, models.AccountLoginPolicy) || c.isLoginThrottled(ctx, timedContext, ipKey, models.IpLoginPolicy) {
+		return
+	}
+
+	if !c.verifySecondFactor(timedContext, user, code, recoveryCode, time.Now()) {
+		c.recordLoginFailure(ctx, timedContext, user, accountKey, ipKey)
+		ctx.Error(models.NewApiError(models.INVALID_CREDENTIALS, "invalid code"))
+		return
+	}
+
+	c.completeLogin(ctx, timedContext, user, accountKey)
+}
+
+// verifySecondFactor accepts either a totp code valid at now or one of the recovery codes, using up the recovery code
+func (c *UserController) verifySecondFactor(timedContext context.Context, user *models.User, code string, recoveryCode string, now time.Time) bool {
+	if code != "" {
+		counter, ok := utils.VerifyTotpCode(user.Mfa.Secret, code, now)
+		return ok && c.UserRepository.SetMfaLastUsedCounter(timedContext, user.ID, counter) == nil
+	}
+	if recoveryCode != "" {
+		codeHash := utils.HashToken(utils.NormalizeRecoveryCode(recoveryCode))
+		return c.UserRepository.ConsumeRecoveryCode(timedContext, user.ID, codeHash) == nil
+	}
+	return false
+}
+
+// generateRecoveryCodes returns the codes to show to the user once and the hashes to store
+func generateRecoveryCodes() ([]string, []string, error) {
+	codes, err := utils.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	hashes := make([]string, 0, len(codes))
+	for _, code := range codes {
+		hashes = append(hashes, utils.HashToken(code))
+	}
+	return codes, hashes, nil
+}
+
+func (c *UserController) EnrollMfa(ctx *gin.Context) {
+	timedContext, cancel := config.GetTimedContext()
+	defer cancel()
+
+	user, err := c.UserRepository.GetUserCredentialsById(timedContext, middleware.GetUserId(ctx))
+	if err != nil {
+		ctx.Error(models.NewApiError(models.USER_NOT_FOUND, "User not found"))
+		return
+	}
+	if user.IsMfaEnabled() {
+		ctx.Error(models.NewApiError(models.INVALID_STATE, "Two factor authentication is already enabled"))
+		return
+	}
+
+	secret, err := utils.GenerateTotpSecret()
+	if err != nil {
+		ctx.Error(models.NewApiError(models.INTERNAL_ERROR, "Could not generate secret").WithCause(err))
+		return
+	}
+
+	err = c.UserRepository.UpdateUser(timedContext, user.ID, bson.M{"mfa.enabled": false, "mfa.pendingSecret": secret})
+	if err != nil {
+		ctx.Error(models.NewApiError(models.INTERNAL_ERROR, "Could not start two factor enrolment").WithCause(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"secret": secret, "otpauthUri": utils.GetTotpUri(secret, user.Email, totpIssuer)})
+}
+
+func (c *UserController) ActivateMfa(ctx *gin.Context) {
+	code := ctx.PostForm("code")
+	if code == "" {
+		ctx.Error(models.NewApiError(models.INVALID_REQUEST, "missing body params"))
+		return
+	}
+
+	timedContext, cancel := config.GetTimedContext()
+	defer cancel()
+
+	user, err := c.UserRepository.GetUserCredentialsById(timedContext, middleware.GetUserId(ctx))
+	if err != nil {
+		ctx.Error(models.NewApiError(models.USER_NOT_FOUND, "User not found"))
+		return
+	}
+	if user.IsMfaEnabled() {
+		ctx.Error(models.NewApiError(models.INVALID_STATE, "Two factor authentication is already enabled"))
+		return
+	}
+	if user.Mfa == nil || user.Mfa.PendingSecret == "" {
+		ctx.Error(models.NewApiError(models.INVALID_STATE, "Two factor enrolment not started"))
+		return
+	}
+
+	// The first code proves the authenticator app was set up with the secret
+	counter, ok := utils.VerifyTotpCode(user.Mfa.PendingSecret, code, time.Now())
+	if !ok {
+		ctx.Error(models.NewApiError(models.INVALID_MFA_CODE, "invalid code"))
+		return
+	}
+
+	recoveryCodes, recoveryCodeHashes, err := generateRecoveryCodes()
+	if err != nil {
+		ctx.Error(models.NewApiError(models.INTERNAL_ERROR, "Could not generate recovery codes").WithCause(err))
+		return
+	}
+
+	now := time.Now()
+	mfa := models.MfaSettings{
+		Enabled:            true,
+		EnabledAt:          &now,
+		Secret:             user.Mfa.PendingSecret,
+		RecoveryCodeHashes: recoveryCodeHashes,
+		LastUsedCounter:    counter,
+	}
+	err = c.UserRepository.UpdateUser(timedContext, user.ID, bson.M{"mfa": mfa})
+	if err != nil {
+		ctx.Error(models.NewApiError(models.INTERNAL_ERROR, "Could not enable two factor authentication").WithCause(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Two factor authentication enabled", "recoveryCodes": recoveryCodes})
+}
+
+func (c *UserController) DisableMfa(ctx *gin.Context) {
+	password := ctx.PostForm("password")
+	code := ctx.PostForm("code")
+	recoveryCode := ctx.PostForm("recoveryCode")
+	if password == "" || (code == "" && recoveryCode == "") {
+		ctx.Error(models.NewApiError(models.INVALID_REQUEST, "missing body params"))
+		return
+	}
+
+	timedContext, cancel := config.GetTimedContext()
+	defer cancel()
+
+	user, err := c.UserRepository.GetUserCredentialsById(timedContext, middleware.GetUserId(ctx))
+	if err != nil {
+		ctx.Error(models.NewApiError(models.USER_NOT_FOUND, "User not found"))
+		return
+	}
+	if !user.IsMfaEnabled() {
+		ctx.Error(models.NewApiError(models.INVALID_STATE, "Two factor authentication is not enabled"))
+		return
+	}
+
+	if !utils.IsPasswordCorrect(user.Password, password) || !c.verifySecondFactor(timedContext, user, code, recoveryCode, time.Now()) {
+		ctx.Error(models.NewApiError(models.INVALID_CREDENTIALS, "invalid Credentials"))
+		return
+	}
+
+	err = c.UserRepository.UnsetUserFields(timedContext, user.ID, "mfa")
+	if err != nil {
+		ctx.Error(models.NewApiError(models.INTERNAL_ERROR, "Could not disable two factor authentication").WithCause(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Two factor authentication disabled"})
+}
+
+func (c *UserController) RegenerateRecoveryCodes(ctx *gin.Context) {
+	code := ctx.PostForm("code")
+	if code == "" {
+		ctx.Error(models.NewApiError(models.INVALID_REQUEST, "missing body params"))
+		return
+	}
+
+	timedContext, cancel := config.GetTimedContext()
+	defer cancel()
+
+	user, err := c.UserRepository.GetUserCredentialsById(timedContext, middleware.GetUserId(ctx))
+	if err != nil {
+		ctx.Error(models.NewApiError(models.USER_NOT_FOUND, "User not found"))
+		return
+	}
+	if !user.IsMfaEnabled() {
+		ctx.Error(models.NewApiError(models.INVALID_STATE, "Two factor authentication is not enabled"))
+		return
+	}
+
+	// Only a totp code is accepted, a recovery code could have leaked along with the others
+	if !c.verifySecondFactor(timedContext, user, code, "", time.Now()) {
+		ctx.Error(models.NewApiError(models.INVALID_MFA_CODE, "invalid code"))
+		return
+	}
+
+	recoveryCodes, recoveryCodeHashes, err := generateRecoveryCodes()
+	if err != nil {
+		ctx.Error(models.NewApiError(models.INTERNAL_ERROR, "Could not generate recovery codes").WithCause(err))
+		return
+	}
+
+	err = c.UserRepository.UpdateUser(timedContext, user.ID, bson.M{"mfa.recoveryCodeHashes": recoveryCodeHashes})
+	if err != nil {
+		ctx.Error(models.NewApiError(models.INTERNAL_ERROR, "Could not save recovery codes").WithCause(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"recoveryCodes": recoveryCodes})
+}
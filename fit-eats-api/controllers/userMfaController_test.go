@@ -0,0 +1,51 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"fit-eats-api/models"
+	"fit-eats-api/repositories"
+	"fit-eats-api/utils"
+)
+
+func TestVerifySecondFactorRejectsReplayedCodes(t *testing.T) {
+	ctx := context.Background()
+	userRepository := repositories.NewInMemoryUserRepository()
+	controller := &UserController{UserRepository: userRepository}
+
+	secret, err := utils.GenerateTotpSecret()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	user := &models.User{Name: "Asha", Email: "asha@fiteats.test", Mfa: &models.MfaSettings{Enabled: true, Secret: secret}}
+	if err := userRepository.CreateUser(ctx, user); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	now := time.Unix(1700000000, 0)
+	code := func(at time.Time) string {
+		code, err := utils.GenerateTotpCode(secret, at)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return code
+	}
+
+	if !controller.verifySecondFactor(ctx, user, code(now), "", now) {
+		t.Fatal("expected the current code to be accepted")
+	}
+	if controller.verifySecondFactor(ctx, user, code(now), "", now) {
+		t.Fatal("expected the same code to be rejected the second time")
+	}
+	// The previous period is still within the skew but before the code already used
+	if controller.verifySecondFactor(ctx, user, code(now.Add(-utils.TotpPeriod*time.Second)), "", now) {
+		t.Fatal("expected a code older than the last used one to be rejected")
+	}
+
+	next := now.Add(utils.TotpPeriod * time.Second)
+	if !controller.verifySecondFactor(ctx, user, code(next), "", next) {
+		t.Fatal("expected the code of the next period to be accepted")
+	}
+}
@@ -0,0 +1,177 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fit-eats-api/config"
+	"fit-eats-api/models"
+	"fit-eats-api/repositories"
+	"fit-eats-api/utils"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/generative-ai-go/genai"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type WorkoutController struct {
+	UserRepository     repositories.UserRepository
+	UserGoalRepository repositories.UserGoalRepository
+	WorkoutRepository  *repositories.WorkoutRepository
+	UnitOfWork         repositories.UnitOfWork
+}
+
+func NewWorkoutController(userRepository repositories.UserRepository, userGoalRepository repositories.UserGoalRepository,
+	workoutRepository *repositories.WorkoutRepository, unitOfWork repositories.UnitOfWork) *WorkoutController {
+	return &WorkoutController{UserRepository: userRepository, UserGoalRepository: userGoalRepository, WorkoutRepository: workoutRepository, UnitOfWork: unitOfWork}
+}
+
+func (c *WorkoutController) GetExercises(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, gin.H{"exercises": models.ExerciseCatalogue})
+}
+
+func (c *WorkoutController) GetWeeklyWorkoutRoutine(ctx *gin.Context) {
+	requiredFields := []string{"userId", "mainGoalId", "weeklyGoalId"}
+	values := make(map[string]string)
+
+	for _, field := range requiredFields {
+		value, ok := ctx.GetQuery(field)
+		if !ok {
+			ctx.Error(models.NewApiError(models.INVALID_REQUEST, fmt.Sprintf("Invalid request format: missing %s", field)))
+			return
+		}
+		values[field] = value
+	}
+
+	mongoUserId, err := primitive.ObjectIDFromHex(values["userId"])
+	if err != nil {
+		ctx.Error(models.NewApiError(models.INVALID_REQUEST, "Invalid userId format: must be a valid ObjectId"))
+		return
+	}
+	mongoMainGoalId, err := primitive.ObjectIDFromHex(values["mainGoalId"])
+	if err != nil {
+		ctx.Error(models.NewApiError(models.INVALID_REQUEST, "Invalid mainGoalId format: must be a valid ObjectId"))
+		return
+	}
+	mongoWeeklyGoalId, err := primitive.ObjectIDFromHex(values["weeklyGoalId"])
+	if err != nil {
+		ctx.Error(models.NewApiError(models.INVALID_REQUEST, "Invalid weeklyGoalId format: must be a valid ObjectId"))
+		return
+	}
+
+	timedContext, cancel := config.GetTimedContext()
+	defer cancel()
+
+	routine, err := c.WorkoutRepository.GetWorkoutRoutine(timedContext, mongoUserId, mongoMainGoalId, mongoWeeklyGoalId)
+	if err != nil || routine == nil {
+		ctx.Error(models.NewApiError(models.WORKOUT_ROUTINE_NOT_FOUND, "Workout routine is not yet created"))
+		return
+	}
+	ctx.JSON(http.StatusOK, routine)
+}
+
+func (c *WorkoutController) CreateWeeklyWorkoutRoutine(ctx *gin.Context) {
+	requiredFields := []string{"userId", "mainGoalId", "weeklyGoalId"}
+	values := make(map[string]string)
+
+	for _, field := range requiredFields {
+		value, ok := ctx.GetQuery(field)
+		if !ok {
+			ctx.Error(models.NewApiError(models.INVALID_REQUEST, fmt.Sprintf("Invalid request format: missing %s", field)))
+			return
+		}
+		values[field] = value
+	}
+
+	mongoUserId, err := primitive.ObjectIDFromHex(values["userId"])
+	if err != nil {
+		ctx.Error(models.NewApiError(models.INVALID_REQUEST, "Invalid userId format: must be a valid ObjectId"))
+		return
+	}
+	mongoMainGoalId, err := primitive.ObjectIDFromHex(values["mainGoalId"])
+	if err != nil {
+		ctx.Error(models.NewApiError(models.INVALID_REQUEST, "Invalid mainGoalId format: must be a valid ObjectId"))
+		return
+	}
+	mongoWeeklyGoalId, err := primitive.ObjectIDFromHex(values["weeklyGoalId"])
+	if err != nil {
+		ctx.Error(models.NewApiError(models.INVALID_REQUEST, "Invalid weeklyGoalId format: must be a valid ObjectId"))
+		return
+	}
+
+	// 120 seconds for llm to respond
+	timedContext, cancel := config.GetTimedContext(120)
+	defer cancel()
+
+	user, err := c.UserRepository.GetUserProfileById(timedContext, mongoUserId)
+	if err != nil {
+		ctx.Error(models.NewApiError(models.USER_NOT_FOUND, "User not found"))
+		return
+	}
+	if !user.IsProfileComplete() {
+		ctx.Error(models.NewApiError(models.PROFILE_INCOMPLETE, "Profile incomplete"))
+		return
+	}
+
+	// The userId was authorized by the route, the goal must belong to the same user
+	goal, err := c.UserGoalRepository.GetUserWeeklyGoal(timedContext, mongoMainGoalId, mongoWeeklyGoalId)
+	if err != nil || goal.UserId != mongoUserId {
+		ctx.Error(models.NewApiError(models.GOAL_NOT_FOUND, "Goal not found"))
+		return
+	}
+
+	if c.WorkoutRepository.IsWorkoutRoutineCreated(timedContext, mongoUserId, mongoWeeklyGoalId) {
+		ctx.Error(models.NewApiError(models.ALREADY_EXISTS, "Workout routine is already created"))
+		return
+	}
+
+	extraPrompt := ctx.Query("prompt")
+	weeklyGoal := goal.WeeklyGoals[0]
+
+	prompt := config.GetWeeklyWorkoutPrompt(*user, extraPrompt, float32(weeklyGoal.CurrentWeightInKg), float32(weeklyGoal.CurrentFatPercentage),
+		float32(goal.TargetWeightInKg), float32(goal.TargetFatPercentage), goal.GoalType, weeklyGoal.ActivityLevel)
+
+	resp, err := config.GetWorkoutModel().GenerateContent(timedContext, genai.Text(prompt))
+	if err != nil {
+		ctx.Error(models.NewApiError(models.LLM_UNAVAILABLE, "Could not generate content, please try again").WithCause(err))
+		return
+	}
+
+	if len(resp.Candidates) == 0 || len(resp.Candidates[0].Content.Parts) == 0 {
+		ctx.Error(models.NewApiError(models.LLM_INVALID_RESPONSE, "No content generated by the model"))
+		return
+	}
+
+	content, ok := resp.Candidates[0].Content.Parts[0].(genai.Text)
+	if !ok {
+		ctx.Error(models.NewApiError(models.LLM_INVALID_RESPONSE, "Unexpected content format from the model"))
+		return
+	}
+
+	var result map[string]any
+	if err := json.Unmarshal([]byte(content), &result); err != nil {
+		ctx.Error(models.NewApiError(models.LLM_INVALID_RESPONSE, "Could not read the generated content, please try again").WithCause(err))
+		return
+	}
+
+	routine := utils.ParseWorkoutRoutineResponse(mongoUserId, mongoMainGoalId, mongoWeeklyGoalId, goal.GoalType, weeklyGoal.StartDate, result)
+	if len(routine.WorkoutDays) == 0 {
+		ctx.Error(models.NewApiError(models.LLM_INVALID_RESPONSE, "No workout days generated by the model"))
+		return
+	}
+
+	// The routine is only kept when it is linked to the weekly goal
+	err = c.UnitOfWork.Do(timedContext, func(txContext context.Context) error {
+		if err := c.WorkoutRepository.CreateWorkoutRoutine(txContext, &routine); err != nil {
+			return err
+		}
+		return c.UserGoalRepository.SetWeeklyGoalWorkoutRoutine(txContext, mongoMainGoalId, mongoWeeklyGoalId, routine.ID)
+	})
+	if err != nil {
+		ctx.Error(models.NewApiError(models.INTERNAL_ERROR, "Could not save workout routine").WithCause(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, routine)
+}
@@ -3,13 +3,19 @@ package main
 import (
 	"fmt"
 	"log"
+	"time"
 
 	"fit-eats-api/config"
 	"fit-eats-api/controllers"
+	"fit-eats-api/middleware"
+	"fit-eats-api/migrations"
 	"fit-eats-api/repositories"
 	"fit-eats-api/routes"
+	"fit-eats-api/services"
+	"fit-eats-api/utils"
 
 	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
 /*
@@ -19,16 +25,16 @@ Repository: Deals directly with data persistence (e.g., MongoDB). It abstracts a
 
 Data Flow:
 
-+----------+     +-----------+     +------------+
-| HTTP     | --> | Controller| --> | Repository | --> MongoDB
-| Request  |     |           |     |            |
-+----------+     +-----------+     +------------+
-                                         ^
-                                         |
-+----------+     +-----------+     +------------+
-| HTTP     | <-- | Controller| <-- | Repository | <-- MongoDB
-| Response |     |           |     |            |
-+----------+     +-----------+     +------------+
++----------+     +-----------+     +-----------+     +------------+
+| HTTP     | --> | Controller| --> | Service   | --> | Repository | --> MongoDB
+| Request  |     |           |     |           |     |            |
++----------+     +-----------+     +-----------+     +------------+
+                                                           ^
+                                                           |
++----------+     +-----------+     +-----------+     +------------+
+| HTTP     | <-- | Controller| <-- | Service   | <-- | Repository | <-- MongoDB
+| Response |     |           |     |           |     |            |
++----------+     +-----------+     +-----------+     +------------+
 
 */
 
@@ -36,33 +42,36 @@ func main() {
 	// Load configuration
 	cfg := config.GetConfig()
 
+	if err := utils.CheckDayLocation(); err != nil {
+		log.Fatal("Could not load the time zone of the days: ", err)
+	}
+
 	// Connect to MongoDB using config
 	client := config.ConnectDB(cfg)
 	db := client.Database(cfg.Database)
 	fmt.Println("Connected to MongoDB:", cfg.Database)
 
-	// Initialize repositories, and controllers
-	userRepo := repositories.NewUserRepository(db)
-	userController := controllers.NewUserController(userRepo)
-
-	// Initialize repositories, and controllers
-	userGoalRepo := repositories.NewUserGoalRepository(db)
-	userGoalController := controllers.NewUserGoalController(userRepo, userGoalRepo)
-
-	// Initialize repositories, and controllers
-	mealRepo := repositories.NewMealRepository(db)
-	mealController := controllers.NewMealController(userRepo, userGoalRepo, mealRepo)
+	// Apply the pending index and data migrations before serving requests
+	if cfg.MigrateOnStartup {
+		runMigrations(db)
+	}
 
-	dashboardController := controllers.NewDashboardController(userRepo, userGoalRepo, mealRepo)
+	mailer, err := utils.NewMailer(cfg)
+	if err != nil {
+		log.Fatal("Could not configure the mailer: ", err)
+	}
 
-	// Set up Gin router
-	router := gin.Default()
+	// Writes spanning several documents or collections share a transaction through the unit of work
+	unitOfWork := repositories.NewMongoUnitOfWork(db, cfg.MongoWithoutTransactions)
+	checkTransactions(unitOfWork)
 
-	// Define API routes
-	routes.SetupUserRoutes(router, userController)
-	routes.SetupUserGoalRoutes(router, userGoalController)
-	routes.SetupMealRoutes(router, mealController)
-	routes.SetupDashboardRoutes(router, dashboardController)
+	stores, err := newMongoStores(db, unitOfWork)
+	if err != nil {
+		log.Fatal("Could not open the repositories: ", err)
+	}
+	router, accountService := newRouter(cfg, db, stores, mailer, middleware.AuthMiddleware)
+	// Deleted accounts are purged once their grace period is over
+	go accountService.RunScheduledJobs(time.Hour)
 
 	// Start the server
 	fmt.Println("Server is running on port " + cfg.Port)
@@ -70,3 +79,141 @@ func main() {
 		log.Fatal("Server failed to start:", err)
 	}
 }
+
+func runMigrations(db *mongo.Database) {
+	ctx, cancel := config.GetTimedContext(300)
+	defer cancel()
+
+	ran, err := migrations.NewRunner(db, repositories.NewMongoMigrationRepository(db)).Run(ctx)
+	if err != nil {
+		log.Fatal("Could not migrate the database:", err)
+	}
+	if len(ran) > 0 {
+		fmt.Println("Applied migrations:", ran)
+	}
+}
+
+func checkTransactions(unitOfWork *repositories.MongoUnitOfWork) {
+	ctx, cancel := config.GetTimedContext()
+	defer cancel()
+
+	if err := unitOfWork.CheckTransactions(ctx); err != nil {
+		log.Fatal("Could not check the transactions of the database: ", err)
+	}
+}
+
+// routerStores are the repositories with an in memory implementation, the contract tests seed them instead of
+// mocking every query
+type routerStores struct {
+	unitOfWork    repositories.UnitOfWork
+	users         repositories.UserRepository
+	userGoals     repositories.UserGoalRepository
+	meals         repositories.MealRepository
+	loginAttempts repositories.LoginAttemptStore
+	dataExports   repositories.DataExportRepository
+}
+
+func newMongoStores(db *mongo.Database, unitOfWork repositories.UnitOfWork) (routerStores, error) {
+	dataExports, err := repositories.NewMongoDataExportRepository(db)
+	if err != nil {
+		return routerStores{}, err
+	}
+	return routerStores{
+		unitOfWork:    unitOfWork,
+		users:         repositories.NewMongoUserRepository(db),
+		userGoals:     repositories.NewMongoUserGoalRepository(db),
+		meals:         repositories.NewMongoMealRepository(db),
+		loginAttempts: repositories.NewMongoLoginAttemptStore(db),
+		dataExports:   dataExports,
+	}, nil
+}
+
+// newRouter wires the repositories, services, controllers and routes on the database. The stores, the mailer and
+// the auth middleware are passed in so the contract tests can run without smtp and authenticate without signed tokens
+func newRouter(cfg *config.Config, db *mongo.Database, stores routerStores, mailer utils.Mailer, newAuthMiddleware func(*repositories.SessionRepository) gin.HandlerFunc) (*gin.Engine, *services.AccountService) {
+	unitOfWork := stores.unitOfWork
+
+	// Initialize repositories, and controllers
+	userRepo := stores.users
+	sessionRepo := repositories.NewSessionRepository(db)
+	userTokenRepo := repositories.NewUserTokenRepository(db)
+	loginAttemptStore := stores.loginAttempts
+	auditRepo := repositories.NewMongoAuditRepository(db)
+	userService := services.NewUserService(userRepo)
+	userController := controllers.NewUserController(userService, userRepo, sessionRepo, userTokenRepo, loginAttemptStore, auditRepo, mailer)
+
+	oidcStateRepo := repositories.NewOidcStateRepository(db)
+	oidcController := controllers.NewOidcController(userController, oidcStateRepo, utils.NewOidcClients(cfg))
+
+	coachLinkRepo := repositories.NewCoachLinkRepository(db)
+	coachController := controllers.NewCoachController(userRepo, coachLinkRepo, mailer)
+	// Decides who can act on a user's data, used by routes and controllers
+	userAccess := middleware.NewUserAccess(coachLinkRepo)
+
+	userGoalRepo := stores.userGoals
+	mealRepo := stores.meals
+	goalService := services.NewGoalService(userRepo, userGoalRepo, mealRepo, unitOfWork)
+	userGoalController := controllers.NewUserGoalController(goalService, userAccess)
+
+	mealPlanService := services.NewMealPlanService(userRepo, userGoalRepo, mealRepo, unitOfWork)
+	mealController := controllers.NewMealController(mealPlanService, goalService, userAccess)
+
+	hydrationRepo := repositories.NewHydrationRepository(db)
+	hydrationController := controllers.NewHydrationController(userGoalRepo, hydrationRepo)
+
+	workoutRepo := repositories.NewWorkoutRepository(db)
+	workoutController := controllers.NewWorkoutController(userRepo, userGoalRepo, workoutRepo, unitOfWork)
+
+	activityRepo := repositories.NewActivityRepository(db)
+	activityController := controllers.NewActivityController(userRepo, userGoalRepo, activityRepo)
+
+	dashboardService := services.NewDashboardService(userRepo, userGoalRepo, mealRepo, hydrationRepo, workoutRepo, activityRepo)
+	dashboardController := controllers.NewDashboardController(dashboardService)
+
+	// Operator actions, shared with the fiteats-admin cli
+	adminService := services.NewAdminService(userRepo, sessionRepo, userTokenRepo, loginAttemptStore, userGoalRepo, mealRepo, auditRepo, unitOfWork)
+	adminController := controllers.NewAdminController(adminService)
+
+	accountRepo := repositories.NewMongoAccountRepository(db)
+	dataExportRepo := stores.dataExports
+	accountService := services.NewAccountService(userRepo, sessionRepo, accountRepo, dataExportRepo, loginAttemptStore, auditRepo, mailer, unitOfWork)
+	accountController := controllers.NewAccountController(userController, accountService)
+
+	// Set up Gin router
+	router := gin.Default()
+	// Without trusted proxies the client ip is the remote address, X-Forwarded-For could be set by anyone
+	if err := router.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		log.Fatal("Invalid TRUSTED_PROXIES: ", err)
+	}
+	router.Use(middleware.RequestIdMiddleware())
+	// Writes the errors of the handlers and middleware below as the shared error response
+	router.Use(middleware.ErrorMiddleware())
+
+	// Every protected route checks the access token against its session
+	authMiddleware := newAuthMiddleware(sessionRepo)
+	// AI generation is only available to verified accounts
+	verifiedEmailMiddleware := middleware.VerifiedEmailMiddleware(userRepo)
+	// Records who changed what on the mutating routes
+	auditRecorder := middleware.NewAuditRecorder(auditRepo)
+	auditController := controllers.NewAuditController(auditRepo)
+
+	// Define API routes
+	routes.SetupUserRoutes(router, userController, authMiddleware, auditRecorder)
+	routes.SetupOidcRoutes(router, oidcController, authMiddleware, auditRecorder)
+	routes.SetupCoachRoutes(router, coachController, authMiddleware, auditRecorder)
+	routes.SetupUserGoalRoutes(router, userGoalController, authMiddleware, verifiedEmailMiddleware, userAccess, auditRecorder)
+	routes.SetupMealRoutes(router, mealController, authMiddleware, verifiedEmailMiddleware, userAccess, auditRecorder)
+	routes.SetupHydrationRoutes(router, hydrationController, authMiddleware, userAccess, auditRecorder)
+	routes.SetupWorkoutRoutes(router, workoutController, authMiddleware, verifiedEmailMiddleware, userAccess, auditRecorder)
+	routes.SetupActivityRoutes(router, activityController, authMiddleware, userAccess, auditRecorder)
+	routes.SetupDashboardRoutes(router, dashboardController, authMiddleware, userAccess)
+	routes.SetupAdminRoutes(router, adminController, authMiddleware)
+	routes.SetupAuditRoutes(router, auditController, authMiddleware)
+	routes.SetupAccountRoutes(router, accountController, authMiddleware, auditRecorder)
+	routes.SetupV1Routes(router, userController, userGoalController, mealController, authMiddleware, verifiedEmailMiddleware, userAccess, auditRecorder)
+
+	// Documents every route above, registered last so it sees them all
+	routes.SetupOpenApiRoutes(router)
+
+	return router, accountService
+}
@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"fit-eats-api/config"
+	"fit-eats-api/models"
+	"fit-eats-api/repositories"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// UserAccess decides whether the authenticated user may act on another user's data.
+// Users can always access their own data and admins can access everyone's,
+// coaches can access the goals and meal plans of clients with an active coach link.
+type UserAccess struct {
+	CoachLinkRepository *repositories.CoachLinkRepository
+}
+
+func NewUserAccess(coachLinkRepository *repositories.CoachLinkRepository) *UserAccess {
+	return &UserAccess{CoachLinkRepository: coachLinkRepository}
+}
+
+// CanAccess checks access to the data of ownerId, allowCoach is set for the goal and meal endpoints
+func (a *UserAccess) CanAccess(c *gin.Context, ownerId primitive.ObjectID, allowCoach bool) bool {
+	if IsSelfOrAdmin(c, ownerId) {
+		return true
+	}
+	if !allowCoach || GetRole(c) != models.ROLE_COACH {
+		return false
+	}
+
+	timedContext, cancel := config.GetTimedContext()
+	defer cancel()
+	return a.CoachLinkRepository.IsActiveCoach(timedContext, GetUserId(c), ownerId)
+}
+
+// IsSelfOrAdmin is the access rule for account data, which coaches cannot see
+func IsSelfOrAdmin(c *gin.Context, ownerId primitive.ObjectID) bool {
+	return ownerId == GetUserId(c) || GetRole(c) == models.ROLE_ADMIN
+}
+
+// OwnerMiddleware rejects requests whose userId query param is another user, except for admins
+func (a *UserAccess) OwnerMiddleware() gin.HandlerFunc {
+	return a.queryUserMiddleware(false)
+}
+
+// ClientMiddleware also lets linked coaches through, it guards the goal and meal routes
+func (a *UserAccess) ClientMiddleware() gin.HandlerFunc {
+	return a.queryUserMiddleware(true)
+}
+
+// queryUserMiddleware only checks the userId query param, handlers addressing data by another id
+// check the owner of that data with CanAccess
+func (a *UserAccess) queryUserMiddleware(allowCoach bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userId, ok := c.GetQuery("userId")
+		if !ok {
+			c.Next()
+			return
+		}
+
+		// Invalid ids are left for the handler to report
+		mongoUserId, err := primitive.ObjectIDFromHex(userId)
+		if err == nil && !a.CanAccess(c, mongoUserId, allowCoach) {
+			c.Error(models.NewApiError(models.FORBIDDEN, "You do not have access to this user"))
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,176 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+
+	"fit-eats-api/config"
+	"fit-eats-api/models"
+	"fit-eats-api/repositories"
+	"fit-eats-api/utils"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// AuditSnapshot loads the data a request changes and the user who owns it. It runs before and after the handler,
+// a nil snapshot means the data does not exist at that point.
+type AuditSnapshot func(c *gin.Context, ctx context.Context) (ownerId primitive.ObjectID, snapshot any, err error)
+
+// AUDIT_USER_ID_KEY holds the user a public route acted on, set by the handler with SetAuditUserId
+const AUDIT_USER_ID_KEY = "auditUserId"
+
+// SetAuditUserId names the user a request acted on for routes without a signed in user, like a password reset
+// confirmed with an emailed token. Public requests which did not act on a user are not recorded
+func SetAuditUserId(c *gin.Context, userId primitive.ObjectID) {
+	c.Set(AUDIT_USER_ID_KEY, userId)
+}
+
+// AUDIT_ACTION_KEY holds the action a handler actually took when the route can take several, set with SetAuditAction
+const AUDIT_ACTION_KEY = "auditAction"
+
+// SetAuditAction records the request as the action instead of the one of its route, like a social login callback
+// which registers a new account rather than linking the identity to an existing one
+func SetAuditAction(c *gin.Context, action models.AuditAction) {
+	c.Set(AUDIT_ACTION_KEY, action)
+}
+
+// AuditRecorder writes the changes made by successful requests to the audit log
+type AuditRecorder struct {
+	AuditRepository repositories.AuditRepository
+}
+
+func NewAuditRecorder(auditRepository repositories.AuditRepository) *AuditRecorder {
+	return &AuditRecorder{AuditRepository: auditRepository}
+}
+
+// Audit records the action with the difference between the snapshots taken around the handler, snapshot can be nil
+// for actions whose data is not worth keeping, like security settings
+func (r *AuditRecorder) Audit(action models.AuditAction, snapshot AuditSnapshot) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ownerId primitive.ObjectID
+		var before any
+		if snapshot != nil {
+			ownerId, before = r.takeSnapshot(c, snapshot)
+		}
+
+		c.Next()
+		if c.Writer.Status() >= 400 || c.IsAborted() || len(c.Errors) > 0 {
+			return
+		}
+
+		var changes []models.AuditChange
+		if snapshot != nil {
+			afterOwnerId, after := r.takeSnapshot(c, snapshot)
+			if !afterOwnerId.IsZero() {
+				ownerId = afterOwnerId
+			}
+			changes = utils.DiffSnapshots(before, after)
+		}
+		r.record(c, action, ownerId, changes)
+	}
+}
+
+// AuditCreated records the action with the response body as the created data, for handlers which return what they created
+func (r *AuditRecorder) AuditCreated(action models.AuditAction) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		writer := &bodyCaptureWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+
+		c.Next()
+		if c.Writer.Status() >= 400 || c.IsAborted() || len(c.Errors) > 0 {
+			return
+		}
+
+		var created map[string]any
+		if err := json.Unmarshal(writer.body.Bytes(), &created); err != nil {
+			created = nil
+		}
+
+		// Owned data carries the userId of its owner
+		var ownerId primitive.ObjectID
+		if userId, ok := created["userId"].(string); ok {
+			ownerId, _ = primitive.ObjectIDFromHex(userId)
+		}
+		r.record(c, action, ownerId, utils.DiffSnapshots(nil, created))
+	}
+}
+
+func (r *AuditRecorder) takeSnapshot(c *gin.Context, snapshot AuditSnapshot) (primitive.ObjectID, any) {
+	timedContext, cancel := config.GetTimedContext()
+	defer cancel()
+
+	ownerId, value, err := snapshot(c, timedContext)
+	if err != nil {
+		log.Printf("Could not take audit snapshot for %s: %v", c.FullPath(), err)
+		return primitive.NilObjectID, nil
+	}
+	return ownerId, value
+}
+
+func (r *AuditRecorder) record(c *gin.Context, action models.AuditAction, ownerId primitive.ObjectID, changes []models.AuditChange) {
+	// On public routes the user the handler acted on is also the actor, holding the emailed token or the identity
+	actorId := GetUserId(c)
+	if actorId.IsZero() {
+		auditUserId, _ := c.Get(AUDIT_USER_ID_KEY)
+		actorId, _ = auditUserId.(primitive.ObjectID)
+	}
+	if ownerId.IsZero() {
+		ownerId = actorId
+	}
+	if ownerId.IsZero() {
+		return
+	}
+	if handlerAction, ok := c.Get(AUDIT_ACTION_KEY); ok {
+		action = handlerAction.(models.AuditAction)
+	}
+
+	details := map[string]any{"method": c.Request.Method, "path": c.FullPath()}
+	if query := c.Request.URL.Query(); len(query) > 0 {
+		details["query"] = query
+	}
+
+	auditLog := models.AuditLog{
+		UserId:    ownerId,
+		Actor:     &models.AuditActor{ID: actorId, Name: GetEmail(c), Source: models.AUDIT_SOURCE_API},
+		Action:    action,
+		IP:        c.ClientIP(),
+		RequestId: GetRequestId(c),
+		Details:   details,
+		Changes:   changes,
+	}
+
+	timedContext, cancel := config.GetTimedContext()
+	defer cancel()
+	if err := r.AuditRepository.CreateAuditLog(timedContext, &auditLog); err != nil {
+		log.Printf("Could not write audit log for request %s: %v", auditLog.RequestId, err)
+	}
+}
+
+// PeekJSONBody decodes the json body for a snapshot and puts it back for the handler
+func PeekJSONBody(c *gin.Context, target any) error {
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		return err
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+	return json.Unmarshal(body, target)
+}
+
+type bodyCaptureWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *bodyCaptureWriter) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *bodyCaptureWriter) WriteString(data string) (int, error) {
+	w.body.WriteString(data)
+	return w.ResponseWriter.WriteString(data)
+}
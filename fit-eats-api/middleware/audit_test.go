@@ -0,0 +1,111 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"fit-eats-api/models"
+	"fit-eats-api/repositories"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+type auditTestLog struct {
+	AmountInMl int `json:"amountInMl"`
+}
+
+// serveAudited runs the handler as the user behind the audit of the action, returning the audit log it inserted if any
+func serveAudited(mt *mtest.T, userId primitive.ObjectID, action models.AuditAction, snapshot AuditSnapshot, handler gin.HandlerFunc) *models.AuditLog {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(RequestIdMiddleware(), func(c *gin.Context) {
+		c.Set(USER_ID_KEY, userId)
+		c.Next()
+	})
+	router.DELETE("/", NewAuditRecorder(repositories.NewMongoAuditRepository(mt.DB)).Audit(action, snapshot), handler)
+
+	mt.AddMockResponses(mtest.CreateSuccessResponse())
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodDelete, "/", nil))
+
+	for event := mt.GetStartedEvent(); event != nil; event = mt.GetStartedEvent() {
+		if event.CommandName != "insert" {
+			continue
+		}
+		var auditLog models.AuditLog
+		if err := bson.Unmarshal(event.Command.Lookup("documents").Array().Index(0).Value().Document(), &auditLog); err != nil {
+			mt.Fatal(err)
+		}
+		return &auditLog
+	}
+	return nil
+}
+
+func TestAuditRecordsDeletedSnapshot(t *testing.T) {
+	userId := primitive.NewObjectID()
+	ownerId := primitive.NewObjectID()
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	mt.Run("deleted", func(mt *mtest.T) {
+		waterLog := &auditTestLog{AmountInMl: 250}
+		snapshot := func(c *gin.Context, ctx context.Context) (primitive.ObjectID, any, error) {
+			if waterLog == nil {
+				return ownerId, nil, nil
+			}
+			return ownerId, waterLog, nil
+		}
+
+		auditLog := serveAudited(mt, userId, models.AUDIT_WATER_LOG_DELETED, snapshot, func(c *gin.Context) {
+			waterLog = nil
+			c.Status(http.StatusOK)
+		})
+		if auditLog == nil {
+			mt.Fatal("expected the delete to be recorded")
+		}
+		if auditLog.Action != models.AUDIT_WATER_LOG_DELETED || auditLog.UserId != ownerId || auditLog.Actor.ID != userId || auditLog.RequestId == "" {
+			mt.Fatalf("expected the delete of the owner's log by the user, got %+v", auditLog)
+		}
+		if len(auditLog.Changes) != 1 || auditLog.Changes[0].Path != "" || auditLog.Changes[0].Before == nil || auditLog.Changes[0].After != nil {
+			mt.Fatalf("expected the deleted log in the changes, got %+v", auditLog.Changes)
+		}
+	})
+
+	mt.Run("failed", func(mt *mtest.T) {
+		auditLog := serveAudited(mt, userId, models.AUDIT_WATER_LOG_DELETED, nil, func(c *gin.Context) {
+			c.Error(models.NewApiError(models.NOT_FOUND, "Water log not found"))
+			c.Status(http.StatusNotFound)
+		})
+		if auditLog != nil {
+			mt.Fatalf("expected a failed request not to be recorded, got %+v", auditLog)
+		}
+	})
+}
+
+func TestAuditHandlerAction(t *testing.T) {
+	userId := primitive.NewObjectID()
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	mt.Run("registered", func(mt *mtest.T) {
+		auditLog := serveAudited(mt, userId, models.AUDIT_IDENTITY_LINKED, nil, func(c *gin.Context) {
+			SetAuditAction(c, models.AUDIT_USER_REGISTERED)
+			c.Status(http.StatusOK)
+		})
+		if auditLog == nil || auditLog.Action != models.AUDIT_USER_REGISTERED {
+			mt.Fatalf("expected the action of the handler to be recorded, got %+v", auditLog)
+		}
+	})
+
+	mt.Run("route action", func(mt *mtest.T) {
+		auditLog := serveAudited(mt, userId, models.AUDIT_IDENTITY_LINKED, nil, func(c *gin.Context) {
+			c.Status(http.StatusOK)
+		})
+		if auditLog == nil || auditLog.Action != models.AUDIT_IDENTITY_LINKED {
+			mt.Fatalf("expected the action of the route to be recorded, got %+v", auditLog)
+		}
+	})
+}
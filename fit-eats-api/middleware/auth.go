@@ -1,31 +1,101 @@
 package middleware
 
 import (
-	"net/http"
+	"log"
 	"strings"
 
+	"fit-eats-api/config"
+	"fit-eats-api/models"
+	"fit-eats-api/repositories"
 	"fit-eats-api/utils"
 
 	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
-func AuthMiddleware() gin.HandlerFunc {
+// Keys under which the authenticated identity is stored in the gin context
+const (
+	USER_ID_KEY    = "userId"
+	EMAIL_KEY      = "email"
+	SESSION_ID_KEY = "sessionId"
+	ROLE_KEY       = "role"
+)
+
+func AuthMiddleware(sessionRepository *repositories.SessionRepository) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "No token provided"})
+			c.Error(models.NewApiError(models.UNAUTHORIZED, "No token provided"))
 			c.Abort()
 			return
 		}
 
 		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
 
-		if !utils.IsAccessTokenValid(tokenString) {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid jwt token"})
+		claims, err := utils.ParseAccessToken(tokenString)
+		if err != nil {
+			log.Printf("Error parsing token: %v", err)
+			c.Error(models.NewApiError(models.UNAUTHORIZED, "Invalid jwt token"))
+			c.Abort()
+			return
+		}
+
+		// Access tokens of a revoked session stop working straight away instead of at expiry
+		timedContext, cancel := config.GetTimedContext()
+		defer cancel()
+
+		if !sessionRepository.IsSessionActive(timedContext, claims.SessionId) {
+			c.Error(models.NewApiError(models.UNAUTHORIZED, "Session expired or revoked"))
 			c.Abort()
 			return
 		}
 
+		c.Set(USER_ID_KEY, claims.UserId)
+		c.Set(EMAIL_KEY, claims.Email)
+		c.Set(SESSION_ID_KEY, claims.SessionId)
+		c.Set(ROLE_KEY, claims.Role)
+
 		c.Next()
 	}
 }
+
+func GetUserId(c *gin.Context) primitive.ObjectID {
+	userId, _ := c.Get(USER_ID_KEY)
+	mongoUserId, _ := userId.(primitive.ObjectID)
+	return mongoUserId
+}
+
+func GetEmail(c *gin.Context) string {
+	return c.GetString(EMAIL_KEY)
+}
+
+func GetSessionId(c *gin.Context) primitive.ObjectID {
+	sessionId, _ := c.Get(SESSION_ID_KEY)
+	mongoSessionId, _ := sessionId.(primitive.ObjectID)
+	return mongoSessionId
+}
+
+func GetRole(c *gin.Context) models.Role {
+	role, _ := c.Get(ROLE_KEY)
+	userRole, _ := role.(models.Role)
+	if userRole == "" {
+		return models.ROLE_USER
+	}
+	return userRole
+}
+
+// RequireRole only lets users with one of the roles through, it must run after AuthMiddleware
+func RequireRole(roles ...models.Role) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role := GetRole(c)
+		for _, allowed := range roles {
+			if role == allowed {
+				c.Next()
+				return
+			}
+		}
+
+		c.Error(models.NewApiError(models.FORBIDDEN, "You are not allowed to use this feature"))
+		c.Abort()
+	}
+}
@@ -0,0 +1,111 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"sort"
+
+	"fit-eats-api/models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// errorStatuses maps the error codes to their http status, codes missing here are server errors
+var errorStatuses = map[models.ErrorCode]int{
+	models.INVALID_REQUEST:   http.StatusBadRequest,
+	models.VALIDATION_FAILED: http.StatusBadRequest,
+	models.INVALID_STATE:     http.StatusConflict,
+	models.ALREADY_EXISTS:    http.StatusConflict,
+
+	models.UNAUTHORIZED:        http.StatusUnauthorized,
+	models.INVALID_CREDENTIALS: http.StatusUnauthorized,
+	models.INVALID_MFA_CODE:    http.StatusBadRequest,
+	models.INVALID_LINK:        http.StatusBadRequest,
+	models.LOGIN_LOCKED:        http.StatusLocked,
+	models.TOO_MANY_ATTEMPTS:   http.StatusTooManyRequests,
+
+	models.FORBIDDEN:          http.StatusForbidden,
+	models.EMAIL_NOT_VERIFIED: http.StatusForbidden,
+	models.PROFILE_INCOMPLETE: http.StatusUnprocessableEntity,
+
+	models.NOT_FOUND:                 http.StatusNotFound,
+	models.USER_NOT_FOUND:            http.StatusNotFound,
+	models.SESSION_NOT_FOUND:         http.StatusNotFound,
+	models.GOAL_NOT_FOUND:            http.StatusNotFound,
+	models.WEEKLY_GOAL_NOT_FOUND:     http.StatusNotFound,
+	models.MEAL_PLAN_NOT_FOUND:       http.StatusNotFound,
+	models.MEAL_NOT_FOUND:            http.StatusNotFound,
+	models.WORKOUT_ROUTINE_NOT_FOUND: http.StatusNotFound,
+	models.LOG_NOT_FOUND:             http.StatusNotFound,
+	models.COACH_LINK_NOT_FOUND:      http.StatusNotFound,
+	models.IDENTITY_NOT_FOUND:        http.StatusNotFound,
+	models.EXPORT_NOT_FOUND:          http.StatusNotFound,
+
+	models.LLM_UNAVAILABLE:      http.StatusServiceUnavailable,
+	models.LLM_INVALID_RESPONSE: http.StatusBadGateway,
+	models.DIET_VIOLATION:       http.StatusBadGateway,
+	models.PROVIDER_UNAVAILABLE: http.StatusBadGateway,
+
+	models.TIMEOUT:        http.StatusGatewayTimeout,
+	models.INTERNAL_ERROR: http.StatusInternalServerError,
+}
+
+// ErrorMiddleware writes the error passed to ctx.Error by the handlers and middleware as an ErrorResponse.
+// It must run before the other middleware so that it sees the errors of all of them
+func ErrorMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+
+		apiError := ToApiError(c.Errors.Last().Err)
+		status := GetErrorStatus(apiError.Code)
+		if status >= http.StatusInternalServerError {
+			log.Printf("Request %s %s %s failed: %v", GetRequestId(c), c.Request.Method, c.FullPath(), apiError)
+		}
+
+		c.JSON(status, models.ErrorResponse{
+			Error:     apiError.Message,
+			Code:      apiError.Code,
+			Fields:    apiError.Fields,
+			Details:   apiError.Details,
+			RequestId: GetRequestId(c),
+		})
+	}
+}
+
+// ToApiError turns the errors handlers pass on unwrapped, like those of the repositories, into an ApiError
+func ToApiError(err error) *models.ApiError {
+	var apiError *models.ApiError
+	switch {
+	case errors.As(err, &apiError):
+		return apiError
+	case errors.Is(err, mongo.ErrNoDocuments):
+		return models.NewApiError(models.NOT_FOUND, "Not found").WithCause(err)
+	case errors.Is(err, context.DeadlineExceeded):
+		return models.NewApiError(models.TIMEOUT, "The request took too long, please try again").WithCause(err)
+	}
+	return models.NewApiError(models.INTERNAL_ERROR, "Something went wrong").WithCause(err)
+}
+
+// GetErrorCodes lists the codes sent to clients, sorted for the api documentation
+func GetErrorCodes() []models.ErrorCode {
+	codes := make([]models.ErrorCode, 0, len(errorStatuses))
+	for code := range errorStatuses {
+		codes = append(codes, code)
+	}
+	sort.Slice(codes, func(i, j int) bool { return codes[i] < codes[j] })
+	return codes
+}
+
+func GetErrorStatus(code models.ErrorCode) int {
+	if status, ok := errorStatuses[code]; ok {
+		return status
+	}
+	return http.StatusInternalServerError
+}
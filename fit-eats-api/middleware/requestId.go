@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"regexp"
+
+	"fit-eats-api/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	REQUEST_ID_KEY    = "requestId"
+	REQUEST_ID_HEADER = "X-Request-Id"
+)
+
+// Ids sent by clients or proxies are kept when they are short and safe to log
+var requestIdPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// RequestIdMiddleware gives every request an id, returned in the X-Request-Id header and written to the audit log
+func RequestIdMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestId := c.GetHeader(REQUEST_ID_HEADER)
+		if !requestIdPattern.MatchString(requestId) {
+			requestId = utils.GenerateRandomToken(16)
+		}
+
+		c.Set(REQUEST_ID_KEY, requestId)
+		c.Header(REQUEST_ID_HEADER, requestId)
+
+		c.Next()
+	}
+}
+
+func GetRequestId(c *gin.Context) string {
+	return c.GetString(REQUEST_ID_KEY)
+}
@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// serveRequestId answers with the request id the handlers see, sending the header when it is not empty
+func serveRequestId(header string) (*httptest.ResponseRecorder, string) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(RequestIdMiddleware())
+
+	var requestId string
+	router.GET("/", func(c *gin.Context) {
+		requestId = GetRequestId(c)
+		c.Status(http.StatusOK)
+	})
+
+	request := httptest.NewRequest(http.MethodGet, "/", nil)
+	if header != "" {
+		request.Header.Set(REQUEST_ID_HEADER, header)
+	}
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+	return recorder, requestId
+}
+
+func TestRequestIdMiddlewareKeepsValidId(t *testing.T) {
+	recorder, requestId := serveRequestId("proxy-7f3a.01_b")
+
+	if requestId != "proxy-7f3a.01_b" {
+		t.Fatalf("expected the id of the client, got %q", requestId)
+	}
+	if header := recorder.Header().Get(REQUEST_ID_HEADER); header != requestId {
+		t.Fatalf("expected the id to be returned, got %q", header)
+	}
+}
+
+func TestRequestIdMiddlewareGeneratesId(t *testing.T) {
+	invalidIds := map[string]string{
+		"missing":    "",
+		"too long":   strings.Repeat("a", 65),
+		"spaces":     "id with spaces",
+		"log breaks": "id\r\nforged: entry",
+	}
+
+	seen := map[string]bool{}
+	for name, header := range invalidIds {
+		recorder, requestId := serveRequestId(header)
+
+		if requestId == "" || requestId == header || !requestIdPattern.MatchString(requestId) {
+			t.Fatalf("%s: expected a generated id, got %q", name, requestId)
+		}
+		if seen[requestId] {
+			t.Fatalf("%s: expected a new id, got %q again", name, requestId)
+		}
+		seen[requestId] = true
+		if header := recorder.Header().Get(REQUEST_ID_HEADER); header != requestId {
+			t.Fatalf("%s: expected the generated id to be returned, got %q", name, header)
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"fit-eats-api/config"
+	"fit-eats-api/models"
+	"fit-eats-api/repositories"
+
+	"github.com/gin-gonic/gin"
+)
+
+// VerifiedEmailMiddleware restricts a route to users with a verified email, it must run after AuthMiddleware.
+// It guards the ai generation endpoints so throwaway accounts cannot be used to burn model quota.
+func VerifiedEmailMiddleware(userRepository repositories.UserRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		timedContext, cancel := config.GetTimedContext()
+		defer cancel()
+
+		user, err := userRepository.GetUserProfileById(timedContext, GetUserId(c))
+		if err != nil {
+			c.Error(models.NewApiError(models.UNAUTHORIZED, "User not found"))
+			c.Abort()
+			return
+		}
+
+		if !user.EmailVerified {
+			c.Error(models.NewApiError(models.EMAIL_NOT_VERIFIED, "Please verify your email to use this feature"))
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,46 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"fit-eats-api/models"
+	"fit-eats-api/repositories"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Mongo error codes of dropping an index which is already gone
+const (
+	namespaceNotFoundCode = 26
+	indexNotFoundCode     = 27
+)
+
+// goalStatusIndexes list the goals of a user by start date and allow a single active goal per user
+var goalStatusIndexes = map[string][]mongo.IndexModel{
+	"userGoals": {
+		repositories.NewIndex("userId_goalStartDate", bson.D{{Key: "userId", Value: 1}, {Key: "goalStartDate", Value: -1}}, nil),
+		repositories.NewIndex("userId_active_unique", bson.D{{Key: "userId", Value: 1}},
+			options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{"status": models.GOAL_ACTIVE})),
+	},
+}
+
+// addGoalStatus makes the goals created before the goal lifecycle active, a user could only have one goal then, and
+// replaces the userId index of the goals with the indexes of the goal history and the single active goal
+func addGoalStatus(ctx context.Context, db *mongo.Database) error {
+	collection := db.Collection("userGoals")
+	filter := bson.M{"status": bson.M{"$exists": false}}
+	if _, err := collection.UpdateMany(ctx, filter, bson.M{"$set": bson.M{"status": models.GOAL_ACTIVE}}); err != nil {
+		return fmt.Errorf("could not set the status of the goals: %w", err)
+	}
+
+	var commandErr mongo.CommandError
+	if _, err := collection.Indexes().DropOne(ctx, "userId"); err != nil &&
+		!(errors.As(err, &commandErr) && (commandErr.Code == namespaceNotFoundCode || commandErr.Code == indexNotFoundCode)) {
+		return fmt.Errorf("could not drop the userId index of the goals: %w", err)
+	}
+
+	return repositories.CreateCollectionIndexes(ctx, db, goalStatusIndexes)
+}
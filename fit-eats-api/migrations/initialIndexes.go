@@ -0,0 +1,108 @@
+package migrations
+
+import (
+	"context"
+	"fit-eats-api/repositories"
+	"fmt"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// initialIndexes are the indexes of the collections when the migrations were introduced
+var initialIndexes = map[string][]mongo.IndexModel{
+	"users": {
+		repositories.NewIndex("email_unique", bson.D{{Key: "email", Value: 1}}, options.Index().SetUnique(true)),
+		repositories.NewIndex("identities_provider_subject", bson.D{{Key: "identities.provider", Value: 1}, {Key: "identities.subject", Value: 1}}, nil),
+		repositories.NewIndex("deletionScheduledAt", bson.D{{Key: "deletionScheduledAt", Value: 1}}, options.Index().SetSparse(true)),
+	},
+	"userGoals": {
+		repositories.NewIndex("userId", bson.D{{Key: "userId", Value: 1}}, nil),
+	},
+	"meals": {
+		repositories.NewIndex("userId_weeklyGoalId_mainGoalId", bson.D{{Key: "userId", Value: 1}, {Key: "weeklyGoalId", Value: 1}, {Key: "mainGoalId", Value: 1}}, nil),
+		repositories.NewIndex("mainGoalId_weeklyGoalId", bson.D{{Key: "mainGoalId", Value: 1}, {Key: "weeklyGoalId", Value: 1}}, nil),
+		repositories.NewIndex("userId_dayMeals_date", bson.D{{Key: "userId", Value: 1}, {Key: "dayMeals.date", Value: 1}}, nil),
+		repositories.NewIndex("dayMeals_meals_id", bson.D{{Key: "dayMeals.meals._id", Value: 1}}, nil),
+	},
+	"workoutRoutines": {
+		repositories.NewIndex("userId_weeklyGoalId_mainGoalId", bson.D{{Key: "userId", Value: 1}, {Key: "weeklyGoalId", Value: 1}, {Key: "mainGoalId", Value: 1}}, nil),
+		repositories.NewIndex("userId_workoutDays_date", bson.D{{Key: "userId", Value: 1}, {Key: "workoutDays.date", Value: 1}}, nil),
+	},
+	"hydrationLogs": {
+		repositories.NewIndex("userId_loggedAt", bson.D{{Key: "userId", Value: 1}, {Key: "loggedAt", Value: 1}}, nil),
+	},
+	"workoutSessions": {
+		repositories.NewIndex("userId_date", bson.D{{Key: "userId", Value: 1}, {Key: "date", Value: 1}}, nil),
+	},
+	"activityLogs": {
+		repositories.NewIndex("userId_date", bson.D{{Key: "userId", Value: 1}, {Key: "date", Value: 1}}, nil),
+	},
+	"coachLinks": {
+		repositories.NewIndex("coachId_clientId", bson.D{{Key: "coachId", Value: 1}, {Key: "clientId", Value: 1}}, nil),
+		repositories.NewIndex("clientId_status", bson.D{{Key: "clientId", Value: 1}, {Key: "status", Value: 1}}, nil),
+	},
+	"auditLogs": {
+		repositories.NewIndex("userId_createdAt", bson.D{{Key: "userId", Value: 1}, {Key: "createdAt", Value: -1}}, nil),
+		repositories.NewIndex("actor_id_createdAt", bson.D{{Key: "actor.id", Value: 1}, {Key: "createdAt", Value: -1}}, nil),
+		repositories.NewIndex("createdAt", bson.D{{Key: "createdAt", Value: -1}}, nil),
+	},
+	"sessions": {
+		repositories.NewIndex("userId", bson.D{{Key: "userId", Value: 1}}, nil),
+		repositories.NewIndex("expiresAt_ttl", bson.D{{Key: "expiresAt", Value: 1}}, options.Index().SetExpireAfterSeconds(0)),
+	},
+	"userTokens": {
+		repositories.NewIndex("tokenHash", bson.D{{Key: "tokenHash", Value: 1}}, nil),
+		repositories.NewIndex("userId_purpose", bson.D{{Key: "userId", Value: 1}, {Key: "purpose", Value: 1}}, nil),
+		repositories.NewIndex("expiresAt_ttl", bson.D{{Key: "expiresAt", Value: 1}}, options.Index().SetExpireAfterSeconds(0)),
+	},
+	"oidcStates": {
+		repositories.NewIndex("stateHash", bson.D{{Key: "stateHash", Value: 1}}, nil),
+		repositories.NewIndex("expiresAt_ttl", bson.D{{Key: "expiresAt", Value: 1}}, options.Index().SetExpireAfterSeconds(0)),
+	},
+	"dataExports": {
+		repositories.NewIndex("userId_status_createdAt", bson.D{{Key: "userId", Value: 1}, {Key: "status", Value: 1}, {Key: "createdAt", Value: 1}}, nil),
+		repositories.NewIndex("expiresAt", bson.D{{Key: "expiresAt", Value: 1}}, nil),
+	},
+}
+
+// createInitialIndexes creates the initial indexes. Users could register the same email twice before the unique email
+// index, the migration fails listing the ids of these accounts so that they can be merged first
+func createInitialIndexes(ctx context.Context, db *mongo.Database) error {
+	pipeline := mongo.Pipeline{
+		{{Key: "$group", Value: bson.M{"_id": "$email", "ids": bson.M{"$push": "$_id"}}}},
+		{{Key: "$match", Value: bson.M{"ids.1": bson.M{"$exists": true}}}},
+	}
+	cursor, err := db.Collection("users").Aggregate(ctx, pipeline)
+	if err != nil {
+		return fmt.Errorf("could not find the duplicate emails: %w", err)
+	}
+
+	var emails []struct {
+		Email string               `bson:"_id"`
+		Ids   []primitive.ObjectID `bson:"ids"`
+	}
+	if err := cursor.All(ctx, &emails); err != nil {
+		return fmt.Errorf("could not read the duplicate emails: %w", err)
+	}
+	if len(emails) > 0 {
+		conflicts := make([]string, len(emails))
+		for i, email := range emails {
+			conflicts[i] = email.Email + " (" + joinIds(email.Ids) + ")"
+		}
+		return fmt.Errorf("could not create the unique email index, several accounts have the emails %s", strings.Join(conflicts, ", "))
+	}
+
+	return repositories.CreateCollectionIndexes(ctx, db, initialIndexes)
+}
+
+func joinIds(ids []primitive.ObjectID) string {
+	hexIds := make([]string, len(ids))
+	for i, id := range ids {
+		hexIds[i] = id.Hex()
+	}
+	return strings.Join(hexIds, ", ")
+}
@@ -0,0 +1,32 @@
+package migrations
+
+import (
+	"context"
+	"fit-eats-api/models"
+	"fit-eats-api/repositories"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// loginAttemptIndexes remove the failed login counters at their expiry
+var loginAttemptIndexes = map[string][]mongo.IndexModel{
+	"loginAttempts": {
+		repositories.NewIndex("expireAt_ttl", bson.D{{Key: "expireAt", Value: 1}}, options.Index().SetExpireAfterSeconds(0)),
+	},
+}
+
+// expireLoginAttempts gives the failed login counters stored before the failure window an expiry, the end of their
+// window or of their lock, and creates the ttl index removing them
+func expireLoginAttempts(ctx context.Context, db *mongo.Database) error {
+	windowEnd := bson.M{"$add": bson.A{"$lastFailedAt", max(models.AccountLoginPolicy.FailureWindow, models.IpLoginPolicy.FailureWindow).Milliseconds()}}
+	update := mongo.Pipeline{{{Key: "$set", Value: bson.M{"expireAt": bson.M{"$max": bson.A{"$lockedUntil", windowEnd}}}}}}
+	filter := bson.M{"expireAt": bson.M{"$exists": false}}
+	if _, err := db.Collection("loginAttempts").UpdateMany(ctx, filter, update); err != nil {
+		return fmt.Errorf("could not set the expiry of the login attempts: %w", err)
+	}
+
+	return repositories.CreateCollectionIndexes(ctx, db, loginAttemptIndexes)
+}
@@ -0,0 +1,138 @@
+// Package migrations applies the index and data changes of the database in order and records each applied
+// migration in the migrations collection, so a migration runs once per database.
+//
+// Migrations run when the server starts and with the fiteats-migrate cli. Two instances starting together may
+// both apply a pending migration, so every migration must be safe to run twice. A migration is recorded once
+// applied, so it never changes afterwards: the indexes it creates are its own copy rather than the current ones.
+package migrations
+
+import (
+	"context"
+	"fit-eats-api/models"
+	"fit-eats-api/repositories"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Migration changes the database once, ids sort in the order the migrations are applied
+type Migration struct {
+	Id          string
+	Description string
+	Up          func(ctx context.Context, db *mongo.Database) error
+}
+
+// Migrations are applied in this order, new migrations are appended with the next id
+var Migrations = []Migration{
+	{"0001_create_indexes", "Create the indexes of the collections, a unique email and ttl indexes for sessions and tokens", createInitialIndexes},
+	{"0002_goal_status", "Make the existing goals active and allow a single active goal per user", addGoalStatus},
+	{"0003_verify_existing_emails", "Mark the emails of the users registered before email verification as verified", verifyExistingEmails},
+	{"0004_login_attempt_ttl", "Expire the failed login counters at the end of their failure window or lock", expireLoginAttempts},
+	{"0005_normalize_emails", "Lowercase and trim the emails of the users so that the lookups by email ignore their case", normalizeEmails},
+	{"0006_unique_meal_plans", "Keep the meal plan with consumed meals, or the first one, of every weekly goal and allow a single meal plan per weekly goal", removeDuplicateMealPlans},
+}
+
+// MigrationStatus tells whether the migration is applied and when
+type MigrationStatus struct {
+	Id          string                  `json:"id"`
+	Description string                  `json:"description"`
+	Applied     *models.MigrationRecord `json:"applied,omitempty"`
+}
+
+type Runner struct {
+	Database            *mongo.Database
+	MigrationRepository repositories.MigrationRepository
+	Migrations          []Migration
+}
+
+func NewRunner(db *mongo.Database, migrationRepository repositories.MigrationRepository) *Runner {
+	return &Runner{Database: db, MigrationRepository: migrationRepository, Migrations: Migrations}
+}
+
+// GetStatus lists every migration with its record when it is applied
+func (r *Runner) GetStatus(ctx context.Context) ([]MigrationStatus, error) {
+	if err := r.validate(); err != nil {
+		return nil, err
+	}
+
+	applied, err := r.getApplied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := []MigrationStatus{}
+	for _, migration := range r.Migrations {
+		status := MigrationStatus{Id: migration.Id, Description: migration.Description}
+		if record, ok := applied[migration.Id]; ok {
+			status.Applied = &record
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// Run applies the pending migrations in order and returns the ids of the migrations it applied.
+// It stops at the first migration which fails, the migrations after it stay pending
+func (r *Runner) Run(ctx context.Context) ([]string, error) {
+	if err := r.validate(); err != nil {
+		return nil, err
+	}
+
+	applied, err := r.getApplied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ran := []string{}
+	for _, migration := range r.Migrations {
+		if _, ok := applied[migration.Id]; ok {
+			continue
+		}
+
+		start := time.Now()
+		if err := migration.Up(ctx, r.Database); err != nil {
+			return ran, fmt.Errorf("migration %s failed: %w", migration.Id, err)
+		}
+
+		record := models.MigrationRecord{
+			ID:          migration.Id,
+			Description: migration.Description,
+			AppliedAt:   time.Now(),
+			DurationMs:  time.Since(start).Milliseconds(),
+		}
+		// Another instance applied the same migration meanwhile, its record is kept
+		if err := r.MigrationRepository.RecordMigration(ctx, &record); err != nil && !mongo.IsDuplicateKeyError(err) {
+			return ran, fmt.Errorf("could not record migration %s: %w", migration.Id, err)
+		}
+		ran = append(ran, migration.Id)
+	}
+	return ran, nil
+}
+
+func (r *Runner) getApplied(ctx context.Context) (map[string]models.MigrationRecord, error) {
+	records, err := r.MigrationRepository.GetAppliedMigrations(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("could not get applied migrations: %w", err)
+	}
+
+	applied := map[string]models.MigrationRecord{}
+	for _, record := range records {
+		applied[record.ID] = record
+	}
+	return applied, nil
+}
+
+// validate checks the ids are set and strictly increasing, a migration added out of order would never run
+// on databases which already applied the migrations after it
+func (r *Runner) validate() error {
+	for i, migration := range r.Migrations {
+		if migration.Id == "" || migration.Up == nil {
+			return fmt.Errorf("migration %d needs an id and an up function", i)
+		}
+		if i > 0 && migration.Id <= r.Migrations[i-1].Id {
+			return fmt.Errorf("migration %s must sort after %s", migration.Id, r.Migrations[i-1].Id)
+		}
+	}
+	return nil
+}
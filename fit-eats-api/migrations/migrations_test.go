@@ -0,0 +1,122 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"fit-eats-api/repositories"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// newTestRunner runs the migrations against the in-memory repository, the migrations get a nil database
+func newTestRunner(migrations ...Migration) *Runner {
+	return &Runner{MigrationRepository: repositories.NewInMemoryMigrationRepository(), Migrations: migrations}
+}
+
+func countingMigration(id string, count *int) Migration {
+	return Migration{Id: id, Description: id, Up: func(ctx context.Context, db *mongo.Database) error {
+		*count++
+		return nil
+	}}
+}
+
+func TestRunAppliesPendingMigrationsOnce(t *testing.T) {
+	first, second := 0, 0
+	runner := newTestRunner(countingMigration("0001_first", &first), countingMigration("0002_second", &second))
+
+	ran, err := runner.Run(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(ran, []string{"0001_first", "0002_second"}) {
+		t.Fatalf("expected both migrations to run, got %v", ran)
+	}
+
+	ran, err = runner.Run(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(ran) != 0 || first != 1 || second != 1 {
+		t.Fatalf("expected every migration to run once, got %v and counts %d, %d", ran, first, second)
+	}
+
+	statuses, err := runner.GetStatus(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, status := range statuses {
+		if status.Applied == nil || status.Applied.AppliedAt.IsZero() {
+			t.Fatalf("expected %s to be recorded as applied, got %+v", status.Id, status)
+		}
+	}
+}
+
+func TestRunStopsAtFailedMigration(t *testing.T) {
+	first, third := 0, 0
+	failure := errors.New("index build failed")
+	runner := newTestRunner(
+		countingMigration("0001_first", &first),
+		Migration{Id: "0002_failing", Up: func(ctx context.Context, db *mongo.Database) error { return failure }},
+		countingMigration("0003_third", &third),
+	)
+
+	ran, err := runner.Run(context.Background())
+	if !errors.Is(err, failure) {
+		t.Fatalf("expected the migration error, got %v", err)
+	}
+	if !reflect.DeepEqual(ran, []string{"0001_first"}) || third != 0 {
+		t.Fatalf("expected only the first migration to run, got %v", ran)
+	}
+
+	statuses, err := runner.GetStatus(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if statuses[0].Applied == nil || statuses[1].Applied != nil || statuses[2].Applied != nil {
+		t.Fatalf("expected the failed and later migrations to stay pending, got %+v", statuses)
+	}
+}
+
+func TestRunKeepsRecordOfConcurrentRun(t *testing.T) {
+	count := 0
+	runner := newTestRunner(countingMigration("0001_first", &count))
+	// Another instance records the migration while this one applies it
+	other := newTestRunner(countingMigration("0001_first", &count))
+	other.MigrationRepository = runner.MigrationRepository
+	runner.Migrations[0].Up = func(ctx context.Context, db *mongo.Database) error {
+		if _, err := other.Run(ctx); err != nil {
+			return err
+		}
+		count++
+		return nil
+	}
+
+	ran, err := runner.Run(context.Background())
+	if err != nil {
+		t.Fatalf("expected the duplicate record to be ignored, got %v", err)
+	}
+	if len(ran) != 1 || count != 2 {
+		t.Fatalf("expected both runs to apply the migration, got %v and count %d", ran, count)
+	}
+}
+
+func TestRunRejectsUnorderedMigrations(t *testing.T) {
+	count := 0
+	runner := newTestRunner(countingMigration("0002_second", &count), countingMigration("0001_first", &count))
+
+	if _, err := runner.Run(context.Background()); err == nil {
+		t.Fatal("expected unordered migrations to be rejected")
+	}
+	if count != 0 {
+		t.Fatalf("expected no migration to run, %d ran", count)
+	}
+}
+
+func TestMigrationsAreOrdered(t *testing.T) {
+	if err := NewRunner(nil, repositories.NewInMemoryMigrationRepository()).validate(); err != nil {
+		t.Fatal(err)
+	}
+}
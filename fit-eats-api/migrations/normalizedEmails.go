@@ -0,0 +1,45 @@
+package migrations
+
+import (
+	"context"
+	"fit-eats-api/models"
+	"fmt"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// normalizeEmails lowercases and trims the emails stored before the users repository normalized them, so that the
+// lookups by email find them. Two accounts whose emails only differ in case cannot both be normalized, the migration
+// leaves them as they are and fails listing them so that they can be merged first
+func normalizeEmails(ctx context.Context, db *mongo.Database) error {
+	collection := db.Collection("users")
+	filter := bson.M{"$expr": bson.M{"$ne": bson.A{"$email", bson.M{"$toLower": bson.M{"$trim": bson.M{"input": "$email"}}}}}}
+	cursor, err := collection.Find(ctx, filter, options.Find().SetProjection(bson.M{"_id": 1, "email": 1}))
+	if err != nil {
+		return fmt.Errorf("could not find the emails to normalize: %w", err)
+	}
+
+	var users []models.User
+	if err := cursor.All(ctx, &users); err != nil {
+		return fmt.Errorf("could not read the emails to normalize: %w", err)
+	}
+
+	var conflicts []string
+	for _, user := range users {
+		_, err := collection.UpdateOne(ctx, bson.M{"_id": user.ID}, bson.M{"$set": bson.M{"email": models.NormalizeEmail(user.Email)}})
+		if mongo.IsDuplicateKeyError(err) {
+			conflicts = append(conflicts, user.Email)
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("could not normalize the email of user %s: %w", user.ID.Hex(), err)
+		}
+	}
+	if len(conflicts) > 0 {
+		return fmt.Errorf("could not normalize the emails %s, another account has the same email in another case", strings.Join(conflicts, ", "))
+	}
+	return nil
+}
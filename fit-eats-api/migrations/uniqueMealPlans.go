@@ -0,0 +1,95 @@
+package migrations
+
+import (
+	"context"
+	"fit-eats-api/repositories"
+	"fmt"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// mealPlanIndexes allow a single meal plan per weekly goal
+var mealPlanIndexes = map[string][]mongo.IndexModel{
+	"meals": {
+		repositories.NewIndex("weeklyGoalId_unique", bson.D{{Key: "weeklyGoalId", Value: 1}}, options.Index().SetUnique(true)),
+	},
+}
+
+// removeDuplicateMealPlans keeps a single meal plan per weekly goal and creates the unique index of the weekly goal.
+// Duplicates come from generations of the same week finishing together, the reads by week return any of them and
+// meals are consumed by id, so the plan to keep is the one with consumed meals, the first one when none has any.
+// Weeks with consumed meals in several plans are left as they are and the migration fails listing them, so that
+// no consumption is lost
+func removeDuplicateMealPlans(ctx context.Context, db *mongo.Database) error {
+	collection := db.Collection("meals")
+	pipeline := mongo.Pipeline{
+		{{Key: "$sort", Value: bson.M{"_id": 1}}},
+		{{Key: "$group", Value: bson.M{"_id": "$weeklyGoalId", "ids": bson.M{"$push": "$_id"}}}},
+		{{Key: "$match", Value: bson.M{"ids.1": bson.M{"$exists": true}}}},
+	}
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return fmt.Errorf("could not find the duplicate meal plans: %w", err)
+	}
+
+	var weeks []struct {
+		WeeklyGoalId primitive.ObjectID   `bson:"_id"`
+		Ids          []primitive.ObjectID `bson:"ids"`
+	}
+	if err := cursor.All(ctx, &weeks); err != nil {
+		return fmt.Errorf("could not read the duplicate meal plans: %w", err)
+	}
+
+	var conflicts []string
+	for _, week := range weeks {
+		consumed, err := getConsumedMealPlanIds(ctx, collection, week.Ids)
+		if err != nil {
+			return err
+		}
+		if len(consumed) > 1 {
+			conflicts = append(conflicts, week.WeeklyGoalId.Hex()+" ("+joinIds(consumed)+")")
+			continue
+		}
+
+		kept := week.Ids[0]
+		if len(consumed) == 1 {
+			kept = consumed[0]
+		}
+		filter := bson.M{"_id": bson.M{"$in": week.Ids, "$ne": kept}}
+		if _, err := collection.DeleteMany(ctx, filter); err != nil {
+			return fmt.Errorf("could not delete the duplicate meal plans of weekly goal %s: %w", week.WeeklyGoalId.Hex(), err)
+		}
+	}
+	if len(conflicts) > 0 {
+		return fmt.Errorf("could not remove the duplicate meal plans of the weekly goals %s, several of their meal plans have consumed meals",
+			strings.Join(conflicts, ", "))
+	}
+
+	return repositories.CreateCollectionIndexes(ctx, db, mealPlanIndexes)
+}
+
+// getConsumedMealPlanIds returns which of the meal plans have a consumed meal
+func getConsumedMealPlanIds(ctx context.Context, collection *mongo.Collection, mealPlanIds []primitive.ObjectID) ([]primitive.ObjectID, error) {
+	filter := bson.M{"_id": bson.M{"$in": mealPlanIds}, "dayMeals.meals.isConsumed": true}
+	cursor, err := collection.Find(ctx, filter, options.Find().SetProjection(bson.M{"_id": 1}).SetSort(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, fmt.Errorf("could not find the consumed meal plans: %w", err)
+	}
+
+	var mealPlans []struct {
+		ID primitive.ObjectID `bson:"_id"`
+	}
+	if err := cursor.All(ctx, &mealPlans); err != nil {
+		return nil, fmt.Errorf("could not read the consumed meal plans: %w", err)
+	}
+
+	ids := make([]primitive.ObjectID, len(mealPlans))
+	for i, mealPlan := range mealPlans {
+		ids[i] = mealPlan.ID
+	}
+	return ids, nil
+}
@@ -0,0 +1,20 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// verifyExistingEmails marks the users registered before email verification as verified, the ai endpoints require
+// a verified email and these users were never sent a verification link. Users registered since then always store
+// emailVerified, so only the older documents lack the field
+func verifyExistingEmails(ctx context.Context, db *mongo.Database) error {
+	filter := bson.M{"emailVerified": bson.M{"$exists": false}}
+	if _, err := db.Collection("users").UpdateMany(ctx, filter, bson.M{"$set": bson.M{"emailVerified": true}}); err != nil {
+		return fmt.Errorf("could not verify the emails of the existing users: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ACCOUNT_DELETION_GRACE_PERIOD is how long a deleted account can still be restored before its data is purged
+const ACCOUNT_DELETION_GRACE_PERIOD = 30 * 24 * time.Hour
+
+// DATA_EXPORT_VALIDITY is how long a generated export can be downloaded
+const DATA_EXPORT_VALIDITY = 7 * 24 * time.Hour
+
+// DATA_EXPORT_TIMEOUT is how long an export may take, a pending export older than this was interrupted
+const DATA_EXPORT_TIMEOUT = 30 * time.Minute
+
+type DataExportStatus string
+
+const (
+	DATA_EXPORT_PENDING DataExportStatus = "pending"
+	DATA_EXPORT_READY   DataExportStatus = "ready"
+	DATA_EXPORT_FAILED  DataExportStatus = "failed"
+)
+
+// DataExport is a personal data export generated in the background, the zip file is kept in GridFS until it expires
+type DataExport struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	UserId      primitive.ObjectID `bson:"userId" json:"userId"`
+	Status      DataExportStatus   `bson:"status" json:"status"`
+	FileId      primitive.ObjectID `bson:"fileId,omitempty" json:"-"`
+	Size        int64              `bson:"size,omitempty" json:"size,omitempty"`
+	Error       string             `bson:"error,omitempty" json:"error,omitempty"`
+	CreatedAt   time.Time          `bson:"createdAt" json:"createdAt"`
+	CompletedAt *time.Time         `bson:"completedAt,omitempty" json:"completedAt,omitempty"`
+	ExpiresAt   time.Time          `bson:"expiresAt" json:"expiresAt"`
+}
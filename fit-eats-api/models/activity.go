@@ -0,0 +1,71 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type ActivityType string
+
+const (
+	WALKING       ActivityType = "Walking"
+	BRISK_WALKING ActivityType = "Brisk walking"
+	RUNNING       ActivityType = "Running"
+	CYCLING       ActivityType = "Cycling"
+	SWIMMING      ActivityType = "Swimming"
+	HIKING        ActivityType = "Hiking"
+	YOGA          ActivityType = "Yoga"
+	DANCING       ActivityType = "Dancing"
+	SPORTS        ActivityType = "Sports"
+)
+
+// WorkoutSession is a completed training session, optionally following a day of the workout routine
+type WorkoutSession struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	UserId       primitive.ObjectID `bson:"userId" json:"userId" validate:"required"`
+	WorkoutDayId primitive.ObjectID `bson:"workoutDayId,omitempty" json:"workoutDayId,omitempty"`
+
+	Date            time.Time `bson:"date" json:"date"`
+	DurationMinutes int       `bson:"durationMinutes" json:"durationMinutes" validate:"required,gt=0,lte=600"`
+	RPE             int       `bson:"rpe,omitempty" json:"rpe,omitempty" validate:"omitempty,min=1,max=10"` // rate of perceived exertion
+
+	Exercises []LoggedExercise `bson:"exercises" json:"exercises"`
+	Notes     string           `bson:"notes,omitempty" json:"notes,omitempty"`
+
+	EstimatedCalories float64 `bson:"estimatedCalories" json:"estimatedCalories"`
+}
+
+type LoggedExercise struct {
+	ExerciseId      string      `bson:"exerciseId" json:"exerciseId"`
+	Name            string      `bson:"name" json:"name"`
+	Sets            []LoggedSet `bson:"sets" json:"sets"`
+	DurationMinutes int         `bson:"durationMinutes,omitempty" json:"durationMinutes,omitempty"`
+}
+
+type LoggedSet struct {
+	Reps       int     `bson:"reps" json:"reps"`
+	WeightInKg float64 `bson:"weightInKg" json:"weightInKg"`
+}
+
+// ActivityLog is ad-hoc activity outside of training, either a duration or a step count is required
+type ActivityLog struct {
+	ID     primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	UserId primitive.ObjectID `bson:"userId" json:"userId" validate:"required"`
+
+	ActivityType    ActivityType `bson:"activityType" json:"activityType" validate:"required"`
+	Date            time.Time    `bson:"date" json:"date"`
+	DurationMinutes int          `bson:"durationMinutes,omitempty" json:"durationMinutes,omitempty" validate:"omitempty,gt=0,lte=1440"`
+	Steps           int          `bson:"steps,omitempty" json:"steps,omitempty" validate:"omitempty,gt=0,lte=100000"`
+
+	EstimatedCalories float64 `bson:"estimatedCalories" json:"estimatedCalories"`
+}
+
+type ActivitySummary struct {
+	Date              time.Time        `json:"date"`
+	WorkoutSessions   []WorkoutSession `json:"workoutSessions"`
+	ActivityLogs      []ActivityLog    `json:"activityLogs"`
+	BurnedCalories    float64          `json:"burnedCalories"`
+	EatBackPercentage int              `json:"eatBackPercentage"`
+	EatBackCalories   float64          `json:"eatBackCalories"`
+}
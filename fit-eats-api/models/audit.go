@@ -0,0 +1,107 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type AuditAction string
+
+const (
+	AUDIT_ACCOUNT_LOCKED   AuditAction = "Account locked"
+	AUDIT_ACCOUNT_UNLOCKED AuditAction = "Account unlocked"
+	AUDIT_IP_LOCKED        AuditAction = "Ip locked"
+
+	AUDIT_ADMIN_SEARCH_USERS        AuditAction = "Admin searched users"
+	AUDIT_ADMIN_SET_ROLE            AuditAction = "Admin set user role"
+	AUDIT_ADMIN_RESET_PASSWORD      AuditAction = "Admin reset password"
+	AUDIT_ADMIN_REVOKE_SESSIONS     AuditAction = "Admin revoked sessions"
+	AUDIT_ADMIN_VIEW_GOAL           AuditAction = "Admin viewed goal"
+	AUDIT_ADMIN_DELETE_GOAL         AuditAction = "Admin deleted goal"
+	AUDIT_ADMIN_VIEW_MEAL_PLANS     AuditAction = "Admin viewed meal plans"
+	AUDIT_ADMIN_DELETE_MEAL_PLAN    AuditAction = "Admin deleted meal plan"
+	AUDIT_ADMIN_REFRESH_MEAL_IMAGES AuditAction = "Admin refreshed meal images"
+
+	AUDIT_USER_REGISTERED            AuditAction = "User registered"
+	AUDIT_EMAIL_VERIFIED             AuditAction = "Email verified"
+	AUDIT_VERIFICATION_EMAIL_SENT    AuditAction = "Verification email sent"
+	AUDIT_PASSWORD_RESET_REQUESTED   AuditAction = "Password reset requested"
+	AUDIT_PASSWORD_RESET             AuditAction = "Password reset"
+	AUDIT_LOGGED_OUT                 AuditAction = "Logged out"
+	AUDIT_PROFILE_UPDATED            AuditAction = "Profile updated"
+	AUDIT_MFA_ENROLLMENT_STARTED     AuditAction = "Two factor enrollment started"
+	AUDIT_MFA_ENABLED                AuditAction = "Two factor enabled"
+	AUDIT_MFA_DISABLED               AuditAction = "Two factor disabled"
+	AUDIT_RECOVERY_CODES_RENEWED     AuditAction = "Recovery codes regenerated"
+	AUDIT_SESSION_REVOKED            AuditAction = "Session revoked"
+	AUDIT_OTHER_SESSIONS_REVOKED     AuditAction = "Other sessions revoked"
+	AUDIT_IDENTITY_LINKED            AuditAction = "Social login linked"
+	AUDIT_IDENTITY_UNLINKED          AuditAction = "Social login unlinked"
+	AUDIT_GOAL_CREATED               AuditAction = "Goal created"
+	AUDIT_WEEKLY_GOAL_CREATED        AuditAction = "Weekly goal created"
+	AUDIT_GOAL_DELETED               AuditAction = "Goal deleted"
+	AUDIT_GOAL_STATUS_CHANGED        AuditAction = "Goal status changed"
+	AUDIT_WEEKLY_GOAL_UPDATED        AuditAction = "Weekly goal updated"
+	AUDIT_WEEKLY_GOAL_DELETED        AuditAction = "Weekly goal deleted"
+	AUDIT_MEAL_PLAN_CREATED          AuditAction = "Meal plan created"
+	AUDIT_MEAL_PLAN_CUSTOMIZED       AuditAction = "Meal plan customized"
+	AUDIT_MEAL_CONSUMED              AuditAction = "Meal consumed"
+	AUDIT_WATER_LOGGED               AuditAction = "Water logged"
+	AUDIT_WATER_LOG_DELETED          AuditAction = "Water log deleted"
+	AUDIT_WORKOUT_ROUTINE_CREATED    AuditAction = "Workout routine created"
+	AUDIT_WORKOUT_SESSION_LOGGED     AuditAction = "Workout session logged"
+	AUDIT_WORKOUT_SESSION_DELETED    AuditAction = "Workout session deleted"
+	AUDIT_ACTIVITY_LOGGED            AuditAction = "Activity logged"
+	AUDIT_ACTIVITY_LOG_DELETED       AuditAction = "Activity log deleted"
+	AUDIT_COACH_INVITED              AuditAction = "Coach invitation sent"
+	AUDIT_COACH_INVITATION_ANSWERED  AuditAction = "Coach invitation answered"
+	AUDIT_COACH_LINK_ENDED           AuditAction = "Coaching ended"
+	AUDIT_ACCOUNT_DELETION_REQUESTED AuditAction = "Account deletion requested"
+	AUDIT_ACCOUNT_RESTORED           AuditAction = "Account restored"
+	AUDIT_ACCOUNT_PURGED             AuditAction = "Account purged"
+	AUDIT_DATA_EXPORTED              AuditAction = "Personal data exported"
+)
+
+// AuditActor is who performed an action, admins acting through the api have an id and the cli records the os user
+type AuditActor struct {
+	ID     primitive.ObjectID `bson:"id,omitempty" json:"id,omitempty"`
+	Name   string             `bson:"name" json:"name"`
+	Source string             `bson:"source" json:"source"`
+}
+
+const (
+	AUDIT_SOURCE_API       = "api"
+	AUDIT_SOURCE_CLI       = "cli"
+	AUDIT_SOURCE_SCHEDULER = "scheduler"
+)
+
+type AuditLog struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	UserId    primitive.ObjectID `bson:"userId,omitempty" json:"userId,omitempty"`
+	Actor     *AuditActor        `bson:"actor,omitempty" json:"actor,omitempty"`
+	Action    AuditAction        `bson:"action" json:"action"`
+	IP        string             `bson:"ip,omitempty" json:"ip,omitempty"`
+	RequestId string             `bson:"requestId,omitempty" json:"requestId,omitempty"`
+	Details   map[string]any     `bson:"details,omitempty" json:"details,omitempty"`
+	Changes   []AuditChange      `bson:"changes,omitempty" json:"changes,omitempty"`
+	CreatedAt time.Time          `bson:"createdAt" json:"createdAt"`
+	// Set when the personal data of a purged user was removed from the entry
+	RedactedAt *time.Time `bson:"redactedAt,omitempty" json:"redactedAt,omitempty"`
+}
+
+// AuditChange is one changed field of the data a request touched, Path is empty when the whole document was created or deleted
+type AuditChange struct {
+	Path   string `bson:"path" json:"path"`
+	Before any    `bson:"before" json:"before"`
+	After  any    `bson:"after" json:"after"`
+}
+
+// AuditLogFilter selects audit logs, zero values match everything
+type AuditLogFilter struct {
+	UserId  primitive.ObjectID
+	ActorId primitive.ObjectID
+	Action  AuditAction
+	From    time.Time
+	To      time.Time
+}
@@ -0,0 +1,111 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type Role string
+
+const (
+	ROLE_USER  Role = "user"
+	ROLE_COACH Role = "coach"
+	ROLE_ADMIN Role = "admin"
+)
+
+func IsValidRole(role Role) bool {
+	return role == ROLE_USER || role == ROLE_COACH || role == ROLE_ADMIN
+}
+
+type CoachLinkStatus string
+
+const (
+	COACH_LINK_PENDING  CoachLinkStatus = "Pending"
+	COACH_LINK_ACTIVE   CoachLinkStatus = "Active"
+	COACH_LINK_DECLINED CoachLinkStatus = "Declined"
+	COACH_LINK_ENDED    CoachLinkStatus = "Ended"
+)
+
+// CoachLink lets a coach view and edit the goals and meal plans of a client while it is active.
+// It starts as a pending invitation sent by either the coach or the client, which the other side accepts or declines.
+type CoachLink struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	CoachId   primitive.ObjectID `bson:"coachId" json:"coachId"`
+	ClientId  primitive.ObjectID `bson:"clientId" json:"clientId"`
+	InvitedBy primitive.ObjectID `bson:"invitedBy" json:"invitedBy"`
+	Status    CoachLinkStatus    `bson:"status" json:"status"`
+
+	InvitedAt   time.Time  `bson:"invitedAt" json:"invitedAt"`
+	RespondedAt *time.Time `bson:"respondedAt,omitempty" json:"respondedAt,omitempty"`
+	EndedAt     *time.Time `bson:"endedAt,omitempty" json:"endedAt,omitempty"`
+
+	// Filled in when listing links so the app can show who is on the other side
+	CoachName   string `bson:"-" json:"coachName,omitempty"`
+	ClientName  string `bson:"-" json:"clientName,omitempty"`
+	ClientEmail string `bson:"-" json:"clientEmail,omitempty"`
+}
+
+type CoachAlert string
+
+const (
+	ALERT_NO_GOAL          CoachAlert = "No goal"
+	ALERT_NO_WEEKLY_GOAL   CoachAlert = "No weekly goal for this week"
+	ALERT_NO_MEAL_PLAN     CoachAlert = "No meal plan for this week"
+	ALERT_WEIGHT_OFF_TREND CoachAlert = "Weight moving away from goal"
+)
+
+// ClientOverviewRecord is one client as read by the coach overview pipeline
+type ClientOverviewRecord struct {
+	CoachLinkId        primitive.ObjectID `bson:"_id"`
+	Client             User               `bson:"client"`
+	Goal               *Goal              `bson:"goal"`
+	LatestWeeklyGoal   *WeeklyGoal        `bson:"latestWeeklyGoal"`
+	PreviousWeeklyGoal *WeeklyGoal        `bson:"previousWeeklyGoal"`
+	RecentDayMeals     []DayMeal          `bson:"recentDayMeals"` // meals only carry calories, macros and isConsumed
+	HasCurrentMealPlan bool               `bson:"hasCurrentMealPlan"`
+}
+
+type DayAdherence struct {
+	Date     time.Time  `json:"date"`
+	Planned  MealTotals `json:"planned"`
+	Consumed MealTotals `json:"consumed"`
+}
+
+type ClientOverview struct {
+	CoachLinkId primitive.ObjectID `json:"coachLinkId"`
+	ClientId    primitive.ObjectID `json:"clientId"`
+	Name        string             `json:"name"`
+	Email       string             `json:"email"`
+
+	GoalType          GoalType `json:"goalType,omitempty"`
+	CurrentWeightInKg float64  `json:"currentWeightInKg,omitempty"`
+	TargetWeightInKg  float64  `json:"targetWeightInKg,omitempty"`
+	WeightToGoalInKg  float64  `json:"weightToGoalInKg,omitempty"`
+
+	// Share of the planned calories eaten over the last days, nil when nothing was planned
+	Adherence        *float64       `json:"adherence"`
+	PlannedCalories  int            `json:"plannedCalories"`
+	ConsumedCalories int            `json:"consumedCalories"`
+	DailyAdherence   []DayAdherence `json:"dailyAdherence"`
+
+	// nil when the client never created a weekly goal
+	DaysSinceWeeklyGoal *int `json:"daysSinceWeeklyGoal"`
+
+	Alerts []CoachAlert `json:"alerts"`
+}
+
+type CoachOverview struct {
+	Clients  []ClientOverview `json:"clients"`
+	Page     int              `json:"page"`
+	PageSize int              `json:"pageSize"`
+	Total    int              `json:"total"`
+}
+
+// CoachOverviewSortFields maps the sortBy values of the overview to the fields computed by its pipeline
+var CoachOverviewSortFields = map[string]string{
+	"name":                "name",
+	"adherence":           "adherence",
+	"weightToGoal":        "weightToGoal",
+	"daysSinceWeeklyGoal": "lastWeeklyGoalAt", // sorted in reverse, a later weekly goal means fewer days
+}
@@ -1,10 +1,12 @@
 package models
 
 type DashboardResponse struct {
-	UserInfo        UserInfoSection `json:"user"`
-	ProgressSummary ProgressSummary `json:"progressSummary"`
-	CalorieOverview CalorieOverview `json:"calorieOverview"`
-	TodayMeals      []Meal          `json:"todayMeals"`
+	UserInfo        UserInfoSection  `json:"user"`
+	ProgressSummary ProgressSummary  `json:"progressSummary"`
+	CalorieOverview CalorieOverview  `json:"calorieOverview"`
+	Hydration       HydrationSummary `json:"hydration"`
+	TodayMeals      []Meal           `json:"todayMeals"`
+	TodayWorkout    *WorkoutDay      `json:"todayWorkout,omitempty"`
 }
 
 type UserInfoSection struct {
@@ -31,18 +33,30 @@ type CalorieOverview struct {
 }
 
 type CalorieData struct {
-	Consumed  float64 `json:"consumed"`  // e.g., 1650
-	Goal      float64 `json:"goal"`      // e.g., 2000
+	Consumed float64 `json:"consumed"` // e.g., 1650
+	Goal     float64 `json:"goal"`     // e.g., 2000, includes eaten back activity calories
+	BaseGoal float64 `json:"baseGoal"` // planned calories before activity adjustment
+	Burned   float64 `json:"burned"`   // calories from logged workouts and activity
+	EatBack  float64 `json:"eatBack"`  // share of burned calories added to the goal
 }
 
 type MacroData struct {
-	Protein MacroItem `json:"protein"`
-	Carbs   MacroItem `json:"carbs"`
-	Fats    MacroItem `json:"fats"`
+	Protein      MacroItem `json:"protein"`
+	Carbs        MacroItem `json:"carbs"`
+	Fats         MacroItem `json:"fats"`
+	Fibre        MacroItem `json:"fibre"`
+	Sugar        MacroItem `json:"sugar"`
+	SaturatedFat MacroItem `json:"saturatedFat"`
+	Sodium       MacroItem `json:"sodium"`
+	Iron         MacroItem `json:"iron"`
+	Calcium      MacroItem `json:"calcium"`
+	VitaminB12   MacroItem `json:"vitaminB12"`
+	VitaminD     MacroItem `json:"vitaminD"`
 }
 
 type MacroItem struct {
-	Consumed float64 `json:"consumed"`
-	Goal     float64 `json:"goal"`
-	Unit     string  `json:"unit"` // e.g., "g"
+	Consumed  float64 `json:"consumed"`
+	Goal      float64 `json:"goal"`
+	Unit      string  `json:"unit"`                // e.g., "g"
+	Reference float64 `json:"reference,omitempty"` // daily reference intake for the user's age and sex
 }
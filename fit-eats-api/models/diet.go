@@ -0,0 +1,119 @@
+package models
+
+type DietPattern string
+
+const (
+	OMNIVORE    DietPattern = "Omnivore"
+	FLEXITARIAN DietPattern = "Flexitarian"
+	VEGETARIAN  DietPattern = "Vegetarian"
+	EGGETARIAN  DietPattern = "Eggetarian"
+	VEGAN       DietPattern = "Vegan"
+	PESCATARIAN DietPattern = "Pescatarian"
+	KETO        DietPattern = "Keto"
+	JAIN        DietPattern = "Jain"
+	HALAL       DietPattern = "Halal"
+)
+
+type IngredientCategory string
+
+const (
+	RED_MEAT       IngredientCategory = "Red meat"
+	PORK           IngredientCategory = "Pork"
+	POULTRY        IngredientCategory = "Poultry"
+	FISH           IngredientCategory = "Fish"
+	SEAFOOD        IngredientCategory = "Seafood"
+	EGG            IngredientCategory = "Egg"
+	DAIRY          IngredientCategory = "Dairy"
+	HONEY          IngredientCategory = "Honey"
+	GELATIN        IngredientCategory = "Gelatin"
+	ALCOHOL        IngredientCategory = "Alcohol"
+	ROOT_VEGETABLE IngredientCategory = "Root vegetable"
+	GRAIN          IngredientCategory = "Grain"
+	ADDED_SUGAR    IngredientCategory = "Added sugar"
+	STARCHY_FOOD   IngredientCategory = "Starchy food"
+	LEGUME         IngredientCategory = "Legume"
+)
+
+// DietRule describes what a dietary pattern allows, used both when prompting the model and when validating its meals.
+type DietRule struct {
+	Pattern             DietPattern          `json:"pattern"`
+	Description         string               `json:"description"`
+	ForbiddenCategories []IngredientCategory `json:"forbiddenCategories"`
+}
+
+var DietRules = map[DietPattern]DietRule{
+	OMNIVORE: {
+		Pattern:     OMNIVORE,
+		Description: "eats everything",
+	},
+	FLEXITARIAN: {
+		Pattern:     FLEXITARIAN,
+		Description: "mostly plant based with occasional meat, poultry or fish",
+	},
+	VEGETARIAN: {
+		Pattern:             VEGETARIAN,
+		Description:         "no meat, poultry, fish, seafood or eggs, dairy is allowed",
+		ForbiddenCategories: []IngredientCategory{RED_MEAT, PORK, POULTRY, FISH, SEAFOOD, EGG, GELATIN},
+	},
+	EGGETARIAN: {
+		Pattern:             EGGETARIAN,
+		Description:         "vegetarian that also eats eggs, no meat, poultry, fish or seafood",
+		ForbiddenCategories: []IngredientCategory{RED_MEAT, PORK, POULTRY, FISH, SEAFOOD, GELATIN},
+	},
+	VEGAN: {
+		Pattern:             VEGAN,
+		Description:         "no animal products at all, including dairy, eggs and honey",
+		ForbiddenCategories: []IngredientCategory{RED_MEAT, PORK, POULTRY, FISH, SEAFOOD, EGG, DAIRY, HONEY, GELATIN},
+	},
+	PESCATARIAN: {
+		Pattern:             PESCATARIAN,
+		Description:         "vegetarian that also eats fish and seafood, no meat or poultry",
+		ForbiddenCategories: []IngredientCategory{RED_MEAT, PORK, POULTRY, GELATIN},
+	},
+	KETO: {
+		Pattern:             KETO,
+		Description:         "very low carb and high fat, no grains, added sugar, starchy vegetables or legumes",
+		ForbiddenCategories: []IngredientCategory{GRAIN, ADDED_SUGAR, STARCHY_FOOD, LEGUME},
+	},
+	JAIN: {
+		Pattern:             JAIN,
+		Description:         "vegetarian without eggs, honey or any root vegetables such as onion, garlic, potato, carrot or ginger",
+		ForbiddenCategories: []IngredientCategory{RED_MEAT, PORK, POULTRY, FISH, SEAFOOD, EGG, HONEY, GELATIN, ALCOHOL, ROOT_VEGETABLE},
+	},
+	HALAL: {
+		Pattern:             HALAL,
+		Description:         "only halal slaughtered meat and poultry, no pork, alcohol or gelatin",
+		ForbiddenCategories: []IngredientCategory{PORK, ALCOHOL, GELATIN},
+	},
+}
+
+// DietPatterns keeps the order in which patterns are listed to clients.
+var DietPatterns = []DietPattern{OMNIVORE, FLEXITARIAN, VEGETARIAN, EGGETARIAN, VEGAN, PESCATARIAN, KETO, JAIN, HALAL}
+
+var Cuisines = []string{
+	"Indian", "Chinese", "Italian", "Mexican", "Mediterranean", "Middle Eastern",
+	"Thai", "Japanese", "Korean", "American", "Continental", "French",
+}
+
+// MAX_MEALS_PER_DAY is the most meals a user can ask to have planned in a day
+const MAX_MEALS_PER_DAY = 8
+
+// FastingWindow is the part of the day where no meals are planned, times are in am/pm format for eg. 8:00 pm.
+type FastingWindow struct {
+	StartTime string `bson:"startTime" json:"startTime"`
+	EndTime   string `bson:"endTime" json:"endTime"`
+}
+
+func IsValidDietPattern(pattern DietPattern) bool {
+	_, ok := DietRules[pattern]
+	return ok
+}
+
+func IsValidCuisine(cuisine string) bool {
+	for _, c := range Cuisines {
+		if c == cuisine {
+			return true
+		}
+	}
+	return false
+}
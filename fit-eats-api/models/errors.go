@@ -0,0 +1,92 @@
+package models
+
+// ErrorCode is a stable, machine readable reason for a failed request, clients switch on it instead of the message
+type ErrorCode string
+
+const (
+	INVALID_REQUEST   ErrorCode = "INVALID_REQUEST"   // Missing or malformed params
+	VALIDATION_FAILED ErrorCode = "VALIDATION_FAILED" // Field errors are listed in the fields of the response
+	INVALID_STATE     ErrorCode = "INVALID_STATE"     // The request does not apply to the current state, like disabling mfa twice
+	ALREADY_EXISTS    ErrorCode = "ALREADY_EXISTS"
+
+	UNAUTHORIZED        ErrorCode = "UNAUTHORIZED" // Missing, invalid or revoked tokens
+	INVALID_CREDENTIALS ErrorCode = "INVALID_CREDENTIALS"
+	INVALID_MFA_CODE    ErrorCode = "INVALID_MFA_CODE"
+	INVALID_LINK        ErrorCode = "INVALID_LINK" // Emailed reset, verification and unlock links which are unknown, used or expired
+	LOGIN_LOCKED        ErrorCode = "LOGIN_LOCKED"
+	TOO_MANY_ATTEMPTS   ErrorCode = "TOO_MANY_ATTEMPTS"
+
+	FORBIDDEN          ErrorCode = "FORBIDDEN"
+	EMAIL_NOT_VERIFIED ErrorCode = "EMAIL_NOT_VERIFIED"
+	PROFILE_INCOMPLETE ErrorCode = "PROFILE_INCOMPLETE" // Height, age and sex are needed for the estimates and plans
+
+	NOT_FOUND                 ErrorCode = "NOT_FOUND"
+	USER_NOT_FOUND            ErrorCode = "USER_NOT_FOUND"
+	SESSION_NOT_FOUND         ErrorCode = "SESSION_NOT_FOUND"
+	GOAL_NOT_FOUND            ErrorCode = "GOAL_NOT_FOUND"
+	WEEKLY_GOAL_NOT_FOUND     ErrorCode = "WEEKLY_GOAL_NOT_FOUND"
+	MEAL_PLAN_NOT_FOUND       ErrorCode = "MEAL_PLAN_NOT_FOUND"
+	MEAL_NOT_FOUND            ErrorCode = "MEAL_NOT_FOUND"
+	WORKOUT_ROUTINE_NOT_FOUND ErrorCode = "WORKOUT_ROUTINE_NOT_FOUND"
+	LOG_NOT_FOUND             ErrorCode = "LOG_NOT_FOUND" // Hydration, workout and activity logs
+	COACH_LINK_NOT_FOUND      ErrorCode = "COACH_LINK_NOT_FOUND"
+	IDENTITY_NOT_FOUND        ErrorCode = "IDENTITY_NOT_FOUND"
+	EXPORT_NOT_FOUND          ErrorCode = "EXPORT_NOT_FOUND"
+
+	LLM_UNAVAILABLE      ErrorCode = "LLM_UNAVAILABLE"      // The model could not be reached or refused to answer
+	LLM_INVALID_RESPONSE ErrorCode = "LLM_INVALID_RESPONSE" // The model answered with content the api could not use
+	DIET_VIOLATION       ErrorCode = "DIET_VIOLATION"       // Generated meals break the diet preferences, the violations are in the details
+	PROVIDER_UNAVAILABLE ErrorCode = "PROVIDER_UNAVAILABLE" // An OpenID Connect provider could not be reached
+
+	TIMEOUT        ErrorCode = "TIMEOUT"
+	INTERNAL_ERROR ErrorCode = "INTERNAL_ERROR"
+)
+
+// ApiError is a failed request, handlers pass it to ctx.Error and the ErrorMiddleware writes the response
+// with the http status of its code
+type ApiError struct {
+	Code    ErrorCode
+	Message string
+	Fields  map[string]string // Field level errors of a validation failure
+	Details map[string]any    // Extra context for the client, like the violations of generated meals
+	Cause   error             // Logged, never sent to the client
+}
+
+// ErrorResponse is the body of every failed request. Error holds the message shown to users
+type ErrorResponse struct {
+	Error     string            `json:"error"`
+	Code      ErrorCode         `json:"code"`
+	Fields    map[string]string `json:"fields,omitempty"`
+	Details   map[string]any    `json:"details,omitempty"`
+	RequestId string            `json:"requestId,omitempty"`
+}
+
+func NewApiError(code ErrorCode, message string) *ApiError {
+	return &ApiError{Code: code, Message: message}
+}
+
+// NewValidationError reports the field errors of ValidateStruct
+func NewValidationError(fields map[string]string) *ApiError {
+	return &ApiError{Code: VALIDATION_FAILED, Message: "Invalid request, check the highlighted fields", Fields: fields}
+}
+
+func (e *ApiError) Error() string {
+	if e.Cause != nil {
+		return string(e.Code) + ": " + e.Message + ": " + e.Cause.Error()
+	}
+	return string(e.Code) + ": " + e.Message
+}
+
+func (e *ApiError) Unwrap() error {
+	return e.Cause
+}
+
+func (e *ApiError) WithCause(err error) *ApiError {
+	e.Cause = err
+	return e
+}
+
+func (e *ApiError) WithDetails(details map[string]any) *ApiError {
+	e.Details = details
+	return e
+}
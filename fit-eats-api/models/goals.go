@@ -9,8 +9,72 @@ import (
 type GoalType string
 
 const (
-	FAT_LOSS    GoalType = "Fat loss"
-	MUSCLE_GAIN GoalType = "Muscle gain"
+	FAT_LOSS      GoalType = "Fat loss"
+	MUSCLE_GAIN   GoalType = "Muscle gain"
+	RECOMPOSITION GoalType = "Body recomposition"
+	MAINTENANCE   GoalType = "Maintenance"
+	EVENT         GoalType = "Event"
+)
+
+// GoalTypeRule describes what a goal type asks of the weight and body fat, used when validating goals and deriving
+// their weekly weight change
+type GoalTypeRule struct {
+	GoalType GoalType `json:"goalType"`
+	// WeightDirection is -1 when the target weight is below the start weight and 1 when above, with 0 the target weight
+	// stays within MaxWeightDifferenceInKg of the start weight
+	WeightDirection         int     `json:"weightDirection"`
+	MaxWeightDifferenceInKg float64 `json:"maxWeightDifferenceInKg,omitempty"`
+	// LowersBodyFat needs a target body fat below the start body fat
+	LowersBodyFat bool `json:"lowersBodyFat"`
+	// HasEventDate goals end on the date of an event, the target weight may be either way and the weekly weight change
+	// is what reaching it by then takes
+	HasEventDate bool `json:"hasEventDate"`
+}
+
+var GoalTypeRules = map[GoalType]GoalTypeRule{
+	FAT_LOSS:      {GoalType: FAT_LOSS, WeightDirection: -1},
+	MUSCLE_GAIN:   {GoalType: MUSCLE_GAIN, WeightDirection: 1},
+	RECOMPOSITION: {GoalType: RECOMPOSITION, MaxWeightDifferenceInKg: 3, LowersBodyFat: true},
+	MAINTENANCE:   {GoalType: MAINTENANCE, MaxWeightDifferenceInKg: 1},
+	EVENT:         {GoalType: EVENT, HasEventDate: true},
+}
+
+func IsValidGoalType(goalType GoalType) bool {
+	_, ok := GoalTypeRules[goalType]
+	return ok
+}
+
+// GoalStatus is the lifecycle of a main goal. A user has a single active goal, it ends completed or abandoned and
+// ended goals stay as history until they are archived
+type GoalStatus string
+
+const (
+	GOAL_ACTIVE    GoalStatus = "Active"
+	GOAL_COMPLETED GoalStatus = "Completed"
+	GOAL_ABANDONED GoalStatus = "Abandoned"
+	GOAL_ARCHIVED  GoalStatus = "Archived"
+)
+
+// CanChangeTo checks the lifecycle allows going from the status to the given one
+func (s GoalStatus) CanChangeTo(status GoalStatus) bool {
+	switch s {
+	case GOAL_ACTIVE:
+		return status == GOAL_COMPLETED || status == GOAL_ABANDONED
+	case GOAL_COMPLETED, GOAL_ABANDONED:
+		return status == GOAL_ARCHIVED
+	}
+	return false
+}
+
+// ActivityLevel matches the lifestyles returned by the tdee model
+type ActivityLevel string
+
+const (
+	SEDENTARY    ActivityLevel = "Sedentary"
+	LIGHT        ActivityLevel = "Light"
+	MODERATE     ActivityLevel = "Moderate"
+	VERY_ACTIVE  ActivityLevel = "Very Active"
+	EXTRA_ACTIVE ActivityLevel = "Extra Active"
 )
 
 type Goal struct {
@@ -26,10 +90,40 @@ type Goal struct {
 	GoalStartDate time.Time `bson:"goalStartDate" json:"goalStartDate"`
 	GoalEndDate   time.Time `bson:"goalEndDate" json:"goalEndDate"`
 
-	GoalType           GoalType `bson:"goalType" json:"goalType"`
+	GoalType           GoalType `bson:"goalType" json:"goalType" validate:"required,oneof='Fat loss' 'Muscle gain' 'Body recomposition' Maintenance Event"`
 	WeeklyWeightChange float64  `bson:"weeklyWeightChange" json:"weeklyWeightChange"`
 
+	// EventName is what an event goal gets ready for, the event is on the goal end date
+	EventName string `bson:"eventName,omitempty" json:"eventName,omitempty" validate:"max=100"`
+
 	WeeklyGoals []WeeklyGoal `bson:"weeklyGoals" json:"weeklyGoals"`
+
+	Status  GoalStatus   `bson:"status" json:"status"`
+	EndedAt *time.Time   `bson:"endedAt,omitempty" json:"endedAt,omitempty"`
+	Outcome *GoalOutcome `bson:"outcome,omitempty" json:"outcome,omitempty"`
+
+	// Filled in when the goal is created, the weekly weight change is faster than what is safe
+	Warnings []string `bson:"-" json:"warnings,omitempty"`
+}
+
+// GoalOutcome summarises a goal when it is completed or abandoned
+type GoalOutcome struct {
+	StartWeightInKg  float64 `bson:"startWeightInKg" json:"startWeightInKg"`
+	EndWeightInKg    float64 `bson:"endWeightInKg" json:"endWeightInKg"`
+	WeightChangeInKg float64 `bson:"weightChangeInKg" json:"weightChangeInKg"`
+	// Share of the planned weight change made, nil when the goal had no change planned
+	Progress *float64 `bson:"progress" json:"progress"`
+
+	WeeksTracked int `bson:"weeksTracked" json:"weeksTracked"`
+	// Share of the planned calories eaten over the meal plans of the goal, nil when nothing was planned
+	Adherence        *float64 `bson:"adherence" json:"adherence"`
+	PlannedCalories  int      `bson:"plannedCalories" json:"plannedCalories"`
+	ConsumedCalories int      `bson:"consumedCalories" json:"consumedCalories"`
+}
+
+// GoalStatusUpdate ends the active goal or archives an ended one
+type GoalStatusUpdate struct {
+	Status GoalStatus `json:"status" validate:"required,oneof=Completed Abandoned Archived"`
 }
 
 type WeeklyGoal struct {
@@ -41,6 +135,8 @@ type WeeklyGoal struct {
 	CurrentWeightInKg    float64 `bson:"currentWeightInKg" json:"currentWeightInKg"`
 	CurrentFatPercentage float64 `bson:"currentFatPercentage" json:"currentFatPercentage"`
 
+	ActivityLevel ActivityLevel `bson:"activityLevel,omitempty" json:"activityLevel,omitempty" validate:"omitempty,oneof=Sedentary Light Moderate 'Very Active' 'Extra Active'"`
+
 	DailyMaintenanceCalories float64 `bson:"dailyMaintenanceCalories" json:"dailyMaintenanceCalories"`
 	TargetDailyCalories      float64 `bson:"targetDailyCalories" json:"targetDailyCalories"`
 
@@ -48,5 +144,30 @@ type WeeklyGoal struct {
 	TargetDailyMacrosCarbs   float64 `bson:"targetDailyMacrosCarbs" json:"targetDailyMacrosCarbs"`
 	TargetDailyMacrosFats    float64 `bson:"targetDailyMacrosFats" json:"targetDailyMacrosFats"`
 
-	WorkoutRoutine string `bson:"workoutRoutine" json:"workoutRoutine"`
+	WorkoutRoutineId primitive.ObjectID `bson:"workoutRoutineId,omitempty" json:"workoutRoutineId,omitempty"`
+}
+
+// WeeklyGoalUpdate holds the fields of a weekly goal which can be edited, the fields left out are unchanged.
+// The calories follow the macros when only the macros are set, so both cannot be set together
+type WeeklyGoalUpdate struct {
+	CurrentWeightInKg    *float64       `json:"currentWeightInKg,omitempty" validate:"omitempty,gte=30,lte=250"`
+	CurrentFatPercentage *float64       `json:"currentFatPercentage,omitempty" validate:"omitempty,gte=3,lte=80"`
+	ActivityLevel        *ActivityLevel `json:"activityLevel,omitempty" validate:"omitempty,oneof=Sedentary Light Moderate 'Very Active' 'Extra Active'"`
+
+	DailyMaintenanceCalories *float64 `json:"dailyMaintenanceCalories,omitempty" validate:"omitempty,gte=800,lte=6000"`
+	TargetDailyCalories      *float64 `json:"targetDailyCalories,omitempty" validate:"omitempty,gte=800,lte=6000"`
+
+	TargetDailyMacrosProtein *float64 `json:"targetDailyMacrosProtein,omitempty" validate:"omitempty,gte=0,lte=500"`
+	TargetDailyMacrosCarbs   *float64 `json:"targetDailyMacrosCarbs,omitempty" validate:"omitempty,gte=0,lte=1000"`
+	TargetDailyMacrosFats    *float64 `json:"targetDailyMacrosFats,omitempty" validate:"omitempty,gte=0,lte=400"`
+}
+
+// HasMacros is true when any of the macros is set
+func (u WeeklyGoalUpdate) HasMacros() bool {
+	return u.TargetDailyMacrosProtein != nil || u.TargetDailyMacrosCarbs != nil || u.TargetDailyMacrosFats != nil
+}
+
+// ChangesTargets is true when the update can change the calories or macros the meal plan is generated for
+func (u WeeklyGoalUpdate) ChangesTargets() bool {
+	return u.CurrentWeightInKg != nil || u.ActivityLevel != nil || u.DailyMaintenanceCalories != nil || u.TargetDailyCalories != nil || u.HasMacros()
 }
@@ -0,0 +1,60 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type BeverageType string
+
+const (
+	WATER       BeverageType = "Water"
+	TEA         BeverageType = "Tea"
+	COFFEE      BeverageType = "Coffee"
+	MILK        BeverageType = "Milk"
+	JUICE       BeverageType = "Juice"
+	ELECTROLYTE BeverageType = "Electrolyte drink"
+	SOFT_DRINK  BeverageType = "Soft drink"
+)
+
+// BeverageHydrationFactors is the share of a beverage's volume that counts towards the daily hydration target
+var BeverageHydrationFactors = map[BeverageType]float64{
+	WATER:       1,
+	TEA:         0.9,
+	COFFEE:      0.8,
+	MILK:        1,
+	JUICE:       0.9,
+	ELECTROLYTE: 1,
+	SOFT_DRINK:  0.8,
+}
+
+// HydrationQuickAddPresets are the amounts in ml for the quick add endpoint
+var HydrationQuickAddPresets = map[string]float64{
+	"cup":         200,
+	"glass":       250,
+	"bottle":      500,
+	"largeBottle": 1000,
+}
+
+type HydrationLog struct {
+	ID     primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	UserId primitive.ObjectID `bson:"userId" json:"userId" validate:"required"`
+
+	AmountInMl   float64      `bson:"amountInMl" json:"amountInMl" validate:"required,gt=0,lte=5000"`
+	BeverageType BeverageType `bson:"beverageType" json:"beverageType"`
+	LoggedAt     time.Time    `bson:"loggedAt" json:"loggedAt"`
+}
+
+type HydrationSummary struct {
+	Date          time.Time      `json:"date"`
+	TargetInMl    float64        `json:"targetInMl"`
+	ConsumedInMl  float64        `json:"consumedInMl"` // after applying the beverage hydration factors
+	RemainingInMl float64        `json:"remainingInMl"`
+	Logs          []HydrationLog `json:"logs"`
+}
+
+func IsValidBeverageType(beverageType BeverageType) bool {
+	_, ok := BeverageHydrationFactors[beverageType]
+	return ok
+}
@@ -0,0 +1,95 @@
+package models
+
+import (
+	"math"
+	"time"
+)
+
+// LoginPolicy decides how failed logins against one key are slowed down and when the key is locked
+type LoginPolicy struct {
+	FreeAttempts     int           // failures allowed before any delay
+	BaseDelay        time.Duration // delay after the first failure past the free attempts, doubled on every further failure
+	MaxDelay         time.Duration
+	LockoutThreshold int // failures after which the key is locked
+	LockoutDuration  time.Duration
+	// The failures are counted again from one when the previous failure is older than the window or the lock of
+	// the key ended, so a key is not locked again by its first failure after the lock
+	FailureWindow time.Duration
+}
+
+// AccountLoginPolicy applies to failed logins for one email
+var AccountLoginPolicy = LoginPolicy{
+	FreeAttempts:     3,
+	BaseDelay:        time.Second * 2,
+	MaxDelay:         time.Minute * 5,
+	LockoutThreshold: 10,
+	LockoutDuration:  time.Minute * 30,
+	FailureWindow:    time.Hour,
+}
+
+// IpLoginPolicy applies to failed logins from one ip, it is lenient since many users can share an ip
+var IpLoginPolicy = LoginPolicy{
+	FreeAttempts:     20,
+	BaseDelay:        time.Second,
+	MaxDelay:         time.Minute,
+	LockoutThreshold: 100,
+	LockoutDuration:  time.Hour,
+	FailureWindow:    time.Hour,
+}
+
+const (
+	LOGIN_ACCOUNT_KEY_PREFIX = "account:"
+	LOGIN_IP_KEY_PREFIX      = "ip:"
+)
+
+// LoginAttempt counts the recent failed logins for an account or an ip
+type LoginAttempt struct {
+	Key          string     `bson:"_id" json:"key"`
+	FailedCount  int        `bson:"failedCount" json:"failedCount"`
+	LastFailedAt time.Time  `bson:"lastFailedAt" json:"lastFailedAt"`
+	LockedUntil  *time.Time `bson:"lockedUntil,omitempty" json:"lockedUntil,omitempty"`
+	// The counter is removed once it no longer affects logins, the end of the failure window or of the lock
+	ExpireAt time.Time `bson:"expireAt" json:"-"`
+}
+
+// IsStale is true when the next failure starts a new count, the last failure left the window or the lock ended
+func (attempt *LoginAttempt) IsStale(policy LoginPolicy, now time.Time) bool {
+	if attempt.LockedUntil != nil && !now.Before(*attempt.LockedUntil) {
+		return true
+	}
+	return attempt.LastFailedAt.Before(now.Add(-policy.FailureWindow))
+}
+
+func (attempt *LoginAttempt) IsLocked(now time.Time) bool {
+	return attempt.LockedUntil != nil && now.Before(*attempt.LockedUntil)
+}
+
+// ShouldLock is true once the failures reach the lockout threshold and the key is not locked already
+func (attempt *LoginAttempt) ShouldLock(policy LoginPolicy, now time.Time) bool {
+	return attempt.FailedCount >= policy.LockoutThreshold && !attempt.IsLocked(now)
+}
+
+// GetBackoffDelay doubles the delay for every failure past the free attempts
+func (attempt *LoginAttempt) GetBackoffDelay(policy LoginPolicy) time.Duration {
+	extraFailures := attempt.FailedCount - policy.FreeAttempts
+	if extraFailures <= 0 {
+		return 0
+	}
+	delay := float64(policy.BaseDelay) * math.Pow(2, float64(extraFailures-1))
+	if delay > float64(policy.MaxDelay) {
+		return policy.MaxDelay
+	}
+	return time.Duration(delay)
+}
+
+// GetRetryAfter returns how long the caller has to wait before the next login attempt is evaluated
+func (attempt *LoginAttempt) GetRetryAfter(policy LoginPolicy, now time.Time) time.Duration {
+	if attempt.IsLocked(now) {
+		return attempt.LockedUntil.Sub(now)
+	}
+	retryAt := attempt.LastFailedAt.Add(attempt.GetBackoffDelay(policy))
+	if now.Before(retryAt) {
+		return retryAt.Sub(now)
+	}
+	return 0
+}
@@ -32,6 +32,7 @@ type Meal struct {
 	Carbs       int                `bson:"carbs" json:"carbs"`
 	Protein     int                `bson:"protein" json:"protein"`
 	Fat         int                `bson:"fat" json:"fat"`
+	Nutrients   Micronutrients     `bson:"micronutrients" json:"micronutrients"`
 	Time        string             `bson:"time" json:"time"`
 	Ingredients []Ingredient       `bson:"ingredients" json:"ingredients"`
 	RecipeSteps []string           `bson:"recipe_steps" json:"recipe_steps"`
@@ -42,3 +43,19 @@ type Ingredient struct {
 	Name     string `bson:"name" json:"name"`
 	Quantity string `bson:"quantity" json:"quantity"`
 }
+
+type MealTotals struct {
+	Calories int `json:"calories"`
+	Protein  int `json:"protein"`
+	Carbs    int `json:"carbs"`
+	Fat      int `json:"fat"`
+}
+
+func (totals MealTotals) AddMeal(meal Meal) MealTotals {
+	return MealTotals{
+		Calories: totals.Calories + meal.Calories,
+		Protein:  totals.Protein + meal.Protein,
+		Carbs:    totals.Carbs + meal.Carbs,
+		Fat:      totals.Fat + meal.Fat,
+	}
+}
@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+// MigrationRecord marks a migration as applied, it is stored in the migrations collection under the id of the migration
+type MigrationRecord struct {
+	ID          string    `bson:"_id" json:"id"`
+	Description string    `bson:"description" json:"description"`
+	AppliedAt   time.Time `bson:"appliedAt" json:"appliedAt"`
+	DurationMs  int64     `bson:"durationMs" json:"durationMs"`
+}
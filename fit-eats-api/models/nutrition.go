@@ -0,0 +1,72 @@
+package models
+
+import "time"
+
+// Micronutrients of a meal, fibre, sugar and saturated fat are in grams,
+// sodium, iron and calcium in milligrams, vitamin b12 and vitamin d in micrograms.
+type Micronutrients struct {
+	Fibre        float64 `bson:"fibre" json:"fibre"`
+	Sugar        float64 `bson:"sugar" json:"sugar"`
+	Sodium       float64 `bson:"sodium" json:"sodium"`
+	SaturatedFat float64 `bson:"saturatedFat" json:"saturatedFat"`
+	Iron         float64 `bson:"iron" json:"iron"`
+	Calcium      float64 `bson:"calcium" json:"calcium"`
+	VitaminB12   float64 `bson:"vitaminB12" json:"vitaminB12"`
+	VitaminD     float64 `bson:"vitaminD" json:"vitaminD"`
+}
+
+func (m Micronutrients) Add(other Micronutrients) Micronutrients {
+	return Micronutrients{
+		Fibre:        m.Fibre + other.Fibre,
+		Sugar:        m.Sugar + other.Sugar,
+		Sodium:       m.Sodium + other.Sodium,
+		SaturatedFat: m.SaturatedFat + other.SaturatedFat,
+		Iron:         m.Iron + other.Iron,
+		Calcium:      m.Calcium + other.Calcium,
+		VitaminB12:   m.VitaminB12 + other.VitaminB12,
+		VitaminD:     m.VitaminD + other.VitaminD,
+	}
+}
+
+func (m Micronutrients) Scale(factor float64) Micronutrients {
+	return Micronutrients{
+		Fibre:        m.Fibre * factor,
+		Sugar:        m.Sugar * factor,
+		Sodium:       m.Sodium * factor,
+		SaturatedFat: m.SaturatedFat * factor,
+		Iron:         m.Iron * factor,
+		Calcium:      m.Calcium * factor,
+		VitaminB12:   m.VitaminB12 * factor,
+		VitaminD:     m.VitaminD * factor,
+	}
+}
+
+type ReferenceKind string
+
+const (
+	// Daily amount that should be reached, eg. fibre or iron
+	REFERENCE_TARGET ReferenceKind = "target"
+	// Daily amount that should not be exceeded, eg. sugar or sodium
+	REFERENCE_LIMIT ReferenceKind = "limit"
+)
+
+type NutrientComparison struct {
+	Nutrient           string        `json:"nutrient"`
+	Unit               string        `json:"unit"`
+	Kind               ReferenceKind `json:"kind"`
+	Planned            float64       `json:"planned"`
+	Consumed           float64       `json:"consumed"`
+	Reference          float64       `json:"reference"`
+	PercentOfReference float64       `json:"percentOfReference"` // planned amount as a percentage of reference
+}
+
+type DayNutrition struct {
+	Date      time.Time            `json:"date"`
+	Nutrients []NutrientComparison `json:"nutrients"`
+}
+
+type NutritionReport struct {
+	MealPlanId string               `json:"mealPlanId"`
+	Days       []DayNutrition       `json:"days"`
+	Weekly     []NutrientComparison `json:"weekly"`
+}
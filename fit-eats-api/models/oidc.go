@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Identity is an account at an OpenID Connect provider linked to the user
+type Identity struct {
+	Provider string    `bson:"provider" json:"provider"`
+	Subject  string    `bson:"subject" json:"-"`
+	Email    string    `bson:"email,omitempty" json:"email,omitempty"`
+	LinkedAt time.Time `bson:"linkedAt" json:"linkedAt"`
+}
+
+// OidcState is kept between sending the user to the provider and the callback.
+// The state value is stored hashed, and a UserId means the callback links the identity instead of logging in.
+type OidcState struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	StateHash    string             `bson:"stateHash" json:"-"`
+	Provider     string             `bson:"provider" json:"provider"`
+	Nonce        string             `bson:"nonce" json:"-"`
+	CodeVerifier string             `bson:"codeVerifier" json:"-"`
+	UserId       primitive.ObjectID `bson:"userId,omitempty" json:"userId,omitempty"`
+	CreatedAt    time.Time          `bson:"createdAt" json:"createdAt"`
+	ExpiresAt    time.Time          `bson:"expiresAt" json:"expiresAt"`
+}
+
+// How long the user has to complete the login at the provider
+const OidcStateValidity = time.Minute * 10
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Session is one logged in device. The refresh token is rotated on every use and only its hash is stored,
+// so a session is also the family of refresh tokens descending from a single login.
+type Session struct {
+	ID               primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	UserId           primitive.ObjectID `bson:"userId" json:"userId"`
+	RefreshTokenHash string             `bson:"refreshTokenHash" json:"-"`
+
+	DeviceName string `bson:"deviceName" json:"deviceName"`
+	IP         string `bson:"ip" json:"ip"`
+	UserAgent  string `bson:"userAgent" json:"userAgent"`
+
+	CreatedAt     time.Time  `bson:"createdAt" json:"createdAt"`
+	LastUsedAt    time.Time  `bson:"lastUsedAt" json:"lastUsedAt"`
+	ExpiresAt     time.Time  `bson:"expiresAt" json:"expiresAt"`
+	RevokedAt     *time.Time `bson:"revokedAt,omitempty" json:"revokedAt,omitempty"`
+	RevokedReason string     `bson:"revokedReason,omitempty" json:"revokedReason,omitempty"`
+
+	Current bool `bson:"-" json:"current"` // set when listing sessions for the device making the request
+}
+
+const (
+	SESSION_LOGOUT         = "Logged out"
+	SESSION_REVOKED        = "Revoked by user"
+	SESSION_TOKEN_REUSE    = "Refresh token reuse detected"
+	SESSION_PASSWORD_RESET = "Password reset"
+	SESSION_ROLE_CHANGED   = "Role changed"
+)
+
+func (session *Session) IsActive(now time.Time) bool {
+	return session.RevokedAt == nil && now.Before(session.ExpiresAt)
+}
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type TokenPurpose string
+
+const (
+	PASSWORD_RESET     TokenPurpose = "Password reset"
+	EMAIL_VERIFICATION TokenPurpose = "Email verification"
+	ACCOUNT_UNLOCK     TokenPurpose = "Account unlock"
+)
+
+// TokenValidity is how long a token of each purpose can be used after it is issued
+var TokenValidity = map[TokenPurpose]time.Duration{
+	PASSWORD_RESET:     time.Hour,
+	EMAIL_VERIFICATION: time.Hour * 24,
+	ACCOUNT_UNLOCK:     time.Hour,
+}
+
+// UserToken is a single use token sent to the user by email, only the hash of the token is stored
+type UserToken struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	UserId    primitive.ObjectID `bson:"userId" json:"userId"`
+	Purpose   TokenPurpose       `bson:"purpose" json:"purpose"`
+	TokenHash string             `bson:"tokenHash" json:"-"`
+	CreatedAt time.Time          `bson:"createdAt" json:"createdAt"`
+	ExpiresAt time.Time          `bson:"expiresAt" json:"expiresAt"`
+	UsedAt    *time.Time         `bson:"usedAt,omitempty" json:"usedAt,omitempty"`
+}
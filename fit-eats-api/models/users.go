@@ -1,21 +1,79 @@
 package models
 
-import "go.mongodb.org/mongo-driver/bson/primitive"
+import (
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
 
 type User struct {
-	ID             primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
-	Name           string             `bson:"name" json:"name" validate:"required,min=3,max=50"`
-	Email          string             `bson:"email" json:"email" validate:"required,email"`
-	Password       string             `bson:"password" json:"password,omitempty" validate:"required,min=6"`
-	HeightInCm     float64            `bson:"heightInCm" json:"heightInCm,omitempty"`
-	Age            string             `bson:"age" json:"age,omitempty"`
-	Sex            string             `bson:"sex" json:"sex,omitempty"`
-	Country        string             `bson:"country" json:"country,omitempty"`
-	DietPreference string             `bson:"dietPreference" json:"dietPreference,omitempty"`
-	RefreshToken   string             `bson:"refreshToken" json:"refreshToken,omitempty"`
+	ID                 primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	Name               string             `bson:"name" json:"name" validate:"required,min=3,max=50"`
+	Email              string             `bson:"email" json:"email" validate:"required,email"`
+	Password           string             `bson:"password" json:"password,omitempty" validate:"required,min=6"`
+	EmailVerified      bool               `bson:"emailVerified" json:"emailVerified"`
+	Role               Role               `bson:"role,omitempty" json:"role,omitempty"`
+	HeightInCm         float64            `bson:"heightInCm" json:"heightInCm,omitempty"`
+	Age                string             `bson:"age" json:"age,omitempty"`
+	Sex                string             `bson:"sex" json:"sex,omitempty"`
+	Country            string             `bson:"country" json:"country,omitempty"`
+	DietPreference     DietPattern        `bson:"dietPreference" json:"dietPreference,omitempty"`
+	CuisinePreferences []string           `bson:"cuisinePreferences,omitempty" json:"cuisinePreferences,omitempty"`
+	MealsPerDay        int                `bson:"mealsPerDay,omitempty" json:"mealsPerDay,omitempty"`
+	FastingWindow      *FastingWindow     `bson:"fastingWindow,omitempty" json:"fastingWindow,omitempty"`
+	EatBackPercentage  *int               `bson:"eatBackPercentage,omitempty" json:"eatBackPercentage,omitempty"` // share of logged activity calories added to the daily target
+	Mfa                *MfaSettings       `bson:"mfa,omitempty" json:"mfa,omitempty"`
+	Identities         []Identity         `bson:"identities,omitempty" json:"identities,omitempty"` // linked social logins
+
+	// Set while a deletion requested by the user waits for its grace period, the account is purged at DeletionScheduledAt
+	DeletionRequestedAt *time.Time `bson:"deletionRequestedAt,omitempty" json:"deletionRequestedAt,omitempty"`
+	DeletionScheduledAt *time.Time `bson:"deletionScheduledAt,omitempty" json:"deletionScheduledAt,omitempty"`
+}
+
+// MfaSettings holds the totp two factor setup, secrets never leave the server
+type MfaSettings struct {
+	Enabled            bool       `bson:"enabled" json:"enabled"`
+	EnabledAt          *time.Time `bson:"enabledAt,omitempty" json:"enabledAt,omitempty"`
+	Secret             string     `bson:"secret,omitempty" json:"-"`
+	PendingSecret      string     `bson:"pendingSecret,omitempty" json:"-"` // set during enrolment until the first code is verified
+	RecoveryCodeHashes []string   `bson:"recoveryCodeHashes,omitempty" json:"-"`
+	LastUsedCounter    int64      `bson:"lastUsedCounter,omitempty" json:"-"` // totp period of the last accepted code, to block replays
+}
+
+// GetRole treats users without a role as regular users
+func (user *User) GetRole() Role {
+	if user.Role == "" {
+		return ROLE_USER
+	}
+	return user.Role
+}
+
+// HasPassword is false for accounts created through a social login until a password is set with a reset
+func (user *User) HasPassword() bool {
+	return user.Password != ""
+}
+
+// NormalizeEmail is the form emails are stored and looked up in, addresses differing only in case or surrounding
+// spaces belong to the same account
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// IsMfaEnabled checks if logins need a second factor
+func (user *User) IsMfaEnabled() bool {
+	return user.Mfa != nil && user.Mfa.Enabled
 }
 
 // IsProfileComplete checks if the user profile is complete based on certain fields.
 func (user *User) IsProfileComplete() bool {
 	return user.HeightInCm != 0 && user.Age != "" && user.Sex != "" && user.Country != ""
 }
+
+// GetEatBackPercentage returns 0 when the user never configured eating back activity calories.
+func (user *User) GetEatBackPercentage() int {
+	if user.EatBackPercentage == nil {
+		return 0
+	}
+	return *user.EatBackPercentage
+}
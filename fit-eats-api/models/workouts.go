@@ -0,0 +1,137 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type MuscleGroup string
+
+const (
+	CHEST     MuscleGroup = "Chest"
+	BACK      MuscleGroup = "Back"
+	LEGS      MuscleGroup = "Legs"
+	SHOULDERS MuscleGroup = "Shoulders"
+	ARMS      MuscleGroup = "Arms"
+	CORE      MuscleGroup = "Core"
+	FULL_BODY MuscleGroup = "Full body"
+)
+
+type ExerciseCategory string
+
+const (
+	STRENGTH ExerciseCategory = "Strength"
+	CARDIO   ExerciseCategory = "Cardio"
+	MOBILITY ExerciseCategory = "Mobility"
+)
+
+type Exercise struct {
+	ID          string           `bson:"_id" json:"id"`
+	Name        string           `bson:"name" json:"name"`
+	MuscleGroup MuscleGroup      `bson:"muscleGroup" json:"muscleGroup"`
+	Category    ExerciseCategory `bson:"category" json:"category"`
+	Equipment   string           `bson:"equipment" json:"equipment"`
+}
+
+// ExerciseCatalogue lists every exercise a routine can be built from, the model is restricted to these names
+var ExerciseCatalogue = []Exercise{
+	{ID: "barbell-bench-press", Name: "Barbell Bench Press", MuscleGroup: CHEST, Category: STRENGTH, Equipment: "Barbell"},
+	{ID: "dumbbell-bench-press", Name: "Dumbbell Bench Press", MuscleGroup: CHEST, Category: STRENGTH, Equipment: "Dumbbell"},
+	{ID: "incline-dumbbell-press", Name: "Incline Dumbbell Press", MuscleGroup: CHEST, Category: STRENGTH, Equipment: "Dumbbell"},
+	{ID: "push-up", Name: "Push Up", MuscleGroup: CHEST, Category: STRENGTH, Equipment: "Bodyweight"},
+	{ID: "cable-fly", Name: "Cable Fly", MuscleGroup: CHEST, Category: STRENGTH, Equipment: "Cable"},
+	{ID: "deadlift", Name: "Deadlift", MuscleGroup: BACK, Category: STRENGTH, Equipment: "Barbell"},
+	{ID: "barbell-row", Name: "Barbell Row", MuscleGroup: BACK, Category: STRENGTH, Equipment: "Barbell"},
+	{ID: "dumbbell-row", Name: "Dumbbell Row", MuscleGroup: BACK, Category: STRENGTH, Equipment: "Dumbbell"},
+	{ID: "pull-up", Name: "Pull Up", MuscleGroup: BACK, Category: STRENGTH, Equipment: "Bodyweight"},
+	{ID: "lat-pulldown", Name: "Lat Pulldown", MuscleGroup: BACK, Category: STRENGTH, Equipment: "Cable"},
+	{ID: "seated-cable-row", Name: "Seated Cable Row", MuscleGroup: BACK, Category: STRENGTH, Equipment: "Cable"},
+	{ID: "back-squat", Name: "Back Squat", MuscleGroup: LEGS, Category: STRENGTH, Equipment: "Barbell"},
+	{ID: "goblet-squat", Name: "Goblet Squat", MuscleGroup: LEGS, Category: STRENGTH, Equipment: "Dumbbell"},
+	{ID: "romanian-deadlift", Name: "Romanian Deadlift", MuscleGroup: LEGS, Category: STRENGTH, Equipment: "Barbell"},
+	{ID: "walking-lunge", Name: "Walking Lunge", MuscleGroup: LEGS, Category: STRENGTH, Equipment: "Dumbbell"},
+	{ID: "leg-press", Name: "Leg Press", MuscleGroup: LEGS, Category: STRENGTH, Equipment: "Machine"},
+	{ID: "leg-curl", Name: "Leg Curl", MuscleGroup: LEGS, Category: STRENGTH, Equipment: "Machine"},
+	{ID: "calf-raise", Name: "Calf Raise", MuscleGroup: LEGS, Category: STRENGTH, Equipment: "Machine"},
+	{ID: "bodyweight-squat", Name: "Bodyweight Squat", MuscleGroup: LEGS, Category: STRENGTH, Equipment: "Bodyweight"},
+	{ID: "overhead-press", Name: "Overhead Press", MuscleGroup: SHOULDERS, Category: STRENGTH, Equipment: "Barbell"},
+	{ID: "dumbbell-shoulder-press", Name: "Dumbbell Shoulder Press", MuscleGroup: SHOULDERS, Category: STRENGTH, Equipment: "Dumbbell"},
+	{ID: "lateral-raise", Name: "Lateral Raise", MuscleGroup: SHOULDERS, Category: STRENGTH, Equipment: "Dumbbell"},
+	{ID: "face-pull", Name: "Face Pull", MuscleGroup: SHOULDERS, Category: STRENGTH, Equipment: "Cable"},
+	{ID: "barbell-curl", Name: "Barbell Curl", MuscleGroup: ARMS, Category: STRENGTH, Equipment: "Barbell"},
+	{ID: "dumbbell-curl", Name: "Dumbbell Curl", MuscleGroup: ARMS, Category: STRENGTH, Equipment: "Dumbbell"},
+	{ID: "tricep-pushdown", Name: "Tricep Pushdown", MuscleGroup: ARMS, Category: STRENGTH, Equipment: "Cable"},
+	{ID: "dips", Name: "Dips", MuscleGroup: ARMS, Category: STRENGTH, Equipment: "Bodyweight"},
+	{ID: "plank", Name: "Plank", MuscleGroup: CORE, Category: STRENGTH, Equipment: "Bodyweight"},
+	{ID: "hanging-leg-raise", Name: "Hanging Leg Raise", MuscleGroup: CORE, Category: STRENGTH, Equipment: "Bodyweight"},
+	{ID: "russian-twist", Name: "Russian Twist", MuscleGroup: CORE, Category: STRENGTH, Equipment: "Bodyweight"},
+	{ID: "kettlebell-swing", Name: "Kettlebell Swing", MuscleGroup: FULL_BODY, Category: STRENGTH, Equipment: "Kettlebell"},
+	{ID: "burpee", Name: "Burpee", MuscleGroup: FULL_BODY, Category: CARDIO, Equipment: "Bodyweight"},
+	{ID: "treadmill-run", Name: "Treadmill Run", MuscleGroup: FULL_BODY, Category: CARDIO, Equipment: "Treadmill"},
+	{ID: "brisk-walk", Name: "Brisk Walk", MuscleGroup: FULL_BODY, Category: CARDIO, Equipment: "None"},
+	{ID: "stationary-bike", Name: "Stationary Bike", MuscleGroup: FULL_BODY, Category: CARDIO, Equipment: "Bike"},
+	{ID: "rowing-machine", Name: "Rowing Machine", MuscleGroup: FULL_BODY, Category: CARDIO, Equipment: "Rower"},
+	{ID: "jump-rope", Name: "Jump Rope", MuscleGroup: FULL_BODY, Category: CARDIO, Equipment: "Rope"},
+	{ID: "hip-mobility-flow", Name: "Hip Mobility Flow", MuscleGroup: LEGS, Category: MOBILITY, Equipment: "None"},
+	{ID: "full-body-stretch", Name: "Full Body Stretch", MuscleGroup: FULL_BODY, Category: MOBILITY, Equipment: "None"},
+}
+
+func GetExerciseById(id string) (Exercise, bool) {
+	for _, exercise := range ExerciseCatalogue {
+		if exercise.ID == id {
+			return exercise, true
+		}
+	}
+	return Exercise{}, false
+}
+
+func GetExerciseByName(name string) (Exercise, bool) {
+	for _, exercise := range ExerciseCatalogue {
+		if strings.EqualFold(exercise.Name, strings.TrimSpace(name)) {
+			return exercise, true
+		}
+	}
+	return Exercise{}, false
+}
+
+func GetExerciseNames() []string {
+	names := make([]string, len(ExerciseCatalogue))
+	for i, exercise := range ExerciseCatalogue {
+		names[i] = exercise.Name
+	}
+	return names
+}
+
+type WorkoutRoutine struct {
+	ID primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+
+	UserId       primitive.ObjectID `bson:"userId" json:"userId"`
+	MainGoalId   primitive.ObjectID `bson:"mainGoalId" json:"mainGoalId"`
+	WeeklyGoalId primitive.ObjectID `bson:"weeklyGoalId" json:"weeklyGoalId"`
+	GoalType     GoalType           `bson:"goalType" json:"goalType"`
+
+	WorkoutDays []WorkoutDay `bson:"workoutDays" json:"workoutDays"`
+}
+
+type WorkoutDay struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	Date      time.Time          `bson:"date" json:"date"`
+	Name      string             `bson:"name" json:"name"` // e.g., "Upper body strength"
+	IsRestDay bool               `bson:"isRestDay" json:"isRestDay"`
+
+	Exercises []RoutineExercise `bson:"exercises" json:"exercises"`
+}
+
+type RoutineExercise struct {
+	ID              primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	ExerciseId      string             `bson:"exerciseId" json:"exerciseId"`
+	Name            string             `bson:"name" json:"name"`
+	Sets            int                `bson:"sets" json:"sets"`
+	Reps            int                `bson:"reps" json:"reps"`
+	WeightInKg      float64            `bson:"weightInKg" json:"weightInKg"`
+	DurationMinutes int                `bson:"durationMinutes" json:"durationMinutes"` // for cardio and mobility exercises
+	RestSeconds     int                `bson:"restSeconds" json:"restSeconds"`
+	Notes           string             `bson:"notes" json:"notes"`
+}
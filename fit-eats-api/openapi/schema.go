@@ -0,0 +1,198 @@
+package openapi
+
+import (
+	"reflect"
+	"strings"
+	"time"
+	"unicode"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const OBJECT_ID_PATTERN = "^[0-9a-f]{24}$"
+
+// Schema is the subset of the OpenAPI 3.0 schema object produced from the Go types
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	Enum                 []any              `json:"enum,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	AllOf                []*Schema          `json:"allOf,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Description          string             `json:"description,omitempty"`
+}
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	objectIdType = reflect.TypeOf(primitive.ObjectID{})
+	durationType = reflect.TypeOf(time.Duration(0))
+)
+
+// schemaGenerator turns Go types into schemas following their json encoding. Named structs are
+// registered once as components and referenced, anonymous structs are inlined
+type schemaGenerator struct {
+	components map[string]*Schema
+	enums      map[reflect.Type][]any
+}
+
+func newSchemaGenerator(enums map[reflect.Type][]any) *schemaGenerator {
+	return &schemaGenerator{components: map[string]*Schema{}, enums: enums}
+}
+
+func (g *schemaGenerator) schemaOf(t reflect.Type) *Schema {
+	switch t {
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case objectIdType:
+		return &Schema{Type: "string", Pattern: OBJECT_ID_PATTERN}
+	case durationType:
+		return &Schema{Type: "integer", Format: "int64", Description: "Duration in nanoseconds"}
+	}
+	if values, ok := g.enums[t]; ok {
+		return &Schema{Type: "string", Enum: values}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return nullable(g.schemaOf(t.Elem()))
+	case reflect.Interface:
+		return &Schema{}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		// Nil slices are encoded as null
+		return &Schema{Type: "array", Items: g.schemaOf(t.Elem()), Nullable: true}
+	case reflect.Array:
+		return &Schema{Type: "array", Items: g.schemaOf(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.schemaOf(t.Elem()), Nullable: true}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.structSchema(t)
+		}
+		name := componentName(t)
+		if _, ok := g.components[name]; !ok {
+			g.components[name] = &Schema{} // Placeholder for recursive types
+			g.components[name] = g.structSchema(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + name}
+	}
+	return &Schema{}
+}
+
+func (g *schemaGenerator) structSchema(t reflect.Type) *Schema {
+	schema := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	g.addFields(schema, t)
+	return schema
+}
+
+func (g *schemaGenerator) addFields(schema *Schema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+
+		// Embedded structs without a json name are flattened like encoding/json does
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			g.addFields(schema, field.Type)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		property := g.schemaOf(field.Type)
+		omitEmpty := strings.Contains(options, "omitempty")
+		if values := getOneOfValues(field.Tag.Get("validate")); values != nil && property.Type == "string" {
+			property = &Schema{Type: "string", Enum: values}
+			if !omitEmpty {
+				property.Enum = append(property.Enum, "")
+			}
+		}
+		schema.Properties[name] = property
+		if !omitEmpty {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+}
+
+// getOneOfValues reads the values of a oneof validation, quoted values may contain spaces
+func getOneOfValues(tag string) []any {
+	for _, rule := range splitRules(tag) {
+		list, ok := strings.CutPrefix(rule, "oneof=")
+		if !ok {
+			continue
+		}
+
+		var values []any
+		for len(list) > 0 {
+			list = strings.TrimLeft(list, " ")
+			if strings.HasPrefix(list, "'") {
+				end := strings.Index(list[1:], "'")
+				if end < 0 {
+					break
+				}
+				values = append(values, list[1:end+1])
+				list = list[end+2:]
+				continue
+			}
+			value, rest, _ := strings.Cut(list, " ")
+			values = append(values, value)
+			list = rest
+		}
+		return values
+	}
+	return nil
+}
+
+// splitRules splits the validate tag on the commas outside of quoted values
+func splitRules(tag string) []string {
+	var rules []string
+	quoted, start := false, 0
+	for i, r := range tag {
+		switch {
+		case r == '\'':
+			quoted = !quoted
+		case r == ',' && !quoted:
+			rules = append(rules, tag[start:i])
+			start = i + 1
+		}
+	}
+	return append(rules, tag[start:])
+}
+
+func nullable(schema *Schema) *Schema {
+	if schema.Ref != "" {
+		return &Schema{AllOf: []*Schema{schema}, Nullable: true}
+	}
+	copy := *schema
+	copy.Nullable = true
+	return &copy
+}
+
+func componentName(t reflect.Type) string {
+	name := []rune(t.Name())
+	name[0] = unicode.ToUpper(name[0])
+	return string(name)
+}
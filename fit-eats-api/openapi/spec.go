@@ -0,0 +1,237 @@
+package openapi
+
+import (
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	OPENAPI_VERSION = "3.0.3"
+	BEARER_AUTH     = "bearerAuth"
+)
+
+type Document struct {
+	OpenApi    string              `json:"openapi"`
+	Info       Info                `json:"info"`
+	Paths      map[string]PathItem `json:"paths"`
+	Components Components          `json:"components"`
+}
+
+type Info struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+// PathItem holds the operations of a path keyed by the lower case http method
+type PathItem map[string]*Operation
+
+type Operation struct {
+	OperationId string                `json:"operationId"`
+	Summary     string                `json:"summary,omitempty"`
+	Tags        []string              `json:"tags,omitempty"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]*Response  `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+}
+
+type Parameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required"`
+	Schema   *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+type Components struct {
+	Schemas         map[string]*Schema        `json:"schemas"`
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes"`
+}
+
+type SecurityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+}
+
+// Route documents a gin route, the request and response bodies are described by Go values whose types
+// are turned into schemas. A nil response value is a response without a body
+type Route struct {
+	Summary   string
+	Public    bool // Reachable without an access token
+	Query     []Param
+	Form      []Param
+	Body      any
+	Responses map[int]any
+}
+
+// Param is a string query or form param, Id params are validated as ObjectIds
+type Param struct {
+	Name     string
+	Required bool
+	Id       bool
+}
+
+// Binary is a response value for a file download of the content type
+type Binary string
+
+// Build documents every registered route with its entry of docs, keyed by method and gin path like "GET /api/profile".
+// Every operation answers errors with errorBody as its default response, routes without an entry are still listed
+// with it so the document never hides a route
+func Build(info Info, routes gin.RoutesInfo, docs map[string]Route, errorBody any, enums map[reflect.Type][]any) *Document {
+	generator := newSchemaGenerator(enums)
+	errorSchema := generator.schemaOf(reflect.TypeOf(errorBody))
+
+	document := &Document{
+		OpenApi: OPENAPI_VERSION,
+		Info:    info,
+		Paths:   map[string]PathItem{},
+		Components: Components{
+			SecuritySchemes: map[string]SecurityScheme{BEARER_AUTH: {Type: "http", Scheme: "bearer", BearerFormat: "JWT"}},
+		},
+	}
+
+	sorted := append(gin.RoutesInfo{}, routes...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Path+sorted[i].Method < sorted[j].Path+sorted[j].Method
+	})
+
+	operationIds := map[string]int{}
+	for _, route := range sorted {
+		doc := docs[RouteKey(route.Method, route.Path)]
+		path, pathParams := toOpenApiPath(route.Path)
+
+		// Handlers shared by the legacy and v1 routes need distinct operation ids
+		operationId := getOperationId(route.Handler)
+		if operationIds[operationId]++; operationIds[operationId] > 1 {
+			operationId += strconv.Itoa(operationIds[operationId])
+		}
+
+		operation := &Operation{
+			OperationId: operationId,
+			Summary:     doc.Summary,
+			Tags:        []string{getTag(route.Handler)},
+			Responses: map[string]*Response{
+				"default": {Description: "Error", Content: jsonContent(errorSchema)},
+			},
+		}
+		if !doc.Public {
+			operation.Security = []map[string][]string{{BEARER_AUTH: {}}}
+		}
+
+		for _, name := range pathParams {
+			operation.Parameters = append(operation.Parameters, Parameter{Name: name, In: "path", Required: true, Schema: &Schema{Type: "string", Pattern: OBJECT_ID_PATTERN}})
+		}
+		for _, param := range doc.Query {
+			operation.Parameters = append(operation.Parameters, Parameter{Name: param.Name, In: "query", Required: param.Required, Schema: param.schema()})
+		}
+
+		if doc.Body != nil {
+			operation.RequestBody = &RequestBody{Required: true, Content: jsonContent(generator.schemaOf(reflect.TypeOf(doc.Body)))}
+		} else if len(doc.Form) > 0 {
+			form := &Schema{Type: "object", Properties: map[string]*Schema{}}
+			for _, param := range doc.Form {
+				form.Properties[param.Name] = param.schema()
+				if param.Required {
+					form.Required = append(form.Required, param.Name)
+				}
+			}
+			operation.RequestBody = &RequestBody{Required: true, Content: map[string]MediaType{"application/x-www-form-urlencoded": {Schema: form}}}
+		}
+
+		for status, value := range doc.Responses {
+			response := &Response{Description: statusDescription(status)}
+			switch value := value.(type) {
+			case nil:
+			case Binary:
+				response.Content = map[string]MediaType{string(value): {Schema: &Schema{Type: "string", Format: "binary"}}}
+			default:
+				response.Content = jsonContent(generator.schemaOf(reflect.TypeOf(value)))
+			}
+			operation.Responses[strconv.Itoa(status)] = response
+		}
+
+		if document.Paths[path] == nil {
+			document.Paths[path] = PathItem{}
+		}
+		document.Paths[path][strings.ToLower(route.Method)] = operation
+	}
+
+	document.Components.Schemas = generator.components
+	return document
+}
+
+func RouteKey(method string, path string) string {
+	return method + " " + path
+}
+
+func (p Param) schema() *Schema {
+	if p.Id {
+		return &Schema{Type: "string", Pattern: OBJECT_ID_PATTERN}
+	}
+	return &Schema{Type: "string"}
+}
+
+func jsonContent(schema *Schema) map[string]MediaType {
+	return map[string]MediaType{"application/json": {Schema: schema}}
+}
+
+// toOpenApiPath turns gin params like :goalId into {goalId} and returns their names
+func toOpenApiPath(path string) (string, []string) {
+	var params []string
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if name, ok := strings.CutPrefix(segment, ":"); ok {
+			segments[i] = "{" + name + "}"
+			params = append(params, name)
+		}
+	}
+	return strings.Join(segments, "/"), params
+}
+
+// getOperationId uses the method name of the handler, like GetMe for fit-eats-api/controllers.(*UserController).GetMe-fm
+func getOperationId(handler string) string {
+	name := strings.TrimSuffix(handler, "-fm")
+	return name[strings.LastIndex(name, ".")+1:]
+}
+
+// getTag groups the operations by controller, like User for fit-eats-api/controllers.(*UserController).GetMe-fm
+func getTag(handler string) string {
+	start := strings.Index(handler, "(*")
+	end := strings.Index(handler, "Controller)")
+	if start < 0 || end < start {
+		return "Api"
+	}
+	return handler[start+2 : end]
+}
+
+func statusDescription(status int) string {
+	switch status {
+	case 200:
+		return "OK"
+	case 201:
+		return "Created"
+	case 202:
+		return "Accepted"
+	case 204:
+		return "No Content"
+	}
+	return strconv.Itoa(status)
+}
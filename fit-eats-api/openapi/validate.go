@@ -0,0 +1,172 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"mime"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ValidateResponse checks a response of the route, given with its gin path, against the document.
+// Objects are validated strictly, a property missing from the schema is reported so that handlers
+// and models drifting away from the documented shapes are caught
+func (d *Document) ValidateResponse(method string, path string, status int, contentType string, body []byte) error {
+	openApiPath, _ := toOpenApiPath(path)
+	operation := d.Paths[openApiPath][strings.ToLower(method)]
+	if operation == nil {
+		return fmt.Errorf("%s %s is not documented", method, path)
+	}
+
+	response := operation.Responses[strconv.Itoa(status)]
+	if response == nil {
+		if status < 400 {
+			return fmt.Errorf("%s %s responded with undocumented status %d", method, path, status)
+		}
+		response = operation.Responses["default"]
+	}
+
+	if len(response.Content) == 0 {
+		if len(body) > 0 {
+			return fmt.Errorf("%s %s %d: expected no body, got %s", method, path, status, body)
+		}
+		return nil
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	content, ok := response.Content[mediaType]
+	if !ok {
+		return fmt.Errorf("%s %s %d: undocumented content type %q", method, path, status, contentType)
+	}
+	if mediaType != "application/json" {
+		return nil
+	}
+
+	var value any
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	if err := decoder.Decode(&value); err != nil {
+		return fmt.Errorf("%s %s %d: invalid json: %v", method, path, status, err)
+	}
+	if err := d.validate(content.Schema, value, "$"); err != nil {
+		return fmt.Errorf("%s %s %d: %v", method, path, status, err)
+	}
+	return nil
+}
+
+func (d *Document) validate(schema *Schema, value any, at string) error {
+	if schema.Ref != "" {
+		name := strings.TrimPrefix(schema.Ref, "#/components/schemas/")
+		component, ok := d.Components.Schemas[name]
+		if !ok {
+			return fmt.Errorf("%s: unknown schema %s", at, schema.Ref)
+		}
+		return d.validate(component, value, at)
+	}
+
+	if value == nil {
+		if schema.Nullable || schema.Type == "" && len(schema.AllOf) == 0 {
+			return nil
+		}
+		return fmt.Errorf("%s: must not be null", at)
+	}
+	for _, part := range schema.AllOf {
+		if err := d.validate(part, value, at); err != nil {
+			return err
+		}
+	}
+	if len(schema.Enum) > 0 && !containsValue(schema.Enum, value) {
+		return fmt.Errorf("%s: %v is not one of %v", at, value, schema.Enum)
+	}
+
+	switch schema.Type {
+	case "object":
+		object, ok := value.(map[string]any)
+		if !ok {
+			return fmt.Errorf("%s: expected an object, got %T", at, value)
+		}
+		return d.validateObject(schema, object, at)
+	case "array":
+		array, ok := value.([]any)
+		if !ok {
+			return fmt.Errorf("%s: expected an array, got %T", at, value)
+		}
+		for i, item := range array {
+			if err := d.validate(schema.Items, item, fmt.Sprintf("%s[%d]", at, i)); err != nil {
+				return err
+			}
+		}
+	case "string":
+		text, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("%s: expected a string, got %T", at, value)
+		}
+		if schema.Pattern != "" && !regexp.MustCompile(schema.Pattern).MatchString(text) {
+			return fmt.Errorf("%s: %q does not match %s", at, text, schema.Pattern)
+		}
+		if schema.Format == "date-time" {
+			if _, err := time.Parse(time.RFC3339Nano, text); err != nil {
+				return fmt.Errorf("%s: %q is not a date-time", at, text)
+			}
+		}
+	case "integer", "number":
+		number, ok := value.(json.Number)
+		if !ok {
+			return fmt.Errorf("%s: expected a number, got %T", at, value)
+		}
+		parsed, err := number.Float64()
+		if err != nil {
+			return fmt.Errorf("%s: %v", at, err)
+		}
+		if schema.Type == "integer" && parsed != math.Trunc(parsed) {
+			return fmt.Errorf("%s: %v is not an integer", at, number)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("%s: expected a boolean, got %T", at, value)
+		}
+	}
+	return nil
+}
+
+func (d *Document) validateObject(schema *Schema, object map[string]any, at string) error {
+	for _, name := range schema.Required {
+		if _, ok := object[name]; !ok {
+			return fmt.Errorf("%s: missing required property %s", at, name)
+		}
+	}
+
+	names := make([]string, 0, len(object))
+	for name := range object {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		property, ok := schema.Properties[name]
+		if !ok {
+			property = schema.AdditionalProperties
+		}
+		if property == nil {
+			return fmt.Errorf("%s: undocumented property %s", at, name)
+		}
+		if err := d.validate(property, object[name], at+"."+name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func containsValue(values []any, value any) bool {
+	for _, candidate := range values {
+		if fmt.Sprint(candidate) == fmt.Sprint(value) {
+			return true
+		}
+	}
+	return false
+}
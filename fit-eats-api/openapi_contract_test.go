@@ -0,0 +1,410 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"fit-eats-api/config"
+	"fit-eats-api/middleware"
+	"fit-eats-api/models"
+	"fit-eats-api/openapi"
+	"fit-eats-api/repositories"
+	"fit-eats-api/routes"
+	"fit-eats-api/utils"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+var contractUserId = primitive.NewObjectID()
+var contractSessionId = primitive.NewObjectID()
+
+// contractAuthMiddleware signs every request carrying an Authorization header in as the given user
+func contractAuthMiddleware(userId primitive.ObjectID, role models.Role) func(*repositories.SessionRepository) gin.HandlerFunc {
+	return func(*repositories.SessionRepository) gin.HandlerFunc {
+		return func(c *gin.Context) {
+			if c.GetHeader("Authorization") == "" {
+				c.Error(models.NewApiError(models.UNAUTHORIZED, "No token provided"))
+				c.Abort()
+				return
+			}
+			c.Set(middleware.USER_ID_KEY, userId)
+			c.Set(middleware.EMAIL_KEY, "contract@fiteats.test")
+			c.Set(middleware.SESSION_ID_KEY, contractSessionId)
+			c.Set(middleware.ROLE_KEY, role)
+			c.Next()
+		}
+	}
+}
+
+// runContract runs the test on a router backed by a mocked database, answering the queries with the queued responses
+func runContract(t *testing.T, name string, test func(mt *mtest.T, router *gin.Engine, document *openapi.Document)) {
+	gin.SetMode(gin.TestMode)
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run(name, func(mt *mtest.T) {
+		stores, err := newMongoStores(mt.DB, repositories.NewMongoUnitOfWork(mt.DB, true))
+		if err != nil {
+			mt.Fatal(err)
+		}
+		router, _ := newRouter(&config.Config{}, mt.DB, stores, &utils.LogMailer{}, contractAuthMiddleware(contractUserId, models.ROLE_USER))
+
+		recorder := serve(router, http.MethodGet, routes.OPENAPI_PATH, "", true)
+		if recorder.Code != http.StatusOK {
+			mt.Fatalf("GET %s: %d %s", routes.OPENAPI_PATH, recorder.Code, recorder.Body)
+		}
+		var document openapi.Document
+		if err := json.Unmarshal(recorder.Body.Bytes(), &document); err != nil {
+			mt.Fatalf("GET %s: %v", routes.OPENAPI_PATH, err)
+		}
+
+		test(mt, router, &document)
+	})
+}
+
+func serve(router *gin.Engine, method string, target string, body string, authenticated bool) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(method, target, strings.NewReader(body))
+	if body != "" {
+		request.Header.Set("Content-Type", "application/json")
+	}
+	if authenticated {
+		request.Header.Set("Authorization", "Bearer contract")
+	}
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func toDocument(t *mtest.T, value any) bson.D {
+	data, err := bson.Marshal(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var document bson.D
+	if err := bson.Unmarshal(data, &document); err != nil {
+		t.Fatal(err)
+	}
+	return document
+}
+
+func findResponse(t *mtest.T, collection string, values ...any) bson.D {
+	batch := make([]bson.D, len(values))
+	for i, value := range values {
+		batch[i] = toDocument(t, value)
+	}
+	return mtest.CreateCursorResponse(0, "fiteats."+collection, mtest.FirstBatch, batch...)
+}
+
+func TestOpenApiDocumentsEveryRoute(t *testing.T) {
+	runContract(t, "documented", func(mt *mtest.T, router *gin.Engine, document *openapi.Document) {
+		if undocumented := routes.GetUndocumentedRoutes(router); len(undocumented) > 0 {
+			mt.Errorf("routes missing from the OpenAPI documentation: %v", undocumented)
+		}
+
+		operationIds := map[string]string{}
+		for _, route := range router.Routes() {
+			path := route.Path
+			for _, segment := range strings.Split(route.Path, "/") {
+				if name, ok := strings.CutPrefix(segment, ":"); ok {
+					path = strings.Replace(path, segment, "{"+name+"}", 1)
+				}
+			}
+			operation := document.Paths[path][strings.ToLower(route.Method)]
+			if operation == nil {
+				mt.Errorf("%s %s is missing from the served document", route.Method, route.Path)
+				continue
+			}
+			if other, ok := operationIds[operation.OperationId]; ok {
+				mt.Errorf("%s %s and %s share the operation id %s", route.Method, route.Path, other, operation.OperationId)
+			}
+			operationIds[operation.OperationId] = route.Method + " " + route.Path
+		}
+	})
+}
+
+// TestOpenApiErrorResponses calls every route without its params, with and without an access token,
+// and checks the rejections match the documented error schema
+func TestOpenApiErrorResponses(t *testing.T) {
+	runContract(t, "errors", func(mt *mtest.T, router *gin.Engine, document *openapi.Document) {
+		for _, route := range router.Routes() {
+			if route.Path == routes.OPENAPI_PATH {
+				continue
+			}
+
+			target := route.Path
+			for _, segment := range strings.Split(route.Path, "/") {
+				if strings.HasPrefix(segment, ":") {
+					target = strings.Replace(target, segment, primitive.NewObjectID().Hex(), 1)
+				}
+			}
+
+			for _, authenticated := range []bool{false, true} {
+				recorder := serve(router, route.Method, target, "", authenticated)
+				err := document.ValidateResponse(route.Method, route.Path, recorder.Code, recorder.Header().Get("Content-Type"), recorder.Body.Bytes())
+				if err != nil {
+					mt.Errorf("authenticated %v: %v", authenticated, err)
+				}
+			}
+		}
+	})
+}
+
+// contractCase is a request expected to succeed, answered by the queued responses of the mocked database and the
+// data the prepare function leaves in the in memory stores
+type contractCase struct {
+	name      string
+	method    string
+	path      string
+	target    string
+	body      string
+	role      models.Role
+	prepare   func(ctx context.Context, stores routerStores) error
+	responses func(mt *mtest.T) []bson.D
+	status    int
+}
+
+func expectContractResponse(mt *mtest.T, router *gin.Engine, document *openapi.Document, c contractCase) {
+	mt.ClearMockResponses()
+	if c.responses != nil {
+		mt.AddMockResponses(c.responses(mt)...)
+	}
+
+	recorder := serve(router, c.method, c.target, c.body, true)
+	if recorder.Code != c.status {
+		mt.Errorf("%s: expected %d, got %d %s", c.name, c.status, recorder.Code, recorder.Body)
+		return
+	}
+	if err := document.ValidateResponse(c.method, c.path, recorder.Code, recorder.Header().Get("Content-Type"), recorder.Body.Bytes()); err != nil {
+		mt.Errorf("%s: %v", c.name, err)
+	}
+}
+
+func toJSON(t *testing.T, value any) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+// TestOpenApiSuccessResponses checks the successful responses of the main resources against the document
+func TestOpenApiSuccessResponses(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	user := models.User{ID: contractUserId, Name: "Contract", Email: "contract@fiteats.test", EmailVerified: true, HeightInCm: 180, MealsPerDay: 3}
+	session := models.Session{ID: contractSessionId, UserId: contractUserId, DeviceName: "Pixel", CreatedAt: now, LastUsedAt: now, ExpiresAt: now.Add(time.Hour)}
+	weeklyGoal := models.WeeklyGoal{ID: primitive.NewObjectID(), StartDate: now, EndDate: now.AddDate(0, 0, 7), CurrentWeightInKg: 80, ActivityLevel: models.MODERATE}
+	goal := models.Goal{ID: primitive.NewObjectID(), UserId: contractUserId, GoalType: models.FAT_LOSS, Status: models.GOAL_ACTIVE, GoalStartDate: now, GoalEndDate: now.AddDate(0, 3, 0), WeeklyGoals: []models.WeeklyGoal{weeklyGoal}}
+	self := "?userId=" + contractUserId.Hex()
+
+	cases := []contractCase{
+		{name: "diet options", method: http.MethodGet, path: "/api/dietOptions", target: "/api/dietOptions", status: http.StatusOK},
+		{name: "exercises", method: http.MethodGet, path: "/api/getExercises", target: "/api/getExercises", status: http.StatusOK},
+		{name: "me", method: http.MethodGet, path: "/api/v1/users/me", target: "/api/v1/users/me", status: http.StatusOK, responses: func(mt *mtest.T) []bson.D {
+			return []bson.D{findResponse(mt, "users", user)}
+		}},
+		{name: "deleted me", method: http.MethodGet, path: "/api/v1/users/me", target: "/api/v1/users/me", status: http.StatusNotFound, responses: func(mt *mtest.T) []bson.D {
+			return []bson.D{findResponse(mt, "users")}
+		}},
+		{name: "sessions", method: http.MethodGet, path: "/api/v1/users/me/sessions", target: "/api/v1/users/me/sessions", status: http.StatusOK, responses: func(mt *mtest.T) []bson.D {
+			return []bson.D{findResponse(mt, "sessions", session)}
+		}},
+		{name: "legacy sessions", method: http.MethodGet, path: "/api/getSessions", target: "/api/getSessions", status: http.StatusOK, responses: func(mt *mtest.T) []bson.D {
+			return []bson.D{findResponse(mt, "sessions", session)}
+		}},
+		{name: "goals", method: http.MethodGet, path: "/api/v1/goals", target: "/api/v1/goals", status: http.StatusOK, responses: func(mt *mtest.T) []bson.D {
+			return []bson.D{findResponse(mt, "userGoals", goal)}
+		}},
+		{name: "no goals", method: http.MethodGet, path: "/api/v1/goals", target: "/api/v1/goals", status: http.StatusOK, responses: func(mt *mtest.T) []bson.D {
+			return []bson.D{findResponse(mt, "userGoals")}
+		}},
+		{name: "goal", method: http.MethodGet, path: "/api/v1/goals/:goalId", target: "/api/v1/goals/" + goal.ID.Hex(), status: http.StatusOK, responses: func(mt *mtest.T) []bson.D {
+			return []bson.D{findResponse(mt, "userGoals", goal), findResponse(mt, "userGoals", goal)}
+		}},
+		{name: "legacy goals", method: http.MethodGet, path: "/api/getGoals", target: "/api/getGoals" + self, status: http.StatusOK, responses: func(mt *mtest.T) []bson.D {
+			return []bson.D{findResponse(mt, "userGoals", goal)}
+		}},
+	}
+
+	runContract(t, "success", func(mt *mtest.T, router *gin.Engine, document *openapi.Document) {
+		for _, c := range cases {
+			expectContractResponse(mt, router, document, c)
+		}
+	})
+}
+
+// TestOpenApiStoredSuccessResponses checks the successful responses of the goals, meal plans, dashboard, hydration,
+// activity and coach routes against the document. Users, goals and meal plans are read from the in memory stores,
+// seeded again for every case, the other collections answer with the queued responses. The routes generating with
+// the AI models are left out, they cannot succeed without the model
+func TestOpenApiStoredSuccessResponses(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Millisecond)
+
+	// The in memory user repository picks the id of the user, the cases address it
+	users := repositories.NewInMemoryUserRepository()
+	user := &models.User{Name: "Contract", Email: "contract@fiteats.test", EmailVerified: true, HeightInCm: 180, MealsPerDay: 3, Age: "30", Sex: "male"}
+	if err := users.CreateUser(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+	self := "?userId=" + user.ID.Hex()
+
+	week := models.WeeklyGoal{ID: primitive.NewObjectID(), StartDate: now.AddDate(0, 0, -1), EndDate: now.AddDate(0, 0, 6), CurrentWeightInKg: 80, ActivityLevel: models.MODERATE,
+		DailyMaintenanceCalories: 2500, TargetDailyCalories: 2000, TargetDailyMacrosProtein: 150, TargetDailyMacrosCarbs: 200, TargetDailyMacrosFats: 60}
+	nextWeek := models.WeeklyGoal{ID: primitive.NewObjectID(), StartDate: now.AddDate(0, 0, 6), EndDate: now.AddDate(0, 0, 13), CurrentWeightInKg: 79.5}
+	goal := models.Goal{ID: primitive.NewObjectID(), UserId: user.ID, GoalType: models.FAT_LOSS, Status: models.GOAL_ACTIVE, StartWeightInKg: 81, TargetWeightInKg: 75,
+		GoalStartDate: now.AddDate(0, 0, -1), GoalEndDate: now.AddDate(0, 3, 0), WeeklyWeightChange: -0.5, WeeklyGoals: []models.WeeklyGoal{week, nextWeek}}
+	endedAt := now.AddDate(0, -1, 0)
+	endedGoal := models.Goal{ID: primitive.NewObjectID(), UserId: user.ID, GoalType: models.MAINTENANCE, Status: models.GOAL_COMPLETED, StartWeightInKg: 82, TargetWeightInKg: 82,
+		GoalStartDate: now.AddDate(0, -4, 0), GoalEndDate: endedAt, EndedAt: &endedAt, WeeklyGoals: []models.WeeklyGoal{}}
+
+	breakfast := models.Meal{ID: primitive.NewObjectID(), Name: "Oats", Calories: 400, Protein: 20, Carbs: 60, Fat: 8, Time: "08:00",
+		Nutrients: models.Micronutrients{Fibre: 8, Iron: 4, Calcium: 150}, Ingredients: []models.Ingredient{{Name: "Oats", Quantity: "80 g"}}, RecipeSteps: []string{"Simmer the oats"}}
+	dinner := models.Meal{ID: primitive.NewObjectID(), Name: "Dal", Calories: 600, Protein: 30, Carbs: 80, Fat: 15, Time: "20:00", IsConsumed: true}
+	today := models.DayMeal{ID: primitive.NewObjectID(), Date: now, Meals: []models.Meal{breakfast, dinner}}
+	mealPlan := models.MealPlan{ID: primitive.NewObjectID(), UserId: user.ID, MainGoalId: goal.ID, WeeklyGoalId: week.ID,
+		DayMeals: []models.DayMeal{today, {ID: primitive.NewObjectID(), Date: now.AddDate(0, 0, 1), Meals: []models.Meal{breakfast}}}}
+
+	seed := func() routerStores {
+		stores := routerStores{
+			unitOfWork:    repositories.NewInMemoryUnitOfWork(),
+			users:         users,
+			userGoals:     repositories.NewInMemoryUserGoalRepository(),
+			meals:         repositories.NewInMemoryMealRepository(),
+			loginAttempts: repositories.NewInMemoryLoginAttemptStore(),
+			dataExports:   repositories.NewInMemoryDataExportRepository(),
+		}
+		for _, seeded := range []models.Goal{goal, endedGoal} {
+			seeded.WeeklyGoals = append([]models.WeeklyGoal{}, seeded.WeeklyGoals...)
+			if err := stores.userGoals.CreateMainUserGoal(ctx, &seeded); err != nil {
+				t.Fatal(err)
+			}
+		}
+		seededMealPlan := mealPlan
+		if err := stores.meals.CreateWeeklyMealPlan(ctx, &seededMealPlan); err != nil {
+			t.Fatal(err)
+		}
+		return stores
+	}
+	// The goal creations need the seeded active goal to be ended first
+	endActiveGoal := func(ctx context.Context, stores routerStores) error {
+		return stores.userGoals.EndUserGoal(ctx, goal.ID, models.GOAL_ABANDONED, nil)
+	}
+
+	hydrationLog := models.HydrationLog{ID: primitive.NewObjectID(), UserId: user.ID, AmountInMl: 250, BeverageType: models.WATER, LoggedAt: now}
+	workoutSession := models.WorkoutSession{ID: primitive.NewObjectID(), UserId: user.ID, Date: now, DurationMinutes: 45, RPE: 7,
+		Exercises: []models.LoggedExercise{{ExerciseId: "push-up", Name: "Push Up", Sets: []models.LoggedSet{{Reps: 12}}}}, EstimatedCalories: 300}
+	activityLog := models.ActivityLog{ID: primitive.NewObjectID(), UserId: user.ID, ActivityType: models.WALKING, Date: now, Steps: 8000, EstimatedCalories: 250}
+	clientWeek := week
+	clientOverview := models.ClientOverviewRecord{CoachLinkId: primitive.NewObjectID(), Client: models.User{ID: primitive.NewObjectID(), Name: "Client", Email: "client@fiteats.test"},
+		Goal: &models.Goal{ID: primitive.NewObjectID(), GoalType: models.FAT_LOSS, StartWeightInKg: 90, TargetWeightInKg: 80}, LatestWeeklyGoal: &clientWeek,
+		RecentDayMeals: []models.DayMeal{{ID: primitive.NewObjectID(), Date: now, Meals: []models.Meal{{Calories: 500, Protein: 30, IsConsumed: true}, {Calories: 700}}}}, HasCurrentMealPlan: true}
+
+	// The routes below the auth middleware write an audit log once they succeed
+	audited := func(responses ...bson.D) func(mt *mtest.T) []bson.D {
+		return func(mt *mtest.T) []bson.D {
+			return append(responses, mtest.CreateSuccessResponse())
+		}
+	}
+	deleted := mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1})
+	// The deletes of logs snapshot the log before and after the delete
+	auditedDelete := func(collection string, deletedLog any) func(mt *mtest.T) []bson.D {
+		return func(mt *mtest.T) []bson.D {
+			return []bson.D{findResponse(mt, collection, deletedLog), deleted, findResponse(mt, collection), mtest.CreateSuccessResponse()}
+		}
+	}
+
+	goalPath := "/api/v1/goals/" + goal.ID.Hex()
+	weekPath := goalPath + "/weeks/" + week.ID.Hex()
+	goalQuery := self + "&mainGoalId=" + goal.ID.Hex() + "&weeklyGoalId=" + week.ID.Hex()
+	newGoal := toJSON(t, map[string]any{"userId": user.ID, "goalType": models.FAT_LOSS, "startWeightInKg": 80, "targetWeightInKg": 76, "goalStartDate": now, "goalEndDate": now.AddDate(0, 3, 0)})
+	newWeek := toJSON(t, map[string]any{"startDate": now.AddDate(0, 0, 13), "endDate": now.AddDate(0, 0, 20), "currentWeightInKg": 79, "activityLevel": models.MODERATE})
+
+	cases := []contractCase{
+		{name: "active goal", method: http.MethodGet, path: "/api/v1/goals/active", target: "/api/v1/goals/active", status: http.StatusOK},
+		{name: "legacy active goal", method: http.MethodGet, path: "/api/getActiveGoal", target: "/api/getActiveGoal" + self, status: http.StatusOK},
+		{name: "create goal", method: http.MethodPost, path: "/api/v1/goals", target: "/api/v1/goals", body: newGoal, prepare: endActiveGoal, responses: audited(), status: http.StatusCreated},
+		{name: "legacy create goal", method: http.MethodPost, path: "/api/registerMainGoal", target: "/api/registerMainGoal", body: newGoal, prepare: endActiveGoal, responses: audited(), status: http.StatusCreated},
+		{name: "complete goal", method: http.MethodPatch, path: "/api/v1/goals/:goalId", target: goalPath, body: `{"status":"Completed"}`, responses: audited(), status: http.StatusOK},
+		{name: "legacy archive goal", method: http.MethodPatch, path: "/api/updateGoalStatus", target: "/api/updateGoalStatus?goalId=" + endedGoal.ID.Hex(), body: `{"status":"Archived"}`, responses: audited(), status: http.StatusOK},
+		{name: "delete goal", method: http.MethodDelete, path: "/api/v1/goals/:goalId", target: goalPath, responses: audited(), status: http.StatusNoContent},
+		{name: "legacy delete goal", method: http.MethodDelete, path: "/api/deleteMainGoal", target: "/api/deleteMainGoal?goalId=" + goal.ID.Hex(), responses: audited(), status: http.StatusOK},
+		{name: "android delete goal", method: http.MethodDelete, path: "/api/goals", target: "/api/goals?goalId=" + goal.ID.Hex(), responses: audited(), status: http.StatusOK},
+
+		{name: "weekly goal", method: http.MethodGet, path: "/api/v1/goals/:goalId/weeks/:weeklyGoalId", target: weekPath, status: http.StatusOK},
+		{name: "create weekly goal", method: http.MethodPost, path: "/api/v1/goals/:goalId/weeks", target: goalPath + "/weeks", body: newWeek, responses: audited(), status: http.StatusCreated},
+		{name: "legacy create weekly goal", method: http.MethodPost, path: "/api/registerWeeklyGoal", target: "/api/registerWeeklyGoal?mainGoalId=" + goal.ID.Hex(), body: newWeek, responses: audited(), status: http.StatusCreated},
+		{name: "update weekly goal", method: http.MethodPatch, path: "/api/v1/goals/:goalId/weeks/:weeklyGoalId", target: weekPath, body: `{"currentWeightInKg":79.5}`, responses: audited(), status: http.StatusOK},
+		{name: "legacy update weekly goal", method: http.MethodPatch, path: "/api/updateWeeklyGoal", target: "/api/updateWeeklyGoal?goalId=" + goal.ID.Hex() + "&weeklyGoalId=" + week.ID.Hex(),
+			body: `{"targetDailyCalories":1900}`, responses: audited(), status: http.StatusOK},
+		{name: "delete weekly goal", method: http.MethodDelete, path: "/api/v1/goals/:goalId/weeks/:weeklyGoalId", target: goalPath + "/weeks/" + nextWeek.ID.Hex(), responses: audited(), status: http.StatusNoContent},
+		{name: "legacy delete weekly goal", method: http.MethodDelete, path: "/api/deleteWeeklyGoal", target: "/api/deleteWeeklyGoal?goalId=" + goal.ID.Hex() + "&weeklyGoalId=" + nextWeek.ID.Hex(),
+			responses: audited(), status: http.StatusOK},
+
+		{name: "week meal plan", method: http.MethodGet, path: "/api/v1/goals/:goalId/weeks/:weeklyGoalId/meal-plan", target: weekPath + "/meal-plan", status: http.StatusOK},
+		{name: "legacy meal plan", method: http.MethodGet, path: "/api/getMealPlan", target: "/api/getMealPlan" + goalQuery, status: http.StatusOK},
+		{name: "meal plan", method: http.MethodGet, path: "/api/v1/meal-plans/:mealPlanId", target: "/api/v1/meal-plans/" + mealPlan.ID.Hex(), status: http.StatusOK},
+		{name: "nutrition report", method: http.MethodGet, path: "/api/v1/goals/:goalId/weeks/:weeklyGoalId/nutrition-report", target: weekPath + "/nutrition-report", status: http.StatusOK},
+		{name: "legacy nutrition report", method: http.MethodGet, path: "/api/getNutritionReport", target: "/api/getNutritionReport" + goalQuery, status: http.StatusOK},
+		{name: "consume meal", method: http.MethodPatch, path: "/api/v1/meal-plans/:mealPlanId/days/:dayMealId/meals/:mealId",
+			target: "/api/v1/meal-plans/" + mealPlan.ID.Hex() + "/days/" + today.ID.Hex() + "/meals/" + breakfast.ID.Hex(), body: `{"isConsumed":true}`, responses: audited(), status: http.StatusNoContent},
+		{name: "legacy consume meal", method: http.MethodPut, path: "/api/consumeMeal", target: "/api/consumeMeal?mealId=" + breakfast.ID.Hex(), responses: audited(), status: http.StatusOK},
+
+		{name: "dashboard", method: http.MethodGet, path: "/api/getDashboard", target: "/api/getDashboard" + self, status: http.StatusOK, responses: func(mt *mtest.T) []bson.D {
+			return []bson.D{findResponse(mt, "hydrationLogs", hydrationLog), findResponse(mt, "workoutRoutines"), findResponse(mt, "workoutSessions", workoutSession), findResponse(mt, "activityLogs", activityLog)}
+		}},
+
+		{name: "hydration", method: http.MethodGet, path: "/api/getHydration", target: "/api/getHydration" + self, status: http.StatusOK, responses: func(mt *mtest.T) []bson.D {
+			return []bson.D{findResponse(mt, "hydrationLogs", hydrationLog)}
+		}},
+		{name: "log water", method: http.MethodPost, path: "/api/logWater", target: "/api/logWater", body: `{"amountInMl":250}`, responses: audited(mtest.CreateSuccessResponse()), status: http.StatusCreated},
+		{name: "quick add water", method: http.MethodPost, path: "/api/quickAddWater", target: "/api/quickAddWater" + self + "&preset=glass", responses: audited(mtest.CreateSuccessResponse()), status: http.StatusCreated},
+		{name: "delete water log", method: http.MethodDelete, path: "/api/deleteWaterLog", target: "/api/deleteWaterLog" + self + "&logId=" + hydrationLog.ID.Hex(), responses: auditedDelete("hydrationLogs", hydrationLog), status: http.StatusOK},
+
+		{name: "activity", method: http.MethodGet, path: "/api/getActivity", target: "/api/getActivity" + self, status: http.StatusOK, responses: func(mt *mtest.T) []bson.D {
+			return []bson.D{findResponse(mt, "workoutSessions", workoutSession), findResponse(mt, "activityLogs", activityLog)}
+		}},
+		{name: "log workout session", method: http.MethodPost, path: "/api/logWorkoutSession", target: "/api/logWorkoutSession",
+			body: `{"durationMinutes":45,"rpe":7,"exercises":[{"exerciseId":"push-up","sets":[{"reps":12},{"reps":10}]}]}`, responses: audited(mtest.CreateSuccessResponse()), status: http.StatusCreated},
+		{name: "log activity", method: http.MethodPost, path: "/api/logActivity", target: "/api/logActivity", body: `{"activityType":"Walking","steps":8000}`,
+			responses: audited(mtest.CreateSuccessResponse()), status: http.StatusCreated},
+		{name: "delete workout session", method: http.MethodDelete, path: "/api/deleteWorkoutSession", target: "/api/deleteWorkoutSession" + self + "&sessionId=" + workoutSession.ID.Hex(),
+			responses: auditedDelete("workoutSessions", workoutSession), status: http.StatusOK},
+		{name: "delete activity log", method: http.MethodDelete, path: "/api/deleteActivityLog", target: "/api/deleteActivityLog" + self + "&activityLogId=" + activityLog.ID.Hex(),
+			responses: auditedDelete("activityLogs", activityLog), status: http.StatusOK},
+
+		{name: "coach overview", method: http.MethodGet, path: "/api/getCoachOverview", target: "/api/getCoachOverview?sortBy=adherence", role: models.ROLE_COACH, status: http.StatusOK,
+			responses: func(mt *mtest.T) []bson.D {
+				facet := bson.D{{Key: "total", Value: bson.A{bson.D{{Key: "count", Value: 1}}}}, {Key: "clients", Value: bson.A{toDocument(mt, clientOverview)}}}
+				return []bson.D{mtest.CreateCursorResponse(0, "fiteats.coachLinks", mtest.FirstBatch, facet)}
+			}},
+		{name: "empty coach overview", method: http.MethodGet, path: "/api/getCoachOverview", target: "/api/getCoachOverview", role: models.ROLE_COACH, status: http.StatusOK,
+			responses: func(mt *mtest.T) []bson.D {
+				return []bson.D{mtest.CreateCursorResponse(0, "fiteats.coachLinks", mtest.FirstBatch)}
+			}},
+	}
+
+	runContract(t, "stored success", func(mt *mtest.T, _ *gin.Engine, document *openapi.Document) {
+		for _, c := range cases {
+			stores := seed()
+			if c.prepare != nil {
+				if err := c.prepare(ctx, stores); err != nil {
+					mt.Fatalf("%s: %v", c.name, err)
+				}
+			}
+			role := c.role
+			if role == "" {
+				role = models.ROLE_USER
+			}
+
+			router, _ := newRouter(&config.Config{}, mt.DB, stores, &utils.LogMailer{}, contractAuthMiddleware(user.ID, role))
+			expectContractResponse(mt, router, document, c)
+		}
+	})
+}
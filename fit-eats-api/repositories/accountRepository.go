@@ -0,0 +1,119 @@
+package repositories
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// UserDataCollection is a collection holding personal data, its documents belong to the user in any of the UserFields
+type UserDataCollection struct {
+	Name       string
+	UserFields []string
+	// Export adds the documents to the personal data export, without the HiddenFields
+	Export       bool
+	HiddenFields []string
+}
+
+// UserDataCollections lists every collection with personal data. A new collection storing user data must be added here
+// so that it is exported and purged with the account. The audit log is kept as the record of who changed what, the
+// account service redacts the personal data of the purged user from it.
+var UserDataCollections = []UserDataCollection{
+	{Name: "users", UserFields: []string{"_id"}}, // exported as the profile
+	{Name: "userGoals", UserFields: []string{"userId"}, Export: true},
+	{Name: "meals", UserFields: []string{"userId"}, Export: true},
+	{Name: "hydrationLogs", UserFields: []string{"userId"}, Export: true},
+	{Name: "workoutRoutines", UserFields: []string{"userId"}, Export: true},
+	{Name: "workoutSessions", UserFields: []string{"userId"}, Export: true},
+	{Name: "activityLogs", UserFields: []string{"userId"}, Export: true},
+	{Name: "coachLinks", UserFields: []string{"coachId", "clientId"}, Export: true},
+	{Name: "sessions", UserFields: []string{"userId"}, Export: true, HiddenFields: []string{"refreshTokenHash"}},
+	{Name: "userTokens", UserFields: []string{"userId"}},
+	{Name: "oidcStates", UserFields: []string{"userId"}},
+	{Name: "dataExports", UserFields: []string{"userId"}}, // the files are removed by the data export repository
+}
+
+// AccountRepository reads and removes the documents of a user across the UserDataCollections
+type AccountRepository interface {
+	// CountUserDocuments counts the exported documents of the user, to decide whether an export is generated in the background
+	CountUserDocuments(ctx context.Context, userId primitive.ObjectID) (int64, error)
+	// GetUserDocuments returns the documents of the user in the collection without its hidden fields
+	GetUserDocuments(ctx context.Context, collection UserDataCollection, userId primitive.ObjectID) ([]bson.M, error)
+	// DeleteUserData removes the documents of the user from every registered collection, the user document goes last
+	// so that an interrupted purge is picked up again by the next run
+	DeleteUserData(ctx context.Context, userId primitive.ObjectID) error
+}
+
+type MongoAccountRepository struct {
+	Database *mongo.Database
+}
+
+func NewMongoAccountRepository(db *mongo.Database) *MongoAccountRepository {
+	return &MongoAccountRepository{
+		Database: db,
+	}
+}
+
+func userDataFilter(collection UserDataCollection, userId primitive.ObjectID) bson.M {
+	conditions := bson.A{}
+	for _, field := range collection.UserFields {
+		conditions = append(conditions, bson.M{field: userId})
+	}
+	return bson.M{"$or": conditions}
+}
+
+func (r *MongoAccountRepository) CountUserDocuments(ctx context.Context, userId primitive.ObjectID) (int64, error) {
+	var total int64
+	for _, collection := range UserDataCollections {
+		if !collection.Export {
+			continue
+		}
+		count, err := r.Database.Collection(collection.Name).CountDocuments(ctx, userDataFilter(collection, userId))
+		if err != nil {
+			return 0, err
+		}
+		total += count
+	}
+	return total, nil
+}
+
+func userDocumentsOptions(collection UserDataCollection) *options.FindOptions {
+	opts := options.Find().SetSort(bson.M{"_id": 1})
+	if len(collection.HiddenFields) > 0 {
+		projection := bson.M{}
+		for _, field := range collection.HiddenFields {
+			projection[field] = 0
+		}
+		opts.SetProjection(projection)
+	}
+	return opts
+}
+
+func (r *MongoAccountRepository) GetUserDocuments(ctx context.Context, collection UserDataCollection, userId primitive.ObjectID) ([]bson.M, error) {
+	opts := userDocumentsOptions(collection)
+	cursor, err := r.Database.Collection(collection.Name).Find(ctx, userDataFilter(collection, userId), opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	documents := []bson.M{}
+	if err := cursor.All(ctx, &documents); err != nil {
+		return nil, err
+	}
+	return documents, nil
+}
+
+func (r *MongoAccountRepository) DeleteUserData(ctx context.Context, userId primitive.ObjectID) error {
+	for i := len(UserDataCollections) - 1; i >= 0; i-- {
+		collection := UserDataCollections[i]
+		_, err := r.Database.Collection(collection.Name).DeleteMany(ctx, userDataFilter(collection, userId))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package repositories
+
+import (
+	"context"
+	"fit-eats-api/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type ActivityRepository struct {
+	WorkoutSessionCollection *mongo.Collection
+	ActivityLogCollection    *mongo.Collection
+}
+
+func NewActivityRepository(db *mongo.Database) *ActivityRepository {
+	return &ActivityRepository{
+		WorkoutSessionCollection: db.Collection("workoutSessions"),
+		ActivityLogCollection:    db.Collection("activityLogs"),
+	}
+}
+
+func (r *ActivityRepository) CreateWorkoutSession(ctx context.Context, session *models.WorkoutSession) error {
+	session.ID = primitive.NewObjectID()
+	_, err := r.WorkoutSessionCollection.InsertOne(ctx, session)
+	return err
+}
+
+func (r *ActivityRepository) CreateActivityLog(ctx context.Context, activityLog *models.ActivityLog) error {
+	activityLog.ID = primitive.NewObjectID()
+	_, err := r.ActivityLogCollection.InsertOne(ctx, activityLog)
+	return err
+}
+
+func (r *ActivityRepository) GetWorkoutSessions(ctx context.Context, userId primitive.ObjectID, from time.Time, to time.Time) ([]models.WorkoutSession, error) {
+	filter := bson.M{"userId": userId, "date": bson.M{"$gte": from, "$lt": to}}
+
+	cursor, err := r.WorkoutSessionCollection.Find(ctx, filter, options.Find().SetSort(bson.M{"date": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	sessions := []models.WorkoutSession{}
+	if err := cursor.All(ctx, &sessions); err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+func (r *ActivityRepository) GetActivityLogs(ctx context.Context, userId primitive.ObjectID, from time.Time, to time.Time) ([]models.ActivityLog, error) {
+	filter := bson.M{"userId": userId, "date": bson.M{"$gte": from, "$lt": to}}
+
+	cursor, err := r.ActivityLogCollection.Find(ctx, filter, options.Find().SetSort(bson.M{"date": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	activityLogs := []models.ActivityLog{}
+	if err := cursor.All(ctx, &activityLogs); err != nil {
+		return nil, err
+	}
+	return activityLogs, nil
+}
+
+// GetWorkoutSession returns the session of the user, mongo.ErrNoDocuments when the user has no such session
+func (r *ActivityRepository) GetWorkoutSession(ctx context.Context, userId primitive.ObjectID, sessionId primitive.ObjectID) (*models.WorkoutSession, error) {
+	var session models.WorkoutSession
+	if err := r.WorkoutSessionCollection.FindOne(ctx, bson.M{"_id": sessionId, "userId": userId}).Decode(&session); err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// GetActivityLog returns the log of the user, mongo.ErrNoDocuments when the user has no such log
+func (r *ActivityRepository) GetActivityLog(ctx context.Context, userId primitive.ObjectID, activityLogId primitive.ObjectID) (*models.ActivityLog, error) {
+	var activityLog models.ActivityLog
+	if err := r.ActivityLogCollection.FindOne(ctx, bson.M{"_id": activityLogId, "userId": userId}).Decode(&activityLog); err != nil {
+		return nil, err
+	}
+	return &activityLog, nil
+}
+
+func (r *ActivityRepository) DeleteWorkoutSession(ctx context.Context, userId primitive.ObjectID, sessionId primitive.ObjectID) error {
+	result, err := r.WorkoutSessionCollection.DeleteOne(ctx, bson.M{"_id": sessionId, "userId": userId})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+func (r *ActivityRepository) DeleteActivityLog(ctx context.Context, userId primitive.ObjectID, activityLogId primitive.ObjectID) error {
+	result, err := r.ActivityLogCollection.DeleteOne(ctx, bson.M{"_id": activityLogId, "userId": userId})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
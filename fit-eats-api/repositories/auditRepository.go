@@ -0,0 +1,104 @@
+package repositories
+
+import (
+	"context"
+	"fit-eats-api/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// AuditRepository stores the audit trail, entries are only ever inserted and the repository has no way to remove them.
+// The only change made to an entry is the redaction of the personal data of a purged account
+type AuditRepository interface {
+	CreateAuditLog(ctx context.Context, auditLog *models.AuditLog) error
+	// RedactUserAuditLogs removes the personal data of a purged user from the audit trail. The entries about the user lose
+	// the snapshots of their data, the request details and the ip, the entries the user acted in lose their email and ip
+	RedactUserAuditLogs(ctx context.Context, userId primitive.ObjectID, redactedAt time.Time) error
+	// GetAuditLogs returns a page of the matching audit logs, newest first, with the number of matches
+	GetAuditLogs(ctx context.Context, auditLogFilter models.AuditLogFilter, skip int64, limit int64) ([]models.AuditLog, int64, error)
+}
+
+type MongoAuditRepository struct {
+	Collection *mongo.Collection
+}
+
+func NewMongoAuditRepository(db *mongo.Database) *MongoAuditRepository {
+	return &MongoAuditRepository{
+		Collection: db.Collection("auditLogs"),
+	}
+}
+
+func (r *MongoAuditRepository) CreateAuditLog(ctx context.Context, auditLog *models.AuditLog) error {
+	auditLog.ID = primitive.NewObjectID()
+	if auditLog.CreatedAt.IsZero() {
+		auditLog.CreatedAt = time.Now()
+	}
+	_, err := r.Collection.InsertOne(ctx, auditLog)
+	return err
+}
+
+// auditLogRedactions updates the entries about a purged user, then the entries the user acted in
+func auditLogRedactions(userId primitive.ObjectID, redactedAt time.Time) []struct{ filter, update bson.M } {
+	return []struct{ filter, update bson.M }{
+		{bson.M{"userId": userId}, bson.M{"$unset": bson.M{"changes": "", "details": "", "ip": ""}, "$set": bson.M{"redactedAt": redactedAt}}},
+		{bson.M{"actor.id": userId}, bson.M{"$unset": bson.M{"ip": ""}, "$set": bson.M{"actor.name": "", "redactedAt": redactedAt}}},
+	}
+}
+
+func (r *MongoAuditRepository) RedactUserAuditLogs(ctx context.Context, userId primitive.ObjectID, redactedAt time.Time) error {
+	for _, redaction := range auditLogRedactions(userId, redactedAt) {
+		if _, err := r.Collection.UpdateMany(ctx, redaction.filter, redaction.update); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func auditLogQuery(auditLogFilter models.AuditLogFilter) bson.M {
+	filter := bson.M{}
+	if !auditLogFilter.UserId.IsZero() {
+		filter["userId"] = auditLogFilter.UserId
+	}
+	if !auditLogFilter.ActorId.IsZero() {
+		filter["actor.id"] = auditLogFilter.ActorId
+	}
+	if auditLogFilter.Action != "" {
+		filter["action"] = auditLogFilter.Action
+	}
+	createdAt := bson.M{}
+	if !auditLogFilter.From.IsZero() {
+		createdAt["$gte"] = auditLogFilter.From
+	}
+	if !auditLogFilter.To.IsZero() {
+		createdAt["$lt"] = auditLogFilter.To
+	}
+	if len(createdAt) > 0 {
+		filter["createdAt"] = createdAt
+	}
+	return filter
+}
+
+func (r *MongoAuditRepository) GetAuditLogs(ctx context.Context, auditLogFilter models.AuditLogFilter, skip int64, limit int64) ([]models.AuditLog, int64, error) {
+	filter := auditLogQuery(auditLogFilter)
+	total, err := r.Collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}, {Key: "_id", Value: -1}}).SetSkip(skip).SetLimit(limit)
+	cursor, err := r.Collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	auditLogs := []models.AuditLog{}
+	if err := cursor.All(ctx, &auditLogs); err != nil {
+		return nil, 0, err
+	}
+	return auditLogs, total, nil
+}
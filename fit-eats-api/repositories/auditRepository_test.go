@@ -0,0 +1,74 @@
+package repositories
+
+import (
+	"context"
+	"fit-eats-api/models"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestAuditRepositoryGetAuditLogs(t *testing.T) {
+	runRepositoryTest(t, func(t *testing.T, stores repositoryStores) {
+		ctx := context.Background()
+		userId := primitive.NewObjectID()
+		coachId := primitive.NewObjectID()
+		start := time.Now().Truncate(time.Millisecond)
+
+		for i, actorId := range []primitive.ObjectID{userId, coachId, userId} {
+			expectNoError(t, stores.audit.CreateAuditLog(ctx, &models.AuditLog{UserId: userId, Actor: &models.AuditActor{ID: actorId},
+				Action: models.AUDIT_PROFILE_UPDATED, CreatedAt: start.Add(time.Duration(i) * time.Hour)}))
+		}
+		expectNoError(t, stores.audit.CreateAuditLog(ctx, &models.AuditLog{UserId: coachId, Actor: &models.AuditActor{ID: coachId}, Action: models.AUDIT_GOAL_CREATED, CreatedAt: start}))
+
+		auditLogs, total, err := stores.audit.GetAuditLogs(ctx, models.AuditLogFilter{UserId: userId}, 1, 1)
+		expectNoError(t, err)
+		if total != 3 || len(auditLogs) != 1 || !auditLogs[0].CreatedAt.Equal(toStoredTime(start.Add(time.Hour))) {
+			t.Fatalf("expected the second newest of 3 entries, got %d %+v", total, auditLogs)
+		}
+
+		auditLogs, total, err = stores.audit.GetAuditLogs(ctx, models.AuditLogFilter{ActorId: coachId, From: start.Add(time.Minute)}, 0, 10)
+		expectNoError(t, err)
+		if total != 1 || auditLogs[0].UserId != userId {
+			t.Fatalf("expected the change of the coach to the user, got %d %+v", total, auditLogs)
+		}
+	})
+}
+
+func TestAuditRepositoryRedactUserAuditLogs(t *testing.T) {
+	runRepositoryTest(t, func(t *testing.T, stores repositoryStores) {
+		ctx := context.Background()
+		userId := primitive.NewObjectID()
+		otherId := primitive.NewObjectID()
+		redactedAt := time.Now().Truncate(time.Millisecond)
+
+		about := &models.AuditLog{UserId: userId, Actor: &models.AuditActor{ID: userId, Name: "asha@fiteats.test"}, Action: models.AUDIT_PROFILE_UPDATED,
+			IP: "203.0.113.7", Details: map[string]any{"path": "/api/updateUser"}, Changes: []models.AuditChange{{Path: "name", Before: "A", After: "Asha"}}}
+		actedIn := &models.AuditLog{UserId: otherId, Actor: &models.AuditActor{ID: userId, Name: "asha@fiteats.test"}, Action: models.AUDIT_GOAL_CREATED,
+			IP: "203.0.113.7", Changes: []models.AuditChange{{Path: "goalType", After: "Fat loss"}}}
+		unrelated := &models.AuditLog{UserId: otherId, Actor: &models.AuditActor{ID: otherId, Name: "ravi@fiteats.test"}, Action: models.AUDIT_GOAL_CREATED, IP: "198.51.100.2"}
+		for _, auditLog := range []*models.AuditLog{about, actedIn, unrelated} {
+			expectNoError(t, stores.audit.CreateAuditLog(ctx, auditLog))
+		}
+
+		expectNoError(t, stores.audit.RedactUserAuditLogs(ctx, userId, redactedAt))
+
+		auditLogs, _, err := stores.audit.GetAuditLogs(ctx, models.AuditLogFilter{}, 0, 10)
+		expectNoError(t, err)
+		redacted := map[primitive.ObjectID]models.AuditLog{}
+		for _, auditLog := range auditLogs {
+			redacted[auditLog.ID] = auditLog
+		}
+
+		if entry := redacted[about.ID]; entry.RedactedAt == nil || entry.Changes != nil || entry.Details != nil || entry.IP != "" || entry.Actor.Name != "" {
+			t.Errorf("expected the entry about the user to lose its data, got %+v", entry)
+		}
+		if entry := redacted[actedIn.ID]; entry.RedactedAt == nil || len(entry.Changes) != 1 || entry.IP != "" || entry.Actor.Name != "" || entry.Actor.ID != userId {
+			t.Errorf("expected the entry the user acted in to only lose their email and ip, got %+v", entry)
+		}
+		if entry := redacted[unrelated.ID]; entry.RedactedAt != nil || entry.IP == "" || entry.Actor.Name == "" {
+			t.Errorf("expected the other entries to be kept, got %+v", entry)
+		}
+	})
+}
@@ -0,0 +1,241 @@
+package repositories
+
+import (
+	"context"
+	"fit-eats-api/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type CoachLinkRepository struct {
+	Collection *mongo.Collection
+}
+
+func NewCoachLinkRepository(db *mongo.Database) *CoachLinkRepository {
+	return &CoachLinkRepository{
+		Collection: db.Collection("coachLinks"),
+	}
+}
+
+func (r *CoachLinkRepository) CreateCoachLink(ctx context.Context, coachLink *models.CoachLink) error {
+	coachLink.ID = primitive.NewObjectID()
+	_, err := r.Collection.InsertOne(ctx, coachLink)
+	return err
+}
+
+// GetOpenCoachLink returns the pending or active link between the coach and the client, nil when there is none
+func (r *CoachLinkRepository) GetOpenCoachLink(ctx context.Context, coachId primitive.ObjectID, clientId primitive.ObjectID) (*models.CoachLink, error) {
+	filter := bson.M{"coachId": coachId, "clientId": clientId, "status": bson.M{"$in": []models.CoachLinkStatus{models.COACH_LINK_PENDING, models.COACH_LINK_ACTIVE}}}
+
+	var coachLink models.CoachLink
+	err := r.Collection.FindOne(ctx, filter).Decode(&coachLink)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &coachLink, nil
+}
+
+func (r *CoachLinkRepository) IsActiveCoach(ctx context.Context, coachId primitive.ObjectID, clientId primitive.ObjectID) bool {
+	filter := bson.M{"coachId": coachId, "clientId": clientId, "status": models.COACH_LINK_ACTIVE}
+	count, err := r.Collection.CountDocuments(ctx, filter)
+	return err == nil && count > 0
+}
+
+func (r *CoachLinkRepository) GetCoachLinksByCoachId(ctx context.Context, coachId primitive.ObjectID, statuses []models.CoachLinkStatus) ([]models.CoachLink, error) {
+	return r.findCoachLinks(ctx, bson.M{"coachId": coachId, "status": bson.M{"$in": statuses}})
+}
+
+func (r *CoachLinkRepository) GetCoachLinksByClientId(ctx context.Context, clientId primitive.ObjectID, statuses []models.CoachLinkStatus) ([]models.CoachLink, error) {
+	return r.findCoachLinks(ctx, bson.M{"clientId": clientId, "status": bson.M{"$in": statuses}})
+}
+
+func (r *CoachLinkRepository) findCoachLinks(ctx context.Context, filter bson.M) ([]models.CoachLink, error) {
+	cursor, err := r.Collection.Find(ctx, filter, options.Find().SetSort(bson.M{"invitedAt": -1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	coachLinks := []models.CoachLink{}
+	if err := cursor.All(ctx, &coachLinks); err != nil {
+		return nil, err
+	}
+	return coachLinks, nil
+}
+
+// notInvitedBy matches links the user did not send, invitations from before clients could invite were sent by the coach
+func notInvitedBy(userId primitive.ObjectID) bson.M {
+	return bson.M{"$ne": bson.A{bson.M{"$ifNull": bson.A{"$invitedBy", "$coachId"}}, userId}}
+}
+
+// GetReceivedInvitations returns the pending invitations sent to the user by coaches or clients
+func (r *CoachLinkRepository) GetReceivedInvitations(ctx context.Context, userId primitive.ObjectID) ([]models.CoachLink, error) {
+	return r.findCoachLinks(ctx, bson.M{
+		"$or":    bson.A{bson.M{"coachId": userId}, bson.M{"clientId": userId}},
+		"$expr":  notInvitedBy(userId),
+		"status": models.COACH_LINK_PENDING,
+	})
+}
+
+// RespondToInvitation accepts or declines a pending invitation, only the side that did not send it can respond
+func (r *CoachLinkRepository) RespondToInvitation(ctx context.Context, coachLinkId primitive.ObjectID, userId primitive.ObjectID, status models.CoachLinkStatus) error {
+	filter := bson.M{
+		"_id":    coachLinkId,
+		"$or":    bson.A{bson.M{"coachId": userId}, bson.M{"clientId": userId}},
+		"$expr":  notInvitedBy(userId),
+		"status": models.COACH_LINK_PENDING,
+	}
+	update := bson.M{"$set": bson.M{"status": status, "respondedAt": time.Now()}}
+
+	result, err := r.Collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// EndCoachLink ends a pending or active link, either the coach or the client can end it
+func (r *CoachLinkRepository) EndCoachLink(ctx context.Context, coachLinkId primitive.ObjectID, userId primitive.ObjectID) error {
+	filter := bson.M{
+		"_id":    coachLinkId,
+		"$or":    bson.A{bson.M{"coachId": userId}, bson.M{"clientId": userId}},
+		"status": bson.M{"$in": []models.CoachLinkStatus{models.COACH_LINK_PENDING, models.COACH_LINK_ACTIVE}},
+	}
+	update := bson.M{"$set": bson.M{"status": models.COACH_LINK_ENDED, "endedAt": time.Now()}}
+
+	result, err := r.Collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// GetClientOverviews reads the active clients of the coach with their goal, latest weekly goals and the day meals since the given time.
+// Adherence, distance to the target weight and the last weekly goal are computed here so that sorting and paging happen in the database.
+func (r *CoachLinkRepository) GetClientOverviews(ctx context.Context, coachId primitive.ObjectID, since time.Time, until time.Time,
+	sortField string, sortOrder int, skip int, limit int) ([]models.ClientOverviewRecord, int, error) {
+	mealTotal := func(field string, consumedOnly bool) bson.M {
+		meals := bson.M{"$ifNull": bson.A{"$$dayMeal.meals", bson.A{}}}
+		if consumedOnly {
+			meals = bson.M{"$filter": bson.M{"input": meals, "as": "meal", "cond": bson.M{"$eq": bson.A{"$$meal.isConsumed", true}}}}
+		}
+		return bson.M{"$sum": bson.M{"$map": bson.M{
+			"input": "$recentDayMeals",
+			"as":    "dayMeal",
+			"in":    bson.M{"$sum": bson.M{"$map": bson.M{"input": meals, "as": "meal", "in": "$$meal." + field}}},
+		}}}
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"coachId": coachId, "status": models.COACH_LINK_ACTIVE}}},
+		{{Key: "$lookup", Value: bson.M{"from": "users", "localField": "clientId", "foreignField": "_id", "as": "client"}}},
+		{{Key: "$unwind", Value: "$client"}}, // links to deleted users are skipped
+		{{Key: "$lookup", Value: bson.M{
+			"from": "userGoals",
+			"let":  bson.M{"clientId": "$clientId"},
+			"pipeline": bson.A{
+				bson.M{"$match": bson.M{"status": models.GOAL_ACTIVE, "$expr": bson.M{"$eq": bson.A{"$userId", "$$clientId"}}}},
+			},
+			"as": "goal",
+		}}},
+		{{Key: "$addFields", Value: bson.M{"goal": bson.M{"$arrayElemAt": bson.A{"$goal", 0}}}}},
+		// Weekly goals are pushed in order, so the last one is the latest
+		{{Key: "$addFields", Value: bson.M{
+			"latestWeeklyGoal":   bson.M{"$arrayElemAt": bson.A{bson.M{"$ifNull": bson.A{"$goal.weeklyGoals", bson.A{}}}, -1}},
+			"previousWeeklyGoal": bson.M{"$arrayElemAt": bson.A{bson.M{"$ifNull": bson.A{"$goal.weeklyGoals", bson.A{}}}, -2}},
+		}}},
+		{{Key: "$lookup", Value: bson.M{
+			"from": "meals",
+			"let":  bson.M{"clientId": "$clientId"},
+			"pipeline": bson.A{
+				bson.M{"$match": bson.M{"$expr": bson.M{"$eq": bson.A{"$userId", "$$clientId"}}}},
+				bson.M{"$unwind": "$dayMeals"},
+				bson.M{"$match": bson.M{"dayMeals.date": bson.M{"$gte": since, "$lt": until}}},
+				bson.M{"$project": bson.M{
+					"_id":  "$dayMeals._id",
+					"date": "$dayMeals.date",
+					"meals": bson.M{"$map": bson.M{"input": "$dayMeals.meals", "as": "meal", "in": bson.M{
+						"calories": "$$meal.calories", "protein": "$$meal.protein", "carbs": "$$meal.carbs", "fat": "$$meal.fat", "isConsumed": "$$meal.isConsumed",
+					}}},
+				}},
+				bson.M{"$sort": bson.M{"date": 1}},
+			},
+			"as": "recentDayMeals",
+		}}},
+		{{Key: "$lookup", Value: bson.M{
+			"from": "meals",
+			"let":  bson.M{"weeklyGoalId": "$latestWeeklyGoal._id"},
+			"pipeline": bson.A{
+				bson.M{"$match": bson.M{"$expr": bson.M{"$eq": bson.A{"$weeklyGoalId", "$$weeklyGoalId"}}}},
+				bson.M{"$project": bson.M{"_id": 1}},
+				bson.M{"$limit": 1},
+			},
+			"as": "currentMealPlan",
+		}}},
+		{{Key: "$addFields", Value: bson.M{
+			"name":               "$client.name",
+			"hasCurrentMealPlan": bson.M{"$gt": bson.A{bson.M{"$size": "$currentMealPlan"}, 0}},
+			"weightToGoal":       bson.M{"$abs": bson.M{"$subtract": bson.A{"$latestWeeklyGoal.currentWeightInKg", "$goal.targetWeightInKg"}}},
+			// The weekly goal id is generated when the weekly goal is created
+			"lastWeeklyGoalAt": bson.M{"$toDate": "$latestWeeklyGoal._id"},
+			"plannedCalories":  mealTotal("calories", false),
+			"consumedCalories": mealTotal("calories", true),
+		}}},
+		{{Key: "$addFields", Value: bson.M{
+			"adherence": bson.M{"$cond": bson.A{
+				bson.M{"$gt": bson.A{"$plannedCalories", 0}},
+				bson.M{"$divide": bson.A{"$consumedCalories", "$plannedCalories"}},
+				nil,
+			}},
+		}}},
+		{{Key: "$project", Value: bson.M{
+			"client": bson.M{"_id": "$client._id", "name": "$client.name", "email": "$client.email"},
+			"goal": bson.M{"$cond": bson.A{bson.M{"$ifNull": bson.A{"$goal", false}}, bson.M{
+				"_id": "$goal._id", "goalType": "$goal.goalType", "targetWeightInKg": "$goal.targetWeightInKg", "startWeightInKg": "$goal.startWeightInKg",
+			}, nil}},
+			"latestWeeklyGoal": 1, "previousWeeklyGoal": 1, "recentDayMeals": 1, "hasCurrentMealPlan": 1,
+			"name": 1, "weightToGoal": 1, "lastWeeklyGoalAt": 1, "adherence": 1,
+		}}},
+		{{Key: "$facet", Value: bson.M{
+			"total": bson.A{bson.M{"$count": "count"}},
+			"clients": bson.A{
+				bson.M{"$sort": bson.D{{Key: sortField, Value: sortOrder}, {Key: "_id", Value: 1}}},
+				bson.M{"$skip": skip},
+				bson.M{"$limit": limit},
+			},
+		}}},
+	}
+
+	cursor, err := r.Collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var result []struct {
+		Total []struct {
+			Count int `bson:"count"`
+		} `bson:"total"`
+		Clients []models.ClientOverviewRecord `bson:"clients"`
+	}
+	if err := cursor.All(ctx, &result); err != nil {
+		return nil, 0, err
+	}
+	if len(result) == 0 || len(result[0].Total) == 0 {
+		return []models.ClientOverviewRecord{}, 0, nil
+	}
+	return result[0].Clients, result[0].Total[0].Count, nil
+}
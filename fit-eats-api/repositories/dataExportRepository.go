@@ -0,0 +1,161 @@
+package repositories
+
+import (
+	"context"
+	"fit-eats-api/models"
+	"io"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/gridfs"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// DataExportRepository keeps the export jobs and their zip files
+type DataExportRepository interface {
+	CreateDataExport(ctx context.Context, dataExport *models.DataExport) error
+	// GetDataExport returns nil when the user has no export with the id
+	GetDataExport(ctx context.Context, userId primitive.ObjectID, dataExportId primitive.ObjectID) (*models.DataExport, error)
+	// GetPendingDataExport returns the export of the user still being generated, nil when there is none
+	GetPendingDataExport(ctx context.Context, userId primitive.ObjectID) (*models.DataExport, error)
+	// SaveDataExportFile stores what write writes as the zip file of the export and returns the file and its size,
+	// nothing is kept when write fails
+	SaveDataExportFile(ctx context.Context, dataExportId primitive.ObjectID, write func(w io.Writer) error) (primitive.ObjectID, int64, error)
+	CompleteDataExport(ctx context.Context, dataExportId primitive.ObjectID, fileId primitive.ObjectID, size int64) error
+	FailDataExport(ctx context.Context, dataExportId primitive.ObjectID, message string) error
+	DownloadFile(ctx context.Context, fileId primitive.ObjectID, w io.Writer) error
+	// DeleteUserDataExports and DeleteExpiredDataExports remove the exports with their files
+	DeleteUserDataExports(ctx context.Context, userId primitive.ObjectID) error
+	DeleteExpiredDataExports(ctx context.Context, now time.Time) (int, error)
+}
+
+// MongoDataExportRepository stores the zip files in GridFS so every server can serve them
+type MongoDataExportRepository struct {
+	Collection *mongo.Collection
+	Bucket     *gridfs.Bucket
+}
+
+func NewMongoDataExportRepository(db *mongo.Database) (*MongoDataExportRepository, error) {
+	bucket, err := gridfs.NewBucket(db, options.GridFSBucket().SetName("dataExportFiles"))
+	if err != nil {
+		return nil, err
+	}
+	return &MongoDataExportRepository{
+		Collection: db.Collection("dataExports"),
+		Bucket:     bucket,
+	}, nil
+}
+
+func (r *MongoDataExportRepository) CreateDataExport(ctx context.Context, dataExport *models.DataExport) error {
+	dataExport.ID = primitive.NewObjectID()
+	_, err := r.Collection.InsertOne(ctx, dataExport)
+	return err
+}
+
+// GetDataExport returns nil when the user has no export with the id
+func (r *MongoDataExportRepository) GetDataExport(ctx context.Context, userId primitive.ObjectID, dataExportId primitive.ObjectID) (*models.DataExport, error) {
+	var dataExport models.DataExport
+	err := r.Collection.FindOne(ctx, bson.M{"_id": dataExportId, "userId": userId}).Decode(&dataExport)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &dataExport, nil
+}
+
+// GetPendingDataExport returns the export of the user still being generated, nil when there is none
+func (r *MongoDataExportRepository) GetPendingDataExport(ctx context.Context, userId primitive.ObjectID) (*models.DataExport, error) {
+	var dataExport models.DataExport
+	filter := bson.M{"userId": userId, "status": models.DATA_EXPORT_PENDING, "createdAt": bson.M{"$gt": time.Now().Add(-models.DATA_EXPORT_TIMEOUT)}}
+	err := r.Collection.FindOne(ctx, filter).Decode(&dataExport)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &dataExport, nil
+}
+
+func (r *MongoDataExportRepository) SaveDataExportFile(ctx context.Context, dataExportId primitive.ObjectID, write func(w io.Writer) error) (primitive.ObjectID, int64, error) {
+	uploadStream, err := r.Bucket.OpenUploadStream("export-" + dataExportId.Hex() + ".zip")
+	if err != nil {
+		return primitive.NilObjectID, 0, err
+	}
+	counter := &countingWriter{writer: uploadStream}
+	if err := write(counter); err != nil {
+		uploadStream.Abort()
+		return primitive.NilObjectID, 0, err
+	}
+	if err := uploadStream.Close(); err != nil {
+		return primitive.NilObjectID, 0, err
+	}
+
+	fileId, _ := uploadStream.FileID.(primitive.ObjectID)
+	return fileId, counter.size, nil
+}
+
+func (r *MongoDataExportRepository) CompleteDataExport(ctx context.Context, dataExportId primitive.ObjectID, fileId primitive.ObjectID, size int64) error {
+	update := bson.M{"$set": bson.M{"status": models.DATA_EXPORT_READY, "fileId": fileId, "size": size, "completedAt": time.Now()}}
+	_, err := r.Collection.UpdateOne(ctx, bson.M{"_id": dataExportId}, update)
+	return err
+}
+
+func (r *MongoDataExportRepository) FailDataExport(ctx context.Context, dataExportId primitive.ObjectID, message string) error {
+	update := bson.M{"$set": bson.M{"status": models.DATA_EXPORT_FAILED, "error": message, "completedAt": time.Now()}}
+	_, err := r.Collection.UpdateOne(ctx, bson.M{"_id": dataExportId}, update)
+	return err
+}
+
+func (r *MongoDataExportRepository) DownloadFile(ctx context.Context, fileId primitive.ObjectID, w io.Writer) error {
+	_, err := r.Bucket.DownloadToStream(fileId, w)
+	return err
+}
+
+// DeleteDataExports removes the matching exports with their files and returns how many
+func (r *MongoDataExportRepository) DeleteDataExports(ctx context.Context, filter bson.M) (int, error) {
+	cursor, err := r.Collection.Find(ctx, filter, options.Find().SetProjection(bson.M{"fileId": 1}))
+	if err != nil {
+		return 0, err
+	}
+	var dataExports []models.DataExport
+	if err := cursor.All(ctx, &dataExports); err != nil {
+		return 0, err
+	}
+
+	for _, dataExport := range dataExports {
+		if !dataExport.FileId.IsZero() {
+			if err := r.Bucket.DeleteContext(ctx, dataExport.FileId); err != nil && err != gridfs.ErrFileNotFound {
+				return 0, err
+			}
+		}
+		if _, err := r.Collection.DeleteOne(ctx, bson.M{"_id": dataExport.ID}); err != nil {
+			return 0, err
+		}
+	}
+	return len(dataExports), nil
+}
+
+func (r *MongoDataExportRepository) DeleteUserDataExports(ctx context.Context, userId primitive.ObjectID) error {
+	_, err := r.DeleteDataExports(ctx, bson.M{"userId": userId})
+	return err
+}
+
+func (r *MongoDataExportRepository) DeleteExpiredDataExports(ctx context.Context, now time.Time) (int, error) {
+	return r.DeleteDataExports(ctx, bson.M{"expiresAt": bson.M{"$lt": now}})
+}
+
+type countingWriter struct {
+	writer io.Writer
+	size   int64
+}
+
+func (w *countingWriter) Write(data []byte) (int, error) {
+	n, err := w.writer.Write(data)
+	w.size += int64(n)
+	return n, err
+}
@@ -0,0 +1,94 @@
+package repositories
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fit-eats-api/models"
+	"io"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func createTestDataExport(t *testing.T, dataExports DataExportRepository, userId primitive.ObjectID, expiresAt time.Time, content string) (*models.DataExport, primitive.ObjectID) {
+	t.Helper()
+	ctx := context.Background()
+	dataExport := &models.DataExport{UserId: userId, Status: models.DATA_EXPORT_PENDING, CreatedAt: time.Now(), ExpiresAt: expiresAt}
+	expectNoError(t, dataExports.CreateDataExport(ctx, dataExport))
+
+	fileId, size, err := dataExports.SaveDataExportFile(ctx, dataExport.ID, func(w io.Writer) error {
+		_, err := io.WriteString(w, content)
+		return err
+	})
+	expectNoError(t, err)
+	if size != int64(len(content)) {
+		t.Fatalf("expected a file of %d bytes, got %d", len(content), size)
+	}
+	expectNoError(t, dataExports.CompleteDataExport(ctx, dataExport.ID, fileId, size))
+	return dataExport, fileId
+}
+
+func TestDataExportRepositoryFiles(t *testing.T) {
+	runRepositoryTest(t, func(t *testing.T, stores repositoryStores) {
+		ctx := context.Background()
+		userId := primitive.NewObjectID()
+		now := time.Now()
+
+		dataExport, fileId := createTestDataExport(t, stores.dataExports, userId, now.Add(time.Hour), "zip of the user")
+		stored, err := stores.dataExports.GetDataExport(ctx, userId, dataExport.ID)
+		expectNoError(t, err)
+		if stored == nil || stored.Status != models.DATA_EXPORT_READY || stored.FileId != fileId || stored.CompletedAt == nil {
+			t.Fatalf("expected the export to be ready with its file, got %+v", stored)
+		}
+		if other, err := stores.dataExports.GetDataExport(ctx, primitive.NewObjectID(), dataExport.ID); err != nil || other != nil {
+			t.Fatalf("expected no export for another user, got %+v %v", other, err)
+		}
+
+		var file bytes.Buffer
+		expectNoError(t, stores.dataExports.DownloadFile(ctx, fileId, &file))
+		if file.String() != "zip of the user" {
+			t.Fatalf("expected the saved file, got %q", file.String())
+		}
+
+		// A failed write keeps no file
+		writeErr := errors.New("zip failed")
+		if _, _, err := stores.dataExports.SaveDataExportFile(ctx, dataExport.ID, func(w io.Writer) error { return writeErr }); !errors.Is(err, writeErr) {
+			t.Fatalf("expected the write error, got %v", err)
+		}
+	})
+}
+
+func TestDataExportRepositoryDeleteDataExports(t *testing.T) {
+	runRepositoryTest(t, func(t *testing.T, stores repositoryStores) {
+		ctx := context.Background()
+		userId := primitive.NewObjectID()
+		otherId := primitive.NewObjectID()
+		now := time.Now()
+
+		_, userFileId := createTestDataExport(t, stores.dataExports, userId, now.Add(time.Hour), "user")
+		expired, expiredFileId := createTestDataExport(t, stores.dataExports, otherId, now.Add(-time.Hour), "expired")
+		kept, keptFileId := createTestDataExport(t, stores.dataExports, otherId, now.Add(time.Hour), "kept")
+
+		expectNoError(t, stores.dataExports.DeleteUserDataExports(ctx, userId))
+		deleted, err := stores.dataExports.DeleteExpiredDataExports(ctx, now)
+		expectNoError(t, err)
+		if deleted != 1 {
+			t.Fatalf("expected the expired export to be deleted, got %d", deleted)
+		}
+
+		for _, fileId := range []primitive.ObjectID{userFileId, expiredFileId} {
+			if err := stores.dataExports.DownloadFile(ctx, fileId, io.Discard); err == nil {
+				t.Fatalf("expected the file %s to be deleted", fileId.Hex())
+			}
+		}
+		if dataExport, err := stores.dataExports.GetDataExport(ctx, otherId, expired.ID); err != nil || dataExport != nil {
+			t.Fatalf("expected the expired export to be deleted, got %+v %v", dataExport, err)
+		}
+		if dataExport, err := stores.dataExports.GetDataExport(ctx, otherId, kept.ID); err != nil || dataExport == nil {
+			t.Fatalf("expected the other export to be kept, got %v", err)
+		}
+		expectNoError(t, stores.dataExports.DownloadFile(ctx, keptFileId, io.Discard))
+	})
+}
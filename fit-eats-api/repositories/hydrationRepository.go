@@ -0,0 +1,70 @@
+package repositories
+
+import (
+	"context"
+	"fit-eats-api/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type HydrationRepository struct {
+	Collection *mongo.Collection
+}
+
+func NewHydrationRepository(db *mongo.Database) *HydrationRepository {
+	return &HydrationRepository{
+		Collection: db.Collection("hydrationLogs"),
+	}
+}
+
+func (r *HydrationRepository) CreateHydrationLog(ctx context.Context, hydrationLog *models.HydrationLog) error {
+	hydrationLog.ID = primitive.NewObjectID()
+	_, err := r.Collection.InsertOne(ctx, hydrationLog)
+	return err
+}
+
+func (r *HydrationRepository) GetHydrationLogs(ctx context.Context, userId primitive.ObjectID, from time.Time, to time.Time) ([]models.HydrationLog, error) {
+	filter := bson.M{
+		"userId": userId,
+		"loggedAt": bson.M{
+			"$gte": from,
+			"$lt":  to,
+		},
+	}
+
+	cursor, err := r.Collection.Find(ctx, filter, options.Find().SetSort(bson.M{"loggedAt": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	logs := []models.HydrationLog{}
+	if err := cursor.All(ctx, &logs); err != nil {
+		return nil, err
+	}
+	return logs, nil
+}
+
+// GetHydrationLog returns the log of the user, mongo.ErrNoDocuments when the user has no such log
+func (r *HydrationRepository) GetHydrationLog(ctx context.Context, userId primitive.ObjectID, logId primitive.ObjectID) (*models.HydrationLog, error) {
+	var hydrationLog models.HydrationLog
+	if err := r.Collection.FindOne(ctx, bson.M{"_id": logId, "userId": userId}).Decode(&hydrationLog); err != nil {
+		return nil, err
+	}
+	return &hydrationLog, nil
+}
+
+func (r *HydrationRepository) DeleteHydrationLog(ctx context.Context, userId primitive.ObjectID, logId primitive.ObjectID) error {
+	result, err := r.Collection.DeleteOne(ctx, bson.M{"_id": logId, "userId": userId})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
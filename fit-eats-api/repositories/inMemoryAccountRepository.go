@@ -0,0 +1,70 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// InMemoryAccountRepository reads and removes the documents of a user across in memory collections named like the
+// UserDataCollections, for tests. The users, goals, meal plans and exports are the collections of the in memory
+// repositories it is given, so they see a purge. The other collections are filled with InsertUserDocument
+type InMemoryAccountRepository struct {
+	collections map[string]*inMemoryCollection
+}
+
+func NewInMemoryAccountRepository(users *InMemoryUserRepository, userGoals *InMemoryUserGoalRepository, meals *InMemoryMealRepository,
+	dataExports *InMemoryDataExportRepository) *InMemoryAccountRepository {
+	collections := map[string]*inMemoryCollection{
+		"users":       users.collection,
+		"userGoals":   userGoals.collection,
+		"meals":       meals.collection,
+		"dataExports": dataExports.collection,
+	}
+	for _, collection := range UserDataCollections {
+		if _, ok := collections[collection.Name]; !ok {
+			collections[collection.Name] = newInMemoryCollection()
+		}
+	}
+	return &InMemoryAccountRepository{collections: collections}
+}
+
+// InsertUserDocument adds a document to a user data collection without an in memory repository, like the hydration logs
+func (r *InMemoryAccountRepository) InsertUserDocument(collectionName string, document any) error {
+	collection, ok := r.collections[collectionName]
+	if !ok {
+		return fmt.Errorf("%s is not a user data collection", collectionName)
+	}
+	return collection.InsertOne(document)
+}
+
+func (r *InMemoryAccountRepository) CountUserDocuments(ctx context.Context, userId primitive.ObjectID) (int64, error) {
+	var total int64
+	for _, collection := range UserDataCollections {
+		if !collection.Export {
+			continue
+		}
+		documents, err := r.collections[collection.Name].Find(userDataFilter(collection, userId))
+		if err != nil {
+			return 0, err
+		}
+		total += int64(len(documents))
+	}
+	return total, nil
+}
+
+func (r *InMemoryAccountRepository) GetUserDocuments(ctx context.Context, collection UserDataCollection, userId primitive.ObjectID) ([]bson.M, error) {
+	return r.collections[collection.Name].Find(userDataFilter(collection, userId), userDocumentsOptions(collection))
+}
+
+func (r *InMemoryAccountRepository) DeleteUserData(ctx context.Context, userId primitive.ObjectID) error {
+	for i := len(UserDataCollections) - 1; i >= 0; i-- {
+		collection := UserDataCollections[i]
+		if _, err := r.collections[collection.Name].DeleteMany(userDataFilter(collection, userId)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package repositories
+
+import (
+	"bytes"
+	"context"
+	"fit-eats-api/models"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// InMemoryAuditRepository keeps the audit trail in process memory with the same queries as MongoAuditRepository, for tests
+type InMemoryAuditRepository struct {
+	collection *inMemoryCollection
+}
+
+func NewInMemoryAuditRepository() *InMemoryAuditRepository {
+	return &InMemoryAuditRepository{collection: newInMemoryCollection()}
+}
+
+func (r *InMemoryAuditRepository) CreateAuditLog(ctx context.Context, auditLog *models.AuditLog) error {
+	auditLog.ID = primitive.NewObjectID()
+	if auditLog.CreatedAt.IsZero() {
+		auditLog.CreatedAt = time.Now()
+	}
+	return r.collection.InsertOne(auditLog)
+}
+
+func (r *InMemoryAuditRepository) RedactUserAuditLogs(ctx context.Context, userId primitive.ObjectID, redactedAt time.Time) error {
+	for _, redaction := range auditLogRedactions(userId, redactedAt) {
+		if _, err := r.collection.UpdateMany(redaction.filter, redaction.update); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *InMemoryAuditRepository) GetAuditLogs(ctx context.Context, auditLogFilter models.AuditLogFilter, skip int64, limit int64) ([]models.AuditLog, int64, error) {
+	documents, err := r.collection.Find(auditLogQuery(auditLogFilter))
+	if err != nil {
+		return nil, 0, err
+	}
+	auditLogs := []models.AuditLog{}
+	if err := decodeBsonDocuments(documents, &auditLogs); err != nil {
+		return nil, 0, err
+	}
+
+	// Newest first, the ids order the entries created in the same millisecond
+	sort.Slice(auditLogs, func(i, j int) bool {
+		if !auditLogs[i].CreatedAt.Equal(auditLogs[j].CreatedAt) {
+			return auditLogs[i].CreatedAt.After(auditLogs[j].CreatedAt)
+		}
+		return bytes.Compare(auditLogs[i].ID[:], auditLogs[j].ID[:]) > 0
+	})
+
+	total := int64(len(auditLogs))
+	auditLogs = auditLogs[min(skip, total):]
+	if limit > 0 && int64(len(auditLogs)) > limit {
+		auditLogs = auditLogs[:limit]
+	}
+	return auditLogs, total, nil
+}
@@ -0,0 +1,832 @@
+package repositories
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// inMemoryCollection keeps documents in process memory in their bson form and understands the part of the
+// query and update language the repositories use. Values come back the way a round trip through Mongo returns
+// them, dotted paths reach into embedded documents and arrays, and positional and filtered updates change the
+// same array elements Mongo would. Inserts enforce the unique top level fields like a unique index
+type inMemoryCollection struct {
+	mutex        sync.Mutex
+	documents    []bson.M
+	uniqueFields []string
+}
+
+type inMemorySingleResult struct {
+	document bson.M
+	err      error
+}
+
+func (r *inMemorySingleResult) Decode(value any) error {
+	if r.err != nil {
+		return r.err
+	}
+	return decodeBsonDocument(r.document, value)
+}
+
+func newInMemoryCollection(uniqueFields ...string) *inMemoryCollection {
+	return &inMemoryCollection{uniqueFields: append([]string{"_id"}, uniqueFields...)}
+}
+
+func (c *inMemoryCollection) InsertOne(value any) error {
+	document, err := toBsonDocument(value)
+	if err != nil {
+		return err
+	}
+	if _, ok := document["_id"]; !ok {
+		document["_id"] = primitive.NewObjectID()
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for _, other := range c.documents {
+		for _, field := range c.uniqueFields {
+			if isBsonEqual(other[field], document[field]) {
+				return mongo.WriteException{WriteErrors: mongo.WriteErrors{{
+					Code:    11000,
+					Message: fmt.Sprintf("E11000 duplicate key error dup key: { %s: %v }", field, document[field]),
+				}}}
+			}
+		}
+	}
+	c.documents = append(c.documents, document)
+	return nil
+}
+
+func (c *inMemoryCollection) FindOne(filter bson.M, opts ...*options.FindOneOptions) *inMemorySingleResult {
+	query, err := toBsonDocument(filter)
+	if err != nil {
+		return &inMemorySingleResult{err: err}
+	}
+
+	var projection any
+	for _, opt := range opts {
+		if opt.Projection != nil {
+			projection = opt.Projection
+		}
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for _, document := range c.documents {
+		if matchBsonDocument(document, query) {
+			projected, err := projectBsonDocument(document, projection)
+			return &inMemorySingleResult{document: projected, err: err}
+		}
+	}
+	return &inMemorySingleResult{err: mongo.ErrNoDocuments}
+}
+
+// Find returns copies of the matching documents, decode them with decodeBsonDocuments
+func (c *inMemoryCollection) Find(filter bson.M, opts ...*options.FindOptions) ([]bson.M, error) {
+	query, err := toBsonDocument(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var projection, sorting any
+	var limit int64
+	for _, opt := range opts {
+		if opt.Projection != nil {
+			projection = opt.Projection
+		}
+		if opt.Sort != nil {
+			sorting = opt.Sort
+		}
+		if opt.Limit != nil {
+			limit = *opt.Limit
+		}
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	documents := []bson.M{}
+	for _, document := range c.documents {
+		if matchBsonDocument(document, query) {
+			documents = append(documents, document)
+		}
+	}
+
+	if sorting != nil {
+		keys, err := toBsonDocument(sorting)
+		if err != nil {
+			return nil, err
+		}
+		for key, direction := range keys {
+			path := strings.Split(key, ".")
+			descending := toBsonNumber(direction) < 0
+			sort.SliceStable(documents, func(i, j int) bool {
+				order := compareBsonSortValues(lookupBsonPath(documents[i], path), lookupBsonPath(documents[j], path))
+				if descending {
+					return order > 0
+				}
+				return order < 0
+			})
+		}
+	}
+	if limit > 0 && int64(len(documents)) > limit {
+		documents = documents[:limit]
+	}
+
+	for i, document := range documents {
+		if documents[i], err = projectBsonDocument(document, projection); err != nil {
+			return nil, err
+		}
+	}
+	return documents, nil
+}
+
+func (c *inMemoryCollection) UpdateOne(filter bson.M, update bson.M, opts ...*options.UpdateOptions) (*mongo.UpdateResult, error) {
+	query, err := toBsonDocument(filter)
+	if err != nil {
+		return nil, err
+	}
+	changes, err := toBsonDocument(update)
+	if err != nil {
+		return nil, err
+	}
+
+	var arrayFilters []bson.M
+	for _, opt := range opts {
+		if opt.ArrayFilters == nil {
+			continue
+		}
+		for _, arrayFilter := range opt.ArrayFilters.Filters {
+			normalized, err := toBsonDocument(arrayFilter)
+			if err != nil {
+				return nil, err
+			}
+			arrayFilters = append(arrayFilters, normalized)
+		}
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for i, document := range c.documents {
+		if !matchBsonDocument(document, query) {
+			continue
+		}
+
+		updated, err := copyBsonDocument(document)
+		if err != nil {
+			return nil, err
+		}
+		if err := applyBsonUpdate(updated, changes, query, arrayFilters); err != nil {
+			return nil, err
+		}
+
+		result := &mongo.UpdateResult{MatchedCount: 1}
+		if !reflect.DeepEqual(document, updated) {
+			c.documents[i] = updated
+			result.ModifiedCount = 1
+		}
+		return result, nil
+	}
+	return &mongo.UpdateResult{}, nil
+}
+
+// UpdateMany applies the update to every matching document
+func (c *inMemoryCollection) UpdateMany(filter bson.M, update bson.M) (*mongo.UpdateResult, error) {
+	query, err := toBsonDocument(filter)
+	if err != nil {
+		return nil, err
+	}
+	changes, err := toBsonDocument(update)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	result := &mongo.UpdateResult{}
+	for i, document := range c.documents {
+		if !matchBsonDocument(document, query) {
+			continue
+		}
+
+		updated, err := copyBsonDocument(document)
+		if err != nil {
+			return nil, err
+		}
+		if err := applyBsonUpdate(updated, changes, query, nil); err != nil {
+			return nil, err
+		}
+
+		result.MatchedCount++
+		if !reflect.DeepEqual(document, updated) {
+			c.documents[i] = updated
+			result.ModifiedCount++
+		}
+	}
+	return result, nil
+}
+
+func (c *inMemoryCollection) DeleteOne(filter bson.M) (*mongo.DeleteResult, error) {
+	query, err := toBsonDocument(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for i, document := range c.documents {
+		if matchBsonDocument(document, query) {
+			c.documents = append(c.documents[:i], c.documents[i+1:]...)
+			return &mongo.DeleteResult{DeletedCount: 1}, nil
+		}
+	}
+	return &mongo.DeleteResult{}, nil
+}
+
+func (c *inMemoryCollection) DeleteMany(filter bson.M) (*mongo.DeleteResult, error) {
+	query, err := toBsonDocument(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	kept := []bson.M{}
+	for _, document := range c.documents {
+		if !matchBsonDocument(document, query) {
+			kept = append(kept, document)
+		}
+	}
+	result := &mongo.DeleteResult{DeletedCount: int64(len(c.documents) - len(kept))}
+	c.documents = kept
+	return result, nil
+}
+
+// toBsonDocument converts the value to what reading it back from Mongo gives, like primitive.DateTime for time.Time
+func toBsonDocument(value any) (bson.M, error) {
+	data, err := bson.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	document := bson.M{}
+	if err := bson.Unmarshal(data, &document); err != nil {
+		return nil, err
+	}
+	return document, nil
+}
+
+func copyBsonDocument(document bson.M) (bson.M, error) {
+	return toBsonDocument(document)
+}
+
+func decodeBsonDocument(document bson.M, value any) error {
+	data, err := bson.Marshal(document)
+	if err != nil {
+		return err
+	}
+	return bson.Unmarshal(data, value)
+}
+
+// decodeBsonDocuments decodes the documents into the slice values points to
+func decodeBsonDocuments(documents []bson.M, values any) error {
+	slice := reflect.ValueOf(values).Elem()
+	for _, document := range documents {
+		value := reflect.New(slice.Type().Elem())
+		if err := decodeBsonDocument(document, value.Interface()); err != nil {
+			return err
+		}
+		slice.Set(reflect.Append(slice, value.Elem()))
+	}
+	return nil
+}
+
+// lookupBsonPath returns the values at the dotted path. Arrays on the way are traversed element by element,
+// and an array at the end of the path yields the array and each of its elements, as Mongo queries see them
+func lookupBsonPath(value any, path []string) []any {
+	if len(path) == 0 {
+		if array, ok := value.(bson.A); ok {
+			return append([]any{array}, array...)
+		}
+		return []any{value}
+	}
+
+	switch value := value.(type) {
+	case bson.M:
+		child, ok := value[path[0]]
+		if !ok {
+			return nil
+		}
+		return lookupBsonPath(child, path[1:])
+	case bson.A:
+		var values []any
+		for _, element := range value {
+			if _, ok := element.(bson.M); ok {
+				values = append(values, lookupBsonPath(element, path)...)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+func matchBsonDocument(document bson.M, filter bson.M) bool {
+	for key, condition := range filter {
+		switch key {
+		case "$or":
+			if !matchAnyBsonFilter(document, condition) {
+				return false
+			}
+		case "$and":
+			for _, subFilter := range toBsonFilters(condition) {
+				if !matchBsonDocument(document, subFilter) {
+					return false
+				}
+			}
+		default:
+			if !matchBsonCondition(lookupBsonPath(document, strings.Split(key, ".")), condition) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func matchAnyBsonFilter(document bson.M, filters any) bool {
+	for _, filter := range toBsonFilters(filters) {
+		if matchBsonDocument(document, filter) {
+			return true
+		}
+	}
+	return false
+}
+
+func toBsonFilters(value any) []bson.M {
+	array, _ := value.(bson.A)
+	filters := make([]bson.M, 0, len(array))
+	for _, element := range array {
+		if filter, ok := element.(bson.M); ok {
+			filters = append(filters, filter)
+		}
+	}
+	return filters
+}
+
+func isBsonOperatorDocument(value any) bool {
+	document, ok := value.(bson.M)
+	if !ok || len(document) == 0 {
+		return false
+	}
+	for key := range document {
+		if !strings.HasPrefix(key, "$") {
+			return false
+		}
+	}
+	return true
+}
+
+func matchBsonCondition(values []any, condition any) bool {
+	if !isBsonOperatorDocument(condition) {
+		return matchBsonOperator(values, "$eq", condition)
+	}
+	for operator, operand := range condition.(bson.M) {
+		if !matchBsonOperator(values, operator, operand) {
+			return false
+		}
+	}
+	return true
+}
+
+func matchBsonOperator(values []any, operator string, operand any) bool {
+	switch operator {
+	case "$eq":
+		if operand == nil {
+			return len(values) == 0 || containsBsonValue(values, nil)
+		}
+		if regex, ok := operand.(primitive.Regex); ok {
+			pattern := regex.Pattern
+			if regex.Options != "" {
+				pattern = "(?" + regex.Options + ")" + pattern
+			}
+			matcher := regexp.MustCompile(pattern)
+			for _, value := range values {
+				if text, ok := value.(string); ok && matcher.MatchString(text) {
+					return true
+				}
+			}
+			return false
+		}
+		return containsBsonValue(values, operand)
+	case "$ne":
+		return !matchBsonOperator(values, "$eq", operand)
+	case "$in":
+		operands, _ := operand.(bson.A)
+		for _, other := range operands {
+			if matchBsonOperator(values, "$eq", other) {
+				return true
+			}
+		}
+		return false
+	case "$nin":
+		return !matchBsonOperator(values, "$in", operand)
+	case "$exists":
+		return (len(values) > 0) == isBsonTruthy(operand)
+	case "$lt", "$lte", "$gt", "$gte":
+		for _, value := range values {
+			order, ok := compareBsonValues(value, operand)
+			if !ok {
+				continue
+			}
+			if (operator == "$lt" && order < 0) || (operator == "$lte" && order <= 0) ||
+				(operator == "$gt" && order > 0) || (operator == "$gte" && order >= 0) {
+				return true
+			}
+		}
+		return false
+	case "$elemMatch":
+		filter, _ := operand.(bson.M)
+		for _, value := range values {
+			array, ok := value.(bson.A)
+			if !ok {
+				continue
+			}
+			for _, element := range array {
+				if document, ok := element.(bson.M); ok && matchBsonDocument(document, filter) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	panic("in memory collection: unsupported query operator " + operator)
+}
+
+func containsBsonValue(values []any, value any) bool {
+	for _, other := range values {
+		if isBsonEqual(other, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func isBsonEqual(a any, b any) bool {
+	if order, ok := compareBsonValues(a, b); ok {
+		return order == 0
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func isBsonNumber(value any) bool {
+	switch value.(type) {
+	case int32, int64, float64:
+		return true
+	}
+	return false
+}
+
+func toBsonNumber(value any) float64 {
+	switch value := value.(type) {
+	case int32:
+		return float64(value)
+	case int64:
+		return float64(value)
+	case float64:
+		return value
+	}
+	return 0
+}
+
+// compareBsonValues orders values of the same bson type, ok is false when they cannot be compared
+func compareBsonValues(a any, b any) (int, bool) {
+	if isBsonNumber(a) && isBsonNumber(b) {
+		x, y := toBsonNumber(a), toBsonNumber(b)
+		switch {
+		case x < y:
+			return -1, true
+		case x > y:
+			return 1, true
+		}
+		return 0, true
+	}
+
+	switch a := a.(type) {
+	case string:
+		if b, ok := b.(string); ok {
+			return strings.Compare(a, b), true
+		}
+	case bool:
+		if b, ok := b.(bool); ok {
+			switch {
+			case a == b:
+				return 0, true
+			case b:
+				return -1, true
+			}
+			return 1, true
+		}
+	case primitive.DateTime:
+		if b, ok := b.(primitive.DateTime); ok {
+			switch {
+			case a < b:
+				return -1, true
+			case a > b:
+				return 1, true
+			}
+			return 0, true
+		}
+	case primitive.ObjectID:
+		if b, ok := b.(primitive.ObjectID); ok {
+			return bytes.Compare(a[:], b[:]), true
+		}
+	}
+	return 0, false
+}
+
+// compareBsonSortValues orders by the first value of each path, missing values first like Mongo sorts them
+func compareBsonSortValues(a []any, b []any) int {
+	switch {
+	case len(a) == 0 && len(b) == 0:
+		return 0
+	case len(a) == 0:
+		return -1
+	case len(b) == 0:
+		return 1
+	}
+	order, _ := compareBsonValues(a[0], b[0])
+	return order
+}
+
+func isBsonTruthy(value any) bool {
+	if isBsonNumber(value) {
+		return toBsonNumber(value) != 0
+	}
+	return value == true
+}
+
+// projectBsonDocument returns a copy of the document with only the included fields, or without the excluded ones
+func projectBsonDocument(document bson.M, projection any) (bson.M, error) {
+	projected, err := copyBsonDocument(document)
+	if err != nil || projection == nil {
+		return projected, err
+	}
+	fields, err := toBsonDocument(projection)
+	if err != nil {
+		return nil, err
+	}
+
+	inclusive := false
+	for key, value := range fields {
+		if isBsonOperatorDocument(value) {
+			return nil, fmt.Errorf("in memory collection: unsupported projection of %s", key)
+		}
+		if key != "_id" && isBsonTruthy(value) {
+			inclusive = true
+		}
+	}
+
+	if !inclusive {
+		for key := range fields {
+			unsetBsonPath(projected, strings.Split(key, "."))
+		}
+		return projected, nil
+	}
+
+	included := bson.M{}
+	if id, ok := projected["_id"]; ok && (fields["_id"] == nil || isBsonTruthy(fields["_id"])) {
+		included["_id"] = id
+	}
+	for key, value := range fields {
+		if key == "_id" || !isBsonTruthy(value) {
+			continue
+		}
+		if strings.Contains(key, ".") {
+			return nil, fmt.Errorf("in memory collection: unsupported projection of %s", key)
+		}
+		if field, ok := projected[key]; ok {
+			included[key] = field
+		}
+	}
+	return included, nil
+}
+
+func unsetBsonPath(value any, path []string) {
+	switch value := value.(type) {
+	case bson.M:
+		if len(path) == 1 {
+			delete(value, path[0])
+			return
+		}
+		if child, ok := value[path[0]]; ok {
+			unsetBsonPath(child, path[1:])
+		}
+	case bson.A:
+		for _, element := range value {
+			unsetBsonPath(element, path)
+		}
+	}
+}
+
+// bsonUpdate resolves the positional operators of an update, $ from the query and $[identifier] from the array filters
+type bsonUpdate struct {
+	query        bson.M
+	arrayFilters []bson.M
+}
+
+func applyBsonUpdate(document bson.M, changes bson.M, query bson.M, arrayFilters []bson.M) error {
+	update := bsonUpdate{query: query, arrayFilters: arrayFilters}
+
+	for operator, fields := range changes {
+		fields, ok := fields.(bson.M)
+		if !ok {
+			return fmt.Errorf("in memory collection: %s expects a document", operator)
+		}
+
+		for key, value := range fields {
+			var apply func(parent bson.M, field string) error
+			create := true
+
+			switch operator {
+			case "$set":
+				apply = func(parent bson.M, field string) error {
+					parent[field] = value
+					return nil
+				}
+			case "$unset":
+				create = false
+				apply = func(parent bson.M, field string) error {
+					delete(parent, field)
+					return nil
+				}
+			case "$push":
+				apply = func(parent bson.M, field string) error {
+					current, ok := parent[field]
+					if !ok {
+						parent[field] = bson.A{value}
+						return nil
+					}
+					array, ok := current.(bson.A)
+					if !ok {
+						return fmt.Errorf("in memory collection: %s is a %T, not an array", field, current)
+					}
+					parent[field] = append(array, value)
+					return nil
+				}
+			case "$pull":
+				create = false
+				apply = func(parent bson.M, field string) error {
+					array, ok := parent[field].(bson.A)
+					if !ok {
+						return nil
+					}
+					kept := bson.A{}
+					for _, element := range array {
+						if !matchBsonPullCondition(element, value) {
+							kept = append(kept, element)
+						}
+					}
+					parent[field] = kept
+					return nil
+				}
+			default:
+				return fmt.Errorf("in memory collection: unsupported update operator %s", operator)
+			}
+
+			if err := update.apply(document, strings.Split(key, "."), 0, create, apply); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func matchBsonPullCondition(element any, condition any) bool {
+	if filter, ok := condition.(bson.M); ok && !isBsonOperatorDocument(condition) {
+		document, ok := element.(bson.M)
+		return ok && matchBsonDocument(document, filter)
+	}
+	return matchBsonCondition([]any{element}, condition)
+}
+
+// apply walks the path from path[index], calling apply on the document holding the last field of every match
+func (u bsonUpdate) apply(value any, path []string, index int, create bool, apply func(parent bson.M, field string) error) error {
+	segment := path[index]
+
+	switch value := value.(type) {
+	case bson.M:
+		if index == len(path)-1 {
+			return apply(value, segment)
+		}
+		child, ok := value[segment]
+		if !ok || child == nil {
+			if !create {
+				return nil
+			}
+			child = bson.M{}
+			value[segment] = child
+		}
+		return u.apply(child, path, index+1, create, apply)
+	case bson.A:
+		positions, err := u.getPositions(value, path, index)
+		if err != nil {
+			return err
+		}
+		if index == len(path)-1 {
+			// The element is updated as the field of a holder document, an unset element becomes null like in Mongo
+			for _, position := range positions {
+				holder := bson.M{segment: value[position]}
+				if err := apply(holder, segment); err != nil {
+					return err
+				}
+				value[position] = holder[segment]
+			}
+			return nil
+		}
+		for _, position := range positions {
+			if err := u.apply(value[position], path, index+1, create, apply); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("in memory collection: cannot create %s in %s, it is a %T", segment, strings.Join(path[:index], "."), value)
+}
+
+// getPositions returns the indexes of the array elements addressed by path[index]
+func (u bsonUpdate) getPositions(array bson.A, path []string, index int) ([]int, error) {
+	segment := path[index]
+	arrayPath := strings.Join(path[:index], ".")
+
+	var filter bson.M
+	switch {
+	case segment == "$":
+		filter = bson.M{}
+		for key, condition := range u.query {
+			if field, ok := strings.CutPrefix(key, arrayPath+"."); ok {
+				filter[field] = condition
+			} else if key == arrayPath && isBsonOperatorDocument(condition) {
+				if elemMatch, ok := condition.(bson.M)["$elemMatch"].(bson.M); ok {
+					for field, condition := range elemMatch {
+						filter[field] = condition
+					}
+				}
+			}
+		}
+		if len(filter) == 0 {
+			return nil, fmt.Errorf("in memory collection: the query does not match an element of %s for the positional operator", arrayPath)
+		}
+	case segment == "$[]":
+		filter = bson.M{}
+	case strings.HasPrefix(segment, "$[") && strings.HasSuffix(segment, "]"):
+		identifier := strings.TrimSuffix(strings.TrimPrefix(segment, "$["), "]")
+		for _, arrayFilter := range u.arrayFilters {
+			for key, condition := range arrayFilter {
+				if field, ok := strings.CutPrefix(key, identifier+"."); ok {
+					if filter == nil {
+						filter = bson.M{}
+					}
+					filter[field] = condition
+				}
+			}
+		}
+		if filter == nil {
+			return nil, fmt.Errorf("in memory collection: no array filter for %s in %s", identifier, strings.Join(path, "."))
+		}
+	default:
+		position, err := strconv.Atoi(segment)
+		if err != nil || position < 0 {
+			return nil, fmt.Errorf("in memory collection: cannot create %s in the array %s", segment, arrayPath)
+		}
+		if position >= len(array) {
+			return nil, nil
+		}
+		return []int{position}, nil
+	}
+
+	var positions []int
+	for position, element := range array {
+		document, ok := element.(bson.M)
+		if ok && matchBsonDocument(document, filter) {
+			positions = append(positions, position)
+			if segment == "$" {
+				break
+			}
+		}
+	}
+	return positions, nil
+}
@@ -0,0 +1,123 @@
+package repositories
+
+import (
+	"bytes"
+	"context"
+	"fit-eats-api/models"
+	"io"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/gridfs"
+)
+
+// InMemoryDataExportRepository keeps the exports and their zip files in process memory with the same queries as
+// MongoDataExportRepository, for tests
+type InMemoryDataExportRepository struct {
+	collection *inMemoryCollection
+	mutex      sync.Mutex
+	files      map[primitive.ObjectID][]byte
+}
+
+func NewInMemoryDataExportRepository() *InMemoryDataExportRepository {
+	return &InMemoryDataExportRepository{collection: newInMemoryCollection(), files: map[primitive.ObjectID][]byte{}}
+}
+
+func (r *InMemoryDataExportRepository) CreateDataExport(ctx context.Context, dataExport *models.DataExport) error {
+	dataExport.ID = primitive.NewObjectID()
+	return r.collection.InsertOne(dataExport)
+}
+
+func (r *InMemoryDataExportRepository) findDataExport(filter bson.M) (*models.DataExport, error) {
+	var dataExport models.DataExport
+	err := r.collection.FindOne(filter).Decode(&dataExport)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &dataExport, nil
+}
+
+func (r *InMemoryDataExportRepository) GetDataExport(ctx context.Context, userId primitive.ObjectID, dataExportId primitive.ObjectID) (*models.DataExport, error) {
+	return r.findDataExport(bson.M{"_id": dataExportId, "userId": userId})
+}
+
+func (r *InMemoryDataExportRepository) GetPendingDataExport(ctx context.Context, userId primitive.ObjectID) (*models.DataExport, error) {
+	return r.findDataExport(bson.M{"userId": userId, "status": models.DATA_EXPORT_PENDING, "createdAt": bson.M{"$gt": time.Now().Add(-models.DATA_EXPORT_TIMEOUT)}})
+}
+
+func (r *InMemoryDataExportRepository) SaveDataExportFile(ctx context.Context, dataExportId primitive.ObjectID, write func(w io.Writer) error) (primitive.ObjectID, int64, error) {
+	var file bytes.Buffer
+	if err := write(&file); err != nil {
+		return primitive.NilObjectID, 0, err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	fileId := primitive.NewObjectID()
+	r.files[fileId] = file.Bytes()
+	return fileId, int64(file.Len()), nil
+}
+
+func (r *InMemoryDataExportRepository) CompleteDataExport(ctx context.Context, dataExportId primitive.ObjectID, fileId primitive.ObjectID, size int64) error {
+	update := bson.M{"$set": bson.M{"status": models.DATA_EXPORT_READY, "fileId": fileId, "size": size, "completedAt": time.Now()}}
+	_, err := r.collection.UpdateOne(bson.M{"_id": dataExportId}, update)
+	return err
+}
+
+func (r *InMemoryDataExportRepository) FailDataExport(ctx context.Context, dataExportId primitive.ObjectID, message string) error {
+	update := bson.M{"$set": bson.M{"status": models.DATA_EXPORT_FAILED, "error": message, "completedAt": time.Now()}}
+	_, err := r.collection.UpdateOne(bson.M{"_id": dataExportId}, update)
+	return err
+}
+
+// DownloadFile fails with gridfs.ErrFileNotFound for a missing file, like the GridFS bucket
+func (r *InMemoryDataExportRepository) DownloadFile(ctx context.Context, fileId primitive.ObjectID, w io.Writer) error {
+	r.mutex.Lock()
+	file, ok := r.files[fileId]
+	r.mutex.Unlock()
+
+	if !ok {
+		return gridfs.ErrFileNotFound
+	}
+	_, err := w.Write(file)
+	return err
+}
+
+func (r *InMemoryDataExportRepository) deleteDataExports(filter bson.M) (int, error) {
+	documents, err := r.collection.Find(filter)
+	if err != nil {
+		return 0, err
+	}
+	var dataExports []models.DataExport
+	if err := decodeBsonDocuments(documents, &dataExports); err != nil {
+		return 0, err
+	}
+
+	r.mutex.Lock()
+	for _, dataExport := range dataExports {
+		delete(r.files, dataExport.FileId)
+	}
+	r.mutex.Unlock()
+
+	result, err := r.collection.DeleteMany(filter)
+	if err != nil {
+		return 0, err
+	}
+	return int(result.DeletedCount), nil
+}
+
+func (r *InMemoryDataExportRepository) DeleteUserDataExports(ctx context.Context, userId primitive.ObjectID) error {
+	_, err := r.deleteDataExports(bson.M{"userId": userId})
+	return err
+}
+
+func (r *InMemoryDataExportRepository) DeleteExpiredDataExports(ctx context.Context, now time.Time) (int, error) {
+	return r.deleteDataExports(bson.M{"expiresAt": bson.M{"$lt": now}})
+}
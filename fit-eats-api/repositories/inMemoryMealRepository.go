@@ -0,0 +1,183 @@
+package repositories
+
+import (
+	"context"
+	"fit-eats-api/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// InMemoryMealRepository keeps the meal plans in process memory with the same queries as MongoMealRepository, for tests
+type InMemoryMealRepository struct {
+	collection *inMemoryCollection
+}
+
+func NewInMemoryMealRepository() *InMemoryMealRepository {
+	return &InMemoryMealRepository{collection: newInMemoryCollection("weeklyGoalId")}
+}
+
+// findMealPlan returns nil when no meal plan matches, like the finds of MongoMealRepository
+func (r *InMemoryMealRepository) findMealPlan(filter bson.M, opts ...*options.FindOneOptions) (*models.MealPlan, error) {
+	var mealPlan models.MealPlan
+	err := r.collection.FindOne(filter, opts...).Decode(&mealPlan)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &mealPlan, nil
+}
+
+func (r *InMemoryMealRepository) CreateWeeklyMealPlan(ctx context.Context, mealPlan *models.MealPlan) error {
+	return r.collection.InsertOne(mealPlan)
+}
+
+func (r *InMemoryMealRepository) UpdateSingleDayMeal(ctx context.Context, mealPlanId primitive.ObjectID, dayMealId primitive.ObjectID, meals []models.Meal) error {
+	filter := bson.M{"_id": mealPlanId, "dayMeals._id": dayMealId}
+	_, err := r.collection.UpdateOne(filter, bson.M{"$set": bson.M{"dayMeals.$.meals": meals}})
+	return err
+}
+
+func (r *InMemoryMealRepository) IsWeeklyMealPlanCreated(ctx context.Context, userId primitive.ObjectID, weeklyGoalId primitive.ObjectID) bool {
+	mealPlan, err := r.findMealPlan(bson.M{"userId": userId, "weeklyGoalId": weeklyGoalId}, options.FindOne().SetProjection(bson.M{"dayMeals": 0}))
+	return err == nil && mealPlan != nil
+}
+
+func (r *InMemoryMealRepository) GetWeeklyMealPlan(ctx context.Context, userId primitive.ObjectID, mainGoalId primitive.ObjectID, weeklyGoalId primitive.ObjectID) (*models.MealPlan, error) {
+	return r.findMealPlan(bson.M{"userId": userId, "mainGoalId": mainGoalId, "weeklyGoalId": weeklyGoalId})
+}
+
+func (r *InMemoryMealRepository) GetSingleDayMeal(ctx context.Context, mainGoalId primitive.ObjectID, weeklyGoalId primitive.ObjectID, dayMealId primitive.ObjectID) (*models.DayMeal, error) {
+	var dayMeal models.DayMeal
+
+	err := r.collection.FindOne(bson.M{"mainGoalId": mainGoalId, "weeklyGoalId": weeklyGoalId, "dayMeals._id": dayMealId}).Decode(&dayMeal)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &dayMeal, nil
+}
+
+func (r *InMemoryMealRepository) GetSingleDayMealByDate(ctx context.Context, userId primitive.ObjectID) (*models.DayMeal, error) {
+	startOfDay, endOfDay := getMealDayRange()
+
+	filter := bson.M{"userId": userId, "dayMeals.date": bson.M{"$gte": startOfDay, "$lt": endOfDay}}
+	mealPlan, err := r.findMealPlan(filter)
+	if mealPlan == nil || err != nil {
+		return nil, err
+	}
+
+	// The $filter projection of MongoMealRepository, keeping the day meals of today
+	for _, dayMeal := range mealPlan.DayMeals {
+		if !dayMeal.Date.Before(startOfDay) && dayMeal.Date.Before(endOfDay) {
+			return &dayMeal, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *InMemoryMealRepository) GetMealPlanMeta(ctx context.Context, mealPlanId primitive.ObjectID) (*models.MealPlan, error) {
+	return r.findMealPlan(bson.M{"_id": mealPlanId}, options.FindOne().SetProjection(bson.M{"dayMeals": 0}))
+}
+
+func (r *InMemoryMealRepository) GetMealPlanById(ctx context.Context, mealPlanId primitive.ObjectID) (*models.MealPlan, error) {
+	return r.findMealPlan(bson.M{"_id": mealPlanId})
+}
+
+func (r *InMemoryMealRepository) GetMealPlanByMealId(ctx context.Context, mealId primitive.ObjectID) (*models.MealPlan, error) {
+	return r.findMealPlan(bson.M{"dayMeals.meals._id": mealId})
+}
+
+func (r *InMemoryMealRepository) GetMealPlansByUserId(ctx context.Context, userId primitive.ObjectID) ([]models.MealPlan, error) {
+	documents, err := r.collection.Find(bson.M{"userId": userId}, options.Find().SetSort(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, err
+	}
+
+	mealPlans := []models.MealPlan{}
+	if err := decodeBsonDocuments(documents, &mealPlans); err != nil {
+		return nil, err
+	}
+	return mealPlans, nil
+}
+
+func (r *InMemoryMealRepository) GetMealPlansByMainGoalId(ctx context.Context, mainGoalId primitive.ObjectID) ([]models.MealPlan, error) {
+	documents, err := r.collection.Find(bson.M{"mainGoalId": mainGoalId}, options.Find().SetSort(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, err
+	}
+
+	mealPlans := []models.MealPlan{}
+	if err := decodeBsonDocuments(documents, &mealPlans); err != nil {
+		return nil, err
+	}
+	return mealPlans, nil
+}
+
+func (r *InMemoryMealRepository) DeleteMealPlan(ctx context.Context, mealPlanId primitive.ObjectID) error {
+	result, err := r.collection.DeleteOne(bson.M{"_id": mealPlanId})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+func (r *InMemoryMealRepository) DeleteMealPlansByMainGoalId(ctx context.Context, mainGoalId primitive.ObjectID) (int64, error) {
+	result, err := r.collection.DeleteMany(bson.M{"mainGoalId": mainGoalId})
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
+
+func (r *InMemoryMealRepository) DeleteMealPlansByWeeklyGoalId(ctx context.Context, mainGoalId primitive.ObjectID, weeklyGoalId primitive.ObjectID) (int64, error) {
+	result, err := r.collection.DeleteMany(bson.M{"mainGoalId": mainGoalId, "weeklyGoalId": weeklyGoalId})
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
+
+func (r *InMemoryMealRepository) HasConsumedMeals(ctx context.Context, mainGoalId primitive.ObjectID, weeklyGoalId primitive.ObjectID) (bool, error) {
+	documents, err := r.collection.Find(bson.M{"mainGoalId": mainGoalId, "weeklyGoalId": weeklyGoalId, "dayMeals.meals.isConsumed": true})
+	return len(documents) > 0, err
+}
+
+func (r *InMemoryMealRepository) GetMealOwnerId(ctx context.Context, mealId primitive.ObjectID) (primitive.ObjectID, error) {
+	var mealPlan models.MealPlan
+	err := r.collection.FindOne(bson.M{"dayMeals.meals._id": mealId}, options.FindOne().SetProjection(bson.M{"userId": 1})).Decode(&mealPlan)
+	return mealPlan.UserId, err
+}
+
+func (r *InMemoryMealRepository) SetMealConsumed(ctx context.Context, mealPlanId primitive.ObjectID, dayMealId primitive.ObjectID, mealId primitive.ObjectID, isConsumed bool) error {
+	filter := bson.M{"dayMeals.meals._id": mealId}
+	path := "dayMeals.$[].meals.$[meal].isConsumed"
+	arrayFilters := []interface{}{bson.M{"meal._id": mealId}}
+	if !mealPlanId.IsZero() {
+		filter["_id"] = mealPlanId
+	}
+	if !dayMealId.IsZero() {
+		filter["dayMeals"] = bson.M{"$elemMatch": bson.M{"_id": dayMealId, "meals._id": mealId}}
+		path = "dayMeals.$[dayMeal].meals.$[meal].isConsumed"
+		arrayFilters = append(arrayFilters, bson.M{"dayMeal._id": dayMealId})
+	}
+
+	update := bson.M{"$set": bson.M{path: isConsumed}}
+	result, err := r.collection.UpdateOne(filter, update, options.Update().SetArrayFilters(options.ArrayFilters{Filters: arrayFilters}))
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
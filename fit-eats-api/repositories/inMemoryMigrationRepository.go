@@ -0,0 +1,31 @@
+package repositories
+
+import (
+	"context"
+	"fit-eats-api/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// InMemoryMigrationRepository keeps the applied migrations in process memory, for tests
+type InMemoryMigrationRepository struct {
+	collection *inMemoryCollection
+}
+
+func NewInMemoryMigrationRepository() *InMemoryMigrationRepository {
+	return &InMemoryMigrationRepository{collection: newInMemoryCollection()}
+}
+
+func (r *InMemoryMigrationRepository) GetAppliedMigrations(ctx context.Context) ([]models.MigrationRecord, error) {
+	documents, err := r.collection.Find(bson.M{}, options.Find().SetSort(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, err
+	}
+	records := []models.MigrationRecord{}
+	return records, decodeBsonDocuments(documents, &records)
+}
+
+func (r *InMemoryMigrationRepository) RecordMigration(ctx context.Context, record *models.MigrationRecord) error {
+	return r.collection.InsertOne(record)
+}
@@ -0,0 +1,15 @@
+package repositories
+
+import "context"
+
+// InMemoryUnitOfWork runs fn straight away for the in memory repositories, for tests. Nothing is rolled back,
+// the writes made before fn fails are kept
+type InMemoryUnitOfWork struct{}
+
+func NewInMemoryUnitOfWork() *InMemoryUnitOfWork {
+	return &InMemoryUnitOfWork{}
+}
+
+func (u *InMemoryUnitOfWork) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
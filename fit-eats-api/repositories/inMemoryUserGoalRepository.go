@@ -0,0 +1,188 @@
+package repositories
+
+import (
+	"context"
+	"fit-eats-api/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// InMemoryUserGoalRepository keeps the goals in process memory with the same queries as MongoUserGoalRepository, for tests
+type InMemoryUserGoalRepository struct {
+	collection *inMemoryCollection
+}
+
+func NewInMemoryUserGoalRepository() *InMemoryUserGoalRepository {
+	return &InMemoryUserGoalRepository{collection: newInMemoryCollection()}
+}
+
+func (r *InMemoryUserGoalRepository) CreateMainUserGoal(ctx context.Context, mainGoal *models.Goal) error {
+	return r.collection.InsertOne(mainGoal)
+}
+
+func (r *InMemoryUserGoalRepository) CreateWeeklyUserGoal(ctx context.Context, mainGoalId primitive.ObjectID, weeklyGoal *models.WeeklyGoal) error {
+	weeklyGoal.ID = primitive.NewObjectID()
+
+	filter := bson.M{"_id": mainGoalId, "$or": []bson.M{{"weeklyGoals": bson.M{"$exists": false}}, {"weeklyGoals": nil}}}
+	if _, err := r.collection.UpdateOne(filter, bson.M{"$set": bson.M{"weeklyGoals": bson.A{}}}); err != nil {
+		return err
+	}
+
+	result, err := r.collection.UpdateOne(bson.M{"_id": mainGoalId, "status": models.GOAL_ACTIVE}, bson.M{"$push": bson.M{"weeklyGoals": weeklyGoal}})
+	if err != nil {
+		return err
+	}
+	if result.ModifiedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// getRunningGoal keeps the weekly goals running now, like the $filter stage of the Mongo aggregations
+func (r *InMemoryUserGoalRepository) getRunningGoal(filter bson.M) (*models.Goal, error) {
+	var userGoal models.Goal
+	if err := r.collection.FindOne(filter).Decode(&userGoal); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var weeklyGoals []models.WeeklyGoal
+	for _, weeklyGoal := range userGoal.WeeklyGoals {
+		if !weeklyGoal.StartDate.After(now) && !weeklyGoal.EndDate.Before(now) {
+			weeklyGoals = append(weeklyGoals, weeklyGoal)
+		}
+	}
+	if len(weeklyGoals) < 1 {
+		return nil, mongo.ErrNoDocuments
+	}
+
+	userGoal.WeeklyGoals = weeklyGoals
+	return &userGoal, nil
+}
+
+func (r *InMemoryUserGoalRepository) GetUserActiveGoalByUserId(ctx context.Context, mongoUserId primitive.ObjectID) (*models.Goal, error) {
+	return r.getRunningGoal(bson.M{"userId": mongoUserId, "status": models.GOAL_ACTIVE})
+}
+
+func (r *InMemoryUserGoalRepository) GetUserGoalByUserId(ctx context.Context, mongoUserId primitive.ObjectID) (*models.Goal, error) {
+	var userGoal models.Goal
+	if err := r.collection.FindOne(bson.M{"userId": mongoUserId, "status": models.GOAL_ACTIVE}).Decode(&userGoal); err != nil {
+		return nil, err
+	}
+	return &userGoal, nil
+}
+
+func (r *InMemoryUserGoalRepository) GetUserGoalsByUserId(ctx context.Context, mongoUserId primitive.ObjectID, statuses []models.GoalStatus) ([]models.Goal, error) {
+	filter := bson.M{"userId": mongoUserId, "status": bson.M{"$in": statuses}}
+	documents, err := r.collection.Find(filter, options.Find().SetSort(bson.M{"goalStartDate": -1}))
+	if err != nil {
+		return nil, err
+	}
+
+	goals := []models.Goal{}
+	if err := decodeBsonDocuments(documents, &goals); err != nil {
+		return nil, err
+	}
+	return goals, nil
+}
+
+func (r *InMemoryUserGoalRepository) GetUserGoalById(ctx context.Context, goalId primitive.ObjectID) (*models.Goal, error) {
+	var userGoal models.Goal
+	if err := r.collection.FindOne(bson.M{"_id": goalId}).Decode(&userGoal); err != nil {
+		return nil, err
+	}
+	return &userGoal, nil
+}
+
+func (r *InMemoryUserGoalRepository) GetGoalOwnerId(ctx context.Context, goalId primitive.ObjectID) (primitive.ObjectID, error) {
+	var goal models.Goal
+	err := r.collection.FindOne(bson.M{"_id": goalId}, options.FindOne().SetProjection(bson.M{"userId": 1})).Decode(&goal)
+	return goal.UserId, err
+}
+
+func (r *InMemoryUserGoalRepository) DeleteMainUserGoal(ctx context.Context, goalId primitive.ObjectID) error {
+	_, err := r.collection.DeleteOne(bson.M{"_id": goalId})
+	return err
+}
+
+func (r *InMemoryUserGoalRepository) EndUserGoal(ctx context.Context, goalId primitive.ObjectID, status models.GoalStatus, outcome *models.GoalOutcome) error {
+	filter := bson.M{"_id": goalId, "status": models.GOAL_ACTIVE}
+	result, err := r.collection.UpdateOne(filter, bson.M{"$set": bson.M{"status": status, "endedAt": time.Now(), "outcome": outcome}})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+func (r *InMemoryUserGoalRepository) ArchiveUserGoal(ctx context.Context, goalId primitive.ObjectID) error {
+	filter := bson.M{"_id": goalId, "status": bson.M{"$in": []models.GoalStatus{models.GOAL_COMPLETED, models.GOAL_ABANDONED}}}
+	result, err := r.collection.UpdateOne(filter, bson.M{"$set": bson.M{"status": models.GOAL_ARCHIVED}})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+func (r *InMemoryUserGoalRepository) DeleteWeeklyUserGoal(ctx context.Context, mainGoalId primitive.ObjectID, weeklyGoalId primitive.ObjectID) error {
+	filter := bson.M{"_id": mainGoalId, "weeklyGoals._id": weeklyGoalId}
+	result, err := r.collection.UpdateOne(filter, bson.M{"$pull": bson.M{"weeklyGoals": bson.M{"_id": weeklyGoalId}}})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+func (r *InMemoryUserGoalRepository) UpdateWeeklyUserGoal(ctx context.Context, mainGoalId primitive.ObjectID, weeklyGoal *models.WeeklyGoal) error {
+	filter := bson.M{"_id": mainGoalId, "weeklyGoals._id": weeklyGoal.ID}
+	result, err := r.collection.UpdateOne(filter, bson.M{"$set": getWeeklyGoalTargetFields(weeklyGoal)})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+func (r *InMemoryUserGoalRepository) GetUserWeeklyGoal(ctx context.Context, mainGoalId primitive.ObjectID, weeklyGoalId primitive.ObjectID) (*models.Goal, error) {
+	return r.getRunningGoal(bson.M{"_id": mainGoalId, "weeklyGoals._id": weeklyGoalId})
+}
+
+func (r *InMemoryUserGoalRepository) SetWeeklyGoalWorkoutRoutine(ctx context.Context, mainGoalId primitive.ObjectID, weeklyGoalId primitive.ObjectID, routineId primitive.ObjectID) error {
+	filter := bson.M{"_id": mainGoalId, "weeklyGoals._id": weeklyGoalId}
+
+	result, err := r.collection.UpdateOne(filter, bson.M{"$set": bson.M{"weeklyGoals.$.workoutRoutineId": routineId}})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+func (r *InMemoryUserGoalRepository) LockWeeklyGoal(ctx context.Context, mainGoalId primitive.ObjectID, weeklyGoalId primitive.ObjectID) error {
+	filter := bson.M{"_id": mainGoalId, "weeklyGoals._id": weeklyGoalId}
+
+	result, err := r.collection.UpdateOne(filter, bson.M{"$set": bson.M{"weeklyGoals.$.lockId": primitive.NewObjectID()}})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
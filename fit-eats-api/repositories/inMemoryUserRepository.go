@@ -0,0 +1,189 @@
+package repositories
+
+import (
+	"context"
+	"fit-eats-api/models"
+	"regexp"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// InMemoryUserRepository keeps the users in process memory with the same queries as MongoUserRepository, for tests
+type InMemoryUserRepository struct {
+	collection *inMemoryCollection
+}
+
+func NewInMemoryUserRepository() *InMemoryUserRepository {
+	return &InMemoryUserRepository{collection: newInMemoryCollection("email")}
+}
+
+func (r *InMemoryUserRepository) CreateUser(ctx context.Context, user *models.User) error {
+	user.ID = primitive.NewObjectID()
+	user.Email = models.NormalizeEmail(user.Email)
+	return r.collection.InsertOne(user)
+}
+
+func (r *InMemoryUserRepository) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	var user models.User
+	err := r.collection.FindOne(bson.M{"email": models.NormalizeEmail(email)}, options.FindOne().SetProjection(bson.M{"_id": 1, "name": 1, "email": 1, "password": 1, "role": 1, "mfa": 1})).Decode(&user)
+	return &user, err
+}
+
+func (r *InMemoryUserRepository) GetUserByIdentity(ctx context.Context, provider string, subject string) (*models.User, error) {
+	var user models.User
+	filter := bson.M{"identities": bson.M{"$elemMatch": bson.M{"provider": provider, "subject": subject}}}
+	err := r.collection.FindOne(filter, options.FindOne().SetProjection(userCredentialsProjection)).Decode(&user)
+	return &user, err
+}
+
+func (r *InMemoryUserRepository) GetUserCredentialsById(ctx context.Context, mongoUserId primitive.ObjectID) (*models.User, error) {
+	var user models.User
+	err := r.collection.FindOne(bson.M{"_id": mongoUserId}, options.FindOne().SetProjection(userCredentialsProjection)).Decode(&user)
+	return &user, err
+}
+
+func (r *InMemoryUserRepository) GetUserProfileById(ctx context.Context, mongoUserId primitive.ObjectID) (*models.User, error) {
+	var user models.User
+	err := r.collection.FindOne(bson.M{"_id": mongoUserId}, options.FindOne().SetProjection(userProfileProjection)).Decode(&user)
+	return &user, err
+}
+
+func (r *InMemoryUserRepository) GetUserProfileByEmailId(ctx context.Context, emailId string) (*models.User, error) {
+	var user models.User
+	err := r.collection.FindOne(bson.M{"email": models.NormalizeEmail(emailId)}, options.FindOne().SetProjection(userProfileProjection)).Decode(&user)
+	return &user, err
+}
+
+func (r *InMemoryUserRepository) SearchUsers(ctx context.Context, query string, limit int64) ([]models.User, error) {
+	pattern := primitive.Regex{Pattern: regexp.QuoteMeta(query), Options: "i"}
+	filter := bson.M{"$or": bson.A{bson.M{"name": pattern}, bson.M{"email": pattern}}}
+	opts := options.Find().
+		SetProjection(userProfileProjection).
+		SetSort(bson.M{"email": 1}).
+		SetLimit(limit)
+
+	documents, err := r.collection.Find(filter, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	users := []models.User{}
+	if err := decodeBsonDocuments(documents, &users); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+func (r *InMemoryUserRepository) UpdateUser(ctx context.Context, userID primitive.ObjectID, updateData bson.M) error {
+	_, err := r.collection.UpdateOne(bson.M{"_id": userID}, bson.M{"$set": updateData})
+	return err
+}
+
+func (r *InMemoryUserRepository) UnsetUserFields(ctx context.Context, userID primitive.ObjectID, fields ...string) error {
+	unset := bson.M{}
+	for _, field := range fields {
+		unset[field] = ""
+	}
+
+	_, err := r.collection.UpdateOne(bson.M{"_id": userID}, bson.M{"$unset": unset})
+	return err
+}
+
+func (r *InMemoryUserRepository) SetMfaLastUsedCounter(ctx context.Context, userID primitive.ObjectID, counter int64) error {
+	filter := bson.M{"_id": userID, "$or": bson.A{
+		bson.M{"mfa.lastUsedCounter": bson.M{"$lt": counter}},
+		bson.M{"mfa.lastUsedCounter": bson.M{"$exists": false}},
+	}}
+
+	result, err := r.collection.UpdateOne(filter, bson.M{"$set": bson.M{"mfa.lastUsedCounter": counter}})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+func (r *InMemoryUserRepository) ConsumeRecoveryCode(ctx context.Context, userID primitive.ObjectID, codeHash string) error {
+	filter := bson.M{"_id": userID, "mfa.recoveryCodeHashes": codeHash}
+
+	result, err := r.collection.UpdateOne(filter, bson.M{"$pull": bson.M{"mfa.recoveryCodeHashes": codeHash}})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+func (r *InMemoryUserRepository) AddUserIdentity(ctx context.Context, userID primitive.ObjectID, identity models.Identity) error {
+	filter := bson.M{"_id": userID, "identities.provider": bson.M{"$ne": identity.Provider}}
+
+	result, err := r.collection.UpdateOne(filter, bson.M{"$push": bson.M{"identities": identity}})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+func (r *InMemoryUserRepository) RemoveUserIdentity(ctx context.Context, userID primitive.ObjectID, provider string) error {
+	result, err := r.collection.UpdateOne(bson.M{"_id": userID}, bson.M{"$pull": bson.M{"identities": bson.M{"provider": provider}}})
+	if err != nil {
+		return err
+	}
+	if result.ModifiedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+func (r *InMemoryUserRepository) ScheduleUserDeletion(ctx context.Context, userID primitive.ObjectID, requestedAt time.Time, scheduledAt time.Time) error {
+	filter := bson.M{"_id": userID, "deletionScheduledAt": bson.M{"$exists": false}}
+	update := bson.M{"$set": bson.M{"deletionRequestedAt": requestedAt, "deletionScheduledAt": scheduledAt}}
+
+	result, err := r.collection.UpdateOne(filter, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+func (r *InMemoryUserRepository) CancelUserDeletion(ctx context.Context, userID primitive.ObjectID) error {
+	filter := bson.M{"_id": userID, "deletionScheduledAt": bson.M{"$exists": true}}
+	update := bson.M{"$unset": bson.M{"deletionRequestedAt": "", "deletionScheduledAt": ""}}
+
+	result, err := r.collection.UpdateOne(filter, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+func (r *InMemoryUserRepository) GetUsersDueForDeletion(ctx context.Context, now time.Time) ([]models.User, error) {
+	opts := options.Find().SetProjection(bson.M{"_id": 1, "email": 1, "deletionScheduledAt": 1})
+	documents, err := r.collection.Find(bson.M{"deletionScheduledAt": bson.M{"$lte": now}}, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	users := []models.User{}
+	if err := decodeBsonDocuments(documents, &users); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
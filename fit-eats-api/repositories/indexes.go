@@ -0,0 +1,106 @@
+package repositories
+
+import (
+	"context"
+	"fit-eats-api/models"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// collectionIndexes are the indexes the queries of the repositories rely on, keyed by collection.
+// Every index is named so a changed definition shows up as a conflict instead of a second index.
+// The migrations create them, each migration keeps its own copy of the indexes it adds so that a database
+// migrating later builds the same indexes, in the same order as the data changes between them
+var collectionIndexes = map[string][]mongo.IndexModel{
+	"users": {
+		NewIndex("email_unique", bson.D{{Key: "email", Value: 1}}, options.Index().SetUnique(true)),
+		NewIndex("identities_provider_subject", bson.D{{Key: "identities.provider", Value: 1}, {Key: "identities.subject", Value: 1}}, nil),
+		NewIndex("deletionScheduledAt", bson.D{{Key: "deletionScheduledAt", Value: 1}}, options.Index().SetSparse(true)),
+	},
+	// A user has a single active goal, ended goals are kept as history
+	"userGoals": {
+		NewIndex("userId_goalStartDate", bson.D{{Key: "userId", Value: 1}, {Key: "goalStartDate", Value: -1}}, nil),
+		NewIndex("userId_active_unique", bson.D{{Key: "userId", Value: 1}},
+			options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{"status": models.GOAL_ACTIVE})),
+	},
+	// A weekly goal has a single meal plan
+	"meals": {
+		NewIndex("weeklyGoalId_unique", bson.D{{Key: "weeklyGoalId", Value: 1}}, options.Index().SetUnique(true)),
+		NewIndex("userId_weeklyGoalId_mainGoalId", bson.D{{Key: "userId", Value: 1}, {Key: "weeklyGoalId", Value: 1}, {Key: "mainGoalId", Value: 1}}, nil),
+		NewIndex("mainGoalId_weeklyGoalId", bson.D{{Key: "mainGoalId", Value: 1}, {Key: "weeklyGoalId", Value: 1}}, nil),
+		NewIndex("userId_dayMeals_date", bson.D{{Key: "userId", Value: 1}, {Key: "dayMeals.date", Value: 1}}, nil),
+		NewIndex("dayMeals_meals_id", bson.D{{Key: "dayMeals.meals._id", Value: 1}}, nil),
+	},
+	"workoutRoutines": {
+		NewIndex("userId_weeklyGoalId_mainGoalId", bson.D{{Key: "userId", Value: 1}, {Key: "weeklyGoalId", Value: 1}, {Key: "mainGoalId", Value: 1}}, nil),
+		NewIndex("userId_workoutDays_date", bson.D{{Key: "userId", Value: 1}, {Key: "workoutDays.date", Value: 1}}, nil),
+	},
+	"hydrationLogs": {
+		NewIndex("userId_loggedAt", bson.D{{Key: "userId", Value: 1}, {Key: "loggedAt", Value: 1}}, nil),
+	},
+	"workoutSessions": {
+		NewIndex("userId_date", bson.D{{Key: "userId", Value: 1}, {Key: "date", Value: 1}}, nil),
+	},
+	"activityLogs": {
+		NewIndex("userId_date", bson.D{{Key: "userId", Value: 1}, {Key: "date", Value: 1}}, nil),
+	},
+	"coachLinks": {
+		NewIndex("coachId_clientId", bson.D{{Key: "coachId", Value: 1}, {Key: "clientId", Value: 1}}, nil),
+		NewIndex("clientId_status", bson.D{{Key: "clientId", Value: 1}, {Key: "status", Value: 1}}, nil),
+	},
+	"auditLogs": {
+		NewIndex("userId_createdAt", bson.D{{Key: "userId", Value: 1}, {Key: "createdAt", Value: -1}}, nil),
+		NewIndex("actor_id_createdAt", bson.D{{Key: "actor.id", Value: 1}, {Key: "createdAt", Value: -1}}, nil),
+		NewIndex("createdAt", bson.D{{Key: "createdAt", Value: -1}}, nil),
+	},
+	// Expired sessions, tokens and login states are removed by Mongo, they can no longer be used anyway
+	"sessions": {
+		NewIndex("userId", bson.D{{Key: "userId", Value: 1}}, nil),
+		NewIndex("expiresAt_ttl", bson.D{{Key: "expiresAt", Value: 1}}, options.Index().SetExpireAfterSeconds(0)),
+	},
+	"userTokens": {
+		NewIndex("tokenHash", bson.D{{Key: "tokenHash", Value: 1}}, nil),
+		NewIndex("userId_purpose", bson.D{{Key: "userId", Value: 1}, {Key: "purpose", Value: 1}}, nil),
+		NewIndex("expiresAt_ttl", bson.D{{Key: "expiresAt", Value: 1}}, options.Index().SetExpireAfterSeconds(0)),
+	},
+	"oidcStates": {
+		NewIndex("stateHash", bson.D{{Key: "stateHash", Value: 1}}, nil),
+		NewIndex("expiresAt_ttl", bson.D{{Key: "expiresAt", Value: 1}}, options.Index().SetExpireAfterSeconds(0)),
+	},
+	// Failed login counters are removed once their failure window or lock ends
+	"loginAttempts": {
+		NewIndex("expireAt_ttl", bson.D{{Key: "expireAt", Value: 1}}, options.Index().SetExpireAfterSeconds(0)),
+	},
+	// Expired exports also have a file to remove, DeleteExpiredDataExports cleans them up instead of a ttl index
+	"dataExports": {
+		NewIndex("userId_status_createdAt", bson.D{{Key: "userId", Value: 1}, {Key: "status", Value: 1}, {Key: "createdAt", Value: 1}}, nil),
+		NewIndex("expiresAt", bson.D{{Key: "expiresAt", Value: 1}}, nil),
+	},
+}
+
+// NewIndex names the index, the options may be nil
+func NewIndex(name string, keys bson.D, opts *options.IndexOptions) mongo.IndexModel {
+	if opts == nil {
+		opts = options.Index()
+	}
+	return mongo.IndexModel{Keys: keys, Options: opts.SetName(name)}
+}
+
+// CreateIndexes creates the current indexes of every collection, the databases of the repository tests start from them
+func CreateIndexes(ctx context.Context, db *mongo.Database) error {
+	return CreateCollectionIndexes(ctx, db, collectionIndexes)
+}
+
+// CreateCollectionIndexes creates the indexes keyed by collection, indexes which already exist are left as they are.
+// Building a unique index fails while the collection holds duplicates, they have to be resolved first
+func CreateCollectionIndexes(ctx context.Context, db *mongo.Database, indexesByCollection map[string][]mongo.IndexModel) error {
+	for collection, indexes := range indexesByCollection {
+		if _, err := db.Collection(collection).Indexes().CreateMany(ctx, indexes); err != nil {
+			return fmt.Errorf("could not create the indexes of %s: %w", collection, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package repositories
+
+import (
+	"context"
+	"fit-eats-api/models"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// LoginAttemptStore keeps failed login counters keyed by account or ip
+type LoginAttemptStore interface {
+	// GetLoginAttempt returns nil when there were no failures for the key
+	GetLoginAttempt(ctx context.Context, key string) (*models.LoginAttempt, error)
+	// RecordFailure increments the failures for the key and returns the updated counter, a stale counter
+	// starts again from one and loses its ended lock
+	RecordFailure(ctx context.Context, key string, policy models.LoginPolicy, failedAt time.Time) (*models.LoginAttempt, error)
+	LockUntil(ctx context.Context, key string, lockedUntil time.Time) error
+	ResetLoginAttempt(ctx context.Context, key string) error
+}
+
+type MongoLoginAttemptStore struct {
+	Collection *mongo.Collection
+}
+
+func NewMongoLoginAttemptStore(db *mongo.Database) *MongoLoginAttemptStore {
+	return &MongoLoginAttemptStore{
+		Collection: db.Collection("loginAttempts"),
+	}
+}
+
+func (s *MongoLoginAttemptStore) GetLoginAttempt(ctx context.Context, key string) (*models.LoginAttempt, error) {
+	var attempt models.LoginAttempt
+	err := s.Collection.FindOne(ctx, bson.M{"_id": key}).Decode(&attempt)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &attempt, nil
+}
+
+func (s *MongoLoginAttemptStore) RecordFailure(ctx context.Context, key string, policy models.LoginPolicy, failedAt time.Time) (*models.LoginAttempt, error) {
+	// The same rule as LoginAttempt.IsStale, evaluated in the update so concurrent failures are counted atomically
+	lockEnded := bson.M{"$and": bson.A{
+		bson.M{"$ne": bson.A{bson.M{"$type": "$lockedUntil"}, "missing"}},
+		bson.M{"$lte": bson.A{"$lockedUntil", failedAt}},
+	}}
+	isStale := bson.M{"$or": bson.A{
+		lockEnded,
+		bson.M{"$lt": bson.A{bson.M{"$ifNull": bson.A{"$lastFailedAt", nil}}, failedAt.Add(-policy.FailureWindow)}},
+	}}
+	update := mongo.Pipeline{{{Key: "$set", Value: bson.M{
+		"failedCount":  bson.M{"$cond": bson.A{isStale, 1, bson.M{"$add": bson.A{"$failedCount", 1}}}},
+		"lastFailedAt": failedAt,
+		"lockedUntil":  bson.M{"$cond": bson.A{lockEnded, "$$REMOVE", "$lockedUntil"}},
+		"expireAt":     bson.M{"$max": bson.A{"$lockedUntil", failedAt.Add(policy.FailureWindow)}},
+	}}}}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var attempt models.LoginAttempt
+	err := s.Collection.FindOneAndUpdate(ctx, bson.M{"_id": key}, update, opts).Decode(&attempt)
+	if err != nil {
+		return nil, err
+	}
+	return &attempt, nil
+}
+
+func (s *MongoLoginAttemptStore) LockUntil(ctx context.Context, key string, lockedUntil time.Time) error {
+	update := bson.M{"$set": bson.M{"lockedUntil": lockedUntil}, "$max": bson.M{"expireAt": lockedUntil}}
+	_, err := s.Collection.UpdateOne(ctx, bson.M{"_id": key}, update)
+	return err
+}
+
+func (s *MongoLoginAttemptStore) ResetLoginAttempt(ctx context.Context, key string) error {
+	_, err := s.Collection.DeleteOne(ctx, bson.M{"_id": key})
+	return err
+}
+
+// InMemoryLoginAttemptStore keeps counters in process memory, for tests and single instance deployments
+type InMemoryLoginAttemptStore struct {
+	mutex    sync.Mutex
+	attempts map[string]models.LoginAttempt
+}
+
+func NewInMemoryLoginAttemptStore() *InMemoryLoginAttemptStore {
+	return &InMemoryLoginAttemptStore{attempts: map[string]models.LoginAttempt{}}
+}
+
+func (s *InMemoryLoginAttemptStore) GetLoginAttempt(ctx context.Context, key string) (*models.LoginAttempt, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	attempt, ok := s.attempts[key]
+	if !ok {
+		return nil, nil
+	}
+	return &attempt, nil
+}
+
+func (s *InMemoryLoginAttemptStore) RecordFailure(ctx context.Context, key string, policy models.LoginPolicy, failedAt time.Time) (*models.LoginAttempt, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	attempt, ok := s.attempts[key]
+	if !ok || attempt.IsStale(policy, failedAt) {
+		attempt = models.LoginAttempt{Key: key}
+	}
+	attempt.FailedCount++
+	attempt.LastFailedAt = failedAt
+	attempt.ExpireAt = failedAt.Add(policy.FailureWindow)
+	s.attempts[key] = attempt
+	return &attempt, nil
+}
+
+func (s *InMemoryLoginAttemptStore) LockUntil(ctx context.Context, key string, lockedUntil time.Time) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	attempt, ok := s.attempts[key]
+	if !ok {
+		return nil
+	}
+	attempt.LockedUntil = &lockedUntil
+	if lockedUntil.After(attempt.ExpireAt) {
+		attempt.ExpireAt = lockedUntil
+	}
+	s.attempts[key] = attempt
+	return nil
+}
+
+func (s *InMemoryLoginAttemptStore) ResetLoginAttempt(ctx context.Context, key string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.attempts, key)
+	return nil
+}
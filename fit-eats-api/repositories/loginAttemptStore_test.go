@@ -0,0 +1,92 @@
+package repositories
+
+import (
+	"context"
+	"fit-eats-api/models"
+	"testing"
+	"time"
+)
+
+var testLoginPolicy = models.LoginPolicy{
+	FreeAttempts:     1,
+	BaseDelay:        time.Second,
+	MaxDelay:         time.Minute,
+	LockoutThreshold: 3,
+	LockoutDuration:  time.Hour,
+	FailureWindow:    time.Hour,
+}
+
+func recordTestFailure(t *testing.T, store LoginAttemptStore, failedAt time.Time) *models.LoginAttempt {
+	t.Helper()
+	attempt, err := store.RecordFailure(context.Background(), "account:asha@fiteats.test", testLoginPolicy, failedAt)
+	expectNoError(t, err)
+	return attempt
+}
+
+func TestLoginAttemptStoreCountsFailuresInWindow(t *testing.T) {
+	runRepositoryTest(t, func(t *testing.T, stores repositoryStores) {
+		start := time.Now().Truncate(time.Millisecond)
+
+		recordTestFailure(t, stores.loginAttempts, start)
+		attempt := recordTestFailure(t, stores.loginAttempts, start.Add(50*time.Minute))
+		if attempt.FailedCount != 2 || attempt.ShouldLock(testLoginPolicy, start.Add(50*time.Minute)) {
+			t.Fatalf("expected 2 failures without a lock, got %+v", attempt)
+		}
+		if !attempt.ExpireAt.Equal(toStoredTime(start.Add(110 * time.Minute))) {
+			t.Errorf("expected the counter to expire an hour after the last failure, got %v", attempt.ExpireAt)
+		}
+
+		// The previous failure left the window, the count starts again
+		attempt = recordTestFailure(t, stores.loginAttempts, start.Add(3*time.Hour))
+		if attempt.FailedCount != 1 {
+			t.Fatalf("expected the count to restart after the window, got %d", attempt.FailedCount)
+		}
+	})
+}
+
+func TestLoginAttemptStoreRestartsAfterLock(t *testing.T) {
+	runRepositoryTest(t, func(t *testing.T, stores repositoryStores) {
+		ctx := context.Background()
+		start := time.Now().Truncate(time.Millisecond)
+
+		var attempt *models.LoginAttempt
+		for i := 0; i < 3; i++ {
+			attempt = recordTestFailure(t, stores.loginAttempts, start.Add(time.Duration(i)*time.Minute))
+		}
+		lockedAt := start.Add(2 * time.Minute)
+		if !attempt.ShouldLock(testLoginPolicy, lockedAt) {
+			t.Fatalf("expected the third failure to lock, got %+v", attempt)
+		}
+		expectNoError(t, stores.loginAttempts.LockUntil(ctx, attempt.Key, lockedAt.Add(testLoginPolicy.LockoutDuration)))
+
+		attempt, err := stores.loginAttempts.GetLoginAttempt(ctx, attempt.Key)
+		expectNoError(t, err)
+		if !attempt.IsLocked(lockedAt) || attempt.GetRetryAfter(testLoginPolicy, lockedAt) != time.Hour {
+			t.Fatalf("expected the key to be locked for an hour, got %+v", attempt)
+		}
+		if !attempt.ExpireAt.Equal(toStoredTime(lockedAt.Add(time.Hour))) {
+			t.Errorf("expected the counter to expire with the lock, got %v", attempt.ExpireAt)
+		}
+
+		// The first failure after the lock ended is not enough to lock the key again
+		afterLock := lockedAt.Add(time.Hour + time.Minute)
+		attempt = recordTestFailure(t, stores.loginAttempts, afterLock)
+		if attempt.FailedCount != 1 || attempt.LockedUntil != nil || attempt.ShouldLock(testLoginPolicy, afterLock) {
+			t.Fatalf("expected the count to restart without the ended lock, got %+v", attempt)
+		}
+	})
+}
+
+func TestLoginAttemptStoreReset(t *testing.T) {
+	runRepositoryTest(t, func(t *testing.T, stores repositoryStores) {
+		ctx := context.Background()
+		attempt := recordTestFailure(t, stores.loginAttempts, time.Now())
+
+		expectNoError(t, stores.loginAttempts.ResetLoginAttempt(ctx, attempt.Key))
+		attempt, err := stores.loginAttempts.GetLoginAttempt(ctx, attempt.Key)
+		expectNoError(t, err)
+		if attempt != nil {
+			t.Fatalf("expected the counter to be removed, got %+v", attempt)
+		}
+	})
+}
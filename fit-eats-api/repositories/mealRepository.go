@@ -12,22 +12,47 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-type MealRepository struct {
+// MealRepository stores the weekly meal plans, the finds return nil when there is no meal plan
+type MealRepository interface {
+	CreateWeeklyMealPlan(ctx context.Context, mealPlan *models.MealPlan) error
+	UpdateSingleDayMeal(ctx context.Context, mealPlanId primitive.ObjectID, dayMealId primitive.ObjectID, meals []models.Meal) error
+	IsWeeklyMealPlanCreated(ctx context.Context, userId primitive.ObjectID, weeklyGoalId primitive.ObjectID) bool
+	GetWeeklyMealPlan(ctx context.Context, userId primitive.ObjectID, mainGoalId primitive.ObjectID, weeklyGoalId primitive.ObjectID) (*models.MealPlan, error)
+	GetSingleDayMeal(ctx context.Context, mainGoalId primitive.ObjectID, weeklyGoalId primitive.ObjectID, dayMealId primitive.ObjectID) (*models.DayMeal, error)
+	// GetSingleDayMealByDate returns today's meals of the user
+	GetSingleDayMealByDate(ctx context.Context, userId primitive.ObjectID) (*models.DayMeal, error)
+	// GetMealPlanMeta returns the meal plan without its day meals
+	GetMealPlanMeta(ctx context.Context, mealPlanId primitive.ObjectID) (*models.MealPlan, error)
+	GetMealPlanById(ctx context.Context, mealPlanId primitive.ObjectID) (*models.MealPlan, error)
+	GetMealPlanByMealId(ctx context.Context, mealId primitive.ObjectID) (*models.MealPlan, error)
+	GetMealPlansByUserId(ctx context.Context, userId primitive.ObjectID) ([]models.MealPlan, error)
+	GetMealPlansByMainGoalId(ctx context.Context, mainGoalId primitive.ObjectID) ([]models.MealPlan, error)
+	DeleteMealPlan(ctx context.Context, mealPlanId primitive.ObjectID) error
+	// DeleteMealPlansByMainGoalId removes the meal plans of every weekly goal of the main goal
+	DeleteMealPlansByMainGoalId(ctx context.Context, mainGoalId primitive.ObjectID) (int64, error)
+	DeleteMealPlansByWeeklyGoalId(ctx context.Context, mainGoalId primitive.ObjectID, weeklyGoalId primitive.ObjectID) (int64, error)
+	// HasConsumedMeals is true when a meal of the meal plan of the weekly goal is marked consumed
+	HasConsumedMeals(ctx context.Context, mainGoalId primitive.ObjectID, weeklyGoalId primitive.ObjectID) (bool, error)
+	GetMealOwnerId(ctx context.Context, mealId primitive.ObjectID) (primitive.ObjectID, error)
+	SetMealConsumed(ctx context.Context, mealPlanId primitive.ObjectID, dayMealId primitive.ObjectID, mealId primitive.ObjectID, isConsumed bool) error
+}
+
+type MongoMealRepository struct {
 	Collection *mongo.Collection
 }
 
-func NewMealRepository(db *mongo.Database) *MealRepository {
-	return &MealRepository{
+func NewMongoMealRepository(db *mongo.Database) *MongoMealRepository {
+	return &MongoMealRepository{
 		Collection: db.Collection("meals"),
 	}
 }
 
-func (r *MealRepository) CreateWeeklyMealPlan(ctx context.Context, mealPlan *models.MealPlan) error {
+func (r *MongoMealRepository) CreateWeeklyMealPlan(ctx context.Context, mealPlan *models.MealPlan) error {
 	_, err := r.Collection.InsertOne(ctx, mealPlan)
 	return err
 }
 
-func (r *MealRepository) UpdateSingleDayMeal(ctx context.Context, mealPlanId primitive.ObjectID,
+func (r *MongoMealRepository) UpdateSingleDayMeal(ctx context.Context, mealPlanId primitive.ObjectID,
 	dayMealId primitive.ObjectID, meals []models.Meal) error {
 	filter := bson.M{"_id": mealPlanId, "dayMeals._id": dayMealId} // Find by ID
 
@@ -39,14 +64,14 @@ func (r *MealRepository) UpdateSingleDayMeal(ctx context.Context, mealPlanId pri
 	return err
 }
 
-func (r *MealRepository) IsWeeklyMealPlanCreated(ctx context.Context, userId primitive.ObjectID, weeklyGoalId primitive.ObjectID) bool {
+func (r *MongoMealRepository) IsWeeklyMealPlanCreated(ctx context.Context, userId primitive.ObjectID, weeklyGoalId primitive.ObjectID) bool {
 	var mealPlan models.MealPlan
 	err := r.Collection.FindOne(ctx, bson.M{"userId": userId, "weeklyGoalId": weeklyGoalId}, options.FindOne().SetProjection(bson.M{"dayMeals": 0})).Decode(&mealPlan)
 
 	return err == nil
 }
 
-func (r *MealRepository) GetWeeklyMealPlan(ctx context.Context, userId primitive.ObjectID, mainGoalId primitive.ObjectID, weeklyGoalId primitive.ObjectID) (*models.MealPlan, error) {
+func (r *MongoMealRepository) GetWeeklyMealPlan(ctx context.Context, userId primitive.ObjectID, mainGoalId primitive.ObjectID, weeklyGoalId primitive.ObjectID) (*models.MealPlan, error) {
 	var mealPlan models.MealPlan
 
 	err := r.Collection.FindOne(ctx, bson.M{"userId": userId, "mainGoalId": mainGoalId, "weeklyGoalId": weeklyGoalId}).Decode(&mealPlan)
@@ -60,7 +85,7 @@ func (r *MealRepository) GetWeeklyMealPlan(ctx context.Context, userId primitive
 	return &mealPlan, nil
 }
 
-func (r *MealRepository) GetSingleDayMeal(ctx context.Context, mainGoalId primitive.ObjectID, weeklyGoalId primitive.ObjectID, dayMealId primitive.ObjectID) (*models.DayMeal, error) {
+func (r *MongoMealRepository) GetSingleDayMeal(ctx context.Context, mainGoalId primitive.ObjectID, weeklyGoalId primitive.ObjectID, dayMealId primitive.ObjectID) (*models.DayMeal, error) {
 	var mealPlan models.DayMeal
 
 	err := r.Collection.FindOne(ctx, bson.M{"mainGoalId": mainGoalId, "weeklyGoalId": weeklyGoalId, "dayMeals._id": dayMealId}).Decode(&mealPlan)
@@ -74,11 +99,11 @@ func (r *MealRepository) GetSingleDayMeal(ctx context.Context, mainGoalId primit
 	return &mealPlan, nil
 }
 
-func (r *MealRepository) GetSingleDayMealByDate(ctx context.Context, userId primitive.ObjectID) (*models.DayMeal, error) {
-
-	loc, err1 := time.LoadLocation("Asia/Kolkata")
-	if err1 != nil {
-		panic(err1)
+// getMealDayRange returns the bounds of today, meal plan days follow the Indian time zone
+func getMealDayRange() (time.Time, time.Time) {
+	loc, err := time.LoadLocation("Asia/Kolkata")
+	if err != nil {
+		panic(err)
 	}
 
 	nowIST := time.Now().In(loc)
@@ -90,7 +115,11 @@ func (r *MealRepository) GetSingleDayMealByDate(ctx context.Context, userId prim
 		0, 0, 0, 0,
 		loc,
 	)
-	endOfDay := startOfDay.Add(24 * time.Hour)
+	return startOfDay, startOfDay.Add(24 * time.Hour)
+}
+
+func (r *MongoMealRepository) GetSingleDayMealByDate(ctx context.Context, userId primitive.ObjectID) (*models.DayMeal, error) {
+	startOfDay, endOfDay := getMealDayRange()
 
 	fmt.Println(startOfDay.String())
 	fmt.Println(endOfDay.String())
@@ -136,7 +165,7 @@ func (r *MealRepository) GetSingleDayMealByDate(ctx context.Context, userId prim
 	return &result.DayMeals[0], nil
 }
 
-func (r *MealRepository) GetMealPlanMeta(ctx context.Context, mealPlanId primitive.ObjectID) (*models.MealPlan, error) {
+func (r *MongoMealRepository) GetMealPlanMeta(ctx context.Context, mealPlanId primitive.ObjectID) (*models.MealPlan, error) {
 	var mealPlan models.MealPlan
 
 	err := r.Collection.FindOne(ctx, bson.M{"_id": mealPlanId}, options.FindOne().SetProjection(bson.M{"dayMeals": 0})).Decode(&mealPlan)
@@ -150,17 +179,129 @@ func (r *MealRepository) GetMealPlanMeta(ctx context.Context, mealPlanId primiti
 	return &mealPlan, nil
 }
 
-func (r *MealRepository) ConsumeSingleMeal(ctx context.Context, mealId primitive.ObjectID) error {
-	filter := bson.M{"dayMeals.meals._id": mealId}
+func (r *MongoMealRepository) GetMealPlanById(ctx context.Context, mealPlanId primitive.ObjectID) (*models.MealPlan, error) {
+	var mealPlan models.MealPlan
 
-	update := bson.M{"$set": bson.M{"dayMeals.$[].meals.$[meal].isConsumed": true}}
+	err := r.Collection.FindOne(ctx, bson.M{"_id": mealPlanId}).Decode(&mealPlan)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
 
-	options := options.Update().SetArrayFilters(options.ArrayFilters{
-		Filters: []interface{}{
-			bson.M{"meal._id": mealId},
-		},
-	})
+	return &mealPlan, nil
+}
 
-	_, err := r.Collection.UpdateOne(ctx, filter, update, options)
-	return err
+// GetMealPlanByMealId returns the meal plan containing the meal, nil when there is none
+func (r *MongoMealRepository) GetMealPlanByMealId(ctx context.Context, mealId primitive.ObjectID) (*models.MealPlan, error) {
+	var mealPlan models.MealPlan
+
+	err := r.Collection.FindOne(ctx, bson.M{"dayMeals.meals._id": mealId}).Decode(&mealPlan)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &mealPlan, nil
+}
+
+// GetMealPlansByUserId returns every meal plan of the user, oldest first
+func (r *MongoMealRepository) GetMealPlansByUserId(ctx context.Context, userId primitive.ObjectID) ([]models.MealPlan, error) {
+	cursor, err := r.Collection.Find(ctx, bson.M{"userId": userId}, options.Find().SetSort(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	mealPlans := []models.MealPlan{}
+	if err := cursor.All(ctx, &mealPlans); err != nil {
+		return nil, err
+	}
+	return mealPlans, nil
+}
+
+// GetMealPlansByMainGoalId returns the meal plans of every weekly goal of the main goal, oldest first
+func (r *MongoMealRepository) GetMealPlansByMainGoalId(ctx context.Context, mainGoalId primitive.ObjectID) ([]models.MealPlan, error) {
+	cursor, err := r.Collection.Find(ctx, bson.M{"mainGoalId": mainGoalId}, options.Find().SetSort(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	mealPlans := []models.MealPlan{}
+	if err := cursor.All(ctx, &mealPlans); err != nil {
+		return nil, err
+	}
+	return mealPlans, nil
+}
+
+func (r *MongoMealRepository) DeleteMealPlan(ctx context.Context, mealPlanId primitive.ObjectID) error {
+	result, err := r.Collection.DeleteOne(ctx, bson.M{"_id": mealPlanId})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+func (r *MongoMealRepository) DeleteMealPlansByMainGoalId(ctx context.Context, mainGoalId primitive.ObjectID) (int64, error) {
+	result, err := r.Collection.DeleteMany(ctx, bson.M{"mainGoalId": mainGoalId})
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
+
+func (r *MongoMealRepository) DeleteMealPlansByWeeklyGoalId(ctx context.Context, mainGoalId primitive.ObjectID, weeklyGoalId primitive.ObjectID) (int64, error) {
+	result, err := r.Collection.DeleteMany(ctx, bson.M{"mainGoalId": mainGoalId, "weeklyGoalId": weeklyGoalId})
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
+
+func (r *MongoMealRepository) HasConsumedMeals(ctx context.Context, mainGoalId primitive.ObjectID, weeklyGoalId primitive.ObjectID) (bool, error) {
+	filter := bson.M{"mainGoalId": mainGoalId, "weeklyGoalId": weeklyGoalId, "dayMeals.meals.isConsumed": true}
+	count, err := r.Collection.CountDocuments(ctx, filter, options.Count().SetLimit(1))
+	return count > 0, err
+}
+
+// GetMealOwnerId returns the user whose meal plan contains the meal
+func (r *MongoMealRepository) GetMealOwnerId(ctx context.Context, mealId primitive.ObjectID) (primitive.ObjectID, error) {
+	var mealPlan models.MealPlan
+	err := r.Collection.FindOne(ctx, bson.M{"dayMeals.meals._id": mealId}, options.FindOne().SetProjection(bson.M{"userId": 1})).Decode(&mealPlan)
+	return mealPlan.UserId, err
+}
+
+// SetMealConsumed marks the meal consumed or not, the meal plan and day meal ids are optional and narrow the match
+// when the meal is addressed by its full path. Returns mongo.ErrNoDocuments when no meal matches
+func (r *MongoMealRepository) SetMealConsumed(ctx context.Context, mealPlanId primitive.ObjectID, dayMealId primitive.ObjectID, mealId primitive.ObjectID, isConsumed bool) error {
+	filter := bson.M{"dayMeals.meals._id": mealId}
+	path := "dayMeals.$[].meals.$[meal].isConsumed"
+	arrayFilters := []interface{}{bson.M{"meal._id": mealId}}
+	if !mealPlanId.IsZero() {
+		filter["_id"] = mealPlanId
+	}
+	if !dayMealId.IsZero() {
+		filter["dayMeals"] = bson.M{"$elemMatch": bson.M{"_id": dayMealId, "meals._id": mealId}}
+		path = "dayMeals.$[dayMeal].meals.$[meal].isConsumed"
+		arrayFilters = append(arrayFilters, bson.M{"dayMeal._id": dayMealId})
+	}
+
+	update := bson.M{"$set": bson.M{path: isConsumed}}
+	options := options.Update().SetArrayFilters(options.ArrayFilters{Filters: arrayFilters})
+
+	result, err := r.Collection.UpdateOne(ctx, filter, update, options)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
 }
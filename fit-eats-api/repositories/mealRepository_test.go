@@ -0,0 +1,213 @@
+package repositories
+
+import (
+	"context"
+	"fit-eats-api/models"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// createTestMealPlan creates a meal plan starting yesterday, with the shared meal planned on every day
+func createTestMealPlan(t *testing.T, meals MealRepository, userId primitive.ObjectID, sharedMealId primitive.ObjectID) *models.MealPlan {
+	t.Helper()
+	startOfDay, _ := getMealDayRange()
+	mealPlan := &models.MealPlan{ID: primitive.NewObjectID(), UserId: userId, MainGoalId: primitive.NewObjectID(), WeeklyGoalId: primitive.NewObjectID()}
+
+	for day := -1; day < 2; day++ {
+		mealPlan.DayMeals = append(mealPlan.DayMeals, models.DayMeal{
+			ID:   primitive.NewObjectID(),
+			Date: startOfDay.AddDate(0, 0, day).Add(8 * time.Hour),
+			Meals: []models.Meal{
+				{ID: primitive.NewObjectID(), Name: "Oats", Calories: 350},
+				{ID: sharedMealId, Name: "Dal", Calories: 500},
+			},
+		})
+	}
+	expectNoError(t, meals.CreateWeeklyMealPlan(context.Background(), mealPlan))
+	return mealPlan
+}
+
+func TestMealRepositoryMealPlans(t *testing.T) {
+	runRepositoryTest(t, func(t *testing.T, stores repositoryStores) {
+		ctx := context.Background()
+		userId := primitive.NewObjectID()
+		mealPlan := createTestMealPlan(t, stores.meals, userId, primitive.NewObjectID())
+		other := createTestMealPlan(t, stores.meals, userId, primitive.NewObjectID())
+
+		found, err := stores.meals.GetWeeklyMealPlan(ctx, userId, mealPlan.MainGoalId, mealPlan.WeeklyGoalId)
+		expectNoError(t, err)
+		if found == nil || found.ID != mealPlan.ID || len(found.DayMeals) != 3 || found.DayMeals[1].Meals[1].Name != "Dal" {
+			t.Fatalf("unexpected meal plan: %+v", found)
+		}
+		if !stores.meals.IsWeeklyMealPlanCreated(ctx, userId, mealPlan.WeeklyGoalId) || stores.meals.IsWeeklyMealPlanCreated(ctx, userId, primitive.NewObjectID()) {
+			t.Error("IsWeeklyMealPlanCreated does not match the meal plans")
+		}
+
+		meta, err := stores.meals.GetMealPlanMeta(ctx, mealPlan.ID)
+		expectNoError(t, err)
+		if meta == nil || meta.UserId != userId || meta.DayMeals != nil {
+			t.Errorf("expected the meal plan without its days, got %+v", meta)
+		}
+
+		byMeal, err := stores.meals.GetMealPlanByMealId(ctx, other.DayMeals[2].Meals[0].ID)
+		expectNoError(t, err)
+		if byMeal == nil || byMeal.ID != other.ID {
+			t.Errorf("expected meal plan %s, got %+v", other.ID.Hex(), byMeal)
+		}
+		ownerId, err := stores.meals.GetMealOwnerId(ctx, other.DayMeals[0].Meals[0].ID)
+		expectNoError(t, err)
+		if ownerId != userId {
+			t.Errorf("expected owner %s, got %s", userId.Hex(), ownerId.Hex())
+		}
+
+		plans, err := stores.meals.GetMealPlansByUserId(ctx, userId)
+		expectNoError(t, err)
+		if len(plans) != 2 || plans[0].ID != mealPlan.ID || plans[1].ID != other.ID {
+			t.Errorf("expected both meal plans oldest first, got %d", len(plans))
+		}
+
+		// Missing meal plans are nil rather than errors
+		missing, err := stores.meals.GetMealPlanById(ctx, primitive.NewObjectID())
+		expectNoError(t, err)
+		if missing != nil {
+			t.Errorf("expected no meal plan, got %+v", missing)
+		}
+		_, err = stores.meals.GetMealOwnerId(ctx, primitive.NewObjectID())
+		expectNoDocuments(t, err)
+
+		expectNoError(t, stores.meals.DeleteMealPlan(ctx, mealPlan.ID))
+		expectNoDocuments(t, stores.meals.DeleteMealPlan(ctx, mealPlan.ID))
+		plans, err = stores.meals.GetMealPlansByUserId(ctx, userId)
+		expectNoError(t, err)
+		if len(plans) != 1 {
+			t.Errorf("expected one meal plan left, got %d", len(plans))
+		}
+
+		deleted, err := stores.meals.DeleteMealPlansByWeeklyGoalId(ctx, other.MainGoalId, mealPlan.WeeklyGoalId)
+		expectNoError(t, err)
+		if deleted != 0 {
+			t.Errorf("expected no meal plan of another week deleted, got %d", deleted)
+		}
+		deleted, err = stores.meals.DeleteMealPlansByMainGoalId(ctx, other.MainGoalId)
+		expectNoError(t, err)
+		if deleted != 1 {
+			t.Errorf("expected the meal plan of the main goal deleted, got %d", deleted)
+		}
+		found, err = stores.meals.GetMealPlanById(ctx, other.ID)
+		expectNoError(t, err)
+		if found != nil {
+			t.Errorf("expected no meal plan left, got %+v", found)
+		}
+	})
+}
+
+func TestMealRepositoryUniqueWeeklyGoal(t *testing.T) {
+	runRepositoryTest(t, func(t *testing.T, stores repositoryStores) {
+		mealPlan := createTestMealPlan(t, stores.meals, primitive.NewObjectID(), primitive.NewObjectID())
+
+		duplicate := &models.MealPlan{ID: primitive.NewObjectID(), UserId: mealPlan.UserId, MainGoalId: mealPlan.MainGoalId, WeeklyGoalId: mealPlan.WeeklyGoalId}
+		err := stores.meals.CreateWeeklyMealPlan(context.Background(), duplicate)
+		if !mongo.IsDuplicateKeyError(err) {
+			t.Fatalf("expected a duplicate key error, got %v", err)
+		}
+	})
+}
+
+func TestMealRepositoryDayMeals(t *testing.T) {
+	runRepositoryTest(t, func(t *testing.T, stores repositoryStores) {
+		ctx := context.Background()
+		userId := primitive.NewObjectID()
+		mealPlan := createTestMealPlan(t, stores.meals, userId, primitive.NewObjectID())
+		today := mealPlan.DayMeals[1]
+
+		dayMeal, err := stores.meals.GetSingleDayMealByDate(ctx, userId)
+		expectNoError(t, err)
+		if dayMeal == nil || dayMeal.ID != today.ID || !dayMeal.Date.Equal(toStoredTime(today.Date)) {
+			t.Fatalf("expected today's meals, got %+v", dayMeal)
+		}
+		dayMeal, err = stores.meals.GetSingleDayMealByDate(ctx, primitive.NewObjectID())
+		expectNoError(t, err)
+		if dayMeal != nil {
+			t.Errorf("expected no meals for another user, got %+v", dayMeal)
+		}
+
+		replacement := []models.Meal{{ID: primitive.NewObjectID(), Name: "Poha", Calories: 300}}
+		expectNoError(t, stores.meals.UpdateSingleDayMeal(ctx, mealPlan.ID, today.ID, replacement))
+		expectNoError(t, stores.meals.UpdateSingleDayMeal(ctx, mealPlan.ID, primitive.NewObjectID(), replacement))
+
+		found, err := stores.meals.GetMealPlanById(ctx, mealPlan.ID)
+		expectNoError(t, err)
+		for i, day := range found.DayMeals {
+			replaced := len(day.Meals) == 1 && day.Meals[0].Name == "Poha"
+			if replaced != (i == 1) {
+				t.Errorf("expected only today's meals replaced, day %d has %+v", i, day.Meals)
+			}
+		}
+	})
+}
+
+func TestMealRepositorySetMealConsumed(t *testing.T) {
+	isConsumed := func(t *testing.T, meals MealRepository, mealPlanId primitive.ObjectID) [][]bool {
+		t.Helper()
+		mealPlan, err := meals.GetMealPlanById(context.Background(), mealPlanId)
+		expectNoError(t, err)
+		consumed := [][]bool{}
+		for _, dayMeal := range mealPlan.DayMeals {
+			day := []bool{}
+			for _, meal := range dayMeal.Meals {
+				day = append(day, meal.IsConsumed)
+			}
+			consumed = append(consumed, day)
+		}
+		return consumed
+	}
+	expectConsumed := func(t *testing.T, actual [][]bool, expected [][]bool) {
+		t.Helper()
+		for i := range expected {
+			for j := range expected[i] {
+				if actual[i][j] != expected[i][j] {
+					t.Fatalf("expected consumed meals %v, got %v", expected, actual)
+				}
+			}
+		}
+	}
+
+	runRepositoryTest(t, func(t *testing.T, stores repositoryStores) {
+		ctx := context.Background()
+		sharedMealId := primitive.NewObjectID()
+		mealPlan := createTestMealPlan(t, stores.meals, primitive.NewObjectID(), sharedMealId)
+		nobody := primitive.NilObjectID
+
+		// By its id alone the meal is consumed wherever it is planned
+		expectNoError(t, stores.meals.SetMealConsumed(ctx, nobody, nobody, sharedMealId, true))
+		expectConsumed(t, isConsumed(t, stores.meals, mealPlan.ID), [][]bool{{false, true}, {false, true}, {false, true}})
+
+		// The day meal narrows the update to that day
+		expectNoError(t, stores.meals.SetMealConsumed(ctx, mealPlan.ID, mealPlan.DayMeals[1].ID, sharedMealId, false))
+		expectConsumed(t, isConsumed(t, stores.meals, mealPlan.ID), [][]bool{{false, true}, {false, false}, {false, true}})
+
+		single := mealPlan.DayMeals[2].Meals[0].ID
+		expectNoError(t, stores.meals.SetMealConsumed(ctx, mealPlan.ID, nobody, single, true))
+		expectConsumed(t, isConsumed(t, stores.meals, mealPlan.ID), [][]bool{{false, true}, {false, false}, {true, true}})
+
+		// Setting the same value again still matches
+		expectNoError(t, stores.meals.SetMealConsumed(ctx, nobody, nobody, single, true))
+
+		consumed, err := stores.meals.HasConsumedMeals(ctx, mealPlan.MainGoalId, mealPlan.WeeklyGoalId)
+		expectNoError(t, err)
+		other := createTestMealPlan(t, stores.meals, primitive.NewObjectID(), primitive.NewObjectID())
+		otherConsumed, err := stores.meals.HasConsumedMeals(ctx, other.MainGoalId, other.WeeklyGoalId)
+		expectNoError(t, err)
+		if !consumed || otherConsumed {
+			t.Errorf("expected only the first meal plan to have consumed meals, got %t and %t", consumed, otherConsumed)
+		}
+
+		expectNoDocuments(t, stores.meals.SetMealConsumed(ctx, nobody, nobody, primitive.NewObjectID(), true))
+		expectNoDocuments(t, stores.meals.SetMealConsumed(ctx, primitive.NewObjectID(), nobody, single, true))
+		expectNoDocuments(t, stores.meals.SetMealConsumed(ctx, mealPlan.ID, mealPlan.DayMeals[0].ID, single, true))
+		expectConsumed(t, isConsumed(t, stores.meals, mealPlan.ID), [][]bool{{false, true}, {false, false}, {true, true}})
+	})
+}
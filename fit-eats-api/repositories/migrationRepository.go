@@ -0,0 +1,47 @@
+package repositories
+
+import (
+	"context"
+	"fit-eats-api/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MigrationRepository records the migrations applied to the database
+type MigrationRepository interface {
+	// GetAppliedMigrations returns the applied migrations ordered by id
+	GetAppliedMigrations(ctx context.Context) ([]models.MigrationRecord, error)
+	// RecordMigration fails with a duplicate key error when the migration is already recorded
+	RecordMigration(ctx context.Context, record *models.MigrationRecord) error
+}
+
+type MongoMigrationRepository struct {
+	Collection *mongo.Collection
+}
+
+func NewMongoMigrationRepository(db *mongo.Database) *MongoMigrationRepository {
+	return &MongoMigrationRepository{
+		Collection: db.Collection("migrations"),
+	}
+}
+
+func (r *MongoMigrationRepository) GetAppliedMigrations(ctx context.Context) ([]models.MigrationRecord, error) {
+	cursor, err := r.Collection.Find(ctx, bson.M{}, options.Find().SetSort(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	records := []models.MigrationRecord{}
+	if err := cursor.All(ctx, &records); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+func (r *MongoMigrationRepository) RecordMigration(ctx context.Context, record *models.MigrationRecord) error {
+	_, err := r.Collection.InsertOne(ctx, record)
+	return err
+}
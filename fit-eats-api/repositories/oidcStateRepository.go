@@ -0,0 +1,39 @@
+package repositories
+
+import (
+	"context"
+	"fit-eats-api/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type OidcStateRepository struct {
+	Collection *mongo.Collection
+}
+
+func NewOidcStateRepository(db *mongo.Database) *OidcStateRepository {
+	return &OidcStateRepository{
+		Collection: db.Collection("oidcStates"),
+	}
+}
+
+func (r *OidcStateRepository) CreateOidcState(ctx context.Context, oidcState *models.OidcState) error {
+	oidcState.ID = primitive.NewObjectID()
+	_, err := r.Collection.InsertOne(ctx, oidcState)
+	return err
+}
+
+// ConsumeOidcState deletes and returns the unexpired state, so each state can complete only one callback
+func (r *OidcStateRepository) ConsumeOidcState(ctx context.Context, provider string, stateHash string) (*models.OidcState, error) {
+	filter := bson.M{"stateHash": stateHash, "provider": provider, "expiresAt": bson.M{"$gt": time.Now()}}
+
+	var oidcState models.OidcState
+	err := r.Collection.FindOneAndDelete(ctx, filter).Decode(&oidcState)
+	if err != nil {
+		return nil, err
+	}
+	return &oidcState, nil
+}
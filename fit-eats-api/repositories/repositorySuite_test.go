@@ -0,0 +1,124 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MONGO_TEST_URI points the suite at a Mongo server, the mongo runs are skipped when it cannot be reached
+const MONGO_TEST_URI = "MONGO_TEST_URI"
+const DEFAULT_MONGO_TEST_URI = "mongodb://localhost:27017"
+
+// repositoryStores holds the repositories of one store, every test of the suite runs against each store
+type repositoryStores struct {
+	users UserRepository
+	goals UserGoalRepository
+	meals MealRepository
+
+	loginAttempts LoginAttemptStore
+	audit         AuditRepository
+	dataExports   DataExportRepository
+
+	unitOfWork UnitOfWork
+}
+
+var testMongo struct {
+	once   sync.Once
+	uri    string
+	client *mongo.Client
+	err    error
+}
+
+// runRepositoryTest runs the test against the in memory repositories and against Mongo, each time with empty collections
+func runRepositoryTest(t *testing.T, test func(t *testing.T, stores repositoryStores)) {
+	t.Run("memory", func(t *testing.T) {
+		test(t, repositoryStores{
+			users: NewInMemoryUserRepository(),
+			goals: NewInMemoryUserGoalRepository(),
+			meals: NewInMemoryMealRepository(),
+
+			loginAttempts: NewInMemoryLoginAttemptStore(),
+			audit:         NewInMemoryAuditRepository(),
+			dataExports:   NewInMemoryDataExportRepository(),
+
+			unitOfWork: NewInMemoryUnitOfWork(),
+		})
+	})
+
+	t.Run("mongo", func(t *testing.T) {
+		db := getTestDatabase(t)
+		// The in memory repositories enforce the same unique fields as the indexes
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		expectNoError(t, CreateIndexes(ctx, db))
+		dataExports, err := NewMongoDataExportRepository(db)
+		expectNoError(t, err)
+		test(t, repositoryStores{
+			users: NewMongoUserRepository(db),
+			goals: NewMongoUserGoalRepository(db),
+			meals: NewMongoMealRepository(db),
+
+			loginAttempts: NewMongoLoginAttemptStore(db),
+			audit:         NewMongoAuditRepository(db),
+			dataExports:   dataExports,
+
+			// The suite also runs against a standalone server, TestMongoUnitOfWorkRollback needs a replica set
+			unitOfWork: NewMongoUnitOfWork(db, true),
+		})
+	})
+}
+
+// getTestDatabase returns a new database dropped at the end of the test
+func getTestDatabase(t *testing.T) *mongo.Database {
+	testMongo.once.Do(func() {
+		testMongo.uri = os.Getenv(MONGO_TEST_URI)
+		if testMongo.uri == "" {
+			testMongo.uri = DEFAULT_MONGO_TEST_URI
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		defer cancel()
+		testMongo.client, testMongo.err = mongo.Connect(ctx, options.Client().ApplyURI(testMongo.uri).SetServerSelectionTimeout(2*time.Second))
+		if testMongo.err == nil {
+			testMongo.err = testMongo.client.Ping(ctx, nil)
+		}
+	})
+	if testMongo.err != nil {
+		t.Skipf("no Mongo at %s, set %s to run the suite against one: %v", testMongo.uri, MONGO_TEST_URI, testMongo.err)
+	}
+
+	db := testMongo.client.Database("fiteats_test_" + primitive.NewObjectID().Hex())
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		db.Drop(ctx)
+	})
+	return db
+}
+
+func expectNoError(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func expectNoDocuments(t *testing.T, err error) {
+	t.Helper()
+	if !errors.Is(err, mongo.ErrNoDocuments) {
+		t.Fatalf("expected mongo.ErrNoDocuments, got %v", err)
+	}
+}
+
+// toStoredTime is the time as Mongo returns it, in UTC with millisecond precision
+func toStoredTime(value time.Time) time.Time {
+	return value.UTC().Truncate(time.Millisecond)
+}
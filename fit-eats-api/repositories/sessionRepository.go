@@ -0,0 +1,107 @@
+package repositories
+
+import (
+	"context"
+	"fit-eats-api/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type SessionRepository struct {
+	Collection *mongo.Collection
+}
+
+func NewSessionRepository(db *mongo.Database) *SessionRepository {
+	return &SessionRepository{
+		Collection: db.Collection("sessions"),
+	}
+}
+
+// CreateSession expects the ID to be set by the caller since it is embedded in the refresh token
+func (r *SessionRepository) CreateSession(ctx context.Context, session *models.Session) error {
+	_, err := r.Collection.InsertOne(ctx, session)
+	return err
+}
+
+func (r *SessionRepository) GetSessionById(ctx context.Context, sessionId primitive.ObjectID) (*models.Session, error) {
+	var session models.Session
+	err := r.Collection.FindOne(ctx, bson.M{"_id": sessionId}).Decode(&session)
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (r *SessionRepository) IsSessionActive(ctx context.Context, sessionId primitive.ObjectID) bool {
+	filter := bson.M{"_id": sessionId, "revokedAt": nil, "expiresAt": bson.M{"$gt": time.Now()}}
+	count, err := r.Collection.CountDocuments(ctx, filter)
+	return err == nil && count > 0
+}
+
+func (r *SessionRepository) GetActiveSessions(ctx context.Context, userId primitive.ObjectID) ([]models.Session, error) {
+	filter := bson.M{"userId": userId, "revokedAt": nil, "expiresAt": bson.M{"$gt": time.Now()}}
+
+	cursor, err := r.Collection.Find(ctx, filter, options.Find().SetSort(bson.M{"lastUsedAt": -1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	sessions := []models.Session{}
+	if err := cursor.All(ctx, &sessions); err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// RotateRefreshToken swaps the stored token hash only if the presented one is still current,
+// so two concurrent refreshes with the same token cannot both succeed
+func (r *SessionRepository) RotateRefreshToken(ctx context.Context, sessionId primitive.ObjectID, currentHash string, newHash string, expiresAt time.Time, ip string, userAgent string) error {
+	filter := bson.M{"_id": sessionId, "refreshTokenHash": currentHash, "revokedAt": nil}
+	update := bson.M{"$set": bson.M{
+		"refreshTokenHash": newHash,
+		"lastUsedAt":       time.Now(),
+		"expiresAt":        expiresAt,
+		"ip":               ip,
+		"userAgent":        userAgent,
+	}}
+
+	result, err := r.Collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+func (r *SessionRepository) RevokeSession(ctx context.Context, userId primitive.ObjectID, sessionId primitive.ObjectID, reason string) error {
+	filter := bson.M{"_id": sessionId, "userId": userId, "revokedAt": nil}
+	update := bson.M{"$set": bson.M{"revokedAt": time.Now(), "revokedReason": reason}}
+
+	result, err := r.Collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// RevokeUserSessions revokes every active session of the user, except the given one when it is not nil
+func (r *SessionRepository) RevokeUserSessions(ctx context.Context, userId primitive.ObjectID, exceptSessionId primitive.ObjectID, reason string) error {
+	filter := bson.M{"userId": userId, "revokedAt": nil}
+	if !exceptSessionId.IsZero() {
+		filter["_id"] = bson.M{"$ne": exceptSessionId}
+	}
+	update := bson.M{"$set": bson.M{"revokedAt": time.Now(), "revokedReason": reason}}
+
+	_, err := r.Collection.UpdateMany(ctx, filter, update)
+	return err
+}
@@ -0,0 +1,100 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// UnitOfWork applies the writes of several repository calls together or not at all
+type UnitOfWork interface {
+	// Do runs fn in a transaction which commits when fn returns nil, the repositories must be called with the ctx
+	// passed to fn. fn runs again when the transaction hits a transient error, so it must only change the database.
+	// A Do inside fn joins the transaction of the outer one
+	Do(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// ErrNoTransactions is returned by the unit of work against a standalone server, unless writes without a transaction are allowed
+var ErrNoTransactions = errors.New("MongoDB is a standalone server without transactions, use a replica set or allow writes without transactions")
+
+// MongoUnitOfWork runs fn in a Mongo transaction. Transactions need a replica set, against a standalone server
+// such as a local development database fn only runs, without one, when AllowWithoutTransactions is set
+type MongoUnitOfWork struct {
+	Database                 *mongo.Database
+	AllowWithoutTransactions bool
+
+	mutex        sync.Mutex
+	transactions *bool
+}
+
+func NewMongoUnitOfWork(db *mongo.Database, allowWithoutTransactions bool) *MongoUnitOfWork {
+	return &MongoUnitOfWork{
+		Database:                 db,
+		AllowWithoutTransactions: allowWithoutTransactions,
+	}
+}
+
+// CheckTransactions returns ErrNoTransactions when the units of work would fail, the server checks it when it starts
+func (u *MongoUnitOfWork) CheckTransactions(ctx context.Context) error {
+	transactions, err := u.supportsTransactions(ctx)
+	if err != nil {
+		return err
+	}
+	if !transactions && !u.AllowWithoutTransactions {
+		return ErrNoTransactions
+	}
+	return nil
+}
+
+func (u *MongoUnitOfWork) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	if mongo.SessionFromContext(ctx) != nil {
+		return fn(ctx)
+	}
+
+	transactions, err := u.supportsTransactions(ctx)
+	if err != nil {
+		return err
+	}
+	if !transactions {
+		if !u.AllowWithoutTransactions {
+			return ErrNoTransactions
+		}
+		return fn(ctx)
+	}
+
+	session, err := u.Database.Client().StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(context.Background())
+
+	// WithTransaction runs fn again on a TransientTransactionError and retries the commit on an unknown commit result
+	_, err = session.WithTransaction(ctx, func(sessionContext mongo.SessionContext) (any, error) {
+		return nil, fn(sessionContext)
+	})
+	return err
+}
+
+// supportsTransactions asks the server once whether it is part of a replica set or a sharded cluster
+func (u *MongoUnitOfWork) supportsTransactions(ctx context.Context) (bool, error) {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	if u.transactions == nil {
+		var hello bson.M
+		if err := u.Database.RunCommand(ctx, bson.D{{Key: "hello", Value: 1}}).Decode(&hello); err != nil {
+			return false, err
+		}
+		_, replicaSet := hello["setName"]
+		transactions := replicaSet || hello["msg"] == "isdbgrid"
+		if !transactions && u.AllowWithoutTransactions {
+			log.Println("MongoDB is a standalone server, multi document writes run without transactions")
+		}
+		u.transactions = &transactions
+	}
+	return *u.transactions, nil
+}
@@ -0,0 +1,78 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fit-eats-api/models"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestUnitOfWork(t *testing.T) {
+	runRepositoryTest(t, func(t *testing.T, stores repositoryStores) {
+		ctx := context.Background()
+		goal := &models.Goal{ID: primitive.NewObjectID(), UserId: primitive.NewObjectID(), Status: models.GOAL_ACTIVE}
+		weeklyGoal := &models.WeeklyGoal{CurrentWeightInKg: 90}
+
+		err := stores.unitOfWork.Do(ctx, func(ctx context.Context) error {
+			if err := stores.goals.CreateMainUserGoal(ctx, goal); err != nil {
+				return err
+			}
+			// A nested unit of work joins the outer one
+			return stores.unitOfWork.Do(ctx, func(ctx context.Context) error {
+				return stores.goals.CreateWeeklyUserGoal(ctx, goal.ID, weeklyGoal)
+			})
+		})
+		expectNoError(t, err)
+
+		found, err := stores.goals.GetUserGoalById(ctx, goal.ID)
+		expectNoError(t, err)
+		if len(found.WeeklyGoals) != 1 || found.WeeklyGoals[0].ID != weeklyGoal.ID {
+			t.Errorf("expected the goal with its weekly goal, got %+v", found)
+		}
+
+		failure := errors.New("meal plan generation failed")
+		err = stores.unitOfWork.Do(ctx, func(ctx context.Context) error { return failure })
+		if !errors.Is(err, failure) {
+			t.Errorf("expected the error of fn, got %v", err)
+		}
+	})
+}
+
+// A unit of work which fails keeps none of its writes, against a standalone server it refuses to run unless allowed
+func TestMongoUnitOfWorkRollback(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	db := getTestDatabase(t)
+	expectNoError(t, CreateIndexes(ctx, db))
+	users, goals := NewMongoUserRepository(db), NewMongoUserGoalRepository(db)
+	createTestUser(t, users, "Asha", "asha@fiteats.test")
+
+	unitOfWork := NewMongoUnitOfWork(db, false)
+	if err := unitOfWork.CheckTransactions(ctx); errors.Is(err, ErrNoTransactions) {
+		err = unitOfWork.Do(ctx, func(ctx context.Context) error { return nil })
+		if !errors.Is(err, ErrNoTransactions) {
+			t.Errorf("expected the unit of work to refuse running without a transaction, got %v", err)
+		}
+		expectNoError(t, NewMongoUnitOfWork(db, true).CheckTransactions(ctx))
+		t.Skip("MongoDB is a standalone server, the rollback needs a replica set")
+	} else {
+		expectNoError(t, err)
+	}
+
+	goal := &models.Goal{ID: primitive.NewObjectID(), UserId: primitive.NewObjectID()}
+	err := unitOfWork.Do(ctx, func(ctx context.Context) error {
+		if err := goals.CreateMainUserGoal(ctx, goal); err != nil {
+			return err
+		}
+		return users.CreateUser(ctx, &models.User{Name: "Other Asha", Email: "asha@fiteats.test"})
+	})
+	if !mongo.IsDuplicateKeyError(err) {
+		t.Fatalf("expected the duplicate key error of the second write, got %v", err)
+	}
+	_, err = goals.GetUserGoalById(ctx, goal.ID)
+	expectNoDocuments(t, err)
+}
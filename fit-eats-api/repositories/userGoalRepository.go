@@ -8,36 +8,72 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-type UserGoalRepository struct {
+// UserGoalRepository stores the main goals with their weekly goals embedded
+type UserGoalRepository interface {
+	CreateMainUserGoal(ctx context.Context, mainGoal *models.Goal) error
+	// CreateWeeklyUserGoal makes sure weeklyGoals is an array before pushing to it, a failed push leaves at most an
+	// empty array behind so it needs no unit of work. Weeks are only pushed to an active goal, mongo.ErrNoDocuments
+	// when the goal is missing or not active
+	CreateWeeklyUserGoal(ctx context.Context, mainGoalId primitive.ObjectID, weeklyGoal *models.WeeklyGoal) error
+	// GetUserActiveGoalByUserId returns the active goal with only the weekly goals running today, mongo.ErrNoDocuments when none is
+	GetUserActiveGoalByUserId(ctx context.Context, mongoUserId primitive.ObjectID) (*models.Goal, error)
+	// GetUserGoalByUserId returns the active goal of the user
+	GetUserGoalByUserId(ctx context.Context, mongoUserId primitive.ObjectID) (*models.Goal, error)
+	// GetUserGoalsByUserId returns the goals of the user in the given statuses, the latest started first
+	GetUserGoalsByUserId(ctx context.Context, mongoUserId primitive.ObjectID, statuses []models.GoalStatus) ([]models.Goal, error)
+	GetUserGoalById(ctx context.Context, goalId primitive.ObjectID) (*models.Goal, error)
+	GetGoalOwnerId(ctx context.Context, goalId primitive.ObjectID) (primitive.ObjectID, error)
+	DeleteMainUserGoal(ctx context.Context, goalId primitive.ObjectID) error
+	// EndUserGoal completes or abandons the active goal with its outcome, mongo.ErrNoDocuments when the goal is not active
+	EndUserGoal(ctx context.Context, goalId primitive.ObjectID, status models.GoalStatus, outcome *models.GoalOutcome) error
+	// ArchiveUserGoal archives a completed or abandoned goal, mongo.ErrNoDocuments when the goal has not ended
+	ArchiveUserGoal(ctx context.Context, goalId primitive.ObjectID) error
+	// DeleteWeeklyUserGoal removes the week from the main goal, mongo.ErrNoDocuments when the goal has no such week
+	DeleteWeeklyUserGoal(ctx context.Context, mainGoalId primitive.ObjectID, weeklyGoalId primitive.ObjectID) error
+	// UpdateWeeklyUserGoal sets the measurements and targets of the week with the same id, its dates and workout routine
+	// are left as they are. mongo.ErrNoDocuments when the goal has no such week
+	UpdateWeeklyUserGoal(ctx context.Context, mainGoalId primitive.ObjectID, weeklyGoal *models.WeeklyGoal) error
+	GetUserWeeklyGoal(ctx context.Context, mainGoalId primitive.ObjectID, weeklyGoalId primitive.ObjectID) (*models.Goal, error)
+	SetWeeklyGoalWorkoutRoutine(ctx context.Context, mainGoalId primitive.ObjectID, weeklyGoalId primitive.ObjectID, routineId primitive.ObjectID) error
+	// LockWeeklyGoal writes to the week inside a unit of work, so that a transaction editing or deleting the week at
+	// the same time conflicts with it. mongo.ErrNoDocuments when the goal has no such week
+	LockWeeklyGoal(ctx context.Context, mainGoalId primitive.ObjectID, weeklyGoalId primitive.ObjectID) error
+}
+
+type MongoUserGoalRepository struct {
 	Collection *mongo.Collection
 }
 
-func NewUserGoalRepository(db *mongo.Database) *UserGoalRepository {
-	return &UserGoalRepository{
+func NewMongoUserGoalRepository(db *mongo.Database) *MongoUserGoalRepository {
+	return &MongoUserGoalRepository{
 		Collection: db.Collection("userGoals"),
 	}
 }
 
-func (r *UserGoalRepository) CreateMainUserGoal(ctx context.Context, mainGoal *models.Goal) error {
+func (r *MongoUserGoalRepository) CreateMainUserGoal(ctx context.Context, mainGoal *models.Goal) error {
 	_, err := r.Collection.InsertOne(ctx, mainGoal)
 	return err
 }
 
-func (r *UserGoalRepository) CreateWeeklyUserGoal(ctx context.Context, mainGoalId primitive.ObjectID, weeklyGoal *models.WeeklyGoal) error {
+func (r *MongoUserGoalRepository) CreateWeeklyUserGoal(ctx context.Context, mainGoalId primitive.ObjectID, weeklyGoal *models.WeeklyGoal) error {
 	weeklyGoal.ID = primitive.NewObjectID() // Generate a new ID for the weekly goal
 
 	// Ensure 'weeklyGoals' is an array before pushing a new item
 	filter := bson.M{"_id": mainGoalId, "$or": []bson.M{{"weeklyGoals": bson.M{"$exists": false}}, {"weeklyGoals": nil}}}
 	initUpdate := bson.M{"$set": bson.M{"weeklyGoals": bson.A{}}}
 
-	_, _ = r.Collection.UpdateOne(ctx, filter, initUpdate) // Set only if 'weeklyGoals' does not exist
+	_, err := r.Collection.UpdateOne(ctx, filter, initUpdate) // Set only if 'weeklyGoals' does not exist
+	if err != nil {
+		return err
+	}
 
 	// Now push the new weekly goal into the array
 	update := bson.M{"$push": bson.M{"weeklyGoals": weeklyGoal}}
 
-	result, err := r.Collection.UpdateOne(ctx, bson.M{"_id": mainGoalId}, update)
+	result, err := r.Collection.UpdateOne(ctx, bson.M{"_id": mainGoalId, "status": models.GOAL_ACTIVE}, update)
 	if err != nil {
 		return err
 	}
@@ -49,12 +85,13 @@ func (r *UserGoalRepository) CreateWeeklyUserGoal(ctx context.Context, mainGoalI
 	return nil
 }
 
-func (r *UserGoalRepository) GetUserActiveGoalByUserId(ctx context.Context, mongoUserId primitive.ObjectID) (*models.Goal, error) {
+func (r *MongoUserGoalRepository) GetUserActiveGoalByUserId(ctx context.Context, mongoUserId primitive.ObjectID) (*models.Goal, error) {
 	var userGoal models.Goal
 
 	pipeline := mongo.Pipeline{
 		bson.D{{Key: "$match", Value: bson.D{
 			{Key: "userId", Value: mongoUserId},
+			{Key: "status", Value: models.GOAL_ACTIVE},
 		}}},
 		bson.D{{Key: "$addFields", Value: bson.D{ // Keeps all fields, modifies only weeklyGoals
 			{Key: "weeklyGoals", Value: bson.D{{Key: "$filter", Value: bson.D{
@@ -90,9 +127,35 @@ func (r *UserGoalRepository) GetUserActiveGoalByUserId(ctx context.Context, mong
 	return &userGoal, nil
 }
 
-func (r *UserGoalRepository) GetUserGoalByUserId(ctx context.Context, mongoUserId primitive.ObjectID) (*models.Goal, error) {
+func (r *MongoUserGoalRepository) GetUserGoalByUserId(ctx context.Context, mongoUserId primitive.ObjectID) (*models.Goal, error) {
+	var userGoal models.Goal
+	err := r.Collection.FindOne(ctx, bson.M{"userId": mongoUserId, "status": models.GOAL_ACTIVE}).Decode(&userGoal)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &userGoal, nil
+}
+
+func (r *MongoUserGoalRepository) GetUserGoalsByUserId(ctx context.Context, mongoUserId primitive.ObjectID, statuses []models.GoalStatus) ([]models.Goal, error) {
+	filter := bson.M{"userId": mongoUserId, "status": bson.M{"$in": statuses}}
+	cursor, err := r.Collection.Find(ctx, filter, options.Find().SetSort(bson.M{"goalStartDate": -1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	goals := []models.Goal{}
+	if err := cursor.All(ctx, &goals); err != nil {
+		return nil, err
+	}
+	return goals, nil
+}
+
+func (r *MongoUserGoalRepository) GetUserGoalById(ctx context.Context, goalId primitive.ObjectID) (*models.Goal, error) {
 	var userGoal models.Goal
-	err := r.Collection.FindOne(ctx, bson.M{"userId": mongoUserId}).Decode(&userGoal)
+	err := r.Collection.FindOne(ctx, bson.M{"_id": goalId}).Decode(&userGoal)
 
 	if err != nil {
 		return nil, err
@@ -101,19 +164,98 @@ func (r *UserGoalRepository) GetUserGoalByUserId(ctx context.Context, mongoUserI
 	return &userGoal, nil
 }
 
-func (r *UserGoalRepository) DeleteMainUserGoal(ctx context.Context, goalId primitive.ObjectID) error {
+// GetGoalOwnerId returns the user the main goal belongs to
+func (r *MongoUserGoalRepository) GetGoalOwnerId(ctx context.Context, goalId primitive.ObjectID) (primitive.ObjectID, error) {
+	var goal models.Goal
+	err := r.Collection.FindOne(ctx, bson.M{"_id": goalId}, options.FindOne().SetProjection(bson.M{"userId": 1})).Decode(&goal)
+	return goal.UserId, err
+}
+
+func (r *MongoUserGoalRepository) DeleteMainUserGoal(ctx context.Context, goalId primitive.ObjectID) error {
 	filter := bson.M{"_id": goalId} // Find by ID
 	_, err := r.Collection.DeleteOne(ctx, filter)
 	return err
 }
 
-func (r *UserGoalRepository) DeleteWeeklyUserGoal(ctx context.Context, mainGoalId primitive.ObjectID, weeklyGoalId primitive.ObjectID) error {
-	filter := bson.M{"_id": mainGoalId, "weeklyGoals._id": weeklyGoalId} // Find by ID
-	_, err := r.Collection.DeleteOne(ctx, filter)
-	return err
+func (r *MongoUserGoalRepository) EndUserGoal(ctx context.Context, goalId primitive.ObjectID, status models.GoalStatus, outcome *models.GoalOutcome) error {
+	filter := bson.M{"_id": goalId, "status": models.GOAL_ACTIVE}
+	update := bson.M{"$set": bson.M{"status": status, "endedAt": time.Now(), "outcome": outcome}}
+
+	result, err := r.Collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	return nil
+}
+
+func (r *MongoUserGoalRepository) ArchiveUserGoal(ctx context.Context, goalId primitive.ObjectID) error {
+	filter := bson.M{"_id": goalId, "status": bson.M{"$in": []models.GoalStatus{models.GOAL_COMPLETED, models.GOAL_ABANDONED}}}
+	update := bson.M{"$set": bson.M{"status": models.GOAL_ARCHIVED}}
+
+	result, err := r.Collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	return nil
+}
+
+func (r *MongoUserGoalRepository) DeleteWeeklyUserGoal(ctx context.Context, mainGoalId primitive.ObjectID, weeklyGoalId primitive.ObjectID) error {
+	filter := bson.M{"_id": mainGoalId, "weeklyGoals._id": weeklyGoalId}
+	update := bson.M{"$pull": bson.M{"weeklyGoals": bson.M{"_id": weeklyGoalId}}}
+
+	result, err := r.Collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	return nil
+}
+
+func (r *MongoUserGoalRepository) UpdateWeeklyUserGoal(ctx context.Context, mainGoalId primitive.ObjectID, weeklyGoal *models.WeeklyGoal) error {
+	filter := bson.M{"_id": mainGoalId, "weeklyGoals._id": weeklyGoal.ID}
+	update := bson.M{"$set": getWeeklyGoalTargetFields(weeklyGoal)}
+
+	result, err := r.Collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	return nil
+}
+
+// getWeeklyGoalTargetFields are the positional updates of the fields a weekly goal edit changes
+func getWeeklyGoalTargetFields(weeklyGoal *models.WeeklyGoal) bson.M {
+	return bson.M{
+		"weeklyGoals.$.currentWeightInKg":        weeklyGoal.CurrentWeightInKg,
+		"weeklyGoals.$.currentFatPercentage":     weeklyGoal.CurrentFatPercentage,
+		"weeklyGoals.$.activityLevel":            weeklyGoal.ActivityLevel,
+		"weeklyGoals.$.dailyMaintenanceCalories": weeklyGoal.DailyMaintenanceCalories,
+		"weeklyGoals.$.targetDailyCalories":      weeklyGoal.TargetDailyCalories,
+		"weeklyGoals.$.targetDailyMacrosProtein": weeklyGoal.TargetDailyMacrosProtein,
+		"weeklyGoals.$.targetDailyMacrosCarbs":   weeklyGoal.TargetDailyMacrosCarbs,
+		"weeklyGoals.$.targetDailyMacrosFats":    weeklyGoal.TargetDailyMacrosFats,
+	}
 }
 
-func (r *UserGoalRepository) GetUserWeeklyGoal(ctx context.Context, mainGoalId primitive.ObjectID, weeklyGoalId primitive.ObjectID) (*models.Goal, error) {
+func (r *MongoUserGoalRepository) GetUserWeeklyGoal(ctx context.Context, mainGoalId primitive.ObjectID, weeklyGoalId primitive.ObjectID) (*models.Goal, error) {
 
 	var userGoal models.Goal
 
@@ -155,3 +297,37 @@ func (r *UserGoalRepository) GetUserWeeklyGoal(ctx context.Context, mainGoalId p
 
 	return &userGoal, nil
 }
+
+// SetWeeklyGoalWorkoutRoutine links a workout routine to a single weekly goal
+func (r *MongoUserGoalRepository) SetWeeklyGoalWorkoutRoutine(ctx context.Context, mainGoalId primitive.ObjectID, weeklyGoalId primitive.ObjectID, routineId primitive.ObjectID) error {
+	filter := bson.M{"_id": mainGoalId, "weeklyGoals._id": weeklyGoalId}
+	update := bson.M{"$set": bson.M{"weeklyGoals.$.workoutRoutineId": routineId}}
+
+	result, err := r.Collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	return nil
+}
+
+func (r *MongoUserGoalRepository) LockWeeklyGoal(ctx context.Context, mainGoalId primitive.ObjectID, weeklyGoalId primitive.ObjectID) error {
+	// A new id every time, writing the same value again would not be a write
+	filter := bson.M{"_id": mainGoalId, "weeklyGoals._id": weeklyGoalId}
+	update := bson.M{"$set": bson.M{"weeklyGoals.$.lockId": primitive.NewObjectID()}}
+
+	result, err := r.Collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	return nil
+}
@@ -0,0 +1,213 @@
+package repositories
+
+import (
+	"context"
+	"fit-eats-api/models"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// createTestGoal creates a goal with a past, a running and a future week
+func createTestGoal(t *testing.T, goals UserGoalRepository, userId primitive.ObjectID) *models.Goal {
+	t.Helper()
+	ctx := context.Background()
+	now := time.Now()
+	goal := &models.Goal{
+		ID:               primitive.NewObjectID(),
+		UserId:           userId,
+		GoalType:         models.FAT_LOSS,
+		Status:           models.GOAL_ACTIVE,
+		StartWeightInKg:  90,
+		TargetWeightInKg: 80,
+		GoalStartDate:    now.AddDate(0, 0, -7),
+		GoalEndDate:      now.AddDate(0, 3, 0),
+	}
+	expectNoError(t, goals.CreateMainUserGoal(ctx, goal))
+
+	for _, start := range []time.Time{now.AddDate(0, 0, -10), now.AddDate(0, 0, -3), now.AddDate(0, 0, 4)} {
+		weeklyGoal := &models.WeeklyGoal{StartDate: start, EndDate: start.AddDate(0, 0, 7), CurrentWeightInKg: 88}
+		expectNoError(t, goals.CreateWeeklyUserGoal(ctx, goal.ID, weeklyGoal))
+		goal.WeeklyGoals = append(goal.WeeklyGoals, *weeklyGoal)
+	}
+	return goal
+}
+
+func TestUserGoalRepositoryGoals(t *testing.T) {
+	runRepositoryTest(t, func(t *testing.T, stores repositoryStores) {
+		ctx := context.Background()
+		userId := primitive.NewObjectID()
+		goal := createTestGoal(t, stores.goals, userId)
+
+		found, err := stores.goals.GetUserGoalById(ctx, goal.ID)
+		expectNoError(t, err)
+		if found.UserId != userId || found.TargetWeightInKg != 80 || len(found.WeeklyGoals) != 3 {
+			t.Fatalf("unexpected goal: %+v", found)
+		}
+		if found.WeeklyGoals[0].ID != goal.WeeklyGoals[0].ID || !found.GoalStartDate.Equal(toStoredTime(goal.GoalStartDate)) {
+			t.Errorf("expected the weekly goals in creation order and times as stored: %+v", found)
+		}
+
+		byUser, err := stores.goals.GetUserGoalByUserId(ctx, userId)
+		expectNoError(t, err)
+		if byUser.ID != goal.ID {
+			t.Errorf("expected goal %s, got %s", goal.ID.Hex(), byUser.ID.Hex())
+		}
+
+		ownerId, err := stores.goals.GetGoalOwnerId(ctx, goal.ID)
+		expectNoError(t, err)
+		if ownerId != userId {
+			t.Errorf("expected owner %s, got %s", userId.Hex(), ownerId.Hex())
+		}
+
+		_, err = stores.goals.GetUserGoalById(ctx, primitive.NewObjectID())
+		expectNoDocuments(t, err)
+		_, err = stores.goals.GetGoalOwnerId(ctx, primitive.NewObjectID())
+		expectNoDocuments(t, err)
+		expectNoDocuments(t, stores.goals.CreateWeeklyUserGoal(ctx, primitive.NewObjectID(), &models.WeeklyGoal{}))
+
+		expectNoError(t, stores.goals.DeleteMainUserGoal(ctx, goal.ID))
+		_, err = stores.goals.GetUserGoalByUserId(ctx, userId)
+		expectNoDocuments(t, err)
+		expectNoError(t, stores.goals.DeleteMainUserGoal(ctx, goal.ID))
+	})
+}
+
+func TestUserGoalRepositoryRunningWeeks(t *testing.T) {
+	runRepositoryTest(t, func(t *testing.T, stores repositoryStores) {
+		ctx := context.Background()
+		userId := primitive.NewObjectID()
+		goal := createTestGoal(t, stores.goals, userId)
+		running := goal.WeeklyGoals[1]
+
+		active, err := stores.goals.GetUserActiveGoalByUserId(ctx, userId)
+		expectNoError(t, err)
+		if len(active.WeeklyGoals) != 1 || active.WeeklyGoals[0].ID != running.ID || active.ID != goal.ID {
+			t.Errorf("expected only the running week, got %+v", active.WeeklyGoals)
+		}
+
+		// The weekly goal is only found while it runs
+		weekly, err := stores.goals.GetUserWeeklyGoal(ctx, goal.ID, running.ID)
+		expectNoError(t, err)
+		if len(weekly.WeeklyGoals) != 1 || weekly.WeeklyGoals[0].ID != running.ID {
+			t.Errorf("expected the running week, got %+v", weekly.WeeklyGoals)
+		}
+		_, err = stores.goals.GetUserWeeklyGoal(ctx, goal.ID, primitive.NewObjectID())
+		expectNoDocuments(t, err)
+
+		_, err = stores.goals.GetUserActiveGoalByUserId(ctx, primitive.NewObjectID())
+		expectNoDocuments(t, err)
+
+		future := &models.Goal{ID: primitive.NewObjectID(), UserId: primitive.NewObjectID(), Status: models.GOAL_ACTIVE}
+		expectNoError(t, stores.goals.CreateMainUserGoal(ctx, future))
+		_, err = stores.goals.GetUserActiveGoalByUserId(ctx, future.UserId)
+		expectNoDocuments(t, err)
+	})
+}
+
+func TestUserGoalRepositoryWeeklyGoals(t *testing.T) {
+	runRepositoryTest(t, func(t *testing.T, stores repositoryStores) {
+		ctx := context.Background()
+		goal := createTestGoal(t, stores.goals, primitive.NewObjectID())
+
+		// The routine linked after the week was read is kept
+		edited := goal.WeeklyGoals[1]
+		routineId := primitive.NewObjectID()
+		expectNoError(t, stores.goals.SetWeeklyGoalWorkoutRoutine(ctx, goal.ID, edited.ID, routineId))
+		edited.CurrentWeightInKg, edited.TargetDailyCalories = 86.5, 2100
+		expectNoError(t, stores.goals.UpdateWeeklyUserGoal(ctx, goal.ID, &edited))
+		expectNoDocuments(t, stores.goals.UpdateWeeklyUserGoal(ctx, goal.ID, &models.WeeklyGoal{ID: primitive.NewObjectID()}))
+
+		// Deleting a week keeps the main goal and its other weeks
+		expectNoError(t, stores.goals.DeleteWeeklyUserGoal(ctx, goal.ID, goal.WeeklyGoals[0].ID))
+		expectNoDocuments(t, stores.goals.DeleteWeeklyUserGoal(ctx, goal.ID, goal.WeeklyGoals[0].ID))
+
+		found, err := stores.goals.GetUserGoalById(ctx, goal.ID)
+		expectNoError(t, err)
+		if len(found.WeeklyGoals) != 2 || found.WeeklyGoals[0].ID != edited.ID || found.WeeklyGoals[1].ID != goal.WeeklyGoals[2].ID {
+			t.Fatalf("expected the second and third week left, got %+v", found.WeeklyGoals)
+		}
+		if found.WeeklyGoals[0].CurrentWeightInKg != 86.5 || found.WeeklyGoals[0].TargetDailyCalories != 2100 || found.WeeklyGoals[0].WorkoutRoutineId != routineId ||
+			found.WeeklyGoals[1].CurrentWeightInKg != 88 {
+			t.Errorf("expected only the edited week changed, got %+v", found.WeeklyGoals)
+		}
+	})
+}
+
+func TestUserGoalRepositoryWorkoutRoutine(t *testing.T) {
+	runRepositoryTest(t, func(t *testing.T, stores repositoryStores) {
+		ctx := context.Background()
+		goal := createTestGoal(t, stores.goals, primitive.NewObjectID())
+		routineId := primitive.NewObjectID()
+
+		expectNoError(t, stores.goals.SetWeeklyGoalWorkoutRoutine(ctx, goal.ID, goal.WeeklyGoals[2].ID, routineId))
+		expectNoDocuments(t, stores.goals.SetWeeklyGoalWorkoutRoutine(ctx, goal.ID, primitive.NewObjectID(), routineId))
+
+		found, err := stores.goals.GetUserGoalById(ctx, goal.ID)
+		expectNoError(t, err)
+		for i, weeklyGoal := range found.WeeklyGoals {
+			if (i == 2) != (weeklyGoal.WorkoutRoutineId == routineId) {
+				t.Errorf("expected the routine on the third week only, week %d has %s", i, weeklyGoal.WorkoutRoutineId.Hex())
+			}
+		}
+	})
+}
+
+func TestUserGoalRepositoryLockWeeklyGoal(t *testing.T) {
+	runRepositoryTest(t, func(t *testing.T, stores repositoryStores) {
+		ctx := context.Background()
+		goal := createTestGoal(t, stores.goals, primitive.NewObjectID())
+
+		expectNoError(t, stores.goals.LockWeeklyGoal(ctx, goal.ID, goal.WeeklyGoals[1].ID))
+		expectNoError(t, stores.goals.LockWeeklyGoal(ctx, goal.ID, goal.WeeklyGoals[1].ID))
+		expectNoError(t, stores.goals.DeleteWeeklyUserGoal(ctx, goal.ID, goal.WeeklyGoals[1].ID))
+		expectNoDocuments(t, stores.goals.LockWeeklyGoal(ctx, goal.ID, goal.WeeklyGoals[1].ID))
+	})
+}
+
+func TestUserGoalRepositoryGoalHistory(t *testing.T) {
+	runRepositoryTest(t, func(t *testing.T, stores repositoryStores) {
+		ctx := context.Background()
+		userId := primitive.NewObjectID()
+		ended := createTestGoal(t, stores.goals, userId)
+
+		outcome := &models.GoalOutcome{StartWeightInKg: 90, EndWeightInKg: 88, WeightChangeInKg: -2, WeeksTracked: 3}
+		expectNoError(t, stores.goals.EndUserGoal(ctx, ended.ID, models.GOAL_COMPLETED, outcome))
+		expectNoDocuments(t, stores.goals.EndUserGoal(ctx, ended.ID, models.GOAL_ABANDONED, outcome))
+		_, err := stores.goals.GetUserGoalByUserId(ctx, userId)
+		expectNoDocuments(t, err)
+		_, err = stores.goals.GetUserActiveGoalByUserId(ctx, userId)
+		expectNoDocuments(t, err)
+		// Weeks are no longer added to the ended goal
+		expectNoDocuments(t, stores.goals.CreateWeeklyUserGoal(ctx, ended.ID, &models.WeeklyGoal{}))
+
+		// The next goal starts later and becomes the active one
+		now := time.Now()
+		active := &models.Goal{ID: primitive.NewObjectID(), UserId: userId, GoalType: models.MUSCLE_GAIN, Status: models.GOAL_ACTIVE, GoalStartDate: now, GoalEndDate: now.AddDate(0, 3, 0)}
+		expectNoError(t, stores.goals.CreateMainUserGoal(ctx, active))
+		found, err := stores.goals.GetUserGoalByUserId(ctx, userId)
+		expectNoError(t, err)
+		if found.ID != active.ID {
+			t.Errorf("expected the new goal active, got %+v", found)
+		}
+		expectNoDocuments(t, stores.goals.ArchiveUserGoal(ctx, active.ID))
+
+		goals, err := stores.goals.GetUserGoalsByUserId(ctx, userId, []models.GoalStatus{models.GOAL_ACTIVE, models.GOAL_COMPLETED})
+		expectNoError(t, err)
+		if len(goals) != 2 || goals[0].ID != active.ID || goals[1].ID != ended.ID {
+			t.Fatalf("expected both goals, the latest started first, got %+v", goals)
+		}
+		if goals[1].Status != models.GOAL_COMPLETED || goals[1].EndedAt == nil || goals[1].Outcome == nil || *goals[1].Outcome != *outcome {
+			t.Errorf("expected the ended goal with its outcome, got %+v", goals[1])
+		}
+
+		expectNoError(t, stores.goals.ArchiveUserGoal(ctx, ended.ID))
+		expectNoDocuments(t, stores.goals.ArchiveUserGoal(ctx, ended.ID))
+		goals, err = stores.goals.GetUserGoalsByUserId(ctx, userId, []models.GoalStatus{models.GOAL_ARCHIVED})
+		expectNoError(t, err)
+		if len(goals) != 1 || goals[0].ID != ended.ID {
+			t.Errorf("expected the archived goal, got %+v", goals)
+		}
+	})
+}
@@ -3,6 +3,8 @@ package repositories
 import (
 	"context"
 	"fit-eats-api/models"
+	"regexp"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -10,40 +12,108 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-type UserRepository struct {
+// UserRepository stores the accounts. Lookups of a missing user fail with mongo.ErrNoDocuments
+type UserRepository interface {
+	// CreateUser stores the email normalized, the lookups by email normalize the email they are given
+	CreateUser(ctx context.Context, user *models.User) error
+	// GetUserByEmail, GetUserByIdentity and GetUserCredentialsById return the login fields, including the password hash
+	GetUserByEmail(ctx context.Context, email string) (*models.User, error)
+	GetUserByIdentity(ctx context.Context, provider string, subject string) (*models.User, error)
+	GetUserCredentialsById(ctx context.Context, mongoUserId primitive.ObjectID) (*models.User, error)
+	// GetUserProfileById and GetUserProfileByEmailId leave out the password and mfa secrets
+	GetUserProfileById(ctx context.Context, mongoUserId primitive.ObjectID) (*models.User, error)
+	GetUserProfileByEmailId(ctx context.Context, emailId string) (*models.User, error)
+	SearchUsers(ctx context.Context, query string, limit int64) ([]models.User, error)
+	// UpdateUser sets the fields, dotted keys set nested fields
+	UpdateUser(ctx context.Context, userID primitive.ObjectID, updateData bson.M) error
+	UnsetUserFields(ctx context.Context, userID primitive.ObjectID, fields ...string) error
+	SetMfaLastUsedCounter(ctx context.Context, userID primitive.ObjectID, counter int64) error
+	ConsumeRecoveryCode(ctx context.Context, userID primitive.ObjectID, codeHash string) error
+	AddUserIdentity(ctx context.Context, userID primitive.ObjectID, identity models.Identity) error
+	RemoveUserIdentity(ctx context.Context, userID primitive.ObjectID, provider string) error
+	ScheduleUserDeletion(ctx context.Context, userID primitive.ObjectID, requestedAt time.Time, scheduledAt time.Time) error
+	CancelUserDeletion(ctx context.Context, userID primitive.ObjectID) error
+	GetUsersDueForDeletion(ctx context.Context, now time.Time) ([]models.User, error)
+}
+
+// userProfileProjection leaves out the password and mfa secrets
+var userProfileProjection = bson.M{"password": 0, "refreshToken": 0, "mfa.secret": 0, "mfa.pendingSecret": 0, "mfa.recoveryCodeHashes": 0}
+
+var userCredentialsProjection = bson.M{"_id": 1, "name": 1, "email": 1, "password": 1, "role": 1, "mfa": 1, "identities": 1}
+
+type MongoUserRepository struct {
 	Collection *mongo.Collection
 }
 
-func NewUserRepository(db *mongo.Database) *UserRepository {
-	return &UserRepository{
+func NewMongoUserRepository(db *mongo.Database) *MongoUserRepository {
+	return &MongoUserRepository{
 		Collection: db.Collection("users"),
 	}
 }
 
-func (r *UserRepository) CreateUser(ctx context.Context, user *models.User) error {
+func (r *MongoUserRepository) CreateUser(ctx context.Context, user *models.User) error {
+	user.ID = primitive.NewObjectID()
+	user.Email = models.NormalizeEmail(user.Email)
 	_, err := r.Collection.InsertOne(ctx, user)
 	return err
 }
 
-func (r *UserRepository) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+func (r *MongoUserRepository) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	var user models.User
+	err := r.Collection.FindOne(ctx, bson.M{"email": models.NormalizeEmail(email)}, options.FindOne().SetProjection(bson.M{"_id": 1, "name": 1, "email": 1, "password": 1, "role": 1, "mfa": 1})).Decode(&user)
+	return &user, err
+}
+
+// GetUserByIdentity finds the user who linked the provider account
+func (r *MongoUserRepository) GetUserByIdentity(ctx context.Context, provider string, subject string) (*models.User, error) {
 	var user models.User
-	err := r.Collection.FindOne(ctx, bson.M{"email": email}, options.FindOne().SetProjection(bson.M{"_id": 1, "email": 1, "password": 1})).Decode(&user)
+	filter := bson.M{"identities": bson.M{"$elemMatch": bson.M{"provider": provider, "subject": subject}}}
+	err := r.Collection.FindOne(ctx, filter, options.FindOne().SetProjection(userCredentialsProjection)).Decode(&user)
 	return &user, err
 }
 
-func (r *UserRepository) GetUserProfileById(ctx context.Context, mongoUserId primitive.ObjectID) (*models.User, error) {
+// GetUserCredentialsById returns the password hash and mfa settings which are left out of the profile
+func (r *MongoUserRepository) GetUserCredentialsById(ctx context.Context, mongoUserId primitive.ObjectID) (*models.User, error) {
 	var user models.User
-	err := r.Collection.FindOne(ctx, bson.M{"_id": mongoUserId}, options.FindOne().SetProjection(bson.M{"password": 0, "refreshToken": 0})).Decode(&user)
+	err := r.Collection.FindOne(ctx, bson.M{"_id": mongoUserId}, options.FindOne().SetProjection(userCredentialsProjection)).Decode(&user)
 	return &user, err
 }
 
-func (r *UserRepository) GetUserProfileByEmailId(ctx context.Context, emailId string) (*models.User, error) {
+func (r *MongoUserRepository) GetUserProfileById(ctx context.Context, mongoUserId primitive.ObjectID) (*models.User, error) {
 	var user models.User
-	err := r.Collection.FindOne(ctx, bson.M{"email": emailId}, options.FindOne().SetProjection(bson.M{"password": 0, "refreshToken": 0})).Decode(&user)
+	err := r.Collection.FindOne(ctx, bson.M{"_id": mongoUserId}, options.FindOne().SetProjection(userProfileProjection)).Decode(&user)
 	return &user, err
 }
 
-func (r *UserRepository) UpdateUser(ctx context.Context, userID primitive.ObjectID, updateData bson.M) error {
+func (r *MongoUserRepository) GetUserProfileByEmailId(ctx context.Context, emailId string) (*models.User, error) {
+	var user models.User
+	err := r.Collection.FindOne(ctx, bson.M{"email": models.NormalizeEmail(emailId)}, options.FindOne().SetProjection(userProfileProjection)).Decode(&user)
+	return &user, err
+}
+
+// SearchUsers finds users whose name or email contains the query, ignoring case
+func (r *MongoUserRepository) SearchUsers(ctx context.Context, query string, limit int64) ([]models.User, error) {
+	pattern := primitive.Regex{Pattern: regexp.QuoteMeta(query), Options: "i"}
+	filter := bson.M{"$or": bson.A{bson.M{"name": pattern}, bson.M{"email": pattern}}}
+	opts := options.Find().
+		SetProjection(userProfileProjection).
+		SetSort(bson.M{"email": 1}).
+		SetLimit(limit)
+
+	cursor, err := r.Collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	users := []models.User{}
+	if err := cursor.All(ctx, &users); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+func (r *MongoUserRepository) UpdateUser(ctx context.Context, userID primitive.ObjectID, updateData bson.M) error {
 	filter := bson.M{"_id": userID} // Find user by ID
 
 	update := bson.M{"$set": updateData} // Update fields
@@ -51,3 +121,115 @@ func (r *UserRepository) UpdateUser(ctx context.Context, userID primitive.Object
 	_, err := r.Collection.UpdateOne(ctx, filter, update)
 	return err
 }
+
+func (r *MongoUserRepository) UnsetUserFields(ctx context.Context, userID primitive.ObjectID, fields ...string) error {
+	unset := bson.M{}
+	for _, field := range fields {
+		unset[field] = ""
+	}
+
+	_, err := r.Collection.UpdateOne(ctx, bson.M{"_id": userID}, bson.M{"$unset": unset})
+	return err
+}
+
+// SetMfaLastUsedCounter only moves the counter forward, so the same totp code cannot be accepted twice
+func (r *MongoUserRepository) SetMfaLastUsedCounter(ctx context.Context, userID primitive.ObjectID, counter int64) error {
+	filter := bson.M{"_id": userID, "$or": bson.A{
+		bson.M{"mfa.lastUsedCounter": bson.M{"$lt": counter}},
+		bson.M{"mfa.lastUsedCounter": bson.M{"$exists": false}},
+	}}
+
+	result, err := r.Collection.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"mfa.lastUsedCounter": counter}})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// ConsumeRecoveryCode removes the recovery code hash, failing when it was not there
+func (r *MongoUserRepository) ConsumeRecoveryCode(ctx context.Context, userID primitive.ObjectID, codeHash string) error {
+	filter := bson.M{"_id": userID, "mfa.recoveryCodeHashes": codeHash}
+
+	result, err := r.Collection.UpdateOne(ctx, filter, bson.M{"$pull": bson.M{"mfa.recoveryCodeHashes": codeHash}})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// AddUserIdentity links a provider account, one identity per provider
+func (r *MongoUserRepository) AddUserIdentity(ctx context.Context, userID primitive.ObjectID, identity models.Identity) error {
+	filter := bson.M{"_id": userID, "identities.provider": bson.M{"$ne": identity.Provider}}
+
+	result, err := r.Collection.UpdateOne(ctx, filter, bson.M{"$push": bson.M{"identities": identity}})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+func (r *MongoUserRepository) RemoveUserIdentity(ctx context.Context, userID primitive.ObjectID, provider string) error {
+	result, err := r.Collection.UpdateOne(ctx, bson.M{"_id": userID}, bson.M{"$pull": bson.M{"identities": bson.M{"provider": provider}}})
+	if err != nil {
+		return err
+	}
+	if result.ModifiedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// ScheduleUserDeletion marks the account for deletion, it fails with mongo.ErrNoDocuments when a deletion is already scheduled
+func (r *MongoUserRepository) ScheduleUserDeletion(ctx context.Context, userID primitive.ObjectID, requestedAt time.Time, scheduledAt time.Time) error {
+	filter := bson.M{"_id": userID, "deletionScheduledAt": bson.M{"$exists": false}}
+	update := bson.M{"$set": bson.M{"deletionRequestedAt": requestedAt, "deletionScheduledAt": scheduledAt}}
+
+	result, err := r.Collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// CancelUserDeletion restores an account during its grace period, it fails with mongo.ErrNoDocuments when no deletion is scheduled
+func (r *MongoUserRepository) CancelUserDeletion(ctx context.Context, userID primitive.ObjectID) error {
+	filter := bson.M{"_id": userID, "deletionScheduledAt": bson.M{"$exists": true}}
+	update := bson.M{"$unset": bson.M{"deletionRequestedAt": "", "deletionScheduledAt": ""}}
+
+	result, err := r.Collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// GetUsersDueForDeletion returns the id and email of the accounts whose grace period is over
+func (r *MongoUserRepository) GetUsersDueForDeletion(ctx context.Context, now time.Time) ([]models.User, error) {
+	opts := options.Find().SetProjection(bson.M{"_id": 1, "email": 1, "deletionScheduledAt": 1})
+	cursor, err := r.Collection.Find(ctx, bson.M{"deletionScheduledAt": bson.M{"$lte": now}}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	users := []models.User{}
+	if err := cursor.All(ctx, &users); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
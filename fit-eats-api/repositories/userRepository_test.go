@@ -0,0 +1,225 @@
+package repositories
+
+import (
+	"context"
+	"fit-eats-api/models"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func createTestUser(t *testing.T, users UserRepository, name string, email string) *models.User {
+	t.Helper()
+	user := &models.User{
+		Name:       name,
+		Email:      email,
+		Password:   "hash-" + name,
+		Role:       models.ROLE_USER,
+		HeightInCm: 180,
+		Mfa:        &models.MfaSettings{Enabled: true, Secret: "secret", RecoveryCodeHashes: []string{"code-1", "code-2"}},
+	}
+	expectNoError(t, users.CreateUser(context.Background(), user))
+	return user
+}
+
+func TestUserRepositoryProjections(t *testing.T) {
+	runRepositoryTest(t, func(t *testing.T, stores repositoryStores) {
+		ctx := context.Background()
+		user := createTestUser(t, stores.users, "Asha", "asha@fiteats.test")
+		if user.ID.IsZero() {
+			t.Fatal("CreateUser did not set the id")
+		}
+
+		profile, err := stores.users.GetUserProfileById(ctx, user.ID)
+		expectNoError(t, err)
+		if profile.Name != "Asha" || profile.HeightInCm != 180 || profile.Password != "" {
+			t.Errorf("profile has the wrong fields: %+v", profile)
+		}
+		if profile.Mfa == nil || !profile.Mfa.Enabled || profile.Mfa.Secret != "" || profile.Mfa.RecoveryCodeHashes != nil {
+			t.Errorf("profile mfa leaks the secrets: %+v", profile.Mfa)
+		}
+
+		credentials, err := stores.users.GetUserByEmail(ctx, "asha@fiteats.test")
+		expectNoError(t, err)
+		if credentials.ID != user.ID || credentials.Password != "hash-Asha" || credentials.Mfa.Secret != "secret" || credentials.HeightInCm != 0 {
+			t.Errorf("credentials have the wrong fields: %+v", credentials)
+		}
+
+		_, err = stores.users.GetUserProfileById(ctx, primitive.NewObjectID())
+		expectNoDocuments(t, err)
+		_, err = stores.users.GetUserProfileByEmailId(ctx, "nobody@fiteats.test")
+		expectNoDocuments(t, err)
+	})
+}
+
+func TestUserRepositoryUniqueEmail(t *testing.T) {
+	runRepositoryTest(t, func(t *testing.T, stores repositoryStores) {
+		createTestUser(t, stores.users, "Asha", "asha@fiteats.test")
+
+		err := stores.users.CreateUser(context.Background(), &models.User{Name: "Other Asha", Email: "asha@fiteats.test"})
+		if !mongo.IsDuplicateKeyError(err) {
+			t.Fatalf("expected a duplicate key error, got %v", err)
+		}
+	})
+}
+
+func TestUserRepositoryEmailCase(t *testing.T) {
+	runRepositoryTest(t, func(t *testing.T, stores repositoryStores) {
+		ctx := context.Background()
+		user := createTestUser(t, stores.users, "Asha", " Asha@FitEats.test")
+		if user.Email != "asha@fiteats.test" {
+			t.Errorf("expected the email to be normalized, got %q", user.Email)
+		}
+
+		credentials, err := stores.users.GetUserByEmail(ctx, "ASHA@fiteats.test ")
+		expectNoError(t, err)
+		if credentials.ID != user.ID {
+			t.Errorf("expected the lookup to ignore the case, got %+v", credentials)
+		}
+		profile, err := stores.users.GetUserProfileByEmailId(ctx, "asha@FITEATS.test")
+		expectNoError(t, err)
+		if profile.ID != user.ID {
+			t.Errorf("expected the profile lookup to ignore the case, got %+v", profile)
+		}
+
+		err = stores.users.CreateUser(ctx, &models.User{Name: "Other Asha", Email: "asha@fiteats.TEST"})
+		if !mongo.IsDuplicateKeyError(err) {
+			t.Fatalf("expected a duplicate key error, got %v", err)
+		}
+	})
+}
+
+func TestUserRepositoryUpdates(t *testing.T) {
+	runRepositoryTest(t, func(t *testing.T, stores repositoryStores) {
+		ctx := context.Background()
+		user := createTestUser(t, stores.users, "Asha", "asha@fiteats.test")
+
+		expectNoError(t, stores.users.UpdateUser(ctx, user.ID, bson.M{"heightInCm": 170.5, "mfa.pendingSecret": "pending"}))
+		credentials, err := stores.users.GetUserCredentialsById(ctx, user.ID)
+		expectNoError(t, err)
+		if credentials.Mfa.PendingSecret != "pending" || credentials.Mfa.Secret != "secret" {
+			t.Errorf("dotted update replaced the mfa settings: %+v", credentials.Mfa)
+		}
+
+		expectNoError(t, stores.users.UnsetUserFields(ctx, user.ID, "mfa"))
+		profile, err := stores.users.GetUserProfileById(ctx, user.ID)
+		expectNoError(t, err)
+		if profile.HeightInCm != 170.5 || profile.Mfa != nil {
+			t.Errorf("expected the height updated and mfa removed: %+v", profile)
+		}
+
+		// Updating a missing user is not an error
+		expectNoError(t, stores.users.UpdateUser(ctx, primitive.NewObjectID(), bson.M{"name": "Ghost"}))
+	})
+}
+
+func TestUserRepositoryMfa(t *testing.T) {
+	runRepositoryTest(t, func(t *testing.T, stores repositoryStores) {
+		ctx := context.Background()
+		user := createTestUser(t, stores.users, "Asha", "asha@fiteats.test")
+
+		expectNoError(t, stores.users.SetMfaLastUsedCounter(ctx, user.ID, 5))
+		expectNoDocuments(t, stores.users.SetMfaLastUsedCounter(ctx, user.ID, 5))
+		expectNoDocuments(t, stores.users.SetMfaLastUsedCounter(ctx, user.ID, 4))
+		expectNoError(t, stores.users.SetMfaLastUsedCounter(ctx, user.ID, 6))
+
+		expectNoError(t, stores.users.ConsumeRecoveryCode(ctx, user.ID, "code-1"))
+		expectNoDocuments(t, stores.users.ConsumeRecoveryCode(ctx, user.ID, "code-1"))
+		expectNoDocuments(t, stores.users.ConsumeRecoveryCode(ctx, user.ID, "unknown"))
+
+		credentials, err := stores.users.GetUserCredentialsById(ctx, user.ID)
+		expectNoError(t, err)
+		if credentials.Mfa.LastUsedCounter != 6 || len(credentials.Mfa.RecoveryCodeHashes) != 1 || credentials.Mfa.RecoveryCodeHashes[0] != "code-2" {
+			t.Errorf("unexpected mfa settings: %+v", credentials.Mfa)
+		}
+	})
+}
+
+func TestUserRepositoryIdentities(t *testing.T) {
+	runRepositoryTest(t, func(t *testing.T, stores repositoryStores) {
+		ctx := context.Background()
+		user := createTestUser(t, stores.users, "Asha", "asha@fiteats.test")
+		linkedAt := time.Now()
+
+		expectNoError(t, stores.users.AddUserIdentity(ctx, user.ID, models.Identity{Provider: "google", Subject: "g-1", LinkedAt: linkedAt}))
+		expectNoDocuments(t, stores.users.AddUserIdentity(ctx, user.ID, models.Identity{Provider: "google", Subject: "g-2"}))
+		expectNoError(t, stores.users.AddUserIdentity(ctx, user.ID, models.Identity{Provider: "apple", Subject: "a-1"}))
+
+		found, err := stores.users.GetUserByIdentity(ctx, "google", "g-1")
+		expectNoError(t, err)
+		if found.ID != user.ID || len(found.Identities) != 2 || !found.Identities[0].LinkedAt.Equal(toStoredTime(linkedAt)) {
+			t.Errorf("unexpected identities: %+v", found.Identities)
+		}
+		_, err = stores.users.GetUserByIdentity(ctx, "google", "a-1")
+		expectNoDocuments(t, err)
+
+		expectNoError(t, stores.users.RemoveUserIdentity(ctx, user.ID, "google"))
+		expectNoDocuments(t, stores.users.RemoveUserIdentity(ctx, user.ID, "google"))
+		_, err = stores.users.GetUserByIdentity(ctx, "google", "g-1")
+		expectNoDocuments(t, err)
+	})
+}
+
+func TestUserRepositorySearch(t *testing.T) {
+	runRepositoryTest(t, func(t *testing.T, stores repositoryStores) {
+		ctx := context.Background()
+		createTestUser(t, stores.users, "Zoe Fit", "zoe@fiteats.test")
+		createTestUser(t, stores.users, "Asha", "asha.fit@fiteats.test")
+		createTestUser(t, stores.users, "Ravi", "ravi@fiteats.test")
+		createTestUser(t, stores.users, "Dot", "d.o.t@fiteats.test")
+
+		users, err := stores.users.SearchUsers(ctx, "FIT", 10)
+		expectNoError(t, err)
+		if len(users) != 4 {
+			t.Fatalf("expected every user to match, got %d", len(users))
+		}
+		if users[0].Email != "asha.fit@fiteats.test" || users[3].Email != "zoe@fiteats.test" || users[0].Password != "" {
+			t.Errorf("expected the users sorted by email without passwords: %+v", users)
+		}
+
+		users, err = stores.users.SearchUsers(ctx, "fit", 2)
+		expectNoError(t, err)
+		if len(users) != 2 {
+			t.Errorf("expected the limit to apply, got %d users", len(users))
+		}
+
+		users, err = stores.users.SearchUsers(ctx, "d.o", 10)
+		expectNoError(t, err)
+		if len(users) != 1 || users[0].Name != "Dot" {
+			t.Errorf("expected the query matched literally, got %+v", users)
+		}
+	})
+}
+
+func TestUserRepositoryDeletion(t *testing.T) {
+	runRepositoryTest(t, func(t *testing.T, stores repositoryStores) {
+		ctx := context.Background()
+		now := time.Now()
+		due := createTestUser(t, stores.users, "Asha", "asha@fiteats.test")
+		later := createTestUser(t, stores.users, "Ravi", "ravi@fiteats.test")
+
+		expectNoError(t, stores.users.ScheduleUserDeletion(ctx, due.ID, now.Add(-time.Hour), now.Add(-time.Minute)))
+		expectNoDocuments(t, stores.users.ScheduleUserDeletion(ctx, due.ID, now, now))
+		expectNoError(t, stores.users.ScheduleUserDeletion(ctx, later.ID, now, now.Add(time.Hour)))
+
+		users, err := stores.users.GetUsersDueForDeletion(ctx, now)
+		expectNoError(t, err)
+		if len(users) != 1 || users[0].ID != due.ID || users[0].Name != "" {
+			t.Fatalf("expected only the id and email of the due user, got %+v", users)
+		}
+		if !users[0].DeletionScheduledAt.Equal(toStoredTime(now.Add(-time.Minute))) || users[0].DeletionScheduledAt.Location() != time.UTC {
+			t.Errorf("expected the scheduled time as stored, got %v", users[0].DeletionScheduledAt)
+		}
+
+		expectNoError(t, stores.users.CancelUserDeletion(ctx, later.ID))
+		expectNoDocuments(t, stores.users.CancelUserDeletion(ctx, later.ID))
+		profile, err := stores.users.GetUserProfileById(ctx, later.ID)
+		expectNoError(t, err)
+		if profile.DeletionRequestedAt != nil || profile.DeletionScheduledAt != nil {
+			t.Errorf("expected the deletion cancelled: %+v", profile)
+		}
+	})
+}
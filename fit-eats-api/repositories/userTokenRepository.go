@@ -0,0 +1,49 @@
+package repositories
+
+import (
+	"context"
+	"fit-eats-api/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type UserTokenRepository struct {
+	Collection *mongo.Collection
+}
+
+func NewUserTokenRepository(db *mongo.Database) *UserTokenRepository {
+	return &UserTokenRepository{
+		Collection: db.Collection("userTokens"),
+	}
+}
+
+func (r *UserTokenRepository) CreateUserToken(ctx context.Context, userToken *models.UserToken) error {
+	userToken.ID = primitive.NewObjectID()
+	_, err := r.Collection.InsertOne(ctx, userToken)
+	return err
+}
+
+// ConsumeUserToken marks an unused and unexpired token as used and returns it, so a token can only be used once
+func (r *UserTokenRepository) ConsumeUserToken(ctx context.Context, purpose models.TokenPurpose, tokenHash string) (*models.UserToken, error) {
+	now := time.Now()
+	filter := bson.M{"tokenHash": tokenHash, "purpose": purpose, "usedAt": nil, "expiresAt": bson.M{"$gt": now}}
+	update := bson.M{"$set": bson.M{"usedAt": now}}
+
+	var userToken models.UserToken
+	err := r.Collection.FindOneAndUpdate(ctx, filter, update, options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&userToken)
+	if err != nil {
+		return nil, err
+	}
+	return &userToken, nil
+}
+
+// InvalidateUserTokens marks every outstanding token of a purpose as used, so only the latest email works
+func (r *UserTokenRepository) InvalidateUserTokens(ctx context.Context, userId primitive.ObjectID, purpose models.TokenPurpose) error {
+	filter := bson.M{"userId": userId, "purpose": purpose, "usedAt": nil}
+	_, err := r.Collection.UpdateMany(ctx, filter, bson.M{"$set": bson.M{"usedAt": time.Now()}})
+	return err
+}
@@ -0,0 +1,91 @@
+package repositories
+
+import (
+	"context"
+	"fit-eats-api/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type WorkoutRepository struct {
+	Collection *mongo.Collection
+}
+
+func NewWorkoutRepository(db *mongo.Database) *WorkoutRepository {
+	return &WorkoutRepository{
+		Collection: db.Collection("workoutRoutines"),
+	}
+}
+
+func (r *WorkoutRepository) CreateWorkoutRoutine(ctx context.Context, routine *models.WorkoutRoutine) error {
+	_, err := r.Collection.InsertOne(ctx, routine)
+	return err
+}
+
+func (r *WorkoutRepository) IsWorkoutRoutineCreated(ctx context.Context, userId primitive.ObjectID, weeklyGoalId primitive.ObjectID) bool {
+	var routine models.WorkoutRoutine
+	err := r.Collection.FindOne(ctx, bson.M{"userId": userId, "weeklyGoalId": weeklyGoalId}, options.FindOne().SetProjection(bson.M{"workoutDays": 0})).Decode(&routine)
+
+	return err == nil
+}
+
+func (r *WorkoutRepository) GetWorkoutRoutine(ctx context.Context, userId primitive.ObjectID, mainGoalId primitive.ObjectID, weeklyGoalId primitive.ObjectID) (*models.WorkoutRoutine, error) {
+	var routine models.WorkoutRoutine
+
+	err := r.Collection.FindOne(ctx, bson.M{"userId": userId, "mainGoalId": mainGoalId, "weeklyGoalId": weeklyGoalId}).Decode(&routine)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &routine, nil
+}
+
+// GetWorkoutDayByDate returns the workout day of the user between the given times, nil if there is none
+func (r *WorkoutRepository) GetWorkoutDayByDate(ctx context.Context, userId primitive.ObjectID, startOfDay time.Time, endOfDay time.Time) (*models.WorkoutDay, error) {
+	filter := bson.M{
+		"userId": userId,
+		"workoutDays.date": bson.M{
+			"$gte": startOfDay,
+			"$lt":  endOfDay,
+		},
+	}
+
+	projection := bson.M{
+		"workoutDays": bson.M{
+			"$filter": bson.M{
+				"input": "$workoutDays",
+				"as":    "wd",
+				"cond": bson.M{
+					"$and": []bson.M{
+						{"$gte": []any{"$$wd.date", startOfDay}},
+						{"$lt": []any{"$$wd.date", endOfDay}},
+					},
+				},
+			},
+		},
+	}
+
+	var result struct {
+		WorkoutDays []models.WorkoutDay `bson:"workoutDays"`
+	}
+
+	err := r.Collection.
+		FindOne(ctx, filter, options.FindOne().SetProjection(projection)).
+		Decode(&result)
+
+	if err != nil && err != mongo.ErrNoDocuments {
+		return nil, err
+	}
+	if err == mongo.ErrNoDocuments || len(result.WorkoutDays) == 0 {
+		return nil, nil
+	}
+
+	return &result.WorkoutDays[0], nil
+}
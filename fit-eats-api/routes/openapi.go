@@ -0,0 +1,310 @@
+package routes
+
+import (
+	"fit-eats-api/middleware"
+	"fit-eats-api/models"
+	"fit-eats-api/openapi"
+	"net/http"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const OPENAPI_PATH = "/api/openapi.json"
+
+type messageResponse struct {
+	Message string `json:"message"`
+}
+
+type successResponse struct {
+	Success bool `json:"success"`
+}
+
+// loginResponse covers both steps of a login, the tokens are only sent once the second factor is verified
+type loginResponse struct {
+	AccessToken  string       `json:"accessToken,omitempty"`
+	RefreshToken string       `json:"refreshToken,omitempty"`
+	User         *models.User `json:"user,omitempty"`
+	MfaRequired  bool         `json:"mfaRequired,omitempty"`
+	MfaToken     string       `json:"mfaToken,omitempty"`
+	Message      string       `json:"message,omitempty"`
+}
+
+type authorizationResponse struct {
+	AuthorizationUrl string `json:"authorizationUrl"`
+	State            string `json:"state"`
+}
+
+type sessionsResponse struct {
+	Sessions []models.Session `json:"sessions"`
+}
+
+type coachLinksResponse struct {
+	Clients     []models.CoachLink `json:"clients,omitempty"`
+	Coaches     []models.CoachLink `json:"coaches,omitempty"`
+	Invitations []models.CoachLink `json:"invitations,omitempty"`
+}
+
+// estimateResponse follows the response schema of the generative model behind each estimate
+type estimateResponse map[string]any
+
+func required(names ...string) []openapi.Param {
+	return params(names, true, false)
+}
+
+func optional(names ...string) []openapi.Param {
+	return params(names, false, false)
+}
+
+func requiredIds(names ...string) []openapi.Param {
+	return params(names, true, true)
+}
+
+func optionalIds(names ...string) []openapi.Param {
+	return params(names, false, true)
+}
+
+func params(names []string, required bool, id bool) []openapi.Param {
+	params := make([]openapi.Param, len(names))
+	for i, name := range names {
+		params[i] = openapi.Param{Name: name, Required: required, Id: id}
+	}
+	return params
+}
+
+func join(groups ...[]openapi.Param) []openapi.Param {
+	var params []openapi.Param
+	for _, group := range groups {
+		params = append(params, group...)
+	}
+	return params
+}
+
+// getOpenApiEnums lists the values of the string types the clients switch on
+func getOpenApiEnums() map[reflect.Type][]any {
+	errorCodes := []any{}
+	for _, code := range middleware.GetErrorCodes() {
+		errorCodes = append(errorCodes, code)
+	}
+
+	return map[reflect.Type][]any{
+		reflect.TypeOf(models.Role("")):             {models.ROLE_USER, models.ROLE_COACH, models.ROLE_ADMIN},
+		reflect.TypeOf(models.GoalType("")):         {models.FAT_LOSS, models.MUSCLE_GAIN, models.RECOMPOSITION, models.MAINTENANCE, models.EVENT},
+		reflect.TypeOf(models.GoalStatus("")):       {models.GOAL_ACTIVE, models.GOAL_COMPLETED, models.GOAL_ABANDONED, models.GOAL_ARCHIVED},
+		reflect.TypeOf(models.CoachLinkStatus("")):  {models.COACH_LINK_PENDING, models.COACH_LINK_ACTIVE, models.COACH_LINK_DECLINED, models.COACH_LINK_ENDED},
+		reflect.TypeOf(models.DataExportStatus("")): {models.DATA_EXPORT_PENDING, models.DATA_EXPORT_READY, models.DATA_EXPORT_FAILED},
+		reflect.TypeOf(models.ErrorCode("")):        errorCodes,
+	}
+}
+
+var goalQuery = requiredIds("userId", "mainGoalId", "weeklyGoalId")
+var auditQuery = optional("action", "from", "to", "page", "pageSize")
+
+// routeDocs documents the routes by method and gin path, every registered route must have an entry
+var routeDocs = map[string]openapi.Route{
+	"GET " + OPENAPI_PATH: {Summary: "This document", Public: true, Responses: map[int]any{200: map[string]any{}}},
+
+	"POST /api/register":             {Summary: "Register a user with a password", Public: true, Body: models.User{}, Responses: map[int]any{201: messageResponse{}}},
+	"POST /api/login":                {Summary: "Log in, asks for a second factor when enabled", Public: true, Form: join(required("email", "password"), optional("deviceName")), Responses: map[int]any{200: loginResponse{}}},
+	"POST /api/requestAccessToken":   {Summary: "Rotate the refresh token of the session", Public: true, Form: required("refreshToken"), Responses: map[int]any{200: loginResponse{}}},
+	"POST /api/requestPasswordReset": {Summary: "Email a password reset link", Public: true, Form: required("email"), Responses: map[int]any{200: messageResponse{}}},
+	"POST /api/confirmPasswordReset": {Summary: "Set a new password with a reset token", Public: true, Form: required("token", "password"), Responses: map[int]any{200: messageResponse{}}},
+	"POST /api/verifyEmail":          {Summary: "Verify the email with the emailed token", Public: true, Form: required("token"), Responses: map[int]any{200: messageResponse{}}},
+	"POST /api/unlockAccount":        {Summary: "Unlock a login locked after failed attempts", Public: true, Form: required("token"), Responses: map[int]any{200: messageResponse{}}},
+	"POST /api/verifyMfaLogin":       {Summary: "Complete a login with a totp or recovery code", Public: true, Form: join(required("mfaToken"), optional("code", "recoveryCode", "deviceName")), Responses: map[int]any{200: loginResponse{}}},
+
+	"PUT /api/profile": {Summary: "Update the profile", Body: models.User{}, Responses: map[int]any{201: messageResponse{}}},
+	"GET /api/profile": {Summary: "Get a profile by email", Query: required("emailId"), Responses: map[int]any{201: struct {
+		User *models.User `json:"user"`
+	}{}}},
+	"GET /api/dietOptions": {Summary: "Diet patterns and cuisines to pick from", Responses: map[int]any{200: struct {
+		DietPatterns   []models.DietRule `json:"dietPatterns"`
+		Cuisines       []string          `json:"cuisines"`
+		MaxMealsPerDay int               `json:"maxMealsPerDay"`
+	}{}}},
+	"POST /api/logout":                {Summary: "Revoke the current session", Responses: map[int]any{200: messageResponse{}}},
+	"POST /api/sendVerificationEmail": {Summary: "Email a new verification link", Responses: map[int]any{200: messageResponse{}}},
+	"POST /api/enrollMfa": {Summary: "Start the totp enrolment", Responses: map[int]any{200: struct {
+		Secret     string `json:"secret"`
+		OtpauthUri string `json:"otpauthUri"`
+	}{}}},
+	"POST /api/activateMfa": {Summary: "Enable totp with a first code", Form: required("code"), Responses: map[int]any{200: struct {
+		Message       string   `json:"message"`
+		RecoveryCodes []string `json:"recoveryCodes"`
+	}{}}},
+	"POST /api/disableMfa": {Summary: "Disable totp", Form: join(required("password"), optional("code", "recoveryCode")), Responses: map[int]any{200: messageResponse{}}},
+	"POST /api/regenerateRecoveryCodes": {Summary: "Replace the recovery codes", Form: required("code"), Responses: map[int]any{200: struct {
+		RecoveryCodes []string `json:"recoveryCodes"`
+	}{}}},
+	"GET /api/getSessions":          {Summary: "Active sessions of the caller", Responses: map[int]any{200: sessionsResponse{}}},
+	"DELETE /api/revokeSession":     {Summary: "Revoke a session of the caller", Query: requiredIds("sessionId"), Responses: map[int]any{200: messageResponse{}}},
+	"POST /api/revokeOtherSessions": {Summary: "Revoke every other session of the caller", Responses: map[int]any{200: messageResponse{}}},
+
+	"GET /api/oidcProviders": {Summary: "Configured social logins", Public: true, Responses: map[int]any{200: struct {
+		Providers []string `json:"providers"`
+	}{}}},
+	"GET /api/oidcLogin":         {Summary: "Start a social login", Public: true, Query: required("provider"), Responses: map[int]any{200: authorizationResponse{}}},
+	"POST /api/oidcCallback":     {Summary: "Complete a social login or link", Public: true, Form: required("provider", "state", "code"), Responses: map[int]any{200: loginResponse{}}},
+	"GET /api/linkIdentity":      {Summary: "Start linking a social login", Query: required("provider"), Responses: map[int]any{200: authorizationResponse{}}},
+	"DELETE /api/unlinkIdentity": {Summary: "Unlink a social login", Query: required("provider"), Responses: map[int]any{200: messageResponse{}}},
+
+	"GET /api/getIdealWeight":      {Summary: "Estimate the ideal weight range", Query: join(requiredIds("userId"), required("currentWeightInKg", "currentBodyFatPercentage")), Responses: map[int]any{200: estimateResponse{}}},
+	"GET /api/getGoalDuration":     {Summary: "Estimate the duration of a goal", Query: join(requiredIds("userId"), required("currentWeightInKg", "goalWeightInKg", "currentBodyFatPercentage", "goalBodyFatPercentage")), Responses: map[int]any{200: estimateResponse{}}},
+	"GET /api/getTdee":             {Summary: "Estimate the daily energy expenditure", Query: join(requiredIds("userId"), required("currentWeightInKg", "goalWeightInKg", "currentBodyFatPercentage", "goalBodyFatPercentage", "goalType")), Responses: map[int]any{200: estimateResponse{}}},
+	"GET /api/getMacros":           {Summary: "Estimate the daily macros", Query: join(requiredIds("userId"), required("currentWeightInKg", "goalWeightInKg", "currentBodyFatPercentage", "goalBodyFatPercentage", "goalType", "currentBmr", "currentTdee", "weightChange")), Responses: map[int]any{200: estimateResponse{}}},
+	"POST /api/registerMainGoal":   {Summary: "Create the main goal", Body: models.Goal{}, Responses: map[int]any{201: messageResponse{}}},
+	"POST /api/registerWeeklyGoal": {Summary: "Add a weekly goal", Query: requiredIds("mainGoalId"), Body: models.WeeklyGoal{}, Responses: map[int]any{201: messageResponse{}}},
+	"GET /api/getGoals": {Summary: "Active main goal of the user", Query: requiredIds("userId"), Responses: map[int]any{200: struct {
+		UserGoals *models.Goal `json:"userGoals"`
+	}{}}},
+	"GET /api/getActiveGoal":       {Summary: "Main goal with the weekly goal running today", Query: requiredIds("userId"), Responses: map[int]any{200: models.Goal{}}},
+	"PATCH /api/updateGoalStatus":  {Summary: "Complete or abandon the active goal, or archive an ended goal", Query: requiredIds("goalId"), Body: models.GoalStatusUpdate{}, Responses: map[int]any{200: models.Goal{}}},
+	"DELETE /api/deleteMainGoal":   {Summary: "Delete the main goal", Query: requiredIds("goalId"), Responses: map[int]any{200: successResponse{}}},
+	"DELETE /api/goals":            {Summary: "Delete the main goal", Query: requiredIds("goalId"), Responses: map[int]any{200: successResponse{}}},
+	"PATCH /api/updateWeeklyGoal":  {Summary: "Edit the weight and targets of a weekly goal", Query: requiredIds("goalId", "weeklyGoalId"), Body: models.WeeklyGoalUpdate{}, Responses: map[int]any{200: models.WeeklyGoal{}}},
+	"DELETE /api/deleteWeeklyGoal": {Summary: "Delete a weekly goal", Query: requiredIds("goalId", "weeklyGoalId"), Responses: map[int]any{200: successResponse{}}},
+
+	"GET /api/getMealPlan":        {Summary: "Meal plan of a weekly goal", Query: goalQuery, Responses: map[int]any{200: models.MealPlan{}}},
+	"GET /api/getNutritionReport": {Summary: "Micronutrients of a meal plan against the reference intake", Query: goalQuery, Responses: map[int]any{200: models.NutritionReport{}}},
+	"POST /api/createMealPlan":    {Summary: "Generate the meal plan of a weekly goal", Query: join(goalQuery, optional("prompt")), Responses: map[int]any{200: models.MealPlan{}}},
+	"PUT /api/customizeMealPlan": {Summary: "Regenerate the meals of a day", Query: join(requiredIds("mealPlanId", "dayMealId"), required("userPrompt")), Responses: map[int]any{200: struct {
+		MealPlanId   string `json:"mealPlanId"`
+		MainGoalId   string `json:"mainGoalId"`
+		WeeklyGoalId string `json:"weeklyGoalId"`
+	}{}}},
+	"PUT /api/consumeMeal": {Summary: "Mark a meal consumed", Query: requiredIds("mealId"), Responses: map[int]any{200: struct {
+		Sucess bool `json:"sucess"`
+	}{}}},
+
+	"GET /api/getDashboard": {Summary: "Today at a glance", Query: requiredIds("userId"), Responses: map[int]any{200: models.DashboardResponse{}}},
+
+	"GET /api/getHydration":      {Summary: "Water logged on a day", Query: join(requiredIds("userId"), optional("date")), Responses: map[int]any{200: models.HydrationSummary{}}},
+	"POST /api/logWater":         {Summary: "Log a drink", Body: models.HydrationLog{}, Responses: map[int]any{201: models.HydrationLog{}}},
+	"POST /api/quickAddWater":    {Summary: "Log a preset amount", Query: join(requiredIds("userId"), required("preset"), optional("beverageType")), Responses: map[int]any{201: models.HydrationLog{}}},
+	"DELETE /api/deleteWaterLog": {Summary: "Delete a drink", Query: requiredIds("userId", "logId"), Responses: map[int]any{200: successResponse{}}},
+
+	"GET /api/getExercises": {Summary: "Exercise catalogue", Responses: map[int]any{200: struct {
+		Exercises []models.Exercise `json:"exercises"`
+	}{}}},
+	"GET /api/getWorkoutRoutine":     {Summary: "Workout routine of a weekly goal", Query: goalQuery, Responses: map[int]any{200: models.WorkoutRoutine{}}},
+	"POST /api/createWorkoutRoutine": {Summary: "Generate the workout routine of a weekly goal", Query: join(goalQuery, optional("prompt")), Responses: map[int]any{200: models.WorkoutRoutine{}}},
+
+	"GET /api/getActivity":             {Summary: "Workouts and activities of a day", Query: join(requiredIds("userId"), optional("date")), Responses: map[int]any{200: models.ActivitySummary{}}},
+	"POST /api/logWorkoutSession":      {Summary: "Log a workout session", Body: models.WorkoutSession{}, Responses: map[int]any{201: models.WorkoutSession{}}},
+	"POST /api/logActivity":            {Summary: "Log an activity", Body: models.ActivityLog{}, Responses: map[int]any{201: models.ActivityLog{}}},
+	"DELETE /api/deleteWorkoutSession": {Summary: "Delete a workout session", Query: requiredIds("userId", "sessionId"), Responses: map[int]any{200: successResponse{}}},
+	"DELETE /api/deleteActivityLog":    {Summary: "Delete an activity", Query: requiredIds("userId", "activityLogId"), Responses: map[int]any{200: successResponse{}}},
+
+	"POST /api/inviteClient":            {Summary: "Invite a client, coaches only", Form: required("email"), Responses: map[int]any{201: models.CoachLink{}}},
+	"GET /api/getClients":               {Summary: "Clients of the coach", Responses: map[int]any{200: coachLinksResponse{}}},
+	"GET /api/getCoachOverview":         {Summary: "Adherence and alerts of the clients of the coach", Query: optional("sortBy", "order", "page", "pageSize"), Responses: map[int]any{200: models.CoachOverview{}}},
+	"POST /api/inviteCoach":             {Summary: "Invite a coach", Form: required("email"), Responses: map[int]any{201: models.CoachLink{}}},
+	"GET /api/getCoachInvitations":      {Summary: "Pending invitations sent to the caller", Responses: map[int]any{200: coachLinksResponse{}}},
+	"PUT /api/respondToCoachInvitation": {Summary: "Accept or decline an invitation", Form: join(requiredIds("coachLinkId"), required("accept")), Responses: map[int]any{200: messageResponse{}}},
+	"GET /api/getCoaches":               {Summary: "Coaches of the caller", Responses: map[int]any{200: coachLinksResponse{}}},
+	"DELETE /api/endCoachLink":          {Summary: "End a coaching", Query: requiredIds("coachLinkId"), Responses: map[int]any{200: messageResponse{}}},
+
+	"GET /api/admin/searchUsers": {Summary: "Search users by name or email", Query: join(required("query"), optional("limit")), Responses: map[int]any{200: struct {
+		Users []models.User `json:"users"`
+	}{}}},
+	"PUT /api/admin/setUserRole": {Summary: "Change the role of a user, which logs them out", Form: join(requiredIds("userId"), required("role")), Responses: map[int]any{200: messageResponse{}}},
+	"POST /api/admin/resetPassword": {Summary: "Reset the password of a user", Form: requiredIds("userId"), Responses: map[int]any{200: struct {
+		Message           string `json:"message"`
+		TemporaryPassword string `json:"temporaryPassword"`
+	}{}}},
+	"POST /api/admin/revokeSessions": {Summary: "Log a user out everywhere", Form: requiredIds("userId"), Responses: map[int]any{200: messageResponse{}}},
+	"GET /api/admin/getUserGoal":     {Summary: "Main goal of a user", Query: requiredIds("userId"), Responses: map[int]any{200: models.Goal{}}},
+	"DELETE /api/admin/deleteGoal":   {Summary: "Delete a main goal", Query: requiredIds("goalId"), Responses: map[int]any{200: messageResponse{}}},
+	"GET /api/admin/getMealPlans": {Summary: "Meal plans of a user", Query: requiredIds("userId"), Responses: map[int]any{200: struct {
+		MealPlans []models.MealPlan `json:"mealPlans"`
+	}{}}},
+	"DELETE /api/admin/deleteMealPlan": {Summary: "Delete a meal plan", Query: requiredIds("mealPlanId"), Responses: map[int]any{200: messageResponse{}}},
+	"POST /api/admin/refreshMealImages": {Summary: "Look up the images of a meal plan again", Form: requiredIds("mealPlanId"), Responses: map[int]any{200: struct {
+		Message string `json:"message"`
+		Meals   int    `json:"meals"`
+	}{}}},
+
+	"GET /api/getAuditLogs":       {Summary: "Audit history of the caller", Query: auditQuery, Responses: map[int]any{200: auditLogsResponse{}}},
+	"GET /api/admin/getAuditLogs": {Summary: "Audit history of every user", Query: join(auditQuery, optionalIds("userId", "actorId")), Responses: map[int]any{200: auditLogsResponse{}}},
+
+	"DELETE /api/account": {Summary: "Schedule the deletion of the account", Body: struct {
+		Password     string `json:"password"`
+		Code         string `json:"code,omitempty"`
+		RecoveryCode string `json:"recoveryCode,omitempty"`
+	}{}, Responses: map[int]any{202: struct {
+		Message             string    `json:"message"`
+		DeletionScheduledAt time.Time `json:"deletionScheduledAt"`
+	}{}}},
+	"POST /api/account/restore":        {Summary: "Cancel a scheduled deletion", Responses: map[int]any{200: messageResponse{}}},
+	"GET /api/account/export":          {Summary: "Export the personal data, large accounts are exported in the background", Query: optional("async"), Responses: map[int]any{200: openapi.Binary("application/zip"), 202: models.DataExport{}}},
+	"GET /api/account/export/status":   {Summary: "State of a background export", Query: requiredIds("exportId"), Responses: map[int]any{200: models.DataExport{}}},
+	"GET /api/account/export/download": {Summary: "Download a finished export", Query: requiredIds("exportId"), Responses: map[int]any{200: openapi.Binary("application/zip")}},
+
+	"GET /api/v1/users/me":                        {Summary: "Profile of the caller", Responses: map[int]any{200: models.User{}}},
+	"PATCH /api/v1/users/me":                      {Summary: "Update the profile of the caller", Body: models.User{}, Responses: map[int]any{200: models.User{}}},
+	"GET /api/v1/users/me/sessions":               {Summary: "Active sessions of the caller", Responses: map[int]any{200: sessionsResponse{}}},
+	"DELETE /api/v1/users/me/sessions":            {Summary: "Revoke every other session of the caller", Responses: map[int]any{204: nil}},
+	"DELETE /api/v1/users/me/sessions/:sessionId": {Summary: "Revoke a session of the caller", Responses: map[int]any{204: nil}},
+
+	"GET /api/v1/estimates/ideal-weight":  {Summary: "Estimate the ideal weight range", Query: join(requiredIds("userId"), required("currentWeightInKg", "currentBodyFatPercentage")), Responses: map[int]any{200: estimateResponse{}}},
+	"GET /api/v1/estimates/goal-duration": {Summary: "Estimate the duration of a goal", Query: join(requiredIds("userId"), required("currentWeightInKg", "goalWeightInKg", "currentBodyFatPercentage", "goalBodyFatPercentage")), Responses: map[int]any{200: estimateResponse{}}},
+	"GET /api/v1/estimates/tdee":          {Summary: "Estimate the daily energy expenditure", Query: join(requiredIds("userId"), required("currentWeightInKg", "goalWeightInKg", "currentBodyFatPercentage", "goalBodyFatPercentage", "goalType")), Responses: map[int]any{200: estimateResponse{}}},
+	"GET /api/v1/estimates/macros":        {Summary: "Estimate the daily macros", Query: join(requiredIds("userId"), required("currentWeightInKg", "goalWeightInKg", "currentBodyFatPercentage", "goalBodyFatPercentage", "goalType", "currentBmr", "currentTdee", "weightChange")), Responses: map[int]any{200: estimateResponse{}}},
+
+	"GET /api/v1/goals": {Summary: "Goals of the user, the latest started first, archived goals only when asked for by status", Query: join(optionalIds("userId"), optional("status")), Responses: map[int]any{200: struct {
+		Goals []models.Goal `json:"goals"`
+	}{}}},
+	"POST /api/v1/goals":                                             {Summary: "Create the active goal, starting from the last measured weight when no start weight is given, with warnings when its pace is not safe", Body: models.Goal{}, Responses: map[int]any{201: models.Goal{}}},
+	"GET /api/v1/goals/active":                                       {Summary: "Main goal with the weekly goal running today", Query: optionalIds("userId"), Responses: map[int]any{200: models.Goal{}}},
+	"GET /api/v1/goals/:goalId":                                      {Summary: "A main goal", Responses: map[int]any{200: models.Goal{}}},
+	"PATCH /api/v1/goals/:goalId":                                    {Summary: "Complete or abandon the active goal, or archive an ended goal", Body: models.GoalStatusUpdate{}, Responses: map[int]any{200: models.Goal{}}},
+	"DELETE /api/v1/goals/:goalId":                                   {Summary: "Delete a main goal", Responses: map[int]any{204: nil}},
+	"POST /api/v1/goals/:goalId/weeks":                               {Summary: "Add a weekly goal", Body: models.WeeklyGoal{}, Responses: map[int]any{201: models.WeeklyGoal{}}},
+	"GET /api/v1/goals/:goalId/weeks/:weeklyGoalId":                  {Summary: "A weekly goal", Responses: map[int]any{200: models.WeeklyGoal{}}},
+	"PATCH /api/v1/goals/:goalId/weeks/:weeklyGoalId":                {Summary: "Edit the weight and targets of a weekly goal", Body: models.WeeklyGoalUpdate{}, Responses: map[int]any{200: models.WeeklyGoal{}}},
+	"DELETE /api/v1/goals/:goalId/weeks/:weeklyGoalId":               {Summary: "Delete a weekly goal, not when it has consumed meals", Responses: map[int]any{204: nil}},
+	"GET /api/v1/goals/:goalId/weeks/:weeklyGoalId/meal-plan":        {Summary: "Meal plan of a weekly goal", Responses: map[int]any{200: models.MealPlan{}}},
+	"POST /api/v1/goals/:goalId/weeks/:weeklyGoalId/meal-plan":       {Summary: "Generate the meal plan of a weekly goal", Query: optional("prompt"), Responses: map[int]any{201: models.MealPlan{}}},
+	"GET /api/v1/goals/:goalId/weeks/:weeklyGoalId/nutrition-report": {Summary: "Micronutrients of the meal plan against the reference intake", Responses: map[int]any{200: models.NutritionReport{}}},
+
+	"GET /api/v1/meal-plans/:mealPlanId": {Summary: "A meal plan", Responses: map[int]any{200: models.MealPlan{}}},
+	"PATCH /api/v1/meal-plans/:mealPlanId/days/:dayMealId": {Summary: "Regenerate the meals of a day", Body: struct {
+		UserPrompt string `json:"userPrompt"`
+	}{}, Responses: map[int]any{200: models.DayMeal{}}},
+	"PATCH /api/v1/meal-plans/:mealPlanId/days/:dayMealId/meals/:mealId": {Summary: "Mark a meal consumed or not", Body: struct {
+		IsConsumed bool `json:"isConsumed"`
+	}{}, Responses: map[int]any{204: nil}},
+}
+
+type auditLogsResponse struct {
+	AuditLogs []models.AuditLog `json:"auditLogs"`
+	Page      int               `json:"page"`
+	PageSize  int               `json:"pageSize"`
+	Total     int64             `json:"total"`
+}
+
+// SetupOpenApiRoutes serves the OpenAPI document of every route registered on the router, it must be called last
+func SetupOpenApiRoutes(router *gin.Engine) {
+	var document *openapi.Document
+	var buildOnce sync.Once
+
+	router.GET(OPENAPI_PATH, func(ctx *gin.Context) {
+		buildOnce.Do(func() {
+			document = openapi.Build(openapi.Info{Title: "Fit Eats API", Version: "1.0.0"}, router.Routes(), routeDocs, models.ErrorResponse{}, getOpenApiEnums())
+		})
+		ctx.JSON(http.StatusOK, document)
+	})
+}
+
+// GetUndocumentedRoutes lists the registered routes missing from the OpenAPI documentation
+func GetUndocumentedRoutes(router *gin.Engine) []string {
+	var undocumented []string
+	for _, route := range router.Routes() {
+		if _, ok := routeDocs[openapi.RouteKey(route.Method, route.Path)]; !ok {
+			undocumented = append(undocumented, openapi.RouteKey(route.Method, route.Path))
+		}
+	}
+	return undocumented
+}
@@ -3,61 +3,231 @@ package routes
 import (
 	"fit-eats-api/controllers"
 	"fit-eats-api/middleware"
+	"fit-eats-api/models"
 
 	"github.com/gin-gonic/gin"
 )
 
-func SetupUserRoutes(router *gin.Engine, userController *controllers.UserController) {
+func SetupUserRoutes(router *gin.Engine, userController *controllers.UserController, authMiddleware gin.HandlerFunc, auditRecorder *middleware.AuditRecorder) {
 	api := router.Group("/api")
 	{
-		api.POST("/register", userController.Register)
+		// The public routes name the user they acted on for the audit log, there is no signed in user
+		api.POST("/register", auditRecorder.Audit(models.AUDIT_USER_REGISTERED, nil), userController.Register)
 		api.POST("/login", userController.Login)
 		api.POST("/requestAccessToken", userController.RequestAccessToken)
+		api.POST("/requestPasswordReset", auditRecorder.Audit(models.AUDIT_PASSWORD_RESET_REQUESTED, nil), userController.RequestPasswordReset)
+		api.POST("/confirmPasswordReset", auditRecorder.Audit(models.AUDIT_PASSWORD_RESET, nil), userController.ConfirmPasswordReset)
+		api.POST("/verifyEmail", auditRecorder.Audit(models.AUDIT_EMAIL_VERIFIED, nil), userController.VerifyEmail)
+		api.POST("/unlockAccount", userController.UnlockAccount)
+		api.POST("/verifyMfaLogin", userController.VerifyMfaLogin)
 
 		protected := api.Group("/")
-		protected.Use(middleware.AuthMiddleware()) // Apply JWT auth middleware
+		protected.Use(authMiddleware) // Apply JWT auth middleware
 		{
-			protected.PUT("/profile", userController.UpdateUser)
+			protected.PUT("/profile", auditRecorder.Audit(models.AUDIT_PROFILE_UPDATED, userController.ProfileSnapshot), userController.UpdateUser)
 			protected.GET("/profile", userController.GetUser)
-			protected.POST("/logout", userController.LogoutUser)
+			protected.GET("/dietOptions", userController.GetDietOptions)
+			protected.POST("/logout", auditRecorder.Audit(models.AUDIT_LOGGED_OUT, nil), userController.LogoutUser)
+			protected.POST("/sendVerificationEmail", auditRecorder.Audit(models.AUDIT_VERIFICATION_EMAIL_SENT, nil), userController.SendVerificationEmail)
+			protected.POST("/enrollMfa", auditRecorder.Audit(models.AUDIT_MFA_ENROLLMENT_STARTED, nil), userController.EnrollMfa)
+			protected.POST("/activateMfa", auditRecorder.Audit(models.AUDIT_MFA_ENABLED, nil), userController.ActivateMfa)
+			protected.POST("/disableMfa", auditRecorder.Audit(models.AUDIT_MFA_DISABLED, nil), userController.DisableMfa)
+			protected.POST("/regenerateRecoveryCodes", auditRecorder.Audit(models.AUDIT_RECOVERY_CODES_RENEWED, nil), userController.RegenerateRecoveryCodes)
+			protected.GET("/getSessions", userController.GetSessions)
+			protected.DELETE("/revokeSession", auditRecorder.Audit(models.AUDIT_SESSION_REVOKED, nil), userController.RevokeSession)
+			protected.POST("/revokeOtherSessions", auditRecorder.Audit(models.AUDIT_OTHER_SESSIONS_REVOKED, nil), userController.RevokeOtherSessions)
 		}
 	}
 }
 
-func SetupUserGoalRoutes(router *gin.Engine, userGoalController *controllers.UserGoalController) {
+func SetupOidcRoutes(router *gin.Engine, oidcController *controllers.OidcController, authMiddleware gin.HandlerFunc, auditRecorder *middleware.AuditRecorder) {
+	api := router.Group("/api")
+	{
+		api.GET("/oidcProviders", oidcController.GetOidcProviders)
+		api.GET("/oidcLogin", oidcController.StartOidcLogin)
+		// Recorded as a registration when the callback creates the account
+		api.POST("/oidcCallback", auditRecorder.Audit(models.AUDIT_IDENTITY_LINKED, nil), oidcController.OidcCallback)
+
+		protected := api.Group("/")
+		protected.Use(authMiddleware) // Apply JWT auth middleware
+		{
+			protected.GET("/linkIdentity", oidcController.LinkIdentity)
+			protected.DELETE("/unlinkIdentity", auditRecorder.Audit(models.AUDIT_IDENTITY_UNLINKED, nil), oidcController.UnlinkIdentity)
+		}
+	}
+}
+
+func SetupUserGoalRoutes(router *gin.Engine, userGoalController *controllers.UserGoalController, authMiddleware gin.HandlerFunc, verifiedEmailMiddleware gin.HandlerFunc, userAccess *middleware.UserAccess, auditRecorder *middleware.AuditRecorder) {
 	protected := router.Group("/api")
-	protected.Use(middleware.AuthMiddleware()) // Apply JWT auth middleware
+	protected.Use(authMiddleware, userAccess.ClientMiddleware()) // Apply JWT auth middleware
 	{
-		protected.GET("/getIdealWeight", userGoalController.GetIdealWeightRange)
-		protected.GET("/getGoalDuration", userGoalController.GetGoalDuration)
-		protected.GET("/getTdee", userGoalController.GetTdee)
-		protected.GET("/getMacros", userGoalController.GetMacros)
+		protected.GET("/getIdealWeight", verifiedEmailMiddleware, userGoalController.GetIdealWeightRange)
+		protected.GET("/getGoalDuration", verifiedEmailMiddleware, userGoalController.GetGoalDuration)
+		protected.GET("/getTdee", verifiedEmailMiddleware, userGoalController.GetTdee)
+		protected.GET("/getMacros", verifiedEmailMiddleware, userGoalController.GetMacros)
 
-		protected.POST("/registerMainGoal", userGoalController.RegisterUserGoal)
-		protected.POST("/registerWeeklyGoal", userGoalController.RegisterWeeklyUserGoal)
+		protected.POST("/registerMainGoal", auditRecorder.Audit(models.AUDIT_GOAL_CREATED, userGoalController.GoalSnapshot), userGoalController.RegisterUserGoal)
+		protected.POST("/registerWeeklyGoal", auditRecorder.Audit(models.AUDIT_WEEKLY_GOAL_CREATED, userGoalController.GoalSnapshot), userGoalController.RegisterWeeklyUserGoal)
 
 		protected.GET("/getGoals", userGoalController.GetUserGoals)
 		protected.GET("/getActiveGoal", userGoalController.GetActiveUserGoal)
-		protected.DELETE("/deleteMainGoal", userGoalController.DeleteUserMainGoal)
-		protected.DELETE("/deleteWeeklyGoal", userGoalController.DeleteUserWeeklyGoal)
+		protected.DELETE("/deleteMainGoal", auditRecorder.Audit(models.AUDIT_GOAL_DELETED, userGoalController.GoalSnapshot), userGoalController.DeleteUserMainGoal)
+		protected.DELETE("/goals", auditRecorder.Audit(models.AUDIT_GOAL_DELETED, userGoalController.GoalSnapshot), userGoalController.DeleteUserMainGoal) // Called by the Android app
+		protected.PATCH("/updateGoalStatus", auditRecorder.Audit(models.AUDIT_GOAL_STATUS_CHANGED, userGoalController.GoalSnapshot), userGoalController.UpdateUserGoalStatus)
+		protected.PATCH("/updateWeeklyGoal", auditRecorder.Audit(models.AUDIT_WEEKLY_GOAL_UPDATED, userGoalController.GoalSnapshot), userGoalController.UpdateUserWeeklyGoal)
+		protected.DELETE("/deleteWeeklyGoal", auditRecorder.Audit(models.AUDIT_WEEKLY_GOAL_DELETED, userGoalController.GoalSnapshot), userGoalController.DeleteUserWeeklyGoal)
 	}
 }
 
-func SetupMealRoutes(router *gin.Engine, mealController *controllers.MealController) {
+func SetupMealRoutes(router *gin.Engine, mealController *controllers.MealController, authMiddleware gin.HandlerFunc, verifiedEmailMiddleware gin.HandlerFunc, userAccess *middleware.UserAccess, auditRecorder *middleware.AuditRecorder) {
 	protected := router.Group("/api")
-	protected.Use(middleware.AuthMiddleware()) // Apply JWT auth middleware
+	protected.Use(authMiddleware, userAccess.ClientMiddleware()) // Apply JWT auth middleware
 	{
 		protected.GET("/getMealPlan", mealController.GetWeeklyMealPlan)
-		protected.POST("/createMealPlan", mealController.CreateWeeklyMealPlan)
-		protected.PUT("/customizeMealPlan", mealController.CustomizeDayMealPlan)
-		protected.PUT("/consumeMeal", mealController.ConsumeMeal)
+		protected.GET("/getNutritionReport", mealController.GetNutritionReport)
+		protected.POST("/createMealPlan", verifiedEmailMiddleware, auditRecorder.Audit(models.AUDIT_MEAL_PLAN_CREATED, mealController.MealPlanSnapshot), mealController.CreateWeeklyMealPlan)
+		protected.PUT("/customizeMealPlan", verifiedEmailMiddleware, auditRecorder.Audit(models.AUDIT_MEAL_PLAN_CUSTOMIZED, mealController.MealPlanSnapshot), mealController.CustomizeDayMealPlan)
+		protected.PUT("/consumeMeal", auditRecorder.Audit(models.AUDIT_MEAL_CONSUMED, mealController.MealPlanSnapshot), mealController.ConsumeMeal)
 	}
 }
 
-func SetupDashboardRoutes(router *gin.Engine, dashboardController *controllers.DashboardController) {
+func SetupDashboardRoutes(router *gin.Engine, dashboardController *controllers.DashboardController, authMiddleware gin.HandlerFunc, userAccess *middleware.UserAccess) {
 	protected := router.Group("/api")
-	protected.Use(middleware.AuthMiddleware()) // Apply JWT auth middleware
+	protected.Use(authMiddleware, userAccess.OwnerMiddleware()) // Apply JWT auth middleware
 	{
 		protected.GET("/getDashboard", dashboardController.GetDashboard)
 	}
 }
+
+func SetupHydrationRoutes(router *gin.Engine, hydrationController *controllers.HydrationController, authMiddleware gin.HandlerFunc, userAccess *middleware.UserAccess, auditRecorder *middleware.AuditRecorder) {
+	protected := router.Group("/api")
+	protected.Use(authMiddleware, userAccess.OwnerMiddleware()) // Apply JWT auth middleware
+	{
+		protected.GET("/getHydration", hydrationController.GetHydration)
+		protected.POST("/logWater", auditRecorder.AuditCreated(models.AUDIT_WATER_LOGGED), hydrationController.LogWater)
+		protected.POST("/quickAddWater", auditRecorder.AuditCreated(models.AUDIT_WATER_LOGGED), hydrationController.QuickAddWater)
+		protected.DELETE("/deleteWaterLog", auditRecorder.Audit(models.AUDIT_WATER_LOG_DELETED, hydrationController.WaterLogSnapshot()), hydrationController.DeleteWaterLog)
+	}
+}
+
+func SetupWorkoutRoutes(router *gin.Engine, workoutController *controllers.WorkoutController, authMiddleware gin.HandlerFunc, verifiedEmailMiddleware gin.HandlerFunc, userAccess *middleware.UserAccess, auditRecorder *middleware.AuditRecorder) {
+	protected := router.Group("/api")
+	protected.Use(authMiddleware, userAccess.OwnerMiddleware()) // Apply JWT auth middleware
+	{
+		protected.GET("/getExercises", workoutController.GetExercises)
+		protected.GET("/getWorkoutRoutine", workoutController.GetWeeklyWorkoutRoutine)
+		protected.POST("/createWorkoutRoutine", verifiedEmailMiddleware, auditRecorder.AuditCreated(models.AUDIT_WORKOUT_ROUTINE_CREATED), workoutController.CreateWeeklyWorkoutRoutine)
+	}
+}
+
+func SetupActivityRoutes(router *gin.Engine, activityController *controllers.ActivityController, authMiddleware gin.HandlerFunc, userAccess *middleware.UserAccess, auditRecorder *middleware.AuditRecorder) {
+	protected := router.Group("/api")
+	protected.Use(authMiddleware, userAccess.OwnerMiddleware()) // Apply JWT auth middleware
+	{
+		protected.GET("/getActivity", activityController.GetActivity)
+		protected.POST("/logWorkoutSession", auditRecorder.AuditCreated(models.AUDIT_WORKOUT_SESSION_LOGGED), activityController.LogWorkoutSession)
+		protected.POST("/logActivity", auditRecorder.AuditCreated(models.AUDIT_ACTIVITY_LOGGED), activityController.LogActivity)
+		protected.DELETE("/deleteWorkoutSession", auditRecorder.Audit(models.AUDIT_WORKOUT_SESSION_DELETED, activityController.WorkoutSessionSnapshot()), activityController.DeleteWorkoutSession)
+		protected.DELETE("/deleteActivityLog", auditRecorder.Audit(models.AUDIT_ACTIVITY_LOG_DELETED, activityController.ActivityLogSnapshot()), activityController.DeleteActivityLog)
+	}
+}
+
+func SetupCoachRoutes(router *gin.Engine, coachController *controllers.CoachController, authMiddleware gin.HandlerFunc, auditRecorder *middleware.AuditRecorder) {
+	protected := router.Group("/api")
+	protected.Use(authMiddleware) // Apply JWT auth middleware
+	{
+		protected.POST("/inviteClient", middleware.RequireRole(models.ROLE_COACH), auditRecorder.AuditCreated(models.AUDIT_COACH_INVITED), coachController.InviteClient)
+		protected.GET("/getClients", middleware.RequireRole(models.ROLE_COACH), coachController.GetClients)
+		protected.GET("/getCoachOverview", middleware.RequireRole(models.ROLE_COACH), coachController.GetCoachOverview)
+
+		protected.POST("/inviteCoach", auditRecorder.AuditCreated(models.AUDIT_COACH_INVITED), coachController.InviteCoach)
+		protected.GET("/getCoachInvitations", coachController.GetCoachInvitations)
+		protected.PUT("/respondToCoachInvitation", auditRecorder.Audit(models.AUDIT_COACH_INVITATION_ANSWERED, nil), coachController.RespondToCoachInvitation)
+		protected.GET("/getCoaches", coachController.GetCoaches)
+		protected.DELETE("/endCoachLink", auditRecorder.Audit(models.AUDIT_COACH_LINK_ENDED, nil), coachController.EndCoachLink)
+	}
+}
+
+func SetupAdminRoutes(router *gin.Engine, adminController *controllers.AdminController, authMiddleware gin.HandlerFunc) {
+	admin := router.Group("/api/admin")
+	admin.Use(authMiddleware, middleware.RequireRole(models.ROLE_ADMIN)) // Every admin route is written to the audit log by the admin service
+	{
+		admin.GET("/searchUsers", adminController.SearchUsers)
+		admin.PUT("/setUserRole", adminController.SetUserRole)
+		admin.POST("/resetPassword", adminController.ResetUserPassword)
+		admin.POST("/revokeSessions", adminController.RevokeUserSessions)
+		admin.GET("/getUserGoal", adminController.GetUserGoal)
+		admin.DELETE("/deleteGoal", adminController.DeleteGoal)
+		admin.GET("/getMealPlans", adminController.GetMealPlans)
+		admin.DELETE("/deleteMealPlan", adminController.DeleteMealPlan)
+		admin.POST("/refreshMealImages", adminController.RefreshMealImages)
+	}
+}
+
+func SetupAuditRoutes(router *gin.Engine, auditController *controllers.AuditController, authMiddleware gin.HandlerFunc) {
+	protected := router.Group("/api")
+	protected.Use(authMiddleware) // Apply JWT auth middleware
+	{
+		protected.GET("/getAuditLogs", auditController.GetAuditLogs)
+		protected.GET("/admin/getAuditLogs", middleware.RequireRole(models.ROLE_ADMIN), auditController.GetAllAuditLogs)
+	}
+}
+
+func SetupAccountRoutes(router *gin.Engine, accountController *controllers.AccountController, authMiddleware gin.HandlerFunc, auditRecorder *middleware.AuditRecorder) {
+	protected := router.Group("/api/account")
+	protected.Use(authMiddleware) // Apply JWT auth middleware
+	{
+		protected.DELETE("", auditRecorder.Audit(models.AUDIT_ACCOUNT_DELETION_REQUESTED, nil), accountController.DeleteAccount)
+		protected.POST("/restore", auditRecorder.Audit(models.AUDIT_ACCOUNT_RESTORED, nil), accountController.RestoreAccount)
+		protected.GET("/export", auditRecorder.Audit(models.AUDIT_DATA_EXPORTED, nil), accountController.ExportAccount)
+		protected.GET("/export/status", accountController.GetAccountExport)
+		protected.GET("/export/download", auditRecorder.Audit(models.AUDIT_DATA_EXPORTED, nil), accountController.DownloadAccountExport)
+	}
+}
+
+// SetupV1Routes registers the versioned resource api. The RPC style routes above share the same handler
+// logic and stay until the Android app has migrated
+func SetupV1Routes(router *gin.Engine, userController *controllers.UserController, userGoalController *controllers.UserGoalController, mealController *controllers.MealController,
+	authMiddleware gin.HandlerFunc, verifiedEmailMiddleware gin.HandlerFunc, userAccess *middleware.UserAccess, auditRecorder *middleware.AuditRecorder) {
+	v1 := router.Group(controllers.API_V1_PATH)
+	v1.Use(authMiddleware) // Apply JWT auth middleware
+	{
+		me := v1.Group("/users/me")
+		{
+			me.GET("", userController.GetMe)
+			me.PATCH("", auditRecorder.Audit(models.AUDIT_PROFILE_UPDATED, userController.ProfileSnapshot), userController.UpdateMe)
+			me.GET("/sessions", userController.GetSessions)
+			me.DELETE("/sessions", auditRecorder.Audit(models.AUDIT_OTHER_SESSIONS_REVOKED, nil), userController.DeleteOtherSessions)
+			me.DELETE("/sessions/:sessionId", auditRecorder.Audit(models.AUDIT_SESSION_REVOKED, nil), userController.DeleteSession)
+		}
+
+		// Coaches and admins address a client with the userId query param, the caller by default
+		clients := v1.Group("/")
+		clients.Use(userAccess.ClientMiddleware())
+		{
+			clients.GET("/estimates/ideal-weight", verifiedEmailMiddleware, userGoalController.GetIdealWeightRange)
+			clients.GET("/estimates/goal-duration", verifiedEmailMiddleware, userGoalController.GetGoalDuration)
+			clients.GET("/estimates/tdee", verifiedEmailMiddleware, userGoalController.GetTdee)
+			clients.GET("/estimates/macros", verifiedEmailMiddleware, userGoalController.GetMacros)
+
+			clients.GET("/goals", userGoalController.GetGoals)
+			clients.POST("/goals", auditRecorder.Audit(models.AUDIT_GOAL_CREATED, userGoalController.GoalSnapshot), userGoalController.CreateGoal)
+			clients.GET("/goals/active", userGoalController.GetActiveGoal)
+			clients.GET("/goals/:goalId", userGoalController.GetGoal)
+			clients.PATCH("/goals/:goalId", auditRecorder.Audit(models.AUDIT_GOAL_STATUS_CHANGED, userGoalController.GoalSnapshot), userGoalController.UpdateGoalStatus)
+			clients.DELETE("/goals/:goalId", auditRecorder.Audit(models.AUDIT_GOAL_DELETED, userGoalController.GoalSnapshot), userGoalController.DeleteGoal)
+			clients.POST("/goals/:goalId/weeks", auditRecorder.Audit(models.AUDIT_WEEKLY_GOAL_CREATED, userGoalController.GoalSnapshot), userGoalController.CreateWeeklyGoal)
+			clients.GET("/goals/:goalId/weeks/:weeklyGoalId", userGoalController.GetWeeklyGoal)
+			clients.PATCH("/goals/:goalId/weeks/:weeklyGoalId", auditRecorder.Audit(models.AUDIT_WEEKLY_GOAL_UPDATED, userGoalController.GoalSnapshot), userGoalController.UpdateWeeklyGoal)
+			clients.DELETE("/goals/:goalId/weeks/:weeklyGoalId", auditRecorder.Audit(models.AUDIT_WEEKLY_GOAL_DELETED, userGoalController.GoalSnapshot), userGoalController.DeleteWeeklyGoal)
+
+			clients.GET("/goals/:goalId/weeks/:weeklyGoalId/meal-plan", mealController.GetWeekMealPlan)
+			clients.POST("/goals/:goalId/weeks/:weeklyGoalId/meal-plan", verifiedEmailMiddleware, auditRecorder.Audit(models.AUDIT_MEAL_PLAN_CREATED, mealController.MealPlanSnapshot), mealController.CreateMealPlan)
+			clients.GET("/goals/:goalId/weeks/:weeklyGoalId/nutrition-report", mealController.GetWeekNutritionReport)
+
+			clients.GET("/meal-plans/:mealPlanId", mealController.GetMealPlan)
+			clients.PATCH("/meal-plans/:mealPlanId/days/:dayMealId", verifiedEmailMiddleware, auditRecorder.Audit(models.AUDIT_MEAL_PLAN_CUSTOMIZED, mealController.MealPlanSnapshot), mealController.CustomizeDayMeal)
+			clients.PATCH("/meal-plans/:mealPlanId/days/:dayMealId/meals/:mealId", auditRecorder.Audit(models.AUDIT_MEAL_CONSUMED, mealController.MealPlanSnapshot), mealController.UpdateMeal)
+		}
+	}
+}
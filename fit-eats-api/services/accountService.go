@@ -0,0 +1,306 @@
+package services
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fit-eats-api/config"
+	"fit-eats-api/models"
+	"fit-eats-api/repositories"
+	"fit-eats-api/utils"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// EXPORT_SYNC_DOCUMENT_LIMIT is the number of documents up to which an export is returned straight away,
+// larger accounts get their export generated in the background
+const EXPORT_SYNC_DOCUMENT_LIMIT = 200
+
+// AccountService handles the data subject rights, deleting an account after its grace period and exporting its data
+type AccountService struct {
+	UserRepository       repositories.UserRepository
+	SessionRepository    *repositories.SessionRepository
+	AccountRepository    repositories.AccountRepository
+	DataExportRepository repositories.DataExportRepository
+	LoginAttemptStore    repositories.LoginAttemptStore
+	AuditRepository      repositories.AuditRepository
+	Mailer               utils.Mailer
+	UnitOfWork           repositories.UnitOfWork
+}
+
+func NewAccountService(userRepository repositories.UserRepository, sessionRepository *repositories.SessionRepository,
+	accountRepository repositories.AccountRepository, dataExportRepository repositories.DataExportRepository,
+	loginAttemptStore repositories.LoginAttemptStore, auditRepository repositories.AuditRepository, mailer utils.Mailer,
+	unitOfWork repositories.UnitOfWork) *AccountService {
+	return &AccountService{
+		UserRepository:       userRepository,
+		SessionRepository:    sessionRepository,
+		AccountRepository:    accountRepository,
+		DataExportRepository: dataExportRepository,
+		LoginAttemptStore:    loginAttemptStore,
+		AuditRepository:      auditRepository,
+		Mailer:               mailer,
+		UnitOfWork:           unitOfWork,
+	}
+}
+
+// ScheduleDeletion starts the grace period of the account and logs it out everywhere, logging in again and restoring cancels it
+func (s *AccountService) ScheduleDeletion(ctx context.Context, user *models.User) (time.Time, error) {
+	now := time.Now()
+	scheduledAt := now.Add(models.ACCOUNT_DELETION_GRACE_PERIOD)
+	err := s.UnitOfWork.Do(ctx, func(ctx context.Context) error {
+		if err := s.UserRepository.ScheduleUserDeletion(ctx, user.ID, now, scheduledAt); err != nil {
+			return err
+		}
+		return s.SessionRepository.RevokeUserSessions(ctx, user.ID, primitive.NilObjectID, models.SESSION_REVOKED)
+	})
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	body := fmt.Sprintf("Hi %s,\n\nYour FitEats account and all of its data will be deleted on %s.\n\nIf you change your mind, log in and restore your account before then.",
+		user.Name, scheduledAt.Format("2 January 2006"))
+	if err := s.Mailer.Send(ctx, user.Email, "Your FitEats account will be deleted", body); err != nil {
+		log.Printf("Could not send account deletion email to %s: %v", user.Email, err)
+	}
+	return scheduledAt, nil
+}
+
+func (s *AccountService) CancelDeletion(ctx context.Context, userId primitive.ObjectID) error {
+	return s.UserRepository.CancelUserDeletion(ctx, userId)
+}
+
+// PurgeAccount removes every document of the user from the registered collections along with their exports and login throttling.
+// The export files are removed first outside of the transaction, the documents, the redaction of the user's audit log
+// entries and the entry of the purge are written together
+func (s *AccountService) PurgeAccount(ctx context.Context, user models.User) error {
+	if err := s.DataExportRepository.DeleteUserDataExports(ctx, user.ID); err != nil {
+		return err
+	}
+	if err := s.LoginAttemptStore.ResetLoginAttempt(ctx, models.LOGIN_ACCOUNT_KEY_PREFIX+strings.ToLower(user.Email)); err != nil {
+		return err
+	}
+
+	return s.UnitOfWork.Do(ctx, func(ctx context.Context) error {
+		if err := s.AccountRepository.DeleteUserData(ctx, user.ID); err != nil {
+			return err
+		}
+		if err := s.AuditRepository.RedactUserAuditLogs(ctx, user.ID, time.Now()); err != nil {
+			return err
+		}
+
+		// The email is left out, the purged user is only known by id
+		return s.AuditRepository.CreateAuditLog(ctx, &models.AuditLog{
+			UserId: user.ID,
+			Actor:  &models.AuditActor{Name: "account purge", Source: models.AUDIT_SOURCE_SCHEDULER},
+			Action: models.AUDIT_ACCOUNT_PURGED,
+		})
+	})
+}
+
+// PurgeDueAccounts hard deletes the accounts whose grace period is over and returns how many were purged. An account
+// which fails is logged and retried on the next run, it must not hold back the accounts after it
+func (s *AccountService) PurgeDueAccounts(ctx context.Context, now time.Time) (int, error) {
+	users, err := s.UserRepository.GetUsersDueForDeletion(ctx, now)
+	if err != nil {
+		return 0, err
+	}
+
+	purged, failed := 0, 0
+	for _, user := range users {
+		if err := s.PurgeAccount(ctx, user); err != nil {
+			log.Printf("Could not purge account %s: %v", user.ID.Hex(), err)
+			failed++
+			continue
+		}
+		purged++
+	}
+	if failed > 0 {
+		return purged, fmt.Errorf("could not purge %d of %d accounts", failed, len(users))
+	}
+	return purged, nil
+}
+
+// RunScheduledJobs purges due accounts and expired exports every interval, it is started once by the server
+func (s *AccountService) RunScheduledJobs(interval time.Duration) {
+	for {
+		timedContext, cancel := config.GetTimedContext(300)
+		now := time.Now()
+		if purged, err := s.PurgeDueAccounts(timedContext, now); err != nil {
+			log.Printf("Purged %d deleted accounts, %v", purged, err)
+		} else if purged > 0 {
+			log.Printf("Purged %d deleted accounts", purged)
+		}
+		if _, err := s.DataExportRepository.DeleteExpiredDataExports(timedContext, now); err != nil {
+			log.Printf("Could not delete expired data exports: %v", err)
+		}
+		cancel()
+
+		time.Sleep(interval)
+	}
+}
+
+// IsLargeAccount decides if the export of the user is generated in the background
+func (s *AccountService) IsLargeAccount(ctx context.Context, userId primitive.ObjectID) (bool, error) {
+	count, err := s.AccountRepository.CountUserDocuments(ctx, userId)
+	return count > EXPORT_SYNC_DOCUMENT_LIMIT, err
+}
+
+// StartExport generates the export in the background and emails the user when it is ready,
+// an export already being generated is returned instead of starting another one
+func (s *AccountService) StartExport(ctx context.Context, user *models.User) (*models.DataExport, error) {
+	pending, err := s.DataExportRepository.GetPendingDataExport(ctx, user.ID)
+	if err != nil || pending != nil {
+		return pending, err
+	}
+
+	now := time.Now()
+	dataExport := models.DataExport{UserId: user.ID, Status: models.DATA_EXPORT_PENDING, CreatedAt: now, ExpiresAt: now.Add(models.DATA_EXPORT_VALIDITY)}
+	if err := s.DataExportRepository.CreateDataExport(ctx, &dataExport); err != nil {
+		return nil, err
+	}
+
+	go s.generateExport(dataExport, *user)
+	return &dataExport, nil
+}
+
+func (s *AccountService) generateExport(dataExport models.DataExport, user models.User) {
+	timedContext, cancel := context.WithTimeout(context.Background(), models.DATA_EXPORT_TIMEOUT)
+	defer cancel()
+
+	fail := func(err error) {
+		log.Printf("Data export %s failed: %v", dataExport.ID.Hex(), err)
+		if err := s.DataExportRepository.FailDataExport(timedContext, dataExport.ID, "Export could not be generated"); err != nil {
+			log.Printf("Could not mark data export %s as failed: %v", dataExport.ID.Hex(), err)
+		}
+	}
+
+	fileId, size, err := s.DataExportRepository.SaveDataExportFile(timedContext, dataExport.ID, func(w io.Writer) error {
+		return s.WriteExport(timedContext, user.ID, w)
+	})
+	if err != nil {
+		fail(err)
+		return
+	}
+	if err := s.DataExportRepository.CompleteDataExport(timedContext, dataExport.ID, fileId, size); err != nil {
+		fail(err)
+		return
+	}
+
+	link := fmt.Sprintf("%s/account/export?exportId=%s", config.GetConfig().AppBaseUrl, dataExport.ID.Hex())
+	body := fmt.Sprintf("Hi %s,\n\nThe export of your FitEats data is ready. You can download it until %s:\n\n%s",
+		user.Name, dataExport.ExpiresAt.Format("2 January 2006"), link)
+	if err := s.Mailer.Send(timedContext, user.Email, "Your FitEats data export is ready", body); err != nil {
+		log.Printf("Could not send data export email to %s: %v", user.Email, err)
+	}
+}
+
+// WriteExport writes the zip of the user's personal data: the profile, goals, weekly goals, meal plans and
+// consumption history as json and csv, and the documents of every other exported collection as json
+func (s *AccountService) WriteExport(ctx context.Context, userId primitive.ObjectID, w io.Writer) error {
+	user, err := s.UserRepository.GetUserProfileById(ctx, userId)
+	if err != nil {
+		return err
+	}
+
+	goals := []models.Goal{}
+	mealPlans := []models.MealPlan{}
+	collections := map[string][]bson.M{}
+	for _, collection := range repositories.UserDataCollections {
+		if !collection.Export {
+			continue
+		}
+		documents, err := s.AccountRepository.GetUserDocuments(ctx, collection, userId)
+		if err != nil {
+			return err
+		}
+		collections[collection.Name] = documents
+
+		switch collection.Name {
+		case "userGoals":
+			goals, err = decodeDocuments[models.Goal](documents)
+		case "meals":
+			mealPlans, err = decodeDocuments[models.MealPlan](documents)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	zipWriter := zip.NewWriter(w)
+	files := []struct {
+		name string
+		json any
+		csv  [][]string
+	}{
+		{"profile", user, utils.GetProfileCsv(*user)},
+		{"goals", goals, utils.GetGoalsCsv(goals)},
+		{"weekly_goals", nil, utils.GetWeeklyGoalsCsv(goals)},
+		{"meal_plans", mealPlans, utils.GetMealPlansCsv(mealPlans)},
+		{"consumption_history", nil, utils.GetConsumptionCsv(mealPlans)},
+	}
+	for _, file := range files {
+		if file.json != nil {
+			if err := writeZipJson(zipWriter, file.name+".json", file.json); err != nil {
+				return err
+			}
+		}
+		if err := writeZipCsv(zipWriter, file.name+".csv", file.csv); err != nil {
+			return err
+		}
+	}
+	for _, collection := range repositories.UserDataCollections {
+		if collection.Export && collection.Name != "userGoals" && collection.Name != "meals" {
+			if err := writeZipJson(zipWriter, "data/"+collection.Name+".json", collections[collection.Name]); err != nil {
+				return err
+			}
+		}
+	}
+
+	return zipWriter.Close()
+}
+
+func decodeDocuments[T any](documents []bson.M) ([]T, error) {
+	values := make([]T, 0, len(documents))
+	for _, document := range documents {
+		bytes, err := bson.Marshal(document)
+		if err != nil {
+			return nil, err
+		}
+		var value T
+		if err := bson.Unmarshal(bytes, &value); err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+func writeZipJson(zipWriter *zip.Writer, name string, value any) error {
+	file, err := zipWriter.Create(name)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(value)
+}
+
+func writeZipCsv(zipWriter *zip.Writer, name string, rows [][]string) error {
+	file, err := zipWriter.Create(name)
+	if err != nil {
+		return err
+	}
+	csvWriter := csv.NewWriter(file)
+	if err := csvWriter.WriteAll(rows); err != nil {
+		return err
+	}
+	return csvWriter.Error()
+}
@@ -0,0 +1,239 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"fit-eats-api/models"
+	"fit-eats-api/repositories"
+	"fit-eats-api/utils"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// accountFixture is an account service on in memory stores sharing their collections with the account repository
+type accountFixture struct {
+	service     *AccountService
+	users       *repositories.InMemoryUserRepository
+	userGoals   *repositories.InMemoryUserGoalRepository
+	meals       *repositories.InMemoryMealRepository
+	accounts    *repositories.InMemoryAccountRepository
+	dataExports *repositories.InMemoryDataExportRepository
+	audit       *repositories.InMemoryAuditRepository
+	attempts    *repositories.InMemoryLoginAttemptStore
+}
+
+func newAccountFixture() accountFixture {
+	f := accountFixture{
+		users:       repositories.NewInMemoryUserRepository(),
+		userGoals:   repositories.NewInMemoryUserGoalRepository(),
+		meals:       repositories.NewInMemoryMealRepository(),
+		dataExports: repositories.NewInMemoryDataExportRepository(),
+		audit:       repositories.NewInMemoryAuditRepository(),
+		attempts:    repositories.NewInMemoryLoginAttemptStore(),
+	}
+	f.accounts = repositories.NewInMemoryAccountRepository(f.users, f.userGoals, f.meals, f.dataExports)
+	f.service = NewAccountService(f.users, nil, f.accounts, f.dataExports, f.attempts, f.audit, &utils.LogMailer{}, repositories.NewInMemoryUnitOfWork())
+	return f
+}
+
+// seedUserData gives the user documents in every user data collection and returns the file of their export
+func (f accountFixture) seedUserData(t *testing.T, userId primitive.ObjectID, coachId primitive.ObjectID) primitive.ObjectID {
+	t.Helper()
+	ctx := context.Background()
+
+	goal := newTestGoal(userId)
+	goal.ID, goal.Status = primitive.NewObjectID(), models.GOAL_ACTIVE
+	expectNoError(t, f.userGoals.CreateMainUserGoal(ctx, goal))
+	start := time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC)
+	weeklyGoal := &models.WeeklyGoal{StartDate: start, EndDate: start.AddDate(0, 0, 7), CurrentWeightInKg: 90, TargetDailyCalories: 2100}
+	expectNoError(t, f.userGoals.CreateWeeklyUserGoal(ctx, goal.ID, weeklyGoal))
+
+	mealPlan := &models.MealPlan{ID: primitive.NewObjectID(), UserId: userId, MainGoalId: goal.ID, WeeklyGoalId: weeklyGoal.ID}
+	for day := 0; day < 2; day++ {
+		mealPlan.DayMeals = append(mealPlan.DayMeals, models.DayMeal{ID: primitive.NewObjectID(), Date: start.AddDate(0, 0, day),
+			Meals: []models.Meal{{ID: primitive.NewObjectID(), Name: "Oats", Calories: 400, Protein: 20, IsConsumed: day == 0}, {ID: primitive.NewObjectID(), Name: "Dal", Calories: 600}}})
+	}
+	expectNoError(t, f.meals.CreateWeeklyMealPlan(ctx, mealPlan))
+
+	for _, collection := range []string{"hydrationLogs", "workoutRoutines", "workoutSessions", "activityLogs", "userTokens", "oidcStates"} {
+		expectNoError(t, f.accounts.InsertUserDocument(collection, bson.M{"userId": userId, "createdAt": start}))
+	}
+	expectNoError(t, f.accounts.InsertUserDocument("sessions", models.Session{ID: primitive.NewObjectID(), UserId: userId, RefreshTokenHash: "refresh-" + userId.Hex(), DeviceName: "Pixel"}))
+	expectNoError(t, f.accounts.InsertUserDocument("coachLinks", models.CoachLink{ID: primitive.NewObjectID(), CoachId: coachId, ClientId: userId, InvitedBy: coachId, Status: models.COACH_LINK_ACTIVE}))
+
+	dataExport := &models.DataExport{UserId: userId, Status: models.DATA_EXPORT_PENDING, CreatedAt: start, ExpiresAt: time.Now().Add(models.DATA_EXPORT_VALIDITY)}
+	expectNoError(t, f.dataExports.CreateDataExport(ctx, dataExport))
+	fileId, size, err := f.dataExports.SaveDataExportFile(ctx, dataExport.ID, func(w io.Writer) error {
+		return f.service.WriteExport(ctx, userId, w)
+	})
+	expectNoError(t, err)
+	expectNoError(t, f.dataExports.CompleteDataExport(ctx, dataExport.ID, fileId, size))
+	return fileId
+}
+
+func TestAccountServicePurgeDueAccounts(t *testing.T) {
+	ctx := context.Background()
+	f := newAccountFixture()
+	now := time.Now()
+
+	user := &models.User{Name: "Asha", Email: "Asha@fiteats.test"}
+	expectNoError(t, f.users.CreateUser(ctx, user))
+	otherId := createTestUser(t, f.users, true)
+	coachId := createTestUser(t, f.users, true)
+
+	fileId := f.seedUserData(t, user.ID, coachId)
+	otherFileId := f.seedUserData(t, otherId, coachId)
+
+	accountKey := models.LOGIN_ACCOUNT_KEY_PREFIX + "asha@fiteats.test"
+	_, err := f.attempts.RecordFailure(ctx, accountKey, models.AccountLoginPolicy, now)
+	expectNoError(t, err)
+	expectNoError(t, f.audit.CreateAuditLog(ctx, &models.AuditLog{UserId: user.ID, Actor: &models.AuditActor{ID: user.ID, Name: user.Email}, Action: models.AUDIT_PROFILE_UPDATED,
+		IP: "203.0.113.7", Details: map[string]any{"path": "/api/updateUser"}, Changes: []models.AuditChange{{Path: "name", Before: "A", After: "Asha"}}}))
+	expectNoError(t, f.audit.CreateAuditLog(ctx, &models.AuditLog{UserId: otherId, Actor: &models.AuditActor{ID: user.ID, Name: user.Email}, Action: models.AUDIT_GOAL_CREATED,
+		IP: "203.0.113.7", Changes: []models.AuditChange{{Path: "", After: map[string]any{"goalType": "Fat loss"}}}}))
+
+	expectNoError(t, f.users.ScheduleUserDeletion(ctx, user.ID, now.AddDate(0, 0, -31), now.Add(-time.Hour)))
+	expectNoError(t, f.users.ScheduleUserDeletion(ctx, otherId, now, now.Add(time.Hour)))
+
+	purged, err := f.service.PurgeDueAccounts(ctx, now)
+	expectNoError(t, err)
+	if purged != 1 {
+		t.Fatalf("expected the account past its grace period to be purged, got %d", purged)
+	}
+
+	// Every registered collection loses the documents of the purged user and keeps those of the others
+	for _, collection := range repositories.UserDataCollections {
+		documents, err := f.accounts.GetUserDocuments(ctx, collection, user.ID)
+		expectNoError(t, err)
+		if len(documents) != 0 {
+			t.Fatalf("expected no %s of the purged user, got %v", collection.Name, documents)
+		}
+		documents, err = f.accounts.GetUserDocuments(ctx, collection, otherId)
+		expectNoError(t, err)
+		if len(documents) == 0 {
+			t.Fatalf("expected the %s of the other user to be kept", collection.Name)
+		}
+	}
+	if _, err := f.users.GetUserProfileById(ctx, user.ID); err != mongo.ErrNoDocuments {
+		t.Fatalf("expected the user to be removed, got %v", err)
+	}
+	if _, err := f.users.GetUserProfileById(ctx, coachId); err != nil {
+		t.Fatalf("expected the coach of the purged user to be kept, got %v", err)
+	}
+
+	if err := f.dataExports.DownloadFile(ctx, fileId, io.Discard); err == nil {
+		t.Fatal("expected the export file of the purged user to be removed")
+	}
+	expectNoError(t, f.dataExports.DownloadFile(ctx, otherFileId, io.Discard))
+
+	attempt, err := f.attempts.GetLoginAttempt(ctx, accountKey)
+	expectNoError(t, err)
+	if attempt != nil {
+		t.Fatalf("expected the login throttling of the purged user to be reset, got %+v", attempt)
+	}
+
+	// The audit trail is kept without the personal data, with the purge as its last entry
+	about, _, err := f.audit.GetAuditLogs(ctx, models.AuditLogFilter{UserId: user.ID}, 0, 10)
+	expectNoError(t, err)
+	if len(about) != 2 || about[0].Action != models.AUDIT_ACCOUNT_PURGED || about[0].Actor.Source != models.AUDIT_SOURCE_SCHEDULER {
+		t.Fatalf("expected the profile update and the purge, got %+v", about)
+	}
+	redacted := about[1]
+	if redacted.RedactedAt == nil || redacted.Changes != nil || redacted.Details != nil || redacted.IP != "" {
+		t.Fatalf("expected the entry about the user to be redacted, got %+v", redacted)
+	}
+
+	actedIn, _, err := f.audit.GetAuditLogs(ctx, models.AuditLogFilter{UserId: otherId}, 0, 10)
+	expectNoError(t, err)
+	if len(actedIn) != 1 || actedIn[0].RedactedAt == nil || actedIn[0].Actor.ID != user.ID || actedIn[0].Actor.Name != "" || actedIn[0].IP != "" {
+		t.Fatalf("expected the entry the user acted in to lose their email and ip, got %+v", actedIn)
+	}
+	if len(actedIn[0].Changes) != 1 {
+		t.Fatalf("expected the changes made to the other user to be kept, got %+v", actedIn[0].Changes)
+	}
+}
+
+func TestAccountServiceWriteExport(t *testing.T) {
+	ctx := context.Background()
+	f := newAccountFixture()
+
+	userId := createTestUser(t, f.users, true)
+	otherId := createTestUser(t, f.users, true)
+	coachId := createTestUser(t, f.users, true)
+	f.seedUserData(t, userId, coachId)
+	f.seedUserData(t, otherId, coachId)
+
+	var export bytes.Buffer
+	expectNoError(t, f.service.WriteExport(ctx, userId, &export))
+	reader, err := zip.NewReader(bytes.NewReader(export.Bytes()), int64(export.Len()))
+	expectNoError(t, err)
+
+	files := map[string][]byte{}
+	names := []string{}
+	for _, file := range reader.File {
+		opened, err := file.Open()
+		expectNoError(t, err)
+		content, err := io.ReadAll(opened)
+		expectNoError(t, err)
+		opened.Close()
+		files[file.Name] = content
+		names = append(names, file.Name)
+	}
+	sort.Strings(names)
+
+	expected := []string{"consumption_history.csv", "data/activityLogs.json", "data/coachLinks.json", "data/hydrationLogs.json", "data/sessions.json",
+		"data/workoutRoutines.json", "data/workoutSessions.json", "goals.csv", "goals.json", "meal_plans.csv", "meal_plans.json", "profile.csv",
+		"profile.json", "weekly_goals.csv"}
+	if strings.Join(names, ",") != strings.Join(expected, ",") {
+		t.Fatalf("expected the entries %v, got %v", expected, names)
+	}
+
+	var profile models.User
+	expectNoError(t, json.Unmarshal(files["profile.json"], &profile))
+	if profile.ID != userId {
+		t.Fatalf("expected the profile of the user, got %+v", profile)
+	}
+	var goals []models.Goal
+	expectNoError(t, json.Unmarshal(files["goals.json"], &goals))
+	if len(goals) != 1 || goals[0].UserId != userId || len(goals[0].WeeklyGoals) != 1 {
+		t.Fatalf("expected the goal of the user with its week, got %+v", goals)
+	}
+	var mealPlans []models.MealPlan
+	expectNoError(t, json.Unmarshal(files["meal_plans.json"], &mealPlans))
+	if len(mealPlans) != 1 || mealPlans[0].UserId != userId {
+		t.Fatalf("expected the meal plan of the user, got %+v", mealPlans)
+	}
+	var hydrationLogs []map[string]any
+	expectNoError(t, json.Unmarshal(files["data/hydrationLogs.json"], &hydrationLogs))
+	if len(hydrationLogs) != 1 {
+		t.Fatalf("expected only the hydration log of the user, got %v", hydrationLogs)
+	}
+	if bytes.Contains(files["data/sessions.json"], []byte("refreshTokenHash")) || bytes.Contains(files["data/sessions.json"], []byte("refresh-")) {
+		t.Fatalf("expected the sessions without their refresh token hash, got %s", files["data/sessions.json"])
+	}
+
+	// Every csv has its header, then a row per goal, week, planned meal or day
+	rowCounts := map[string]int{"profile.csv": 1, "goals.csv": 1, "weekly_goals.csv": 1, "meal_plans.csv": 4, "consumption_history.csv": 2}
+	for name, count := range rowCounts {
+		rows, err := csv.NewReader(bytes.NewReader(files[name])).ReadAll()
+		expectNoError(t, err)
+		if len(rows) != count+1 {
+			t.Fatalf("expected %s to have %d rows after its header, got %v", name, count, rows)
+		}
+	}
+	consumption, _ := csv.NewReader(bytes.NewReader(files["consumption_history.csv"])).ReadAll()
+	if consumption[1][0] != "2026-10-12" || consumption[1][1] != "1000" || consumption[1][2] != "400" {
+		t.Fatalf("expected the planned and consumed calories of the first day, got %v", consumption[1])
+	}
+}
@@ -0,0 +1,194 @@
+package services
+
+import (
+	"context"
+	"fit-eats-api/models"
+	"fit-eats-api/repositories"
+	"fit-eats-api/utils"
+	"fmt"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// AdminService holds the operator actions shared by the admin api and the fiteats-admin cli.
+// Every action is written to the audit log with the actor who performed it.
+type AdminService struct {
+	UserRepository      repositories.UserRepository
+	SessionRepository   *repositories.SessionRepository
+	UserTokenRepository *repositories.UserTokenRepository
+	LoginAttemptStore   repositories.LoginAttemptStore
+	UserGoalRepository  repositories.UserGoalRepository
+	MealRepository      repositories.MealRepository
+	AuditRepository     repositories.AuditRepository
+	UnitOfWork          repositories.UnitOfWork
+}
+
+func NewAdminService(userRepository repositories.UserRepository, sessionRepository *repositories.SessionRepository,
+	userTokenRepository *repositories.UserTokenRepository, loginAttemptStore repositories.LoginAttemptStore,
+	userGoalRepository repositories.UserGoalRepository, mealRepository repositories.MealRepository,
+	auditRepository repositories.AuditRepository, unitOfWork repositories.UnitOfWork) *AdminService {
+	return &AdminService{
+		UserRepository:      userRepository,
+		SessionRepository:   sessionRepository,
+		UserTokenRepository: userTokenRepository,
+		LoginAttemptStore:   loginAttemptStore,
+		UserGoalRepository:  userGoalRepository,
+		MealRepository:      mealRepository,
+		AuditRepository:     auditRepository,
+		UnitOfWork:          unitOfWork,
+	}
+}
+
+// TEMPORARY_PASSWORD_BYTES is the number of random bytes in a password set by an admin, the user should change it with a reset
+const TEMPORARY_PASSWORD_BYTES = 9
+
+func (s *AdminService) SearchUsers(ctx context.Context, actor models.AuditActor, query string, limit int64) ([]models.User, error) {
+	users, err := s.UserRepository.SearchUsers(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	return users, s.audit(ctx, actor, models.AUDIT_ADMIN_SEARCH_USERS, primitive.NilObjectID, map[string]any{"query": query, "results": len(users)})
+}
+
+// SetUserRole makes a user a coach or an admin. The role is part of the access token, so a change revokes the
+// sessions of the user and the new role applies once they log in again
+func (s *AdminService) SetUserRole(ctx context.Context, actor models.AuditActor, userId primitive.ObjectID, role models.Role) error {
+	if !models.IsValidRole(role) {
+		return fmt.Errorf("role must be one of user, coach or admin")
+	}
+	user, err := s.UserRepository.GetUserProfileById(ctx, userId)
+	if err != nil {
+		return err
+	}
+
+	err = s.UserRepository.UpdateUser(ctx, userId, bson.M{"role": role})
+	if err != nil {
+		return err
+	}
+	if user.GetRole() != role {
+		if err := s.SessionRepository.RevokeUserSessions(ctx, userId, primitive.NilObjectID, models.SESSION_ROLE_CHANGED); err != nil {
+			return err
+		}
+	}
+	return s.audit(ctx, actor, models.AUDIT_ADMIN_SET_ROLE, userId, map[string]any{"from": user.GetRole(), "to": role})
+}
+
+// ResetPassword replaces the password with a random temporary one which is returned once.
+// Every session, pending reset link and login lockout of the user is cleared.
+func (s *AdminService) ResetPassword(ctx context.Context, actor models.AuditActor, userId primitive.ObjectID) (string, error) {
+	user, err := s.UserRepository.GetUserProfileById(ctx, userId)
+	if err != nil {
+		return "", err
+	}
+
+	password := utils.GenerateRandomToken(TEMPORARY_PASSWORD_BYTES)
+	hashedPassword, err := utils.GeneratePasswordHashFromPlainText(password)
+	if err != nil {
+		return "", err
+	}
+	if err := s.UserRepository.UpdateUser(ctx, userId, bson.M{"password": hashedPassword}); err != nil {
+		return "", err
+	}
+	if err := s.SessionRepository.RevokeUserSessions(ctx, userId, primitive.NilObjectID, models.SESSION_PASSWORD_RESET); err != nil {
+		return "", err
+	}
+	if err := s.UserTokenRepository.InvalidateUserTokens(ctx, userId, models.PASSWORD_RESET); err != nil {
+		return "", err
+	}
+	if err := s.LoginAttemptStore.ResetLoginAttempt(ctx, models.LOGIN_ACCOUNT_KEY_PREFIX+strings.ToLower(user.Email)); err != nil {
+		return "", err
+	}
+
+	return password, s.audit(ctx, actor, models.AUDIT_ADMIN_RESET_PASSWORD, userId, nil)
+}
+
+// RevokeSessions clears every refresh token of the user, which logs them out on all devices
+func (s *AdminService) RevokeSessions(ctx context.Context, actor models.AuditActor, userId primitive.ObjectID) error {
+	err := s.SessionRepository.RevokeUserSessions(ctx, userId, primitive.NilObjectID, models.SESSION_REVOKED)
+	if err != nil {
+		return err
+	}
+	return s.audit(ctx, actor, models.AUDIT_ADMIN_REVOKE_SESSIONS, userId, nil)
+}
+
+// GetUserGoal returns nil when the user has no goal
+func (s *AdminService) GetUserGoal(ctx context.Context, actor models.AuditActor, userId primitive.ObjectID) (*models.Goal, error) {
+	goal, err := s.UserGoalRepository.GetUserGoalByUserId(ctx, userId)
+	if err == mongo.ErrNoDocuments {
+		goal, err = nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return goal, s.audit(ctx, actor, models.AUDIT_ADMIN_VIEW_GOAL, userId, nil)
+}
+
+// DeleteGoal deletes the main goal with its meal plans
+func (s *AdminService) DeleteGoal(ctx context.Context, actor models.AuditActor, goalId primitive.ObjectID) error {
+	userId, err := s.UserGoalRepository.GetGoalOwnerId(ctx, goalId)
+	if err != nil {
+		return err
+	}
+	return s.UnitOfWork.Do(ctx, func(ctx context.Context) error {
+		if err := deleteGoalWithMealPlans(ctx, s.UnitOfWork, s.UserGoalRepository, s.MealRepository, goalId); err != nil {
+			return err
+		}
+		return s.audit(ctx, actor, models.AUDIT_ADMIN_DELETE_GOAL, userId, map[string]any{"goalId": goalId})
+	})
+}
+
+func (s *AdminService) GetMealPlans(ctx context.Context, actor models.AuditActor, userId primitive.ObjectID) ([]models.MealPlan, error) {
+	mealPlans, err := s.MealRepository.GetMealPlansByUserId(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	return mealPlans, s.audit(ctx, actor, models.AUDIT_ADMIN_VIEW_MEAL_PLANS, userId, nil)
+}
+
+func (s *AdminService) DeleteMealPlan(ctx context.Context, actor models.AuditActor, mealPlanId primitive.ObjectID) error {
+	mealPlan, err := s.MealRepository.GetMealPlanMeta(ctx, mealPlanId)
+	if err != nil {
+		return err
+	}
+	if mealPlan == nil {
+		return mongo.ErrNoDocuments
+	}
+	if err := s.MealRepository.DeleteMealPlan(ctx, mealPlanId); err != nil {
+		return err
+	}
+	return s.audit(ctx, actor, models.AUDIT_ADMIN_DELETE_MEAL_PLAN, mealPlan.UserId, map[string]any{"mealPlanId": mealPlanId})
+}
+
+// RefreshMealImages looks up the image of every meal in the plan again and returns the number of meals updated
+func (s *AdminService) RefreshMealImages(ctx context.Context, actor models.AuditActor, mealPlanId primitive.ObjectID) (int, error) {
+	mealPlan, err := s.MealRepository.GetMealPlanById(ctx, mealPlanId)
+	if err != nil {
+		return 0, err
+	}
+	if mealPlan == nil {
+		return 0, mongo.ErrNoDocuments
+	}
+
+	meals := 0
+	for _, dayMeal := range mealPlan.DayMeals {
+		utils.FillMealImages(ctx, dayMeal.Meals)
+		if err := s.MealRepository.UpdateSingleDayMeal(ctx, mealPlanId, dayMeal.ID, dayMeal.Meals); err != nil {
+			return meals, err
+		}
+		meals += len(dayMeal.Meals)
+	}
+
+	return meals, s.audit(ctx, actor, models.AUDIT_ADMIN_REFRESH_MEAL_IMAGES, mealPlan.UserId, map[string]any{"mealPlanId": mealPlanId, "meals": meals})
+}
+
+// audit records an action that already happened, a failure is returned so the operator knows the log is missing an entry
+func (s *AdminService) audit(ctx context.Context, actor models.AuditActor, action models.AuditAction, userId primitive.ObjectID, details map[string]any) error {
+	err := s.AuditRepository.CreateAuditLog(ctx, &models.AuditLog{UserId: userId, Actor: &actor, Action: action, Details: details})
+	if err != nil {
+		return fmt.Errorf("action done but could not be written to the audit log: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,179 @@
+package services
+
+import (
+	"context"
+	"fit-eats-api/models"
+	"fit-eats-api/repositories"
+	"fit-eats-api/utils"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// DashboardService aggregates the day of the user: progress of the goal, today's meals, hydration, workout and activity
+type DashboardService struct {
+	UserRepository      repositories.UserRepository
+	UserGoalRepository  repositories.UserGoalRepository
+	MealRepository      repositories.MealRepository
+	HydrationRepository *repositories.HydrationRepository
+	WorkoutRepository   *repositories.WorkoutRepository
+	ActivityRepository  *repositories.ActivityRepository
+}
+
+func NewDashboardService(userRepository repositories.UserRepository, userGoalRepository repositories.UserGoalRepository, mealRepository repositories.MealRepository,
+	hydrationRepository *repositories.HydrationRepository, workoutRepository *repositories.WorkoutRepository, activityRepository *repositories.ActivityRepository) *DashboardService {
+	return &DashboardService{UserRepository: userRepository, UserGoalRepository: userGoalRepository, MealRepository: mealRepository,
+		HydrationRepository: hydrationRepository, WorkoutRepository: workoutRepository, ActivityRepository: activityRepository}
+}
+
+// GetDashboard needs an active goal and a meal plan covering today, workouts and logs are optional
+func (s *DashboardService) GetDashboard(ctx context.Context, userId primitive.ObjectID) (*models.DashboardResponse, error) {
+	user, err := getUserProfile(ctx, s.UserRepository, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	mainGoal, err := s.UserGoalRepository.GetUserActiveGoalByUserId(ctx, userId)
+	if err != nil {
+		return nil, toServiceError(err, models.NewApiError(models.GOAL_NOT_FOUND, "Create a goal"), "Could not get active goal")
+	}
+
+	weeklyGoal := mainGoal.WeeklyGoals[0]
+
+	dayMeal, err := s.MealRepository.GetSingleDayMealByDate(ctx, userId)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return nil, models.NewApiError(models.INTERNAL_ERROR, "Could not get today's meals").WithCause(err)
+	}
+	if dayMeal == nil || len(dayMeal.Meals) == 0 {
+		return nil, models.NewApiError(models.MEAL_PLAN_NOT_FOUND, "Create a weekly meal plan")
+	}
+
+	planned, consumed := utils.SumDayMeals(dayMeal.Meals)
+
+	today := time.Now()
+	startOfDay, endOfDay := utils.GetDayRange(today)
+	hydrationLogs, err := s.HydrationRepository.GetHydrationLogs(ctx, userId, startOfDay, endOfDay)
+	if err != nil {
+		return nil, models.NewApiError(models.INTERNAL_ERROR, "Could not get hydration logs").WithCause(err)
+	}
+
+	// Workouts are optional, the dashboard is still shown without a routine
+	todayWorkout, err := s.WorkoutRepository.GetWorkoutDayByDate(ctx, userId, startOfDay, endOfDay)
+	if err != nil {
+		return nil, models.NewApiError(models.INTERNAL_ERROR, "Could not get today's workout").WithCause(err)
+	}
+
+	workoutSessions, err := s.ActivityRepository.GetWorkoutSessions(ctx, userId, startOfDay, endOfDay)
+	if err != nil {
+		return nil, models.NewApiError(models.INTERNAL_ERROR, "Could not get workout sessions").WithCause(err)
+	}
+	activityLogs, err := s.ActivityRepository.GetActivityLogs(ctx, userId, startOfDay, endOfDay)
+	if err != nil {
+		return nil, models.NewApiError(models.INTERNAL_ERROR, "Could not get activity logs").WithCause(err)
+	}
+	activitySummary := utils.GetActivitySummary(today, workoutSessions, activityLogs, user.GetEatBackPercentage())
+
+	plannedNutrients, consumedNutrients := utils.SumDayMicronutrients(dayMeal.Meals)
+	referenceNutrients := utils.GetDailyReferenceIntake(user.Age, user.Sex)
+
+	dashboardResponse := models.DashboardResponse{
+		UserInfo: models.UserInfoSection{
+			Name:     user.Name,
+			Greeting: "Practice makes perfect",
+		},
+		ProgressSummary: models.ProgressSummary{
+			WeightInKg: models.MetricProgress{
+				Current: weeklyGoal.CurrentWeightInKg,
+				Last:    weeklyGoal.CurrentWeightInKg,
+				Goal:    mainGoal.TargetWeightInKg,
+				Start:   mainGoal.StartWeightInKg,
+			},
+			BodyFatPercentage: models.MetricProgress{
+				Current: weeklyGoal.CurrentFatPercentage,
+				Last:    weeklyGoal.CurrentFatPercentage,
+				Goal:    mainGoal.TargetFatPercentage,
+				Start:   mainGoal.StartFatPercentage,
+			},
+		},
+		CalorieOverview: models.CalorieOverview{
+			Total: models.CalorieData{
+				Consumed: float64(consumed.Calories),
+				Goal:     float64(planned.Calories) + activitySummary.EatBackCalories,
+				BaseGoal: float64(planned.Calories),
+				Burned:   activitySummary.BurnedCalories,
+				EatBack:  activitySummary.EatBackCalories,
+			},
+			Macros: models.MacroData{
+				Protein: models.MacroItem{
+					Consumed: float64(consumed.Protein),
+					Goal:     float64(planned.Protein),
+					Unit:     "g",
+				},
+				Carbs: models.MacroItem{
+					Consumed: float64(consumed.Carbs),
+					Goal:     float64(planned.Carbs),
+					Unit:     "g",
+				},
+				Fats: models.MacroItem{
+					Consumed: float64(consumed.Fat),
+					Goal:     float64(planned.Fat),
+					Unit:     "g",
+				},
+				Fibre: models.MacroItem{
+					Consumed:  consumedNutrients.Fibre,
+					Goal:      plannedNutrients.Fibre,
+					Unit:      "g",
+					Reference: referenceNutrients.Fibre,
+				},
+				Sugar: models.MacroItem{
+					Consumed:  consumedNutrients.Sugar,
+					Goal:      plannedNutrients.Sugar,
+					Unit:      "g",
+					Reference: referenceNutrients.Sugar,
+				},
+				SaturatedFat: models.MacroItem{
+					Consumed:  consumedNutrients.SaturatedFat,
+					Goal:      plannedNutrients.SaturatedFat,
+					Unit:      "g",
+					Reference: referenceNutrients.SaturatedFat,
+				},
+				Sodium: models.MacroItem{
+					Consumed:  consumedNutrients.Sodium,
+					Goal:      plannedNutrients.Sodium,
+					Unit:      "mg",
+					Reference: referenceNutrients.Sodium,
+				},
+				Iron: models.MacroItem{
+					Consumed:  consumedNutrients.Iron,
+					Goal:      plannedNutrients.Iron,
+					Unit:      "mg",
+					Reference: referenceNutrients.Iron,
+				},
+				Calcium: models.MacroItem{
+					Consumed:  consumedNutrients.Calcium,
+					Goal:      plannedNutrients.Calcium,
+					Unit:      "mg",
+					Reference: referenceNutrients.Calcium,
+				},
+				VitaminB12: models.MacroItem{
+					Consumed:  consumedNutrients.VitaminB12,
+					Goal:      plannedNutrients.VitaminB12,
+					Unit:      "mcg",
+					Reference: referenceNutrients.VitaminB12,
+				},
+				VitaminD: models.MacroItem{
+					Consumed:  consumedNutrients.VitaminD,
+					Goal:      plannedNutrients.VitaminD,
+					Unit:      "mcg",
+					Reference: referenceNutrients.VitaminD,
+				},
+			},
+		},
+		Hydration:    utils.GetHydrationSummary(today, utils.GetDailyHydrationTargetInMl(&weeklyGoal), hydrationLogs),
+		TodayMeals:   dayMeal.Meals,
+		TodayWorkout: todayWorkout,
+	}
+
+	return &dashboardResponse, nil
+}
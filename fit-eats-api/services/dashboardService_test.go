@@ -0,0 +1,27 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"fit-eats-api/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// The dashboard needs a user, a running goal and a meal plan covering today before the logs are read
+func TestDashboardServiceRules(t *testing.T) {
+	ctx := context.Background()
+	fixture := newMealPlanFixture(t, true)
+	service := NewDashboardService(fixture.service.UserRepository, fixture.service.UserGoalRepository, fixture.service.MealRepository, nil, nil, nil)
+
+	_, err := service.GetDashboard(ctx, primitive.NewObjectID())
+	expectErrorCode(t, err, models.USER_NOT_FOUND)
+
+	otherId := createTestUser(t, service.UserRepository, true)
+	_, err = service.GetDashboard(ctx, otherId)
+	expectErrorCode(t, err, models.GOAL_NOT_FOUND)
+
+	_, err = service.GetDashboard(ctx, fixture.userId)
+	expectErrorCode(t, err, models.MEAL_PLAN_NOT_FOUND)
+}
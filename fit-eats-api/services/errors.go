@@ -0,0 +1,24 @@
+package services
+
+import (
+	"errors"
+	"fit-eats-api/models"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Services report failed business rules as *models.ApiError so every transport can switch on the same codes,
+// the http api passes them to ctx.Error as they are.
+
+// toServiceError turns a missing document into the not found error, any other repository error is internal.
+// An ApiError of a rule checked inside a unit of work is returned as it is
+func toServiceError(err error, notFound *models.ApiError, failedMessage string) error {
+	var apiError *models.ApiError
+	if errors.As(err, &apiError) {
+		return apiError
+	}
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return notFound
+	}
+	return models.NewApiError(models.INTERNAL_ERROR, failedMessage).WithCause(err)
+}
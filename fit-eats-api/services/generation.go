@@ -0,0 +1,56 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fit-eats-api/models"
+	"strings"
+
+	"github.com/google/generative-ai-go/genai"
+)
+
+// generateJson runs the prompt against the model, which is configured to answer with a json object
+func generateJson(ctx context.Context, model *genai.GenerativeModel, prompt string) (map[string]any, error) {
+	resp, err := model.GenerateContent(ctx, genai.Text(prompt))
+	if err != nil {
+		return nil, models.NewApiError(models.LLM_UNAVAILABLE, "Could not generate content, please try again").WithCause(err)
+	}
+
+	if len(resp.Candidates) == 0 || resp.Candidates[0].Content == nil || len(resp.Candidates[0].Content.Parts) == 0 {
+		return nil, models.NewApiError(models.LLM_INVALID_RESPONSE, "No content generated by the model")
+	}
+
+	content, ok := resp.Candidates[0].Content.Parts[0].(genai.Text)
+	if !ok {
+		return nil, models.NewApiError(models.LLM_INVALID_RESPONSE, "Unexpected content format from the model")
+	}
+
+	var result map[string]any
+	if err := json.Unmarshal([]byte(content), &result); err != nil {
+		return nil, models.NewApiError(models.LLM_INVALID_RESPONSE, "Could not read the generated content, please try again").WithCause(err)
+	}
+	return result, nil
+}
+
+// generateDietCompliantMeals runs the prompt against the model and passes the json result to validate,
+// if any violations are found the model is asked once more with the violations attached to the prompt.
+// The violations of the last attempt are returned.
+func generateDietCompliantMeals(ctx context.Context, model *genai.GenerativeModel, prompt string, validate func(result map[string]any) []string) ([]string, error) {
+	var violations []string
+	for attempt := 0; attempt < 2; attempt++ {
+		if len(violations) > 0 {
+			prompt += " Your previous response did not follow my preferences: " + strings.Join(violations, "; ") + ". Fix these issues."
+		}
+
+		result, err := generateJson(ctx, model, prompt)
+		if err != nil {
+			return nil, err
+		}
+
+		violations = validate(result)
+		if len(violations) == 0 {
+			return nil, nil
+		}
+	}
+	return violations, nil
+}
@@ -0,0 +1,381 @@
+package services
+
+import (
+	"context"
+	"fit-eats-api/config"
+	"fit-eats-api/models"
+	"fit-eats-api/repositories"
+	"fit-eats-api/utils"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// GoalService holds the rules of the main and weekly goals and the estimates the goals are planned with.
+// The meal plans of a goal are deleted along with it
+type GoalService struct {
+	UserRepository     repositories.UserRepository
+	UserGoalRepository repositories.UserGoalRepository
+	MealRepository     repositories.MealRepository
+	UnitOfWork         repositories.UnitOfWork
+}
+
+func NewGoalService(userRepository repositories.UserRepository, userGoalRepository repositories.UserGoalRepository,
+	mealRepository repositories.MealRepository, unitOfWork repositories.UnitOfWork) *GoalService {
+	return &GoalService{UserRepository: userRepository, UserGoalRepository: userGoalRepository, MealRepository: mealRepository, UnitOfWork: unitOfWork}
+}
+
+// GoalEstimate is the body composition of the user an estimate is asked for, each estimate uses a subset of the fields
+type GoalEstimate struct {
+	UserId                   primitive.ObjectID
+	CurrentWeightInKg        float64
+	GoalWeightInKg           float64
+	CurrentBodyFatPercentage float64
+	GoalBodyFatPercentage    float64
+	GoalType                 string
+	CurrentBmr               int64
+	CurrentTdee              int64
+	WeightChange             float64
+}
+
+func (e GoalEstimate) validate() error {
+	if e.CurrentWeightInKg < 30 || e.CurrentWeightInKg > 250 {
+		return models.NewApiError(models.INVALID_REQUEST, "currentWeightInKg must be between 30 and 250")
+	}
+	if e.CurrentBodyFatPercentage < 10 || e.CurrentBodyFatPercentage > 80 {
+		return models.NewApiError(models.INVALID_REQUEST, "currentBodyFatPercentage must be between 10 and 80")
+	}
+	return nil
+}
+
+func (s *GoalService) GetGoalOwnerId(ctx context.Context, goalId primitive.ObjectID) (primitive.ObjectID, error) {
+	ownerId, err := s.UserGoalRepository.GetGoalOwnerId(ctx, goalId)
+	if err != nil {
+		return primitive.NilObjectID, toServiceError(err, models.NewApiError(models.GOAL_NOT_FOUND, "Goal not found"), "Could not get goal")
+	}
+	return ownerId, nil
+}
+
+// GetActiveGoal returns the goal of the user with only the weekly goal running today
+func (s *GoalService) GetActiveGoal(ctx context.Context, userId primitive.ObjectID) (*models.Goal, error) {
+	goal, err := s.UserGoalRepository.GetUserActiveGoalByUserId(ctx, userId)
+	if err != nil {
+		return nil, toServiceError(err, models.NewApiError(models.GOAL_NOT_FOUND, "No active goal"), "Could not get active weekly goal")
+	}
+	return goal, nil
+}
+
+func (s *GoalService) GetUserGoal(ctx context.Context, userId primitive.ObjectID) (*models.Goal, error) {
+	goal, err := s.UserGoalRepository.GetUserGoalByUserId(ctx, userId)
+	if err != nil {
+		return nil, toServiceError(err, models.NewApiError(models.GOAL_NOT_FOUND, "Goal not found"), "Could not get main goal")
+	}
+	return goal, nil
+}
+
+// GetGoals lists the goals of the user in the status, the latest started first. Without a status every goal but the
+// archived ones is listed
+func (s *GoalService) GetGoals(ctx context.Context, userId primitive.ObjectID, status models.GoalStatus) ([]models.Goal, error) {
+	statuses := []models.GoalStatus{models.GOAL_ACTIVE, models.GOAL_COMPLETED, models.GOAL_ABANDONED}
+	if status != "" {
+		statuses = []models.GoalStatus{status}
+	}
+
+	goals, err := s.UserGoalRepository.GetUserGoalsByUserId(ctx, userId, statuses)
+	if err != nil {
+		return nil, models.NewApiError(models.INTERNAL_ERROR, "Could not get goals").WithCause(err)
+	}
+	return goals, nil
+}
+
+func (s *GoalService) GetGoal(ctx context.Context, goalId primitive.ObjectID) (*models.Goal, error) {
+	goal, err := s.UserGoalRepository.GetUserGoalById(ctx, goalId)
+	if err != nil {
+		return nil, toServiceError(err, models.NewApiError(models.GOAL_NOT_FOUND, "Goal not found"), "Could not get goal")
+	}
+	return goal, nil
+}
+
+func (s *GoalService) GetWeeklyGoal(ctx context.Context, goalId primitive.ObjectID, weeklyGoalId primitive.ObjectID) (*models.WeeklyGoal, error) {
+	goal, err := s.GetGoal(ctx, goalId)
+	if err != nil {
+		return nil, err
+	}
+	return findWeeklyGoal(goal, weeklyGoalId)
+}
+
+// getActiveWeeklyGoal returns the week of the active goal. The weeks of an ended goal are kept as they were, its
+// outcome was calculated from them
+func (s *GoalService) getActiveWeeklyGoal(ctx context.Context, goalId primitive.ObjectID, weeklyGoalId primitive.ObjectID) (*models.WeeklyGoal, error) {
+	goal, err := s.GetGoal(ctx, goalId)
+	if err != nil {
+		return nil, err
+	}
+	if goal.Status != models.GOAL_ACTIVE {
+		return nil, models.NewApiError(models.INVALID_STATE, "Only the weeks of the active goal can be changed")
+	}
+	return findWeeklyGoal(goal, weeklyGoalId)
+}
+
+func findWeeklyGoal(goal *models.Goal, weeklyGoalId primitive.ObjectID) (*models.WeeklyGoal, error) {
+	for _, weeklyGoal := range goal.WeeklyGoals {
+		if weeklyGoal.ID == weeklyGoalId {
+			return &weeklyGoal, nil
+		}
+	}
+	return nil, models.NewApiError(models.WEEKLY_GOAL_NOT_FOUND, "Weekly goal not found")
+}
+
+// CreateGoal validates and stores the main goal as the active goal of the user, a user has a single active goal.
+// A goal created without a start weight starts from the last weight measured in the previous goals. The targets are
+// checked against the rule of the goal type, which also sets the weekly weight change, and a pace faster than what is
+// safe is returned as a warning
+func (s *GoalService) CreateGoal(ctx context.Context, goal *models.Goal) error {
+	errors := utils.ValidateStruct(*goal)
+	if errors != nil {
+		return models.NewValidationError(errors)
+	}
+
+	existing, _ := s.UserGoalRepository.GetUserGoalByUserId(ctx, goal.UserId)
+	if existing != nil {
+		return models.NewApiError(models.ALREADY_EXISTS, "An active goal already exists, complete or abandon it first")
+	}
+
+	if goal.StartWeightInKg == 0 {
+		previous, err := s.UserGoalRepository.GetUserGoalsByUserId(ctx, goal.UserId,
+			[]models.GoalStatus{models.GOAL_COMPLETED, models.GOAL_ABANDONED, models.GOAL_ARCHIVED})
+		if err != nil {
+			return models.NewApiError(models.INTERNAL_ERROR, "Could not register goal").WithCause(err)
+		}
+		if weight, fatPercentage, ok := utils.GetLastMeasurement(previous); ok {
+			goal.StartWeightInKg = weight
+			if goal.StartFatPercentage == 0 {
+				goal.StartFatPercentage = fatPercentage
+			}
+		}
+	}
+
+	now := time.Now()
+	if errors := utils.ValidateGoalTargets(*goal, now); errors != nil {
+		return models.NewValidationError(errors)
+	}
+	goal.WeeklyWeightChange = utils.GetGoalWeeklyWeightChange(*goal, now)
+	goal.Warnings = utils.GetGoalWarnings(*goal)
+
+	goal.ID = primitive.NewObjectID()
+	goal.Status = models.GOAL_ACTIVE
+	goal.EndedAt = nil
+	goal.Outcome = nil
+	err := s.UserGoalRepository.CreateMainUserGoal(ctx, goal)
+	if mongo.IsDuplicateKeyError(err) {
+		return models.NewApiError(models.ALREADY_EXISTS, "An active goal already exists, complete or abandon it first")
+	}
+	if err != nil {
+		return models.NewApiError(models.INTERNAL_ERROR, "Could not register goal").WithCause(err)
+	}
+	return nil
+}
+
+// CreateWeeklyGoal validates and adds the weekly goal to the main goal, weeks are only added to the active goal
+func (s *GoalService) CreateWeeklyGoal(ctx context.Context, goalId primitive.ObjectID, weeklyGoal *models.WeeklyGoal) error {
+	errors := utils.ValidateStruct(*weeklyGoal)
+	if errors != nil {
+		return models.NewValidationError(errors)
+	}
+
+	goal, err := s.GetGoal(ctx, goalId)
+	if err != nil {
+		return err
+	}
+	if goal.Status != models.GOAL_ACTIVE {
+		return models.NewApiError(models.INVALID_STATE, "Weekly goals can only be added to the active goal")
+	}
+
+	err = s.UserGoalRepository.CreateWeeklyUserGoal(ctx, goalId, weeklyGoal)
+	if err != nil {
+		// Nothing matches when the goal ended since it was read
+		return toServiceError(err, models.NewApiError(models.INVALID_STATE, "Weekly goals can only be added to the active goal"), "Could not register goal")
+	}
+	return nil
+}
+
+// DeleteGoal deletes the main goal and the meal plans of its weekly goals
+func (s *GoalService) DeleteGoal(ctx context.Context, goalId primitive.ObjectID) error {
+	err := deleteGoalWithMealPlans(ctx, s.UnitOfWork, s.UserGoalRepository, s.MealRepository, goalId)
+	if err != nil {
+		return models.NewApiError(models.INTERNAL_ERROR, "Could not delete goal").WithCause(err)
+	}
+	return nil
+}
+
+// ChangeGoalStatus completes or abandons the active goal, storing its outcome, or archives an ended goal
+func (s *GoalService) ChangeGoalStatus(ctx context.Context, goalId primitive.ObjectID, update models.GoalStatusUpdate) (*models.Goal, error) {
+	errors := utils.ValidateStruct(update)
+	if errors != nil {
+		return nil, models.NewValidationError(errors)
+	}
+
+	goal, err := s.GetGoal(ctx, goalId)
+	if err != nil {
+		return nil, err
+	}
+	if !goal.Status.CanChangeTo(update.Status) {
+		return nil, models.NewApiError(models.INVALID_STATE, fmt.Sprintf("A goal which is %s cannot change to %s", goal.Status, update.Status))
+	}
+
+	if update.Status == models.GOAL_ARCHIVED {
+		err = s.UserGoalRepository.ArchiveUserGoal(ctx, goalId)
+	} else {
+		var mealPlans []models.MealPlan
+		mealPlans, err = s.MealRepository.GetMealPlansByMainGoalId(ctx, goalId)
+		if err != nil {
+			return nil, models.NewApiError(models.INTERNAL_ERROR, "Could not change goal status").WithCause(err)
+		}
+		outcome := utils.GetGoalOutcome(*goal, mealPlans, time.Now())
+		err = s.UserGoalRepository.EndUserGoal(ctx, goalId, update.Status, &outcome)
+	}
+	if err != nil {
+		// Nothing matches when the status changed since the goal was read
+		return nil, toServiceError(err, models.NewApiError(models.INVALID_STATE, "The goal status changed meanwhile, try again"), "Could not change goal status")
+	}
+
+	return s.GetGoal(ctx, goalId)
+}
+
+// UpdateWeeklyGoal edits the weight and targets of a week of the active goal and recalculates the values derived from them.
+// The meal plan of the week was generated for the old targets, it is removed so it can be generated again unless
+// meals of it are already consumed
+func (s *GoalService) UpdateWeeklyGoal(ctx context.Context, goalId primitive.ObjectID, weeklyGoalId primitive.ObjectID, update models.WeeklyGoalUpdate) (*models.WeeklyGoal, error) {
+	errors := utils.ValidateStruct(update)
+	if errors != nil {
+		return nil, models.NewValidationError(errors)
+	}
+	if update.HasMacros() && update.TargetDailyCalories != nil {
+		return nil, models.NewValidationError(map[string]string{"targetdailycalories": "targetdailycalories follows the macros, set one or the other"})
+	}
+
+	// The targets are recalculated from the week read in the same unit of work as the write
+	var updated models.WeeklyGoal
+	err := s.UnitOfWork.Do(ctx, func(ctx context.Context) error {
+		weeklyGoal, err := s.getActiveWeeklyGoal(ctx, goalId, weeklyGoalId)
+		if err != nil {
+			return err
+		}
+		updated = utils.ApplyWeeklyGoalUpdate(*weeklyGoal, update)
+
+		if err := s.UserGoalRepository.UpdateWeeklyUserGoal(ctx, goalId, &updated); err != nil {
+			return err
+		}
+		if !update.ChangesTargets() {
+			return nil
+		}
+
+		consumed, err := s.MealRepository.HasConsumedMeals(ctx, goalId, weeklyGoalId)
+		if err != nil || consumed {
+			return err
+		}
+		_, err = s.MealRepository.DeleteMealPlansByWeeklyGoalId(ctx, goalId, weeklyGoalId)
+		return err
+	})
+	if err != nil {
+		return nil, toServiceError(err, models.NewApiError(models.WEEKLY_GOAL_NOT_FOUND, "Weekly goal not found"), "Could not update goal")
+	}
+	return &updated, nil
+}
+
+// DeleteWeeklyGoal removes the week from the active goal along with its meal plan. A week with consumed meals is kept,
+// its meals are part of the history of the goal
+func (s *GoalService) DeleteWeeklyGoal(ctx context.Context, goalId primitive.ObjectID, weeklyGoalId primitive.ObjectID) error {
+	err := s.UnitOfWork.Do(ctx, func(ctx context.Context) error {
+		if _, err := s.getActiveWeeklyGoal(ctx, goalId, weeklyGoalId); err != nil {
+			return err
+		}
+
+		consumed, err := s.MealRepository.HasConsumedMeals(ctx, goalId, weeklyGoalId)
+		if err != nil {
+			return err
+		}
+		if consumed {
+			return models.NewApiError(models.INVALID_STATE, "The week has consumed meals and cannot be deleted")
+		}
+
+		if err := s.UserGoalRepository.DeleteWeeklyUserGoal(ctx, goalId, weeklyGoalId); err != nil {
+			return err
+		}
+		_, err = s.MealRepository.DeleteMealPlansByWeeklyGoalId(ctx, goalId, weeklyGoalId)
+		return err
+	})
+	if err != nil {
+		return toServiceError(err, models.NewApiError(models.WEEKLY_GOAL_NOT_FOUND, "Weekly goal not found"), "Could not delete goal")
+	}
+	return nil
+}
+
+// GetIdealWeightRange estimates the healthy weight range of the user from the current weight and body fat
+func (s *GoalService) GetIdealWeightRange(ctx context.Context, estimate GoalEstimate) (map[string]any, error) {
+	user, err := s.getEstimateUser(ctx, estimate)
+	if err != nil {
+		return nil, err
+	}
+
+	prompt := config.GetWeightRangePrompt(*user, float32(estimate.CurrentWeightInKg), float32(estimate.CurrentBodyFatPercentage))
+	return generateJson(ctx, config.GetWeightRangeModel(), prompt)
+}
+
+// GetGoalDuration estimates how long reaching the goal weight and body fat takes
+func (s *GoalService) GetGoalDuration(ctx context.Context, estimate GoalEstimate) (map[string]any, error) {
+	user, err := s.getEstimateUser(ctx, estimate)
+	if err != nil {
+		return nil, err
+	}
+
+	prompt := config.GetGoalDurationPrompt(*user, float32(estimate.CurrentWeightInKg), float32(estimate.CurrentBodyFatPercentage),
+		float32(estimate.GoalWeightInKg), float32(estimate.GoalBodyFatPercentage))
+	return generateJson(ctx, config.GetGoalDurationModel(), prompt)
+}
+
+// GetTdee estimates the bmr and daily energy expenditure of the user for the goal type
+func (s *GoalService) GetTdee(ctx context.Context, estimate GoalEstimate) (map[string]any, error) {
+	user, err := s.getEstimateUser(ctx, estimate)
+	if err != nil {
+		return nil, err
+	}
+
+	prompt := config.GetTdeePrompt(*user, float32(estimate.CurrentWeightInKg), float32(estimate.CurrentBodyFatPercentage),
+		float32(estimate.GoalWeightInKg), float32(estimate.GoalBodyFatPercentage), estimate.GoalType)
+	return generateJson(ctx, config.GetTdeeModel(), prompt)
+}
+
+// GetMacros estimates the daily calories and macros from the tdee and the weekly weight change
+func (s *GoalService) GetMacros(ctx context.Context, estimate GoalEstimate) (map[string]any, error) {
+	user, err := s.getEstimateUser(ctx, estimate)
+	if err != nil {
+		return nil, err
+	}
+
+	prompt := config.GetDailyMacroPrompt(*user, float32(estimate.CurrentWeightInKg), float32(estimate.CurrentBodyFatPercentage),
+		float32(estimate.GoalWeightInKg), float32(estimate.GoalBodyFatPercentage), estimate.GoalType,
+		int32(estimate.CurrentBmr), int32(estimate.CurrentTdee), float32(estimate.WeightChange))
+	return generateJson(ctx, config.GetMacroModel(), prompt)
+}
+
+func deleteGoalWithMealPlans(ctx context.Context, unitOfWork repositories.UnitOfWork, userGoalRepository repositories.UserGoalRepository,
+	mealRepository repositories.MealRepository, goalId primitive.ObjectID) error {
+	return unitOfWork.Do(ctx, func(ctx context.Context) error {
+		if err := userGoalRepository.DeleteMainUserGoal(ctx, goalId); err != nil {
+			return err
+		}
+		_, err := mealRepository.DeleteMealPlansByMainGoalId(ctx, goalId)
+		return err
+	})
+}
+
+// getEstimateUser validates the estimate and returns the complete profile of its user
+func (s *GoalService) getEstimateUser(ctx context.Context, estimate GoalEstimate) (*models.User, error) {
+	if err := estimate.validate(); err != nil {
+		return nil, err
+	}
+	return getCompleteUserProfile(ctx, s.UserRepository, estimate.UserId)
+}
@@ -0,0 +1,367 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"fit-eats-api/models"
+	"fit-eats-api/repositories"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func newTestGoalService() *GoalService {
+	return NewGoalService(repositories.NewInMemoryUserRepository(), repositories.NewInMemoryUserGoalRepository(),
+		repositories.NewInMemoryMealRepository(), repositories.NewInMemoryUnitOfWork())
+}
+
+func newTestGoal(userId primitive.ObjectID) *models.Goal {
+	now := time.Now()
+	return &models.Goal{UserId: userId, GoalType: models.FAT_LOSS, StartWeightInKg: 90, TargetWeightInKg: 80, GoalStartDate: now, GoalEndDate: now.AddDate(0, 3, 0)}
+}
+
+func TestGoalServiceCreateGoal(t *testing.T) {
+	ctx := context.Background()
+	service := newTestGoalService()
+	userId := primitive.NewObjectID()
+
+	goal := newTestGoal(userId)
+	expectNoError(t, service.CreateGoal(ctx, goal))
+	if goal.ID.IsZero() {
+		t.Fatal("expected the goal id set")
+	}
+	expectErrorCode(t, service.CreateGoal(ctx, newTestGoal(userId)), models.ALREADY_EXISTS)
+
+	ownerId, err := service.GetGoalOwnerId(ctx, goal.ID)
+	expectNoError(t, err)
+	if ownerId != userId {
+		t.Errorf("expected owner %s, got %s", userId.Hex(), ownerId.Hex())
+	}
+	_, err = service.GetGoalOwnerId(ctx, primitive.NewObjectID())
+	expectErrorCode(t, err, models.GOAL_NOT_FOUND)
+
+	goals, err := service.GetGoals(ctx, primitive.NewObjectID(), "")
+	expectNoError(t, err)
+	if goals == nil || len(goals) != 0 {
+		t.Errorf("expected an empty list, got %+v", goals)
+	}
+	_, err = service.GetUserGoal(ctx, primitive.NewObjectID())
+	expectErrorCode(t, err, models.GOAL_NOT_FOUND)
+}
+
+func TestGoalServiceGoalTypes(t *testing.T) {
+	ctx := context.Background()
+	service := newTestGoalService()
+	now := time.Now()
+	newGoal := func(goalType models.GoalType, startWeight float64, targetWeight float64, end time.Time) *models.Goal {
+		return &models.Goal{UserId: primitive.NewObjectID(), GoalType: goalType, StartWeightInKg: startWeight, TargetWeightInKg: targetWeight,
+			StartFatPercentage: 25, GoalStartDate: now, GoalEndDate: end, WeeklyWeightChange: 0.5}
+	}
+	later := now.AddDate(0, 3, 0)
+
+	expectErrorCode(t, service.CreateGoal(ctx, newGoal("Bulk", 80, 85, later)), models.VALIDATION_FAILED)
+	expectErrorCode(t, service.CreateGoal(ctx, newGoal(models.FAT_LOSS, 80, 85, later)), models.VALIDATION_FAILED)
+	expectErrorCode(t, service.CreateGoal(ctx, newGoal(models.MUSCLE_GAIN, 80, 75, later)), models.VALIDATION_FAILED)
+	expectErrorCode(t, service.CreateGoal(ctx, newGoal(models.MAINTENANCE, 80, 78, later)), models.VALIDATION_FAILED)
+
+	// Recomposition keeps the weight and lowers the body fat
+	recomposition := newGoal(models.RECOMPOSITION, 80, 79, later)
+	expectErrorCode(t, service.CreateGoal(ctx, recomposition), models.VALIDATION_FAILED)
+	recomposition.TargetFatPercentage = 28
+	expectErrorCode(t, service.CreateGoal(ctx, recomposition), models.VALIDATION_FAILED)
+	recomposition.TargetFatPercentage = 20
+	expectNoError(t, service.CreateGoal(ctx, recomposition))
+	maintenance := newGoal(models.MAINTENANCE, 80, 80.5, later)
+	expectNoError(t, service.CreateGoal(ctx, maintenance))
+	if recomposition.WeeklyWeightChange != 0 || maintenance.WeeklyWeightChange != 0 {
+		t.Errorf("expected no weekly weight change, got %v and %v", recomposition.WeeklyWeightChange, maintenance.WeeklyWeightChange)
+	}
+
+	// Event goals change by what reaching the target by the date takes, a pace above 1% of the weight a week is not safe
+	expectErrorCode(t, service.CreateGoal(ctx, newGoal(models.EVENT, 80, 76, now.AddDate(0, 0, -1))), models.VALIDATION_FAILED)
+	event := newGoal(models.EVENT, 80, 76, now.AddDate(0, 0, 70))
+	event.EventName = "Wedding"
+	expectNoError(t, service.CreateGoal(ctx, event))
+	if event.WeeklyWeightChange != -0.4 || len(event.Warnings) != 0 {
+		t.Errorf("expected a safe loss of 0.4 kg a week, got %v with %v", event.WeeklyWeightChange, event.Warnings)
+	}
+	rushed := newGoal(models.EVENT, 80, 76, now.AddDate(0, 0, 14))
+	expectNoError(t, service.CreateGoal(ctx, rushed))
+	if rushed.WeeklyWeightChange != -2 || len(rushed.Warnings) != 1 {
+		t.Errorf("expected an unsafe loss of 2 kg a week with a warning, got %v with %v", rushed.WeeklyWeightChange, rushed.Warnings)
+	}
+
+	// The pace chosen for a muscle gain is kept and checked the same way
+	gain := newGoal(models.MUSCLE_GAIN, 80, 90, later)
+	expectNoError(t, service.CreateGoal(ctx, gain))
+	if gain.WeeklyWeightChange != 0.5 || len(gain.Warnings) != 1 {
+		t.Errorf("expected the chosen gain with a warning above 0.4 kg a week, got %v with %v", gain.WeeklyWeightChange, gain.Warnings)
+	}
+}
+
+// An event goal needs a start weight to pace the weeks until the event, given or taken from the previous goal
+func TestGoalServiceEventGoalStartWeight(t *testing.T) {
+	ctx := context.Background()
+	service := newTestGoalService()
+	userId := primitive.NewObjectID()
+	newEvent := func() *models.Goal {
+		now := time.Now()
+		return &models.Goal{UserId: userId, GoalType: models.EVENT, EventName: "Wedding", TargetWeightInKg: 76, GoalStartDate: now, GoalEndDate: now.AddDate(0, 0, 70)}
+	}
+
+	err := service.CreateGoal(ctx, newEvent())
+	expectErrorCode(t, err, models.VALIDATION_FAILED)
+	if _, ok := err.(*models.ApiError).Fields["startweightinkg"]; !ok {
+		t.Errorf("expected the start weight required, got %v", err)
+	}
+
+	previous := newTestGoal(userId)
+	expectNoError(t, service.CreateGoal(ctx, previous))
+	_, err = service.ChangeGoalStatus(ctx, previous.ID, models.GoalStatusUpdate{Status: models.GOAL_ABANDONED})
+	expectNoError(t, err)
+
+	event := newEvent()
+	expectNoError(t, service.CreateGoal(ctx, event))
+	if event.StartWeightInKg != 90 || event.WeeklyWeightChange != -1.4 || len(event.Warnings) != 1 {
+		t.Errorf("expected the event paced from the previous goal weight with a warning, got %v kg with %v", event.WeeklyWeightChange, event.Warnings)
+	}
+}
+
+func TestGoalServiceGoalLifecycle(t *testing.T) {
+	ctx := context.Background()
+	service := newTestGoalService()
+	userId := primitive.NewObjectID()
+	goal := newTestGoal(userId)
+	expectNoError(t, service.CreateGoal(ctx, goal))
+	start := time.Now().AddDate(0, 0, -8)
+	var weeklyGoalId primitive.ObjectID
+	for i, weight := range []float64{89, 88} {
+		weekStart := start.AddDate(0, 0, 7*i)
+		weeklyGoal := &models.WeeklyGoal{StartDate: weekStart, EndDate: weekStart.AddDate(0, 0, 7), CurrentWeightInKg: weight, CurrentFatPercentage: 24}
+		expectNoError(t, service.CreateWeeklyGoal(ctx, goal.ID, weeklyGoal))
+		weeklyGoalId = weeklyGoal.ID
+	}
+
+	// Half of the calories planned until today are eaten, the day after today does not count
+	dayMeals := []models.DayMeal{
+		{ID: primitive.NewObjectID(), Date: start, Meals: []models.Meal{{ID: primitive.NewObjectID(), Calories: 600, IsConsumed: true}, {ID: primitive.NewObjectID(), Calories: 600}}},
+		{ID: primitive.NewObjectID(), Date: time.Now().AddDate(0, 0, 1), Meals: []models.Meal{{ID: primitive.NewObjectID(), Calories: 800}}},
+	}
+	expectNoError(t, service.MealRepository.CreateWeeklyMealPlan(ctx, &models.MealPlan{UserId: userId, MainGoalId: goal.ID, DayMeals: dayMeals}))
+
+	_, err := service.ChangeGoalStatus(ctx, goal.ID, models.GoalStatusUpdate{Status: models.GOAL_ACTIVE})
+	expectErrorCode(t, err, models.VALIDATION_FAILED)
+	_, err = service.ChangeGoalStatus(ctx, goal.ID, models.GoalStatusUpdate{Status: models.GOAL_ARCHIVED})
+	expectErrorCode(t, err, models.INVALID_STATE)
+
+	ended, err := service.ChangeGoalStatus(ctx, goal.ID, models.GoalStatusUpdate{Status: models.GOAL_ABANDONED})
+	expectNoError(t, err)
+	outcome := ended.Outcome
+	if ended.Status != models.GOAL_ABANDONED || ended.EndedAt == nil || outcome == nil {
+		t.Fatalf("expected the goal abandoned with an outcome, got %+v", ended)
+	}
+	if outcome.StartWeightInKg != 90 || outcome.EndWeightInKg != 88 || outcome.WeightChangeInKg != -2 || outcome.WeeksTracked != 2 ||
+		outcome.Progress == nil || *outcome.Progress != 0.2 {
+		t.Errorf("unexpected weight outcome %+v", outcome)
+	}
+	if outcome.PlannedCalories != 1200 || outcome.ConsumedCalories != 600 || outcome.Adherence == nil || *outcome.Adherence != 0.5 {
+		t.Errorf("unexpected adherence outcome %+v", outcome)
+	}
+
+	_, err = service.ChangeGoalStatus(ctx, goal.ID, models.GoalStatusUpdate{Status: models.GOAL_COMPLETED})
+	expectErrorCode(t, err, models.INVALID_STATE)
+	expectErrorCode(t, service.CreateWeeklyGoal(ctx, goal.ID, &models.WeeklyGoal{StartDate: start, EndDate: start.AddDate(0, 0, 7)}), models.INVALID_STATE)
+	// The weeks of an ended goal are kept as its outcome was calculated from them
+	weight := 80.0
+	_, err = service.UpdateWeeklyGoal(ctx, goal.ID, weeklyGoalId, models.WeeklyGoalUpdate{CurrentWeightInKg: &weight})
+	expectErrorCode(t, err, models.INVALID_STATE)
+	expectErrorCode(t, service.DeleteWeeklyGoal(ctx, goal.ID, weeklyGoalId), models.INVALID_STATE)
+	_, err = service.GetUserGoal(ctx, userId)
+	expectErrorCode(t, err, models.GOAL_NOT_FOUND)
+
+	// The next goal starts from the last weekly check in
+	next := newTestGoal(userId)
+	next.GoalType, next.StartWeightInKg, next.TargetWeightInKg = models.MUSCLE_GAIN, 0, 92
+	next.GoalStartDate = time.Now().Add(time.Hour)
+	expectNoError(t, service.CreateGoal(ctx, next))
+	if next.Status != models.GOAL_ACTIVE || next.StartWeightInKg != 88 || next.StartFatPercentage != 24 {
+		t.Errorf("expected the new goal active from the last measurement, got %+v", next)
+	}
+
+	goals, err := service.GetGoals(ctx, userId, "")
+	expectNoError(t, err)
+	if len(goals) != 2 || goals[0].ID != next.ID || goals[1].ID != goal.ID {
+		t.Fatalf("expected both goals, the latest started first, got %+v", goals)
+	}
+
+	archived, err := service.ChangeGoalStatus(ctx, goal.ID, models.GoalStatusUpdate{Status: models.GOAL_ARCHIVED})
+	expectNoError(t, err)
+	if archived.Status != models.GOAL_ARCHIVED || archived.Outcome == nil {
+		t.Errorf("expected the archived goal to keep its outcome, got %+v", archived)
+	}
+	goals, err = service.GetGoals(ctx, userId, "")
+	expectNoError(t, err)
+	if len(goals) != 1 || goals[0].ID != next.ID {
+		t.Errorf("expected archived goals left out, got %+v", goals)
+	}
+	goals, err = service.GetGoals(ctx, userId, models.GOAL_ARCHIVED)
+	expectNoError(t, err)
+	if len(goals) != 1 || goals[0].ID != goal.ID {
+		t.Errorf("expected the archived goal, got %+v", goals)
+	}
+}
+
+func TestGoalServiceWeeklyGoals(t *testing.T) {
+	ctx := context.Background()
+	service := newTestGoalService()
+	userId := primitive.NewObjectID()
+	goal := newTestGoal(userId)
+	expectNoError(t, service.CreateGoal(ctx, goal))
+
+	_, err := service.GetActiveGoal(ctx, userId)
+	expectErrorCode(t, err, models.GOAL_NOT_FOUND)
+
+	start := time.Now().AddDate(0, 0, -1)
+	invalid := &models.WeeklyGoal{StartDate: start, EndDate: start.AddDate(0, 0, 7), ActivityLevel: "Lazy"}
+	expectErrorCode(t, service.CreateWeeklyGoal(ctx, goal.ID, invalid), models.VALIDATION_FAILED)
+	weeklyGoal := &models.WeeklyGoal{StartDate: start, EndDate: start.AddDate(0, 0, 7), CurrentWeightInKg: 90}
+	expectNoError(t, service.CreateWeeklyGoal(ctx, goal.ID, weeklyGoal))
+
+	active, err := service.GetActiveGoal(ctx, userId)
+	expectNoError(t, err)
+	if len(active.WeeklyGoals) != 1 || active.WeeklyGoals[0].ID != weeklyGoal.ID {
+		t.Errorf("expected the running week, got %+v", active.WeeklyGoals)
+	}
+
+	found, err := service.GetWeeklyGoal(ctx, goal.ID, weeklyGoal.ID)
+	expectNoError(t, err)
+	if found.CurrentWeightInKg != 90 {
+		t.Errorf("unexpected weekly goal %+v", found)
+	}
+	_, err = service.GetWeeklyGoal(ctx, goal.ID, primitive.NewObjectID())
+	expectErrorCode(t, err, models.WEEKLY_GOAL_NOT_FOUND)
+
+	mealPlan := &models.MealPlan{UserId: userId, MainGoalId: goal.ID, WeeklyGoalId: weeklyGoal.ID}
+	expectNoError(t, service.MealRepository.CreateWeeklyMealPlan(ctx, mealPlan))
+
+	expectNoError(t, service.DeleteGoal(ctx, goal.ID))
+	_, err = service.GetGoal(ctx, goal.ID)
+	expectErrorCode(t, err, models.GOAL_NOT_FOUND)
+	if service.MealRepository.IsWeeklyMealPlanCreated(ctx, userId, weeklyGoal.ID) {
+		t.Error("expected the meal plans of the goal deleted with it")
+	}
+}
+
+func TestGoalServiceUpdateWeeklyGoal(t *testing.T) {
+	ctx := context.Background()
+	service := newTestGoalService()
+	userId := primitive.NewObjectID()
+	goal := newTestGoal(userId)
+	expectNoError(t, service.CreateGoal(ctx, goal))
+	start := time.Now()
+	weeklyGoal := &models.WeeklyGoal{StartDate: start, EndDate: start.AddDate(0, 0, 7), CurrentWeightInKg: 90, ActivityLevel: models.MODERATE,
+		DailyMaintenanceCalories: 2600, TargetDailyCalories: 2100, TargetDailyMacrosProtein: 160, TargetDailyMacrosCarbs: 210, TargetDailyMacrosFats: 70}
+	expectNoError(t, service.CreateWeeklyGoal(ctx, goal.ID, weeklyGoal))
+	mealPlan := &models.MealPlan{UserId: userId, MainGoalId: goal.ID, WeeklyGoalId: weeklyGoal.ID}
+	expectNoError(t, service.MealRepository.CreateWeeklyMealPlan(ctx, mealPlan))
+
+	// Losing 2 kg lowers the maintenance by 2 * 10 * 1.55 kcal, the target keeps its deficit and the macros their split
+	weight := 88.0
+	updated, err := service.UpdateWeeklyGoal(ctx, goal.ID, weeklyGoal.ID, models.WeeklyGoalUpdate{CurrentWeightInKg: &weight})
+	expectNoError(t, err)
+	if updated.CurrentWeightInKg != 88 || updated.DailyMaintenanceCalories != 2569 || updated.TargetDailyCalories != 2069 {
+		t.Errorf("expected the calories to follow the weight, got %+v", updated)
+	}
+	if updated.TargetDailyMacrosProtein != 158 || updated.TargetDailyMacrosCarbs != 207 || updated.TargetDailyMacrosFats != 69 {
+		t.Errorf("expected the macros scaled to the target, got %+v", updated)
+	}
+	if service.MealRepository.IsWeeklyMealPlanCreated(ctx, userId, weeklyGoal.ID) {
+		t.Error("expected the meal plan of the old targets removed")
+	}
+
+	// Setting the macros derives the target calories from them
+	protein := 180.0
+	updated, err = service.UpdateWeeklyGoal(ctx, goal.ID, weeklyGoal.ID, models.WeeklyGoalUpdate{TargetDailyMacrosProtein: &protein})
+	expectNoError(t, err)
+	if updated.TargetDailyCalories != 180*4+207*4+69*9 || updated.DailyMaintenanceCalories != 2569 {
+		t.Errorf("expected the calories of the macros, got %+v", updated)
+	}
+	found, err := service.GetWeeklyGoal(ctx, goal.ID, weeklyGoal.ID)
+	expectNoError(t, err)
+	if *found != *updated {
+		t.Errorf("expected the update stored, got %+v", found)
+	}
+
+	calories := 2000.0
+	_, err = service.UpdateWeeklyGoal(ctx, goal.ID, weeklyGoal.ID, models.WeeklyGoalUpdate{TargetDailyCalories: &calories, TargetDailyMacrosProtein: &protein})
+	expectErrorCode(t, err, models.VALIDATION_FAILED)
+	weight = 10
+	_, err = service.UpdateWeeklyGoal(ctx, goal.ID, weeklyGoal.ID, models.WeeklyGoalUpdate{CurrentWeightInKg: &weight})
+	expectErrorCode(t, err, models.VALIDATION_FAILED)
+	_, err = service.UpdateWeeklyGoal(ctx, goal.ID, primitive.NewObjectID(), models.WeeklyGoalUpdate{TargetDailyCalories: &calories})
+	expectErrorCode(t, err, models.WEEKLY_GOAL_NOT_FOUND)
+}
+
+func TestGoalServiceDeleteWeeklyGoal(t *testing.T) {
+	ctx := context.Background()
+	service := newTestGoalService()
+	userId := primitive.NewObjectID()
+	goal := newTestGoal(userId)
+	expectNoError(t, service.CreateGoal(ctx, goal))
+	start := time.Now()
+	weeks := []*models.WeeklyGoal{
+		{StartDate: start.AddDate(0, 0, -7), EndDate: start, CurrentWeightInKg: 90},
+		{StartDate: start, EndDate: start.AddDate(0, 0, 7), CurrentWeightInKg: 89},
+	}
+	for _, week := range weeks {
+		expectNoError(t, service.CreateWeeklyGoal(ctx, goal.ID, week))
+	}
+
+	// The first week has a consumed meal, the second a plan nothing was eaten from yet
+	consumedMealId := primitive.NewObjectID()
+	for i, week := range weeks {
+		meal := models.Meal{ID: primitive.NewObjectID(), Name: "Dal"}
+		if i == 0 {
+			meal.ID, meal.IsConsumed = consumedMealId, true
+		}
+		mealPlan := &models.MealPlan{UserId: userId, MainGoalId: goal.ID, WeeklyGoalId: week.ID, DayMeals: []models.DayMeal{{ID: primitive.NewObjectID(), Meals: []models.Meal{meal}}}}
+		expectNoError(t, service.MealRepository.CreateWeeklyMealPlan(ctx, mealPlan))
+	}
+
+	expectErrorCode(t, service.DeleteWeeklyGoal(ctx, goal.ID, weeks[0].ID), models.INVALID_STATE)
+	expectNoError(t, service.DeleteWeeklyGoal(ctx, goal.ID, weeks[1].ID))
+	expectErrorCode(t, service.DeleteWeeklyGoal(ctx, goal.ID, weeks[1].ID), models.WEEKLY_GOAL_NOT_FOUND)
+
+	found, err := service.GetGoal(ctx, goal.ID)
+	expectNoError(t, err)
+	if len(found.WeeklyGoals) != 1 || found.WeeklyGoals[0].ID != weeks[0].ID {
+		t.Errorf("expected only the week with consumed meals kept, got %+v", found.WeeklyGoals)
+	}
+	if service.MealRepository.IsWeeklyMealPlanCreated(ctx, userId, weeks[1].ID) || !service.MealRepository.IsWeeklyMealPlanCreated(ctx, userId, weeks[0].ID) {
+		t.Error("expected only the meal plan of the deleted week removed")
+	}
+}
+
+// The estimates fail before the model is asked when the input or the profile cannot be used
+func TestGoalServiceEstimateRules(t *testing.T) {
+	ctx := context.Background()
+	service := newTestGoalService()
+	incompleteId := createTestUser(t, service.UserRepository, false)
+	estimate := GoalEstimate{UserId: incompleteId, CurrentWeightInKg: 80, CurrentBodyFatPercentage: 25}
+
+	_, err := service.GetTdee(ctx, estimate)
+	expectErrorCode(t, err, models.PROFILE_INCOMPLETE)
+
+	estimate.UserId = primitive.NewObjectID()
+	_, err = service.GetMacros(ctx, estimate)
+	expectErrorCode(t, err, models.USER_NOT_FOUND)
+
+	estimate.CurrentWeightInKg = 20
+	_, err = service.GetIdealWeightRange(ctx, estimate)
+	expectErrorCode(t, err, models.INVALID_REQUEST)
+
+	estimate.CurrentWeightInKg, estimate.CurrentBodyFatPercentage = 80, 90
+	_, err = service.GetGoalDuration(ctx, estimate)
+	expectErrorCode(t, err, models.INVALID_REQUEST)
+}
@@ -0,0 +1,217 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fit-eats-api/config"
+	"fit-eats-api/models"
+	"fit-eats-api/repositories"
+	"fit-eats-api/utils"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// MealPlanService generates the weekly meal plans of the goals and tracks the meals eaten.
+// Meal images are looked up with FindMealImage, the default searches the web for the meal name
+type MealPlanService struct {
+	UserRepository     repositories.UserRepository
+	UserGoalRepository repositories.UserGoalRepository
+	MealRepository     repositories.MealRepository
+	UnitOfWork         repositories.UnitOfWork
+	FindMealImage      func(ctx context.Context, mealName string) string
+}
+
+func NewMealPlanService(userRepository repositories.UserRepository, userGoalRepository repositories.UserGoalRepository, mealRepository repositories.MealRepository,
+	unitOfWork repositories.UnitOfWork) *MealPlanService {
+	return &MealPlanService{UserRepository: userRepository, UserGoalRepository: userGoalRepository, MealRepository: mealRepository, UnitOfWork: unitOfWork,
+		FindMealImage: utils.GetMealImageUrl}
+}
+
+func (s *MealPlanService) GetWeeklyMealPlan(ctx context.Context, userId primitive.ObjectID, mainGoalId primitive.ObjectID, weeklyGoalId primitive.ObjectID) (*models.MealPlan, error) {
+	mealPlan, err := s.MealRepository.GetWeeklyMealPlan(ctx, userId, mainGoalId, weeklyGoalId)
+	if err != nil || mealPlan == nil {
+		return nil, models.NewApiError(models.MEAL_PLAN_NOT_FOUND, "Meal Plan is not yet created")
+	}
+	return mealPlan, nil
+}
+
+func (s *MealPlanService) GetMealPlan(ctx context.Context, mealPlanId primitive.ObjectID) (*models.MealPlan, error) {
+	mealPlan, err := s.MealRepository.GetMealPlanById(ctx, mealPlanId)
+	if err != nil {
+		return nil, models.NewApiError(models.INTERNAL_ERROR, "Could not get meal plan").WithCause(err)
+	}
+	if mealPlan == nil {
+		return nil, models.NewApiError(models.MEAL_PLAN_NOT_FOUND, "Meal plan not found")
+	}
+	return mealPlan, nil
+}
+
+// GetMealPlanMeta returns the meal plan without its day meals
+func (s *MealPlanService) GetMealPlanMeta(ctx context.Context, mealPlanId primitive.ObjectID) (*models.MealPlan, error) {
+	mealPlan, err := s.MealRepository.GetMealPlanMeta(ctx, mealPlanId)
+	if err != nil || mealPlan == nil {
+		return nil, models.NewApiError(models.MEAL_PLAN_NOT_FOUND, "Meal plan not found")
+	}
+	return mealPlan, nil
+}
+
+// CreateWeeklyMealPlan generates and stores the meal plan of the weekly goal, the extra prompt steers the generation.
+// A weekly goal has a single meal plan. The generation takes a while, so the insert locks the week in the same unit of
+// work: a week deleted meanwhile fails the lock, one being deleted at the same time conflicts with the transaction,
+// and a plan generated at the same time for the week fails the unique index
+func (s *MealPlanService) CreateWeeklyMealPlan(ctx context.Context, userId primitive.ObjectID, mainGoalId primitive.ObjectID, weeklyGoalId primitive.ObjectID, extraPrompt string) (*models.MealPlan, error) {
+	user, err := getCompleteUserProfile(ctx, s.UserRepository, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	goal, err := s.UserGoalRepository.GetUserWeeklyGoal(ctx, mainGoalId, weeklyGoalId)
+	if err != nil || goal.UserId != userId {
+		return nil, models.NewApiError(models.GOAL_NOT_FOUND, "Goal not found")
+	}
+
+	if s.MealRepository.IsWeeklyMealPlanCreated(ctx, userId, weeklyGoalId) {
+		return nil, models.NewApiError(models.ALREADY_EXISTS, "Meal Plan is already created")
+	}
+
+	prompt := config.GetWeeklyMealPrompt(*user, extraPrompt, float32(goal.WeeklyGoals[0].CurrentWeightInKg), float32(goal.WeeklyGoals[0].CurrentFatPercentage),
+		float32(goal.TargetWeightInKg), float32(goal.TargetFatPercentage), int32(goal.WeeklyGoals[0].TargetDailyCalories),
+		int32(goal.WeeklyGoals[0].TargetDailyMacrosFats), int32(goal.WeeklyGoals[0].TargetDailyMacrosCarbs), int32(goal.WeeklyGoals[0].TargetDailyMacrosProtein), string(goal.GoalType))
+
+	var mealPlan models.MealPlan
+	violations, err := generateDietCompliantMeals(ctx, config.GetMealModel(), prompt, func(result map[string]any) []string {
+		mealPlan = utils.ParseMealPlanResponse(userId, mainGoalId, weeklyGoalId, goal.WeeklyGoals[0].StartDate, result)
+		return utils.FindMealPlanViolations(*user, mealPlan)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(violations) > 0 {
+		return nil, models.NewApiError(models.DIET_VIOLATION, "Generated meal plan does not follow diet preferences").WithDetails(map[string]any{"violations": violations})
+	}
+
+	for j := range mealPlan.DayMeals {
+		s.fillMealImages(ctx, mealPlan.DayMeals[j].Meals)
+	}
+	err = s.UnitOfWork.Do(ctx, func(ctx context.Context) error {
+		if err := s.UserGoalRepository.LockWeeklyGoal(ctx, mainGoalId, weeklyGoalId); err != nil {
+			return err
+		}
+		return s.MealRepository.CreateWeeklyMealPlan(ctx, &mealPlan)
+	})
+	if mongo.IsDuplicateKeyError(err) {
+		return nil, models.NewApiError(models.ALREADY_EXISTS, "Meal Plan is already created")
+	}
+	if err != nil {
+		return nil, toServiceError(err, models.NewApiError(models.GOAL_NOT_FOUND, "Goal not found"), "Could not save meal plan")
+	}
+	return &mealPlan, nil
+}
+
+// CustomizeDayMeal regenerates the meals of the day of the meal plan following the user prompt and returns the updated day meal.
+// The meal plan is the one of GetMealPlanMeta
+func (s *MealPlanService) CustomizeDayMeal(ctx context.Context, mealPlan *models.MealPlan, dayMealId primitive.ObjectID, userPrompt string) (*models.DayMeal, error) {
+	user, err := getCompleteUserProfile(ctx, s.UserRepository, mealPlan.UserId)
+	if err != nil {
+		return nil, err
+	}
+
+	goal, err := s.UserGoalRepository.GetUserWeeklyGoal(ctx, mealPlan.MainGoalId, mealPlan.WeeklyGoalId)
+	if err != nil {
+		return nil, models.NewApiError(models.GOAL_NOT_FOUND, "Goal not found")
+	}
+
+	dayMeal, err := s.MealRepository.GetSingleDayMeal(ctx, mealPlan.MainGoalId, mealPlan.WeeklyGoalId, dayMealId)
+	if err != nil {
+		return nil, models.NewApiError(models.MEAL_PLAN_NOT_FOUND, "Day Meal plan not found")
+	}
+
+	jsonBytes, err := json.Marshal(dayMeal)
+	if err != nil {
+		return nil, models.NewApiError(models.MEAL_PLAN_NOT_FOUND, "Day Meal plan not found")
+	}
+
+	prompt := config.GetSingleMealEditPrompt(*user, string(jsonBytes), userPrompt, float32(goal.WeeklyGoals[0].CurrentWeightInKg), float32(goal.WeeklyGoals[0].CurrentFatPercentage),
+		float32(goal.TargetWeightInKg), float32(goal.TargetFatPercentage), int32(goal.WeeklyGoals[0].TargetDailyCalories),
+		int32(goal.WeeklyGoals[0].TargetDailyMacrosFats), int32(goal.WeeklyGoals[0].TargetDailyMacrosCarbs), int32(goal.WeeklyGoals[0].TargetDailyMacrosProtein), string(goal.GoalType))
+
+	var dayMealNew models.DayMeal
+	violations, err := generateDietCompliantMeals(ctx, config.GetSingleMealModel(), prompt, func(result map[string]any) []string {
+		dayMealNew = utils.ParseSingleMealPlanResponse(result)
+		return utils.FindMealViolations(*user, dayMealNew.Meals)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(violations) > 0 {
+		return nil, models.NewApiError(models.DIET_VIOLATION, "Generated meals do not follow diet preferences").WithDetails(map[string]any{"violations": violations})
+	}
+
+	s.fillMealImages(ctx, dayMealNew.Meals)
+
+	err = s.MealRepository.UpdateSingleDayMeal(ctx, mealPlan.ID, dayMealId, dayMealNew.Meals)
+	if err != nil {
+		return nil, models.NewApiError(models.INTERNAL_ERROR, "Could not save meals").WithCause(err)
+	}
+
+	dayMeal.Meals = dayMealNew.Meals
+	return dayMeal, nil
+}
+
+func (s *MealPlanService) GetMealOwnerId(ctx context.Context, mealId primitive.ObjectID) (primitive.ObjectID, error) {
+	ownerId, err := s.MealRepository.GetMealOwnerId(ctx, mealId)
+	if err != nil {
+		return primitive.NilObjectID, toServiceError(err, models.NewApiError(models.MEAL_NOT_FOUND, "Meal not found"), "Could not get meal")
+	}
+	return ownerId, nil
+}
+
+// SetMealConsumed marks the meal consumed or not.
+// The meal plan and day meal ids are zero when the meal is addressed by its id alone
+func (s *MealPlanService) SetMealConsumed(ctx context.Context, mealPlanId primitive.ObjectID, dayMealId primitive.ObjectID, mealId primitive.ObjectID, isConsumed bool) error {
+	err := s.MealRepository.SetMealConsumed(ctx, mealPlanId, dayMealId, mealId, isConsumed)
+	if err != nil {
+		return toServiceError(err, models.NewApiError(models.MEAL_NOT_FOUND, "Meal not found"), "Could not update meal")
+	}
+	return nil
+}
+
+// GetNutritionReport compares the planned and consumed micronutrients of the weekly meal plan with the daily
+// reference intake of the user
+func (s *MealPlanService) GetNutritionReport(ctx context.Context, userId primitive.ObjectID, mainGoalId primitive.ObjectID, weeklyGoalId primitive.ObjectID) (*models.NutritionReport, error) {
+	user, err := getUserProfile(ctx, s.UserRepository, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	mealPlan, err := s.GetWeeklyMealPlan(ctx, userId, mainGoalId, weeklyGoalId)
+	if err != nil {
+		return nil, err
+	}
+
+	reference := utils.GetDailyReferenceIntake(user.Age, user.Sex)
+	report := models.NutritionReport{MealPlanId: mealPlan.ID.Hex()}
+
+	var weeklyPlanned, weeklyConsumed models.Micronutrients
+	for _, dayMeal := range mealPlan.DayMeals {
+		planned, consumed := utils.SumDayMicronutrients(dayMeal.Meals)
+		weeklyPlanned = weeklyPlanned.Add(planned)
+		weeklyConsumed = weeklyConsumed.Add(consumed)
+
+		report.Days = append(report.Days, models.DayNutrition{
+			Date:      dayMeal.Date,
+			Nutrients: utils.CompareWithReference(planned, consumed, reference),
+		})
+	}
+	report.Weekly = utils.CompareWithReference(weeklyPlanned, weeklyConsumed, reference.Scale(float64(len(mealPlan.DayMeals))))
+
+	return &report, nil
+}
+
+// fillMealImages sets the image of every meal
+func (s *MealPlanService) fillMealImages(ctx context.Context, meals []models.Meal) {
+	for i := range meals {
+		meals[i].ImageUrl = s.FindMealImage(ctx, meals[i].Name)
+	}
+}
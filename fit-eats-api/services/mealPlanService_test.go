@@ -0,0 +1,117 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"fit-eats-api/models"
+	"fit-eats-api/repositories"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// mealPlanFixture is a user with a goal running this week
+type mealPlanFixture struct {
+	service    *MealPlanService
+	userId     primitive.ObjectID
+	goal       *models.Goal
+	weeklyGoal *models.WeeklyGoal
+}
+
+func newMealPlanFixture(t *testing.T, completeProfile bool) mealPlanFixture {
+	t.Helper()
+	ctx := context.Background()
+	service := NewMealPlanService(repositories.NewInMemoryUserRepository(), repositories.NewInMemoryUserGoalRepository(), repositories.NewInMemoryMealRepository(), repositories.NewInMemoryUnitOfWork())
+	service.FindMealImage = func(ctx context.Context, mealName string) string { return "https://images.fiteats.test/" + mealName }
+	userId := createTestUser(t, service.UserRepository, completeProfile)
+
+	goal := newTestGoal(userId)
+	goal.ID, goal.Status = primitive.NewObjectID(), models.GOAL_ACTIVE
+	expectNoError(t, service.UserGoalRepository.CreateMainUserGoal(ctx, goal))
+	start := time.Now().AddDate(0, 0, -1)
+	weeklyGoal := &models.WeeklyGoal{StartDate: start, EndDate: start.AddDate(0, 0, 7), CurrentWeightInKg: 90}
+	expectNoError(t, service.UserGoalRepository.CreateWeeklyUserGoal(ctx, goal.ID, weeklyGoal))
+
+	return mealPlanFixture{service: service, userId: userId, goal: goal, weeklyGoal: weeklyGoal}
+}
+
+func (f mealPlanFixture) createMealPlan(t *testing.T) *models.MealPlan {
+	t.Helper()
+	mealPlan := &models.MealPlan{ID: primitive.NewObjectID(), UserId: f.userId, MainGoalId: f.goal.ID, WeeklyGoalId: f.weeklyGoal.ID}
+	for day := 0; day < 2; day++ {
+		mealPlan.DayMeals = append(mealPlan.DayMeals, models.DayMeal{
+			ID:    primitive.NewObjectID(),
+			Date:  f.weeklyGoal.StartDate.AddDate(0, 0, day),
+			Meals: []models.Meal{{ID: primitive.NewObjectID(), Name: "Oats", Nutrients: models.Micronutrients{Fibre: 8}}},
+		})
+	}
+	expectNoError(t, f.service.MealRepository.CreateWeeklyMealPlan(context.Background(), mealPlan))
+	return mealPlan
+}
+
+// Creating a meal plan fails before the model is asked when the rules are not met
+func TestMealPlanServiceCreateRules(t *testing.T) {
+	ctx := context.Background()
+	incomplete := newMealPlanFixture(t, false)
+	_, err := incomplete.service.CreateWeeklyMealPlan(ctx, incomplete.userId, incomplete.goal.ID, incomplete.weeklyGoal.ID, "")
+	expectErrorCode(t, err, models.PROFILE_INCOMPLETE)
+
+	fixture := newMealPlanFixture(t, true)
+	otherId := createTestUser(t, fixture.service.UserRepository, true)
+	_, err = fixture.service.CreateWeeklyMealPlan(ctx, otherId, fixture.goal.ID, fixture.weeklyGoal.ID, "")
+	expectErrorCode(t, err, models.GOAL_NOT_FOUND)
+	_, err = fixture.service.CreateWeeklyMealPlan(ctx, fixture.userId, fixture.goal.ID, primitive.NewObjectID(), "")
+	expectErrorCode(t, err, models.GOAL_NOT_FOUND)
+
+	fixture.createMealPlan(t)
+	_, err = fixture.service.CreateWeeklyMealPlan(ctx, fixture.userId, fixture.goal.ID, fixture.weeklyGoal.ID, "")
+	expectErrorCode(t, err, models.ALREADY_EXISTS)
+}
+
+func TestMealPlanServiceMeals(t *testing.T) {
+	ctx := context.Background()
+	fixture := newMealPlanFixture(t, true)
+
+	_, err := fixture.service.GetWeeklyMealPlan(ctx, fixture.userId, fixture.goal.ID, fixture.weeklyGoal.ID)
+	expectErrorCode(t, err, models.MEAL_PLAN_NOT_FOUND)
+	_, err = fixture.service.GetNutritionReport(ctx, fixture.userId, fixture.goal.ID, fixture.weeklyGoal.ID)
+	expectErrorCode(t, err, models.MEAL_PLAN_NOT_FOUND)
+
+	mealPlan := fixture.createMealPlan(t)
+	found, err := fixture.service.GetMealPlan(ctx, mealPlan.ID)
+	expectNoError(t, err)
+	if len(found.DayMeals) != 2 {
+		t.Errorf("expected the meal plan, got %+v", found)
+	}
+	_, err = fixture.service.GetMealPlan(ctx, primitive.NewObjectID())
+	expectErrorCode(t, err, models.MEAL_PLAN_NOT_FOUND)
+
+	mealId := mealPlan.DayMeals[1].Meals[0].ID
+	ownerId, err := fixture.service.GetMealOwnerId(ctx, mealId)
+	expectNoError(t, err)
+	if ownerId != fixture.userId {
+		t.Errorf("expected owner %s, got %s", fixture.userId.Hex(), ownerId.Hex())
+	}
+	_, err = fixture.service.GetMealOwnerId(ctx, primitive.NewObjectID())
+	expectErrorCode(t, err, models.MEAL_NOT_FOUND)
+
+	expectNoError(t, fixture.service.SetMealConsumed(ctx, mealPlan.ID, mealPlan.DayMeals[1].ID, mealId, true))
+	expectErrorCode(t, fixture.service.SetMealConsumed(ctx, mealPlan.ID, mealPlan.DayMeals[0].ID, mealId, true), models.MEAL_NOT_FOUND)
+
+	report, err := fixture.service.GetNutritionReport(ctx, fixture.userId, fixture.goal.ID, fixture.weeklyGoal.ID)
+	expectNoError(t, err)
+	if report.MealPlanId != mealPlan.ID.Hex() || len(report.Days) != 2 {
+		t.Errorf("expected a report of both days, got %+v", report)
+	}
+}
+
+func TestMealPlanServiceMealImages(t *testing.T) {
+	fixture := newMealPlanFixture(t, true)
+	meals := []models.Meal{{Name: "Poha"}, {Name: "Dal"}}
+
+	fixture.service.fillMealImages(context.Background(), meals)
+	if meals[0].ImageUrl != "https://images.fiteats.test/Poha" || meals[1].ImageUrl != "https://images.fiteats.test/Dal" {
+		t.Errorf("expected the image of every meal, got %+v", meals)
+	}
+}
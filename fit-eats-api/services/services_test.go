@@ -0,0 +1,41 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"fit-eats-api/models"
+	"fit-eats-api/repositories"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func expectErrorCode(t *testing.T, err error, code models.ErrorCode) {
+	t.Helper()
+	var apiError *models.ApiError
+	if !errors.As(err, &apiError) {
+		t.Fatalf("expected error code %s, got %v", code, err)
+	}
+	if apiError.Code != code {
+		t.Fatalf("expected error code %s, got %s", code, apiError.Code)
+	}
+}
+
+func expectNoError(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// createTestUser creates a user, with a complete profile unless complete is false
+func createTestUser(t *testing.T, users repositories.UserRepository, complete bool) primitive.ObjectID {
+	t.Helper()
+	user := &models.User{Name: "Asha", Email: primitive.NewObjectID().Hex() + "@fiteats.test"}
+	if complete {
+		user.HeightInCm, user.Age, user.Sex, user.Country = 165, "31", "female", "India"
+	}
+	expectNoError(t, users.CreateUser(context.Background(), user))
+	return user.ID
+}
@@ -0,0 +1,115 @@
+package services
+
+import (
+	"context"
+	"fit-eats-api/models"
+	"fit-eats-api/repositories"
+	"fit-eats-api/utils"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// UserService holds the profile rules, the estimates and plans need a complete profile
+type UserService struct {
+	UserRepository repositories.UserRepository
+}
+
+func NewUserService(userRepository repositories.UserRepository) *UserService {
+	return &UserService{UserRepository: userRepository}
+}
+
+func (s *UserService) GetProfile(ctx context.Context, userId primitive.ObjectID) (*models.User, error) {
+	return getUserProfile(ctx, s.UserRepository, userId)
+}
+
+func (s *UserService) GetProfileByEmail(ctx context.Context, emailId string) (*models.User, error) {
+	user, err := s.UserRepository.GetUserProfileByEmailId(ctx, emailId)
+	if err != nil {
+		return nil, toServiceError(err, models.NewApiError(models.USER_NOT_FOUND, "User not found"), "Could not get user")
+	}
+	return user, nil
+}
+
+// GetCompleteProfile fails with PROFILE_INCOMPLETE until the height, age and sex of the user are set
+func (s *UserService) GetCompleteProfile(ctx context.Context, userId primitive.ObjectID) (*models.User, error) {
+	return getCompleteUserProfile(ctx, s.UserRepository, userId)
+}
+
+// UpdateProfile stores the profile fields set in user, zero values are left unchanged.
+// An empty fasting window clears the fasting window
+func (s *UserService) UpdateProfile(ctx context.Context, user models.User) error {
+	errors := utils.ValidateDietSettings(user)
+	if errors != nil {
+		return models.NewValidationError(errors)
+	}
+	if user.EatBackPercentage != nil && (*user.EatBackPercentage < 0 || *user.EatBackPercentage > 100) {
+		return models.NewValidationError(map[string]string{"eatbackpercentage": "eatbackpercentage must be between 0 and 100"})
+	}
+
+	update := bson.M{}
+
+	if user.Name != "" {
+		update["name"] = user.Name
+	}
+	if user.Age != "" {
+		update["age"] = user.Age
+	}
+	if user.Sex != "" {
+		update["sex"] = user.Sex
+	}
+	if user.HeightInCm != 0 {
+		update["heightInCm"] = user.HeightInCm
+	}
+	if user.Country != "" {
+		update["country"] = user.Country
+	}
+	if user.DietPreference != "" {
+		update["dietPreference"] = user.DietPreference
+	}
+	if user.CuisinePreferences != nil {
+		update["cuisinePreferences"] = user.CuisinePreferences
+	}
+	if user.MealsPerDay != 0 {
+		update["mealsPerDay"] = user.MealsPerDay
+	}
+	if user.EatBackPercentage != nil {
+		update["eatBackPercentage"] = *user.EatBackPercentage
+	}
+	if user.FastingWindow != nil {
+		if user.FastingWindow.StartTime == "" && user.FastingWindow.EndTime == "" {
+			update["fastingWindow"] = nil
+		} else {
+			update["fastingWindow"] = user.FastingWindow
+		}
+	}
+
+	if len(update) == 0 {
+		return models.NewApiError(models.INVALID_REQUEST, "Nothing to update")
+	}
+
+	err := s.UserRepository.UpdateUser(ctx, user.ID, update)
+	if err != nil {
+		return models.NewApiError(models.INTERNAL_ERROR, "Could not update user").WithCause(err)
+	}
+	return nil
+}
+
+func getUserProfile(ctx context.Context, userRepository repositories.UserRepository, userId primitive.ObjectID) (*models.User, error) {
+	user, err := userRepository.GetUserProfileById(ctx, userId)
+	if err != nil {
+		return nil, toServiceError(err, models.NewApiError(models.USER_NOT_FOUND, "User not found"), "Could not get user")
+	}
+	return user, nil
+}
+
+func getCompleteUserProfile(ctx context.Context, userRepository repositories.UserRepository, userId primitive.ObjectID) (*models.User, error) {
+	user, err := getUserProfile(ctx, userRepository, userId)
+	if err != nil {
+		return nil, err
+	}
+	if !user.IsProfileComplete() {
+		return nil, models.NewApiError(models.PROFILE_INCOMPLETE, "Profile incomplete")
+	}
+	return user, nil
+}
@@ -0,0 +1,51 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"fit-eats-api/models"
+	"fit-eats-api/repositories"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestUserServiceProfile(t *testing.T) {
+	ctx := context.Background()
+	service := NewUserService(repositories.NewInMemoryUserRepository())
+	incompleteId := createTestUser(t, service.UserRepository, false)
+
+	_, err := service.GetProfile(ctx, primitive.NewObjectID())
+	expectErrorCode(t, err, models.USER_NOT_FOUND)
+	_, err = service.GetCompleteProfile(ctx, incompleteId)
+	expectErrorCode(t, err, models.PROFILE_INCOMPLETE)
+
+	height := models.User{ID: incompleteId, HeightInCm: 170, Age: "40", Sex: "male", Country: "India"}
+	expectNoError(t, service.UpdateProfile(ctx, height))
+	user, err := service.GetCompleteProfile(ctx, incompleteId)
+	expectNoError(t, err)
+	if user.HeightInCm != 170 || user.Name != "Asha" {
+		t.Errorf("expected only the set fields updated, got %+v", user)
+	}
+}
+
+func TestUserServiceUpdateProfileRules(t *testing.T) {
+	ctx := context.Background()
+	service := NewUserService(repositories.NewInMemoryUserRepository())
+	userId := createTestUser(t, service.UserRepository, true)
+
+	expectErrorCode(t, service.UpdateProfile(ctx, models.User{ID: userId}), models.INVALID_REQUEST)
+	expectErrorCode(t, service.UpdateProfile(ctx, models.User{ID: userId, MealsPerDay: models.MAX_MEALS_PER_DAY + 1}), models.VALIDATION_FAILED)
+	expectNoError(t, service.UpdateProfile(ctx, models.User{ID: userId, MealsPerDay: models.MAX_MEALS_PER_DAY}))
+	eatBack := 120
+	expectErrorCode(t, service.UpdateProfile(ctx, models.User{ID: userId, EatBackPercentage: &eatBack}), models.VALIDATION_FAILED)
+
+	// An empty fasting window clears the fasting window
+	expectNoError(t, service.UpdateProfile(ctx, models.User{ID: userId, FastingWindow: &models.FastingWindow{StartTime: "8:00 pm", EndTime: "12:00 pm"}}))
+	expectNoError(t, service.UpdateProfile(ctx, models.User{ID: userId, FastingWindow: &models.FastingWindow{}}))
+	user, err := service.GetProfile(ctx, userId)
+	expectNoError(t, err)
+	if user.FastingWindow != nil {
+		t.Errorf("expected the fasting window cleared, got %+v", user.FastingWindow)
+	}
+}
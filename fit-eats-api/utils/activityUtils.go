@@ -0,0 +1,138 @@
+package utils
+
+import (
+	"fit-eats-api/models"
+	"math"
+	"time"
+)
+
+// Used when there is no weekly goal to read the current weight from
+const defaultBodyWeightInKg = 70
+
+// Rough time one set takes including rest, used to weigh set based exercises against timed ones
+const minutesPerSet = 3
+
+// Walking cadence used to turn a step count into a duration
+const stepsPerMinute = 100
+
+// Metabolic equivalents from the Compendium of Physical Activities
+var activityMets = map[models.ActivityType]float64{
+	models.WALKING:       3.5,
+	models.BRISK_WALKING: 4.3,
+	models.RUNNING:       9.8,
+	models.CYCLING:       7.5,
+	models.SWIMMING:      7,
+	models.HIKING:        6,
+	models.YOGA:          2.5,
+	models.DANCING:       5,
+	models.SPORTS:        7,
+}
+
+func IsValidActivityType(activityType models.ActivityType) bool {
+	_, ok := activityMets[activityType]
+	return ok
+}
+
+// getStrengthTrainingMet scales the met of resistance training with the perceived exertion of the session
+func getStrengthTrainingMet(rpe int) float64 {
+	switch {
+	case rpe == 0:
+		return 5
+	case rpe <= 4:
+		return 3.5
+	case rpe <= 7:
+		return 5
+	default:
+		return 6
+	}
+}
+
+// getCardioMet scales the met of cardio work with the perceived exertion of the session
+func getCardioMet(rpe int) float64 {
+	switch {
+	case rpe == 0:
+		return 7
+	case rpe <= 4:
+		return 5
+	case rpe <= 7:
+		return 7
+	default:
+		return 9.5
+	}
+}
+
+const mobilityMet = 2.5
+
+func getExerciseMet(category models.ExerciseCategory, rpe int) float64 {
+	switch category {
+	case models.CARDIO:
+		return getCardioMet(rpe)
+	case models.MOBILITY:
+		return mobilityMet
+	default:
+		return getStrengthTrainingMet(rpe)
+	}
+}
+
+// getWorkoutSessionMet averages the met of the logged exercises weighted by the time spent on each,
+// timed exercises count their minutes and set based ones a fixed time per set
+func getWorkoutSessionMet(session models.WorkoutSession) float64 {
+	totalMet, totalWeight := 0.0, 0.0
+	for _, loggedExercise := range session.Exercises {
+		exercise, ok := models.GetExerciseById(loggedExercise.ExerciseId)
+		if !ok {
+			continue
+		}
+		weight := float64(loggedExercise.DurationMinutes)
+		if weight == 0 {
+			weight = float64(max(len(loggedExercise.Sets), 1) * minutesPerSet)
+		}
+		totalMet += getExerciseMet(exercise.Category, session.RPE) * weight
+		totalWeight += weight
+	}
+	if totalWeight == 0 {
+		return getStrengthTrainingMet(session.RPE)
+	}
+	return totalMet / totalWeight
+}
+
+// estimateCalories returns the energy spent above resting, since resting energy is already part of the tdee
+func estimateCalories(met float64, weightInKg float64, durationMinutes float64) float64 {
+	if weightInKg <= 0 {
+		weightInKg = defaultBodyWeightInKg
+	}
+	return math.Round(math.Max(0, met-1) * weightInKg * durationMinutes / 60)
+}
+
+func EstimateWorkoutSessionCalories(session models.WorkoutSession, weightInKg float64) float64 {
+	return estimateCalories(getWorkoutSessionMet(session), weightInKg, float64(session.DurationMinutes))
+}
+
+func EstimateActivityCalories(activity models.ActivityLog, weightInKg float64) float64 {
+	durationMinutes := float64(activity.DurationMinutes)
+	if durationMinutes == 0 {
+		durationMinutes = float64(activity.Steps) / stepsPerMinute
+	}
+	return estimateCalories(activityMets[activity.ActivityType], weightInKg, durationMinutes)
+}
+
+// GetActivitySummary totals the calories burned on a day and how many of them can be eaten back
+func GetActivitySummary(day time.Time, sessions []models.WorkoutSession, activityLogs []models.ActivityLog, eatBackPercentage int) models.ActivitySummary {
+	burned := 0.0
+	for _, session := range sessions {
+		burned += session.EstimatedCalories
+	}
+	for _, activity := range activityLogs {
+		burned += activity.EstimatedCalories
+	}
+
+	startOfDay, _ := GetDayRange(day)
+	return models.ActivitySummary{
+		Date:              startOfDay,
+		WorkoutSessions:   sessions,
+		ActivityLogs:      activityLogs,
+		BurnedCalories:    burned,
+		EatBackPercentage: eatBackPercentage,
+		EatBackCalories:   math.Round(burned * float64(eatBackPercentage) / 100),
+	}
+}
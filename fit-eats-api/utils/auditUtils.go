@@ -0,0 +1,80 @@
+package utils
+
+import (
+	"encoding/json"
+	"fit-eats-api/models"
+	"reflect"
+	"sort"
+	"strconv"
+)
+
+// DiffSnapshots lists the fields that differ between two states of a document.
+// Both sides go through their json form, so fields hidden from api responses never end up in the audit log.
+func DiffSnapshots(before any, after any) []models.AuditChange {
+	changes := []models.AuditChange{}
+	diffValues("", toJsonValue(before), toJsonValue(after), &changes)
+	return changes
+}
+
+func toJsonValue(snapshot any) any {
+	if snapshot == nil || (reflect.ValueOf(snapshot).Kind() == reflect.Pointer && reflect.ValueOf(snapshot).IsNil()) {
+		return nil
+	}
+	bytes, err := json.Marshal(snapshot)
+	if err != nil {
+		return nil
+	}
+	var value any
+	if err := json.Unmarshal(bytes, &value); err != nil {
+		return nil
+	}
+	return value
+}
+
+func diffValues(path string, before any, after any, changes *[]models.AuditChange) {
+	beforeMap, beforeIsMap := before.(map[string]any)
+	afterMap, afterIsMap := after.(map[string]any)
+	if beforeIsMap && afterIsMap {
+		keys := []string{}
+		for key := range beforeMap {
+			keys = append(keys, key)
+		}
+		for key := range afterMap {
+			if _, ok := beforeMap[key]; !ok {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			diffValues(joinAuditPath(path, key), beforeMap[key], afterMap[key], changes)
+		}
+		return
+	}
+
+	beforeSlice, beforeIsSlice := before.([]any)
+	afterSlice, afterIsSlice := after.([]any)
+	if beforeIsSlice && afterIsSlice {
+		for i := 0; i < max(len(beforeSlice), len(afterSlice)); i++ {
+			var beforeItem, afterItem any
+			if i < len(beforeSlice) {
+				beforeItem = beforeSlice[i]
+			}
+			if i < len(afterSlice) {
+				afterItem = afterSlice[i]
+			}
+			diffValues(joinAuditPath(path, strconv.Itoa(i)), beforeItem, afterItem, changes)
+		}
+		return
+	}
+
+	if !reflect.DeepEqual(before, after) {
+		*changes = append(*changes, models.AuditChange{Path: path, Before: before, After: after})
+	}
+}
+
+func joinAuditPath(path string, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
@@ -0,0 +1,91 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+
+	"fit-eats-api/models"
+)
+
+type auditTestMeal struct {
+	Name     string  `json:"name"`
+	Calories float64 `json:"calories"`
+}
+
+type auditTestDay struct {
+	Day    string          `json:"day"`
+	Notes  string          `json:"notes,omitempty"`
+	Secret string          `json:"-"`
+	Meals  []auditTestMeal `json:"meals"`
+}
+
+func TestDiffSnapshotsPaths(t *testing.T) {
+	before := auditTestDay{
+		Day:    "2026-10-19",
+		Secret: "hidden before",
+		Meals:  []auditTestMeal{{Name: "Oats", Calories: 300}, {Name: "Rice", Calories: 500}},
+	}
+	after := auditTestDay{
+		Day:    "2026-10-19",
+		Notes:  "Cheat day",
+		Secret: "hidden after",
+		Meals:  []auditTestMeal{{Name: "Oats", Calories: 350}, {Name: "Rice", Calories: 500}, {Name: "Cake", Calories: 400}},
+	}
+
+	changes := DiffSnapshots(&before, &after)
+
+	expected := []models.AuditChange{
+		{Path: "meals.0.calories", Before: 300.0, After: 350.0},
+		{Path: "meals.2", Before: nil, After: map[string]any{"name": "Cake", "calories": 400.0}},
+		{Path: "notes", Before: nil, After: "Cheat day"},
+	}
+	if !reflect.DeepEqual(changes, expected) {
+		t.Fatalf("expected %v, got %v", expected, changes)
+	}
+}
+
+func TestDiffSnapshotsRemovedItems(t *testing.T) {
+	before := map[string]any{"tags": []string{"vegan", "quick"}, "servings": 2}
+	after := map[string]any{"tags": []string{"vegan"}}
+
+	changes := DiffSnapshots(before, after)
+
+	expected := []models.AuditChange{
+		{Path: "servings", Before: 2.0, After: nil},
+		{Path: "tags.1", Before: "quick", After: nil},
+	}
+	if !reflect.DeepEqual(changes, expected) {
+		t.Fatalf("expected %v, got %v", expected, changes)
+	}
+}
+
+func TestDiffSnapshotsCreatedAndDeleted(t *testing.T) {
+	meal := auditTestMeal{Name: "Oats", Calories: 300}
+	value := map[string]any{"name": "Oats", "calories": 300.0}
+
+	// A nil pointer is missing data like a nil interface, as returned by the snapshot of a deleted log
+	var missing *auditTestMeal
+
+	created := DiffSnapshots(missing, meal)
+	if !reflect.DeepEqual(created, []models.AuditChange{{Path: "", Before: nil, After: value}}) {
+		t.Fatalf("expected the created meal, got %v", created)
+	}
+
+	deleted := DiffSnapshots(&meal, nil)
+	if !reflect.DeepEqual(deleted, []models.AuditChange{{Path: "", Before: value, After: nil}}) {
+		t.Fatalf("expected the deleted meal, got %v", deleted)
+	}
+}
+
+func TestDiffSnapshotsUnchanged(t *testing.T) {
+	meal := auditTestMeal{Name: "Oats", Calories: 300}
+
+	changes := DiffSnapshots(meal, &meal)
+	if changes == nil || len(changes) != 0 {
+		t.Fatalf("expected no changes, got %v", changes)
+	}
+
+	if changes := DiffSnapshots(nil, nil); len(changes) != 0 {
+		t.Fatalf("expected no changes between two missing snapshots, got %v", changes)
+	}
+}
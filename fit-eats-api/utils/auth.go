@@ -1,72 +1,150 @@
 package utils
 
 import (
-	"log"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"golang.org/x/crypto/bcrypt"
 
 	"fit-eats-api/config"
 	"fit-eats-api/models"
 )
 
-func GenerateAccessJwt(user *models.User) (string, error) {
+const AccessTokenValidity = time.Hour * 24
+const RefreshTokenValidity = time.Hour * 24 * 7 //1 week validity
+const MfaPendingTokenValidity = time.Minute * 5
+
+// Type claim of the token handed out between the password and the second factor
+const mfaPendingTokenType = "mfa_pending"
+
+// TokenClaims are the claims shared by access and refresh tokens
+type TokenClaims struct {
+	UserId    primitive.ObjectID
+	Email     string
+	SessionId primitive.ObjectID
+	Role      models.Role
+}
+
+func GenerateAccessJwt(user *models.User, sessionId primitive.ObjectID) (string, error) {
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub":   user.ID.Hex(),
 		"email": user.Email,
-		"exp":   time.Now().Add(time.Hour * 24).Unix(),
+		"sid":   sessionId.Hex(),
+		"role":  user.GetRole(),
+		"exp":   time.Now().Add(AccessTokenValidity).Unix(),
 	})
 	secret := config.GetConfig().JWTAccessSecret
 	tokenString, err := token.SignedString([]byte(secret))
 	return tokenString, err
 }
 
-func IsAccessTokenValid(tokenString string) bool {
-	secret := config.GetConfig().JWTAccessSecret
+func ParseAccessToken(tokenString string) (*TokenClaims, error) {
+	return parseToken(tokenString, config.GetConfig().JWTAccessSecret)
+}
 
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		return []byte(secret), nil
+// GenerateRefreshJwt adds a random jti so that every rotation yields a distinct token and hash
+func GenerateRefreshJwt(user *models.User, sessionId primitive.ObjectID) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub":   user.ID.Hex(),
+		"email": user.Email,
+		"sid":   sessionId.Hex(),
+		"jti":   GenerateRandomToken(16),
+		"exp":   time.Now().Add(RefreshTokenValidity).Unix(),
 	})
+	secret := config.GetConfig().JWTRefreshSecret
+	tokenString, err := token.SignedString([]byte(secret))
+	return tokenString, err
+}
+
+func ParseRefreshToken(tokenString string) (*TokenClaims, error) {
+	return parseToken(tokenString, config.GetConfig().JWTRefreshSecret)
+}
 
+func parseToken(tokenString string, secret string) (*TokenClaims, error) {
+	claims := jwt.MapClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return []byte(secret), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
 	if err != nil {
-		log.Printf("Error parsing token: %v", err)
-		return false
+		return nil, err
 	}
-
 	if !token.Valid {
-		log.Printf("Error parsing token: invalid")
-		return false
+		return nil, errors.New("invalid token")
+	}
+
+	// Only access and refresh tokens are accepted here, they carry no type
+	if _, ok := claims["typ"]; ok {
+		return nil, errors.New("unexpected token type")
+	}
+
+	// Tokens issued before sessions existed carry no subject or session and are rejected
+	userId, _ := claims["sub"].(string)
+	sessionId, _ := claims["sid"].(string)
+	email, _ := claims["email"].(string)
+	role, _ := claims["role"].(string)
+
+	mongoUserId, err := primitive.ObjectIDFromHex(userId)
+	if err != nil {
+		return nil, errors.New("invalid token subject")
+	}
+	mongoSessionId, err := primitive.ObjectIDFromHex(sessionId)
+	if err != nil {
+		return nil, errors.New("invalid token session")
 	}
-	return true
+
+	// Refresh tokens carry no role, the role is read from the user whenever an access token is issued
+	return &TokenClaims{UserId: mongoUserId, Email: email, SessionId: mongoSessionId, Role: models.Role(role)}, nil
 }
 
-func GenerateRefreshJwt(user *models.User) (string, error) {
+// GenerateMfaPendingJwt is issued after a correct password when a second factor is still needed,
+// it cannot be used as an access token since it has no session
+func GenerateMfaPendingJwt(user *models.User) (string, error) {
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"email": user.Email,
-		"exp":   time.Now().Add(time.Hour * 24 * 7).Unix(), //1 week validity
+		"sub": user.ID.Hex(),
+		"typ": mfaPendingTokenType,
+		"exp": time.Now().Add(MfaPendingTokenValidity).Unix(),
 	})
-	secret := config.GetConfig().JWTRefreshSecret
+	secret := config.GetConfig().JWTAccessSecret
 	tokenString, err := token.SignedString([]byte(secret))
 	return tokenString, err
 }
 
-func IsRefreshTokenValid(tokenString string) bool {
-	secret := config.GetConfig().JWTRefreshSecret
-
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+// ParseMfaPendingToken returns the id of the user who passed the password step
+func ParseMfaPendingToken(tokenString string) (primitive.ObjectID, error) {
+	secret := config.GetConfig().JWTAccessSecret
+	claims := jwt.MapClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
 		return []byte(secret), nil
-	})
-
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
 	if err != nil {
-		log.Printf("Error parsing token: %v", err)
-		return false
+		return primitive.NilObjectID, err
+	}
+	if !token.Valid || claims["typ"] != mfaPendingTokenType {
+		return primitive.NilObjectID, errors.New("invalid mfa token")
 	}
 
-	if !token.Valid {
-		log.Printf("Error parsing token: invalid")
-		return false
+	userId, _ := claims["sub"].(string)
+	return primitive.ObjectIDFromHex(userId)
+}
+
+// HashToken is used for opaque tokens stored in the database, they are random enough that a salt is not needed
+func HashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+// GenerateRandomToken returns a hex encoded token of the given number of random bytes
+func GenerateRandomToken(size int) string {
+	bytes := make([]byte, size)
+	if _, err := rand.Read(bytes); err != nil {
+		panic("crypto/rand failed: " + err.Error())
 	}
-	return true
+	return hex.EncodeToString(bytes)
 }
 
 func IsPasswordCorrect(hashedPassword string, plainTextPassword string) bool {
@@ -0,0 +1,91 @@
+package utils
+
+import (
+	"fit-eats-api/models"
+	"math"
+	"time"
+)
+
+// COACH_OVERVIEW_DAYS is how many days of meals, including today, the adherence of a client is computed over
+const COACH_OVERVIEW_DAYS = 7
+
+// GetClientOverview summarises a client for the coach overview using the same per-day totals as the dashboard
+func GetClientOverview(record models.ClientOverviewRecord, now time.Time) models.ClientOverview {
+	overview := models.ClientOverview{
+		CoachLinkId:    record.CoachLinkId,
+		ClientId:       record.Client.ID,
+		Name:           record.Client.Name,
+		Email:          record.Client.Email,
+		DailyAdherence: []models.DayAdherence{},
+		Alerts:         []models.CoachAlert{},
+	}
+
+	for _, dayMeal := range record.RecentDayMeals {
+		planned, consumed := SumDayMeals(dayMeal.Meals)
+		overview.DailyAdherence = append(overview.DailyAdherence, models.DayAdherence{Date: dayMeal.Date, Planned: planned, Consumed: consumed})
+		overview.PlannedCalories += planned.Calories
+		overview.ConsumedCalories += consumed.Calories
+	}
+	if overview.PlannedCalories > 0 {
+		adherence := float64(overview.ConsumedCalories) / float64(overview.PlannedCalories)
+		overview.Adherence = &adherence
+	}
+
+	if record.Goal == nil {
+		overview.Alerts = append(overview.Alerts, models.ALERT_NO_GOAL)
+		return overview
+	}
+	overview.GoalType = record.Goal.GoalType
+	overview.TargetWeightInKg = record.Goal.TargetWeightInKg
+	overview.CurrentWeightInKg = record.Goal.StartWeightInKg
+
+	latest := record.LatestWeeklyGoal
+	if latest == nil {
+		overview.WeightToGoalInKg = math.Abs(overview.CurrentWeightInKg - overview.TargetWeightInKg)
+		overview.Alerts = append(overview.Alerts, models.ALERT_NO_WEEKLY_GOAL)
+		return overview
+	}
+	overview.CurrentWeightInKg = latest.CurrentWeightInKg
+	overview.WeightToGoalInKg = math.Abs(overview.CurrentWeightInKg - overview.TargetWeightInKg)
+
+	// The weekly goal id is generated when the weekly goal is created
+	daysSinceWeeklyGoal := int(now.Sub(latest.ID.Timestamp()).Hours() / 24)
+	overview.DaysSinceWeeklyGoal = &daysSinceWeeklyGoal
+
+	if now.After(latest.EndDate) {
+		overview.Alerts = append(overview.Alerts, models.ALERT_NO_WEEKLY_GOAL)
+	} else if !record.HasCurrentMealPlan {
+		overview.Alerts = append(overview.Alerts, models.ALERT_NO_MEAL_PLAN)
+	}
+
+	if record.PreviousWeeklyGoal != nil && isWeightOffTrend(*record.Goal, record.PreviousWeeklyGoal.CurrentWeightInKg, latest.CurrentWeightInKg) {
+		overview.Alerts = append(overview.Alerts, models.ALERT_WEIGHT_OFF_TREND)
+	}
+
+	return overview
+}
+
+// isWeightOffTrend checks if the weight between two weekly check ins moved against the goal. Goals keeping the weight
+// are off trend when the weight moves further out of their range, event goals follow the direction of their target
+func isWeightOffTrend(goal models.Goal, previousWeight float64, latestWeight float64) bool {
+	rule, ok := models.GoalTypeRules[goal.GoalType]
+	if !ok {
+		return false
+	}
+
+	direction := rule.WeightDirection
+	if rule.HasEventDate && goal.TargetWeightInKg < goal.StartWeightInKg {
+		direction = -1
+	} else if rule.HasEventDate && goal.TargetWeightInKg > goal.StartWeightInKg {
+		direction = 1
+	}
+
+	switch {
+	case direction < 0:
+		return latestWeight > previousWeight
+	case direction > 0:
+		return latestWeight < previousWeight
+	}
+	latestDistance := math.Abs(latestWeight - goal.TargetWeightInKg)
+	return latestDistance > rule.MaxWeightDifferenceInKg && latestDistance > math.Abs(previousWeight-goal.TargetWeightInKg)
+}
@@ -0,0 +1,40 @@
+package utils
+
+import (
+	"fmt"
+	"time"
+)
+
+// DAY_TIME_ZONE is the time zone of the days, the same as today's meals in the meal repository
+const DAY_TIME_ZONE = "Asia/Kolkata"
+
+// dayLocation falls back to UTC when the time zone cannot be loaded, the server checks CheckDayLocation on startup
+var dayLocation, dayLocationErr = loadDayLocation()
+
+func loadDayLocation() (*time.Location, error) {
+	loc, err := time.LoadLocation(DAY_TIME_ZONE)
+	if err != nil {
+		return time.UTC, fmt.Errorf("could not load the %s time zone: %w", DAY_TIME_ZONE, err)
+	}
+	return loc, nil
+}
+
+// CheckDayLocation returns the error of loading the time zone of the days, the days would be wrong without it
+func CheckDayLocation() error {
+	return dayLocationErr
+}
+
+// GetDayRange returns the start of the given day and the start of the next day
+func GetDayRange(day time.Time) (time.Time, time.Time) {
+	day = day.In(dayLocation)
+	startOfDay := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, dayLocation)
+	return startOfDay, startOfDay.Add(24 * time.Hour)
+}
+
+// ParseDay parses a yyyy-mm-dd date, an empty value is today
+func ParseDay(value string) (time.Time, error) {
+	if value == "" {
+		return time.Now().In(dayLocation), nil
+	}
+	return time.ParseInLocation("2006-01-02", value, dayLocation)
+}
@@ -0,0 +1,172 @@
+package utils
+
+import (
+	"fit-eats-api/models"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Keywords used to place an ingredient name in a category, matched on whole words (plural forms included)
+var ingredientCategoryKeywords = map[models.IngredientCategory][]string{
+	models.RED_MEAT:       {"beef", "mutton", "lamb", "goat", "veal", "venison", "bison", "steak", "meat", "keema"},
+	models.PORK:           {"pork", "bacon", "ham", "lard", "salami", "pepperoni", "prosciutto", "chorizo"},
+	models.POULTRY:        {"chicken", "turkey", "duck", "goose", "quail"},
+	models.FISH:           {"fish", "salmon", "tuna", "cod", "tilapia", "sardine", "mackerel", "trout", "anchovy", "pomfret", "basa", "rohu", "hilsa", "surimi"},
+	models.SEAFOOD:        {"shrimp", "prawn", "crab", "lobster", "squid", "octopus", "clam", "mussel", "oyster", "scallop"},
+	models.EGG:            {"egg", "egg white", "egg yolk", "omelette", "mayonnaise"},
+	models.DAIRY:          {"milk", "cheese", "paneer", "butter", "ghee", "yogurt", "yoghurt", "curd", "cream", "whey", "casein", "khoya", "buttermilk"},
+	models.HONEY:          {"honey"},
+	models.GELATIN:        {"gelatin", "gelatine"},
+	models.ALCOHOL:        {"wine", "beer", "rum", "vodka", "whisky", "whiskey", "brandy", "sake", "mirin", "liqueur"},
+	models.ROOT_VEGETABLE: {"onion", "garlic", "potato", "carrot", "ginger", "beetroot", "beet", "radish", "turnip", "yam", "shallot", "leek", "scallion", "cassava", "arbi"},
+	models.GRAIN:          {"rice", "wheat", "oat", "flour", "bread", "pasta", "noodle", "quinoa", "barley", "millet", "corn", "maize", "roti", "chapati", "tortilla", "couscous", "semolina", "poha", "cereal", "granola"},
+	models.ADDED_SUGAR:    {"sugar", "jaggery", "syrup", "agave", "candy"},
+	models.STARCHY_FOOD:   {"potato", "yam", "cassava", "tapioca", "sago", "banana"},
+	models.LEGUME:         {"lentil", "dal", "dhal", "chickpea", "bean", "rajma", "chana", "moong", "pea"},
+}
+
+// Ingredient names that contain a keyword but do not belong to its category, removed before matching
+var ingredientKeywordExceptions = []string{
+	"coconut milk", "almond milk", "soy milk", "oat milk", "rice milk", "cashew milk",
+	"coconut cream", "peanut butter", "almond butter", "cashew butter", "cocoa butter",
+	"vegan cheese", "vegan butter", "cream of tartar", "green bean", "coffee bean", "vanilla bean",
+	"sugar free", "cauliflower rice",
+}
+
+var mealTimeLayouts = []string{"3:04 pm", "3:04pm", "3 pm", "3pm"}
+
+// ParseMealTime parses a time in am/pm format for eg. 6:30 pm and returns the minutes since midnight
+func ParseMealTime(value string) (int, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	for _, layout := range mealTimeLayouts {
+		parsed, err := time.Parse(layout, value)
+		if err == nil {
+			return parsed.Hour()*60 + parsed.Minute(), nil
+		}
+	}
+	return 0, fmt.Errorf("invalid time %q: must be in am/pm format for eg. 6:30 pm", value)
+}
+
+// ValidateDietSettings checks the diet related fields of a profile update and returns errors keyed by field
+func ValidateDietSettings(user models.User) map[string]string {
+	errors := make(map[string]string)
+
+	if user.DietPreference != "" && !models.IsValidDietPattern(user.DietPreference) {
+		errors["dietpreference"] = "Invalid diet preference"
+	}
+	for _, cuisine := range user.CuisinePreferences {
+		if !models.IsValidCuisine(cuisine) {
+			errors["cuisinepreferences"] = "Invalid cuisine: " + cuisine
+			break
+		}
+	}
+	if user.MealsPerDay < 0 || user.MealsPerDay > models.MAX_MEALS_PER_DAY {
+		errors["mealsperday"] = fmt.Sprintf("mealsperday must be between 1 and %d, or 0 to leave it unset", models.MAX_MEALS_PER_DAY)
+	}
+	if user.FastingWindow != nil && (user.FastingWindow.StartTime != "" || user.FastingWindow.EndTime != "") {
+		_, startErr := ParseMealTime(user.FastingWindow.StartTime)
+		_, endErr := ParseMealTime(user.FastingWindow.EndTime)
+		if startErr != nil || endErr != nil {
+			errors["fastingwindow"] = "fastingwindow times must be in am/pm format for eg. 8:00 pm"
+		}
+	}
+
+	if len(errors) == 0 {
+		return nil
+	}
+	return errors
+}
+
+// GetIngredientCategories returns every category an ingredient name falls into
+func GetIngredientCategories(ingredientName string) []models.IngredientCategory {
+	name := " " + normalizeIngredientName(ingredientName) + " "
+	for _, exception := range ingredientKeywordExceptions {
+		name = strings.ReplaceAll(name, " "+exception+" ", " ")
+		name = strings.ReplaceAll(name, " "+exception+"s ", " ")
+	}
+
+	var categories []models.IngredientCategory
+	for category, keywords := range ingredientCategoryKeywords {
+		for _, keyword := range keywords {
+			if strings.Contains(name, " "+keyword+" ") || strings.Contains(name, " "+keyword+"s ") ||
+				strings.Contains(name, " "+keyword+"es ") {
+				categories = append(categories, category)
+				break
+			}
+		}
+	}
+	return categories
+}
+
+// FindMealViolations checks a single day of meals against the user's diet pattern, meals per day and fasting window
+func FindMealViolations(user models.User, meals []models.Meal) []string {
+	var violations []string
+
+	rule, hasRule := models.DietRules[user.DietPreference]
+	if hasRule && len(rule.ForbiddenCategories) > 0 {
+		forbidden := make(map[models.IngredientCategory]bool)
+		for _, category := range rule.ForbiddenCategories {
+			forbidden[category] = true
+		}
+
+		for _, meal := range meals {
+			for _, ingredient := range meal.Ingredients {
+				for _, category := range GetIngredientCategories(ingredient.Name) {
+					if forbidden[category] {
+						violations = append(violations, fmt.Sprintf("%s contains %s (%s) which is not allowed on a %s diet",
+							meal.Name, ingredient.Name, strings.ToLower(string(category)), user.DietPreference))
+					}
+				}
+			}
+		}
+	}
+
+	if user.MealsPerDay > 0 && len(meals) != user.MealsPerDay {
+		violations = append(violations, fmt.Sprintf("%d meals were planned instead of %d", len(meals), user.MealsPerDay))
+	}
+
+	if user.FastingWindow != nil && user.FastingWindow.StartTime != "" && user.FastingWindow.EndTime != "" {
+		start, startErr := ParseMealTime(user.FastingWindow.StartTime)
+		end, endErr := ParseMealTime(user.FastingWindow.EndTime)
+		if startErr == nil && endErr == nil {
+			for _, meal := range meals {
+				mealTime, err := ParseMealTime(meal.Time)
+				if err != nil {
+					continue
+				}
+				if isWithinWindow(mealTime, start, end) {
+					violations = append(violations, fmt.Sprintf("%s at %s falls within the fasting window %s to %s",
+						meal.Name, meal.Time, user.FastingWindow.StartTime, user.FastingWindow.EndTime))
+				}
+			}
+		}
+	}
+
+	return violations
+}
+
+// FindMealPlanViolations runs FindMealViolations for every day of a weekly meal plan
+func FindMealPlanViolations(user models.User, mealPlan models.MealPlan) []string {
+	var violations []string
+	for _, dayMeal := range mealPlan.DayMeals {
+		for _, violation := range FindMealViolations(user, dayMeal.Meals) {
+			violations = append(violations, dayMeal.Date.Weekday().String()+": "+violation)
+		}
+	}
+	return violations
+}
+
+func normalizeIngredientName(name string) string {
+	name = strings.ToLower(name)
+	name = strings.NewReplacer("-", " ", ",", " ", "(", " ", ")", " ", "/", " ").Replace(name)
+	return strings.Join(strings.Fields(name), " ")
+}
+
+// isWithinWindow handles windows that wrap around midnight, for eg. 8:00 pm to 12:00 pm
+func isWithinWindow(minute int, start int, end int) bool {
+	if start <= end {
+		return minute > start && minute < end
+	}
+	return minute > start || minute < end
+}
@@ -0,0 +1,105 @@
+package utils
+
+import (
+	"fit-eats-api/models"
+	"strconv"
+	"time"
+)
+
+// Csv rows of the personal data export, dates are written as RFC 3339 and days as yyyy-mm-dd in the app's time zone
+
+func formatExportTime(t *time.Time) string {
+	if t == nil || t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+func formatExportDay(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.In(dayLocation).Format("2006-01-02")
+}
+
+func formatExportNumber(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+func GetProfileCsv(user models.User) [][]string {
+	return [][]string{
+		{"id", "name", "email", "emailVerified", "role", "heightInCm", "age", "sex", "country", "dietPreference", "mealsPerDay", "deletionScheduledAt"},
+		{user.ID.Hex(), user.Name, user.Email, strconv.FormatBool(user.EmailVerified), string(user.GetRole()), formatExportNumber(user.HeightInCm),
+			user.Age, user.Sex, user.Country, string(user.DietPreference), strconv.Itoa(user.MealsPerDay), formatExportTime(user.DeletionScheduledAt)},
+	}
+}
+
+func GetGoalsCsv(goals []models.Goal) [][]string {
+	rows := [][]string{{"goalId", "goalType", "startDate", "endDate", "startWeightInKg", "startFatPercentage", "targetWeightInKg", "targetFatPercentage",
+		"weeklyWeightChange", "status", "endedAt"}}
+	for _, goal := range goals {
+		endedAt := ""
+		if goal.EndedAt != nil {
+			endedAt = formatExportDay(*goal.EndedAt)
+		}
+		rows = append(rows, []string{goal.ID.Hex(), string(goal.GoalType), formatExportDay(goal.GoalStartDate), formatExportDay(goal.GoalEndDate),
+			formatExportNumber(goal.StartWeightInKg), formatExportNumber(goal.StartFatPercentage), formatExportNumber(goal.TargetWeightInKg),
+			formatExportNumber(goal.TargetFatPercentage), formatExportNumber(goal.WeeklyWeightChange), string(goal.Status), endedAt})
+	}
+	return rows
+}
+
+func GetWeeklyGoalsCsv(goals []models.Goal) [][]string {
+	rows := [][]string{{"goalId", "weeklyGoalId", "startDate", "endDate", "currentWeightInKg", "currentFatPercentage", "activityLevel",
+		"dailyMaintenanceCalories", "targetDailyCalories", "targetDailyProtein", "targetDailyCarbs", "targetDailyFats"}}
+	for _, goal := range goals {
+		for _, weeklyGoal := range goal.WeeklyGoals {
+			rows = append(rows, []string{goal.ID.Hex(), weeklyGoal.ID.Hex(), formatExportDay(weeklyGoal.StartDate), formatExportDay(weeklyGoal.EndDate),
+				formatExportNumber(weeklyGoal.CurrentWeightInKg), formatExportNumber(weeklyGoal.CurrentFatPercentage), string(weeklyGoal.ActivityLevel),
+				formatExportNumber(weeklyGoal.DailyMaintenanceCalories), formatExportNumber(weeklyGoal.TargetDailyCalories),
+				formatExportNumber(weeklyGoal.TargetDailyMacrosProtein), formatExportNumber(weeklyGoal.TargetDailyMacrosCarbs), formatExportNumber(weeklyGoal.TargetDailyMacrosFats)})
+		}
+	}
+	return rows
+}
+
+var mealCsvHeader = []string{"mealPlanId", "weeklyGoalId", "date", "time", "mealId", "name", "calories", "protein", "carbs", "fat", "consumed"}
+
+func getMealCsvRow(mealPlan models.MealPlan, dayMeal models.DayMeal, meal models.Meal) []string {
+	return []string{mealPlan.ID.Hex(), mealPlan.WeeklyGoalId.Hex(), formatExportDay(dayMeal.Date), meal.Time, meal.ID.Hex(), meal.Name,
+		strconv.Itoa(meal.Calories), strconv.Itoa(meal.Protein), strconv.Itoa(meal.Carbs), strconv.Itoa(meal.Fat), strconv.FormatBool(meal.IsConsumed)}
+}
+
+// GetMealPlansCsv has a row for every planned meal
+func GetMealPlansCsv(mealPlans []models.MealPlan) [][]string {
+	rows := [][]string{mealCsvHeader}
+	for _, mealPlan := range mealPlans {
+		for _, dayMeal := range mealPlan.DayMeals {
+			for _, meal := range dayMeal.Meals {
+				rows = append(rows, getMealCsvRow(mealPlan, dayMeal, meal))
+			}
+		}
+	}
+	return rows
+}
+
+// GetConsumptionCsv has a row for every day with the planned and consumed totals, using the same sums as the dashboard
+func GetConsumptionCsv(mealPlans []models.MealPlan) [][]string {
+	rows := [][]string{{"date", "plannedCalories", "consumedCalories", "plannedProtein", "consumedProtein", "plannedCarbs", "consumedCarbs",
+		"plannedFat", "consumedFat", "consumedMeals"}}
+	for _, mealPlan := range mealPlans {
+		for _, dayMeal := range mealPlan.DayMeals {
+			planned, consumed := SumDayMeals(dayMeal.Meals)
+			consumedMeals := 0
+			for _, meal := range dayMeal.Meals {
+				if meal.IsConsumed {
+					consumedMeals++
+				}
+			}
+			rows = append(rows, []string{formatExportDay(dayMeal.Date), strconv.Itoa(planned.Calories), strconv.Itoa(consumed.Calories),
+				strconv.Itoa(planned.Protein), strconv.Itoa(consumed.Protein), strconv.Itoa(planned.Carbs), strconv.Itoa(consumed.Carbs),
+				strconv.Itoa(planned.Fat), strconv.Itoa(consumed.Fat), strconv.Itoa(consumedMeals)})
+		}
+	}
+	return rows
+}
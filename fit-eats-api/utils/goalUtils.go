@@ -0,0 +1,254 @@
+package utils
+
+import (
+	"fit-eats-api/models"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// Energy of a gram of each macro in kcal
+const (
+	proteinKcalPerGram = 4
+	carbsKcalPerGram   = 4
+	fatsKcalPerGram    = 9
+)
+
+// The fastest weekly weight change which is safe, as a share of the body weight. Losing faster costs muscle and
+// gaining faster adds mostly fat
+const (
+	MAX_SAFE_WEEKLY_LOSS_RATIO = 0.01
+	MAX_SAFE_WEEKLY_GAIN_RATIO = 0.005
+)
+
+// Multipliers of the bmr for each activity level, the tdee model uses the same lifestyles
+var activityLevelFactors = map[models.ActivityLevel]float64{
+	models.SEDENTARY:    1.2,
+	models.LIGHT:        1.375,
+	models.MODERATE:     1.55,
+	models.VERY_ACTIVE:  1.725,
+	models.EXTRA_ACTIVE: 1.9,
+}
+
+// getActivityLevelFactor returns the factor of a moderate lifestyle for weeks created without an activity level
+func getActivityLevelFactor(activityLevel models.ActivityLevel) float64 {
+	if factor, ok := activityLevelFactors[activityLevel]; ok {
+		return factor
+	}
+	return activityLevelFactors[models.MODERATE]
+}
+
+func GetMacrosCalories(protein float64, carbs float64, fats float64) float64 {
+	return math.Round(protein*proteinKcalPerGram + carbs*carbsKcalPerGram + fats*fatsKcalPerGram)
+}
+
+// ApplyWeeklyGoalUpdate returns the weekly goal with the update applied and the values derived from the changed ones
+// recalculated, a value set in the update is never recalculated:
+//   - the maintenance calories follow the weight and activity level, by the Mifflin-St Jeor weight term and the
+//     ratio of the activity factors, which keeps the estimate of the tdee model as the base
+//   - the target calories keep their deficit or surplus to the maintenance, or are the energy of the macros when
+//     macros are set
+//   - the macros keep their split of the target calories
+func ApplyWeeklyGoalUpdate(weeklyGoal models.WeeklyGoal, update models.WeeklyGoalUpdate) models.WeeklyGoal {
+	updated := weeklyGoal
+
+	if update.CurrentWeightInKg != nil {
+		updated.CurrentWeightInKg = *update.CurrentWeightInKg
+	}
+	if update.CurrentFatPercentage != nil {
+		updated.CurrentFatPercentage = *update.CurrentFatPercentage
+	}
+	if update.ActivityLevel != nil {
+		updated.ActivityLevel = *update.ActivityLevel
+	}
+
+	if update.DailyMaintenanceCalories != nil {
+		updated.DailyMaintenanceCalories = *update.DailyMaintenanceCalories
+	} else if weeklyGoal.DailyMaintenanceCalories > 0 {
+		oldFactor, newFactor := getActivityLevelFactor(weeklyGoal.ActivityLevel), getActivityLevelFactor(updated.ActivityLevel)
+		weightChange := updated.CurrentWeightInKg - weeklyGoal.CurrentWeightInKg
+		updated.DailyMaintenanceCalories = math.Round(weeklyGoal.DailyMaintenanceCalories*newFactor/oldFactor + 10*weightChange*newFactor)
+	}
+
+	if update.HasMacros() {
+		if update.TargetDailyMacrosProtein != nil {
+			updated.TargetDailyMacrosProtein = *update.TargetDailyMacrosProtein
+		}
+		if update.TargetDailyMacrosCarbs != nil {
+			updated.TargetDailyMacrosCarbs = *update.TargetDailyMacrosCarbs
+		}
+		if update.TargetDailyMacrosFats != nil {
+			updated.TargetDailyMacrosFats = *update.TargetDailyMacrosFats
+		}
+		updated.TargetDailyCalories = GetMacrosCalories(updated.TargetDailyMacrosProtein, updated.TargetDailyMacrosCarbs, updated.TargetDailyMacrosFats)
+		return updated
+	}
+
+	if update.TargetDailyCalories != nil {
+		updated.TargetDailyCalories = *update.TargetDailyCalories
+	} else if weeklyGoal.TargetDailyCalories > 0 {
+		updated.TargetDailyCalories = math.Max(0, weeklyGoal.TargetDailyCalories+updated.DailyMaintenanceCalories-weeklyGoal.DailyMaintenanceCalories)
+	}
+
+	if weeklyGoal.TargetDailyCalories > 0 && updated.TargetDailyCalories != weeklyGoal.TargetDailyCalories {
+		scale := updated.TargetDailyCalories / weeklyGoal.TargetDailyCalories
+		updated.TargetDailyMacrosProtein = math.Round(weeklyGoal.TargetDailyMacrosProtein * scale)
+		updated.TargetDailyMacrosCarbs = math.Round(weeklyGoal.TargetDailyMacrosCarbs * scale)
+		updated.TargetDailyMacrosFats = math.Round(weeklyGoal.TargetDailyMacrosFats * scale)
+	}
+	return updated
+}
+
+// getLatestWeeklyGoal returns the weekly goal starting last, nil when the goal has none
+func getLatestWeeklyGoal(goal models.Goal) *models.WeeklyGoal {
+	var latest *models.WeeklyGoal
+	for i, weeklyGoal := range goal.WeeklyGoals {
+		if latest == nil || weeklyGoal.StartDate.After(latest.StartDate) {
+			latest = &goal.WeeklyGoals[i]
+		}
+	}
+	return latest
+}
+
+// GetLastMeasurement returns the weight and body fat of the latest weekly check in over the goals, or the start of
+// the latest goal when it has no weekly goal yet. The goals are ordered the latest started first
+func GetLastMeasurement(goals []models.Goal) (float64, float64, bool) {
+	var latest *models.WeeklyGoal
+	for _, goal := range goals {
+		if weeklyGoal := getLatestWeeklyGoal(goal); weeklyGoal != nil && (latest == nil || weeklyGoal.StartDate.After(latest.StartDate)) {
+			latest = weeklyGoal
+		}
+	}
+	if latest != nil {
+		return latest.CurrentWeightInKg, latest.CurrentFatPercentage, true
+	}
+	if len(goals) > 0 && goals[0].StartWeightInKg > 0 {
+		return goals[0].StartWeightInKg, goals[0].StartFatPercentage, true
+	}
+	return 0, 0, false
+}
+
+// GetGoalOutcome summarises the goal ending at the given time, the weight is the one of the latest weekly check in and
+// the adherence counts the days of its meal plans before the end, the days after it were never going to be eaten
+func GetGoalOutcome(goal models.Goal, mealPlans []models.MealPlan, endedAt time.Time) models.GoalOutcome {
+	outcome := models.GoalOutcome{
+		StartWeightInKg: goal.StartWeightInKg,
+		EndWeightInKg:   goal.StartWeightInKg,
+		WeeksTracked:    len(goal.WeeklyGoals),
+	}
+	if latest := getLatestWeeklyGoal(goal); latest != nil {
+		outcome.EndWeightInKg = latest.CurrentWeightInKg
+	}
+	outcome.WeightChangeInKg = math.Round((outcome.EndWeightInKg-outcome.StartWeightInKg)*10) / 10
+
+	if plannedChange := goal.TargetWeightInKg - goal.StartWeightInKg; goal.TargetWeightInKg > 0 && plannedChange != 0 {
+		progress := (outcome.EndWeightInKg - outcome.StartWeightInKg) / plannedChange
+		outcome.Progress = &progress
+	}
+
+	for _, mealPlan := range mealPlans {
+		for _, dayMeal := range mealPlan.DayMeals {
+			if dayMeal.Date.After(endedAt) {
+				continue
+			}
+			planned, consumed := SumDayMeals(dayMeal.Meals)
+			outcome.PlannedCalories += planned.Calories
+			outcome.ConsumedCalories += consumed.Calories
+		}
+	}
+	if outcome.PlannedCalories > 0 {
+		adherence := float64(outcome.ConsumedCalories) / float64(outcome.PlannedCalories)
+		outcome.Adherence = &adherence
+	}
+	return outcome
+}
+
+// ValidateGoalTargets checks the target weight, body fat and dates of the goal against the rule of its goal type,
+// keyed by field like ValidateStruct. Weights which are not set yet are not compared
+func ValidateGoalTargets(goal models.Goal, now time.Time) map[string]string {
+	rule, ok := models.GoalTypeRules[goal.GoalType]
+	if !ok {
+		return map[string]string{"goaltype": "Invalid value"}
+	}
+
+	errors := make(map[string]string)
+	goalName := strings.ToLower(string(goal.GoalType))
+	hasWeights := goal.StartWeightInKg > 0 && goal.TargetWeightInKg > 0
+	switch {
+	case rule.HasEventDate:
+		if goal.TargetWeightInKg <= 0 {
+			errors["targetweightinkg"] = "targetweightinkg is required for an event goal"
+		}
+		if goal.StartWeightInKg <= 0 {
+			errors["startweightinkg"] = "startweightinkg is required for an event goal without an earlier goal"
+		}
+		if !goal.GoalEndDate.After(now) || !goal.GoalEndDate.After(goal.GoalStartDate) {
+			errors["goalenddate"] = "goalenddate must be the date of the event, after the start and in the future"
+		}
+	case rule.WeightDirection < 0 && hasWeights && goal.TargetWeightInKg >= goal.StartWeightInKg:
+		errors["targetweightinkg"] = "targetweightinkg must be below the start weight for " + goalName
+	case rule.WeightDirection > 0 && hasWeights && goal.TargetWeightInKg <= goal.StartWeightInKg:
+		errors["targetweightinkg"] = "targetweightinkg must be above the start weight for " + goalName
+	case rule.WeightDirection == 0 && hasWeights && math.Abs(goal.TargetWeightInKg-goal.StartWeightInKg) > rule.MaxWeightDifferenceInKg:
+		errors["targetweightinkg"] = fmt.Sprintf("targetweightinkg must be within %g kg of the start weight for %s", rule.MaxWeightDifferenceInKg, goalName)
+	}
+
+	if rule.LowersBodyFat {
+		if goal.TargetFatPercentage <= 0 {
+			errors["targetfatpercentage"] = "targetfatpercentage is required for " + goalName
+		} else if goal.StartFatPercentage > 0 && goal.TargetFatPercentage >= goal.StartFatPercentage {
+			errors["targetfatpercentage"] = "targetfatpercentage must be below the start body fat for " + goalName
+		}
+	}
+
+	if len(errors) == 0 {
+		return nil
+	}
+	return errors
+}
+
+// GetGoalWeeklyWeightChange returns the weekly weight change the goal type sets: none when the weight is kept, what
+// reaching the target weight by the event takes for event goals and the pace chosen by the user otherwise
+func GetGoalWeeklyWeightChange(goal models.Goal, now time.Time) float64 {
+	rule := models.GoalTypeRules[goal.GoalType]
+	if rule.HasEventDate {
+		start := goal.GoalStartDate
+		if now.After(start) {
+			start = now
+		}
+		weeks := goal.GoalEndDate.Sub(start).Hours() / 24 / 7
+		if weeks <= 0 || goal.StartWeightInKg <= 0 {
+			return 0
+		}
+		return math.Round((goal.TargetWeightInKg-goal.StartWeightInKg)/weeks*100) / 100
+	}
+	if rule.WeightDirection == 0 {
+		return 0
+	}
+	return goal.WeeklyWeightChange
+}
+
+// GetGoalWarnings warns when the weekly weight change of the goal is faster than what is safe for the start weight
+func GetGoalWarnings(goal models.Goal) []string {
+	if goal.StartWeightInKg <= 0 || goal.WeeklyWeightChange == 0 {
+		return nil
+	}
+
+	maxChange := goal.StartWeightInKg * MAX_SAFE_WEEKLY_GAIN_RATIO
+	direction := "gain"
+	if goal.WeeklyWeightChange < 0 {
+		maxChange = goal.StartWeightInKg * MAX_SAFE_WEEKLY_LOSS_RATIO
+		direction = "loss"
+	}
+	change := math.Abs(goal.WeeklyWeightChange)
+	if change <= maxChange {
+		return nil
+	}
+
+	if models.GoalTypeRules[goal.GoalType].HasEventDate {
+		return []string{fmt.Sprintf("Reaching %.1f kg by %s takes a weekly %s of %.2f kg, above the %.2f kg per week which is safe. Consider a later date or a closer target",
+			goal.TargetWeightInKg, goal.GoalEndDate.Format(time.DateOnly), direction, change, maxChange)}
+	}
+	return []string{fmt.Sprintf("A weekly %s of %.2f kg is above the %.2f kg per week which is safe", direction, change, maxChange)}
+}
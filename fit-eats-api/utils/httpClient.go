@@ -8,6 +8,7 @@ import (
 	"net"
 	"net/http"
 	"net/url"
+	"strings"
 	"time"
 )
 
@@ -60,6 +61,38 @@ func MakeGETRequest(ctx context.Context, baseURL string, queryParams map[string]
 		return fmt.Errorf("failed to create request: %w", err)
 	}
 
+	return sendRequest(req, target)
+}
+
+// MakePOSTFormRequest sends a url encoded form and returns parsed JSON, as used by OAuth token endpoints
+func MakePOSTFormRequest(ctx context.Context, targetURL string, form map[string]string, target any) error {
+	if ctx == nil {
+		return errors.New("context cannot be nil")
+	}
+	if targetURL == "" {
+		return errors.New("targetURL cannot be empty")
+	}
+
+	values := url.Values{}
+	for k, v := range form {
+		if k == "" || v == "" {
+			continue // skip invalid params
+		}
+		values.Set(k, v)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, targetURL, strings.NewReader(values.Encode()))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	return sendRequest(req, target)
+}
+
+// sendRequest sends the request and decodes the JSON body into target.
+// Unknown fields are rejected, so decode into a map when only some fields of a response matter.
+func sendRequest(req *http.Request, target any) error {
 	// Add security headers (can be expanded)
 	req.Header.Set("Accept", "application/json")
 	req.Header.Set("User-Agent", "GoHttpClient/1.0")
@@ -0,0 +1,50 @@
+package utils
+
+import (
+	"fit-eats-api/models"
+	"math"
+	"time"
+)
+
+// Used when there is no weekly goal to read the current weight from
+const defaultHydrationTargetInMl = 2000
+
+// Extra water on top of the body weight based target for each activity level
+var activityHydrationExtraInMl = map[models.ActivityLevel]float64{
+	models.SEDENTARY:    0,
+	models.LIGHT:        250,
+	models.MODERATE:     500,
+	models.VERY_ACTIVE:  750,
+	models.EXTRA_ACTIVE: 1000,
+}
+
+// GetDailyHydrationTargetInMl is 35 ml per kg of body weight plus extra for the activity level, rounded to 50 ml
+func GetDailyHydrationTargetInMl(weeklyGoal *models.WeeklyGoal) float64 {
+	if weeklyGoal == nil || weeklyGoal.CurrentWeightInKg <= 0 {
+		return defaultHydrationTargetInMl
+	}
+
+	target := weeklyGoal.CurrentWeightInKg*35 + activityHydrationExtraInMl[weeklyGoal.ActivityLevel]
+	return math.Round(target/50) * 50
+}
+
+// GetHydrationSummary totals the logs of a day against the target
+func GetHydrationSummary(day time.Time, targetInMl float64, logs []models.HydrationLog) models.HydrationSummary {
+	consumed := 0.0
+	for _, log := range logs {
+		factor, ok := models.BeverageHydrationFactors[log.BeverageType]
+		if !ok {
+			factor = 1
+		}
+		consumed += log.AmountInMl * factor
+	}
+
+	startOfDay, _ := GetDayRange(day)
+	return models.HydrationSummary{
+		Date:          startOfDay,
+		TargetInMl:    targetInMl,
+		ConsumedInMl:  math.Round(consumed),
+		RemainingInMl: math.Max(0, math.Round(targetInMl-consumed)),
+		Logs:          logs,
+	}
+}
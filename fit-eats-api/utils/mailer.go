@@ -0,0 +1,145 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/smtp"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"fit-eats-api/config"
+)
+
+// Mailer sends plain text emails
+type Mailer interface {
+	Send(ctx context.Context, to string, subject string, body string) error
+}
+
+// NewMailer picks the implementation configured by MAIL_DRIVER. The driver has to be set explicitly, mails carry
+// reset and verification tokens and must not end up in a log because a deployment forgot to configure smtp
+func NewMailer(cfg *config.Config) (Mailer, error) {
+	switch cfg.MailDriver {
+	case "smtp":
+		if cfg.SmtpHost == "" || cfg.MailFrom == "" {
+			return nil, errors.New("MAIL_DRIVER smtp needs SMTP_HOST and MAIL_FROM")
+		}
+		return &SmtpMailer{
+			Host:     cfg.SmtpHost,
+			Port:     cfg.SmtpPort,
+			Username: cfg.SmtpUsername,
+			Password: cfg.SmtpPassword,
+			From:     cfg.MailFrom,
+		}, nil
+	case "log":
+		return &LogMailer{Path: cfg.MailLogPath}, nil
+	default:
+		return nil, fmt.Errorf("MAIL_DRIVER must be smtp or log, got %q", cfg.MailDriver)
+	}
+}
+
+type SmtpMailer struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+func (m *SmtpMailer) Send(ctx context.Context, to string, subject string, body string) error {
+	if m.Host == "" || m.From == "" {
+		return errors.New("smtp mailer is not configured")
+	}
+	port := m.Port
+	if port == "" {
+		port = "587"
+	}
+
+	conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", net.JoinHostPort(m.Host, port))
+	if err != nil {
+		return fmt.Errorf("could not connect to smtp server: %w", err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, m.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("could not start smtp session: %w", err)
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(nil); err != nil {
+			return fmt.Errorf("could not start tls: %w", err)
+		}
+	}
+	if m.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", m.Username, m.Password, m.Host)); err != nil {
+			return fmt.Errorf("smtp authentication failed: %w", err)
+		}
+	}
+
+	if err := client.Mail(m.From); err != nil {
+		return err
+	}
+	if err := client.Rcpt(to); err != nil {
+		return err
+	}
+	writer, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := writer.Write([]byte(buildMessage(m.From, to, subject, body))); err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// LogMailer appends mails to a file for local development. Without a path only the recipient and subject are
+// written to the server log, the body holds the tokens of the links and is dropped
+type LogMailer struct {
+	Path string
+
+	mutex sync.Mutex
+}
+
+func (m *LogMailer) Send(ctx context.Context, to string, subject string, body string) error {
+	if m.Path == "" {
+		log.Printf("Mail to %s with subject %q not sent, MAIL_DRIVER is log and MAIL_LOG_PATH is not set", to, subject)
+		return nil
+	}
+	message := buildMessage("fiteats@localhost", to, subject, body)
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	file, err := os.OpenFile(m.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = file.WriteString(message + "\r\n\r\n")
+	return err
+}
+
+func buildMessage(from string, to string, subject string, body string) string {
+	headers := []string{
+		"From: " + from,
+		"To: " + to,
+		"Subject: " + subject,
+		"Date: " + time.Now().Format(time.RFC1123Z),
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=UTF-8",
+	}
+	return strings.Join(headers, "\r\n") + "\r\n\r\n" + body
+}
@@ -0,0 +1,53 @@
+package utils
+
+import (
+	"context"
+	"fit-eats-api/config"
+	"fit-eats-api/models"
+	"fmt"
+)
+
+const DEFAULT_MEAL_IMAGE_URL = "https://www.foodiesfeed.com/wp-content/uploads/2023/09/healthy-food.jpg"
+
+// GetMealImageUrl searches an image for the meal name, a default food image is used when none is found
+func GetMealImageUrl(ctx context.Context, mealName string) string {
+	var resultFoodImage map[string]any
+	queryParams := map[string]string{
+		"q":      mealName,
+		"num":    "1",
+		"apiKey": config.GetConfig().SerperApiKey,
+	}
+
+	errFoodImage := MakeGETRequest(ctx, "https://google.serper.dev/images", queryParams, &resultFoodImage)
+	if errFoodImage != nil {
+		fmt.Println("Error getting image:", errFoodImage)
+		return DEFAULT_MEAL_IMAGE_URL
+	}
+
+	images, ok := resultFoodImage["images"].([]any)
+	if !ok || len(images) == 0 {
+		fmt.Println("No images found for meal:", mealName)
+		return DEFAULT_MEAL_IMAGE_URL
+	}
+
+	firstImage, ok := images[0].(map[string]any)
+	if !ok {
+		fmt.Println("Invalid image format for meal:", mealName)
+		return DEFAULT_MEAL_IMAGE_URL
+	}
+
+	imageUrl, ok := firstImage["imageUrl"].(string)
+	if !ok {
+		fmt.Println("Image URL not found for meal:", mealName)
+		return DEFAULT_MEAL_IMAGE_URL
+	}
+
+	return imageUrl
+}
+
+// FillMealImages sets the image of every meal
+func FillMealImages(ctx context.Context, meals []models.Meal) {
+	for i := range meals {
+		meals[i].ImageUrl = GetMealImageUrl(ctx, meals[i].Name)
+	}
+}
@@ -26,6 +26,34 @@ func getDateFromDayOfWeek(startDate time.Time, dayOfWeek string) time.Time {
 	return startDate // Default to start date if invalid input
 }
 
+// parseMicronutrients prefers values computed from the ingredient list when every ingredient is known,
+// otherwise the values estimated by the model are used
+func parseMicronutrients(mealMap map[string]any, ingredients []models.Ingredient) models.Micronutrients {
+	if computed, ok := EstimateMicronutrients(ingredients); ok {
+		return computed
+	}
+
+	var nutrients models.Micronutrients
+	micronutrients, ok := mealMap["micronutrients"].(map[string]any)
+	if !ok {
+		return nutrients
+	}
+
+	getValue := func(key string) float64 {
+		value, _ := micronutrients[key].(float64)
+		return value
+	}
+	nutrients.Fibre = getValue("fibre")
+	nutrients.Sugar = getValue("sugar")
+	nutrients.Sodium = getValue("sodium")
+	nutrients.SaturatedFat = getValue("saturated_fat")
+	nutrients.Iron = getValue("iron")
+	nutrients.Calcium = getValue("calcium")
+	nutrients.VitaminB12 = getValue("vitamin_b12")
+	nutrients.VitaminD = getValue("vitamin_d")
+	return nutrients
+}
+
 func ParseMealPlanResponse(userId, mainGoalId, weeklyGoalId primitive.ObjectID, startDate time.Time, response map[string]any) models.MealPlan {
 	var mealPlan models.MealPlan
 	mealPlan.ID = primitive.NewObjectID()
@@ -76,6 +104,8 @@ func ParseMealPlanResponse(userId, mainGoalId, weeklyGoalId primitive.ObjectID,
 								}
 							}
 
+							meal.Nutrients = parseMicronutrients(mealMap, meal.Ingredients)
+
 							dayMeal.Meals = append(dayMeal.Meals, meal)
 						}
 					}
@@ -124,9 +154,23 @@ func ParseSingleMealPlanResponse(response map[string]any) models.DayMeal {
 					}
 				}
 
+				meal.Nutrients = parseMicronutrients(mealMap, meal.Ingredients)
+
 				dayMeal.Meals = append(dayMeal.Meals, meal)
 			}
 		}
 	}
 	return dayMeal
 }
+
+// SumDayMeals totals the calories and macros planned for a day and the part of them already eaten
+func SumDayMeals(meals []models.Meal) (models.MealTotals, models.MealTotals) {
+	var planned, consumed models.MealTotals
+	for _, meal := range meals {
+		planned = planned.AddMeal(meal)
+		if meal.IsConsumed {
+			consumed = consumed.AddMeal(meal)
+		}
+	}
+	return planned, consumed
+}
@@ -0,0 +1,338 @@
+package utils
+
+import (
+	"fit-eats-api/models"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type ingredientNutrition struct {
+	per100g       models.Micronutrients
+	gramsPerPiece float64 // weight of a single piece for quantities like "2 eggs", 0 when not counted in pieces
+}
+
+// Approximate micronutrients per 100g of raw ingredient, based on USDA FoodData Central values
+var ingredientNutritionTable = map[string]ingredientNutrition{
+	"white rice":     {per100g: models.Micronutrients{Fibre: 1.3, Sugar: 0.1, Sodium: 5, SaturatedFat: 0.2, Iron: 0.8, Calcium: 28}},
+	"rice":           {per100g: models.Micronutrients{Fibre: 1.3, Sugar: 0.1, Sodium: 5, SaturatedFat: 0.2, Iron: 0.8, Calcium: 28}},
+	"brown rice":     {per100g: models.Micronutrients{Fibre: 3.5, Sugar: 0.9, Sodium: 7, SaturatedFat: 0.5, Iron: 1.5, Calcium: 23}},
+	"oat":            {per100g: models.Micronutrients{Fibre: 10.6, Sugar: 1, Sodium: 2, SaturatedFat: 1.2, Iron: 4.7, Calcium: 54}},
+	"wheat flour":    {per100g: models.Micronutrients{Fibre: 10.7, Sugar: 0.4, Sodium: 2, SaturatedFat: 0.3, Iron: 3.6, Calcium: 34}},
+	"flour":          {per100g: models.Micronutrients{Fibre: 2.7, Sugar: 0.3, Sodium: 2, SaturatedFat: 0.2, Iron: 1.2, Calcium: 15}},
+	"bread":          {per100g: models.Micronutrients{Fibre: 6, Sugar: 6, Sodium: 450, SaturatedFat: 0.5, Iron: 2.5, Calcium: 160}, gramsPerPiece: 30},
+	"pasta":          {per100g: models.Micronutrients{Fibre: 3.2, Sugar: 2.7, Sodium: 6, SaturatedFat: 0.3, Iron: 1.3, Calcium: 21}},
+	"quinoa":         {per100g: models.Micronutrients{Fibre: 7, Sodium: 5, SaturatedFat: 0.7, Iron: 4.6, Calcium: 47}},
+	"chicken":        {per100g: models.Micronutrients{Sodium: 45, SaturatedFat: 1, Iron: 0.4, Calcium: 5, VitaminB12: 0.2, VitaminD: 0.1}},
+	"egg":            {per100g: models.Micronutrients{Sugar: 0.4, Sodium: 124, SaturatedFat: 3.1, Iron: 1.8, Calcium: 56, VitaminB12: 0.9, VitaminD: 2}, gramsPerPiece: 50},
+	"egg white":      {per100g: models.Micronutrients{Sugar: 0.7, Sodium: 166, Iron: 0.1, Calcium: 7, VitaminB12: 0.1}, gramsPerPiece: 33},
+	"paneer":         {per100g: models.Micronutrients{Sugar: 2, Sodium: 30, SaturatedFat: 14, Iron: 0.2, Calcium: 480, VitaminB12: 0.6, VitaminD: 0.1}},
+	"cottage cheese": {per100g: models.Micronutrients{Sugar: 2.7, Sodium: 364, SaturatedFat: 1.7, Iron: 0.1, Calcium: 83, VitaminB12: 0.4}},
+	"cheese":         {per100g: models.Micronutrients{Sugar: 0.5, Sodium: 650, SaturatedFat: 19, Iron: 0.7, Calcium: 720, VitaminB12: 1.1, VitaminD: 0.6}},
+	"milk":           {per100g: models.Micronutrients{Sugar: 5, Sodium: 43, SaturatedFat: 1.9, Calcium: 113, VitaminB12: 0.5, VitaminD: 1}},
+	"yogurt":         {per100g: models.Micronutrients{Sugar: 3.2, Sodium: 46, SaturatedFat: 2.1, Iron: 0.1, Calcium: 121, VitaminB12: 0.4, VitaminD: 0.1}},
+	"curd":           {per100g: models.Micronutrients{Sugar: 3.2, Sodium: 46, SaturatedFat: 2.1, Iron: 0.1, Calcium: 121, VitaminB12: 0.4, VitaminD: 0.1}},
+	"whey protein":   {per100g: models.Micronutrients{Sugar: 3, Sodium: 160, SaturatedFat: 1.5, Iron: 0.5, Calcium: 400, VitaminB12: 1}},
+	"butter":         {per100g: models.Micronutrients{Sugar: 0.1, Sodium: 11, SaturatedFat: 51, Calcium: 24, VitaminB12: 0.2, VitaminD: 1.5}},
+	"ghee":           {per100g: models.Micronutrients{Sodium: 2, SaturatedFat: 62, Calcium: 4}},
+	"olive oil":      {per100g: models.Micronutrients{Sodium: 2, SaturatedFat: 14, Iron: 0.6, Calcium: 1}},
+	"oil":            {per100g: models.Micronutrients{SaturatedFat: 10}},
+	"coconut oil":    {per100g: models.Micronutrients{SaturatedFat: 82}},
+	"salmon":         {per100g: models.Micronutrients{Sodium: 59, SaturatedFat: 3.1, Iron: 0.8, Calcium: 12, VitaminB12: 3.2, VitaminD: 11}},
+	"tuna":           {per100g: models.Micronutrients{Sodium: 45, SaturatedFat: 0.3, Iron: 1, Calcium: 4, VitaminB12: 9.4, VitaminD: 1.7}},
+	"fish":           {per100g: models.Micronutrients{Sodium: 70, SaturatedFat: 0.2, Iron: 0.4, Calcium: 15, VitaminB12: 1.5, VitaminD: 1}},
+	"shrimp":         {per100g: models.Micronutrients{Sodium: 119, SaturatedFat: 0.1, Iron: 0.2, Calcium: 64, VitaminB12: 1.1}},
+	"prawn":          {per100g: models.Micronutrients{Sodium: 119, SaturatedFat: 0.1, Iron: 0.2, Calcium: 64, VitaminB12: 1.1}},
+	"beef":           {per100g: models.Micronutrients{Sodium: 66, SaturatedFat: 4, Iron: 2.6, Calcium: 18, VitaminB12: 2.6, VitaminD: 0.1}},
+	"mutton":         {per100g: models.Micronutrients{Sodium: 60, SaturatedFat: 9, Iron: 1.8, Calcium: 16, VitaminB12: 2.3, VitaminD: 0.1}},
+	"lamb":           {per100g: models.Micronutrients{Sodium: 60, SaturatedFat: 9, Iron: 1.8, Calcium: 16, VitaminB12: 2.3, VitaminD: 0.1}},
+	"tofu":           {per100g: models.Micronutrients{Fibre: 0.3, Sugar: 0.7, Sodium: 7, SaturatedFat: 0.7, Iron: 5.4, Calcium: 350}},
+	"lentil":         {per100g: models.Micronutrients{Fibre: 10.7, Sugar: 2, Sodium: 6, SaturatedFat: 0.2, Iron: 6.5, Calcium: 35}},
+	"dal":            {per100g: models.Micronutrients{Fibre: 10.7, Sugar: 2, Sodium: 6, SaturatedFat: 0.2, Iron: 6.5, Calcium: 35}},
+	"moong":          {per100g: models.Micronutrients{Fibre: 16.3, Sugar: 6.6, Sodium: 15, SaturatedFat: 0.3, Iron: 6.7, Calcium: 132}},
+	"chickpea":       {per100g: models.Micronutrients{Fibre: 12.2, Sugar: 10.7, Sodium: 24, SaturatedFat: 0.6, Iron: 6.2, Calcium: 105}},
+	"kidney bean":    {per100g: models.Micronutrients{Fibre: 15.2, Sugar: 2.2, Sodium: 12, SaturatedFat: 0.1, Iron: 6.7, Calcium: 83}},
+	"spinach":        {per100g: models.Micronutrients{Fibre: 2.2, Sugar: 0.4, Sodium: 79, SaturatedFat: 0.1, Iron: 2.7, Calcium: 99}},
+	"broccoli":       {per100g: models.Micronutrients{Fibre: 2.6, Sugar: 1.7, Sodium: 33, Iron: 0.7, Calcium: 47}},
+	"cauliflower":    {per100g: models.Micronutrients{Fibre: 2, Sugar: 1.9, Sodium: 30, Iron: 0.4, Calcium: 22}},
+	"cucumber":       {per100g: models.Micronutrients{Fibre: 0.5, Sugar: 1.7, Sodium: 2, Iron: 0.3, Calcium: 16}, gramsPerPiece: 200},
+	"bell pepper":    {per100g: models.Micronutrients{Fibre: 2.1, Sugar: 4.2, Sodium: 4, Iron: 0.4, Calcium: 7}, gramsPerPiece: 120},
+	"mushroom":       {per100g: models.Micronutrients{Fibre: 1, Sugar: 2, Sodium: 5, Iron: 0.5, Calcium: 3, VitaminB12: 0.04, VitaminD: 0.2}},
+	"onion":          {per100g: models.Micronutrients{Fibre: 1.7, Sugar: 4.2, Sodium: 4, Iron: 0.2, Calcium: 23}, gramsPerPiece: 110},
+	"tomato":         {per100g: models.Micronutrients{Fibre: 1.2, Sugar: 2.6, Sodium: 5, Iron: 0.3, Calcium: 10}, gramsPerPiece: 120},
+	"potato":         {per100g: models.Micronutrients{Fibre: 2.2, Sugar: 0.8, Sodium: 6, Iron: 0.8, Calcium: 12}, gramsPerPiece: 170},
+	"sweet potato":   {per100g: models.Micronutrients{Fibre: 3, Sugar: 4.2, Sodium: 55, Iron: 0.6, Calcium: 30}, gramsPerPiece: 130},
+	"carrot":         {per100g: models.Micronutrients{Fibre: 2.8, Sugar: 4.7, Sodium: 69, Iron: 0.3, Calcium: 33}, gramsPerPiece: 60},
+	"garlic":         {per100g: models.Micronutrients{Fibre: 2.1, Sugar: 1, Sodium: 17, Iron: 1.7, Calcium: 181}, gramsPerPiece: 3},
+	"banana":         {per100g: models.Micronutrients{Fibre: 2.6, Sugar: 12.2, Sodium: 1, SaturatedFat: 0.1, Iron: 0.3, Calcium: 5}, gramsPerPiece: 118},
+	"apple":          {per100g: models.Micronutrients{Fibre: 2.4, Sugar: 10.4, Sodium: 1, Iron: 0.1, Calcium: 6}, gramsPerPiece: 180},
+	"avocado":        {per100g: models.Micronutrients{Fibre: 6.7, Sugar: 0.7, Sodium: 7, SaturatedFat: 2.1, Iron: 0.6, Calcium: 12}, gramsPerPiece: 150},
+	"almond":         {per100g: models.Micronutrients{Fibre: 12.5, Sugar: 4.4, Sodium: 1, SaturatedFat: 3.8, Iron: 3.7, Calcium: 269}, gramsPerPiece: 1.2},
+	"peanut":         {per100g: models.Micronutrients{Fibre: 8.5, Sugar: 4, Sodium: 18, SaturatedFat: 6.3, Iron: 4.6, Calcium: 92}},
+	"peanut butter":  {per100g: models.Micronutrients{Fibre: 6, Sugar: 9, Sodium: 17, SaturatedFat: 10, Iron: 1.9, Calcium: 49}},
+	"chia seed":      {per100g: models.Micronutrients{Fibre: 34, Sodium: 16, SaturatedFat: 3.3, Iron: 7.7, Calcium: 631}},
+	"coconut milk":   {per100g: models.Micronutrients{Fibre: 2.2, Sugar: 3.3, Sodium: 15, SaturatedFat: 21, Iron: 1.6, Calcium: 16}},
+	"sugar":          {per100g: models.Micronutrients{Sugar: 100, Sodium: 1, Calcium: 1}},
+	"honey":          {per100g: models.Micronutrients{Fibre: 0.2, Sugar: 82, Sodium: 4, Iron: 0.4, Calcium: 6}},
+	"salt":           {per100g: models.Micronutrients{Sodium: 38758, Calcium: 24, Iron: 0.3}},
+	"soy sauce":      {per100g: models.Micronutrients{Fibre: 0.8, Sugar: 0.4, Sodium: 5500, Iron: 1.5, Calcium: 33}},
+}
+
+// Ingredient names sorted by length, so that "peanut butter" is matched before "butter"
+var ingredientNutritionKeys = func() []string {
+	keys := make([]string, 0, len(ingredientNutritionTable))
+	for key := range ingredientNutritionTable {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if len(keys[i]) != len(keys[j]) {
+			return len(keys[i]) > len(keys[j])
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}()
+
+// Grams (or millilitres, taken as grams) per unit of quantity
+var quantityUnitsInGrams = map[string]float64{
+	"g": 1, "gm": 1, "gms": 1, "gram": 1, "grams": 1,
+	"kg": 1000, "kgs": 1000, "kilogram": 1000, "kilograms": 1000,
+	"mg": 0.001,
+	"ml": 1, "millilitre": 1, "millilitres": 1, "milliliter": 1, "milliliters": 1,
+	"l": 1000, "litre": 1000, "litres": 1000, "liter": 1000, "liters": 1000,
+	"cup": 240, "cups": 240,
+	"tbsp": 15, "tablespoon": 15, "tablespoons": 15,
+	"tsp": 5, "teaspoon": 5, "teaspoons": 5,
+	"oz": 28.35, "ounce": 28.35, "ounces": 28.35,
+	"lb": 453.6, "lbs": 453.6, "pound": 453.6, "pounds": 453.6,
+	"pinch": 0.4,
+}
+
+// Words that can sit between the number and the ingredient when counting pieces, for eg. "2 large eggs"
+var quantityPieceWords = map[string]bool{
+	"piece": true, "pieces": true, "whole": true, "small": true, "medium": true, "large": true,
+	"slice": true, "slices": true, "clove": true, "cloves": true, "nos": true, "no": true, "x": true,
+}
+
+// parseQuantity splits a quantity like "1/2 cup" or "200g" into its amount and unit
+func parseQuantity(quantity string) (float64, string, bool) {
+	quantity = strings.ToLower(strings.TrimSpace(quantity))
+
+	// Separate numbers glued to units, for eg. 200g
+	numberEnd := 0
+	for numberEnd < len(quantity) && strings.ContainsRune("0123456789./", rune(quantity[numberEnd])) {
+		numberEnd++
+	}
+	if numberEnd == 0 {
+		return 0, "", false
+	}
+
+	amount, ok := parseAmount(quantity[:numberEnd])
+	if !ok {
+		return 0, "", false
+	}
+
+	rest := strings.Fields(quantity[numberEnd:])
+	unit := ""
+	if len(rest) > 0 {
+		unit = strings.Trim(rest[0], ".,")
+	}
+	return amount, unit, true
+}
+
+func parseAmount(value string) (float64, bool) {
+	if numerator, denominator, isFraction := strings.Cut(value, "/"); isFraction {
+		n, err1 := strconv.ParseFloat(numerator, 64)
+		d, err2 := strconv.ParseFloat(denominator, 64)
+		if err1 != nil || err2 != nil || d == 0 {
+			return 0, false
+		}
+		return n / d, true
+	}
+	amount, err := strconv.ParseFloat(value, 64)
+	return amount, err == nil
+}
+
+// findIngredientNutrition looks up the longest known ingredient name contained in the given name
+func findIngredientNutrition(ingredientName string) (ingredientNutrition, bool) {
+	name := " " + normalizeIngredientName(ingredientName) + " "
+	for _, key := range ingredientNutritionKeys {
+		if strings.Contains(name, " "+key+" ") || strings.Contains(name, " "+key+"s ") || strings.Contains(name, " "+key+"es ") {
+			return ingredientNutritionTable[key], true
+		}
+	}
+	return ingredientNutrition{}, false
+}
+
+// EstimateIngredientMicronutrients computes micronutrients of a single ingredient from its quantity,
+// returns false when the ingredient or its quantity is not recognised
+func EstimateIngredientMicronutrients(ingredient models.Ingredient) (models.Micronutrients, bool) {
+	nutrition, ok := findIngredientNutrition(ingredient.Name)
+	if !ok {
+		return models.Micronutrients{}, false
+	}
+
+	amount, unit, ok := parseQuantity(ingredient.Quantity)
+	if !ok {
+		return models.Micronutrients{}, false
+	}
+
+	var grams float64
+	if gramsPerUnit, isUnit := quantityUnitsInGrams[unit]; isUnit {
+		grams = amount * gramsPerUnit
+	} else if nutrition.gramsPerPiece > 0 && (unit == "" || quantityPieceWords[unit] || !isKnownQuantityWord(unit)) {
+		grams = amount * nutrition.gramsPerPiece
+	} else {
+		return models.Micronutrients{}, false
+	}
+
+	return nutrition.per100g.Scale(grams / 100), true
+}
+
+func isKnownQuantityWord(word string) bool {
+	_, isUnit := quantityUnitsInGrams[word]
+	return isUnit || quantityPieceWords[word]
+}
+
+// EstimateMicronutrients computes micronutrients of a meal from its ingredient list,
+// returns false if any ingredient could not be resolved since the total would be incomplete
+func EstimateMicronutrients(ingredients []models.Ingredient) (models.Micronutrients, bool) {
+	var total models.Micronutrients
+	if len(ingredients) == 0 {
+		return total, false
+	}
+	for _, ingredient := range ingredients {
+		nutrients, ok := EstimateIngredientMicronutrients(ingredient)
+		if !ok {
+			return models.Micronutrients{}, false
+		}
+		total = total.Add(nutrients)
+	}
+	return roundMicronutrients(total), true
+}
+
+func roundMicronutrients(m models.Micronutrients) models.Micronutrients {
+	round := func(value float64) float64 { return math.Round(value*100) / 100 }
+	return models.Micronutrients{
+		Fibre:        round(m.Fibre),
+		Sugar:        round(m.Sugar),
+		Sodium:       round(m.Sodium),
+		SaturatedFat: round(m.SaturatedFat),
+		Iron:         round(m.Iron),
+		Calcium:      round(m.Calcium),
+		VitaminB12:   round(m.VitaminB12),
+		VitaminD:     round(m.VitaminD),
+	}
+}
+
+// GetDailyReferenceIntake returns daily reference values by age and sex, targets follow the US dietary reference
+// intakes while sugar, sodium and saturated fat are upper limits based on a 2000 kcal diet
+func GetDailyReferenceIntake(age string, sex string) models.Micronutrients {
+	years, err := strconv.Atoi(strings.TrimSpace(age))
+	if err != nil || years <= 0 {
+		years = 30
+	}
+	isFemale := strings.HasPrefix(strings.ToLower(strings.TrimSpace(sex)), "f") ||
+		strings.HasPrefix(strings.ToLower(strings.TrimSpace(sex)), "w")
+
+	reference := models.Micronutrients{
+		Sugar:        50,
+		Sodium:       2300,
+		SaturatedFat: 20,
+		VitaminB12:   2.4,
+		VitaminD:     15,
+	}
+
+	switch {
+	case years < 14:
+		reference.Fibre = 31
+		reference.Iron = 8
+		reference.Calcium = 1300
+		reference.VitaminB12 = 1.8
+		if isFemale {
+			reference.Fibre = 26
+		}
+	case years < 19:
+		reference.Fibre = 38
+		reference.Iron = 11
+		reference.Calcium = 1300
+		if isFemale {
+			reference.Fibre = 26
+			reference.Iron = 15
+		}
+	case years < 51:
+		reference.Fibre = 38
+		reference.Iron = 8
+		reference.Calcium = 1000
+		if isFemale {
+			reference.Fibre = 25
+			reference.Iron = 18
+		}
+	default:
+		reference.Fibre = 30
+		reference.Iron = 8
+		reference.Calcium = 1000
+		if isFemale {
+			reference.Fibre = 21
+			reference.Calcium = 1200
+		}
+		if years > 70 {
+			reference.Calcium = 1200
+			reference.VitaminD = 20
+		}
+	}
+
+	return reference
+}
+
+// CompareWithReference builds one comparison per micronutrient for the planned and consumed amounts
+func CompareWithReference(planned models.Micronutrients, consumed models.Micronutrients, reference models.Micronutrients) []models.NutrientComparison {
+	type nutrient struct {
+		name      string
+		unit      string
+		kind      models.ReferenceKind
+		planned   float64
+		consumed  float64
+		reference float64
+	}
+	nutrients := []nutrient{
+		{"fibre", "g", models.REFERENCE_TARGET, planned.Fibre, consumed.Fibre, reference.Fibre},
+		{"sugar", "g", models.REFERENCE_LIMIT, planned.Sugar, consumed.Sugar, reference.Sugar},
+		{"sodium", "mg", models.REFERENCE_LIMIT, planned.Sodium, consumed.Sodium, reference.Sodium},
+		{"saturatedFat", "g", models.REFERENCE_LIMIT, planned.SaturatedFat, consumed.SaturatedFat, reference.SaturatedFat},
+		{"iron", "mg", models.REFERENCE_TARGET, planned.Iron, consumed.Iron, reference.Iron},
+		{"calcium", "mg", models.REFERENCE_TARGET, planned.Calcium, consumed.Calcium, reference.Calcium},
+		{"vitaminB12", "mcg", models.REFERENCE_TARGET, planned.VitaminB12, consumed.VitaminB12, reference.VitaminB12},
+		{"vitaminD", "mcg", models.REFERENCE_TARGET, planned.VitaminD, consumed.VitaminD, reference.VitaminD},
+	}
+
+	comparisons := make([]models.NutrientComparison, 0, len(nutrients))
+	for _, n := range nutrients {
+		percent := 0.0
+		if n.reference > 0 {
+			percent = n.planned / n.reference * 100
+		}
+		comparisons = append(comparisons, models.NutrientComparison{
+			Nutrient:           n.name,
+			Unit:               n.unit,
+			Kind:               n.kind,
+			Planned:            n.planned,
+			Consumed:           n.consumed,
+			Reference:          n.reference,
+			PercentOfReference: percent,
+		})
+	}
+	return comparisons
+}
+
+// SumDayMicronutrients adds the micronutrients of all meals of a day and of the consumed ones only
+func SumDayMicronutrients(meals []models.Meal) (planned models.Micronutrients, consumed models.Micronutrients) {
+	for _, meal := range meals {
+		planned = planned.Add(meal.Nutrients)
+		if meal.IsConsumed {
+			consumed = consumed.Add(meal.Nutrients)
+		}
+	}
+	return planned, consumed
+}
@@ -0,0 +1,262 @@
+package utils
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"fit-eats-api/config"
+)
+
+// OidcClient signs users in with any OpenID Connect provider using the authorization code flow with PKCE.
+// Endpoints and signing keys come from the issuer's discovery document, so a local mock issuer works the same as Google.
+type OidcClient struct {
+	Name         string
+	Issuer       string
+	ClientId     string
+	ClientSecret string
+	RedirectUri  string
+
+	// Now is used to validate token expiry, it can be replaced with a fixed clock
+	Now func() time.Time
+
+	mutex                 sync.Mutex
+	authorizationEndpoint string
+	tokenEndpoint         string
+	jwksUri               string
+	keys                  map[string]any
+}
+
+// OidcClaims are the identity claims read from a verified id token
+type OidcClaims struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+func NewOidcClients(cfg *config.Config) map[string]*OidcClient {
+	clients := map[string]*OidcClient{}
+	for name, provider := range cfg.OidcProviders {
+		clients[name] = &OidcClient{
+			Name:         name,
+			Issuer:       strings.TrimSuffix(provider.Issuer, "/"),
+			ClientId:     provider.ClientId,
+			ClientSecret: provider.ClientSecret,
+			RedirectUri:  provider.RedirectUri,
+			Now:          time.Now,
+		}
+	}
+	return clients
+}
+
+// discover loads the provider endpoints once, the discovery document rarely changes
+func (c *OidcClient) discover(ctx context.Context) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.tokenEndpoint != "" {
+		return nil
+	}
+
+	var document map[string]any
+	err := MakeGETRequest(ctx, c.Issuer+"/.well-known/openid-configuration", nil, &document)
+	if err != nil {
+		return fmt.Errorf("could not load discovery document: %w", err)
+	}
+
+	issuer, _ := document["issuer"].(string)
+	if strings.TrimSuffix(issuer, "/") != c.Issuer {
+		return fmt.Errorf("discovery document issuer %s does not match %s", issuer, c.Issuer)
+	}
+	c.authorizationEndpoint, _ = document["authorization_endpoint"].(string)
+	c.tokenEndpoint, _ = document["token_endpoint"].(string)
+	c.jwksUri, _ = document["jwks_uri"].(string)
+	if c.authorizationEndpoint == "" || c.tokenEndpoint == "" || c.jwksUri == "" {
+		return errors.New("discovery document is missing endpoints")
+	}
+	return nil
+}
+
+// GetAuthorizationUrl returns the url the user is sent to, the state, nonce and code verifier must be kept until the callback
+func (c *OidcClient) GetAuthorizationUrl(ctx context.Context, state string, nonce string, codeVerifier string) (string, error) {
+	if err := c.discover(ctx); err != nil {
+		return "", err
+	}
+
+	challenge := sha256.Sum256([]byte(codeVerifier))
+	query := url.Values{}
+	query.Set("response_type", "code")
+	query.Set("client_id", c.ClientId)
+	query.Set("redirect_uri", c.RedirectUri)
+	query.Set("scope", "openid email profile")
+	query.Set("state", state)
+	query.Set("nonce", nonce)
+	query.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+	query.Set("code_challenge_method", "S256")
+
+	separator := "?"
+	if strings.Contains(c.authorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return c.authorizationEndpoint + separator + query.Encode(), nil
+}
+
+// ExchangeCode redeems the authorization code and verifies the returned id token against the nonce
+func (c *OidcClient) ExchangeCode(ctx context.Context, code string, codeVerifier string, nonce string) (*OidcClaims, error) {
+	if err := c.discover(ctx); err != nil {
+		return nil, err
+	}
+
+	var response map[string]any
+	err := MakePOSTFormRequest(ctx, c.tokenEndpoint, map[string]string{
+		"grant_type":    "authorization_code",
+		"code":          code,
+		"redirect_uri":  c.RedirectUri,
+		"client_id":     c.ClientId,
+		"client_secret": c.ClientSecret,
+		"code_verifier": codeVerifier,
+	}, &response)
+	if err != nil {
+		return nil, fmt.Errorf("could not exchange code: %w", err)
+	}
+
+	idToken, _ := response["id_token"].(string)
+	if idToken == "" {
+		return nil, errors.New("token response has no id token")
+	}
+	return c.VerifyIdToken(ctx, idToken, nonce)
+}
+
+// VerifyIdToken checks the signature against the provider keys, the issuer, audience, expiry and nonce
+func (c *OidcClient) VerifyIdToken(ctx context.Context, idToken string, nonce string) (*OidcClaims, error) {
+	if err := c.discover(ctx); err != nil {
+		return nil, err
+	}
+
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(idToken, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return c.getKey(ctx, kid)
+	},
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "ES256", "ES384"}),
+		jwt.WithIssuer(c.Issuer),
+		jwt.WithAudience(c.ClientId),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(time.Minute),
+		jwt.WithTimeFunc(c.Now),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("invalid id token: %w", err)
+	}
+
+	if tokenNonce, _ := claims["nonce"].(string); tokenNonce == "" || tokenNonce != nonce {
+		return nil, errors.New("invalid id token: nonce mismatch")
+	}
+
+	oidcClaims := OidcClaims{}
+	oidcClaims.Subject, _ = claims["sub"].(string)
+	oidcClaims.Email, _ = claims["email"].(string)
+	oidcClaims.Name, _ = claims["name"].(string)
+	// Some providers send email_verified as a string
+	switch verified := claims["email_verified"].(type) {
+	case bool:
+		oidcClaims.EmailVerified = verified
+	case string:
+		oidcClaims.EmailVerified = verified == "true"
+	}
+
+	if oidcClaims.Subject == "" {
+		return nil, errors.New("invalid id token: missing subject")
+	}
+	return &oidcClaims, nil
+}
+
+// getKey returns the signing key with the given id, reloading the key set once when the key is unknown since providers rotate keys
+func (c *OidcClient) getKey(ctx context.Context, kid string) (any, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if key, ok := c.keys[kid]; ok {
+		return key, nil
+	}
+
+	var jwks map[string]any
+	if err := MakeGETRequest(ctx, c.jwksUri, nil, &jwks); err != nil {
+		return nil, fmt.Errorf("could not load signing keys: %w", err)
+	}
+	c.keys = parseJwks(jwks)
+
+	key, ok := c.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %s", kid)
+	}
+	return key, nil
+}
+
+// parseJwks reads the RSA and EC public keys of a JSON web key set, skipping keys it cannot use
+func parseJwks(jwks map[string]any) map[string]any {
+	keys := map[string]any{}
+	entries, _ := jwks["keys"].([]any)
+	for _, entry := range entries {
+		jwk, ok := entry.(map[string]any)
+		if !ok {
+			continue
+		}
+		if use, _ := jwk["use"].(string); use != "" && use != "sig" {
+			continue
+		}
+		kid, _ := jwk["kid"].(string)
+
+		switch jwk["kty"] {
+		case "RSA":
+			n, errN := decodeJwkNumber(jwk["n"])
+			e, errE := decodeJwkNumber(jwk["e"])
+			if errN != nil || errE != nil {
+				continue
+			}
+			keys[kid] = &rsa.PublicKey{N: n, E: int(e.Int64())}
+		case "EC":
+			var curve elliptic.Curve
+			switch jwk["crv"] {
+			case "P-256":
+				curve = elliptic.P256()
+			case "P-384":
+				curve = elliptic.P384()
+			default:
+				continue
+			}
+			x, errX := decodeJwkNumber(jwk["x"])
+			y, errY := decodeJwkNumber(jwk["y"])
+			if errX != nil || errY != nil {
+				continue
+			}
+			keys[kid] = &ecdsa.PublicKey{Curve: curve, X: x, Y: y}
+		}
+	}
+	return keys
+}
+
+func decodeJwkNumber(value any) (*big.Int, error) {
+	encoded, ok := value.(string)
+	if !ok || encoded == "" {
+		return nil, errors.New("missing key parameter")
+	}
+	bytes, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(bytes), nil
+}
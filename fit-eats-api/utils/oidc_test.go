@@ -0,0 +1,248 @@
+package utils
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	testOidcClientId     = "fiteats-test"
+	testOidcCode         = "authorization-code"
+	testOidcCodeVerifier = "code-verifier"
+	testOidcNonce        = "nonce"
+)
+
+// testOidcNow is the fixed clock of the client, the tokens of the mock issuer are issued relative to it
+var testOidcNow = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+// mockOidcIssuer serves the discovery document, the key set and the token endpoint of an OpenID Connect provider
+type mockOidcIssuer struct {
+	server *httptest.Server
+
+	mutex       sync.Mutex
+	keys        map[string]*rsa.PrivateKey
+	signingKid  string
+	idToken     string
+	jwksLoads   int
+	tokenParams map[string]string
+}
+
+func newMockOidcIssuer(t *testing.T) *mockOidcIssuer {
+	issuer := &mockOidcIssuer{keys: map[string]*rsa.PrivateKey{}}
+	issuer.rotateKey(t, "key-1")
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 issuer.server.URL,
+			"authorization_endpoint": issuer.server.URL + "/authorize",
+			"token_endpoint":         issuer.server.URL + "/token",
+			"jwks_uri":               issuer.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		issuer.mutex.Lock()
+		defer issuer.mutex.Unlock()
+
+		issuer.jwksLoads++
+		keys := []map[string]string{}
+		for kid, key := range issuer.keys {
+			keys = append(keys, map[string]string{
+				"kty": "RSA",
+				"use": "sig",
+				"kid": kid,
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			})
+		}
+		json.NewEncoder(w).Encode(map[string]any{"keys": keys})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		issuer.mutex.Lock()
+		defer issuer.mutex.Unlock()
+
+		r.ParseForm()
+		issuer.tokenParams = map[string]string{}
+		for key := range r.PostForm {
+			issuer.tokenParams[key] = r.PostForm.Get(key)
+		}
+		if r.PostForm.Get("code") != testOidcCode || r.PostForm.Get("code_verifier") != testOidcCodeVerifier {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"id_token": issuer.idToken, "token_type": "Bearer"})
+	})
+
+	issuer.server = httptest.NewServer(mux)
+	t.Cleanup(issuer.server.Close)
+	return issuer
+}
+
+// rotateKey adds a key and signs the next tokens with it, the previous keys stay in the key set
+func (issuer *mockOidcIssuer) rotateKey(t *testing.T, kid string) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("could not generate key: %v", err)
+	}
+	issuer.mutex.Lock()
+	defer issuer.mutex.Unlock()
+	issuer.keys[kid] = key
+	issuer.signingKid = kid
+}
+
+// validClaims are the claims of a token the client accepts, tests change them to build invalid tokens
+func (issuer *mockOidcIssuer) validClaims() jwt.MapClaims {
+	return jwt.MapClaims{
+		"iss":            issuer.server.URL,
+		"aud":            testOidcClientId,
+		"sub":            "subject-1",
+		"email":          "asha@fiteats.test",
+		"email_verified": true,
+		"name":           "Asha",
+		"nonce":          testOidcNonce,
+		"iat":            testOidcNow.Unix(),
+		"exp":            testOidcNow.Add(time.Hour).Unix(),
+	}
+}
+
+func (issuer *mockOidcIssuer) sign(t *testing.T, claims jwt.MapClaims) string {
+	issuer.mutex.Lock()
+	defer issuer.mutex.Unlock()
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = issuer.signingKid
+	signed, err := token.SignedString(issuer.keys[issuer.signingKid])
+	if err != nil {
+		t.Fatalf("could not sign token: %v", err)
+	}
+	return signed
+}
+
+// issue makes the token endpoint return a token with the claims
+func (issuer *mockOidcIssuer) issue(t *testing.T, claims jwt.MapClaims) {
+	idToken := issuer.sign(t, claims)
+	issuer.mutex.Lock()
+	defer issuer.mutex.Unlock()
+	issuer.idToken = idToken
+}
+
+func (issuer *mockOidcIssuer) newClient() *OidcClient {
+	return &OidcClient{
+		Name:         "mock",
+		Issuer:       issuer.server.URL,
+		ClientId:     testOidcClientId,
+		ClientSecret: "secret",
+		RedirectUri:  "http://localhost/callback",
+		Now:          func() time.Time { return testOidcNow },
+	}
+}
+
+func TestOidcClientExchangeCode(t *testing.T) {
+	issuer := newMockOidcIssuer(t)
+	client := issuer.newClient()
+	issuer.issue(t, issuer.validClaims())
+
+	authorizationUrl, err := client.GetAuthorizationUrl(context.Background(), "state", testOidcNonce, testOidcCodeVerifier)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(authorizationUrl, issuer.server.URL+"/authorize?") || !strings.Contains(authorizationUrl, "code_challenge_method=S256") {
+		t.Errorf("unexpected authorization url %s", authorizationUrl)
+	}
+
+	claims, err := client.ExchangeCode(context.Background(), testOidcCode, testOidcCodeVerifier, testOidcNonce)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if claims.Subject != "subject-1" || claims.Email != "asha@fiteats.test" || !claims.EmailVerified || claims.Name != "Asha" {
+		t.Errorf("unexpected claims %+v", claims)
+	}
+	if issuer.tokenParams["grant_type"] != "authorization_code" || issuer.tokenParams["client_id"] != testOidcClientId {
+		t.Errorf("unexpected token request %v", issuer.tokenParams)
+	}
+
+	if _, err := client.ExchangeCode(context.Background(), "wrong-code", testOidcCodeVerifier, testOidcNonce); err == nil {
+		t.Error("expected a code the issuer rejects to fail")
+	}
+}
+
+func TestOidcClientRejectsInvalidIdTokens(t *testing.T) {
+	issuer := newMockOidcIssuer(t)
+
+	tests := []struct {
+		name   string
+		change func(claims jwt.MapClaims)
+		nonce  string
+	}{
+		{"wrong audience", func(claims jwt.MapClaims) { claims["aud"] = "another-client" }, testOidcNonce},
+		{"wrong issuer", func(claims jwt.MapClaims) { claims["iss"] = "https://attacker.test" }, testOidcNonce},
+		{"expired", func(claims jwt.MapClaims) { claims["exp"] = testOidcNow.Add(-2 * time.Minute).Unix() }, testOidcNonce},
+		{"missing expiry", func(claims jwt.MapClaims) { delete(claims, "exp") }, testOidcNonce},
+		{"nonce mismatch", func(claims jwt.MapClaims) {}, "another-nonce"},
+		{"missing nonce", func(claims jwt.MapClaims) { delete(claims, "nonce") }, ""},
+		{"missing subject", func(claims jwt.MapClaims) { delete(claims, "sub") }, testOidcNonce},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			claims := issuer.validClaims()
+			test.change(claims)
+			if _, err := issuer.newClient().VerifyIdToken(context.Background(), issuer.sign(t, claims), test.nonce); err == nil {
+				t.Fatal("expected the id token to be rejected")
+			}
+		})
+	}
+
+	t.Run("expired within leeway", func(t *testing.T) {
+		claims := issuer.validClaims()
+		claims["exp"] = testOidcNow.Add(-30 * time.Second).Unix()
+		if _, err := issuer.newClient().VerifyIdToken(context.Background(), issuer.sign(t, claims), testOidcNonce); err != nil {
+			t.Fatalf("expected the clock leeway to accept the token: %v", err)
+		}
+	})
+
+	t.Run("signed by another key", func(t *testing.T) {
+		otherIssuer := newMockOidcIssuer(t)
+		claims := issuer.validClaims()
+		if _, err := issuer.newClient().VerifyIdToken(context.Background(), otherIssuer.sign(t, claims), testOidcNonce); err == nil {
+			t.Fatal("expected a token signed by another key to be rejected")
+		}
+	})
+}
+
+func TestOidcClientReloadsRotatedKeys(t *testing.T) {
+	issuer := newMockOidcIssuer(t)
+	client := issuer.newClient()
+
+	if _, err := client.VerifyIdToken(context.Background(), issuer.sign(t, issuer.validClaims()), testOidcNonce); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := client.VerifyIdToken(context.Background(), issuer.sign(t, issuer.validClaims()), testOidcNonce); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if issuer.jwksLoads != 1 {
+		t.Fatalf("expected the key set to be loaded once, got %d loads", issuer.jwksLoads)
+	}
+
+	// The client has only seen key-1, a token signed with the new key reloads the key set
+	issuer.rotateKey(t, "key-2")
+	if _, err := client.VerifyIdToken(context.Background(), issuer.sign(t, issuer.validClaims()), testOidcNonce); err != nil {
+		t.Fatalf("expected the rotated key to be loaded: %v", err)
+	}
+	if issuer.jwksLoads != 2 {
+		t.Fatalf("expected the key set to be reloaded once, got %d loads", issuer.jwksLoads)
+	}
+}
@@ -0,0 +1,78 @@
+package utils
+
+import (
+	"encoding/base32"
+	"testing"
+	"time"
+)
+
+// rfc6238Secret is the SHA1 key of the test vectors of RFC 6238 Appendix B, "12345678901234567890"
+var rfc6238Secret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+func TestGenerateTotpCodeRfc6238Vectors(t *testing.T) {
+	// The RFC lists 8 digit codes, a 6 digit code is the same value modulo 10^6, its last 6 digits
+	vectors := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},          // 94287082
+		{1111111109, "081804"},  // 07081804
+		{1111111111, "050471"},  // 14050471
+		{1234567890, "005924"},  // 89005924
+		{2000000000, "279037"},  // 69279037
+		{20000000000, "353130"}, // 65353130
+	}
+
+	for _, vector := range vectors {
+		code, err := GenerateTotpCode(rfc6238Secret, time.Unix(vector.unix, 0))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if code != vector.code {
+			t.Errorf("at %d expected %s, got %s", vector.unix, vector.code, code)
+		}
+	}
+}
+
+func TestVerifyTotpCodeSkew(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	counter := GetTotpCounter(now)
+
+	tests := []struct {
+		name      string
+		codeAt    time.Time
+		ok        bool
+		counterAt int64
+	}{
+		{"current period", now, true, counter},
+		{"previous period", now.Add(-TotpPeriod * time.Second), true, counter - 1},
+		{"next period", now.Add(TotpPeriod * time.Second), true, counter + 1},
+		{"two periods before", now.Add(-2 * TotpPeriod * time.Second), false, 0},
+		{"two periods after", now.Add(2 * TotpPeriod * time.Second), false, 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			code, err := GenerateTotpCode(rfc6238Secret, test.codeAt)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			matched, ok := VerifyTotpCode(rfc6238Secret, code, now)
+			if ok != test.ok || matched != test.counterAt {
+				t.Errorf("expected %v at counter %d, got %v at counter %d", test.ok, test.counterAt, ok, matched)
+			}
+		})
+	}
+}
+
+func TestVerifyTotpCodeRejectsMalformedCodes(t *testing.T) {
+	now := time.Unix(59, 0)
+	for _, code := range []string{"", "28708", "94287082", "abcdef"} {
+		if _, ok := VerifyTotpCode(rfc6238Secret, code, now); ok {
+			t.Errorf("expected %q to be rejected", code)
+		}
+	}
+	if _, ok := VerifyTotpCode("not base32!", "287082", now); ok {
+		t.Error("expected an invalid secret to be rejected")
+	}
+}
@@ -0,0 +1,79 @@
+package utils
+
+import (
+	"fit-eats-api/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func ParseWorkoutRoutineResponse(userId, mainGoalId, weeklyGoalId primitive.ObjectID, goalType models.GoalType, startDate time.Time, response map[string]any) models.WorkoutRoutine {
+	var routine models.WorkoutRoutine
+	routine.ID = primitive.NewObjectID()
+	routine.UserId = userId
+	routine.MainGoalId = mainGoalId
+	routine.WeeklyGoalId = weeklyGoalId
+	routine.GoalType = goalType
+
+	workoutDays, ok := response["workoutDays"].([]any)
+	if !ok {
+		return routine
+	}
+
+	for _, wd := range workoutDays {
+		dayData, ok := wd.(map[string]any)
+		if !ok {
+			continue
+		}
+
+		dayOfWeek, _ := dayData["dayOfWeek"].(string)
+		workoutDay := models.WorkoutDay{
+			ID:        primitive.NewObjectID(),
+			Date:      getDateFromDayOfWeek(startDate, dayOfWeek),
+			Exercises: []models.RoutineExercise{},
+		}
+		workoutDay.Name, _ = dayData["name"].(string)
+		workoutDay.IsRestDay, _ = dayData["isRestDay"].(bool)
+
+		if exercises, ok := dayData["exercises"].([]any); ok && !workoutDay.IsRestDay {
+			for _, e := range exercises {
+				exerciseMap, ok := e.(map[string]any)
+				if !ok {
+					continue
+				}
+
+				name, _ := exerciseMap["exercise"].(string)
+				exercise, known := models.GetExerciseByName(name)
+				if !known {
+					continue // the schema restricts names to the catalogue, skip anything else
+				}
+
+				routineExercise := models.RoutineExercise{
+					ID:         primitive.NewObjectID(),
+					ExerciseId: exercise.ID,
+					Name:       exercise.Name,
+				}
+				if sets, ok := exerciseMap["sets"].(float64); ok {
+					routineExercise.Sets = int(sets)
+				}
+				if reps, ok := exerciseMap["reps"].(float64); ok {
+					routineExercise.Reps = int(reps)
+				}
+				routineExercise.WeightInKg, _ = exerciseMap["weight_in_kg"].(float64)
+				if duration, ok := exerciseMap["duration_minutes"].(float64); ok {
+					routineExercise.DurationMinutes = int(duration)
+				}
+				if rest, ok := exerciseMap["rest_seconds"].(float64); ok {
+					routineExercise.RestSeconds = int(rest)
+				}
+				routineExercise.Notes, _ = exerciseMap["notes"].(string)
+
+				workoutDay.Exercises = append(workoutDay.Exercises, routineExercise)
+			}
+		}
+
+		routine.WorkoutDays = append(routine.WorkoutDays, workoutDay)
+	}
+
+	return routine
+}